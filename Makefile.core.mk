@@ -481,6 +481,10 @@ BENCH_TARGETS ?= ./pilot/...
 racetest: $(JUNIT_REPORT)
 	go test ${GOBUILDFLAGS} ${T} -race ./... 2>&1 | tee >($(JUNIT_REPORT) > $(JUNIT_OUT))
 
+.PHONY: envoy-validate-test
+envoy-validate-test: init ## Runs the pilot configuration generation tests, validating the generated configuration with Envoy
+	XDSTEST_ENVOY_VALIDATE=true go test ${GOBUILDFLAGS} ${T} ./pilot/pkg/networking/core/v1alpha3/...
+
 .PHONY: benchtest
 benchtest: $(JUNIT_REPORT) ## Runs all benchmarks
 	prow/benchtest.sh run $(BENCH_TARGETS)
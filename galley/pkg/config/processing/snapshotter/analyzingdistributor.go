@@ -17,6 +17,7 @@ package snapshotter
 import (
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ryanuber/go-glob"
 
@@ -39,6 +40,12 @@ type AnalyzingDistributor struct {
 
 	analysisMu     sync.Mutex
 	cancelAnalysis chan struct{}
+	// lastAnalysis is when the last analysis session started.
+	lastAnalysis time.Time
+	// pending is the latest trigger snapshot received within MinAnalysisInterval of the last analysis, analyzed
+	// once the interval elapses.
+	pending      *Snapshot
+	pendingTimer *time.Timer
 
 	snapshotsMu   sync.RWMutex
 	lastSnapshots map[string]*Snapshot
@@ -73,6 +80,11 @@ type AnalyzingDistributorSettings struct {
 
 	// Suppressions that suppress a set of matching messages.
 	Suppressions []AnalysisSuppression
+
+	// MinAnalysisInterval rate limits the analysis: trigger snapshots received within this interval of the start of
+	// the last analysis are not analyzed, except the latest one, once the interval elapses. Zero analyzes every
+	// trigger snapshot.
+	MinAnalysisInterval time.Duration
 }
 
 // AnalysisSuppression describes a resource and analysis code to be suppressed
@@ -122,6 +134,32 @@ func (d *AnalyzingDistributor) Distribute(name string, s *Snapshot) {
 	d.analysisMu.Lock()
 	defer d.analysisMu.Unlock()
 
+	if wait := d.s.MinAnalysisInterval - time.Since(d.lastAnalysis); d.s.MinAnalysisInterval > 0 && wait > 0 {
+		d.pending = s
+		if d.pendingTimer == nil {
+			d.pendingTimer = time.AfterFunc(wait, d.analyzePending)
+		}
+		return
+	}
+	d.startAnalysis(name, s)
+}
+
+// analyzePending analyzes the latest trigger snapshot received within MinAnalysisInterval of the last analysis.
+func (d *AnalyzingDistributor) analyzePending() {
+	d.analysisMu.Lock()
+	defer d.analysisMu.Unlock()
+
+	s := d.pending
+	d.pending = nil
+	d.pendingTimer = nil
+	if s != nil {
+		d.startAnalysis(d.s.TriggerSnapshot, s)
+	}
+}
+
+// startAnalysis starts a new analysis session of the trigger snapshot, canceling the previous one. analysisMu must
+// be held.
+func (d *AnalyzingDistributor) startAnalysis(name string, s *Snapshot) {
 	// Cancel the previous analysis session, if it is still working.
 	if d.cancelAnalysis != nil {
 		close(d.cancelAnalysis)
@@ -136,6 +174,7 @@ func (d *AnalyzingDistributor) Distribute(name string, s *Snapshot) {
 	// start a new analysis session
 	cancelAnalysis := make(chan struct{})
 	d.cancelAnalysis = cancelAnalysis
+	d.lastAnalysis = time.Now()
 	go d.analyzeAndDistribute(cancelAnalysis, name, s, namespaces)
 }
 
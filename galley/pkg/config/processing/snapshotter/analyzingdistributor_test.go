@@ -186,6 +186,38 @@ func TestAnalyzeAndDistributeSnapshots(t *testing.T) {
 	}
 }
 
+func TestAnalyzeRateLimitsAnalysis(t *testing.T) {
+	g := NewWithT(t)
+
+	u := &updaterMock{}
+	a := &analyzerMock{collectionToAccess: basicmeta.K8SCollection1.Name()}
+	d := NewInMemoryDistributor()
+
+	settings := AnalyzingDistributorSettings{
+		StatusUpdater:       u,
+		Analyzer:            analysis.Combine("testCombined", a),
+		Distributor:         d,
+		AnalysisSnapshots:   []string{snapshots.Default},
+		TriggerSnapshot:     snapshots.Default,
+		MinAnalysisInterval: 200 * time.Millisecond,
+	}
+	ad := NewAnalyzingDistributor(settings)
+
+	s1 := getTestSnapshot(newSchema("a"))
+	s2 := getTestSnapshot(newSchema("b"))
+	s3 := getTestSnapshot(newSchema("c"))
+
+	ad.Distribute(snapshots.Default, s1)
+	ad.Distribute(snapshots.Default, s2)
+	ad.Distribute(snapshots.Default, s3)
+
+	// The snapshots received within the interval are analyzed once, with the latest one, when the interval elapses
+	g.Eventually(func() int { return len(a.getAnalyzeCalls()) }).Should(Equal(2))
+	g.Consistently(func() int { return len(a.getAnalyzeCalls()) }, 400*time.Millisecond).Should(Equal(2))
+	g.Expect(a.getAnalyzeCalls()[1]).To(Equal(getTestSnapshot(newSchema("c"))))
+	g.Eventually(func() snapshot.Snapshot { return d.GetSnapshot(snapshots.Default) }).Should(Equal(s3))
+}
+
 func TestAnalyzeNamespaceMessageHasNoResource(t *testing.T) {
 	g := NewWithT(t)
 
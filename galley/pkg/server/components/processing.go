@@ -16,6 +16,7 @@ package components
 
 import (
 	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/processing"
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
 	"istio.io/istio/galley/pkg/config/processor"
@@ -91,12 +92,17 @@ func (p *Processing) Start() (err error) {
 		combinedAnalyzer := analyzers.AllCombined()
 		combinedAnalyzer.RemoveSkipped(colsInSnapshots, kubeResources.DisabledCollectionNames(), transformProviders)
 
+		if p.args.AnalysisListener != nil {
+			updater = listeningStatusUpdater{StatusUpdater: updater, listener: p.args.AnalysisListener}
+		}
+
 		distributor = snapshotter.NewAnalyzingDistributor(snapshotter.AnalyzingDistributorSettings{
-			StatusUpdater:     updater,
-			Analyzer:          combinedAnalyzer,
-			Distributor:       distributor,
-			AnalysisSnapshots: p.args.Snapshots,
-			TriggerSnapshot:   p.args.TriggerSnapshot,
+			StatusUpdater:       updater,
+			Analyzer:            combinedAnalyzer,
+			Distributor:         distributor,
+			AnalysisSnapshots:   p.args.Snapshots,
+			TriggerSnapshot:     p.args.TriggerSnapshot,
+			MinAnalysisInterval: p.args.MinAnalysisInterval,
 		})
 	}
 
@@ -165,3 +171,15 @@ func (p *Processing) Stop() {
 		p.runtime = nil
 	}
 }
+
+// listeningStatusUpdater passes the analysis messages to a listener, in addition to the status updater.
+type listeningStatusUpdater struct {
+	snapshotter.StatusUpdater
+	listener func(diag.Messages)
+}
+
+// Update implements snapshotter.StatusUpdater
+func (u listeningStatusUpdater) Update(messages diag.Messages) {
+	u.StatusUpdater.Update(messages)
+	u.listener(messages)
+}
@@ -21,6 +21,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/util/kuberesource"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/event"
@@ -60,6 +61,12 @@ type Args struct { // nolint:maligned
 	// Enable Config Analysis service, that will analyze and update CRD status. UseOldProcessor must be set to false.
 	EnableConfigAnalysis bool
 
+	// AnalysisListener, if set, is called with the messages of each config analysis.
+	AnalysisListener func(diag.Messages)
+
+	// MinAnalysisInterval rate limits the config analysis. Zero analyzes every change.
+	MinAnalysisInterval time.Duration
+
 	Snapshots       []string
 	TriggerSnapshot string
 }
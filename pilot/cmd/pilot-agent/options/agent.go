@@ -37,6 +37,11 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		IsIPv6:                   proxy.SupportsIPv6(),
 		ProxyType:                proxy.Type,
 		EnableDynamicProxyConfig: enableProxyConfigXdsEnv,
+		EnableConfigWatermark:    enableConfigWatermarkEnv,
+		EnableXDSResumption:      enableXDSResumptionEnv,
+		XDSFailoverAddresses:     splitAddresses(xdsFailoverAddressesEnv),
+		XDSHealthCheckInterval:   xdsFailoverHealthCheckIntervalEnv,
+		XDSCacheTTL:              xdsCacheTTLEnv,
 		EnableDynamicBootstrap:   enableBootstrapXdsEnv,
 		ProxyIPAddresses:         proxy.IPAddresses,
 		ServiceNode:              proxy.ServiceNode(),
@@ -45,6 +50,7 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		Platform:                 platform.Discover(),
 		GRPCBootstrapPath:        grpcBootstrapEnv,
 		DisableEnvoy:             disableEnvoyEnv,
+		NativeSidecar:            nativeSidecarEnv,
 	}
 	extractXDSHeadersFromEnv(o)
 	if proxyXDSViaAgent {
@@ -52,6 +58,7 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		o.ProxyXDSDebugViaAgent = proxyXDSDebugViaAgent
 		o.DNSCapture = DNSCaptureByAgent.Get()
 		o.DNSAddr = DNSCaptureAddr.Get()
+		o.DNSQueryLogSampleRate = dnsQueryLogSampleRateEnv
 		o.ProxyNamespace = PodNamespaceVar.Get()
 		o.ProxyDomain = proxy.DNSDomain
 	}
@@ -59,6 +66,17 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 	return o
 }
 
+// splitAddresses returns the non-empty addresses of a comma separated list.
+func splitAddresses(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
 // Simplified extraction of gRPC headers from environment.
 // Unlike ISTIO_META, where we need JSON and advanced features - this is just for small string headers.
 func extractXDSHeadersFromEnv(o *istioagent.AgentOptions) {
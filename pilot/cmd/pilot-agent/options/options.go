@@ -89,10 +89,33 @@ var (
 	DNSCaptureAddr = env.RegisterStringVar("DNS_PROXY_ADDR", "localhost:15053",
 		"Custom address for the DNS proxy. If it ends with :53 and running as root allows running without iptable DNS capture")
 
+	dnsQueryLogSampleRateEnv = env.RegisterFloatVar("DNS_QUERY_LOG_SAMPLE_RATE", 0,
+		"Fraction of DNS queries handled by the DNS proxy that are logged, between 0 and 1. Disabled by default.").Get()
+
 	// Ability of istio-agent to retrieve proxyConfig via XDS for dynamic configuration updates
 	enableProxyConfigXdsEnv = env.RegisterBoolVar("PROXY_CONFIG_XDS_AGENT", false,
 		"If set to true, agent retrieves dynamic proxy-config updates via xds channel").Get()
 
+	// Ability of istio-agent to track config freshness via the watermark pushed by istiod
+	enableConfigWatermarkEnv = env.RegisterBoolVar("PROXY_CONFIG_WATERMARK", false,
+		"If set to true, agent subscribes to the config watermark pushed by istiod and reports it as a metric").Get()
+
+	// Ability of istio-agent to resume XDS sessions without receiving unchanged config again
+	enableXDSResumptionEnv = env.RegisterBoolVar("PROXY_XDS_RESUMPTION", false,
+		"If set to true, agent presents the last resumption token issued by istiod when reconnecting").Get()
+
+	// Ability of istio-agent to fail over to other istiods when the one of the proxy config is unhealthy
+	xdsFailoverAddressesEnv = env.RegisterStringVar("PROXY_XDS_FAILOVER_ADDRESSES", "",
+		"Comma separated list of discovery addresses, by order of preference, the agent connects to when the "+
+			"discovery address of the proxy config is unhealthy. The agent fails back to a preferred address once "+
+			"it is healthy again").Get()
+	xdsFailoverHealthCheckIntervalEnv = env.RegisterDurationVar("PROXY_XDS_FAILOVER_HEALTH_CHECK_INTERVAL",
+		10*time.Second, "The interval at which the agent checks the health of the discovery addresses, when "+
+			"PROXY_XDS_FAILOVER_ADDRESSES is set").Get()
+	xdsCacheTTLEnv = env.RegisterDurationVar("PROXY_XDS_CACHE_TTL", 0,
+		"If set, the agent keeps serving Envoy the last config it acked, for up to this duration, while istiod "+
+			"is unreachable. This includes Envoy restarts. Disabled if 0").Get()
+
 	// Ability of istio-agent to retrieve bootstrap via XDS
 	enableBootstrapXdsEnv = env.RegisterBoolVar("BOOTSTRAP_XDS_AGENT", false,
 		"If set to true, agent retrieves the bootstrap configuration prior to starting Envoy").Get()
@@ -108,4 +131,11 @@ var (
 
 	disableEnvoyEnv = env.RegisterBoolVar("DISABLE_ENVOY", false,
 		"Disables all Envoy agent features.").Get()
+
+	readinessChecksEnv = env.RegisterStringVar("ISTIO_READINESS_CHECKS", "",
+		"Comma separated list of optional checks of the readiness endpoint, in addition to the proxy readiness: "+
+			"app, for the readiness probes of the application, and cert, for the validity of the workload certificate.").Get()
+
+	nativeSidecarEnv = env.RegisterBoolVar("NATIVE_SIDECAR", false,
+		"Set by the injector if the proxy runs as a native sidecar, which is only stopped after the application containers.").Get()
 )
@@ -25,6 +25,7 @@ import (
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/jwt"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/uds"
 	"istio.io/istio/security/pkg/credentialfetcher"
 	"istio.io/istio/security/pkg/nodeagent/plugin/providers/google/stsclient"
 	"istio.io/istio/security/pkg/stsservice/tokenmanager"
@@ -88,6 +89,11 @@ func SetupSecurityOptions(proxyConfig *meshconfig.ProxyConfig, secOpt *security.
 
 	// If not set explicitly, default to the discovery address.
 	if o.CAEndpoint == "" {
+		// The CA is only served over TLS, which is not used when the discovery address is a unix
+		// domain socket, so certificates must come from elsewhere.
+		if uds.IsUnixAddress(proxyConfig.DiscoveryAddress) && !o.FileMountedCerts {
+			return nil, fmt.Errorf("discovery address %s is a unix domain socket; CA_ADDR must be set", proxyConfig.DiscoveryAddress)
+		}
 		o.CAEndpoint = proxyConfig.DiscoveryAddress
 	}
 
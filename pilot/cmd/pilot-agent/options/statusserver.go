@@ -15,6 +15,8 @@
 package options
 
 import (
+	"strings"
+
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
@@ -24,15 +26,29 @@ import (
 
 func NewStatusServerOptions(proxy *model.Proxy, proxyConfig *meshconfig.ProxyConfig, agent *istioagent.Agent) *status.Options {
 	return &status.Options{
-		IPv6:           IsIPv6Proxy(proxy.IPAddresses),
-		PodIP:          InstanceIPVar.Get(),
-		AdminPort:      uint16(proxyConfig.ProxyAdminPort),
-		StatusPort:     uint16(proxyConfig.StatusPort),
-		KubeAppProbers: kubeAppProberNameVar.Get(),
-		NodeType:       proxy.Type,
-		Probes:         []ready.Prober{agent},
-		NoEnvoy:        agent.EnvoyDisabled(),
-		FetchDNS:       agent.GetDNSTable,
-		GRPCBootstrap:  agent.GRPCBootstrapPath(),
+		IPv6:              IsIPv6Proxy(proxy.IPAddresses),
+		PodIP:             InstanceIPVar.Get(),
+		AdminPort:         uint16(proxyConfig.ProxyAdminPort),
+		StatusPort:        uint16(proxyConfig.StatusPort),
+		KubeAppProbers:    kubeAppProberNameVar.Get(),
+		NodeType:          proxy.Type,
+		Probes:            []ready.Prober{ready.Named("dns", agent)},
+		NoEnvoy:           agent.EnvoyDisabled(),
+		FetchDNS:          agent.GetDNSTable,
+		FetchDNSCache:     agent.GetDNSCache,
+		FlushDNSCache:     agent.FlushDNSCache,
+		GRPCBootstrap:     agent.GRPCBootstrapPath(),
+		ReadinessChecks:   readinessChecks(),
+		CheckWorkloadCert: agent.CheckWorkloadCert,
+	}
+}
+
+func readinessChecks() []string {
+	var checks []string
+	for _, c := range strings.Split(readinessChecksEnv, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			checks = append(checks, c)
+		}
 	}
+	return checks
 }
@@ -129,3 +129,31 @@ func checkEnvoyStats(host string, port uint16) error {
 
 	return nil
 }
+
+// ProberFunc adapts a function to a Prober.
+type ProberFunc func() error
+
+// Check implements Prober.
+func (f ProberFunc) Check() error {
+	return f()
+}
+
+// NamedProber is a Prober whose result is reported under a name by the readiness endpoint.
+type NamedProber interface {
+	Prober
+	Name() string
+}
+
+type namedProber struct {
+	Prober
+	name string
+}
+
+func (n namedProber) Name() string {
+	return n.name
+}
+
+// Named gives a name to a Prober.
+func Named(name string, p Prober) NamedProber {
+	return namedProber{Prober: p, name: name}
+}
@@ -27,6 +27,7 @@ import (
 	"net/http/pprof"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -46,6 +47,7 @@ import (
 	"istio.io/istio/pilot/cmd/pilot-agent/status/grpcready"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/pkg/model"
+	dnsClient "istio.io/istio/pkg/dns/client"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 	"istio.io/istio/pkg/kube/apimirror"
 	"istio.io/pkg/env"
@@ -111,10 +113,24 @@ type Options struct {
 	EnvoyPrometheusPort int
 	Context             context.Context
 	FetchDNS            func() *dnsProto.NameTable
+	FetchDNSCache       func() *dnsClient.CacheDump
+	FlushDNSCache       func() bool
 	NoEnvoy             bool
 	GRPCBootstrap       string
+	// ReadinessChecks are the optional checks of the readiness endpoint to enable: ReadinessCheckApp
+	// and ReadinessCheckCert.
+	ReadinessChecks []string
+	// CheckWorkloadCert returns an error if the workload certificate is not valid. Required by ReadinessCheckCert.
+	CheckWorkloadCert func() error
 }
 
+const (
+	// ReadinessCheckApp checks the readiness probes of the application containers.
+	ReadinessCheckApp = "app"
+	// ReadinessCheckCert checks the validity of the workload certificate.
+	ReadinessCheckCert = "cert"
+)
+
 // Server provides an endpoint for handling status probes.
 type Server struct {
 	ready                 []ready.Prober
@@ -127,6 +143,8 @@ type Server struct {
 	lastProbeSuccessful   bool
 	envoyStatsPort        int
 	fetchDNS              func() *dnsProto.NameTable
+	fetchDNSCache         func() *dnsClient.CacheDump
+	flushDNSCache         func() bool
 }
 
 func init() {
@@ -152,16 +170,16 @@ func NewServer(config Options) (*Server, error) {
 	}
 	probes := make([]ready.Prober, 0)
 	if !config.NoEnvoy {
-		probes = append(probes, &ready.Probe{
+		probes = append(probes, ready.Named("envoy", &ready.Probe{
 			LocalHostAddr: localhost,
 			AdminPort:     config.AdminPort,
 			Context:       config.Context,
 			NoEnvoy:       config.NoEnvoy,
-		})
+		}))
 	}
 
 	if config.GRPCBootstrap != "" {
-		probes = append(probes, grpcready.NewProbe(config.GRPCBootstrap))
+		probes = append(probes, ready.Named("grpc", grpcready.NewProbe(config.GRPCBootstrap)))
 	}
 
 	probes = append(probes, config.Probes...)
@@ -171,6 +189,21 @@ func NewServer(config Options) (*Server, error) {
 		appProbersDestination: config.PodIP,
 		envoyStatsPort:        config.EnvoyPrometheusPort,
 		fetchDNS:              config.FetchDNS,
+		fetchDNSCache:         config.FetchDNSCache,
+		flushDNSCache:         config.FlushDNSCache,
+	}
+	for _, check := range config.ReadinessChecks {
+		switch check {
+		case ReadinessCheckApp:
+			s.ready = append(s.ready, ready.Named(check, ready.ProberFunc(s.checkAppReadiness)))
+		case ReadinessCheckCert:
+			if config.CheckWorkloadCert == nil {
+				return nil, fmt.Errorf("readiness check %q is not supported", check)
+			}
+			s.ready = append(s.ready, ready.Named(check, ready.ProberFunc(config.CheckWorkloadCert)))
+		default:
+			return nil, fmt.Errorf("unknown readiness check %q", check)
+		}
 	}
 	if LegacyLocalhostProbeDestination.Get() {
 		s.appProbersDestination = "localhost"
@@ -269,6 +302,7 @@ func (s *Server) Run(ctx context.Context) {
 	mux.HandleFunc("/debug/pprof/symbol", s.handlePprofSymbol)
 	mux.HandleFunc("/debug/pprof/trace", s.handlePprofTrace)
 	mux.HandleFunc("/debug/ndsz", s.handleNdsz)
+	mux.HandleFunc("/debug/dnsz", s.handleDnsz)
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.statusPort))
 	if err != nil {
@@ -350,29 +384,100 @@ func (s *Server) handlePprofTrace(w http.ResponseWriter, r *http.Request) {
 	pprof.Trace(w, r)
 }
 
-func (s *Server) handleReadyProbe(w http.ResponseWriter, _ *http.Request) {
-	err := s.isReady()
+// ReadinessCheckResult is the result of a single check of the readiness endpoint.
+type ReadinessCheckResult struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReadinessResponse is the body returned by the readiness endpoint.
+type ReadinessResponse struct {
+	Ready  bool                   `json:"ready"`
+	Checks []ReadinessCheckResult `json:"checks"`
+}
+
+// handleReadyProbe runs all readiness checks, except the ones listed in the comma separated exclude
+// query parameter, and reports their results in the response body.
+func (s *Server) handleReadyProbe(w http.ResponseWriter, req *http.Request) {
+	var exclude []string
+	if e := req.URL.Query().Get("exclude"); e != "" {
+		exclude = strings.Split(e, ",")
+	}
+	results, err := s.checkReadiness(exclude...)
 	s.mutex.Lock()
 	if err != nil {
-		w.WriteHeader(http.StatusServiceUnavailable)
-
 		log.Warnf("Envoy proxy is NOT ready: %s", err.Error())
 		s.lastProbeSuccessful = false
 	} else {
-		w.WriteHeader(http.StatusOK)
-
 		if !s.lastProbeSuccessful {
 			log.Info("Envoy proxy is ready")
 		}
 		s.lastProbeSuccessful = true
 	}
 	s.mutex.Unlock()
+
+	b, _ := json.Marshal(ReadinessResponse{Ready: err == nil, Checks: results})
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_, _ = w.Write(b)
 }
 
 func (s *Server) isReady() error {
-	for _, p := range s.ready {
+	_, err := s.checkReadiness()
+	return err
+}
+
+// checkReadiness runs the readiness checks not excluded by name, returning the result of each and the
+// first error.
+func (s *Server) checkReadiness(exclude ...string) ([]ReadinessCheckResult, error) {
+	var results []ReadinessCheckResult
+	var firstErr error
+outer:
+	for i, p := range s.ready {
+		name := fmt.Sprintf("probe-%d", i)
+		if n, ok := p.(ready.NamedProber); ok {
+			name = n.Name()
+		}
+		for _, e := range exclude {
+			if strings.TrimSpace(e) == name {
+				continue outer
+			}
+		}
+		result := ReadinessCheckResult{Name: name, Ready: true}
 		if err := p.Check(); err != nil {
-			return err
+			result.Ready = false
+			result.Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		results = append(results, result)
+	}
+	return results, firstErr
+}
+
+// checkAppReadiness checks the readiness probes of the application containers taken over by the agent.
+func (s *Server) checkAppReadiness() error {
+	paths := make([]string, 0, len(s.appKubeProbers))
+	for path := range s.appKubeProbers {
+		if strings.HasSuffix(path, "/readyz") {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		code, err := s.probeApp(path, nil)
+		if err != nil {
+			return fmt.Errorf("application probe %v failed: %v", path, err)
+		}
+		// As for Kubernetes, any code in [200, 400) indicates success.
+		if code < http.StatusOK || code >= http.StatusBadRequest {
+			return fmt.Errorf("application probe %v returned status %d", path, code)
 		}
 	}
 	return nil
@@ -533,14 +638,28 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	if !strings.HasPrefix(path, "/") {
 		path = "/" + req.URL.Path
 	}
-	prober, exists := s.appKubeProbers[path]
-	if !exists {
+	if _, exists := s.appKubeProbers[path]; !exists {
 		log.Errorf("Prober does not exists url %v", path)
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte(fmt.Sprintf("app prober config does not exists for %v", path)))
 		return
 	}
 
+	code, err := s.probeApp(path, req.Header)
+	if err != nil {
+		log.Errorf("Request to probe app failed: %v, original URL path = %v", err, path)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// We only write the status code to the response.
+	w.WriteHeader(code)
+}
+
+// probeApp sends the application probe configured for path, forwarding the given headers, and returns
+// the status code of the response.
+func (s *Server) probeApp(path string, header http.Header) (int, error) {
+	prober := s.appKubeProbers[path]
 	proberPath := prober.HTTPGet.Path
 	if !strings.HasPrefix(proberPath, "/") {
 		proberPath = "/" + proberPath
@@ -553,13 +672,11 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	}
 	appReq, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		log.Errorf("Failed to create request to probe app %v, original url %v", err, path)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return 0, fmt.Errorf("failed to create request to probe app: %v", err)
 	}
 
 	// Forward incoming headers to the application.
-	for name, values := range req.Header {
+	for name, values := range header {
 		newValues := make([]string, len(values))
 		copy(newValues, values)
 		appReq.Header[name] = newValues
@@ -587,18 +704,14 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	// Send the request.
 	response, err := httpClient.Do(appReq)
 	if err != nil {
-		log.Errorf("Request to probe app failed: %v, original URL path = %v\napp URL path = %v", err, path, proberPath)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return 0, fmt.Errorf("%v, app URL path = %v", err, proberPath)
 	}
 	defer func() {
 		// Drain and close the body to let the Transport reuse the connection
 		_, _ = io.Copy(ioutil.Discard, response.Body)
 		_ = response.Body.Close()
 	}()
-
-	// We only write the status code to the response.
-	w.WriteHeader(response.StatusCode)
+	return response.StatusCode, nil
 }
 
 func (s *Server) handleNdsz(w http.ResponseWriter, r *http.Request) {
@@ -616,6 +729,41 @@ func (s *Server) handleNdsz(w http.ResponseWriter, r *http.Request) {
 	writeJSONProto(w, nametable)
 }
 
+// handleDnsz dumps the DNS proxy cache on GET, and flushes it on POST.
+func (s *Server) handleDnsz(w http.ResponseWriter, r *http.Request) {
+	if !isRequestFromLocalhost(r) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var dump *dnsClient.CacheDump
+		if s.fetchDNSCache != nil {
+			dump = s.fetchDNSCache()
+		}
+		if dump == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		b, err := json.MarshalIndent(dump, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	case http.MethodPost:
+		if s.flushDNSCache == nil || !s.flushDNSCache() {
+			http.Error(w, "DNS proxy cache is not initialized", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("OK"))
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 // writeJSONProto writes a protobuf to a json payload, handling content type, marshaling, and errors
 func writeJSONProto(w http.ResponseWriter, obj proto.Message) {
 	w.Header().Set("Content-Type", "application/json")
@@ -823,6 +823,48 @@ func TestAdditionalProbes(t *testing.T) {
 	}
 }
 
+func TestReadinessChecks(t *testing.T) {
+	server, err := NewServer(Options{
+		NoEnvoy:           true,
+		Probes:            []ready.Prober{ready.Named("dns", readyProbe{})},
+		ReadinessChecks:   []string{ReadinessCheckCert},
+		CheckWorkloadCert: func() error { return errors.New("expired") },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(url string, wantCode int, want ReadinessResponse) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		server.handleReadyProbe(rr, httptest.NewRequest("GET", url, nil))
+		if rr.Code != wantCode {
+			t.Fatalf("expected status %v, got %v", wantCode, rr.Code)
+		}
+		var got ReadinessResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	}
+	check("/healthz/ready", http.StatusServiceUnavailable, ReadinessResponse{
+		Ready: false,
+		Checks: []ReadinessCheckResult{
+			{Name: "dns", Ready: true},
+			{Name: "cert", Ready: false, Error: "expired"},
+		},
+	})
+	check("/healthz/ready?exclude=cert", http.StatusOK, ReadinessResponse{
+		Ready:  true,
+		Checks: []ReadinessCheckResult{{Name: "dns", Ready: true}},
+	})
+
+	if _, err := NewServer(Options{NoEnvoy: true, ReadinessChecks: []string{"unknown"}}); err == nil {
+		t.Fatalf("expected error for unknown readiness check")
+	}
+}
+
 type readyProbe struct{}
 
 func (s readyProbe) Check() error {
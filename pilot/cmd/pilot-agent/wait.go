@@ -80,7 +80,8 @@ func init() {
 	waitCmd.PersistentFlags().IntVar(&timeoutSeconds, "timeoutSeconds", 60, "maximum number of seconds to wait for Envoy to be ready")
 	waitCmd.PersistentFlags().IntVar(&requestTimeoutMillis, "requestTimeoutMillis", 500, "number of milliseconds to wait for response")
 	waitCmd.PersistentFlags().IntVar(&periodMillis, "periodMillis", 500, "number of milliseconds to wait between attempts")
-	waitCmd.PersistentFlags().StringVar(&url, "url", "http://localhost:15021/healthz/ready", "URL to use in requests")
+	// The application readiness is excluded, as the application is typically held until the proxy is ready.
+	waitCmd.PersistentFlags().StringVar(&url, "url", "http://localhost:15021/healthz/ready?exclude=app", "URL to use in requests")
 
 	rootCmd.AddCommand(waitCmd)
 }
@@ -155,6 +155,10 @@ func addFlags(c *cobra.Command) {
 		"Injection and validation service HTTPS address")
 	c.PersistentFlags().StringVar(&serverArgs.ServerOptions.GRPCAddr, "grpcAddr", ":15010",
 		"Discovery service gRPC address")
+	c.PersistentFlags().StringVar(&serverArgs.ServerOptions.GRPCUDSPath, "grpcUDSPath", "",
+		"If set, the plaintext discovery service gRPC is also served on this unix domain socket path")
+	c.PersistentFlags().IntVar(&serverArgs.ServerOptions.GRPCUDSGroup, "grpcUDSGroup", 0,
+		"The group ID allowed to connect to the grpcUDSPath socket, besides the user of istiod. If zero, only the user of istiod can connect")
 	c.PersistentFlags().StringVar(&serverArgs.ServerOptions.SecureGRPCAddr, "secureGRPCAddr", ":15012",
 		"Discovery service secured gRPC address")
 	c.PersistentFlags().StringVar(&serverArgs.ServerOptions.MonitoringAddr, "monitoringAddr", ":15014",
@@ -17,6 +17,9 @@ package bootstrap
 import (
 	"fmt"
 	"net/url"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/galley/pkg/config/mesh"
@@ -36,6 +39,7 @@ import (
 	"istio.io/istio/pilot/pkg/status"
 	"istio.io/istio/pkg/adsc"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/dependency"
 	"istio.io/pkg/log"
 )
 
@@ -211,12 +215,19 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 			}
 			s.ConfigStores = append(s.ConfigStores, configController)
 		case XDS:
+			address := configSource.Address
+			start := time.Now()
 			xdsMCP, err := adsc.New(srcAddress.Host, &adsc.Config{
 				Meta: model.NodeMetadata{
 					Generator: "api",
 				}.ToStruct(),
 				InitialDiscoveryRequests: adsc.ConfigInitialRequests(),
+				ResponseHandler:          configSourceHealth(address),
+				ErrorHandler: func(err error) {
+					dependency.Report(dependency.ConfigSource, address, time.Time{}, err)
+				},
 			})
+			dependency.Report(dependency.ConfigSource, address, start, err)
 			if err != nil {
 				return fmt.Errorf("failed to dial XDS %s %v", configSource.Address, err)
 			}
@@ -249,6 +260,13 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 	return nil
 }
 
+// configSourceHealth reports the responses received from an XDS config source to the dependency registry.
+type configSourceHealth string
+
+func (h configSourceHealth) HandleResponse(*adsc.ADSC, *discovery.DiscoveryResponse) {
+	dependency.Report(dependency.ConfigSource, string(h), time.Time{}, nil)
+}
+
 // initInprocessAnalysisController spins up an instance of Galley which serves no purpose other than
 // running Analyzers for status updates.  The Status Updater will eventually need to allow input from istiod
 // to support config distribution status as well.
@@ -256,6 +274,8 @@ func (s *Server) initInprocessAnalysisController(args *PilotArgs) error {
 	processingArgs := settings.DefaultArgs()
 	processingArgs.KubeConfig = args.RegistryOptions.KubeConfig
 	processingArgs.EnableConfigAnalysis = true
+	processingArgs.MinAnalysisInterval = features.AnalysisInterval
+	processingArgs.AnalysisListener = s.XDSServer.RecordAnalysis
 	meshSource := mesh.NewInmemoryMeshCfg()
 	meshSource.Set(s.environment.Mesh())
 	s.environment.Watcher.AddMeshHandler(func() {
@@ -300,8 +320,9 @@ func (s *Server) initInprocessAnalysisController(args *PilotArgs) error {
 
 func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 	s.statusReporter = &status.Reporter{
-		UpdateInterval: features.StatusUpdateInterval,
-		PodName:        args.PodName,
+		UpdateInterval:    features.StatusUpdateInterval,
+		HeartbeatInterval: features.StatusHeartbeatInterval,
+		PodName:           args.PodName,
 	}
 	s.addStartFunc(func(stop <-chan struct{}) error {
 		s.statusReporter.Init(s.environment.GetLedger(), stop)
@@ -324,6 +345,7 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 					controller := status.NewController(s.kubeClient.RESTConfig(), args.Namespace, s.RWConfigStore)
 					s.statusReporter.SetController(controller)
 					controller.Start(stop)
+					go s.XDSServer.WritePolicyStatus(s.RWConfigStore, stop)
 				}).Run(stop)
 			return nil
 		})
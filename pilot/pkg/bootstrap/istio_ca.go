@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 	"strings"
@@ -34,6 +35,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	securityModel "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/fips"
 	"istio.io/istio/pkg/jwt"
 	kubelib "istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/security"
@@ -129,6 +131,37 @@ var (
 	caRSAKeySize = env.RegisterIntVar("CITADEL_SELF_SIGNED_CA_RSA_KEY_SIZE", 2048,
 		"Specify the RSA key size to use for self-signed Istio CA certificates.")
 
+	caIdentityCSRRate = env.RegisterFloatVar("CITADEL_IDENTITY_CSR_RATE", 0,
+		"The sustained number of certificates per second the CA issues to each identity. Requests above it are "+
+			"denied with RESOURCE_EXHAUSTED. Zero disables the limit.")
+
+	caIdentityCSRBurst = env.RegisterIntVar("CITADEL_IDENTITY_CSR_BURST", 10,
+		"The number of certificates an identity can get at once, above CITADEL_IDENTITY_CSR_RATE.")
+
+	caNamespaceCSRRate = env.RegisterFloatVar("CITADEL_NAMESPACE_CSR_RATE", 0,
+		"The sustained number of certificates per second the CA issues to the identities of each namespace. "+
+			"Zero disables the limit.")
+
+	caNamespaceCSRBurst = env.RegisterIntVar("CITADEL_NAMESPACE_CSR_BURST", 100,
+		"The number of certificates the identities of a namespace can get at once, above CITADEL_NAMESPACE_CSR_RATE.")
+
+	caIdentityCSRQuota = env.RegisterIntVar("CITADEL_IDENTITY_CSR_QUOTA", 0,
+		"The number of certificates the CA issues to each identity per CITADEL_CSR_QUOTA_WINDOW. Zero disables "+
+			"the quota.")
+
+	caCSRQuotaWindow = env.RegisterDurationVar("CITADEL_CSR_QUOTA_WINDOW", time.Hour,
+		"The window of CITADEL_IDENTITY_CSR_QUOTA, and of the statistics of the top requesters of certificates.")
+
+	caAuditLogSize = env.RegisterIntVar("CITADEL_AUDIT_LOG_SIZE", 1000,
+		"The number of most recent certificate requests kept in the audit log of the CA, served by "+
+			"/debug/ext/ca_auditz. Zero disables the audit log.")
+
+	caAuditLogStdout = env.RegisterBoolVar("CITADEL_AUDIT_LOG_STDOUT", false,
+		"If true, each certificate request is also logged as JSON to the caaudit log scope.")
+
+	caAuditSinkURL = env.RegisterStringVar("CITADEL_AUDIT_SINK_URL", "",
+		"If set, the audit records of the certificate requests are also posted, as JSON arrays, to this URL.")
+
 	// TODO: Likely to be removed and added to mesh config
 	externalCaType = env.RegisterStringVar("EXTERNAL_CA", "",
 		"External CA Integration Type. Permitted Values are ISTIOD_RA_KUBERNETES_API or "+
@@ -150,7 +183,7 @@ func (s *Server) EnableCA() bool {
 // Protected by installer options: the CA will be started only if the JWT token in /var/run/secrets
 // is mounted. If it is missing - for example old versions of K8S that don't support such tokens -
 // we will not start the cert-signing server, since pods will have no way to authenticate.
-func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts *caOptions) {
+func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts *caOptions, stop <-chan struct{}) {
 	iss := trustedIssuer.Get()
 	aud := audience.Get()
 
@@ -194,6 +227,33 @@ func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts
 		}
 	}
 
+	caServer.SetIssuanceLimits(caserver.IssuanceLimits{
+		IdentityRate:   caIdentityCSRRate.Get(),
+		IdentityBurst:  caIdentityCSRBurst.Get(),
+		NamespaceRate:  caNamespaceCSRRate.Get(),
+		NamespaceBurst: caNamespaceCSRBurst.Get(),
+		IdentityQuota:  caIdentityCSRQuota.Get(),
+		QuotaWindow:    caCSRQuotaWindow.Get(),
+	})
+	var auditSinks []caserver.AuditSink
+	if caAuditLogStdout.Get() {
+		auditSinks = append(auditSinks, caserver.NewLogAuditSink())
+	}
+	if url := caAuditSinkURL.Get(); url != "" {
+		auditSinks = append(auditSinks, caserver.NewHTTPAuditSink(url, stop))
+	}
+	if caAuditLogSize.Get() > 0 || len(auditSinks) > 0 {
+		caServer.SetAuditLog(caAuditLogSize.Get(), auditSinks...)
+		if err := s.XDSServer.AddDebugHandler("ca_auditz", "Audit log of the most recent certificate requests of the CA",
+			s.meshWideDebugHandler(caServer.AuditHandler())); err != nil {
+			log.Warnf("failed to register CA debug handler: %v", err)
+		}
+	}
+	if err := s.XDSServer.AddDebugHandler("ca_requesterz", "Certificate issuance limits of the CA and its top requesters",
+		s.meshWideDebugHandler(caServer.IssuanceHandler())); err != nil {
+		log.Warnf("failed to register CA debug handler: %v", err)
+	}
+
 	caServer.Register(grpc)
 
 	log.Info("Istiod CA has started")
@@ -206,6 +266,17 @@ func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts
 //
 // Note that K8S is not required to use JWT tokens - we will fallback to the defaults
 // or require explicit user option for K8S clusters using opaque tokens.
+// meshWideDebugHandler restricts a debug handler to the callers allowed to read all the namespaces. The CA handlers
+// report the requests of the identities of all the namespaces.
+func (s *Server) meshWideDebugHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !s.XDSServer.AllowMeshWide(w, req) {
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
 func detectAuthEnv(jwt string) (*authenticate.JwtPayload, error) {
 	jwtSplit := strings.Split(jwt, ".")
 	if len(jwtSplit) != 3 {
@@ -271,6 +342,11 @@ func (s *Server) createIstioCA(client corev1.CoreV1Interface, opts *caOptions) (
 	var caOpts *ca.IstioCAOptions
 	var err error
 
+	if fips.Enabled() && caRSAKeySize.Get() < fips.MinRSAKeySize {
+		return nil, fmt.Errorf("CITADEL_SELF_SIGNED_CA_RSA_KEY_SIZE %d is smaller than the FIPS minimum of %d",
+			caRSAKeySize.Get(), fips.MinRSAKeySize)
+	}
+
 	// In pods, this is the optional 'cacerts' Secret.
 	signingKeyFile := path.Join(LocalCertDir.Get(), ca.CAPrivateKeyFile)
 
@@ -326,6 +402,14 @@ func (s *Server) createIstioCA(client corev1.CoreV1Interface, opts *caOptions) (
 	if err != nil {
 		return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
 	}
+	if fips.Enabled() {
+		// The CA certificate may be plugged in, with a key of any type.
+		if cert, _, _, _ := istioCA.GetCAKeyCertBundle().GetAll(); cert != nil {
+			if err := fips.CheckPublicKey(cert.PublicKey); err != nil {
+				return nil, fmt.Errorf("the istiod CA certificate is not FIPS compliant: %v", err)
+			}
+		}
+	}
 	// TODO: provide an endpoint returning all the roots. SDS can only pull a single root in current impl.
 	// ca.go saves or uses the secret, but also writes to the configmap "istio-security", under caTLSRootCert
 	// rootCertRotatorChan channel accepts signals to stop root cert rotator for
@@ -45,9 +45,16 @@ const (
 // - if a file exist, load it - will be merged
 // - if istio-REVISION exists, will be used, even if the file is present.
 // - the SHARED_MESH_CONFIG config map will also be loaded and merged.
+//
+// In all cases, with k8s enabled, the mesh config overlays of the namespace annotations are applied on top of
+// the result for the workloads of each namespace.
 func (s *Server) initMeshConfiguration(args *PilotArgs, fileWatcher filewatcher.FileWatcher) {
 	log.Info("initializing mesh configuration ", args.MeshConfigFile)
 	defer func() {
+		if s.environment.Watcher != nil && s.kubeClient != nil {
+			// Namespace overlays are layered on top of whichever source was selected.
+			kubemesh.AddNamespaceMeshConfig(s.kubeClient, s.environment.Watcher)
+		}
 		if s.environment.Watcher != nil {
 			meshdump, _ := gogoprotomarshal.ToJSONWithIndent(s.environment.Mesh(), "    ")
 			log.Infof("mesh configuration: %s", meshdump)
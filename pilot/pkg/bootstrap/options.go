@@ -83,6 +83,14 @@ type DiscoveryServerOptions struct {
 	// a port number is automatically chosen.
 	GRPCAddr string
 
+	// Optional path of a unix domain socket on which the plaintext gRPC discovery service is
+	// additionally served, for node-local control planes. Only the user of istiod and the members
+	// of GRPCUDSGroup can connect to it.
+	GRPCUDSPath string
+
+	// The group ID of the proxies allowed to connect to GRPCUDSPath. If zero, only the user of istiod can.
+	GRPCUDSGroup int
+
 	// The listening address for the monitoring port. If the port in the address is empty or "0" (as in "127.0.0.1:" or "[::1]:0")
 	// a port number is automatically chosen.
 	MonitoringAddr string
@@ -25,6 +25,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -41,11 +42,13 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	"istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/events"
 	"istio.io/istio/pilot/pkg/features"
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
 	"istio.io/istio/pilot/pkg/keycertbundle"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
+	"istio.io/istio/pilot/pkg/networkpolicy"
 	kubesecrets "istio.io/istio/pilot/pkg/secrets/kube"
 	"istio.io/istio/pilot/pkg/server"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
@@ -59,6 +62,7 @@ import (
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -67,6 +71,9 @@ import (
 	"istio.io/istio/pkg/kube/inject"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/uds"
+	"istio.io/istio/pkg/webhooks"
+	validationcontroller "istio.io/istio/pkg/webhooks/validation/controller"
 	"istio.io/istio/security/pkg/k8s/chiron"
 	"istio.io/istio/security/pkg/pki/ca"
 	"istio.io/istio/security/pkg/pki/ra"
@@ -119,6 +126,12 @@ type Server struct {
 	multicluster      *kubecontroller.Multicluster
 	secretsController *kubesecrets.Multicluster
 
+	networkPolicyController *networkpolicy.Controller
+
+	// webhookCertPatcher and validationController manage the webhook configurations, if enabled.
+	webhookCertPatcher   *webhooks.WebhookCertPatcher
+	validationController *validationcontroller.Controller
+
 	configController  model.ConfigStoreCache
 	ConfigStores      []model.ConfigStoreCache
 	serviceEntryStore *serviceentry.ServiceEntryStore
@@ -129,6 +142,8 @@ type Server struct {
 
 	grpcServer        *grpc.Server
 	grpcAddress       string
+	grpcUDSPath       string
+	grpcUDSGroup      int
 	secureGrpcServer  *grpc.Server
 	secureGrpcAddress string
 
@@ -215,6 +230,7 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	// Initialize workload Trust Bundle before XDS Server
 	e.TrustBundle = s.workloadTrustBundle
 	s.XDSServer = xds.NewDiscoveryServer(e, args.Plugins, args.PodName, args.Namespace)
+	s.XDSServer.Revision = args.Revision
 
 	// used for both initKubeRegistry and initClusterRegistries
 	if features.EnableEndpointSliceController {
@@ -265,10 +281,19 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	}
 
 	// Parse and validate Istiod Address.
-	istiodHost, _, err := e.GetDiscoveryAddress()
+	istiodHost, istiodPort, err := e.GetDiscoveryAddress()
 	if err != nil {
 		return nil, err
 	}
+	if features.ConnectionAffinity != "" {
+		// The replicas are the endpoints of the istiod service, named by its FQDN in the registry.
+		istiodService := istiodHost
+		if strings.HasSuffix(string(istiodService), ".svc") {
+			istiodService += host.Name("." + e.DomainSuffix)
+		}
+		port, _ := strconv.Atoi(istiodPort)
+		s.XDSServer.InitConnectionAffinity(features.ConnectionAffinity, istiodService, port)
+	}
 
 	// Create Istiod certs and setup watches.
 	if err := s.initIstiodCerts(args, string(istiodHost)); err != nil {
@@ -291,6 +316,7 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		if err := s.initConfigValidation(args); err != nil {
 			return nil, fmt.Errorf("error initializing config validator: %v", err)
 		}
+		s.initWebhookDebug(args)
 	}
 
 	whc := func() map[string]string {
@@ -300,6 +326,10 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		return map[string]string{}
 	}
 
+	if wh != nil {
+		s.XDSServer.InjectDryRun = wh.DryRun
+	}
+
 	// Used for readiness, monitoring and debug handlers.
 	if err := s.initIstiodAdminServer(args, whc); err != nil {
 		return nil, fmt.Errorf("error initializing debug server: %v", err)
@@ -311,6 +341,8 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 
 	s.initSDSServer(args)
 
+	s.initNetworkPolicyController()
+
 	// Notice that the order of authenticators matters, since at runtime
 	// authenticators are activated sequentially and the first successful attempt
 	// is used as the authentication result.
@@ -344,6 +376,10 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		_, _ = ctrlz.Run(args.CtrlZOptions, nil)
 	}
 
+	if features.EnableKubernetesEvents && s.kubeClient != nil {
+		s.XDSServer.Events = events.NewRecorder(s.kubeClient, args.PodName, args.Namespace, s.internalStop)
+	}
+
 	// This must be last, otherwise we will not know which informers to register
 	if s.kubeClient != nil {
 		s.addStartFunc(func(stop <-chan struct{}) error {
@@ -416,6 +452,8 @@ func (s *Server) Start(stop <-chan struct{}) error {
 	if !s.waitForCacheSync(stop) {
 		return fmt.Errorf("failed to sync cache")
 	}
+	// Warm the XDS cache before accepting connections, so that the proxies reconnecting are served from it.
+	s.XDSServer.Prime(s.clusterID)
 	// Inform Discovery Server so that it can start accepting connections.
 	s.XDSServer.CachesSynced()
 
@@ -447,6 +485,19 @@ func (s *Server) Start(stop <-chan struct{}) error {
 		}()
 	}
 
+	if s.grpcUDSPath != "" {
+		grpcListener, err := uds.NewGroupListener(s.grpcUDSPath, s.grpcUDSGroup)
+		if err != nil {
+			return err
+		}
+		go func() {
+			log.Infof("starting gRPC discovery service at unix://%s", s.grpcUDSPath)
+			if err := s.grpcServer.Serve(grpcListener); err != nil {
+				log.Errorf("error serving GRPC server on unix socket: %v", err)
+			}
+		}()
+	}
+
 	if s.MultiplexGRPC {
 		log.Infof("multiplexing gRPC services with HTTP services")
 		h2s := &http2.Server{
@@ -536,6 +587,32 @@ func (s *Server) initSDSServer(args *PilotArgs) {
 	}
 }
 
+// initNetworkPolicyController sets up evaluation of Kubernetes NetworkPolicies for EDS, if enabled.
+func (s *Server) initNetworkPolicyController() {
+	switch features.NetworkPolicyEDSFilter {
+	case "":
+		return
+	case "prune", "deprioritize":
+	default:
+		log.Warnf("invalid PILOT_NETWORK_POLICY_EDS_FILTER %q; network policies will not be evaluated", features.NetworkPolicyEDSFilter)
+		return
+	}
+	if s.kubeClient == nil {
+		log.Warnf("PILOT_NETWORK_POLICY_EDS_FILTER requires a Kubernetes cluster; network policies will not be evaluated")
+		return
+	}
+	c := networkpolicy.NewController(s.kubeClient, s.clusterID)
+	c.AddEventHandler(func() {
+		// The affected endpoints are not tracked, so clear the entire cache.
+		s.XDSServer.ConfigUpdate(&model.PushRequest{
+			Full:   true,
+			Reason: []model.TriggerReason{model.GlobalUpdate},
+		})
+	})
+	s.environment.NetworkPolicies = c
+	s.networkPolicyController = c
+}
+
 // initKubeClient creates the k8s client if running in an k8s environment.
 // This is determined by the presence of a kube registry, which
 // uses in-context k8s, or a config source of type k8s.
@@ -655,6 +732,8 @@ func (s *Server) initDiscoveryService(args *PilotArgs) {
 		log.Info("multiplexing gRPC on http port ", args.ServerOptions.HTTPAddr)
 		s.MultiplexGRPC = true
 	}
+	s.grpcUDSPath = args.ServerOptions.GRPCUDSPath
+	s.grpcUDSGroup = args.ServerOptions.GRPCUDSGroup
 }
 
 // Wait for the stop, and do cleanups
@@ -664,6 +743,12 @@ func (s *Server) waitForShutdown(stop <-chan struct{}) {
 		close(s.internalStop)
 		s.fileWatcher.Close()
 
+		// Ask the proxies to spread their reconnections to the other replicas, rather than waiting for the
+		// streams to be forcibly stopped.
+		if features.DrainReconnectWindow > 0 {
+			s.XDSServer.Drain(features.DrainReconnectWindow)
+		}
+
 		// Stop gRPC services.  If gRPC services fail to stop in the shutdown duration,
 		// force stop them. This does not happen normally.
 		stopped := make(chan struct{})
@@ -843,6 +928,9 @@ func (s *Server) cachesSynced() bool {
 	if s.multicluster != nil && !s.multicluster.HasSynced() {
 		return false
 	}
+	if s.networkPolicyController != nil && !s.networkPolicyController.HasSynced() {
+		return false
+	}
 	if !s.ServiceController().HasSynced() {
 		return false
 	}
@@ -1074,10 +1162,10 @@ func (s *Server) startCA(caOpts *caOptions) {
 		// Start the RA server if configured, else start the CA server
 		if s.RA != nil {
 			log.Infof("Starting RA")
-			s.RunCA(grpcServer, s.RA, caOpts)
+			s.RunCA(grpcServer, s.RA, caOpts, stop)
 		} else if s.CA != nil {
 			log.Infof("Starting IstioD CA")
-			s.RunCA(grpcServer, s.CA, caOpts)
+			s.RunCA(grpcServer, s.CA, caOpts, stop)
 		}
 		return nil
 	})
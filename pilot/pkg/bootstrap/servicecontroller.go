@@ -17,6 +17,7 @@ package bootstrap
 import (
 	"fmt"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
@@ -37,9 +38,20 @@ func (s *Server) ServiceController() *aggregate.Controller {
 func (s *Server) initServiceControllers(args *PilotArgs) error {
 	serviceControllers := s.ServiceController()
 
+	options := []serviceentry.ServiceDiscoveryOption{serviceentry.WithClusterID(s.clusterID)}
+	if features.AutoAllocateVIPCIDR != "" {
+		var store serviceentry.VIPStore
+		if s.kubeClient != nil {
+			store = serviceentry.NewConfigMapVIPStore(s.kubeClient.Kube(), args.Namespace, serviceentry.VIPAllocationsConfigMap)
+		}
+		allocator, err := serviceentry.NewVIPAllocator(features.AutoAllocateVIPCIDR, store)
+		if err != nil {
+			return fmt.Errorf("invalid PILOT_AUTO_ALLOCATE_VIP_CIDR: %v", err)
+		}
+		options = append(options, serviceentry.WithVIPAllocator(allocator))
+	}
 	s.serviceEntryStore = serviceentry.NewServiceDiscovery(
-		s.configController, s.environment.IstioConfigStore, s.XDSServer,
-		serviceentry.WithClusterID(s.clusterID),
+		s.configController, s.environment.IstioConfigStore, s.XDSServer, options...,
 	)
 	serviceControllers.AddRegistry(s.serviceEntryStore)
 
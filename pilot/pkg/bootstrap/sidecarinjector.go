@@ -90,18 +90,18 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 	// This requires RBAC permissions - a low-priv Istiod should not attempt to patch but rely on
 	// operator or CI/CD
 	if features.InjectionWebhookConfigName != "" {
-		s.addStartFunc(func(stop <-chan struct{}) error {
-			// No leader election - different istiod revisions will patch their own cert.
-			// update webhook configuration by watching the cabundle
-			patcher, err := webhooks.NewWebhookCertPatcher(s.kubeClient, args.Revision, webhookName, s.istiodCertBundleWatcher)
-			if err != nil {
-				log.Errorf("failed to create webhook cert patcher: %v", err)
+		// No leader election - different istiod revisions will patch their own cert.
+		// update webhook configuration by watching the cabundle
+		patcher, err := webhooks.NewWebhookCertPatcher(s.kubeClient, args.Revision, webhookName, s.istiodCertBundleWatcher)
+		if err != nil {
+			log.Errorf("failed to create webhook cert patcher: %v", err)
+		} else {
+			s.webhookCertPatcher = patcher
+			s.addStartFunc(func(stop <-chan struct{}) error {
+				patcher.Run(stop)
 				return nil
-			}
-
-			patcher.Run(stop)
-			return nil
-		})
+			})
+		}
 	}
 	s.addStartFunc(func(stop <-chan struct{}) error {
 		wh.Run(stop)
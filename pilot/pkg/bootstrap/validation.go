@@ -16,7 +16,11 @@ package bootstrap
 
 import (
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/webhooks/validation/controller"
 	"istio.io/istio/pkg/webhooks/validation/server"
 	"istio.io/pkg/log"
@@ -34,18 +38,54 @@ func (s *Server) initConfigValidation(args *PilotArgs) error {
 		DomainSuffix: args.RegistryOptions.KubeOptions.DomainSuffix,
 		Mux:          s.httpsMux,
 	}
+	if features.EnableReferenceValidation {
+		params.Lookup = environmentLookup{env: s.environment, push: s.XDSServer.GlobalPushContext}
+	}
 	_, err := server.New(params)
 	if err != nil {
 		return err
 	}
 
 	if features.ValidationWebhookConfigName != "" && s.kubeClient != nil {
+		s.validationController = controller.NewValidatingWebhookController(
+			s.kubeClient, args.Revision, args.Namespace, s.istiodCertBundleWatcher)
 		s.addStartFunc(func(stop <-chan struct{}) error {
 			log.Infof("Starting validation controller")
-			go controller.NewValidatingWebhookController(
-				s.kubeClient, args.Revision, args.Namespace, s.istiodCertBundleWatcher).Run(stop)
+			go s.validationController.Run(stop)
 			return nil
 		})
 	}
 	return nil
 }
+
+// environmentLookup resolves the references of configs being validated from the config store and the last push
+// context of istiod.
+type environmentLookup struct {
+	env  *model.Environment
+	push func() *model.PushContext
+}
+
+func (l environmentLookup) Ready() bool {
+	push := l.push()
+	return l.env.IstioConfigStore != nil && push != nil && push.InitDone()
+}
+
+func (l environmentLookup) GatewayExists(namespace, name string) bool {
+	return l.env.Get(gvk.Gateway, name, namespace) != nil
+}
+
+func (l environmentLookup) HostExists(hostname host.Name) bool {
+	return len(l.push().ServiceIndex.HostnameAndNamespace[hostname]) > 0
+}
+
+func (l environmentLookup) WorkloadsMatch(hostname host.Name, workloadLabels labels.Instance) bool {
+	push := l.push()
+	for _, svc := range push.ServiceIndex.HostnameAndNamespace[hostname] {
+		for _, port := range svc.Ports {
+			if len(push.ServiceInstancesByPort(svc, port.Port, labels.Collection{workloadLabels})) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/webhooks"
+	"istio.io/pkg/log"
+)
+
+// initWebhookDebug registers the debug handlers showing the state of the webhook configurations
+// managed by istiod, and, if unsafe admin endpoints are enabled, forcing them to be patched again.
+func (s *Server) initWebhookDebug(args *PilotArgs) {
+	if s.kubeClient == nil {
+		return
+	}
+	if err := s.XDSServer.AddDebugHandler("webhookz", "Injection and validation webhooks managed by this istiod revision",
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			statuses, err := webhooks.Statuses(s.kubeClient, args.Revision, s.istiodCertBundleWatcher)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(err.Error()))
+				return
+			}
+			writeWebhookJSON(w, statuses)
+		})); err != nil {
+		log.Warnf("failed to register webhook debug handler: %v", err)
+	}
+
+	if !features.EnableUnsafeAdminEndpoints {
+		return
+	}
+	if err := s.XDSServer.AddDebugHandler("webhookz_repatch", "Patches the CA bundle and failure policy of the managed webhooks again",
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if s.webhookCertPatcher != nil {
+				if err := s.webhookCertPatcher.PatchAll(); err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					_, _ = w.Write([]byte(err.Error()))
+					return
+				}
+			}
+			if s.validationController != nil {
+				s.validationController.Reconcile()
+			}
+			w.WriteHeader(http.StatusOK)
+		})); err != nil {
+		log.Warnf("failed to register webhook debug handler: %v", err)
+	}
+}
+
+func writeWebhookJSON(w http.ResponseWriter, obj interface{}) {
+	b, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,137 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	kubelib "istio.io/istio/pkg/kube"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("events", "kubernetes events", 0)
+
+// Reasons of the events emitted by istiod.
+const (
+	// ReasonConfigRejected is used when a configuration is ignored, fully or partially, while generating the
+	// configuration of proxies.
+	ReasonConfigRejected = "ConfigRejected"
+	// ReasonProxyRejected is used when a proxy rejects (NACKs) the configuration sent to it.
+	ReasonProxyRejected = "ProxyRejected"
+	// ReasonPushFailed is used when istiod fails to generate or send the configuration of proxies.
+	ReasonPushFailed = "PushFailed"
+)
+
+// Recorder emits Kubernetes Events for notable control plane conditions, so that users see them with
+// kubectl describe on the affected object rather than in the istiod logs. Conditions which do not relate to a
+// single object are reported on the istiod pod.
+// A nil Recorder drops all events.
+type Recorder struct {
+	recorder record.EventRecorder
+	pods     corelisters.PodLister
+
+	podName      string
+	podNamespace string
+}
+
+// NewRecorder creates a Recorder. podName and podNamespace identify the istiod pod.
+func NewRecorder(client kubelib.Client, podName, podNamespace string, stop <-chan struct{}) *Recorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	go func() {
+		<-stop
+		broadcaster.Shutdown()
+	}()
+	return &Recorder{
+		recorder:     broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "istiod", Host: podName}),
+		pods:         client.KubeInformer().Core().V1().Pods().Lister(),
+		podName:      podName,
+		podNamespace: podNamespace,
+	}
+}
+
+// ConfigRejected reports a configuration ignored while generating the configuration of proxies.
+func (r *Recorder) ConfigRejected(cfg config.Config, message string) {
+	if r == nil {
+		return
+	}
+	gvk := cfg.GroupVersionKind
+	r.recorder.Event(&corev1.ObjectReference{
+		Kind:            gvk.Kind,
+		APIVersion:      gvk.GroupVersion(),
+		Namespace:       cfg.Namespace,
+		Name:            cfg.Name,
+		UID:             types.UID(cfg.UID),
+		ResourceVersion: cfg.ResourceVersion,
+	}, corev1.EventTypeWarning, ReasonConfigRejected, message)
+}
+
+// ProxyRejected reports a proxy rejecting the configuration of an xDS type, such as LDS.
+func (r *Recorder) ProxyRejected(proxy *model.Proxy, xdsType string, message string) {
+	if r == nil {
+		return
+	}
+	r.recorder.Eventf(r.proxyReference(proxy), corev1.EventTypeWarning, ReasonProxyRejected,
+		"proxy %s rejected %s: %s", proxy.ID, xdsType, message)
+}
+
+// PushFailed reports a failure to push configuration. proxy is nil if the failure affects all proxies.
+func (r *Recorder) PushFailed(proxy *model.Proxy, message string) {
+	if r == nil {
+		return
+	}
+	if proxy == nil {
+		r.recorder.Event(r.podReference(r.podNamespace, r.podName), corev1.EventTypeWarning, ReasonPushFailed, message)
+		return
+	}
+	r.recorder.Eventf(r.proxyReference(proxy), corev1.EventTypeWarning, ReasonPushFailed,
+		"push to proxy %s failed: %s", proxy.ID, message)
+}
+
+// proxyReference returns the pod of a proxy, or the istiod pod if the proxy does not run in a known pod.
+func (r *Recorder) proxyReference(proxy *model.Proxy) *corev1.ObjectReference {
+	if proxy.Metadata != nil && proxy.Metadata.InstanceName != "" {
+		if ref := r.podReference(proxy.ConfigNamespace, proxy.Metadata.InstanceName); ref.UID != "" {
+			return ref
+		}
+	}
+	return r.podReference(r.podNamespace, r.podName)
+}
+
+// podReference returns a reference to a pod. kubectl describe only shows the events of references holding
+// the UID of the pod, so it is looked up from the informer.
+func (r *Recorder) podReference(namespace, name string) *corev1.ObjectReference {
+	ref := &corev1.ObjectReference{
+		Kind:       "Pod",
+		APIVersion: "v1",
+		Namespace:  namespace,
+		Name:       name,
+	}
+	pod, err := r.pods.Pods(namespace).Get(name)
+	if err != nil {
+		log.Debugf("failed to get pod %s/%s: %v", namespace, name, err)
+		return ref
+	}
+	ref.UID = pod.UID
+	ref.ResourceVersion = pod.ResourceVersion
+	return ref
+}
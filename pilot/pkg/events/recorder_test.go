@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestProxyReference(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pod := range []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system", UID: "istiod-uid"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "app-uid"}},
+	} {
+		if err := indexer.Add(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fake := record.NewFakeRecorder(10)
+	r := &Recorder{
+		recorder:     fake,
+		pods:         corelisters.NewPodLister(indexer),
+		podName:      "istiod",
+		podNamespace: "istio-system",
+	}
+
+	cases := []struct {
+		name  string
+		proxy *model.Proxy
+		uid   string
+	}{
+		{"pod", &model.Proxy{ID: "app.default", ConfigNamespace: "default", Metadata: &model.NodeMetadata{InstanceName: "app"}}, "app-uid"},
+		{"unknown pod", &model.Proxy{ID: "vm.default", ConfigNamespace: "default", Metadata: &model.NodeMetadata{InstanceName: "vm"}}, "istiod-uid"},
+		{"no metadata", &model.Proxy{ID: "proxy"}, "istiod-uid"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.proxyReference(tt.proxy).UID; string(got) != tt.uid {
+				t.Fatalf("got reference to %v, want %v", got, tt.uid)
+			}
+		})
+	}
+
+	r.ProxyRejected(cases[0].proxy, "LDS", "bad listener")
+	if got, want := <-fake.Events, "Warning ProxyRejected proxy app.default rejected LDS: bad listener"; got != want {
+		t.Fatalf("got event %q, want %q", got, want)
+	}
+
+	// A nil recorder drops events.
+	var disabled *Recorder
+	disabled.PushFailed(nil, "failed")
+}
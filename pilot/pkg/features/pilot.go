@@ -15,6 +15,7 @@
 package features
 
 import (
+	"strings"
 	"time"
 
 	"github.com/golang/protobuf/ptypes/duration"
@@ -64,6 +65,42 @@ var (
 		"Limits the number of concurrent pushes allowed. On larger machines this can be increased for faster pushes",
 	).Get()
 
+	MaxPushQueueForNewConnections = env.RegisterIntVar(
+		"PILOT_MAX_PUSH_QUEUE_FOR_NEW_CONNECTIONS",
+		0,
+		"If set, new XDS connections are rejected with an Unavailable status while more than this number of proxies "+
+			"wait in the push queue, so that the connected proxies get their pushes first. Zero disables shedding.",
+	).Get()
+
+	EnableStartupPriming = env.RegisterBoolVar(
+		"PILOT_ENABLE_STARTUP_PRIMING",
+		false,
+		"If enabled, istiod computes the push context and generates the clusters and endpoints of typical gateways "+
+			"and sidecars before accepting XDS connections and reporting ready, so that the proxies reconnecting "+
+			"after a restart are served from warm caches.",
+	).Get()
+
+	StartupPrimingNamespaces = env.RegisterIntVar(
+		"PILOT_STARTUP_PRIMING_NAMESPACES",
+		5,
+		"The number of namespaces, with the most services, a default sidecar is primed for when "+
+			"PILOT_ENABLE_STARTUP_PRIMING is enabled.",
+	).Get()
+
+	ShedConnectionMaxDelay = env.RegisterDurationVar(
+		"PILOT_SHED_CONNECTION_MAX_DELAY",
+		0,
+		"How long a new XDS connection waits for the push queue to drain below "+
+			"PILOT_MAX_PUSH_QUEUE_FOR_NEW_CONNECTIONS before it is rejected.",
+	).Get()
+
+	ShedConnectionBackoff = env.RegisterDurationVar(
+		"PILOT_SHED_CONNECTION_BACKOFF",
+		5*time.Second,
+		"The retry delay hinted to rejected XDS connections in the grpc-retry-pushback-ms trailer. A random jitter "+
+			"of up to the same duration is added.",
+	).Get()
+
 	// MaxRecvMsgSize The max receive buffer size of gRPC received channel of Pilot in bytes.
 	MaxRecvMsgSize = env.RegisterIntVar(
 		"ISTIO_GPRC_MAXRECVMSGSIZE",
@@ -146,12 +183,29 @@ var (
 		false,
 		"Skip validating the peer is from the same trust domain when mTLS is enabled in authentication policy").Get()
 
+	// EnablePerEndpointAutoMtls makes auto mTLS decide for each endpoint of an EDS cluster whether it is sent mTLS,
+	// instead of turning mTLS off for all of them when the namespace of the service disables it.
+	EnablePerEndpointAutoMtls = env.RegisterBoolVar(
+		"PILOT_ENABLE_PER_ENDPOINT_AUTO_MTLS",
+		true,
+		"If enabled, auto mTLS selects the transport socket of each endpoint of a cluster from its tlsMode and the "+
+			"PeerAuthentication and DestinationRule applying to it, so that a cluster can mix mTLS and plaintext endpoints.").Get()
+
 	EnableProtocolSniffingForOutbound = env.RegisterBoolVar(
 		"PILOT_ENABLE_PROTOCOL_SNIFFING_FOR_OUTBOUND",
 		true,
 		"If enabled, protocol sniffing will be used for outbound listeners whose port protocol is not specified or unsupported",
 	).Get()
 
+	OutboundListenerConflictPolicy = env.RegisterStringVar(
+		"PILOT_OUTBOUND_LISTENER_CONFLICT_POLICY",
+		"first",
+		"Controls which service wins when services claim the same outbound listener address and port "+
+			"with conflicting protocols or filter chains. Valid values: first (the first service processed, "+
+			"in creation order), oldest (the oldest service, ties broken by hostname and namespace), "+
+			"http (the HTTP service on protocol conflicts, otherwise the oldest).",
+	).Get()
+
 	EnableProtocolSniffingForInbound = env.RegisterBoolVar(
 		"PILOT_ENABLE_PROTOCOL_SNIFFING_FOR_INBOUND",
 		true,
@@ -246,18 +300,48 @@ var (
 			"Istio Resources",
 	).Get()
 
+	AnalysisInterval = env.RegisterDurationVar(
+		"PILOT_ANALYSIS_INTERVAL",
+		10*time.Second,
+		"The minimum interval between two analyses of the configs, when PILOT_ENABLE_ANALYSIS is enabled. Changes "+
+			"within this interval are analyzed together once it elapses. Zero analyzes every change.",
+	).Get()
+
 	EnableStatus = env.RegisterBoolVar(
 		"PILOT_ENABLE_STATUS",
 		false,
 		"If enabled, pilot will update the CRD Status field of all istio resources with reconciliation status.",
 	).Get()
 
+	EnableKubernetesEvents = env.RegisterBoolVar(
+		"PILOT_ENABLE_KUBERNETES_EVENTS",
+		false,
+		"If enabled, pilot will emit Kubernetes Events for configuration rejected while generating proxy "+
+			"configuration, for proxy NACKs, and for push failures.",
+	).Get()
+
+	EnableConfigProvenanceMetadata = env.RegisterBoolVar(
+		"PILOT_ENABLE_CONFIG_PROVENANCE_METADATA",
+		false,
+		"If enabled, pilot will list the Istio configs which produced each listener, cluster and route in its "+
+			"istio metadata, such as the VirtualServices, DestinationRules, Sidecars and EnvoyFilters. "+
+			"This makes proxy config dumps self-explanatory, at the cost of larger configurations.",
+	).Get()
+
 	StatusUpdateInterval = env.RegisterDurationVar(
 		"PILOT_STATUS_UPDATE_INTERVAL",
 		500*time.Millisecond,
 		"Interval to update the XDS distribution status.",
 	).Get()
 
+	StatusHeartbeatInterval = env.RegisterDurationVar(
+		"PILOT_STATUS_HEARTBEAT_INTERVAL",
+		30*time.Second,
+		"Interval after which the XDS distribution status of an istiod replica is written even if it did not "+
+			"change. Unchanged reports are otherwise skipped. Must be shorter than one minute, after which the "+
+			"status leader drops reports as stale. If zero, the report is written every PILOT_STATUS_UPDATE_INTERVAL.",
+	).Get()
+
 	StatusQPS = env.RegisterFloatVar(
 		"PILOT_STATUS_QPS",
 		100,
@@ -307,6 +391,12 @@ var (
 		true,
 		"If set to false, virtualService delegate will not be supported.").Get()
 
+	VirtualServiceDelegateMaxDepth = env.RegisterIntVar(
+		"PILOT_VIRTUAL_SERVICE_DELEGATE_MAX_DEPTH",
+		1,
+		"The maximum number of levels of virtualService delegation. Delegate virtualServices can only "+
+			"delegate further if this is greater than 1.").Get()
+
 	ClusterName = env.RegisterStringVar("CLUSTER_ID", "Kubernetes",
 		"Defines the cluster and service registry that this Istiod instance is belongs to").Get()
 
@@ -322,6 +412,17 @@ var (
 	EnableUnsafeAdminEndpoints = env.RegisterBoolVar("UNSAFE_ENABLE_ADMIN_ENDPOINTS", false,
 		"If this is set to true, dangerous admin endpoins will be exposed on the debug interface. Not recommended for production.").Get()
 
+	DebugAdminIdentities = commaSeparated(env.RegisterStringVar("PILOT_DEBUG_ADMIN_IDENTITIES", "",
+		"Comma separated list of the <namespace>/<service account> identities granted the mesh-wide admin role on "+
+			"the debug interface, reading the information of all namespaces. The service account may be * for all "+
+			"the service accounts of the namespace. The identities of the istiod namespace always have the role, "+
+			"while other authenticated identities only read the information of their own namespace.").Get())
+
+	DebugRedactedHeaders = commaSeparated(env.RegisterStringVar("PILOT_DEBUG_REDACTED_HEADERS",
+		"authorization,proxy-authorization,cookie,set-cookie,x-api-key",
+		"Comma separated list of the headers whose values are redacted from the config dumps of the debug interface, "+
+			"in addition to the private keys, passwords and tokens which are always redacted.").Get())
+
 	XDSAuth = env.RegisterBoolVar("XDS_AUTH", true,
 		"If true, will authenticate XDS clients.").Get()
 
@@ -331,10 +432,58 @@ var (
 		"If enabled, pilot will authorize XDS clients, to ensure they are acting only as namespaces they have permissions for.",
 	).Get()
 
+	NAT64Prefix = env.RegisterStringVar(
+		"PILOT_NAT64_PREFIX",
+		"",
+		"If set, the NAT64 prefix (such as the well-known prefix 64:ff9b::/96) used to reach IPv4 destinations "+
+			"from IPv6-only proxies. IPv4 ServiceEntry endpoints are sent to IPv6-only proxies as addresses "+
+			"synthesized from this prefix.",
+	).Get()
+
+	NetworkPolicyEDSFilter = env.RegisterStringVar(
+		"PILOT_NETWORK_POLICY_EDS_FILTER",
+		"",
+		"If set, Kubernetes NetworkPolicies are evaluated to find endpoints the requesting proxy's pod is not "+
+			"permitted to reach. Valid values: prune (remove such endpoints from EDS), deprioritize (move them to "+
+			"a lower priority, so they are only used if no other endpoints are available). EDS responses are "+
+			"cached for each set of policies selecting the proxies, which reduces the effectiveness of the XDS "+
+			"cache when many workloads are selected by different policies.",
+	).Get()
+
+	XDSIdentityCheckMode = env.RegisterStringVar(
+		"PILOT_XDS_IDENTITY_CHECK_MODE",
+		"",
+		"Controls how the node ID, namespace and service account claimed by XDS clients are verified against the "+
+			"authenticated identity. Valid values: enforce (reject mismatches), permissive (record mismatches but "+
+			"allow the connection), disabled. If unset, enforce is used if PILOT_ENABLE_XDS_IDENTITY_CHECK is true, "+
+			"and disabled otherwise.",
+	).Get()
+
+	DuplicateConnectionPolicy = env.RegisterStringVar(
+		"PILOT_DUPLICATE_CONNECTION_POLICY",
+		"allow",
+		"Controls how overlapping XDS connections with the same node ID are handled. Valid values: "+
+			"allow (serve all connections), reject (refuse the new connection), "+
+			"close-old (close older connections after PILOT_DUPLICATE_CONNECTION_GRACE_PERIOD), "+
+			"newest (keep all connections open, but only push to the newest).",
+	).Get()
+
+	DuplicateConnectionGracePeriod = env.RegisterDurationVar(
+		"PILOT_DUPLICATE_CONNECTION_GRACE_PERIOD",
+		5*time.Second,
+		"With PILOT_DUPLICATE_CONNECTION_POLICY=close-old, the time older connections are kept open after "+
+			"a newer connection with the same node ID is established.",
+	).Get()
+
 	EnableServiceEntrySelectPods = env.RegisterBoolVar("PILOT_ENABLE_SERVICEENTRY_SELECT_PODS", true,
 		"If enabled, service entries with selectors will select pods from the cluster. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
 
+	AutoAllocateVIPCIDR = env.RegisterStringVar("PILOT_AUTO_ALLOCATE_VIP_CIDR", "",
+		"If set, the service entries without addresses are allocated stable virtual IPs out of this IPv4 CIDR, "+
+			"based on the hash of their hosts, instead of sequential addresses out of 240.240.0.0/16. The allocations "+
+			"are persisted in the istio-vip-allocations ConfigMap of the istiod namespace.").Get()
+
 	EnableK8SServiceSelectWorkloadEntries = env.RegisterBoolVar("PILOT_ENABLE_K8S_SELECT_WORKLOAD_ENTRIES", true,
 		"If enabled, Kubernetes services with selectors will select workload entries with matching labels. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
@@ -345,6 +494,10 @@ var (
 	ValidationWebhookConfigName = env.RegisterStringVar("VALIDATION_WEBHOOK_CONFIG_NAME", "istio-istio-system",
 		"Name of the validatingwebhookconfiguration to patch. Empty will skip using cluster admin to patch.").Get()
 
+	EnableReferenceValidation = env.RegisterBoolVar("PILOT_ENABLE_REFERENCE_VALIDATION", true,
+		"If enabled, the validation webhook warns about VirtualServices referencing missing gateways or hosts, and "+
+			"DestinationRules whose host or subsets match no service or workload, as known to istiod.").Get()
+
 	SpiffeBundleEndpoints = env.RegisterStringVar("SPIFFE_BUNDLE_ENDPOINTS", "",
 		"The SPIFFE bundle trust domain to endpoint mappings. Istiod retrieves the root certificate from each SPIFFE "+
 			"bundle endpoint and uses it to verify client certifiates from that trust domain. The endpoint must be "+
@@ -362,6 +515,15 @@ var (
 	EnableCDSCaching = env.RegisterBoolVar("PILOT_ENABLE_CDS_CACHE", true,
 		"If true, Pilot will cache CDS responses. Note: this depends on PILOT_ENABLE_XDS_CACHE.").Get()
 
+	// EnableRDSCaching determines if RDS caching is enabled. Like the CDS cache, it can be disabled on its own.
+	EnableRDSCaching = env.RegisterBoolVar("PILOT_ENABLE_RDS_CACHE", true,
+		"If true, Pilot will cache the RDS responses of sidecars. Note: this depends on PILOT_ENABLE_XDS_CACHE.").Get()
+
+	// EnableAuthzFilterSharing determines if the RBAC filters are built once per set of authorization policies and
+	// shared across the workloads with the same policies, rather than built for each workload.
+	EnableAuthzFilterSharing = env.RegisterBoolVar("PILOT_ENABLE_AUTHZ_FILTER_SHARING", true,
+		"If true, Pilot builds the RBAC filters once for all the workloads with the same authorization policies.").Get()
+
 	EnableXDSCacheMetrics = env.RegisterBoolVar("PILOT_XDS_CACHE_STATS", false,
 		"If true, Pilot will collect metrics for XDS cache efficiency.").Get()
 
@@ -383,6 +545,43 @@ var (
 		"The timeout to send the XDS configuration to proxies. After this timeout is reached, Pilot will discard that push.",
 	).Get()
 
+	DrainReconnectWindow = env.RegisterDurationVar(
+		"PILOT_DRAIN_RECONNECT_WINDOW",
+		0,
+		"If set, istiod closes the XDS streams when shutting down, asking each proxy to wait for a random delay "+
+			"within this window before reconnecting, so that the reconnections are spread over the window. "+
+			"Requires proxies honoring the grpc-retry-pushback-ms trailer, such as the istio agent.",
+	).Get()
+
+	ConnectionAffinity = env.RegisterStringVar(
+		"PILOT_CONNECTION_AFFINITY",
+		"",
+		"If set to node or namespace, the proxies of a node (as set by the NODE_NAME metadata) or of a namespace "+
+			"are assigned to an istiod replica by consistent hashing. A proxy connecting to another replica is "+
+			"rejected with the address of its replica in the istio-affinity-address trailer, which the istio "+
+			"agent connects to directly. This improves the hit rate of the XDS cache, whose entries are shared "+
+			"by similar proxies.",
+	).Get()
+
+	TenantIsolation = env.RegisterStringVar(
+		"PILOT_TENANT_ISOLATION",
+		"",
+		"If set to revision or namespace, the proxies of each revision (as set by their istio.io/rev label) or "+
+			"of each namespace are isolated in istiod: each tenant has its own XDS cache and its own push queue, "+
+			"served in turn within PILOT_PUSH_THROTTLE concurrent pushes overall, so that a config storm of one "+
+			"tenant cannot evict the cache entries or delay the pushes of another. The push context is not "+
+			"isolated: it remains shared by all the tenants.",
+	).Get()
+
+	XDSStreamMaxDuration = env.RegisterDurationVar(
+		"PILOT_XDS_STREAM_MAX_DURATION",
+		0,
+		"The maximum duration of an XDS stream, with up to 10% jitter. Once reached, the stream is closed "+
+			"with an Unavailable status and the proxy opens a new one, keeping its configuration. Combined "+
+			"with --keepaliveMaxServerConnectionAge, this periodically rebalances the proxies across the "+
+			"istiod replicas. Zero does not bound the streams.",
+	).Get()
+
 	RemoteClusterTimeout = env.RegisterDurationVar(
 		"PILOT_REMOTE_CLUSTER_TIMEOUT",
 		30*time.Second,
@@ -404,6 +603,102 @@ var (
 	WorkloadEntryHealthChecks = env.RegisterBoolVar("PILOT_ENABLE_WORKLOAD_ENTRY_HEALTHCHECKS", true,
 		"Enables automatic health checks of WorkloadEntries based on the config provided in the associated WorkloadGroup").Get()
 
+	EnableXDSResumption = env.RegisterBoolVar("PILOT_ENABLE_XDS_RESUMPTION", false,
+		"If enabled, pilot issues resumption tokens to agents. An agent reconnecting with a token does not "+
+			"receive the initial config of a type again if it is identical to the config it last ACKed. Otherwise "+
+			"the complete config of the type is sent, as the token does not record the individual resources.").Get()
+
+	SuppressUnchangedPushes = env.RegisterBoolVar("PILOT_SUPPRESS_UNCHANGED_PUSHES", false,
+		"If enabled, pilot hashes each complete response it generates for a connection, and does not send it "+
+			"on a push if it is identical to the last response of the type ACKed on the connection. This saves "+
+			"the bandwidth and the proxy CPU of the full pushes not changing the config of the proxy, at the "+
+			"cost of hashing the responses.").Get()
+
+	PushHistorySize = env.RegisterIntVar("PILOT_PUSH_HISTORY_SIZE", 10,
+		"The number of recent pushes of each connection whose reasons are kept, and shown on /debug/connections. "+
+			"Zero disables the history.").Get()
+
+	RemoveDrainingEndpoints = env.RegisterBoolVar("PILOT_REMOVE_DRAINING_ENDPOINTS", false,
+		"If enabled, pilot removes the endpoints of a proxy from EDS as soon as its agent reports the workload "+
+			"is terminating, instead of waiting for the platform to remove them.").Get()
+
+	DrainingEndpointTTL = env.RegisterDurationVar("PILOT_DRAINING_ENDPOINT_TTL", time.Minute,
+		"How long the address of a draining proxy is excluded from EDS.").Get()
+
+	MaxClustersPerProxy = env.RegisterIntVar("PILOT_MAX_CLUSTERS_PER_PROXY", 0,
+		"If set, outbound clusters beyond this number are dropped from the config of a proxy, and the truncation "+
+			"is reported in the push status. Zero means unlimited.").Get()
+
+	MaxListenersPerProxy = env.RegisterIntVar("PILOT_MAX_LISTENERS_PER_PROXY", 0,
+		"If set, listeners beyond this number are dropped from the config of a proxy, and the truncation "+
+			"is reported in the push status. Zero means unlimited.").Get()
+
+	MaxVirtualHostsPerRoute = env.RegisterIntVar("PILOT_MAX_VIRTUAL_HOSTS_PER_ROUTE_CONFIG", 0,
+		"If set, virtual hosts beyond this number are dropped from each route configuration, and the truncation "+
+			"is reported in the push status. Zero means unlimited.").Get()
+
+	RouteShardingThresholdBytes = env.RegisterIntVar("PILOT_ROUTE_SHARDING_THRESHOLD_BYTES", 0,
+		"If set, the outbound route configuration of a sidecar port larger than this number of bytes is split into "+
+			"route configurations of at most this size, selected by the Host header with scoped routes. Requests to "+
+			"hosts without a virtual host, including passthrough traffic, are rejected on sharded ports. Zero disables sharding.").Get()
+
+	ScopedRoutesOnDemandServiceThreshold = env.RegisterIntVar("PILOT_SCOPED_ROUTES_ON_DEMAND_SERVICE_THRESHOLD", 0,
+		"If set, sidecars which can reach more than this number of services select their outbound routes by the Host "+
+			"header with scoped RDS, and fetch the route of a host the first time it receives traffic instead of "+
+			"receiving every route upfront. The ports whose routes cannot be selected by the Host header alone, such as "+
+			"ports passing through traffic to unknown hosts or sharing a host name with another port, keep their route "+
+			"configuration. Zero disables it.").Get()
+
+	VirtualHostDiscoveryServiceThreshold = env.RegisterIntVar("PILOT_VHDS_SERVICE_THRESHOLD", 0,
+		"If set, sidecars which can reach more than this number of services receive outbound route configurations "+
+			"holding only their wildcard virtual hosts, and request the virtual host of any other host with VHDS the "+
+			"first time they receive traffic for it. This requires the sidecars to use delta xDS. Requests to hosts "+
+			"without a virtual host, including passthrough traffic, are rejected by these sidecars. Zero disables it.").Get()
+
+	EnableEDSDiffSuppression = env.RegisterBoolVar("PILOT_ENABLE_EDS_DIFF_SUPPRESSION", false,
+		"If enabled, Pilot tracks the load assignments each proxy ACKed over SotW EDS, skips sending the load "+
+			"assignments of incremental pushes which did not change, and reports the endpoint level changes of the "+
+			"others. This costs memory for every EDS cluster of every proxy.").Get()
+
+	EnableConfigDiffTracking = env.RegisterBoolVar("PILOT_ENABLE_CONFIG_DIFF_TRACKING", false,
+		"If enabled, Pilot tracks a hash of the clusters, listeners and routes each proxy ACKed over SotW, so that "+
+			"/debug/config_diff can report the resources the proxy is missing or has stale. This costs memory for "+
+			"every resource of every proxy.").Get()
+
+	EnablePushCostMetrics = env.RegisterBoolVar("PILOT_ENABLE_PUSH_COST_METRICS", false,
+		"If enabled, Pilot exports the time spent generating and the bytes of the configs pushed, by namespace of "+
+			"the proxies and kind of the configs triggering the pushes. The metrics have a label per namespace.").Get()
+
+	LedsEndpointThreshold = env.RegisterIntVar("PILOT_LEDS_ENDPOINT_THRESHOLD", 0,
+		"If set, the load assignment of a cluster with more than this number of endpoints references the endpoints "+
+			"of each locality through a LEDS collection, so that a change to the endpoints only sends the endpoints "+
+			"which changed. This requires proxies to use delta xDS and to support LEDS. Zero disables it.").Get()
+
+	MaxConfigBytesPerType = env.RegisterIntVar("PILOT_MAX_CONFIG_BYTES_PER_TYPE", 0,
+		"If set, clusters and listeners are dropped from the config of a proxy until their total size, for each "+
+			"type, is below this number of bytes. Zero means unlimited.").Get()
+
+	MaxVirtualServicesPerNamespace = env.RegisterIntVar("PILOT_MAX_VIRTUAL_SERVICES_PER_NAMESPACE", 0,
+		"If set, the VirtualServices of a namespace beyond this number, from the newest, are ignored and reported "+
+			"as rejected. Zero means unlimited.").Get()
+
+	MaxRoutesPerVirtualService = env.RegisterIntVar("PILOT_MAX_ROUTES_PER_VIRTUAL_SERVICE", 0,
+		"If set, VirtualServices with more HTTP, TLS and TCP routes than this number are ignored and reported as "+
+			"rejected. Zero means unlimited.").Get()
+
+	MaxWildcardHostsPerNamespace = env.RegisterIntVar("PILOT_MAX_WILDCARD_HOSTS_PER_NAMESPACE", 0,
+		"If set, the VirtualServices of a namespace adding wildcard hosts beyond this number, from the newest, are "+
+			"ignored and reported as rejected. Zero means unlimited.").Get()
+
+	MaxEnvoyFiltersPerNamespace = env.RegisterIntVar("PILOT_MAX_ENVOY_FILTERS_PER_NAMESPACE", 0,
+		"If set, the EnvoyFilters of a namespace beyond this number, from the newest, are ignored and reported as "+
+			"rejected. Zero means unlimited.").Get()
+
+	InboundExactBalanceMaxConcurrency = env.RegisterIntVar("PILOT_INBOUND_EXACT_BALANCE_MAX_CONCURRENCY", 0,
+		"If set, the inbound listener of sidecars running at most this number of worker threads, as configured by "+
+			"the concurrency of their proxy config, balances connections evenly across workers. This improves tail "+
+			"latency of small pods, at the cost of some contention when accepting connections. Zero disables it.").Get()
+
 	WorkloadEntryCrossCluster = env.RegisterBoolVar("PILOT_ENABLE_CROSS_CLUSTER_WORKLOAD_ENTRY", false,
 		"If enabled, pilot will read WorkloadEntry from other clusters, selectable by Services in that cluster.").Get()
 
@@ -499,14 +794,73 @@ var (
 	MulticlusterHeadlessEnabled = env.RegisterBoolVar("ENABLE_MULTICLUSTER_HEADLESS", false,
 		"If true, the DNS name table for a headless service will resolve to same-network endpoints in any cluster.").Get()
 
+	MulticlusterRemoteServiceDNSEnabled = env.RegisterBoolVar("ENABLE_MULTICLUSTER_REMOTE_SERVICE_DNS", false,
+		"If true, the DNS name table for a service that only exists in clusters on other networks will resolve "+
+			"to the addresses of the network gateways in those clusters.").Get()
+
 	// UseTargetPortForGatewayRoutes determines which port to use for the routes. This flag is for safety only, and can be removed in future versions.
 	// Example setup: we have a Service on port 80, targetPort 8080
 	// Old behavior (false): we create listener 0.0.0.0_8080 and route http.80. This has potential for conflicts if there are other port 80s
 	// New behavior (true): we create listener 0.0.0.0_8080 and route http.8080. This has no conflicts; routes are 1:1 with listener.
 	UseTargetPortForGatewayRoutes = env.RegisterBoolVar("PILOT_USE_TARGET_PORT_FOR_GATEWAY_ROUTES", true,
 		"If true, routes will use the target port of the gateway service in the route name, not the service port.").Get()
+
+	StableInboundStatPrefix = env.RegisterBoolVar("PILOT_STABLE_INBOUND_STAT_PREFIX", false,
+		"If true, the stat prefix of each inbound filter chain is derived from its port, service, protocol and TLS mode, "+
+			"such as inbound|8080|reviews.default.svc.cluster.local|http|mtls, rather than shared by all filter chains "+
+			"of the listener.").Get()
+
+	// MTLSCipherSuites are the cipher suites of the istio mTLS connections, inbound and outbound, by order of
+	// preference. Empty for the defaults.
+	MTLSCipherSuites = commaSeparated(env.RegisterStringVar("PILOT_MTLS_CIPHER_SUITES", "",
+		"Comma separated list of the cipher suites of the istio mTLS connections, by order of preference. "+
+			"Defaults to the strong cipher suites supported by Envoy.").Get())
+
+	// MTLSECDHCurves are the elliptic curves of the istio mTLS connections, by order of preference. Empty for the
+	// defaults.
+	MTLSECDHCurves = commaSeparated(env.RegisterStringVar("PILOT_MTLS_ECDH_CURVES", "",
+		"Comma separated list of the ECDH curves of the istio mTLS connections, by order of preference, "+
+			"such as X25519,P-256. Defaults to the curves of Envoy.").Get())
+
+	MTLSSessionTimeout = env.RegisterDurationVar("PILOT_MTLS_SESSION_TIMEOUT", 0,
+		"The lifetime of the TLS sessions of the inbound istio mTLS connections, which clients can resume "+
+			"without a full handshake. Zero uses the Envoy default of 2 hours.").Get()
+
+	MTLSDisableSessionTickets = env.RegisterBoolVar("PILOT_MTLS_DISABLE_SESSION_TICKETS", false,
+		"If true, the inbound istio mTLS connections do not issue session tickets, so that only the sessions "+
+			"cached by the server can be resumed.").Get()
+
+	MTLSMaxSessionKeys = env.RegisterIntVar("PILOT_MTLS_MAX_SESSION_KEYS", 1,
+		"The number of TLS sessions each outbound istio mTLS cluster keeps to resume its connections without a "+
+			"full handshake. Zero disables the resumption. Higher values help clusters with many endpoints.").Get()
+
+	MTLSHandshakeTimeout = env.RegisterDurationVar("PILOT_MTLS_HANDSHAKE_TIMEOUT", 0,
+		"The timeout of the TLS handshake of the inbound TLS connections, after which the connection is "+
+			"closed. Zero does not bound the handshake.").Get()
+
+	TLSProfile = env.RegisterStringVar("PILOT_TLS_PROFILE", "",
+		"The named TLS profile setting the TLS versions, cipher suites and curves of the TLS contexts generated by "+
+			"istiod: compat, modern, fips or experimental-pq. Fields set explicitly, such as the cipher suites of "+
+			"a Gateway server or PILOT_MTLS_CIPHER_SUITES, take precedence. Empty keeps the defaults of each "+
+			"context. Gateways can select another profile with the gateway.istio.io/tls-profile annotation.").Get()
+
+	EnableNativeSidecars = env.RegisterBoolVar("ENABLE_NATIVE_SIDECARS", false,
+		"If true, the proxy is injected as a native sidecar, an init container with restartPolicy Always, so that it "+
+			"is started before and stopped after the application containers. Requires Kubernetes 1.28 or newer. "+
+			"Can be overridden per pod with the sidecar.istio.io/nativeSidecar annotation.").Get()
 )
 
+// commaSeparated returns the non-empty elements of a comma separated list.
+func commaSeparated(s string) []string {
+	var out []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
 // UnsafeFeaturesEnabled returns true if any unsafe features are enabled.
 func UnsafeFeaturesEnabled() bool {
 	return EnableUnsafeAdminEndpoints || EnableUnsafeAssertions
@@ -67,7 +67,8 @@ func ServerOptions(options *istiokeepalive.Options, interceptors ...grpc.UnarySe
 		// Ensure we allow clients sufficient ability to send keep alives. If this is higher than client
 		// keep alive setting, it will prematurely get a GOAWAY sent.
 		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
-			MinTime: options.Time / 2,
+			MinTime:             options.ClientPingInterval(),
+			PermitWithoutStream: options.PermitClientPingsWithoutStream,
 		}),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			Time:                  options.Time,
@@ -15,6 +15,8 @@
 package model
 
 import (
+	"sync"
+
 	authpb "istio.io/api/security/v1beta1"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -37,6 +39,11 @@ type AuthorizationPolicies struct {
 
 	// The name of the root namespace. Policy in the root namespace applies to workloads in all namespaces.
 	RootNamespace string `json:"root_namespace"`
+
+	// sharedFilters are the filters built from the policies, keyed by the set of policies applied to a workload, to
+	// share them across the workloads with the same policies. Guarded by sharedFiltersMutex.
+	sharedFilters      map[string]interface{}
+	sharedFiltersMutex sync.Mutex
 }
 
 // GetAuthorizationPolicies returns the AuthorizationPolicies for the given environment.
@@ -65,6 +72,27 @@ func GetAuthorizationPolicies(env *Environment) (*AuthorizationPolicies, error)
 	return policy, nil
 }
 
+// SharedFilters returns the filters built for the key, calling build only if none were built for the key yet.
+// The filters live as long as the policies, which are rebuilt on any change to them, so the key only needs to
+// identify the set of policies they are built from. It also returns true if the filters were already built.
+func (policy *AuthorizationPolicies) SharedFilters(key string, build func() interface{}) (interface{}, bool) {
+	policy.sharedFiltersMutex.Lock()
+	filters, f := policy.sharedFilters[key]
+	policy.sharedFiltersMutex.Unlock()
+	if f {
+		return filters, true
+	}
+	// Built outside of the lock, the filters of a key may be built concurrently, in which case the last one is kept.
+	filters = build()
+	policy.sharedFiltersMutex.Lock()
+	defer policy.sharedFiltersMutex.Unlock()
+	if policy.sharedFilters == nil {
+		policy.sharedFilters = map[string]interface{}{}
+	}
+	policy.sharedFilters[key] = filters
+	return filters, false
+}
+
 type AuthorizationPoliciesResult struct {
 	Custom []AuthorizationPolicy
 	Deny   []AuthorizationPolicy
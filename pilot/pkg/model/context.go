@@ -33,11 +33,13 @@ import (
 	"github.com/golang/protobuf/ptypes/any"
 	structpb "github.com/golang/protobuf/ptypes/struct"
 
+	"istio.io/api/annotation"
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/trustbundle"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/interception"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/network"
@@ -89,6 +91,9 @@ type Environment struct {
 	clusterLocalServices ClusterLocalProvider
 
 	GatewayAPIController GatewayController
+
+	// NetworkPolicies, if set, is used to prune endpoints unreachable due to platform network policies.
+	NetworkPolicies NetworkPolicyEvaluator
 }
 
 func (e *Environment) Mesh() *meshconfig.MeshConfig {
@@ -98,6 +103,14 @@ func (e *Environment) Mesh() *meshconfig.MeshConfig {
 	return nil
 }
 
+// EffectiveMesh returns the mesh config with the overlay of the given namespace applied.
+func (e *Environment) EffectiveMesh(namespace string) *meshconfig.MeshConfig {
+	if e != nil && e.Watcher != nil {
+		return e.Watcher.EffectiveMesh(namespace)
+	}
+	return nil
+}
+
 // GetDiscoveryAddress parses the DiscoveryAddress specified via MeshConfig.
 func (e *Environment) GetDiscoveryAddress() (host.Name, string, error) {
 	proxyConfig := mesh.DefaultProxyConfig()
@@ -183,6 +196,10 @@ func AnyToUnnamedResources(r []*any.Any) Resources {
 func ResourcesToAny(r Resources) []*any.Any {
 	a := make([]*any.Any, 0, len(r))
 	for _, rr := range r {
+		// Resources without a body only tell delta clients an aliased name does not exist.
+		if rr.Resource == nil {
+			continue
+		}
 		a = append(a, rr.Resource)
 	}
 	return a
@@ -211,6 +228,15 @@ type XdsResourceGenerator interface {
 	Generate(proxy *Proxy, push *PushContext, w *WatchedResource, updates *PushRequest) (Resources, XdsLogDetails, error)
 }
 
+// XdsDeltaResourceGenerator is implemented by generators which compute the changes sent on delta xDS streams
+// themselves, for instance for resources watched through glob collections.
+type XdsDeltaResourceGenerator interface {
+	XdsResourceGenerator
+
+	// GenerateDeltas returns the resources to send and the names of the resources to remove.
+	GenerateDeltas(proxy *Proxy, push *PushContext, w *WatchedResource, updates *PushRequest) (Resources, []string, XdsLogDetails, error)
+}
+
 // Proxy contains information about an specific instance of a proxy (envoy sidecar, gateway,
 // etc). The Proxy is initialized when a sidecar connects to Pilot, and populated from
 // 'node' info in the protocol as well as data extracted from registries.
@@ -333,6 +359,17 @@ type WatchedResource struct {
 	// LastSize tracks the size of the last update
 	LastSize int
 
+	// ResourcesHash is a hash of the resources of the last sent response, if it was complete. It is only
+	// tracked when xDS session resumption or the suppression of unchanged pushes is enabled.
+	ResourcesHash string
+
+	// AckedHash is the ResourcesHash of the last ACKed response.
+	AckedHash string
+
+	// SentResourceHashes is the hash of each resource last sent on a delta stream, for generators computing
+	// their own changes.
+	SentResourceHashes map[string]string
+
 	// Last request contains the last DiscoveryRequest received for
 	// this type. Generators are called immediately after each request,
 	// and may use the information in DiscoveryRequest.
@@ -522,10 +559,17 @@ type NodeMetadata struct {
 	// Namespace is the namespace in which the workload instance is running.
 	Namespace string `json:"NAMESPACE,omitempty"`
 
+	// NodeName is the name of the node the workload instance is running on.
+	NodeName string `json:"NODE_NAME,omitempty"`
+
 	// InterceptionMode is the name of the metadata variable that carries info about
 	// traffic interception mode at the proxy
 	InterceptionMode TrafficInterceptionMode `json:"INTERCEPTION_MODE,omitempty"`
 
+	// CNIInterception is set if the traffic of the workload is intercepted by the Istio CNI plugin, rather than
+	// by an init container.
+	CNIInterception StringBool `json:"CNI_INTERCEPTION,omitempty"`
+
 	// ServiceAccount specifies the service account which is running the workload.
 	ServiceAccount string `json:"SERVICE_ACCOUNT,omitempty"`
 
@@ -585,6 +629,19 @@ type NodeMetadata struct {
 	// This depends on DNSCapture.
 	DNSAutoAllocate StringBool `json:"DNS_AUTO_ALLOCATE,omitempty"`
 
+	// DNSSearchDomainExpansion controls which variants of Kubernetes service names are resolvable by the
+	// DNS proxy. "fqdn" only resolves fully qualified names; "namespace" (the default) also resolves
+	// name, name.namespace and name.namespace.svc.
+	DNSSearchDomainExpansion string `json:"DNS_SEARCH_DOMAIN_EXPANSION,omitempty"`
+
+	// DNSAltDomains is a list of additional domain suffixes (e.g. clusterset.local) under which
+	// Kubernetes services are resolvable by the DNS proxy, as name.namespace.svc.<domain>.
+	DNSAltDomains StringList `json:"DNS_ALT_DOMAINS,omitempty"`
+
+	// ProxyInstanceID is a unique identifier generated by the agent when it starts. It is stable across
+	// reconnects of the same proxy process, allowing Istiod to correlate flapping XDS streams.
+	ProxyInstanceID string `json:"PROXY_INSTANCE_ID,omitempty"`
+
 	// AutoRegister will enable auto registration of the connected endpoint to the service registry using the given WorkloadGroup name
 	AutoRegisterGroup string `json:"AUTO_REGISTER_GROUP,omitempty"`
 
@@ -598,6 +655,18 @@ type NodeMetadata struct {
 	// Used by envoy filters
 	StsPort string `json:"STS_PORT,omitempty"`
 
+	// OverloadMaxHeapBytes enables the Envoy overload manager, with the given heap size in bytes as the limit.
+	// When the heap gets close to it, Envoy releases free memory and then stops accepting new requests.
+	OverloadMaxHeapBytes string `json:"OVERLOAD_MAX_HEAP_BYTES,omitempty"`
+
+	// OverloadHeapShedThreshold is the fraction of OverloadMaxHeapBytes above which Envoy stops accepting
+	// new requests. Defaults to 0.95.
+	OverloadHeapShedThreshold string `json:"OVERLOAD_HEAP_SHED_THRESHOLD,omitempty"`
+
+	// OverloadMaxDownstreamConnections limits the number of downstream connections accepted by Envoy
+	// across all listeners.
+	OverloadMaxDownstreamConnections string `json:"OVERLOAD_MAX_DOWNSTREAM_CONNECTIONS,omitempty"`
+
 	// Envoy status port redirecting to agent status port.
 	EnvoyStatusPort int `json:"ENVOY_STATUS_PORT,omitempty"`
 
@@ -857,6 +926,11 @@ func (node *Proxy) SupportsIPv6() bool {
 	return node.ipv6Support
 }
 
+// IsIPv6Only returns true if proxy only has IPv6 addresses.
+func (node *Proxy) IsIPv6Only() bool {
+	return node.ipv6Support && !node.ipv4Support
+}
+
 // ParseMetadata parses the opaque Metadata from an Envoy Node into string key-value pairs.
 // Any non-string values are ignored.
 func ParseMetadata(metadata *structpb.Struct) (*NodeMetadata, error) {
@@ -1036,6 +1110,37 @@ func (node *Proxy) GetInterceptionMode() TrafficInterceptionMode {
 	return InterceptionRedirect
 }
 
+// GetInboundInterceptionMode returns the mode inbound traffic of the proxy is actually intercepted with. With the
+// Istio CNI, iptables are set up by the CNI plugin from the sidecar.istio.io/interceptionMode annotation of the pod,
+// defaulting to REDIRECT, which takes precedence over the mode reported by the proxy. Only inbound traffic can be
+// intercepted with TPROXY; outbound traffic is always redirected.
+func (node *Proxy) GetInboundInterceptionMode() TrafficInterceptionMode {
+	mode := node.GetInterceptionMode()
+	if node == nil || mode == InterceptionNone || !node.Metadata.CNIInterception || node.Metadata.Annotations == nil {
+		return mode
+	}
+	if node.Metadata.Annotations[annotation.SidecarInterceptionMode.Name] == string(InterceptionTproxy) {
+		return InterceptionTproxy
+	}
+	return InterceptionRedirect
+}
+
+// InboundInterception returns the inbound ports of the proxy which are intercepted, from the traffic annotations
+// of its workload. Invalid annotations are rejected by the injector; should the proxy still have some, all its
+// ports are considered intercepted.
+func (node *Proxy) InboundInterception() *interception.InboundPorts {
+	var annotations map[string]string
+	if node.Metadata != nil {
+		annotations = node.Metadata.Annotations
+	}
+	ports, err := interception.InboundPortsFromAnnotations(annotations)
+	if err != nil {
+		log.Debugf("invalid inbound interception annotations of %s, intercepting all ports: %v", node.ID, err)
+		return &interception.InboundPorts{All: true}
+	}
+	return ports
+}
+
 func (node *Proxy) IsVM() bool {
 	// TODO use node metadata to indicate that this is a VM intstead of the TestVMLabel
 	return node.Metadata != nil && node.Metadata.Labels[constants.TestVMLabel] != ""
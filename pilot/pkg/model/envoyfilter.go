@@ -15,6 +15,7 @@ package model
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/gogo/protobuf/proto"
@@ -22,6 +23,7 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/xds"
 )
 
@@ -171,6 +173,30 @@ func (efw *EnvoyFilterWrapper) Key() string {
 	return efw.Namespace + "/" + efw.Name
 }
 
+// KeysApplyingTo returns the sorted keys of the EnvoyFilters having patches that apply to the given type.
+func (efw *EnvoyFilterWrapper) KeysApplyingTo(applyTo networking.EnvoyFilter_ApplyTo) []ConfigKey {
+	if efw == nil {
+		return nil
+	}
+	seen := map[ConfigKey]struct{}{}
+	var out []ConfigKey
+	for _, cp := range efw.Patches[applyTo] {
+		key := ConfigKey{Kind: gvk.EnvoyFilter, Name: cp.Name, Namespace: cp.Namespace}
+		if _, f := seen[key]; f {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, key)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
 func (cpw *EnvoyFilterConfigPatchWrapper) Key() string {
 	if cpw == nil {
 		return ""
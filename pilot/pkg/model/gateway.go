@@ -16,10 +16,12 @@ package model
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/util/sets"
@@ -77,6 +79,91 @@ type MergedGateway struct {
 
 	// PortMap defines a mapping of targetPorts to the set of Service ports that reference them
 	PortMap GatewayPortMap
+
+	// GatewayTopologies maps from gateway name to the topology overrides configured on that gateway.
+	// Gateways without overrides are not included.
+	GatewayTopologies map[string]*GatewayTopology
+
+	// GatewayTLSProfiles maps from gateway name to the TLS profile selected by that gateway.
+	// Gateways using the mesh profile are not included.
+	GatewayTLSProfiles map[string]string
+}
+
+const (
+	// GatewayNumTrustedProxiesAnnotation overrides the number of trusted proxies in front of the gateway,
+	// used to determine the client address from X-Forwarded-For, for the servers of a Gateway.
+	GatewayNumTrustedProxiesAnnotation = "gateway.istio.io/num-trusted-proxies"
+	// GatewayForwardClientCertDetailsAnnotation overrides how the x-forwarded-client-cert header is handled
+	// for the servers of a Gateway. Values are those of the mesh config ForwardClientCertDetails, such as SANITIZE_SET.
+	GatewayForwardClientCertDetailsAnnotation = "gateway.istio.io/forward-client-cert-details"
+	// GatewaySkipXffAppendAnnotation, if "true", stops the servers of a Gateway from appending the client
+	// address to X-Forwarded-For.
+	GatewaySkipXffAppendAnnotation = "gateway.istio.io/skip-xff-append"
+	// GatewayTLSProfileAnnotation selects the TLS profile, such as modern or fips, of the TLS servers of a Gateway,
+	// instead of the one of the mesh set by PILOT_TLS_PROFILE.
+	GatewayTLSProfileAnnotation = "gateway.istio.io/tls-profile"
+)
+
+// GatewayTopology holds per Gateway overrides of the mesh wide gateway topology.
+type GatewayTopology struct {
+	// NumTrustedProxies, if set, overrides ProxyConfig.GatewayTopology.NumTrustedProxies.
+	NumTrustedProxies *uint32
+	// ForwardClientCertDetails, if not UNDEFINED, overrides ProxyConfig.GatewayTopology.ForwardClientCertDetails.
+	ForwardClientCertDetails meshconfig.Topology_ForwardClientCertDetails
+	// SkipXffAppend disables appending the client address to X-Forwarded-For.
+	SkipXffAppend bool
+}
+
+// parseGatewayTopology reads the topology annotations of a gateway. Nil is returned if there are none.
+func parseGatewayTopology(annotations map[string]string) (*GatewayTopology, error) {
+	var topology *GatewayTopology
+	get := func() *GatewayTopology {
+		if topology == nil {
+			topology = &GatewayTopology{}
+		}
+		return topology
+	}
+	if v, f := annotations[GatewayNumTrustedProxiesAnnotation]; f {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", GatewayNumTrustedProxiesAnnotation, v, err)
+		}
+		num := uint32(n)
+		get().NumTrustedProxies = &num
+	}
+	if v, f := annotations[GatewayForwardClientCertDetailsAnnotation]; f {
+		details, ok := meshconfig.Topology_ForwardClientCertDetails_value[v]
+		if !ok || details == int32(meshconfig.Topology_UNDEFINED) {
+			return nil, fmt.Errorf("invalid %s %q", GatewayForwardClientCertDetailsAnnotation, v)
+		}
+		get().ForwardClientCertDetails = meshconfig.Topology_ForwardClientCertDetails(details)
+	}
+	if v, f := annotations[GatewaySkipXffAppendAnnotation]; f {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", GatewaySkipXffAppendAnnotation, v, err)
+		}
+		get().SkipXffAppend = skip
+	}
+	return topology, nil
+}
+
+// GatewayTopologyForServers returns the topology overrides shared by the gateways of all the given servers.
+// If the gateways disagree, the overrides are ignored and nil is returned.
+func (g *MergedGateway) GatewayTopologyForServers(servers []*networking.Server) *GatewayTopology {
+	var topology *GatewayTopology
+	for i, s := range servers {
+		t := g.GatewayTopologies[g.GatewayNameForServer[s]]
+		if i == 0 {
+			topology = t
+			continue
+		}
+		if !reflect.DeepEqual(t, topology) {
+			log.Warnf("conflicting gateway topology for servers on the same port; using the mesh defaults")
+			return nil
+		}
+	}
+	return topology
 }
 
 var (
@@ -120,6 +207,8 @@ func MergeGateways(gateways []gatewayWithInstances) *MergedGateway {
 	serversByRouteName := make(map[string][]*networking.Server)
 	tlsServerInfo := make(map[*networking.Server]*TLSServerInfo)
 	gatewayNameForServer := make(map[*networking.Server]string)
+	gatewayTopologies := make(map[string]*GatewayTopology)
+	gatewayTLSProfiles := make(map[string]string)
 	tlsHostsByPort := map[uint32]sets.Set{} // port -> host set
 	autoPassthrough := false
 
@@ -129,6 +218,15 @@ func MergeGateways(gateways []gatewayWithInstances) *MergedGateway {
 		gatewayName := gatewayConfig.Namespace + "/" + gatewayConfig.Name // Format: %s/%s
 		gatewayCfg := gatewayConfig.Spec.(*networking.Gateway)
 		log.Debugf("MergeGateways: merging gateway %q :\n%v", gatewayName, gatewayCfg)
+		if topology, err := parseGatewayTopology(gatewayConfig.Annotations); err != nil {
+			log.Warnf("ignoring gateway topology of %s: %v", gatewayName, err)
+			RecordRejectedConfig(gatewayName)
+		} else if topology != nil {
+			gatewayTopologies[gatewayName] = topology
+		}
+		if profile := gatewayConfig.Annotations[GatewayTLSProfileAnnotation]; profile != "" {
+			gatewayTLSProfiles[gatewayName] = profile
+		}
 		snames := sets.Set{}
 		for _, s := range gatewayCfg.Servers {
 			if len(s.Name) > 0 {
@@ -268,6 +366,8 @@ func MergeGateways(gateways []gatewayWithInstances) *MergedGateway {
 		ServersByRouteName:              serversByRouteName,
 		ContainsAutoPassthroughGateways: autoPassthrough,
 		PortMap:                         getTargetPortMap(serversByRouteName),
+		GatewayTopologies:               gatewayTopologies,
+		GatewayTLSProfiles:              gatewayTLSProfiles,
 	}
 }
 
@@ -16,8 +16,10 @@ package model
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pkg/config"
 )
@@ -230,3 +232,51 @@ func TestParseGatewayRDSRouteName(t *testing.T) {
 		})
 	}
 }
+
+func TestParseGatewayTopology(t *testing.T) {
+	two := uint32(2)
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        *GatewayTopology
+		wantErr     bool
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "all",
+			annotations: map[string]string{
+				GatewayNumTrustedProxiesAnnotation:        "2",
+				GatewayForwardClientCertDetailsAnnotation: "FORWARD_ONLY",
+				GatewaySkipXffAppendAnnotation:            "true",
+			},
+			want: &GatewayTopology{
+				NumTrustedProxies:        &two,
+				ForwardClientCertDetails: meshconfig.Topology_FORWARD_ONLY,
+				SkipXffAppend:            true,
+			},
+		},
+		{
+			name:        "invalid num trusted proxies",
+			annotations: map[string]string{GatewayNumTrustedProxiesAnnotation: "-1"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid forward client cert details",
+			annotations: map[string]string{GatewayForwardClientCertDetailsAnnotation: "UNDEFINED"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGatewayTopology(tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+	authpb "istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/util/sets"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// IneffectivePolicy is a policy with rules which cannot take effect on the workloads it selects, as they only apply
+// to HTTP traffic and the workloads serve none.
+type IneffectivePolicy struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+}
+
+// recordIneffectivePolicy records a policy which cannot take effect, with the reason.
+func (ps *PushContext) recordIneffectivePolicy(key ConfigKey, reason string) {
+	ps.proxyStatusMutex.Lock()
+	defer ps.proxyStatusMutex.Unlock()
+	if ps.ineffectivePolicies == nil {
+		ps.ineffectivePolicies = map[ConfigKey]string{}
+	}
+	ps.ineffectivePolicies[key] = reason
+}
+
+// IneffectivePolicies returns the policies which cannot take effect, with the reason.
+func (ps *PushContext) IneffectivePolicies() map[ConfigKey]string {
+	if ps == nil {
+		return nil
+	}
+	ps.proxyStatusMutex.RLock()
+	defer ps.proxyStatusMutex.RUnlock()
+	out := make(map[ConfigKey]string, len(ps.ineffectivePolicies))
+	for key, reason := range ps.ineffectivePolicies {
+		out[key] = reason
+	}
+	return out
+}
+
+// IneffectivePolicyList returns the policies which cannot take effect, sorted by namespace, name and kind.
+func (ps *PushContext) IneffectivePolicyList() []IneffectivePolicy {
+	policies := ps.IneffectivePolicies()
+	out := make([]IneffectivePolicy, 0, len(policies))
+	for key, reason := range policies {
+		out = append(out, IneffectivePolicy{Kind: key.Kind.Kind, Name: key.Name, Namespace: key.Namespace, Reason: reason})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Kind < out[j].Kind
+	})
+	return out
+}
+
+// initIneffectivePolicies finds the authorization policies and request authentications with rules which only
+// apply to HTTP traffic, selecting only workloads which serve none. The policies without selector apply to whole
+// namespaces, and are not checked.
+func (ps *PushContext) initIneffectivePolicies() {
+	if ps.AuthzPolicies != nil {
+		for _, policies := range ps.AuthzPolicies.NamespaceToPolicies {
+			for _, policy := range policies {
+				selector := policy.Spec.GetSelector().GetMatchLabels()
+				fields := httpOnlyFields(policy.Spec)
+				if len(selector) == 0 || len(fields) == 0 {
+					continue
+				}
+				targets, http := ps.httpTargets(policy.Namespace, selector)
+				if len(targets) == 0 || http {
+					continue
+				}
+				consequence := "its rules never match"
+				if policy.Spec.GetAction() != authpb.AuthorizationPolicy_ALLOW {
+					consequence = "its rules apply without these fields"
+				}
+				ps.recordIneffectivePolicy(ConfigKey{Kind: gvk.AuthorizationPolicy, Name: policy.Name, Namespace: policy.Namespace},
+					fmt.Sprintf("uses the HTTP only fields %s, but selects %s, which serve no HTTP traffic: %s",
+						strings.Join(fields, ", "), strings.Join(targets, ", "), consequence))
+			}
+		}
+	}
+	if ps.AuthnPolicies != nil {
+		for _, configs := range ps.AuthnPolicies.requestAuthentications {
+			for _, cfg := range configs {
+				selector := cfg.Spec.(*authpb.RequestAuthentication).GetSelector().GetMatchLabels()
+				if len(selector) == 0 {
+					continue
+				}
+				targets, http := ps.httpTargets(cfg.Namespace, selector)
+				if len(targets) == 0 || http {
+					continue
+				}
+				ps.recordIneffectivePolicy(ConfigKey{Kind: gvk.RequestAuthentication, Name: cfg.Name, Namespace: cfg.Namespace},
+					fmt.Sprintf("selects %s, which serve no HTTP traffic: JWTs are never validated",
+						strings.Join(targets, ", ")))
+			}
+		}
+	}
+}
+
+// httpOnlyFields returns the fields of the rules of the authorization policy which only apply to HTTP traffic.
+func httpOnlyFields(spec *authpb.AuthorizationPolicy) []string {
+	fields := sets.NewSet()
+	for _, rule := range spec.GetRules() {
+		for _, from := range rule.GetFrom() {
+			src := from.GetSource()
+			if len(src.GetRequestPrincipals()) > 0 || len(src.GetNotRequestPrincipals()) > 0 {
+				fields.Insert("requestPrincipals")
+			}
+		}
+		for _, to := range rule.GetTo() {
+			op := to.GetOperation()
+			if len(op.GetHosts()) > 0 || len(op.GetNotHosts()) > 0 {
+				fields.Insert("hosts")
+			}
+			if len(op.GetMethods()) > 0 || len(op.GetNotMethods()) > 0 {
+				fields.Insert("methods")
+			}
+			if len(op.GetPaths()) > 0 || len(op.GetNotPaths()) > 0 {
+				fields.Insert("paths")
+			}
+		}
+		for _, when := range rule.GetWhen() {
+			if strings.HasPrefix(when.GetKey(), "request.") {
+				fields.Insert(when.GetKey())
+			}
+		}
+	}
+	return fields.SortedList()
+}
+
+// httpTargets returns the gateways or services of the workloads a policy of the namespace selects, and whether any
+// of them serves HTTP traffic. The workloads of gateways serve the servers of the gateways, whatever the ports of
+// their services, so the services are only considered if the policy selects no gateway.
+func (ps *PushContext) httpTargets(namespace string, selector map[string]string) ([]string, bool) {
+	policySelector := labels.Instance(selector)
+	var targets []string
+	http := false
+	for _, gw := range ps.gatewayIndex.all {
+		if !policySelector.SubsetOf(gw.Spec.(*networking.Gateway).GetSelector()) {
+			continue
+		}
+		targets = append(targets, "gateway "+gw.Namespace+"/"+gw.Name)
+		http = http || gatewayServesHTTP(gw)
+	}
+	if len(targets) > 0 {
+		return targets, http
+	}
+
+	rootNamespace := ps.Mesh.GetRootNamespace()
+	for _, byNamespace := range ps.ServiceIndex.HostnameAndNamespace {
+		for ns, svc := range byNamespace {
+			if namespace != rootNamespace && ns != namespace {
+				continue
+			}
+			if len(svc.Attributes.LabelSelectors) == 0 || !policySelector.SubsetOf(svc.Attributes.LabelSelectors) {
+				continue
+			}
+			targets = append(targets, "service "+ns+"/"+string(svc.Hostname))
+			for _, port := range svc.Ports {
+				// Ports without a declared protocol are sniffed, and may serve HTTP traffic.
+				http = http || port.Protocol.IsHTTP() || port.Protocol.IsUnsupported()
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets, http
+}
+
+// gatewayServesHTTP returns whether a server of the gateway serves HTTP traffic: HTTPS servers do, unless they pass
+// the TLS connections through.
+func gatewayServesHTTP(gw config.Config) bool {
+	for _, server := range gw.Spec.(*networking.Gateway).GetServers() {
+		p := protocol.Parse(server.GetPort().GetProtocol())
+		if p.IsHTTP() {
+			return true
+		}
+		if p == protocol.HTTPS {
+			mode := server.GetTls().GetMode()
+			if mode != networking.ServerTLSSettings_PASSTHROUGH && mode != networking.ServerTLSSettings_AUTO_PASSTHROUGH {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	securityBeta "istio.io/api/security/v1beta1"
+	selectorpb "istio.io/api/type/v1beta1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestInitIneffectivePolicies(t *testing.T) {
+	gateway := func(name string, selector map[string]string, servers ...*networking.Server) config.Config {
+		return config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.Gateway, Name: name, Namespace: "istio-system"},
+			Spec: &networking.Gateway{Selector: selector, Servers: servers},
+		}
+	}
+	service := func(name, namespace string, selector map[string]string, p protocol.Instance) *Service {
+		return &Service{
+			Hostname:   host.Name(name + "." + namespace + ".svc.cluster.local"),
+			Ports:      PortList{{Name: "port", Port: 80, Protocol: p}},
+			Attributes: ServiceAttributes{Namespace: namespace, LabelSelectors: selector},
+		}
+	}
+	authz := func(name, namespace string, selector map[string]string, rule *securityBeta.Rule) AuthorizationPolicy {
+		return AuthorizationPolicy{
+			Name:      name,
+			Namespace: namespace,
+			Spec: &securityBeta.AuthorizationPolicy{
+				Selector: &selectorpb.WorkloadSelector{MatchLabels: selector},
+				Rules:    []*securityBeta.Rule{rule},
+			},
+		}
+	}
+	methods := &securityBeta.Rule{To: []*securityBeta.Rule_To{{Operation: &securityBeta.Operation{Methods: []string{"GET"}}}}}
+	ports := &securityBeta.Rule{To: []*securityBeta.Rule_To{{Operation: &securityBeta.Operation{Ports: []string{"80"}}}}}
+
+	ps := NewPushContext()
+	ps.Mesh = &meshconfig.MeshConfig{RootNamespace: "istio-system"}
+	ps.gatewayIndex.all = []config.Config{
+		gateway("passthrough", map[string]string{"istio": "passthrough"}, &networking.Server{
+			Port: &networking.Port{Number: 443, Protocol: "HTTPS"},
+			Tls:  &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_PASSTHROUGH},
+		}),
+		gateway("ingress", map[string]string{"istio": "ingress"}, &networking.Server{
+			Port: &networking.Port{Number: 443, Protocol: "HTTPS"},
+			Tls:  &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_SIMPLE},
+		}),
+	}
+	for _, svc := range []*Service{
+		service("db", "foo", map[string]string{"app": "db"}, protocol.TCP),
+		service("web", "foo", map[string]string{"app": "web"}, protocol.HTTP),
+		service("sniffed", "foo", map[string]string{"app": "sniffed"}, protocol.Unsupported),
+		// The gateway workloads serve the servers of the gateways, whatever the ports of their services.
+		service("passthrough", "istio-system", map[string]string{"istio": "passthrough"}, protocol.HTTP2),
+	} {
+		ps.ServiceIndex.HostnameAndNamespace[svc.Hostname] = map[string]*Service{svc.Attributes.Namespace: svc}
+	}
+	ps.AuthzPolicies = &AuthorizationPolicies{
+		RootNamespace: "istio-system",
+		NamespaceToPolicies: map[string][]AuthorizationPolicy{
+			"foo": {
+				authz("db-methods", "foo", map[string]string{"app": "db"}, methods),
+				authz("db-ports", "foo", map[string]string{"app": "db"}, ports),
+				authz("web-methods", "foo", map[string]string{"app": "web"}, methods),
+				authz("sniffed-methods", "foo", map[string]string{"app": "sniffed"}, methods),
+				authz("unknown-methods", "foo", map[string]string{"app": "unknown"}, methods),
+				authz("namespace-methods", "foo", nil, methods),
+			},
+			"istio-system": {
+				authz("passthrough-methods", "istio-system", map[string]string{"istio": "passthrough"}, methods),
+				authz("ingress-methods", "istio-system", map[string]string{"istio": "ingress"}, methods),
+			},
+		},
+	}
+	ps.AuthnPolicies = &AuthenticationPolicies{
+		requestAuthentications: map[string][]config.Config{
+			"foo": {
+				{
+					Meta: config.Meta{GroupVersionKind: gvk.RequestAuthentication, Name: "db-jwt", Namespace: "foo"},
+					Spec: &securityBeta.RequestAuthentication{
+						Selector: &selectorpb.WorkloadSelector{MatchLabels: map[string]string{"app": "db"}},
+					},
+				},
+				{
+					Meta: config.Meta{GroupVersionKind: gvk.RequestAuthentication, Name: "web-jwt", Namespace: "foo"},
+					Spec: &securityBeta.RequestAuthentication{
+						Selector: &selectorpb.WorkloadSelector{MatchLabels: map[string]string{"app": "web"}},
+					},
+				},
+			},
+		},
+	}
+
+	ps.initIneffectivePolicies()
+	got := map[ConfigKey]bool{}
+	for key := range ps.IneffectivePolicies() {
+		got[key] = true
+	}
+	expected := map[ConfigKey]bool{
+		{Kind: gvk.AuthorizationPolicy, Name: "db-methods", Namespace: "foo"}:                   true,
+		{Kind: gvk.AuthorizationPolicy, Name: "passthrough-methods", Namespace: "istio-system"}: true,
+		{Kind: gvk.RequestAuthentication, Name: "db-jwt", Namespace: "foo"}:                     true,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got ineffective policies %v, expected %v", got, expected)
+	}
+
+	list := ps.IneffectivePolicyList()
+	if len(list) != 3 || list[0].Name != "db-jwt" || list[0].Kind != "RequestAuthentication" {
+		t.Fatalf("expected the policies sorted by namespace and name, got %+v", list)
+	}
+	if want := "uses the HTTP only fields methods, but selects service foo/db.foo.svc.cluster.local, which serve no " +
+		"HTTP traffic: its rules never match"; list[1].Reason != want {
+		t.Fatalf("got reason %q, expected %q", list[1].Reason, want)
+	}
+}
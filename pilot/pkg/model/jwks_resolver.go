@@ -35,6 +35,7 @@ import (
 	envoy_jwt "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/jwt_authn/v3"
 
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/dependency"
 	"istio.io/pkg/monitoring"
 )
 
@@ -327,8 +328,10 @@ func (r *JwksResolver) getRemoteContentWithRetry(uri string, retry int) ([]byte,
 	}
 
 	getPublicKey := func() (b []byte, e error) {
+		start := time.Now()
 		resp, err := client.Get(uri)
 		defer func() {
+			dependency.Report(dependency.JWKS, uri, start, e)
 			if e != nil {
 				networkFetchFailCounter.Increment()
 				return
@@ -418,6 +421,7 @@ func (r *JwksResolver) refresh() bool {
 			log.Infof("Removed cached JWT public key (lastRefreshed: %s, lastUsed: %s) from %q",
 				e.lastRefreshedTime, e.lastUsedTime, k.issuer)
 			r.keyEntries.Delete(k)
+			dependency.Remove(dependency.JWKS, k.jwksURI)
 			return true
 		}
 
@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"sort"
+
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+// ListenerConflictService is a service involved in an outbound listener conflict.
+type ListenerConflictService struct {
+	Hostname  host.Name         `json:"hostname"`
+	Namespace string            `json:"namespace,omitempty"`
+	Registry  provider.ID       `json:"registry,omitempty"`
+	Protocol  protocol.Instance `json:"protocol"`
+}
+
+// NewListenerConflictService returns the conflict description of the service, for its port protocol. The service
+// is nil for the listeners declared in the Sidecar resources.
+func NewListenerConflictService(svc *Service, hostname host.Name, p protocol.Instance) ListenerConflictService {
+	out := ListenerConflictService{Hostname: hostname, Protocol: p}
+	if svc != nil {
+		out.Hostname = svc.Hostname
+		out.Namespace = svc.Attributes.Namespace
+		out.Registry = svc.Attributes.ServiceRegistry
+	}
+	return out
+}
+
+// ListenerConflict is a conflict between services claiming the same outbound listener, and how it was resolved.
+type ListenerConflict struct {
+	Listener string                  `json:"listener"`
+	Policy   string                  `json:"policy"`
+	Winner   ListenerConflictService `json:"winner"`
+	Loser    ListenerConflictService `json:"loser"`
+	// Proxy is the last proxy the conflict was found for.
+	Proxy string `json:"proxy"`
+}
+
+// AddListenerConflict records an outbound listener conflict.
+func (ps *PushContext) AddListenerConflict(c ListenerConflict) {
+	if ps == nil {
+		return
+	}
+	ps.proxyStatusMutex.Lock()
+	defer ps.proxyStatusMutex.Unlock()
+	if ps.listenerConflicts == nil {
+		ps.listenerConflicts = map[string]ListenerConflict{}
+	}
+	ps.listenerConflicts[c.Listener+"/"+string(c.Loser.Hostname)+"/"+c.Loser.Namespace] = c
+}
+
+// ListenerConflicts returns the outbound listener conflicts, sorted by listener and losing service.
+func (ps *PushContext) ListenerConflicts() []ListenerConflict {
+	if ps == nil {
+		return nil
+	}
+	ps.proxyStatusMutex.RLock()
+	defer ps.proxyStatusMutex.RUnlock()
+	out := make([]ListenerConflict, 0, len(ps.listenerConflicts))
+	for _, c := range ps.listenerConflicts {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Listener != out[j].Listener {
+			return out[i].Listener < out[j].Listener
+		}
+		if out[i].Loser.Hostname != out[j].Loser.Hostname {
+			return out[i].Loser.Hostname < out[j].Loser.Hostname
+		}
+		return out[i].Loser.Namespace < out[j].Loser.Namespace
+	})
+	return out
+}
@@ -130,6 +130,18 @@ func (mgr *NetworkManager) GatewaysForNetworkAndCluster(nw network.ID, c cluster
 	return mgr.byNetworkAndCluster[networkAndClusterFor(nw, c)]
 }
 
+// GatewaysForCluster returns the gateways residing in the given cluster, across all networks.
+// Gateways from MeshNetworks are not associated with a cluster and are never returned.
+func (mgr *NetworkManager) GatewaysForCluster(c cluster.ID) []*NetworkGateway {
+	var out []*NetworkGateway
+	for nc, gateways := range mgr.byNetworkAndCluster {
+		if nc.cluster == c {
+			out = append(out, gateways...)
+		}
+	}
+	return out
+}
+
 type networkAndCluster struct {
 	network network.ID
 	cluster cluster.ID
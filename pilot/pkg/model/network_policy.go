@@ -0,0 +1,39 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/labels"
+)
+
+// NetworkPolicyWorkload describes one side of a connection, as seen by platform network policies.
+type NetworkPolicyWorkload struct {
+	Namespace string
+	Labels    labels.Instance
+	IP        string
+}
+
+// NetworkPolicyEvaluator determines whether platform network policies (such as Kubernetes
+// NetworkPolicy) permit traffic between two workloads. It is used to avoid sending proxies
+// endpoints that the CNI would drop traffic to.
+type NetworkPolicyEvaluator interface {
+	// Allowed returns false if the network policies of the given cluster deny traffic from src to
+	// dst on the given port. Implementations should err on the side of allowing traffic.
+	Allowed(cluster cluster.ID, src, dst NetworkPolicyWorkload, port uint32) bool
+	// SourceKey returns a key identifying the policies of the given cluster selecting src, as the subject of
+	// egress policies or as a peer of ingress rules. Sources with the same key are allowed the same traffic.
+	SourceKey(cluster cluster.ID, src NetworkPolicyWorkload) string
+}
@@ -149,6 +149,18 @@ type PushContext struct {
 	// by the ID.
 	ProxyStatus map[string]map[string]ProxyPushStatus
 
+	// rejectedConfigs holds the configs ignored, fully or partially, while building the push context, with the
+	// reason. Guarded by proxyStatusMutex.
+	rejectedConfigs map[ConfigKey]string
+
+	// ineffectivePolicies holds the policies with rules which cannot take effect on the workloads they select, with
+	// the reason. Guarded by proxyStatusMutex.
+	ineffectivePolicies map[ConfigKey]string
+
+	// listenerConflicts holds the outbound listener conflicts found while generating the listeners of the proxies,
+	// keyed by listener and losing service. Guarded by proxyStatusMutex.
+	listenerConflicts map[string]ListenerConflict
+
 	// Synthesized from env.Mesh
 	exportToDefaults exportToDefaults
 
@@ -173,6 +185,10 @@ type PushContext struct {
 	// sidecars for each namespace
 	sidecarsByNamespace map[string][]*SidecarScope
 
+	// sidecarsByDependency is the inverse of the config dependencies of the sidecars of sidecarsByNamespace:
+	// the sidecar scopes depending on each config.
+	sidecarsByDependency map[ConfigKey][]*SidecarScope
+
 	// envoy filters for each namespace including global config namespace
 	envoyFiltersByNamespace map[string][]*EnvoyFilterWrapper
 
@@ -205,6 +221,9 @@ type PushContext struct {
 	// this is mainly used for kubernetes multi-cluster scenario
 	networkMgr *NetworkManager
 
+	// networkPolicies evaluates platform network policies, if enabled.
+	networkPolicies NetworkPolicyEvaluator
+
 	initDone        atomic.Bool
 	initializeMutex sync.Mutex
 }
@@ -392,6 +411,36 @@ func (ps *PushContext) AddMetric(metric monitoring.Metric, key string, proxyID,
 	metricMap[key] = ev
 }
 
+// InitDone returns true once the push context is initialized.
+func (ps *PushContext) InitDone() bool {
+	return ps.initDone.Load()
+}
+
+// RejectConfig records a config ignored, fully or partially, while building the push context.
+func (ps *PushContext) RejectConfig(key ConfigKey, reason string) {
+	ps.proxyStatusMutex.Lock()
+	defer ps.proxyStatusMutex.Unlock()
+	if ps.rejectedConfigs == nil {
+		ps.rejectedConfigs = map[ConfigKey]string{}
+	}
+	ps.rejectedConfigs[key] = reason
+}
+
+// RejectedConfigs returns the configs ignored, fully or partially, while building the push context, with the
+// reason.
+func (ps *PushContext) RejectedConfigs() map[ConfigKey]string {
+	if ps == nil {
+		return nil
+	}
+	ps.proxyStatusMutex.RLock()
+	defer ps.proxyStatusMutex.RUnlock()
+	out := make(map[ConfigKey]string, len(ps.rejectedConfigs))
+	for key, reason := range ps.rejectedConfigs {
+		out[key] = reason
+	}
+	return out
+}
+
 var (
 
 	// EndpointNoPod tracks endpoints without an associated pod. This is an error condition, since
@@ -479,6 +528,32 @@ var (
 		"Duplicate subsets across destination rules for same host",
 	)
 
+	// InvalidVirtualServiceDelegation tracks root VirtualServices with delegation chains rejected due to
+	// cycles or excessive depth.
+	InvalidVirtualServiceDelegation = monitoring.NewGauge(
+		"pilot_vservice_delegate_invalid",
+		"Virtual services with delegation chains rejected due to cycles or excessive depth.",
+	)
+
+	// ProxyStatusConfigTruncated tracks generated configs truncated because they exceeded the configured limits.
+	ProxyStatusConfigTruncated = monitoring.NewGauge(
+		"pilot_xds_config_truncated",
+		"Generated configs truncated because they exceeded the configured size limits.",
+	)
+
+	// ProxyStatusInterceptionModeMismatch tracks proxies reporting an interception mode other than the one the
+	// Istio CNI plugin intercepts their inbound traffic with.
+	ProxyStatusInterceptionModeMismatch = monitoring.NewGauge(
+		"pilot_interception_mode_mismatch",
+		"Proxies whose interception mode disagrees with the one of the Istio CNI plugin.",
+	)
+
+	// ConfigQuotaExceeded tracks configs ignored because they exceed the quotas of their namespace.
+	ConfigQuotaExceeded = monitoring.NewGauge(
+		"pilot_config_quota_exceeded",
+		"Configs ignored because they exceed the quotas of their namespace.",
+	)
+
 	// totalVirtualServices tracks the total number of virtual service
 	totalVirtualServices = monitoring.NewGauge(
 		"pilot_virt_services",
@@ -505,6 +580,10 @@ var (
 		ProxyStatusClusterNoInstances,
 		DuplicatedDomains,
 		DuplicatedSubsets,
+		InvalidVirtualServiceDelegation,
+		ProxyStatusConfigTruncated,
+		ProxyStatusInterceptionModeMismatch,
+		ConfigQuotaExceeded,
 	}
 )
 
@@ -522,9 +601,11 @@ func NewPushContext() *PushContext {
 		virtualServiceIndex:     newVirtualServiceIndex(),
 		destinationRuleIndex:    newDestinationRuleIndex(),
 		sidecarsByNamespace:     map[string][]*SidecarScope{},
+		sidecarsByDependency:    map[ConfigKey][]*SidecarScope{},
 		envoyFiltersByNamespace: map[string][]*EnvoyFilterWrapper{},
 		gatewayIndex:            newGatewayIndex(),
 		ProxyStatus:             map[string]map[string]ProxyPushStatus{},
+		rejectedConfigs:         map[ConfigKey]string{},
 		ServiceAccounts:         map[host.Name]map[int][]string{},
 	}
 }
@@ -610,6 +691,18 @@ func virtualServiceDestinationHosts(v *networking.VirtualService) []string {
 	return out
 }
 
+// virtualServiceMirrorHosts returns the hosts traffic is mirrored to by the virtual service.
+// It is called after virtual service short host name is resolved to FQDN
+func virtualServiceMirrorHosts(v *networking.VirtualService) map[string]bool {
+	out := map[string]bool{}
+	for _, h := range v.GetHttp() {
+		if h.Mirror != nil {
+			out[h.Mirror.Host] = true
+		}
+	}
+	return out
+}
+
 // GatewayServices returns the set of services which are referred from the proxy gateways.
 func (ps *PushContext) GatewayServices(proxy *Proxy) []*Service {
 	svcs := ps.Services(proxy)
@@ -780,6 +873,12 @@ func (ps *PushContext) getSidecarScope(proxy *Proxy, workloadLabels labels.Colle
 	return DefaultSidecarScopeForNamespace(ps, proxy.ConfigNamespace)
 }
 
+// SidecarScopeForWorkload returns the SidecarScope a sidecar workload with the given labels would get in the
+// given namespace, without requiring a connected proxy.
+func (ps *PushContext) SidecarScopeForWorkload(namespace string, workloadLabels labels.Instance) *SidecarScope {
+	return ps.getSidecarScope(&Proxy{Type: SidecarProxy, ConfigNamespace: namespace}, labels.Collection{workloadLabels})
+}
+
 // DestinationRule returns a destination rule for a service name in a given domain.
 func (ps *PushContext) DestinationRule(proxy *Proxy, service *Service) *config.Config {
 	if service == nil {
@@ -946,6 +1045,7 @@ func (ps *PushContext) InitContext(env *Environment, oldPushContext *PushContext
 
 	ps.Mesh = env.Mesh()
 	ps.LedgerVersion = env.Version()
+	ps.networkPolicies = env.NetworkPolicies
 
 	// Must be initialized first
 	// as initServiceRegistry/VirtualServices/Destrules
@@ -1010,6 +1110,8 @@ func (ps *PushContext) createNewContext(env *Environment) error {
 		return err
 	}
 
+	ps.initIneffectivePolicies()
+
 	// Must be initialized in the end
 	if err := ps.initSidecarScopes(env); err != nil {
 		return err
@@ -1077,6 +1179,11 @@ func (ps *PushContext) updateContext(
 		}
 	} else {
 		ps.virtualServiceIndex = oldPushContext.virtualServiceIndex
+		for key, reason := range oldPushContext.RejectedConfigs() {
+			if key.Kind == gvk.VirtualService {
+				ps.RejectConfig(key, reason)
+			}
+		}
 	}
 
 	if destinationRulesChanged {
@@ -1118,6 +1225,11 @@ func (ps *PushContext) updateContext(
 		}
 	} else {
 		ps.envoyFiltersByNamespace = oldPushContext.envoyFiltersByNamespace
+		for key, reason := range oldPushContext.RejectedConfigs() {
+			if key.Kind == gvk.EnvoyFilter {
+				ps.RejectConfig(key, reason)
+			}
+		}
 	}
 
 	if gatewayChanged {
@@ -1128,6 +1240,14 @@ func (ps *PushContext) updateContext(
 		ps.gatewayIndex = oldPushContext.gatewayIndex
 	}
 
+	if servicesChanged || gatewayChanged || authnChanged || authzChanged {
+		ps.initIneffectivePolicies()
+	} else {
+		for key, reason := range oldPushContext.IneffectivePolicies() {
+			ps.recordIneffectivePolicy(key, reason)
+		}
+	}
+
 	// Must be initialized in the end
 	// Sidecars need to be updated if services, virtual services, destination rules, or the sidecar configs change
 	if servicesChanged || virtualServicesChanged || destinationRulesChanged || sidecarsChanged {
@@ -1136,6 +1256,7 @@ func (ps *PushContext) updateContext(
 		}
 	} else {
 		ps.sidecarsByNamespace = oldPushContext.sidecarsByNamespace
+		ps.sidecarsByDependency = oldPushContext.sidecarsByDependency
 	}
 
 	return nil
@@ -1259,13 +1380,19 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 	// registry DNS names in the VS.  This should cut down processing in
 	// the RDS code. See separateVSHostsAndServices in route/route.go
 	sortConfigByCreationTime(vservices)
+	vservices = configuredConfigQuotas.virtualServices(ps, vservices)
 
 	// convert all shortnames in virtual services into FQDNs
 	for _, r := range vservices {
 		resolveVirtualServiceShortnames(r.Spec.(*networking.VirtualService), r.Meta)
 	}
 
-	vservices, ps.virtualServiceIndex.delegates = mergeVirtualServicesIfNeeded(vservices, ps.exportToDefaults.virtualService)
+	var delegateErrors map[ConfigKey][]string
+	vservices, ps.virtualServiceIndex.delegates, delegateErrors = mergeVirtualServicesIfNeeded(vservices, ps.exportToDefaults.virtualService)
+	for root, errs := range delegateErrors {
+		ps.AddMetric(InvalidVirtualServiceDelegation, root.String(), "", strings.Join(errs, "; "))
+		ps.RejectConfig(root, "invalid delegation: "+strings.Join(errs, "; "))
+	}
 
 	for _, virtualService := range vservices {
 		ns := virtualService.Namespace
@@ -1453,9 +1580,24 @@ func (ps *PushContext) initSidecarScopes(env *Environment) error {
 		}
 	}
 
+	ps.sidecarsByDependency = make(map[ConfigKey][]*SidecarScope)
+	for _, scopes := range ps.sidecarsByNamespace {
+		for _, sc := range scopes {
+			for _, key := range sc.ConfigDependencies() {
+				ps.sidecarsByDependency[key] = append(ps.sidecarsByDependency[key], sc)
+			}
+		}
+	}
+
 	return nil
 }
 
+// SidecarScopesDependingOn returns the precomputed sidecar scopes which depend on the given config. Scopes of
+// namespaces without services or Sidecar resources are computed on demand and are not included.
+func (ps *PushContext) SidecarScopesDependingOn(key ConfigKey) []*SidecarScope {
+	return ps.sidecarsByDependency[key]
+}
+
 // Split out of DestinationRule expensive conversions - once per push.
 func (ps *PushContext) initDestinationRules(env *Environment) error {
 	configs, err := env.List(gvk.DestinationRule, NamespaceAll)
@@ -1605,6 +1747,7 @@ func (ps *PushContext) initEnvoyFilters(env *Environment) error {
 	if err != nil {
 		return err
 	}
+	envoyFilterConfigs = configuredConfigQuotas.envoyFilters(ps, envoyFilterConfigs)
 
 	sort.SliceStable(envoyFilterConfigs, func(i, j int) bool {
 		ifilter := envoyFilterConfigs[i].Spec.(*networking.EnvoyFilter)
@@ -1878,6 +2021,11 @@ func (ps *PushContext) initNetworkManager(env *Environment) {
 	ps.networkMgr = NewNetworkManager(env)
 }
 
+// NetworkPolicies returns the evaluator for platform network policies, or nil if disabled.
+func (ps *PushContext) NetworkPolicies() NetworkPolicyEvaluator {
+	return ps.networkPolicies
+}
+
 func (ps *PushContext) NetworkManager() *NetworkManager {
 	return ps.networkMgr
 }
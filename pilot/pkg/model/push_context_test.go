@@ -819,6 +819,24 @@ func TestSidecarScope(t *testing.T) {
 		if c.sidecar != scopeToSidecar(scope) {
 			t.Errorf("case with %s should get sidecar %s but got %s", c.describe, c.sidecar, scopeToSidecar(scope))
 		}
+		preview := ps.SidecarScopeForWorkload(c.proxy.ConfigNamespace, c.collection[0])
+		if c.sidecar != scopeToSidecar(preview) {
+			t.Errorf("preview of case with %s should get sidecar %s but got %s", c.describe, c.sidecar, scopeToSidecar(preview))
+		}
+	}
+	dependents := func(name, namespace string) []string {
+		var out []string
+		for _, sc := range ps.SidecarScopesDependingOn(ConfigKey{Kind: gvk.Sidecar, Name: name, Namespace: namespace}) {
+			out = append(out, scopeToSidecar(sc))
+		}
+		sort.Strings(out)
+		return out
+	}
+	if got := dependents("foo", "default"); !reflect.DeepEqual(got, []string{"default/foo"}) {
+		t.Errorf("unexpected scopes depending on default/foo: %v", got)
+	}
+	if got := dependents("global", constants.IstioSystemNamespace); !reflect.DeepEqual(got, []string{"default/global", "istio-system/global", "nosidecar/global"}) {
+		t.Errorf("unexpected scopes depending on the root sidecar: %v", got)
 	}
 }
 
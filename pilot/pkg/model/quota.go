@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config"
+)
+
+// configQuotas bounds the configs of each namespace. Zero means unlimited.
+type configQuotas struct {
+	maxVirtualServices int
+	// maxRoutes bounds the HTTP, TLS and TCP routes of each VirtualService.
+	maxRoutes int
+	// maxWildcardHosts bounds the wildcard hosts of the VirtualServices of a namespace.
+	maxWildcardHosts int
+	maxEnvoyFilters  int
+}
+
+var configuredConfigQuotas = configQuotas{
+	maxVirtualServices: features.MaxVirtualServicesPerNamespace,
+	maxRoutes:          features.MaxRoutesPerVirtualService,
+	maxWildcardHosts:   features.MaxWildcardHostsPerNamespace,
+	maxEnvoyFilters:    features.MaxEnvoyFiltersPerNamespace,
+}
+
+// virtualServices returns the virtual services within the quotas, in order, and rejects the others. The virtual
+// services must be sorted by creation time: the oldest ones of a namespace are kept, so that creating a virtual
+// service can not disable the existing ones.
+func (q configQuotas) virtualServices(ps *PushContext, vservices []config.Config) []config.Config {
+	if q.maxVirtualServices <= 0 && q.maxRoutes <= 0 && q.maxWildcardHosts <= 0 {
+		return vservices
+	}
+	count := map[string]int{}
+	wildcards := map[string]int{}
+	out := make([]config.Config, 0, len(vservices))
+	for _, c := range vservices {
+		vs := c.Spec.(*networking.VirtualService)
+		routes := len(vs.Http) + len(vs.Tls) + len(vs.Tcp)
+		hosts := wildcardHosts(vs.Hosts)
+		reason := ""
+		switch {
+		case q.maxRoutes > 0 && routes > q.maxRoutes:
+			reason = fmt.Sprintf("%d routes exceed the quota of %d routes per VirtualService", routes, q.maxRoutes)
+		case q.maxVirtualServices > 0 && count[c.Namespace] >= q.maxVirtualServices:
+			reason = fmt.Sprintf("namespace %s exceeds its quota of %d VirtualServices", c.Namespace, q.maxVirtualServices)
+		case q.maxWildcardHosts > 0 && hosts > 0 && wildcards[c.Namespace]+hosts > q.maxWildcardHosts:
+			reason = fmt.Sprintf("namespace %s exceeds its quota of %d wildcard hosts", c.Namespace, q.maxWildcardHosts)
+		}
+		if reason != "" {
+			ps.rejectOverQuota(c, reason)
+			continue
+		}
+		count[c.Namespace]++
+		wildcards[c.Namespace] += hosts
+		out = append(out, c)
+	}
+	return out
+}
+
+// envoyFilters returns the envoy filters within the quotas, in order, and rejects the others. The oldest envoy
+// filters of a namespace are kept.
+func (q configQuotas) envoyFilters(ps *PushContext, filters []config.Config) []config.Config {
+	if q.maxEnvoyFilters <= 0 {
+		return filters
+	}
+	byAge := make([]config.Config, len(filters))
+	copy(byAge, filters)
+	sortConfigByCreationTime(byAge)
+	count := map[string]int{}
+	rejected := map[ConfigKey]struct{}{}
+	for _, c := range byAge {
+		if count[c.Namespace] >= q.maxEnvoyFilters {
+			ps.rejectOverQuota(c, fmt.Sprintf("namespace %s exceeds its quota of %d EnvoyFilters", c.Namespace, q.maxEnvoyFilters))
+			rejected[configKey(c)] = struct{}{}
+			continue
+		}
+		count[c.Namespace]++
+	}
+	if len(rejected) == 0 {
+		return filters
+	}
+	out := make([]config.Config, 0, len(filters)-len(rejected))
+	for _, c := range filters {
+		if _, f := rejected[configKey(c)]; !f {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// rejectOverQuota reports a config ignored because it exceeds the quotas of its namespace.
+func (ps *PushContext) rejectOverQuota(c config.Config, reason string) {
+	key := configKey(c)
+	ps.AddMetric(ConfigQuotaExceeded, key.String(), "", reason)
+	ps.RejectConfig(key, "quota exceeded: "+reason)
+}
+
+func configKey(c config.Config) ConfigKey {
+	return ConfigKey{Kind: c.GroupVersionKind, Name: c.Name, Namespace: c.Namespace}
+}
+
+func wildcardHosts(hosts []string) int {
+	n := 0
+	for _, h := range hosts {
+		if strings.HasPrefix(h, "*") {
+			n++
+		}
+	}
+	return n
+}
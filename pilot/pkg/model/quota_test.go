@@ -0,0 +1,124 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestConfigQuotas(t *testing.T) {
+	now := time.Now()
+	vs := func(name, ns string, age time.Duration, routes int, hosts ...string) config.Config {
+		spec := &networking.VirtualService{Hosts: hosts}
+		for i := 0; i < routes; i++ {
+			spec.Http = append(spec.Http, &networking.HTTPRoute{})
+		}
+		return config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: name, Namespace: ns, CreationTimestamp: now.Add(-age)},
+			Spec: spec,
+		}
+	}
+	ef := func(name, ns string, age time.Duration) config.Config {
+		return config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.EnvoyFilter, Name: name, Namespace: ns, CreationTimestamp: now.Add(-age)},
+			Spec: &networking.EnvoyFilter{},
+		}
+	}
+	names := func(configs []config.Config) []string {
+		out := []string{}
+		for _, c := range configs {
+			out = append(out, c.Namespace+"/"+c.Name)
+		}
+		return out
+	}
+	rejected := func(ps *PushContext) []string {
+		out := []string{}
+		for key := range ps.RejectedConfigs() {
+			out = append(out, key.Namespace+"/"+key.Name)
+		}
+		return out
+	}
+
+	cases := []struct {
+		name     string
+		quotas   configQuotas
+		configs  []config.Config
+		filter   func(configQuotas, *PushContext, []config.Config) []config.Config
+		expected []string
+		rejected []string
+	}{
+		{
+			name:     "unlimited",
+			configs:  []config.Config{vs("a", "ns", 2, 10, "*.a.com"), vs("b", "ns", 1, 10, "*.b.com")},
+			filter:   configQuotas.virtualServices,
+			expected: []string{"ns/a", "ns/b"},
+			rejected: []string{},
+		},
+		{
+			name:     "virtual services per namespace",
+			quotas:   configQuotas{maxVirtualServices: 1},
+			configs:  []config.Config{vs("a", "ns", 3, 1), vs("b", "other", 2, 1), vs("c", "ns", 1, 1)},
+			filter:   configQuotas.virtualServices,
+			expected: []string{"ns/a", "other/b"},
+			rejected: []string{"ns/c"},
+		},
+		{
+			name:     "routes per virtual service",
+			quotas:   configQuotas{maxRoutes: 2, maxVirtualServices: 1},
+			configs:  []config.Config{vs("a", "ns", 2, 3), vs("b", "ns", 1, 2)},
+			filter:   configQuotas.virtualServices,
+			expected: []string{"ns/b"},
+			rejected: []string{"ns/a"},
+		},
+		{
+			name:     "wildcard hosts per namespace",
+			quotas:   configQuotas{maxWildcardHosts: 2},
+			configs:  []config.Config{vs("a", "ns", 3, 1, "*.a.com"), vs("b", "ns", 2, 1, "*.b.com", "*.c.com"), vs("c", "ns", 1, 1, "c.com", "*")},
+			filter:   configQuotas.virtualServices,
+			expected: []string{"ns/a", "ns/c"},
+			rejected: []string{"ns/b"},
+		},
+		{
+			// envoy filters are kept in their order, but the oldest ones are kept within the quota
+			name:     "envoy filters per namespace",
+			quotas:   configQuotas{maxEnvoyFilters: 1},
+			configs:  []config.Config{ef("new", "ns", 1), ef("b", "other", 1), ef("old", "ns", 2)},
+			filter:   configQuotas.envoyFilters,
+			expected: []string{"other/b", "ns/old"},
+			rejected: []string{"ns/new"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := NewPushContext()
+			got := tt.filter(tt.quotas, ps, tt.configs)
+			if !reflect.DeepEqual(names(got), tt.expected) {
+				t.Fatalf("got %v, expected %v", names(got), tt.expected)
+			}
+			if r := rejected(ps); !reflect.DeepEqual(r, tt.rejected) {
+				t.Fatalf("got rejected %v, expected %v", r, tt.rejected)
+			}
+			if len(tt.rejected) > 0 && len(ps.ProxyStatus[ConfigQuotaExceeded.Name()]) != len(tt.rejected) {
+				t.Fatalf("expected the rejected configs to be reported in the push status, got %v", ps.ProxyStatus)
+			}
+		})
+	}
+}
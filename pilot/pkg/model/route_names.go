@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+// RouteNameScheme describes a naming scheme of the route configurations sent to proxies.
+type RouteNameScheme struct {
+	// Scheme is the format of the names, such as http.<port>.
+	Scheme string `json:"scheme"`
+	// Description describes the route configurations named with the scheme.
+	Description string `json:"description"`
+}
+
+// RouteNameSchemes lists the naming schemes of route configurations. Route configuration names are referenced by
+// EnvoyFilters and by monitoring, which silently break when they change, so these schemes are stable across
+// releases. Changing one of them requires recording the change in RouteNameAliases.
+var RouteNameSchemes = []RouteNameScheme{
+	{Scheme: "<port>", Description: "Outbound HTTP routes of a sidecar, for a port shared by multiple services"},
+	{Scheme: "<hostname>:<port>", Description: "Outbound HTTP routes of a sidecar, for a service with a dedicated listener"},
+	{Scheme: "<bind>", Description: "Outbound HTTP routes of a sidecar, for a Sidecar egress listener bound to a unix domain socket"},
+	{Scheme: RDSHttpProxy, Description: "Routes of the HTTP proxy listener of a sidecar"},
+	{Scheme: "scoped/<hostname>:<port>", Description: "Outbound virtual host of a sidecar, fetched on demand with scoped RDS"},
+	{Scheme: "http.<port>[.<bind>]", Description: "Routes of the plaintext HTTP servers of a gateway on a port"},
+	{Scheme: "https.<port>.<port name>.<gateway name>.<gateway namespace>[.<bind>]", Description: "Routes of a TLS terminated HTTPS server of a gateway"},
+}
+
+// RouteNameAlias records a change of a route configuration naming scheme.
+type RouteNameAlias struct {
+	// Old is the former naming scheme.
+	Old string `json:"old"`
+	// New is the naming scheme replacing it.
+	New string `json:"new"`
+	// Since is the release which changed the naming scheme.
+	Since string `json:"since"`
+
+	// rename returns the name of a route configuration following the new scheme, if name follows the old one.
+	rename func(name string) (string, bool)
+}
+
+// RouteNameAliases lists the changes of the naming schemes in RouteNameSchemes, oldest first. References to
+// route configurations by former names, such as in EnvoyFilters, are resolved to the current names with it.
+var RouteNameAliases []RouteNameAlias
+
+// ResolveRouteNameAlias returns the current name of a route configuration referenced by a name which may follow a
+// former naming scheme. Names following the current schemes are returned unchanged.
+func ResolveRouteNameAlias(name string) string {
+	for _, alias := range RouteNameAliases {
+		if renamed, ok := alias.rename(name); ok {
+			name = renamed
+		}
+	}
+	return name
+}
@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+)
+
+// TestGatewayRDSRouteNameStability pins the names of gateway route configurations. A failure means a naming scheme
+// changed: record the change in RouteNameAliases rather than updating the expected names.
+func TestGatewayRDSRouteNameStability(t *testing.T) {
+	gw := config.Config{Meta: config.Meta{Name: "gw", Namespace: "ns"}}
+	cases := []struct {
+		server *networking.Server
+		want   string
+	}{
+		{&networking.Server{Port: &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"}}, "http.80"},
+		{&networking.Server{Port: &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"}, Bind: "10.0.0.1"}, "http.80.10.0.0.1"},
+		{
+			&networking.Server{
+				Port: &networking.Port{Number: 443, Name: "https", Protocol: "HTTPS"},
+				Tls:  &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_SIMPLE},
+			},
+			"https.443.https.gw.ns",
+		},
+		{
+			&networking.Server{
+				Port: &networking.Port{Number: 443, Name: "tls", Protocol: "HTTPS"},
+				Tls:  &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_PASSTHROUGH},
+			},
+			"",
+		},
+	}
+	for _, tt := range cases {
+		if got := gatewayRDSRouteName(tt.server, tt.server.Port.Number, gw); got != tt.want {
+			t.Errorf("route name of %v: got %q, want %q", tt.server, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRouteNameAlias(t *testing.T) {
+	defer func(aliases []RouteNameAlias) { RouteNameAliases = aliases }(RouteNameAliases)
+	RouteNameAliases = []RouteNameAlias{
+		{
+			Old: "legacy.<port>", New: "<port>", Since: "1.0",
+			rename: func(name string) (string, bool) {
+				if !strings.HasPrefix(name, "legacy.") {
+					return "", false
+				}
+				return strings.TrimPrefix(name, "legacy."), true
+			},
+		},
+		{
+			Old: "<port>", New: "port-<port>", Since: "2.0",
+			rename: func(name string) (string, bool) {
+				if strings.Contains(name, ".") || strings.HasPrefix(name, "port-") {
+					return "", false
+				}
+				return "port-" + name, true
+			},
+		},
+	}
+	for name, want := range map[string]string{
+		// Renames are applied in order, so names from any former scheme resolve to the current one.
+		"legacy.80": "port-80",
+		"80":        "port-80",
+		"port-80":   "port-80",
+		"http.80":   "http.80",
+	} {
+		if got := ResolveRouteNameAlias(name); got != want {
+			t.Errorf("ResolveRouteNameAlias(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
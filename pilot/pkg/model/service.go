@@ -164,8 +164,54 @@ const (
 
 	// IstioCanonicalServiceRevisionLabelName is the name of label for the Istio Canonical Service revision for a workload instance.
 	IstioCanonicalServiceRevisionLabelName = "service.istio.io/canonical-revision"
+
+	// TunnelLabel is the label set by workloads whose proxy accepts tunneled traffic. Its value is a comma
+	// separated list of the supported tunnel protocols.
+	TunnelLabel = "networking.istio.io/tunnel"
+
+	// TunnelHTTP is the value of TunnelLabel for workloads accepting traffic tunneled over HTTP/2.
+	TunnelHTTP = "http"
+
+	// CaptureLabelShortname name used for the capture of an endpoint in its transport socket metadata.
+	CaptureLabelShortname = "capture"
+)
+
+// WorkloadCapture describes how the traffic sent to a workload is captured by the mesh, which determines
+// how clients connect to each of its endpoints: with plaintext, Istio mTLS or through a tunnel.
+type WorkloadCapture string
+
+const (
+	// CaptureNone is the capture of workloads outside of the mesh, which receive traffic as is.
+	CaptureNone WorkloadCapture = "none"
+
+	// CaptureSidecar is the capture of workloads whose Istio proxy accepts Istio mTLS connections.
+	CaptureSidecar WorkloadCapture = "sidecar"
+
+	// CaptureTunnel is the capture of workloads whose Istio proxy also accepts traffic tunneled over HTTP/2.
+	CaptureTunnel WorkloadCapture = "tunnel"
 )
 
+// GetWorkloadCapture returns the capture of a workload with the given tlsMode and labels.
+func GetWorkloadCapture(tlsMode string, labels map[string]string) WorkloadCapture {
+	if tlsMode != IstioMutualTLSModeLabel {
+		return CaptureNone
+	}
+	for _, tunnel := range strings.Split(labels[TunnelLabel], ",") {
+		if strings.TrimSpace(tunnel) == TunnelHTTP {
+			return CaptureTunnel
+		}
+	}
+	return CaptureSidecar
+}
+
+// TunnelAbility returns the tunnels the endpoints of a workload with this capture can be reached through.
+func (c WorkloadCapture) TunnelAbility() networking.TunnelAbility {
+	if c == CaptureTunnel {
+		return networking.MakeTunnelAbility(networking.H2Tunnel)
+	}
+	return networking.MakeTunnelAbility()
+}
+
 // Port represents a network port where a service is listening for
 // connections. The port should be annotated with the type of protocol
 // used by the port.
@@ -274,6 +320,9 @@ func WorkloadInstancesEqual(first, second *WorkloadInstance) bool {
 	if first.Endpoint.TLSMode != second.Endpoint.TLSMode {
 		return false
 	}
+	if first.Endpoint.Capture != second.Endpoint.Capture {
+		return false
+	}
 	if !first.Endpoint.Labels.Equals(second.Endpoint.Labels) {
 		return false
 	}
@@ -416,10 +465,27 @@ type IstioEndpoint struct {
 	// which are generated for h2 tunnel.
 	TunnelAbility networking.TunnelAbility
 
+	// Capture is how the traffic sent to this endpoint is captured by the mesh, computed by the registries.
+	// It determines whether clients connect to the endpoint with plaintext, Istio mTLS or through a tunnel.
+	Capture WorkloadCapture
+
 	// Determines the discoverability of this endpoint throughout the mesh.
 	DiscoverabilityPolicy EndpointDiscoverabilityPolicy `json:"-"`
 }
 
+// GetCapture returns the capture of the endpoint, computed from its tlsMode, labels and tunnel ability if not set
+// by its registry.
+func (ep *IstioEndpoint) GetCapture() WorkloadCapture {
+	if ep.Capture != "" {
+		return ep.Capture
+	}
+	capture := GetWorkloadCapture(ep.TLSMode, ep.Labels)
+	if capture == CaptureSidecar && ep.TunnelAbility.SupportH2Tunnel() {
+		return CaptureTunnel
+	}
+	return capture
+}
+
 // GetLoadBalancingWeight returns the weight for this endpoint, normalized to always be > 0.
 func (ep *IstioEndpoint) GetLoadBalancingWeight() uint32 {
 	if ep.LbWeight > 0 {
@@ -17,6 +17,7 @@ package model
 import (
 	"testing"
 
+	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 )
@@ -147,6 +148,69 @@ func TestGetLocalityOrDefault(t *testing.T) {
 	}
 }
 
+func TestGetCapture(t *testing.T) {
+	cases := []struct {
+		name     string
+		endpoint *IstioEndpoint
+		expected WorkloadCapture
+	}{
+		{
+			name:     "not in mesh",
+			endpoint: &IstioEndpoint{TLSMode: DisabledTLSModeLabel},
+			expected: CaptureNone,
+		},
+		{
+			name:     "custom tls mode",
+			endpoint: &IstioEndpoint{TLSMode: "custom"},
+			expected: CaptureNone,
+		},
+		{
+			name:     "sidecar",
+			endpoint: &IstioEndpoint{TLSMode: IstioMutualTLSModeLabel},
+			expected: CaptureSidecar,
+		},
+		{
+			name: "tunnel label",
+			endpoint: &IstioEndpoint{
+				TLSMode: IstioMutualTLSModeLabel,
+				Labels:  labels.Instance{TunnelLabel: "http3, http"},
+			},
+			expected: CaptureTunnel,
+		},
+		{
+			name: "tunnel label not in mesh",
+			endpoint: &IstioEndpoint{
+				TLSMode: DisabledTLSModeLabel,
+				Labels:  labels.Instance{TunnelLabel: TunnelHTTP},
+			},
+			expected: CaptureNone,
+		},
+		{
+			name: "tunnel ability",
+			endpoint: &IstioEndpoint{
+				TLSMode:       IstioMutualTLSModeLabel,
+				TunnelAbility: networking.MakeTunnelAbility(networking.H2Tunnel),
+			},
+			expected: CaptureTunnel,
+		},
+		{
+			name:     "set by registry",
+			endpoint: &IstioEndpoint{TLSMode: IstioMutualTLSModeLabel, Capture: CaptureNone},
+			expected: CaptureNone,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.endpoint.GetCapture(); got != tt.expected {
+				t.Errorf("got capture %q, expected %q", got, tt.expected)
+			}
+			if got, expected := tt.expected.TunnelAbility().SupportH2Tunnel(), tt.expected == CaptureTunnel; got != expected {
+				t.Errorf("got h2 tunnel support %v, expected %v", got, expected)
+			}
+		})
+	}
+}
+
 func TestWorkloadInstanceEqual(t *testing.T) {
 	exampleInstance := &WorkloadInstance{
 		Endpoint: &IstioEndpoint{
@@ -171,6 +235,8 @@ func TestWorkloadInstanceEqual(t *testing.T) {
 	differingNetwork.Endpoint.Network = "AnotherNetwork"
 	differingTLSMode := exampleInstance.DeepCopy()
 	differingTLSMode.Endpoint.TLSMode = "permitted"
+	differingCapture := exampleInstance.DeepCopy()
+	differingCapture.Endpoint.Capture = CaptureTunnel
 	differingLabels := exampleInstance.DeepCopy()
 	differingLabels.Endpoint.Labels = labels.Instance{
 		"app":         "prod-app",
@@ -222,6 +288,12 @@ func TestWorkloadInstanceEqual(t *testing.T) {
 			shouldEq: false,
 			name:     "different TLS Mode",
 		},
+		{
+			comparer: exampleInstance.DeepCopy(),
+			comparee: differingCapture.DeepCopy(),
+			shouldEq: false,
+			name:     "different Capture",
+		},
 		{
 			comparer: exampleInstance.DeepCopy(),
 			comparee: differingLabels.DeepCopy(),
@@ -107,7 +107,7 @@ type SidecarScope struct {
 	// Set of known configs this sidecar depends on.
 	// This field will be used to determine the config/resource scope
 	// which means which config changes will affect the proxies within this scope.
-	configDependencies map[uint64]struct{}
+	configDependencies map[uint64]ConfigKey
 
 	// The namespace to treat as the administrative root namespace for
 	// Istio configuration.
@@ -190,7 +190,7 @@ func DefaultSidecarScopeForNamespace(ps *PushContext, configNamespace string) *S
 		services:           defaultEgressListener.services,
 		destinationRules:   make(map[host.Name]*config.Config),
 		servicesByHostname: make(map[host.Name]*Service, len(defaultEgressListener.services)),
-		configDependencies: make(map[uint64]struct{}),
+		configDependencies: make(map[uint64]ConfigKey),
 		RootNamespace:      ps.Mesh.RootNamespace,
 		Version:            ps.PushVersion,
 	}
@@ -263,7 +263,7 @@ func ConvertToSidecarScope(ps *PushContext, sidecarConfig *config.Config, config
 		Name:               sidecarConfig.Name,
 		Namespace:          configNamespace,
 		Sidecar:            sidecar,
-		configDependencies: make(map[uint64]struct{}),
+		configDependencies: make(map[uint64]ConfigKey),
 		RootNamespace:      ps.Mesh.RootNamespace,
 		Version:            ps.PushVersion,
 	}
@@ -348,7 +348,10 @@ func ConvertToSidecarScope(ps *PushContext, sidecarConfig *config.Config, config
 				Namespace: vs.Namespace,
 			})
 
+			mirrors := virtualServiceMirrorHosts(v)
 			for _, h := range virtualServiceDestinationHosts(v) {
+				// Mirror destinations are reached on their own port, not the one of the listener.
+				matchPort := matchPort && !mirrors[h]
 				// Default to this hostname in our config namespace
 				if s, ok := ps.ServiceIndex.HostnameAndNamespace[host.Name(h)][configNamespace]; ok {
 					// This won't overwrite hostnames that have already been found eg because they were requested in hosts
@@ -569,14 +572,29 @@ func (sc *SidecarScope) AddConfigDependencies(dependencies ...ConfigKey) {
 		return
 	}
 	if sc.configDependencies == nil {
-		sc.configDependencies = make(map[uint64]struct{})
+		sc.configDependencies = make(map[uint64]ConfigKey)
 	}
 
 	for _, config := range dependencies {
-		sc.configDependencies[config.HashCode()] = struct{}{}
+		sc.configDependencies[config.HashCode()] = config
 	}
 }
 
+// ConfigDependencies returns the known configs this scope depends on, sorted.
+func (sc *SidecarScope) ConfigDependencies() []ConfigKey {
+	if sc == nil {
+		return nil
+	}
+	out := make([]ConfigKey, 0, len(sc.configDependencies))
+	for _, key := range sc.configDependencies {
+		out = append(out, key)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	return out
+}
+
 // Given a list of virtual services visible to this namespace,
 // selectVirtualServices returns the list of virtual services that are
 // applicable to this egress listener, based on the hosts field specified
@@ -15,6 +15,7 @@
 package model
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/gogo/protobuf/jsonpb"
@@ -89,10 +90,11 @@ func resolveVirtualServiceShortnames(rule *networking.VirtualService, meta confi
 	}
 }
 
-// Return merged virtual services and the root->delegate vs map
+// Return merged virtual services, the root->delegate vs map, including the delegates of delegates, and the
+// errors of the rejected delegation chains by root.
 func mergeVirtualServicesIfNeeded(
 	vServices []config.Config,
-	defaultExportTo map[visibility.Instance]bool) ([]config.Config, map[ConfigKey][]ConfigKey) {
+	defaultExportTo map[visibility.Instance]bool) ([]config.Config, map[ConfigKey][]ConfigKey, map[ConfigKey][]string) {
 	out := make([]config.Config, 0, len(vServices))
 	delegatesMap := map[string]config.Config{}
 	delegatesExportToMap := map[string]map[visibility.Instance]bool{}
@@ -135,48 +137,26 @@ func mergeVirtualServicesIfNeeded(
 	// If `PILOT_ENABLE_VIRTUAL_SERVICE_DELEGATE` feature disabled,
 	// filter out invalid vs(root or delegate), this can happen after enable -> disable
 	if !features.EnableVirtualServiceDelegate {
-		return out, nil
+		return out, nil, nil
 	}
 
 	delegatesByRoot := make(map[ConfigKey][]ConfigKey, len(rootVses))
+	delegateErrors := map[ConfigKey][]string{}
 
 	// 2. merge delegates and root
 	for _, root := range rootVses {
 		rootConfigKey := ConfigKey{Kind: gvk.VirtualService, Name: root.Name, Namespace: root.Namespace}
 		rootVs := root.Spec.(*networking.VirtualService)
+		r := &delegateResolver{delegatesMap: delegatesMap, delegatesExportToMap: delegatesExportToMap}
 		mergedRoutes := []*networking.HTTPRoute{}
 		for _, route := range rootVs.Http {
-			// it is root vs with delegate
-			if delegate := route.Delegate; delegate != nil {
-				delegateNamespace := delegate.Namespace
-				if delegateNamespace == "" {
-					delegateNamespace = root.Namespace
-				}
-				delegateConfigKey := ConfigKey{Kind: gvk.VirtualService, Name: delegate.Name, Namespace: delegateNamespace}
-				delegatesByRoot[rootConfigKey] = append(delegatesByRoot[rootConfigKey], delegateConfigKey)
-				delegateVS, ok := delegatesMap[key(delegate.Name, delegateNamespace)]
-				if !ok {
-					log.Debugf("delegate virtual service %s/%s of %s/%s not found",
-						delegateNamespace, delegate.Name, root.Namespace, root.Name)
-					// delegate not found, ignore only the current HTTP route
-					continue
-				}
-				// make sure that the delegate is visible to root virtual service's namespace
-				exportTo := delegatesExportToMap[key(delegate.Name, delegateNamespace)]
-				if !exportTo[visibility.Public] && !exportTo[visibility.Instance(root.Namespace)] {
-					log.Debugf("delegate virtual service %s/%s of %s/%s is not exported to %s",
-						delegateNamespace, delegate.Name, root.Namespace, root.Name, root.Namespace)
-					continue
-				}
-				// DeepCopy to prevent mutate the original delegate, it can conflict
-				// when multiple routes delegate to one single VS.
-				copiedDelegate := delegateVS.DeepCopy()
-				vs := copiedDelegate.Spec.(*networking.VirtualService)
-				merged := mergeHTTPRoutes(route, vs.Http)
-				mergedRoutes = append(mergedRoutes, merged...)
-			} else {
-				mergedRoutes = append(mergedRoutes, route)
-			}
+			mergedRoutes = append(mergedRoutes, r.resolve(route, []ConfigKey{rootConfigKey})...)
+		}
+		if len(r.delegates) > 0 {
+			delegatesByRoot[rootConfigKey] = r.delegates
+		}
+		if len(r.errors) > 0 {
+			delegateErrors[rootConfigKey] = r.errors
 		}
 		rootVs.Http = mergedRoutes
 		if log.DebugEnabled() {
@@ -187,7 +167,79 @@ func mergeVirtualServicesIfNeeded(
 		out = append(out, root)
 	}
 
-	return out, delegatesByRoot
+	return out, delegatesByRoot, delegateErrors
+}
+
+// delegateResolver expands the delegated HTTP routes of a root virtual service.
+type delegateResolver struct {
+	delegatesMap         map[string]config.Config
+	delegatesExportToMap map[string]map[visibility.Instance]bool
+
+	// delegates referenced from the root, directly or through other delegates.
+	delegates []ConfigKey
+	// errors of the rejected delegation chains.
+	errors []string
+}
+
+// resolve returns the routes an HTTP route of the last virtual service of chain expands to. Delegation is
+// followed up to features.VirtualServiceDelegateMaxDepth levels; routes of chains which are too deep or
+// form a cycle are dropped.
+func (r *delegateResolver) resolve(route *networking.HTTPRoute, chain []ConfigKey) []*networking.HTTPRoute {
+	delegate := route.Delegate
+	if delegate == nil {
+		return []*networking.HTTPRoute{route}
+	}
+	parent := chain[len(chain)-1]
+	delegateNamespace := delegate.Namespace
+	if delegateNamespace == "" {
+		delegateNamespace = parent.Namespace
+	}
+	delegateConfigKey := ConfigKey{Kind: gvk.VirtualService, Name: delegate.Name, Namespace: delegateNamespace}
+	r.delegates = append(r.delegates, delegateConfigKey)
+	for _, k := range chain {
+		if k == delegateConfigKey {
+			r.errors = append(r.errors, fmt.Sprintf("delegation cycle: %s -> %s/%s",
+				chainString(chain), delegateNamespace, delegate.Name))
+			return nil
+		}
+	}
+	if len(chain) > features.VirtualServiceDelegateMaxDepth {
+		r.errors = append(r.errors, fmt.Sprintf("delegation deeper than %d levels: %s -> %s/%s",
+			features.VirtualServiceDelegateMaxDepth, chainString(chain), delegateNamespace, delegate.Name))
+		return nil
+	}
+	delegateVS, ok := r.delegatesMap[key(delegate.Name, delegateNamespace)]
+	if !ok {
+		log.Debugf("delegate virtual service %s/%s of %s/%s not found",
+			delegateNamespace, delegate.Name, parent.Namespace, parent.Name)
+		// delegate not found, ignore only the current HTTP route
+		return nil
+	}
+	// make sure that the delegate is visible to the delegating virtual service's namespace
+	exportTo := r.delegatesExportToMap[key(delegate.Name, delegateNamespace)]
+	if !exportTo[visibility.Public] && !exportTo[visibility.Instance(parent.Namespace)] {
+		log.Debugf("delegate virtual service %s/%s of %s/%s is not exported to %s",
+			delegateNamespace, delegate.Name, parent.Namespace, parent.Name, parent.Namespace)
+		return nil
+	}
+	// DeepCopy to prevent mutate the original delegate, it can conflict
+	// when multiple routes delegate to one single VS.
+	copiedDelegate := delegateVS.DeepCopy()
+	vs := copiedDelegate.Spec.(*networking.VirtualService)
+	next := append(append(make([]ConfigKey, 0, len(chain)+1), chain...), delegateConfigKey)
+	var out []*networking.HTTPRoute
+	for _, merged := range mergeHTTPRoutes(route, vs.Http) {
+		out = append(out, r.resolve(merged, next)...)
+	}
+	return out
+}
+
+func chainString(chain []ConfigKey) string {
+	names := make([]string, 0, len(chain))
+	for _, k := range chain {
+		names = append(names, k.Namespace+"/"+k.Name)
+	}
+	return strings.Join(names, " -> ")
 }
 
 // merge root's route with delegate's and the merged route number equals the delegate's.
@@ -17,6 +17,7 @@ package model
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gogo/protobuf/types"
@@ -24,8 +25,10 @@ import (
 	fuzz "github.com/google/gofuzz"
 
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
 )
 
@@ -672,7 +675,7 @@ func TestMergeVirtualServices(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, _ := mergeVirtualServicesIfNeeded(tc.virtualServices, map[visibility.Instance]bool{visibility.Public: true})
+			got, _, _ := mergeVirtualServicesIfNeeded(tc.virtualServices, map[visibility.Instance]bool{visibility.Public: true})
 			if !reflect.DeepEqual(got, tc.expectedVirtualServices) {
 				t.Errorf("expected vs %v, but got %v,\n diff: %s ", len(tc.expectedVirtualServices), len(got), cmp.Diff(tc.expectedVirtualServices, got))
 			}
@@ -680,6 +683,79 @@ func TestMergeVirtualServices(t *testing.T) {
 	}
 }
 
+func TestMergeVirtualServicesDelegateChains(t *testing.T) {
+	vs := func(name string, hosts []string, routes ...*networking.HTTPRoute) config.Config {
+		return config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind(),
+				Name:             name,
+				Namespace:        "default",
+			},
+			Spec: &networking.VirtualService{Hosts: hosts, Http: routes},
+		}
+	}
+	delegateTo := func(prefix, name string) *networking.HTTPRoute {
+		return &networking.HTTPRoute{
+			Match: []*networking.HTTPMatchRequest{{
+				Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: prefix}},
+			}},
+			Delegate: &networking.Delegate{Name: name},
+		}
+	}
+	routeTo := func(prefix, dest string) *networking.HTTPRoute {
+		return &networking.HTTPRoute{
+			Match: []*networking.HTTPMatchRequest{{
+				Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: prefix}},
+			}},
+			Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: dest}}},
+		}
+	}
+	newVirtualServices := func() []config.Config {
+		return []config.Config{
+			vs("root", []string{"example.org"}, delegateTo("/a", "level1"), delegateTo("/cycle", "cycle1")),
+			vs("level1", nil, delegateTo("/a/b", "level2")),
+			vs("level2", nil, routeTo("/a/b/c", "c.example.org")),
+			vs("cycle1", nil, delegateTo("/cycle", "cycle2")),
+			vs("cycle2", nil, delegateTo("/cycle", "cycle1")),
+		}
+	}
+	rootKey := ConfigKey{Kind: gvk.VirtualService, Name: "root", Namespace: "default"}
+	level2Key := ConfigKey{Kind: gvk.VirtualService, Name: "level2", Namespace: "default"}
+	hasKey := func(keys []ConfigKey, key ConfigKey) bool {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+		return false
+	}
+
+	defer func(depth int) { features.VirtualServiceDelegateMaxDepth = depth }(features.VirtualServiceDelegateMaxDepth)
+
+	features.VirtualServiceDelegateMaxDepth = 1
+	out, _, errs := mergeVirtualServicesIfNeeded(newVirtualServices(), map[visibility.Instance]bool{visibility.Public: true})
+	if len(out) != 1 || len(out[0].Spec.(*networking.VirtualService).Http) != 0 {
+		t.Fatalf("expected nested delegation to be dropped, got %v", out)
+	}
+	if len(errs[rootKey]) != 2 {
+		t.Fatalf("expected depth errors for both chains, got %v", errs)
+	}
+
+	features.VirtualServiceDelegateMaxDepth = 3
+	out, delegates, errs := mergeVirtualServicesIfNeeded(newVirtualServices(), map[visibility.Instance]bool{visibility.Public: true})
+	routes := out[0].Spec.(*networking.VirtualService).Http
+	if len(routes) != 1 || routes[0].Route[0].Destination.Host != "c.example.org" ||
+		routes[0].Match[0].Uri.GetPrefix() != "/a/b/c" || routes[0].Delegate != nil {
+		t.Fatalf("unexpected merged routes %v", routes)
+	}
+	if !hasKey(delegates[rootKey], level2Key) {
+		t.Fatalf("expected indirect delegate to be a dependency of the root, got %v", delegates[rootKey])
+	}
+	if len(errs[rootKey]) != 1 || !strings.Contains(errs[rootKey][0], "cycle") {
+		t.Fatalf("expected a cycle error, got %v", errs)
+	}
+}
+
 func TestMergeHttpRoutes(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -16,6 +16,7 @@ package model
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -38,10 +39,13 @@ func init() {
 }
 
 var (
+	// cacheTag is the type of the entries read, such as cds or eds.
+	cacheTag = monitoring.MustCreateLabel("cache")
+
 	xdsCacheReads = monitoring.NewSum(
 		"xds_cache_reads",
 		"Total number of xds cache xdsCacheReads.",
-		monitoring.WithLabels(typeTag),
+		monitoring.WithLabels(typeTag, cacheTag),
 	)
 
 	xdsCacheEvictions = monitoring.NewSum(
@@ -53,21 +57,26 @@ var (
 		"xds_cache_size",
 		"Current size of xds cache",
 	)
-
-	xdsCacheHits   = xdsCacheReads.With(typeTag.Value("hit"))
-	xdsCacheMisses = xdsCacheReads.With(typeTag.Value("miss"))
 )
 
-func hit() {
+func hit(cacheType string) {
 	if features.EnableXDSCacheMetrics {
-		xdsCacheHits.Increment()
+		xdsCacheReads.With(typeTag.Value("hit"), cacheTag.Value(cacheType)).Increment()
 	}
 }
 
-func miss() {
+func miss(cacheType string) {
 	if features.EnableXDSCacheMetrics {
-		xdsCacheMisses.Increment()
+		xdsCacheReads.With(typeTag.Value("miss"), cacheTag.Value(cacheType)).Increment()
+	}
+}
+
+// cacheTypeOf returns the type of an entry from its key, which is prefixed with the type, as in cds://.
+func cacheTypeOf(key string) string {
+	if i := strings.Index(key, "://"); i > 0 {
+		return key[:i]
 	}
+	return "unknown"
 }
 
 func evict(k interface{}, v interface{}) {
@@ -147,6 +156,14 @@ type XdsCache interface {
 	Keys() []string
 	// Snapshot returns a snapshot of all keys and values. This is for testing/debug only
 	Snapshot() map[string]*discovery.Resource
+	// Stats returns the reads of the cache by type of entry. This is for testing/debug only
+	Stats() map[string]CacheStats
+}
+
+// CacheStats counts the reads of the entries of a type, such as cds or eds.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
 }
 
 // NewXdsCache returns an instance of a cache.
@@ -157,6 +174,7 @@ func NewXdsCache() XdsCache {
 		configIndex:      map[ConfigKey]sets.Set{},
 		typesIndex:       map[config.GroupVersionKind]sets.Set{},
 		nextToken:        atomic.NewUint64(0),
+		stats:            map[string]*CacheStats{},
 	}
 }
 
@@ -168,6 +186,7 @@ func NewLenientXdsCache() XdsCache {
 		configIndex:      map[ConfigKey]sets.Set{},
 		typesIndex:       map[config.GroupVersionKind]sets.Set{},
 		nextToken:        atomic.NewUint64(0),
+		stats:            map[string]*CacheStats{},
 	}
 }
 
@@ -180,6 +199,8 @@ type lruCache struct {
 	mu          sync.RWMutex
 	configIndex map[ConfigKey]sets.Set
 	typesIndex  map[config.GroupVersionKind]sets.Set
+	// stats counts the reads by type of entry. They are not reset when the cache is cleared.
+	stats map[string]*CacheStats
 }
 
 var _ XdsCache = &lruCache{}
@@ -269,9 +290,12 @@ func (l *lruCache) Get(entry XdsCacheEntry) (*discovery.Resource, CacheToken, bo
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	k := entry.Key()
+	t := cacheTypeOf(k)
+	stats := l.statsFor(t)
 	val, ok := l.store.Get(k)
 	if !ok {
-		miss()
+		stats.Misses++
+		miss(t)
 		// If the entry is not found at all, this is our first read of it. We will generate and store
 		// a new token. Subsequent writes must include it.
 		tok := CacheToken(l.nextToken.Inc())
@@ -282,15 +306,27 @@ func (l *lruCache) Get(entry XdsCacheEntry) (*discovery.Resource, CacheToken, bo
 	}
 	cv := val.(cacheValue)
 	if cv.value == nil {
-		miss()
+		stats.Misses++
+		miss(t)
 		// We have generated a token previously, so return that, but this is still a cache miss as
 		// no value is stored.
 		return nil, cv.token, false
 	}
-	hit()
+	stats.Hits++
+	hit(t)
 	return cv.value, cv.token, true
 }
 
+// statsFor returns the stats of a type of entry. The lock must be held.
+func (l *lruCache) statsFor(t string) *CacheStats {
+	stats, f := l.stats[t]
+	if !f {
+		stats = &CacheStats{}
+		l.stats[t] = stats
+	}
+	return stats
+}
+
 func (l *lruCache) Clear(configs map[ConfigKey]struct{}) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -329,6 +365,16 @@ func (l *lruCache) Keys() []string {
 	return keys
 }
 
+func (l *lruCache) Stats() map[string]CacheStats {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	res := make(map[string]CacheStats, len(l.stats))
+	for t, stats := range l.stats {
+		res[t] = *stats
+	}
+	return res
+}
+
 func (l *lruCache) Snapshot() map[string]*discovery.Resource {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
@@ -363,3 +409,5 @@ func (d DisabledCache) ClearAll() {}
 func (d DisabledCache) Keys() []string { return nil }
 
 func (d DisabledCache) Snapshot() map[string]*discovery.Resource { return nil }
+
+func (d DisabledCache) Stats() map[string]CacheStats { return nil }
@@ -0,0 +1,182 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"sort"
+	"sync"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+)
+
+// TenantXdsCache partitions an XdsCache by tenant, such as the revision or the namespace of the proxies, so that
+// the entries generated for the proxies of one tenant cannot evict those of another. Each tenant has its own cache,
+// created on first use. The entries added or read without proxy, through the XdsCache interface, go to the cache of
+// the default tenant; clearing the cache clears all the tenants, since the configs are shared.
+type TenantXdsCache struct {
+	// tenantOf returns the tenant of a proxy.
+	tenantOf func(proxy *Proxy) string
+	newCache func() XdsCache
+
+	mu     sync.RWMutex
+	caches map[string]XdsCache
+}
+
+var _ XdsCache = &TenantXdsCache{}
+
+// DefaultTenant is the tenant of the entries added without proxy.
+const DefaultTenant = "default"
+
+// NewTenantXdsCache returns a cache partitioned by the tenant of the proxies, creating the cache of each tenant with
+// newCache.
+func NewTenantXdsCache(tenantOf func(proxy *Proxy) string, newCache func() XdsCache) *TenantXdsCache {
+	return &TenantXdsCache{
+		tenantOf: tenantOf,
+		newCache: newCache,
+		caches:   map[string]XdsCache{DefaultTenant: newCache()},
+	}
+}
+
+// ForProxy returns the cache of the tenant of the proxy.
+func (t *TenantXdsCache) ForProxy(proxy *Proxy) XdsCache {
+	return t.ForTenant(t.tenantOf(proxy))
+}
+
+// ForTenant returns the cache of the tenant, creating it if needed.
+func (t *TenantXdsCache) ForTenant(tenant string) XdsCache {
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	t.mu.RLock()
+	c, f := t.caches[tenant]
+	t.mu.RUnlock()
+	if f {
+		return c
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, f := t.caches[tenant]; f {
+		return c
+	}
+	c = t.newCache()
+	t.caches[tenant] = c
+	return c
+}
+
+// Remove drops the cache of a tenant, such as a namespace which no longer has proxies. The cache of the default
+// tenant is kept.
+func (t *TenantXdsCache) Remove(tenant string) {
+	if tenant == DefaultTenant {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.caches, tenant)
+}
+
+// Tenants returns the sorted tenants having a cache.
+func (t *TenantXdsCache) Tenants() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	out := make([]string, 0, len(t.caches))
+	for tenant := range t.caches {
+		out = append(out, tenant)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (t *TenantXdsCache) all() []XdsCache {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	out := make([]XdsCache, 0, len(t.caches))
+	for _, c := range t.caches {
+		out = append(out, c)
+	}
+	return out
+}
+
+func (t *TenantXdsCache) Add(entry XdsCacheEntry, token CacheToken, value *discovery.Resource) {
+	t.ForTenant(DefaultTenant).Add(entry, token, value)
+}
+
+func (t *TenantXdsCache) Get(entry XdsCacheEntry) (*discovery.Resource, CacheToken, bool) {
+	return t.ForTenant(DefaultTenant).Get(entry)
+}
+
+func (t *TenantXdsCache) Clear(configs map[ConfigKey]struct{}) {
+	for _, c := range t.all() {
+		c.Clear(configs)
+	}
+}
+
+func (t *TenantXdsCache) ClearAll() {
+	for _, c := range t.all() {
+		c.ClearAll()
+	}
+}
+
+// Keys returns the keys of all the tenants. The keys of the tenants other than the default one are prefixed with
+// the tenant.
+func (t *TenantXdsCache) Keys() []string {
+	var out []string
+	for _, tenant := range t.Tenants() {
+		for _, k := range t.ForTenant(tenant).Keys() {
+			out = append(out, tenantKey(tenant, k))
+		}
+	}
+	return out
+}
+
+// Snapshot returns the entries of all the tenants, keyed as by Keys.
+func (t *TenantXdsCache) Snapshot() map[string]*discovery.Resource {
+	out := map[string]*discovery.Resource{}
+	for _, tenant := range t.Tenants() {
+		for k, v := range t.ForTenant(tenant).Snapshot() {
+			out[tenantKey(tenant, k)] = v
+		}
+	}
+	return out
+}
+
+// Stats returns the reads of all the tenants.
+func (t *TenantXdsCache) Stats() map[string]CacheStats {
+	out := map[string]CacheStats{}
+	for _, c := range t.all() {
+		for typ, stats := range c.Stats() {
+			cur := out[typ]
+			cur.Hits += stats.Hits
+			cur.Misses += stats.Misses
+			out[typ] = cur
+		}
+	}
+	return out
+}
+
+func tenantKey(tenant, key string) string {
+	if tenant == DefaultTenant {
+		return key
+	}
+	return tenant + "/" + key
+}
+
+// CacheForProxy returns the cache of the tenant of the proxy if the cache is partitioned by tenant, or the cache
+// otherwise.
+func CacheForProxy(cache XdsCache, proxy *Proxy) XdsCache {
+	if t, ok := cache.(*TenantXdsCache); ok && proxy != nil {
+		return t.ForProxy(proxy)
+	}
+	return cache
+}
@@ -40,6 +40,12 @@ type ConfigGenerator interface {
 	// BuildHTTPRoutes returns the list of HTTP routes for the given proxy. This is the RDS output
 	BuildHTTPRoutes(node *model.Proxy, push *model.PushContext, routeNames []string) []*route.RouteConfiguration
 
+	// BuildScopedRoutes returns the list of route scopes fetched on demand by the given proxy. This is the SRDS output
+	BuildScopedRoutes(node *model.Proxy, push *model.PushContext) []*route.ScopedRouteConfiguration
+
+	// BuildVirtualHosts returns the list of virtual hosts requested on demand by the given proxy. This is the VHDS output
+	BuildVirtualHosts(node *model.Proxy, push *model.PushContext, resourceNames []string) []*discovery.Resource
+
 	// BuildNameTable returns list of hostnames and the associated IPs
 	BuildNameTable(node *model.Proxy, push *model.PushContext) *dnsProto.NameTable
 
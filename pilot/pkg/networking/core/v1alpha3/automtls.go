@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/labels"
+)
+
+// AutoMtlsExplanation explains the TLS settings of an outbound cluster, and why auto mTLS chose them.
+type AutoMtlsExplanation struct {
+	Cluster string `json:"cluster"`
+	// Type is the discovery type of the cluster.
+	Type string `json:"type"`
+	// Mode is the TLS mode of the cluster: ISTIO_MUTUAL, DISABLE for plaintext, or the mode set by a DestinationRule.
+	Mode string `json:"mode"`
+	// PerEndpoint is set if each endpoint selects Mode or plaintext with its EDS metadata.
+	PerEndpoint bool `json:"perEndpoint,omitempty"`
+	// DestinationRule is the DestinationRule applying to the cluster, as <namespace>/<name>.
+	DestinationRule string `json:"destinationRule,omitempty"`
+	// ServiceMTLSMode is the mTLS mode inferred for the service from the PeerAuthentications and its instances.
+	ServiceMTLSMode string `json:"serviceMTLSMode"`
+	// PeerAuthentications lists the namespace and mesh wide PeerAuthentications the service mTLS mode is inferred
+	// from, as <namespace>/<name>.
+	PeerAuthentications []string `json:"peerAuthentications,omitempty"`
+	// InstancesWithoutSidecar lists the instances of a passthrough cluster without a sidecar, which disable mTLS
+	// for all of them.
+	InstancesWithoutSidecar []string `json:"instancesWithoutSidecar,omitempty"`
+	Reason                  string   `json:"reason"`
+}
+
+// ExplainAutoMtls returns the TLS settings of the outbound cluster of the proxy for the port and subset of the service,
+// following the decisions of the cluster builder, and why they were chosen.
+func ExplainAutoMtls(proxy *model.Proxy, push *model.PushContext, service *model.Service, port *model.Port, subset string) *AutoMtlsExplanation {
+	out := &AutoMtlsExplanation{
+		Cluster: model.BuildSubsetKey(model.TrafficDirectionOutbound, subset, service.Hostname, port.Port),
+	}
+	destRule := push.DestinationRule(proxy, service)
+	destinationRule := castDestinationRule(destRule)
+	if destRule != nil {
+		out.DestinationRule = destRule.Namespace + "/" + destRule.Name
+	}
+	policy := MergeTrafficPolicy(nil, destinationRule.GetTrafficPolicy(), port)
+	serviceMTLSMode := push.BestEffortInferServiceMTLSMode(destinationRule.GetTrafficPolicy(), service, port)
+	if subset != "" {
+		for _, s := range destinationRule.GetSubsets() {
+			if s.Name == subset {
+				serviceMTLSMode = push.BestEffortInferServiceMTLSMode(s.GetTrafficPolicy(), service, port)
+				policy = MergeTrafficPolicy(policy, s.TrafficPolicy, port)
+				break
+			}
+		}
+	}
+	out.ServiceMTLSMode = serviceMTLSMode.String()
+	for _, pa := range push.AuthnPolicies.GetPeerAuthenticationsForWorkload(service.Attributes.Namespace, labels.Collection{}) {
+		out.PeerAuthentications = append(out.PeerAuthentications, pa.Namespace+"/"+pa.Name)
+	}
+
+	clusterType := convertResolution(proxy, service)
+	if policy.GetLoadBalancer().GetSimple() == networking.LoadBalancerSettings_PASSTHROUGH {
+		clusterType = cluster.Cluster_ORIGINAL_DST
+	}
+	out.Type = clusterType.String()
+	if clusterType == cluster.Cluster_ORIGINAL_DST {
+		for _, instance := range push.ServiceInstancesByPort(service, port.Port, nil) {
+			if instance.Endpoint.TLSMode == model.DisabledTLSModeLabel {
+				out.InstancesWithoutSidecar = append(out.InstancesWithoutSidecar, instance.Endpoint.Address)
+			}
+		}
+	}
+
+	_, _, _, tls := selectTrafficPolicyComponents(policy)
+	autoMTLSEnabled := push.Mesh.GetEnableAutoMtls().GetValue()
+	settings, mtlsCtxType := buildAutoMtlsSettings(tls, nil, "", proxy, autoMTLSEnabled, service.MeshExternal,
+		autoMtlsServiceMode(serviceMTLSMode, clusterType))
+	switch {
+	case settings == nil:
+		out.Mode = networking.ClientTLSSettings_DISABLE.String()
+		out.Reason = plaintextReason(out, service, autoMTLSEnabled, serviceMTLSMode)
+	case mtlsCtxType == userSupplied:
+		out.Mode = settings.Mode.String()
+		out.Reason = fmt.Sprintf("the TLS mode is set by the DestinationRule %s", out.DestinationRule)
+	default:
+		out.Mode = settings.Mode.String()
+		if clusterType == cluster.Cluster_ORIGINAL_DST {
+			out.Reason = "auto mTLS is used for all the instances of the passthrough cluster, which all have a sidecar"
+		} else {
+			out.PerEndpoint = true
+			out.Reason = fmt.Sprintf("auto mTLS is used for the endpoints labeled with tlsMode %s, unless a PeerAuthentication "+
+				"or DestinationRule disables it for them; the other endpoints are sent plaintext", model.IstioMutualTLSModeLabel)
+		}
+	}
+	return out
+}
+
+// plaintextReason returns why auto mTLS did not use mTLS for a cluster, in the order buildAutoMtlsSettings checks it.
+func plaintextReason(out *AutoMtlsExplanation, service *model.Service, autoMTLSEnabled bool, serviceMTLSMode model.MutualTLSMode) string {
+	switch {
+	case service.MeshExternal:
+		return "the service is outside of the mesh (MESH_EXTERNAL)"
+	case !autoMTLSEnabled:
+		return "auto mTLS is disabled by enableAutoMtls in the mesh config, and no DestinationRule enables mTLS"
+	case len(out.InstancesWithoutSidecar) > 0:
+		return "the cluster is passthrough and some of its instances have no sidecar"
+	case serviceMTLSMode == model.MTLSDisable && out.Type == cluster.Cluster_ORIGINAL_DST.String():
+		return "the cluster is passthrough and either has no instances or a PeerAuthentication disables mTLS"
+	case serviceMTLSMode == model.MTLSDisable && !features.EnablePerEndpointAutoMtls:
+		return "a PeerAuthentication disables mTLS for the namespace of the service"
+	default:
+		return fmt.Sprintf("auto mTLS is not used for %s clusters with the service mTLS mode %s", out.Type, serviceMTLSMode.String())
+	}
+}
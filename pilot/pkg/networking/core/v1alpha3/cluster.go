@@ -17,6 +17,7 @@ package v1alpha3
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -38,6 +39,7 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/util/gogo"
 )
 
@@ -75,7 +77,7 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, push *mo
 	clusters := make([]*cluster.Cluster, 0)
 	resources := model.Resources{}
 	envoyFilterPatches := push.EnvoyFilters(proxy)
-	cb := NewClusterBuilder(proxy, push, configgen.Cache)
+	cb := NewClusterBuilder(proxy, push, model.CacheForProxy(configgen.Cache, proxy))
 	instances := proxy.ServiceInstances
 	cacheStats := cacheStats{}
 	switch proxy.Type {
@@ -134,18 +136,34 @@ func (c cacheStats) merge(other cacheStats) cacheStats {
 	}
 }
 
-func buildClusterKey(service *model.Service, port *model.Port, cb *ClusterBuilder) *clusterCache {
+func buildClusterKey(service *model.Service, port *model.Port, cb *ClusterBuilder, envoyFilterKeys []model.ConfigKey) *clusterCache {
 	clusterName := model.BuildSubsetKey(model.TrafficDirectionOutbound, "", service.Hostname, port.Port)
 	clusterKey := &clusterCache{
 		clusterName:     clusterName,
 		service:         service,
 		networkView:     cb.proxy.GetNetworkView(),
-		pushVersion:     cb.push.PushVersion,
 		destinationRule: cb.push.DestinationRule(cb.proxy, service),
 		locality:        cb.proxy.Locality,
 		proxySidecar:    cb.proxy.Type == model.SidecarProxy,
+		supportsIPv4:    cb.proxy.SupportsIPv4(),
+		supportsIPv6:    cb.proxy.SupportsIPv6(),
 		http2:           port.Protocol.IsHTTP2(),
 		downstreamAuto:  cb.proxy.Type == model.SidecarProxy && util.IsProtocolSniffingEnabledForOutboundPort(port),
+		envoyFilterKeys: envoyFilterKeys,
+	}
+	if cb.proxy.Metadata != nil {
+		clusterKey.proxyClusterID = cb.proxy.Metadata.ClusterID.String()
+		clusterKey.proxyVersion = cb.proxy.Metadata.IstioVersion
+	}
+	if scope := cb.proxy.SidecarScope; scope != nil && scope.Sidecar != nil {
+		clusterKey.sidecar = &model.ConfigKey{Kind: gvk.Sidecar, Name: scope.Name, Namespace: scope.Namespace}
+	}
+	if cb.push.AuthnPolicies != nil {
+		clusterKey.peerAuthVersion = cb.push.AuthnPolicies.AggregateVersion
+	}
+	if sas := cb.push.ServiceAccounts[service.Hostname][port.Port]; len(sas) > 0 {
+		clusterKey.serviceAccounts = append([]string{}, sas...)
+		sort.Strings(clusterKey.serviceAccounts)
 	}
 	return clusterKey
 }
@@ -160,12 +178,14 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(cb *ClusterBuilder,
 	} else {
 		services = cb.push.Services(cb.proxy)
 	}
+	// The cached clusters are patched, so they depend on the EnvoyFilters patching the clusters of the proxy.
+	envoyFilterKeys := cp.efw.KeysApplyingTo(networking.EnvoyFilter_CLUSTER)
 	for _, service := range services {
 		for _, port := range service.Ports {
 			if port.Protocol == protocol.UDP {
 				continue
 			}
-			clusterKey := buildClusterKey(service, port, cb)
+			clusterKey := buildClusterKey(service, port, cb, envoyFilterKeys)
 			cached, tokens, allFound := cb.getAllCachedSubsetClusters(*clusterKey)
 			if allFound && !features.EnableUnsafeAssertions {
 				hit += len(cached)
@@ -322,6 +342,7 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusters(cb *ClusterBuilder, i
 	sidecarScope := cb.proxy.SidecarScope
 	noneMode := cb.proxy.GetInterceptionMode() == model.InterceptionNone
 
+	tproxy := cb.proxy.GetInboundInterceptionMode() == model.InterceptionTproxy
 	_, actualLocalHost := getActualWildcardAndLocalHost(cb.proxy)
 
 	// No user supplied sidecar scope or the user supplied one has no ingress listeners
@@ -334,17 +355,24 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusters(cb *ClusterBuilder, i
 		}
 
 		clustersToBuild := make(map[int][]*model.ServiceInstance)
+		intercepted := cb.proxy.InboundInterception()
 		for _, instance := range instances {
 			// For service instances with the same port,
 			// we still need to capture all the instances on this port, as its required to populate telemetry metadata
 			// The first instance will be used as the "primary" instance; this means if we have an conflicts between
 			// Services the first one wins
 			ep := int(instance.Endpoint.EndpointPort)
+			// Like listeners, no cluster is needed for ports which are not intercepted.
+			if !intercepted.Captures(ep) {
+				continue
+			}
 			clustersToBuild[ep] = append(clustersToBuild[ep], instance)
 		}
 
 		bind := actualLocalHost
-		if features.EnableInboundPassthrough {
+		// With TPROXY the source address of inbound connections is preserved, and connections from remote
+		// addresses cannot reach the application on localhost.
+		if features.EnableInboundPassthrough || tproxy {
 			bind = ""
 		}
 		// For each workload port, we will construct a cluster
@@ -394,6 +422,11 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusters(cb *ClusterBuilder, i
 				endpointAddress = cb.proxy.IPAddresses[0]
 			} else if parts[0] == model.LocalhostAddressPrefix {
 				endpointAddress = actualLocalHost
+				if tproxy {
+					// Inbound connections keep their source address with TPROXY, which localhost does not
+					// accept; send them to the pod IP instead.
+					endpointAddress = cb.proxy.IPAddresses[0]
+				}
 			}
 		}
 
@@ -520,6 +553,16 @@ func buildAutoMtlsSettings(
 	return buildIstioMutualTLS(serviceAccounts, sni), autoDetected
 }
 
+// autoMtlsServiceMode returns the service mTLS mode auto mTLS uses for a cluster of the given type.
+func autoMtlsServiceMode(serviceMTLSMode model.MutualTLSMode, clusterType cluster.Cluster_DiscoveryType) model.MutualTLSMode {
+	if serviceMTLSMode == model.MTLSDisable && features.EnablePerEndpointAutoMtls && clusterType == cluster.Cluster_EDS {
+		// EDS removes the Istio mTLS metadata of the endpoints with mTLS disabled, so that they select the
+		// plaintext transport socket while the endpoints overriding the mode of the namespace are still sent mTLS.
+		return model.MTLSPermissive
+	}
+	return serviceMTLSMode
+}
+
 func hasMetadataCerts(m *model.NodeMetadata) bool {
 	return m.TLSClientRootCert != "" || m.TLSServerRootCert != ""
 }
@@ -594,6 +637,8 @@ type buildClusterOpts struct {
 	// Indicates the service registry of the cluster being built.
 	serviceRegistry provider.ID
 	cache           model.XdsCache
+	// tlsTuning is the TLS tuning set by the annotations of the DestinationRule of the cluster.
+	tlsTuning upstreamTLSTuning
 }
 
 type upgradeTuple struct {
@@ -178,6 +178,7 @@ func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode C
 		direction:   model.TrafficDirectionOutbound,
 		proxy:       cb.proxy,
 		cache:       cb.cache,
+		tlsTuning:   destinationRuleTLSTuning(destRule),
 	}
 
 	if clusterMode == DefaultClusterMode {
@@ -272,6 +273,10 @@ func (cb *ClusterBuilder) buildDefaultCluster(name string, discoveryType cluster
 	switch discoveryType {
 	case cluster.Cluster_STRICT_DNS:
 		c.DnsLookupFamily = cluster.Cluster_V4_ONLY
+		if cb.proxy.IsIPv6Only() {
+			// IPv4-only destinations are expected to be reachable through DNS64/NAT64.
+			c.DnsLookupFamily = cluster.Cluster_V6_ONLY
+		}
 		dnsRate := gogo.DurationToProtoDuration(cb.push.Mesh.DnsRefreshRate)
 		c.DnsRefreshRate = dnsRate
 		c.RespectDnsTtl = true
@@ -326,23 +331,32 @@ type clusterCache struct {
 	proxyClusterID string
 	// proxySidecar identifies if this proxy is a Sidecar
 	proxySidecar bool
+	// proxyVersion is the Istio version of the proxy, which the EnvoyFilter patches may match
+	proxyVersion string
+	// supportsIPv4 and supportsIPv6 identify the IP families of the proxy, which select the DNS lookup family
+	supportsIPv4 bool
+	supportsIPv6 bool
 	// http2 identifies if thi cluster is for an http2 service
 	http2          bool
 	downstreamAuto bool
-	// Push version is a very broad key. Any config key will invalidate it. Its still valuable to cache,
-	// as that means we can generate a cluster once and send it to all proxies, rather than N times for N proxies.
-	// Hypothetically we could get smarter and determine the exact set of all configs we use and their versions,
-	// which we probably will need for proper delta XDS, but for now this is sufficient.
-	pushVersion string
-	service     *model.Service
-	networkView map[network.ID]bool
+	service        *model.Service
+	networkView    map[network.ID]bool
+	// sidecar is the Sidecar resource scoping the proxy, if any. It selects the DestinationRule of the cluster.
+	sidecar *model.ConfigKey
+	// envoyFilterKeys are the EnvoyFilters patching the clusters of the proxy. The cached clusters are patched.
+	envoyFilterKeys []model.ConfigKey
+	// peerAuthVersion is the version of all the PeerAuthentications, which determine the mTLS mode of the service
+	peerAuthVersion string
+	// serviceAccounts are the service accounts of the service port, verified by the upstream TLS context
+	serviceAccounts []string
 }
 
 func (t *clusterCache) Key() string {
 	params := []string{
-		t.clusterName, t.pushVersion,
+		t.clusterName, t.proxyVersion,
 		strconv.FormatBool(t.proxySidecar), strconv.FormatBool(t.http2), strconv.FormatBool(t.downstreamAuto),
-		util.LocalityToString(t.locality), t.proxyClusterID,
+		strconv.FormatBool(t.supportsIPv4), strconv.FormatBool(t.supportsIPv6),
+		util.LocalityToString(t.locality), t.proxyClusterID, t.peerAuthVersion,
 	}
 	if t.service != nil {
 		params = append(params, string(t.service.Hostname)+"/"+t.service.Attributes.Namespace)
@@ -350,6 +364,13 @@ func (t *clusterCache) Key() string {
 	if t.destinationRule != nil {
 		params = append(params, t.destinationRule.Name+"/"+t.destinationRule.Namespace)
 	}
+	if t.sidecar != nil {
+		params = append(params, "sidecar/"+t.sidecar.Namespace+"/"+t.sidecar.Name)
+	}
+	for _, ef := range t.envoyFilterKeys {
+		params = append(params, "envoyfilter/"+ef.Namespace+"/"+ef.Name)
+	}
+	params = append(params, t.serviceAccounts...)
 	if t.networkView != nil {
 		nv := make([]string, 0, len(t.networkView))
 		for nw := range t.networkView {
@@ -361,6 +382,8 @@ func (t *clusterCache) Key() string {
 	return "cds://" + strings.Join(params, "~")
 }
 
+// DependentConfigs returns the configs the cluster is built from, so that a change of a DestinationRule or a
+// service only invalidates its own clusters.
 func (t clusterCache) DependentConfigs() []model.ConfigKey {
 	configs := []model.ConfigKey{}
 	if t.destinationRule != nil {
@@ -369,6 +392,10 @@ func (t clusterCache) DependentConfigs() []model.ConfigKey {
 	if t.service != nil {
 		configs = append(configs, model.ConfigKey{Kind: gvk.ServiceEntry, Name: string(t.service.Hostname), Namespace: t.service.Attributes.Namespace})
 	}
+	if t.sidecar != nil {
+		configs = append(configs, *t.sidecar)
+	}
+	configs = append(configs, t.envoyFilterKeys...)
 	return configs
 }
 
@@ -490,8 +517,8 @@ func (cb *ClusterBuilder) buildLocalityLbEndpoints(proxyNetworkView map[network.
 				Value: instance.Endpoint.GetLoadBalancingWeight(),
 			},
 		}
-		ep.Metadata = util.BuildLbEndpointMetadata(instance.Endpoint.Network, instance.Endpoint.TLSMode, instance.Endpoint.WorkloadName,
-			instance.Endpoint.Namespace, instance.Endpoint.Locality.ClusterID, instance.Endpoint.Labels)
+		ep.Metadata = util.BuildLbEndpointMetadata(instance.Endpoint.Network, instance.Endpoint.TLSMode, instance.Endpoint.GetCapture(),
+			instance.Endpoint.WorkloadName, instance.Endpoint.Namespace, instance.Endpoint.Locality.ClusterID, instance.Endpoint.Labels)
 		locality := instance.Endpoint.Locality.Label
 		lbEndpoints[locality] = append(lbEndpoints[locality], ep)
 	}
@@ -677,8 +704,9 @@ func (cb *ClusterBuilder) applyTrafficPolicy(opts buildClusterOpts) {
 		applyLoadBalancer(opts.mutable.cluster, loadBalancer, opts.port, opts.proxy, opts.mesh)
 		if opts.clusterMode != SniDnatClusterMode {
 			autoMTLSEnabled := opts.mesh.GetEnableAutoMtls().Value
+			serviceMTLSMode := autoMtlsServiceMode(opts.serviceMTLSMode, opts.mutable.cluster.GetType())
 			tls, mtlsCtxType := buildAutoMtlsSettings(tls, opts.serviceAccounts, opts.istioMtlsSni, opts.proxy,
-				autoMTLSEnabled, opts.meshExternal, opts.serviceMTLSMode)
+				autoMTLSEnabled, opts.meshExternal, serviceMTLSMode)
 			cb.applyUpstreamTLSSettings(&opts, tls, mtlsCtxType)
 		}
 	}
@@ -937,6 +965,17 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 			tlsContext.CommonTlsContext.AlpnProtocols = util.ALPNH2Only
 		}
 	}
+	if tlsContext != nil {
+		if profile := authn_model.MeshTLSProfile(); profile != nil {
+			tlsContext.CommonTlsContext.TlsParams = profile.TLSParameters()
+		}
+		tuning := opts.tlsTuning
+		if tls.Mode == networking.ClientTLSSettings_ISTIO_MUTUAL {
+			tuning = meshMTLSTuning.merge(tuning)
+		}
+		tuning.apply(tlsContext)
+		tlsContext.CommonTlsContext.TlsParams = authn_model.EnforceFIPS(tlsContext.CommonTlsContext.TlsParams)
+	}
 	return tlsContext, nil
 }
 
@@ -949,7 +949,7 @@ func TestBuildLocalityLbEndpoints(t *testing.T) {
 									},
 								},
 							},
-							Metadata: util.BuildLbEndpointMetadata("nw-0", "", "workload-1", "namespace-1", "cluster-1", map[string]string{}),
+							Metadata: util.BuildLbEndpointMetadata("nw-0", "", model.CaptureNone, "workload-1", "namespace-1", "cluster-1", map[string]string{}),
 							LoadBalancingWeight: &wrappers.UInt32Value{
 								Value: 30,
 							},
@@ -969,7 +969,7 @@ func TestBuildLocalityLbEndpoints(t *testing.T) {
 									},
 								},
 							},
-							Metadata: util.BuildLbEndpointMetadata("nw-1", "", "workload-2", "namespace-2", "cluster-2", map[string]string{}),
+							Metadata: util.BuildLbEndpointMetadata("nw-1", "", model.CaptureNone, "workload-2", "namespace-2", "cluster-2", map[string]string{}),
 							LoadBalancingWeight: &wrappers.UInt32Value{
 								Value: 30,
 							},
@@ -1001,7 +1001,7 @@ func TestBuildLocalityLbEndpoints(t *testing.T) {
 									},
 								},
 							},
-							Metadata: util.BuildLbEndpointMetadata("", "", "workload-3", "namespace-3", "cluster-3", map[string]string{}),
+							Metadata: util.BuildLbEndpointMetadata("", "", model.CaptureNone, "workload-3", "namespace-3", "cluster-3", map[string]string{}),
 							LoadBalancingWeight: &wrappers.UInt32Value{
 								Value: 40,
 							},
@@ -1067,7 +1067,7 @@ func TestBuildLocalityLbEndpoints(t *testing.T) {
 									},
 								},
 							},
-							Metadata: util.BuildLbEndpointMetadata("", "", "", "", "cluster-1", map[string]string{}),
+							Metadata: util.BuildLbEndpointMetadata("", "", model.CaptureNone, "", "", "cluster-1", map[string]string{}),
 							LoadBalancingWeight: &wrappers.UInt32Value{
 								Value: 30,
 							},
@@ -1190,7 +1190,7 @@ func TestBuildLocalityLbEndpoints(t *testing.T) {
 									},
 								},
 							},
-							Metadata: util.BuildLbEndpointMetadata("nw-0", "", "workload-1", "namespace-1", "cluster-1", map[string]string{}),
+							Metadata: util.BuildLbEndpointMetadata("nw-0", "", model.CaptureNone, "workload-1", "namespace-1", "cluster-1", map[string]string{}),
 							LoadBalancingWeight: &wrappers.UInt32Value{
 								Value: 30,
 							},
@@ -2495,3 +2495,53 @@ func TestIsHttp2Cluster(t *testing.T) {
 		})
 	}
 }
+
+func TestUpstreamTLSTuning(t *testing.T) {
+	mesh := upstreamTLSTuning{cipherSuites: []string{"ECDHE-ECDSA-AES256-GCM-SHA384"}, ecdhCurves: []string{"P-256"}}
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		expected    *tls.UpstreamTlsContext
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			expected: &tls.UpstreamTlsContext{CommonTlsContext: &tls.CommonTlsContext{TlsParams: &tls.TlsParameters{
+				CipherSuites: []string{"ECDHE-ECDSA-AES256-GCM-SHA384"},
+				EcdhCurves:   []string{"P-256"},
+			}}},
+		},
+		{
+			name: "annotations override the mesh tuning",
+			annotations: map[string]string{
+				TLSCipherSuitesAnnotation:   "ECDHE-RSA-AES128-GCM-SHA256, ECDHE-RSA-AES256-GCM-SHA384",
+				TLSMaxSessionKeysAnnotation: "0",
+			},
+			expected: &tls.UpstreamTlsContext{
+				CommonTlsContext: &tls.CommonTlsContext{TlsParams: &tls.TlsParameters{
+					CipherSuites: []string{"ECDHE-RSA-AES128-GCM-SHA256", "ECDHE-RSA-AES256-GCM-SHA384"},
+					EcdhCurves:   []string{"P-256"},
+				}},
+				MaxSessionKeys: &wrappers.UInt32Value{Value: 0},
+			},
+		},
+		{
+			name:        "invalid max session keys",
+			annotations: map[string]string{TLSMaxSessionKeysAnnotation: "-1"},
+			expected: &tls.UpstreamTlsContext{CommonTlsContext: &tls.CommonTlsContext{TlsParams: &tls.TlsParameters{
+				CipherSuites: []string{"ECDHE-ECDSA-AES256-GCM-SHA384"},
+				EcdhCurves:   []string{"P-256"},
+			}}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			destRule := &config.Config{Meta: config.Meta{Name: "dr", Namespace: "default", Annotations: tt.annotations}}
+			got := &tls.UpstreamTlsContext{CommonTlsContext: &tls.CommonTlsContext{}}
+			mesh.merge(destinationRuleTLSTuning(destRule)).apply(got)
+			if diff := cmp.Diff(tt.expected, got, protocmp.Transform()); diff != "" {
+				t.Fatalf("unexpected TLS context: %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"strconv"
+	"strings"
+
+	auth "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config"
+	"istio.io/pkg/log"
+)
+
+const (
+	// TLSCipherSuitesAnnotation sets the comma separated cipher suites, by order of preference, of the TLS
+	// connections to the clusters of a DestinationRule, whatever their TLS mode.
+	TLSCipherSuitesAnnotation = "networking.istio.io/tls-cipher-suites"
+
+	// TLSECDHCurvesAnnotation sets the comma separated ECDH curves, by order of preference, of the TLS connections
+	// to the clusters of a DestinationRule.
+	TLSECDHCurvesAnnotation = "networking.istio.io/tls-ecdh-curves"
+
+	// TLSMaxSessionKeysAnnotation sets the number of TLS sessions the clusters of a DestinationRule keep to resume
+	// their connections without a full handshake. Zero disables the resumption.
+	TLSMaxSessionKeysAnnotation = "networking.istio.io/tls-max-session-keys"
+)
+
+// upstreamTLSTuning are the cipher suites, curves and session resumption settings of the TLS connections of a
+// cluster. Unset fields keep the defaults.
+type upstreamTLSTuning struct {
+	cipherSuites   []string
+	ecdhCurves     []string
+	maxSessionKeys *wrappers.UInt32Value
+}
+
+// meshMTLSTuning is the tuning of the istio mTLS connections configured in istiod.
+var meshMTLSTuning = func() upstreamTLSTuning {
+	t := upstreamTLSTuning{cipherSuites: features.MTLSCipherSuites, ecdhCurves: features.MTLSECDHCurves}
+	// Envoy keeps a single session by default.
+	if features.MTLSMaxSessionKeys >= 0 && features.MTLSMaxSessionKeys != 1 {
+		t.maxSessionKeys = &wrappers.UInt32Value{Value: uint32(features.MTLSMaxSessionKeys)}
+	}
+	return t
+}()
+
+// destinationRuleTLSTuning returns the tuning set by the annotations of the DestinationRule, if any.
+func destinationRuleTLSTuning(destRule *config.Config) upstreamTLSTuning {
+	var t upstreamTLSTuning
+	if destRule == nil {
+		return t
+	}
+	if v, f := destRule.Annotations[TLSCipherSuitesAnnotation]; f {
+		t.cipherSuites = splitAnnotationList(v)
+	}
+	if v, f := destRule.Annotations[TLSECDHCurvesAnnotation]; f {
+		t.ecdhCurves = splitAnnotationList(v)
+	}
+	if v, f := destRule.Annotations[TLSMaxSessionKeysAnnotation]; f {
+		keys, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			log.Warnf("ignoring invalid %s %q of %s/%s", TLSMaxSessionKeysAnnotation, v, destRule.Namespace, destRule.Name)
+		} else {
+			t.maxSessionKeys = &wrappers.UInt32Value{Value: uint32(keys)}
+		}
+	}
+	return t
+}
+
+func splitAnnotationList(v string) []string {
+	var out []string
+	for _, e := range strings.Split(v, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// merge returns the tuning with the fields set in the override replaced.
+func (t upstreamTLSTuning) merge(override upstreamTLSTuning) upstreamTLSTuning {
+	if len(override.cipherSuites) > 0 {
+		t.cipherSuites = override.cipherSuites
+	}
+	if len(override.ecdhCurves) > 0 {
+		t.ecdhCurves = override.ecdhCurves
+	}
+	if override.maxSessionKeys != nil {
+		t.maxSessionKeys = override.maxSessionKeys
+	}
+	return t
+}
+
+// apply sets the tuning in the TLS context of the cluster.
+func (t upstreamTLSTuning) apply(tlsContext *auth.UpstreamTlsContext) {
+	if len(t.cipherSuites) > 0 || len(t.ecdhCurves) > 0 {
+		if tlsContext.CommonTlsContext.TlsParams == nil {
+			tlsContext.CommonTlsContext.TlsParams = &auth.TlsParameters{}
+		}
+		if len(t.cipherSuites) > 0 {
+			tlsContext.CommonTlsContext.TlsParams.CipherSuites = t.cipherSuites
+		}
+		if len(t.ecdhCurves) > 0 {
+			tlsContext.CommonTlsContext.TlsParams.EcdhCurves = t.ecdhCurves
+		}
+	}
+	if t.maxSessionKeys != nil {
+		tlsContext.MaxSessionKeys = t.maxSessionKeys
+	}
+}
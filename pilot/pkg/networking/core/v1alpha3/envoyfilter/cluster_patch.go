@@ -58,6 +58,7 @@ func ApplyClusterMerge(pctx networking.EnvoyFilter_PatchContext, efw *model.Envo
 			if !ret {
 				proto.Merge(c, cp.Value)
 			}
+			c.Metadata = addProvenance(c.Metadata, cp)
 		}
 		IncrementEnvoyFilterMetric(cp.Key(), Cluster, applied)
 	}
@@ -147,7 +148,9 @@ func InsertedClusters(pctx networking.EnvoyFilter_PatchContext, efw *model.Envoy
 	for _, cp := range efw.Patches[networking.EnvoyFilter_CLUSTER] {
 		if cp.Operation == networking.EnvoyFilter_Patch_ADD {
 			if commonConditionMatch(pctx, cp) {
-				result = append(result, proto.Clone(cp.Value).(*cluster.Cluster))
+				c := proto.Clone(cp.Value).(*cluster.Cluster)
+				c.Metadata = addProvenance(c.Metadata, cp)
+				result = append(result, c)
 			}
 		}
 	}
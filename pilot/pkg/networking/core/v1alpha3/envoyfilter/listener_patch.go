@@ -17,6 +17,7 @@ package envoyfilter
 import (
 	"fmt"
 
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	xdslistener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
@@ -27,6 +28,8 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/util/runtime"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/pkg/log"
 )
@@ -82,7 +85,9 @@ func patchListeners(
 
 				// clone before append. Otherwise, subsequent operations on this listener will corrupt
 				// the master value stored in CP..
-				listeners = append(listeners, proto.Clone(lp.Value).(*xdslistener.Listener))
+				listener := proto.Clone(lp.Value).(*xdslistener.Listener)
+				listener.Metadata = addProvenance(listener.Metadata, lp)
+				listeners = append(listeners, listener)
 				IncrementEnvoyFilterMetric(lp.Key(), Listener, true)
 			}
 		}
@@ -108,6 +113,7 @@ func patchListener(patchContext networking.EnvoyFilter_PatchContext,
 			IncrementEnvoyFilterMetric(lp.Key(), Listener, false)
 			continue
 		}
+		listener.Metadata = addProvenance(listener.Metadata, lp)
 		IncrementEnvoyFilterMetric(lp.Key(), Listener, true)
 		if lp.Operation == networking.EnvoyFilter_Patch_REMOVE {
 			listener.Name = ""
@@ -145,6 +151,7 @@ func patchFilterChains(patchContext networking.EnvoyFilter_PatchContext,
 				IncrementEnvoyFilterMetric(lp.Key(), FilterChain, false)
 				continue
 			}
+			listener.Metadata = addProvenance(listener.Metadata, lp)
 			IncrementEnvoyFilterMetric(lp.Key(), FilterChain, true)
 			listener.FilterChains = append(listener.FilterChains, proto.Clone(lp.Value).(*xdslistener.FilterChain))
 		}
@@ -171,6 +178,7 @@ func patchFilterChain(patchContext networking.EnvoyFilter_PatchContext,
 			IncrementEnvoyFilterMetric(lp.Key(), FilterChain, false)
 			continue
 		}
+		listener.Metadata = addProvenance(listener.Metadata, lp)
 		IncrementEnvoyFilterMetric(lp.Key(), FilterChain, true)
 		if lp.Operation == networking.EnvoyFilter_Patch_REMOVE {
 			fc.Filters = nil
@@ -250,6 +258,7 @@ func patchNetworkFilters(patchContext networking.EnvoyFilter_PatchContext,
 			IncrementEnvoyFilterMetric(lp.Key(), NetworkFilter, false)
 			continue
 		}
+		listener.Metadata = addProvenance(listener.Metadata, lp)
 		applied := false
 		if lp.Operation == networking.EnvoyFilter_Patch_ADD {
 			fc.Filters = append(fc.Filters, proto.Clone(lp.Value).(*xdslistener.Filter))
@@ -349,6 +358,7 @@ func patchNetworkFilter(patchContext networking.EnvoyFilter_PatchContext,
 			IncrementEnvoyFilterMetric(lp.Key(), NetworkFilter, false)
 			continue
 		}
+		listener.Metadata = addProvenance(listener.Metadata, lp)
 		if lp.Operation == networking.EnvoyFilter_Patch_REMOVE {
 			filter.Name = ""
 			*networkFilterRemoved = true
@@ -426,6 +436,7 @@ func patchHTTPFilters(patchContext networking.EnvoyFilter_PatchContext,
 			IncrementEnvoyFilterMetric(lp.Key(), HttpFilter, false)
 			continue
 		}
+		listener.Metadata = addProvenance(listener.Metadata, lp)
 		if lp.Operation == networking.EnvoyFilter_Patch_ADD {
 			applied = true
 			httpconn.HttpFilters = append(httpconn.HttpFilters, proto.Clone(lp.Value).(*hcm.HttpFilter))
@@ -533,6 +544,7 @@ func patchHTTPFilter(patchContext networking.EnvoyFilter_PatchContext,
 			IncrementEnvoyFilterMetric(lp.Key(), HttpFilter, applied)
 			continue
 		}
+		listener.Metadata = addProvenance(listener.Metadata, lp)
 		if lp.Operation == networking.EnvoyFilter_Patch_REMOVE {
 			httpFilter.Name = ""
 			*httpFilterRemoved = true
@@ -719,6 +731,11 @@ func commonConditionMatch(patchContext networking.EnvoyFilter_PatchContext,
 	return patchContextMatch(patchContext, lp)
 }
 
+// addProvenance lists the EnvoyFilter of a patch in the provenance metadata of the patched object.
+func addProvenance(metadata *core.Metadata, lp *model.EnvoyFilterConfigPatchWrapper) *core.Metadata {
+	return util.AddConfigProvenanceMetadata(metadata, config.Meta{GroupVersionKind: gvk.EnvoyFilter, Name: lp.Name, Namespace: lp.Namespace})
+}
+
 // toCanonicalName converts a deprecated filter name to the replacement, if present. Otherwise, the
 // same name is returned.
 func toCanonicalName(name string) string {
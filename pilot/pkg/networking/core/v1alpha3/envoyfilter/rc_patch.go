@@ -155,12 +155,12 @@ func patchHTTPRoutes(patchContext networking.EnvoyFilter_PatchContext,
 			continue
 		}
 		if rp.Operation == networking.EnvoyFilter_Patch_ADD {
-			virtualHost.Routes = append(virtualHost.Routes, proto.Clone(rp.Value).(*route.Route))
+			virtualHost.Routes = append(virtualHost.Routes, clonePatchRoute(rp))
 			applied = true
 		} else if rp.Operation == networking.EnvoyFilter_Patch_INSERT_AFTER {
 			// Insert after without a route match is same as ADD in the end
 			if !hasRouteMatch(rp) {
-				virtualHost.Routes = append(virtualHost.Routes, proto.Clone(rp.Value).(*route.Route))
+				virtualHost.Routes = append(virtualHost.Routes, clonePatchRoute(rp))
 				continue
 			}
 			// find the matching route first
@@ -176,7 +176,7 @@ func patchHTTPRoutes(patchContext networking.EnvoyFilter_PatchContext,
 				continue
 			}
 			applied = true
-			clonedVal := proto.Clone(rp.Value).(*route.Route)
+			clonedVal := clonePatchRoute(rp)
 			virtualHost.Routes = append(virtualHost.Routes, clonedVal)
 			if insertPosition < len(virtualHost.Routes)-1 {
 				copy(virtualHost.Routes[insertPosition+1:], virtualHost.Routes[insertPosition:])
@@ -185,7 +185,7 @@ func patchHTTPRoutes(patchContext networking.EnvoyFilter_PatchContext,
 		} else if rp.Operation == networking.EnvoyFilter_Patch_INSERT_BEFORE || rp.Operation == networking.EnvoyFilter_Patch_INSERT_FIRST {
 			// insert before/first without a route match is same as insert in the beginning
 			if !hasRouteMatch(rp) {
-				virtualHost.Routes = append([]*route.Route{proto.Clone(rp.Value).(*route.Route)}, virtualHost.Routes...)
+				virtualHost.Routes = append([]*route.Route{clonePatchRoute(rp)}, virtualHost.Routes...)
 				continue
 			}
 			// find the matching route first
@@ -209,7 +209,7 @@ func patchHTTPRoutes(patchContext networking.EnvoyFilter_PatchContext,
 				insertPosition = 0
 			}
 
-			clonedVal := proto.Clone(rp.Value).(*route.Route)
+			clonedVal := clonePatchRoute(rp)
 			virtualHost.Routes = append(virtualHost.Routes, clonedVal)
 			copy(virtualHost.Routes[insertPosition+1:], virtualHost.Routes[insertPosition:])
 			virtualHost.Routes[insertPosition] = clonedVal
@@ -246,6 +246,7 @@ func patchHTTPRoute(patchContext networking.EnvoyFilter_PatchContext,
 				return
 			} else if rp.Operation == networking.EnvoyFilter_Patch_MERGE {
 				proto.Merge(virtualHost.Routes[routeIndex], rp.Value)
+				virtualHost.Routes[routeIndex].Metadata = addProvenance(virtualHost.Routes[routeIndex].Metadata, rp)
 			}
 			applied = true
 		}
@@ -253,6 +254,13 @@ func patchHTTPRoute(patchContext networking.EnvoyFilter_PatchContext,
 	}
 }
 
+// clonePatchRoute returns a copy of the route added by a patch.
+func clonePatchRoute(rp *model.EnvoyFilterConfigPatchWrapper) *route.Route {
+	r := proto.Clone(rp.Value).(*route.Route)
+	r.Metadata = addProvenance(r.Metadata, rp)
+	return r
+}
+
 func routeConfigurationMatch(patchContext networking.EnvoyFilter_PatchContext, rc *route.RouteConfiguration,
 	rp *model.EnvoyFilterConfigPatchWrapper, portMap model.GatewayPortMap) bool {
 	rMatch := rp.Match.GetRouteConfiguration()
@@ -276,7 +284,7 @@ func routeConfigurationMatch(patchContext networking.EnvoyFilter_PatchContext, r
 			return false
 		}
 
-		if rMatch.Name != "" && rMatch.Name != rc.Name {
+		if rMatch.Name != "" && model.ResolveRouteNameAlias(rMatch.Name) != rc.Name {
 			return false
 		}
 
@@ -295,7 +303,7 @@ func routeConfigurationMatch(patchContext networking.EnvoyFilter_PatchContext, r
 		return false
 	}
 
-	if rMatch.Name != "" && rMatch.Name != rc.Name {
+	if rMatch.Name != "" && model.ResolveRouteNameAlias(rMatch.Name) != rc.Name {
 		return false
 	}
 
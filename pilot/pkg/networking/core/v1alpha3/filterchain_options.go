@@ -15,6 +15,9 @@
 package v1alpha3
 
 import (
+	"strconv"
+	"strings"
+
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	"github.com/golang/protobuf/ptypes/wrappers"
@@ -234,3 +237,26 @@ func (opt fcOpts) populateFilterChain(mtls plugin.MTLSSettings, port uint32, mat
 	}
 	return opt
 }
+
+// inboundStatPrefix returns the stat prefix of an inbound filter chain. It only depends on the traffic served by
+// the filter chain, so stats remain comparable across pushes and can be told apart between filter chains.
+func (opt fcOpts) inboundStatPrefix(service *model.Service) string {
+	port := "passthrough"
+	if p := opt.fc.FilterChainMatch.GetDestinationPort(); p != nil {
+		port = strconv.Itoa(int(p.GetValue()))
+	}
+	proto := "tcp"
+	switch opt.fc.ListenerProtocol {
+	case networking.ListenerProtocolHTTP:
+		proto = "http"
+	case networking.ListenerProtocolAuto:
+		proto = "auto"
+	}
+	tls := "plaintext"
+	if opt.matchOpts.MTLS {
+		tls = "mtls"
+	} else if opt.matchOpts.TransportProtocol == xdsfilters.TLSTransportProtocol {
+		tls = "tls"
+	}
+	return strings.Join([]string{"inbound", port, string(service.Hostname), proto, tls}, "|")
+}
@@ -0,0 +1,45 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/plugin"
+)
+
+func TestInboundStatPrefix(t *testing.T) {
+	svc := &model.Service{Hostname: "reviews.default.svc.cluster.local"}
+	cases := []struct {
+		name     string
+		match    FilterChainMatchOptions
+		port     uint32
+		service  *model.Service
+		expected string
+	}{
+		{"mtls http", inboundPermissiveHTTPFilterChainMatchWithMxcOptions[0], 8080, svc, "inbound|8080|reviews.default.svc.cluster.local|http|mtls"},
+		{"plaintext tcp", inboundPlainTextTCPFilterChainMatchOptions[0], 8080, svc, "inbound|8080|reviews.default.svc.cluster.local|tcp|plaintext"},
+		{"passthrough", inboundPlainTextTCPFilterChainMatchOptions[0], 0, &model.Service{}, "inbound|passthrough||tcp|plaintext"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := fcOpts{matchOpts: tt.match}.populateFilterChain(plugin.MTLSSettings{}, tt.port, "")
+			if got := opt.inboundStatPrefix(tt.service); got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
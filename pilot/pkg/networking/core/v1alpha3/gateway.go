@@ -507,6 +507,16 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(node *mod
 	routeName string, proxyConfig *meshconfig.ProxyConfig) *filterChainOpts {
 	serverProto := protocol.Parse(port.Protocol)
 
+	var topology *model.GatewayTopology
+	if node.MergedGateway != nil {
+		if server != nil {
+			topology = node.MergedGateway.GatewayTopologyForServers([]*networking.Server{server})
+		} else {
+			// Plain text HTTP servers on the same port share a route.
+			topology = node.MergedGateway.GatewayTopologyForServers(node.MergedGateway.ServersByRouteName[routeName])
+		}
+	}
+
 	if serverProto.IsHTTP() {
 		return &filterChainOpts{
 			// This works because we validate that only HTTPS servers can have same port but still different port names
@@ -517,7 +527,7 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(node *mod
 			httpOpts: &httpListenerOpts{
 				rds:               routeName,
 				useRemoteAddress:  true,
-				connectionManager: buildGatewayConnectionManager(proxyConfig, node),
+				connectionManager: buildGatewayConnectionManager(proxyConfig, node, topology),
 				addGRPCWebFilter:  serverProto == protocol.GRPCWeb,
 			},
 		}
@@ -535,14 +545,17 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(node *mod
 		httpOpts: &httpListenerOpts{
 			rds:               routeName,
 			useRemoteAddress:  true,
-			connectionManager: buildGatewayConnectionManager(proxyConfig, node),
+			connectionManager: buildGatewayConnectionManager(proxyConfig, node, topology),
 			addGRPCWebFilter:  serverProto == protocol.GRPCWeb,
 			statPrefix:        server.Name,
 		},
 	}
 }
 
-func buildGatewayConnectionManager(proxyConfig *meshconfig.ProxyConfig, node *model.Proxy) *hcm.HttpConnectionManager {
+// buildGatewayConnectionManager builds the HTTP connection manager for a gateway. The gateway topology
+// from the proxy config may be overridden for individual Gateways.
+func buildGatewayConnectionManager(proxyConfig *meshconfig.ProxyConfig, node *model.Proxy,
+	topology *model.GatewayTopology) *hcm.HttpConnectionManager {
 	httpProtoOpts := &core.Http1ProtocolOptions{}
 	if features.HTTP10 || node.Metadata.HTTP10 == "1" {
 		httpProtoOpts.AcceptHttp_10 = true
@@ -556,6 +569,16 @@ func buildGatewayConnectionManager(proxyConfig *meshconfig.ProxyConfig, node *mo
 			forwardClientCertDetails = util.MeshConfigToEnvoyForwardClientCertDetails(proxyConfig.GatewayTopology.ForwardClientCertDetails)
 		}
 	}
+	skipXffAppend := false
+	if topology != nil {
+		if topology.NumTrustedProxies != nil {
+			xffNumTrustedHops = *topology.NumTrustedProxies
+		}
+		if topology.ForwardClientCertDetails != meshconfig.Topology_UNDEFINED {
+			forwardClientCertDetails = util.MeshConfigToEnvoyForwardClientCertDetails(topology.ForwardClientCertDetails)
+		}
+		skipXffAppend = topology.SkipXffAppend
+	}
 
 	var stripPortMode *hcm.HttpConnectionManager_StripAnyHostPort = nil
 	if features.StripHostPort {
@@ -563,6 +586,7 @@ func buildGatewayConnectionManager(proxyConfig *meshconfig.ProxyConfig, node *mo
 	}
 	return &hcm.HttpConnectionManager{
 		XffNumTrustedHops: xffNumTrustedHops,
+		SkipXffAppend:     skipXffAppend,
 		// Forward client cert if connection is mTLS
 		ForwardClientCertDetails: forwardClientCertDetails,
 		SetCurrentClientCertDetails: &hcm.HttpConnectionManager_SetCurrentClientCertDetails{
@@ -630,20 +654,47 @@ func buildGatewayListenerTLSContext(
 		authn_model.ApplyToCommonTLSContext(ctx.CommonTlsContext, certProxy, server.Tls.SubjectAltNames, []string{}, ctx.RequireClientCertificate.Value)
 	}
 
-	// Set TLS parameters if they are non-default
+	if profile := gatewayTLSProfile(server, proxy); profile != nil {
+		ctx.CommonTlsContext.TlsParams = profile.TLSParameters()
+	}
+
+	// Set TLS parameters if they are non-default, overriding those of the profile
 	if len(server.Tls.CipherSuites) > 0 ||
 		server.Tls.MinProtocolVersion != networking.ServerTLSSettings_TLS_AUTO ||
 		server.Tls.MaxProtocolVersion != networking.ServerTLSSettings_TLS_AUTO {
-		ctx.CommonTlsContext.TlsParams = &tls.TlsParameters{
-			TlsMinimumProtocolVersion: convertTLSProtocol(server.Tls.MinProtocolVersion),
-			TlsMaximumProtocolVersion: convertTLSProtocol(server.Tls.MaxProtocolVersion),
-			CipherSuites:              server.Tls.CipherSuites,
+		if ctx.CommonTlsContext.TlsParams == nil {
+			ctx.CommonTlsContext.TlsParams = &tls.TlsParameters{}
+		}
+		params := ctx.CommonTlsContext.TlsParams
+		if server.Tls.MinProtocolVersion != networking.ServerTLSSettings_TLS_AUTO {
+			params.TlsMinimumProtocolVersion = convertTLSProtocol(server.Tls.MinProtocolVersion)
+		}
+		if server.Tls.MaxProtocolVersion != networking.ServerTLSSettings_TLS_AUTO {
+			params.TlsMaximumProtocolVersion = convertTLSProtocol(server.Tls.MaxProtocolVersion)
+		}
+		if len(server.Tls.CipherSuites) > 0 {
+			params.CipherSuites = server.Tls.CipherSuites
 		}
 	}
+	ctx.CommonTlsContext.TlsParams = authn_model.EnforceFIPS(ctx.CommonTlsContext.TlsParams)
 
 	return ctx
 }
 
+// gatewayTLSProfile returns the TLS profile of the server: the one selected by its gateway, or the one of the mesh.
+func gatewayTLSProfile(server *networking.Server, proxy *model.Proxy) *authn_model.TLSProfile {
+	if proxy.MergedGateway != nil {
+		gatewayName := proxy.MergedGateway.GatewayNameForServer[server]
+		if name, f := proxy.MergedGateway.GatewayTLSProfiles[gatewayName]; f {
+			if profile, known := authn_model.GetTLSProfile(name); known {
+				return profile
+			}
+			log.Warnf("ignoring unknown %s %q of %s", model.GatewayTLSProfileAnnotation, name, gatewayName)
+		}
+	}
+	return authn_model.MeshTLSProfile()
+}
+
 func convertTLSProtocol(in networking.ServerTLSSettings_TLSProtocol) tls.TlsParameters_TlsProtocol {
 	out := tls.TlsParameters_TlsProtocol(in) // There should be a one-to-one enum mapping
 	if out < tls.TlsParameters_TLS_AUTO || out > tls.TlsParameters_TLSv1_3 {
@@ -854,7 +905,7 @@ func builtAutoPassthroughFilterChains(push *model.PushContext, proxy *model.Prox
 				sniHosts:       []string{clusterName},
 				match:          &listener.FilterChainMatch{ApplicationProtocols: allIstioMtlsALPNs},
 				tlsContext:     nil, // NO TLS context because this is passthrough
-				networkFilters: buildOutboundNetworkFiltersWithSingleDestination(push, proxy, statPrefix, clusterName, port),
+				networkFilters: buildOutboundNetworkFiltersWithSingleDestination(push, proxy, statPrefix, clusterName, port, config.Meta{}),
 			})
 
 			destRule := push.DestinationRule(proxy, service)
@@ -871,7 +922,7 @@ func builtAutoPassthroughFilterChains(push *model.PushContext, proxy *model.Prox
 					sniHosts:       []string{subsetClusterName},
 					match:          &listener.FilterChainMatch{ApplicationProtocols: allIstioMtlsALPNs},
 					tlsContext:     nil, // NO TLS context because this is passthrough
-					networkFilters: buildOutboundNetworkFiltersWithSingleDestination(push, proxy, subsetStatPrefix, subsetClusterName, port),
+					networkFilters: buildOutboundNetworkFiltersWithSingleDestination(push, proxy, subsetStatPrefix, subsetClusterName, port, config.Meta{}),
 				})
 			}
 		}
@@ -1069,6 +1069,16 @@ func runSimulationTest(t *testing.T, proxy *model.Proxy, o xds.FakeOptions, tt s
 			xdstest.ValidateListeners(t, sim.Listeners)
 			xdstest.ValidateRouteConfigurations(t, sim.Routes)
 		})
+		t.Run("validate with envoy", func(t *testing.T) {
+			if tt.skipValidation {
+				t.Skip()
+			}
+			xdstest.ValidateWithEnvoy(t, xdstest.EnvoyConfig{
+				Listeners: sim.Listeners,
+				Clusters:  sim.Clusters,
+				Routes:    sim.Routes,
+			})
+		})
 	}
 	if tt.name != "" {
 		t.Run(tt.name, runTest)
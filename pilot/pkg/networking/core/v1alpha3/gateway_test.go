@@ -511,6 +511,64 @@ func TestBuildGatewayListenerTlsContext(t *testing.T) {
 	}
 }
 
+func TestGatewayTLSProfile(t *testing.T) {
+	server := &networking.Server{
+		Hosts: []string{"httpbin.example.com"},
+		Tls: &networking.ServerTLSSettings{
+			Mode:           networking.ServerTLSSettings_SIMPLE,
+			CredentialName: "httpbin-cred",
+			CipherSuites:   []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+		},
+	}
+	cases := []struct {
+		name     string
+		profile  string
+		expected *auth.TlsParameters
+	}{
+		{
+			name: "no profile",
+			expected: &auth.TlsParameters{
+				CipherSuites: []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+			},
+		},
+		{
+			name:    "fips profile with the cipher suites of the server",
+			profile: model.TLSProfileFIPS,
+			expected: &auth.TlsParameters{
+				TlsMinimumProtocolVersion: auth.TlsParameters_TLSv1_2,
+				TlsMaximumProtocolVersion: auth.TlsParameters_TLSv1_2,
+				CipherSuites:              []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+				EcdhCurves:                []string{"P-256", "P-384"},
+			},
+		},
+		{
+			name:    "unknown profile",
+			profile: "legacy",
+			expected: &auth.TlsParameters{
+				CipherSuites: []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &pilot_model.Proxy{
+				Metadata: &pilot_model.NodeMetadata{},
+				MergedGateway: &pilot_model.MergedGateway{
+					GatewayNameForServer: map[*networking.Server]string{server: "default/gateway"},
+					GatewayTLSProfiles:   map[string]string{},
+				},
+			}
+			if tt.profile != "" {
+				proxy.MergedGateway.GatewayTLSProfiles["default/gateway"] = tt.profile
+			}
+			ret := buildGatewayListenerTLSContext(server, proxy)
+			if diff := cmp.Diff(tt.expected, ret.CommonTlsContext.TlsParams, protocmp.Transform()); diff != "" {
+				t.Errorf("got diff: %v", diff)
+			}
+		})
+	}
+}
+
 func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 	var stripPortMode *hcm.HttpConnectionManager_StripAnyHostPort
 	testCases := []struct {
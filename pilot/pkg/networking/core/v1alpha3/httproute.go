@@ -52,16 +52,35 @@ func (configgen *ConfigGeneratorImpl) BuildHTTPRoutes(node *model.Proxy, push *m
 	switch node.Type {
 	case model.SidecarProxy:
 		vHostCache := make(map[int][]*route.VirtualHost)
+		shardsByRoute := make(map[string][]*route.RouteConfiguration)
+		scopedBaseRoutes := make(map[string]*route.RouteConfiguration)
 		for _, routeName := range routeNames {
-			rc := configgen.buildSidecarOutboundHTTPRouteConfig(node, push, routeName, vHostCache)
-			if rc != nil {
-				rc = envoyfilter.ApplyRouteConfigurationPatches(networking.EnvoyFilter_SIDECAR_OUTBOUND, node, efw, rc)
-			} else {
-				rc = &route.RouteConfiguration{
-					Name:             routeName,
-					VirtualHosts:     []*route.VirtualHost{},
-					ValidateClusters: proto.BoolFalse,
+			if base, vhostName, ok := parseScopedRouteName(routeName); ok {
+				rc, f := scopedBaseRoutes[base]
+				if !f {
+					rc = configgen.buildSidecarOutboundRoute(node, push, base, vHostCache, efw)
+					scopedBaseRoutes[base] = rc
+				}
+				routeConfigurations = append(routeConfigurations, scopedRouteConfiguration(rc, routeName, vhostName))
+				continue
+			}
+			if base, shard, ok := parseRouteShardName(routeName); ok {
+				shards, f := shardsByRoute[base]
+				if !f {
+					rc := configgen.buildSidecarOutboundRoute(node, push, base, vHostCache, efw)
+					shards = shardRouteConfiguration(rc, features.RouteShardingThresholdBytes)
+					shardsByRoute[base] = shards
+				}
+				if shard < len(shards) {
+					routeConfigurations = append(routeConfigurations, shards[shard])
+				} else {
+					routeConfigurations = append(routeConfigurations, emptyRouteConfiguration(routeName))
 				}
+				continue
+			}
+			rc := configgen.buildSidecarOutboundRoute(node, push, routeName, vHostCache, efw)
+			if vhdsEnabled(node) {
+				rc = vhdsRouteConfiguration(rc)
 			}
 			routeConfigurations = append(routeConfigurations, rc)
 		}
@@ -77,6 +96,25 @@ func (configgen *ConfigGeneratorImpl) BuildHTTPRoutes(node *model.Proxy, push *m
 	return routeConfigurations
 }
 
+// buildSidecarOutboundRoute builds the outbound route configuration of a sidecar, with the EnvoyFilter patches
+// applied. Unknown routes are empty.
+func (configgen *ConfigGeneratorImpl) buildSidecarOutboundRoute(node *model.Proxy, push *model.PushContext,
+	routeName string, vHostCache map[int][]*route.VirtualHost, efw *model.EnvoyFilterWrapper) *route.RouteConfiguration {
+	rc := configgen.buildSidecarOutboundHTTPRouteConfig(node, push, routeName, vHostCache)
+	if rc == nil {
+		return emptyRouteConfiguration(routeName)
+	}
+	return envoyfilter.ApplyRouteConfigurationPatches(networking.EnvoyFilter_SIDECAR_OUTBOUND, node, efw, rc)
+}
+
+func emptyRouteConfiguration(routeName string) *route.RouteConfiguration {
+	return &route.RouteConfiguration{
+		Name:             routeName,
+		VirtualHosts:     []*route.VirtualHost{},
+		ValidateClusters: proto.BoolFalse,
+	}
+}
+
 // buildSidecarInboundHTTPRouteConfig builds the route config with a single wildcard virtual host on the inbound path
 // TODO: trace decorators, inbound timeouts
 func (configgen *ConfigGeneratorImpl) buildSidecarInboundHTTPRouteConfig(
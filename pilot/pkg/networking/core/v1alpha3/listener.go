@@ -104,7 +104,7 @@ func (configgen *ConfigGeneratorImpl) BuildListeners(node *model.Proxy,
 
 	switch node.Type {
 	case model.SidecarProxy:
-		builder = configgen.buildSidecarListeners(builder)
+		builder = configgen.buildSidecarListeners(builder).addSidecarProvenance()
 	case model.Router:
 		builder = configgen.buildGatewayListeners(builder)
 	}
@@ -167,8 +167,14 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundListeners(
 		//
 		//	Pilot will generate three listeners, the last one will use protocol sniffing.
 		//
+		// Ports which are not intercepted, as set by the traffic annotations of the workload, never receive
+		// traffic through the proxy, so no listener is generated for them.
+		intercepted := node.InboundInterception()
 		for _, instance := range node.ServiceInstances {
 			endpoint := instance.Endpoint
+			if !intercepted.Captures(int(endpoint.EndpointPort)) {
+				continue
+			}
 			// Inbound listeners will be aggregated into a single virtual listener (port 15006)
 			// As a result, we don't need to worry about binding to the endpoint IP; we already know
 			// all traffic for these listeners is inbound.
@@ -380,6 +386,19 @@ type outboundListenerEntry struct {
 	listener    *listener.Listener
 	locked      bool
 	protocol    protocol.Instance
+	// chainServices are the services of the TCP filter chains of the listener, when known, to resolve the
+	// conflicts between filter chains.
+	chainServices map[*listener.FilterChain]*model.Service
+}
+
+func (e *outboundListenerEntry) setChainService(fc *listener.FilterChain, svc *model.Service) {
+	if svc == nil {
+		return
+	}
+	if e.chainServices == nil {
+		e.chainServices = map[*listener.FilterChain]*model.Service{}
+	}
+	e.chainServices[fc] = svc
 }
 
 func protocolName(p protocol.Instance) string {
@@ -399,29 +418,63 @@ type outboundListenerConflict struct {
 	listenerName    string
 	currentProtocol protocol.Instance
 	currentServices []*model.Service
-	newHostname     host.Name
-	newProtocol     protocol.Instance
+	// currentService is the service owning the conflicting listener or filter chain, if known.
+	currentService *model.Service
+	newService     *model.Service
+	newHostname    host.Name
+	newProtocol    protocol.Instance
 }
 
-func (c outboundListenerConflict) addMetric(metrics model.Metrics) {
+func (c outboundListenerConflict) addMetric(metrics model.Metrics, newAccepted bool) {
 	currentHostnames := make([]string, len(c.currentServices))
 	for i, s := range c.currentServices {
 		currentHostnames[i] = string(s.Hostname)
 	}
 	concatHostnames := strings.Join(currentHostnames, ",")
+	accepted, acceptedProtocol := concatHostnames, c.currentProtocol
+	rejected, rejectedProtocol := string(c.newHostname), c.newProtocol
+	if newAccepted {
+		accepted, acceptedProtocol, rejected, rejectedProtocol = rejected, rejectedProtocol, accepted, acceptedProtocol
+	}
 	metrics.AddMetric(c.metric,
 		c.listenerName,
 		c.node.ID,
 		fmt.Sprintf("Listener=%s Accepted%s=%s Rejected%s=%s %sServices=%d",
 			c.listenerName,
-			protocolName(c.currentProtocol),
-			concatHostnames,
-			protocolName(c.newProtocol),
-			c.newHostname,
+			protocolName(acceptedProtocol),
+			accepted,
+			protocolName(rejectedProtocol),
+			rejected,
 			protocolName(c.currentProtocol),
 			len(c.currentServices)))
 }
 
+// resolve decides whether the new service replaces the current one according to the
+// PILOT_OUTBOUND_LISTENER_CONFLICT_POLICY, and records the conflict in the push context.
+func (c outboundListenerConflict) resolve(push *model.PushContext) bool {
+	newWins := newServiceWins(features.OutboundListenerConflictPolicy, c.currentService, c.newService,
+		c.currentProtocol, c.newProtocol)
+	c.addMetric(push, newWins)
+
+	current := model.NewListenerConflictService(c.currentService, "", c.currentProtocol)
+	if c.currentService == nil && len(c.currentServices) > 0 {
+		current = model.NewListenerConflictService(c.currentServices[0], "", c.currentProtocol)
+	}
+	incoming := model.NewListenerConflictService(c.newService, c.newHostname, c.newProtocol)
+	conflict := model.ListenerConflict{
+		Listener: c.listenerName,
+		Policy:   features.OutboundListenerConflictPolicy,
+		Winner:   current,
+		Loser:    incoming,
+		Proxy:    c.node.ID,
+	}
+	if newWins {
+		conflict.Winner, conflict.Loser = incoming, current
+	}
+	push.AddListenerConflict(conflict)
+	return newWins
+}
+
 // buildSidecarOutboundListeners generates http and tcp listeners for
 // outbound connections from the proxy based on the sidecar scope associated with the proxy.
 func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.Proxy,
@@ -433,6 +486,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 	var tcpListeners, httpListeners []*listener.Listener
 	// For conflict resolution
 	listenerMap := make(map[string]*outboundListenerEntry)
+	_, onDemandRoutes := configgen.onDemandScopes(node, push)
 
 	// The sidecarConfig if provided could filter the list of
 	// services/virtual services that we need to process. It could also
@@ -514,11 +568,12 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 
 			// Build ListenerOpts and PluginParams once and reuse across all Services to avoid unnecessary allocations.
 			listenerOpts := buildListenerOpts{
-				push:       push,
-				proxy:      node,
-				bind:       bind,
-				port:       listenPort,
-				bindToPort: bindToPort,
+				push:           push,
+				proxy:          node,
+				bind:           bind,
+				port:           listenPort,
+				bindToPort:     bindToPort,
+				onDemandRoutes: onDemandRoutes,
 			}
 
 			for _, service := range services {
@@ -561,9 +616,10 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 
 			// Build ListenerOpts and PluginParams once and reuse across all Services to avoid unnecessary allocations.
 			listenerOpts := buildListenerOpts{
-				push:       push,
-				proxy:      node,
-				bindToPort: bindToPort,
+				push:           push,
+				proxy:          node,
+				bindToPort:     bindToPort,
+				onDemandRoutes: onDemandRoutes,
 			}
 
 			for _, service := range services {
@@ -729,23 +785,27 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPListenerOptsForPor
 		}
 
 		if !sniffingEnabled {
-			if listenerOpts.service != nil {
-				if !(*currentListenerEntry).servicePort.Protocol.IsHTTP() {
-					outboundListenerConflict{
-						metric:          model.ProxyStatusConflictOutboundListenerTCPOverHTTP,
-						node:            listenerOpts.proxy,
-						listenerName:    *listenerMapKey,
-						currentServices: (*currentListenerEntry).services,
-						currentProtocol: (*currentListenerEntry).servicePort.Protocol,
-						newHostname:     listenerOpts.service.Hostname,
-						newProtocol:     listenerOpts.port.Protocol,
-					}.addMetric(listenerOpts.push)
-				}
-
+			if listenerOpts.service == nil {
+				return false, nil
+			}
+			if (*currentListenerEntry).servicePort.Protocol.IsHTTP() || !(outboundListenerConflict{
+				metric:          model.ProxyStatusConflictOutboundListenerTCPOverHTTP,
+				node:            listenerOpts.proxy,
+				listenerName:    *listenerMapKey,
+				currentServices: (*currentListenerEntry).services,
+				currentService:  firstService((*currentListenerEntry).services),
+				currentProtocol: (*currentListenerEntry).servicePort.Protocol,
+				newService:      listenerOpts.service,
+				newHostname:     listenerOpts.service.Hostname,
+				newProtocol:     listenerOpts.port.Protocol,
+			}.resolve(listenerOpts.push)) {
 				// Skip building listener for the same http port
 				(*currentListenerEntry).services = append((*currentListenerEntry).services, listenerOpts.service)
+				return false, nil
 			}
-			return false, nil
+			// The HTTP service wins the conflict: replace the TCP listener.
+			delete(listenerMap, *listenerMapKey)
+			*currentListenerEntry = nil
 		}
 	}
 
@@ -763,12 +823,16 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPListenerOptsForPor
 			rdsName = strconv.Itoa(listenerOpts.port.Port)
 		}
 	}
+	scopedRoutes := configgen.buildSidecarOutboundScopedRoutes(listenerOpts.proxy, listenerOpts.push, rdsName,
+		listenerOpts.onDemandRoutes)
 	httpOpts := &httpListenerOpts{
 		// Set useRemoteAddress to true for side car outbound listeners so that it picks up the localhost address of the sender,
 		// which is an internal address, so that trusted headers are not sanitized. This helps to retain the timeout headers
 		// such as "x-envoy-upstream-rq-timeout-ms" set by the calling application.
 		useRemoteAddress: features.UseRemoteAddress,
 		rds:              rdsName,
+		scopedRoutes:     scopedRoutes,
+		vhds:             vhdsEnabled(listenerOpts.proxy),
 	}
 
 	if features.HTTP10 || listenerOpts.proxy.Metadata.HTTP10 == "1" {
@@ -868,16 +932,22 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundTCPListenerOptsForPort
 				// on same IP.  Unfortunately we won't know if this is a real
 				// conflict or not until we process the VirtualServices, etc.
 				// The conflict resolution is done later in this code
-				outboundListenerConflict{
+				if !(outboundListenerConflict{
 					metric:          model.ProxyStatusConflictOutboundListenerHTTPOverTCP,
 					node:            listenerOpts.proxy,
 					listenerName:    *listenerMapKey,
 					currentServices: (*currentListenerEntry).services,
+					currentService:  firstService((*currentListenerEntry).services),
 					currentProtocol: (*currentListenerEntry).servicePort.Protocol,
+					newService:      listenerOpts.service,
 					newHostname:     newHostname,
 					newProtocol:     listenerOpts.port.Protocol,
-				}.addMetric(listenerOpts.push)
-				return false, nil
+				}.resolve(listenerOpts.push)) {
+					return false, nil
+				}
+				// The TCP service wins the conflict: replace the HTTP listener.
+				delete(listenerMap, *listenerMapKey)
+				*currentListenerEntry = nil
 			}
 		}
 	}
@@ -1103,13 +1173,17 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListenerForPortOrUDS(l
 			currentListenerEntry.listener.FilterChains = mergeTCPFilterChains(mutable.Listener.FilterChains,
 				listenerOpts, listenerMapKey, listenerMap)
 		} else {
-			listenerMap[listenerMapKey] = &outboundListenerEntry{
+			entry := &outboundListenerEntry{
 				services:    []*model.Service{listenerOpts.service},
 				servicePort: listenerOpts.port,
 				bind:        listenerOpts.bind,
 				listener:    mutable.Listener,
 				protocol:    listenerPortProtocol,
 			}
+			for _, fc := range mutable.Listener.FilterChains {
+				entry.setChainService(fc, listenerOpts.service)
+			}
+			listenerMap[listenerMapKey] = entry
 		}
 	case HTTPOverTCP:
 		// Merge HTTP filter chain to TCP filter chain
@@ -1171,6 +1245,10 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListenerForPortOrUDS(l
 type httpListenerOpts struct {
 	routeConfig *route.RouteConfiguration
 	rds         string
+	// If set, the routes are selected with these scoped routes instead of rds.
+	scopedRoutes *hcm.ScopedRoutes
+	// If set, the virtual hosts of the rds route configuration are requested on demand with VHDS.
+	vhds bool
 	// If set, use this as a basis
 	connectionManager *hcm.HttpConnectionManager
 	// stat prefix for the http connection manager
@@ -1220,6 +1298,8 @@ type buildListenerOpts struct {
 	class             ListenerClass
 	service           *model.Service
 	protocol          istionetworking.ListenerProtocol
+	// onDemandRoutes are the outbound route configurations replaced by the scopes fetched on demand.
+	onDemandRoutes map[string]struct{}
 }
 
 func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpListenerOpts,
@@ -1270,7 +1350,9 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 	notimeout := durationpb.New(0 * time.Second)
 	connectionManager.StreamIdleTimeout = notimeout
 
-	if httpOpts.rds != "" {
+	if httpOpts.scopedRoutes != nil {
+		connectionManager.RouteSpecifier = &hcm.HttpConnectionManager_ScopedRoutes{ScopedRoutes: httpOpts.scopedRoutes}
+	} else if httpOpts.rds != "" {
 		rds := &hcm.HttpConnectionManager_Rds{
 			Rds: &hcm.Rds{
 				ConfigSource: &core.ConfigSource{
@@ -1308,6 +1390,11 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 		filters = append(filters, xdsfilters.Alpn)
 	}
 
+	// Scopes and virtual hosts fetched on demand must be requested before the router looks up the route.
+	if httpOpts.scopedRoutes.GetScopedRds() != nil || httpOpts.vhds {
+		filters = append(filters, xdsfilters.OnDemand)
+	}
+
 	filters = append(filters, xdsfilters.Cors, xdsfilters.Fault, xdsfilters.BuildRouterFilter(routerFilterCtx))
 
 	connectionManager.HttpFilters = filters
@@ -1331,7 +1418,7 @@ func buildListener(opts buildListenerOpts, trafficDirection core.TrafficDirectio
 		}
 	}
 
-	if opts.proxy.GetInterceptionMode() == model.InterceptionTproxy && trafficDirection == core.TrafficDirection_INBOUND {
+	if opts.proxy.GetInboundInterceptionMode() == model.InterceptionTproxy && trafficDirection == core.TrafficDirection_INBOUND {
 		listenerFiltersMap[xdsfilters.OriginalSrcFilterName] = true
 		listenerFilters = append(listenerFilters, xdsfilters.OriginalSrc)
 	}
@@ -1400,10 +1487,14 @@ func buildListener(opts buildListenerOpts, trafficDirection core.TrafficDirectio
 		if !needMatch && filterChainMatchEmpty(match) {
 			match = nil
 		}
-		filterChains = append(filterChains, &listener.FilterChain{
+		fc := &listener.FilterChain{
 			FilterChainMatch: match,
 			TransportSocket:  buildDownstreamTLSTransportSocket(chain.tlsContext),
-		})
+		}
+		if trafficDirection == core.TrafficDirection_INBOUND && chain.tlsContext != nil {
+			fc.TransportSocketConnectTimeout = inboundTLSHandshakeTimeout()
+		}
+		filterChains = append(filterChains, fc)
 	}
 
 	var deprecatedV1 *listener.Listener_DeprecatedV1
@@ -1549,7 +1640,7 @@ func mergeTCPFilterChains(incoming []*listener.FilterChain, listenerOpts buildLi
 	for _, incomingFilterChain := range incoming {
 		conflict := false
 
-		for _, existingFilterChain := range mergedFilterChains {
+		for i, existingFilterChain := range mergedFilterChains {
 			conflict = isConflict(existingFilterChain, incomingFilterChain)
 
 			if conflict {
@@ -1566,15 +1657,22 @@ func mergeTCPFilterChains(incoming []*listener.FilterChain, listenerOpts buildLi
 					newHostname = "sidecar-config-egress-tcp-listener"
 				}
 
-				outboundListenerConflict{
+				if (outboundListenerConflict{
 					metric:          model.ProxyStatusConflictOutboundListenerTCPOverTCP,
 					node:            listenerOpts.proxy,
 					listenerName:    listenerMapKey,
 					currentServices: currentListenerEntry.services,
+					currentService:  currentListenerEntry.chainServices[existingFilterChain],
 					currentProtocol: currentListenerEntry.servicePort.Protocol,
+					newService:      listenerOpts.service,
 					newHostname:     newHostname,
 					newProtocol:     listenerOpts.port.Protocol,
-				}.addMetric(listenerOpts.push)
+				}.resolve(listenerOpts.push)) {
+					// The new service wins the conflict: replace the filter chain of the current one.
+					mergedFilterChains[i] = incomingFilterChain
+					currentListenerEntry.setChainService(incomingFilterChain, listenerOpts.service)
+					currentListenerEntry.services = append(currentListenerEntry.services, listenerOpts.service)
+				}
 				break
 			}
 
@@ -1586,6 +1684,7 @@ func mergeTCPFilterChains(incoming []*listener.FilterChain, listenerOpts buildLi
 			if listenerOpts.service != nil {
 				lEntry := listenerMap[listenerMapKey]
 				lEntry.services = append(lEntry.services, listenerOpts.service)
+				lEntry.setChainService(incomingFilterChain, listenerOpts.service)
 			}
 		}
 	}
@@ -1727,6 +1826,15 @@ func appendListenerFilters(filters []*listener.ListenerFilter) []*listener.Liste
 	return filters
 }
 
+// inboundTLSHandshakeTimeout returns the timeout of the TLS handshake of the inbound filter chains, or nil if
+// the handshake is not bounded.
+func inboundTLSHandshakeTimeout() *durationpb.Duration {
+	if features.MTLSHandshakeTimeout <= 0 {
+		return nil
+	}
+	return durationpb.New(features.MTLSHandshakeTimeout)
+}
+
 // nolint: interfacer
 func buildDownstreamTLSTransportSocket(tlsContext *auth.DownstreamTlsContext) *core.TransportSocket {
 	if tlsContext == nil {
@@ -15,6 +15,7 @@
 package v1alpha3
 
 import (
+	"fmt"
 	"sort"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -35,7 +36,9 @@ import (
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	"istio.io/istio/pilot/pkg/networking/util"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/proto"
 	"istio.io/pkg/log"
 )
@@ -177,7 +180,9 @@ func (lb *ListenerBuilder) aggregateVirtualInboundListener(passthroughInspectors
 	lb.virtualInboundListener.ListenerFilters = append(lb.virtualInboundListener.ListenerFilters,
 		xdsfilters.OriginalDestination,
 	)
-	if lb.node.GetInterceptionMode() == model.InterceptionTproxy {
+	// The source address is only preserved with TPROXY; with REDIRECT the original_src filter would make the
+	// proxy connect to the application from addresses the pod cannot route back.
+	if lb.node.GetInboundInterceptionMode() == model.InterceptionTproxy {
 		lb.virtualInboundListener.ListenerFilters =
 			append(lb.virtualInboundListener.ListenerFilters, xdsfilters.OriginalSrc)
 	}
@@ -393,8 +398,16 @@ func (lb *ListenerBuilder) buildVirtualOutboundListener(configgen *ConfigGenerat
 // TProxy uses only the virtual outbound listener on 15001 for both directions
 // but we still ship the no-op virtual inbound listener, so that the code flow is same across REDIRECT and TPROXY.
 func (lb *ListenerBuilder) buildVirtualInboundListener(configgen *ConfigGeneratorImpl) *ListenerBuilder {
+	// With the Istio CNI, inbound traffic is intercepted in the mode set up by the CNI plugin, whatever the proxy
+	// reports; generate for the actual mode and flag the mismatch.
+	mode := lb.node.GetInboundInterceptionMode()
+	if reported := lb.node.GetInterceptionMode(); mode != reported {
+		lb.push.AddMetric(model.ProxyStatusInterceptionModeMismatch, lb.node.ID, lb.node.ID,
+			fmt.Sprintf("Proxy reports interception mode %s, but the Istio CNI plugin intercepts its inbound traffic with %s",
+				reported, mode))
+	}
 	var isTransparentProxy *wrappers.BoolValue
-	if lb.node.GetInterceptionMode() == model.InterceptionTproxy {
+	if mode == model.InterceptionTproxy {
 		isTransparentProxy = proto.BoolTrue
 	}
 
@@ -409,12 +422,33 @@ func (lb *ListenerBuilder) buildVirtualInboundListener(configgen *ConfigGenerato
 		TrafficDirection: core.TrafficDirection_INBOUND,
 		FilterChains:     filterChains,
 	}
+	lb.virtualInboundListener.ConnectionBalanceConfig = inboundConnectionBalance(lb.node)
 	accessLogBuilder.setListenerAccessLog(lb.push, lb.node, lb.virtualInboundListener)
 	lb.aggregateVirtualInboundListener(passthroughInspector)
 
 	return lb
 }
 
+// inboundConnectionBalance returns exact connection balancing for sidecars running few worker threads, as set
+// by the concurrency of their proxy config or estimated from their CPU limit at injection. By default the kernel
+// picks the worker accepting each connection, which on small pods can leave one worker handling most of the
+// connections while the others stay idle.
+func inboundConnectionBalance(node *model.Proxy) *listener.Listener_ConnectionBalanceConfig {
+	if features.InboundExactBalanceMaxConcurrency <= 0 || node.Metadata == nil || node.Metadata.ProxyConfig == nil {
+		return nil
+	}
+	concurrency := node.Metadata.ProxyConfig.Concurrency.GetValue()
+	// With a single worker there is nothing to balance; zero means one worker per core, which is unknown here.
+	if concurrency <= 1 || int(concurrency) > features.InboundExactBalanceMaxConcurrency {
+		return nil
+	}
+	return &listener.Listener_ConnectionBalanceConfig{
+		BalanceType: &listener.Listener_ConnectionBalanceConfig_ExactBalance_{
+			ExactBalance: &listener.Listener_ConnectionBalanceConfig_ExactBalance{},
+		},
+	}
+}
+
 func (lb *ListenerBuilder) patchOneListener(l *listener.Listener, ctx networking.EnvoyFilter_PatchContext) *listener.Listener {
 	if l == nil {
 		return nil
@@ -428,6 +462,19 @@ func (lb *ListenerBuilder) patchOneListener(l *listener.Listener, ctx networking
 	return tempArray[0]
 }
 
+// addSidecarProvenance lists the Sidecar scoping the proxy in the provenance metadata of its listeners.
+func (lb *ListenerBuilder) addSidecarProvenance() *ListenerBuilder {
+	scope := lb.node.SidecarScope
+	if !features.EnableConfigProvenanceMetadata || scope == nil || scope.Sidecar == nil {
+		return lb
+	}
+	meta := config.Meta{GroupVersionKind: gvk.Sidecar, Name: scope.Name, Namespace: scope.Namespace}
+	for _, l := range lb.getListeners() {
+		l.Metadata = util.AddConfigProvenanceMetadata(l.Metadata, meta)
+	}
+	return lb
+}
+
 func (lb *ListenerBuilder) patchListeners() {
 	lb.envoyFilterWrapper = lb.push.EnvoyFilters(lb.node)
 	if lb.envoyFilterWrapper == nil {
@@ -569,7 +616,9 @@ func buildInboundCatchAllFilterChains(configgen *ConfigGeneratorImpl,
 				Name:             opt.filterChainName,
 			}
 			if opt.httpOpts != nil {
-				opt.httpOpts.statPrefix = clusterName
+				if opt.httpOpts.statPrefix == "" {
+					opt.httpOpts.statPrefix = clusterName
+				}
 				connectionManager := buildHTTPConnectionManager(listenerOpts, opt.httpOpts, opt.filterChain.HTTP)
 				filter := &listener.Filter{
 					Name:       wellknown.HTTPConnectionManager,
@@ -588,6 +637,7 @@ func buildInboundCatchAllFilterChains(configgen *ConfigGeneratorImpl,
 					Name:       util.EnvoyTLSSocketName,
 					ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: util.MessageToAny(opt.tlsContext)},
 				}
+				filterChain.TransportSocketConnectTimeout = inboundTLSHandshakeTimeout()
 			}
 			inspectors[port] = inspector
 			filterChains = append(filterChains, filterChain)
@@ -641,14 +691,21 @@ func (configgen *ConfigGeneratorImpl) buildInboundFilterchains(in *plugin.InputP
 			// Update transport socket from the TLS context configured by the plugin.
 			fcOpt.tlsContext = opt.fc.TLSContext
 		}
+		statPrefix := ""
+		if features.StableInboundStatPrefix {
+			statPrefix = opt.inboundStatPrefix(in.ServiceInstance.Service)
+		}
 		switch opt.fc.ListenerProtocol {
 		case istionetworking.ListenerProtocolHTTP:
 			fcOpt.httpOpts = configgen.buildSidecarInboundHTTPListenerOptsForPortOrUDS(in.Node, in, clusterName)
 		case istionetworking.ListenerProtocolTCP:
-			fcOpt.networkFilters = buildInboundNetworkFilters(in.Push, in.ServiceInstance, clusterName)
+			fcOpt.networkFilters = buildInboundNetworkFilters(in.Push, in.ServiceInstance, clusterName, statPrefix)
 		case istionetworking.ListenerProtocolAuto:
 			fcOpt.httpOpts = configgen.buildSidecarInboundHTTPListenerOptsForPortOrUDS(in.Node, in, clusterName)
-			fcOpt.networkFilters = buildInboundNetworkFilters(in.Push, in.ServiceInstance, clusterName)
+			fcOpt.networkFilters = buildInboundNetworkFilters(in.Push, in.ServiceInstance, clusterName, statPrefix)
+		}
+		if fcOpt.httpOpts != nil && statPrefix != "" {
+			fcOpt.httpOpts.statPrefix = statPrefix
 		}
 		fcOpt.filterChainName = model.VirtualInboundListenerName
 		if opt.fc.ListenerProtocol == istionetworking.ListenerProtocolHTTP {
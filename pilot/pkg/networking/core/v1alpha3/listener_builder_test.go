@@ -26,6 +26,7 @@ import (
 	"github.com/gogo/protobuf/jsonpb"
 	"github.com/gogo/protobuf/types"
 
+	"istio.io/api/annotation"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
@@ -120,6 +121,12 @@ func setInboundCaptureAllOnThisNode(proxy *model.Proxy, mode model.TrafficInterc
 var testServices = []*model.Service{buildService("test.com", wildcardIP, protocol.HTTP, tnow)}
 
 func prepareListeners(t *testing.T, services []*model.Service, mode model.TrafficInterceptionMode) []*listener.Listener {
+	proxy := getDefaultProxy()
+	setInboundCaptureAllOnThisNode(proxy, mode)
+	return prepareListenersWithServices(t, services, proxy)
+}
+
+func prepareListenersWithServices(t *testing.T, services []*model.Service, proxy *model.Proxy) []*listener.Listener {
 	// prepare
 	ldsEnv := getDefaultLdsEnv()
 
@@ -138,9 +145,7 @@ func prepareListeners(t *testing.T, services []*model.Service, mode model.Traffi
 		}
 	}
 
-	proxy := getDefaultProxy()
 	proxy.ServiceInstances = instances
-	setInboundCaptureAllOnThisNode(proxy, mode)
 	setNilSidecarOnProxy(proxy, env.PushContext)
 
 	builder := NewListenerBuilder(proxy, env.PushContext)
@@ -308,6 +313,78 @@ func TestSidecarInboundListenerWithOriginalSrc(t *testing.T) {
 	}
 }
 
+func TestInboundConnectionBalance(t *testing.T) {
+	defaultValue := features.InboundExactBalanceMaxConcurrency
+	features.InboundExactBalanceMaxConcurrency = 2
+	defer func() { features.InboundExactBalanceMaxConcurrency = defaultValue }()
+
+	cases := []struct {
+		name        string
+		concurrency *types.Int32Value
+		exact       bool
+	}{
+		{"unset", nil, false},
+		{"all cores", &types.Int32Value{Value: 0}, false},
+		{"single worker", &types.Int32Value{Value: 1}, false},
+		{"few workers", &types.Int32Value{Value: 2}, true},
+		{"many workers", &types.Int32Value{Value: 8}, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := getDefaultProxy()
+			proxy.Metadata.ProxyConfig = &model.NodeMetaProxyConfig{Concurrency: tt.concurrency}
+			setInboundCaptureAllOnThisNode(proxy, model.InterceptionRedirect)
+			listeners := prepareListenersWithServices(t, testServices, proxy)
+			got := listeners[1].ConnectionBalanceConfig.GetExactBalance() != nil
+			if got != tt.exact {
+				t.Fatalf("expected exact balance %v, got %v", tt.exact, listeners[1].ConnectionBalanceConfig)
+			}
+		})
+	}
+}
+
+func TestCNIInboundInterceptionMode(t *testing.T) {
+	cases := []struct {
+		name        string
+		reported    model.TrafficInterceptionMode
+		cni         bool
+		annotations map[string]string
+		transparent bool
+	}{
+		{"tproxy", model.InterceptionTproxy, false, map[string]string{annotation.SidecarInterceptionMode.Name: "REDIRECT"}, true},
+		{"redirect", model.InterceptionRedirect, false, map[string]string{annotation.SidecarInterceptionMode.Name: "TPROXY"}, false},
+		{"cni redirect", model.InterceptionTproxy, true, map[string]string{annotation.SidecarInterceptionMode.Name: "REDIRECT"}, false},
+		{"cni default", model.InterceptionTproxy, true, map[string]string{}, false},
+		{"cni tproxy", model.InterceptionRedirect, true, map[string]string{annotation.SidecarInterceptionMode.Name: "TPROXY"}, true},
+		{"cni without annotations", model.InterceptionTproxy, true, nil, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := getDefaultProxy()
+			proxy.Metadata.CNIInterception = model.StringBool(tt.cni)
+			proxy.Metadata.Annotations = tt.annotations
+			setInboundCaptureAllOnThisNode(proxy, tt.reported)
+			listeners := prepareListenersWithServices(t, testServices, proxy)
+			l := listeners[1]
+			if l.Name != model.VirtualInboundListenerName {
+				t.Fatalf("expected virtual inbound listener, found %s", l.Name)
+			}
+			if got := l.Transparent.GetValue(); got != tt.transparent {
+				t.Errorf("expected transparent %v, got %v", tt.transparent, got)
+			}
+			originalSrc := false
+			for _, lf := range l.ListenerFilters {
+				if lf.Name == xdsfilters.OriginalSrcFilterName {
+					originalSrc = true
+				}
+			}
+			if originalSrc != tt.transparent {
+				t.Errorf("expected listener filter %s %v, got %v", xdsfilters.OriginalSrcFilterName, tt.transparent, originalSrc)
+			}
+		})
+	}
+}
+
 func TestListenerBuilderPatchListeners(t *testing.T) {
 	configPatches := []*networking.EnvoyFilter_EnvoyConfigObjectPatch{
 		{
@@ -705,3 +782,83 @@ func evaluateListenerFilterPredicates(t testing.TB, predicate *listener.Listener
 		}
 	}
 }
+
+// inboundBenchmarkConfig selects the benchmarked proxy, 1.1.1.1, with .Services services, each on its own port.
+const inboundBenchmarkConfig = `
+apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: default
+spec:
+  mtls:
+    mode: {{ .MTLSMode }}
+---
+{{- range $i := until .Services }}
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: service-{{ $i }}
+  namespace: default
+spec:
+  hosts:
+  - service-{{ $i }}.example.com
+  ports:
+  - number: {{ add 8000 $i }}
+{{- if $.Protocol }}
+    name: {{ lower $.Protocol }}-{{ $i }}
+    protocol: {{ $.Protocol }}
+{{- else }}
+    name: port-{{ $i }}
+{{- end }}
+  resolution: STATIC
+  location: MESH_INTERNAL
+  endpoints:
+  - address: 1.1.1.1
+    labels:
+      app: benchmark
+---
+{{- end }}
+`
+
+// BenchmarkInboundListenerGeneration measures the generation of the inbound listeners of a sidecar, across the
+// mTLS modes and protocols which determine its filter chains (see getFilterChainMatchOptions).
+func BenchmarkInboundListenerGeneration(b *testing.B) {
+	type input struct {
+		Services int
+		MTLSMode string
+		// Protocol is the protocol of the service ports. Empty uses protocol sniffing.
+		Protocol string
+	}
+	for _, services := range []int{100, 1000} {
+		for _, mode := range []string{"STRICT", "PERMISSIVE", "DISABLE"} {
+			for _, protocol := range []string{"HTTP", "TCP", ""} {
+				in := input{Services: services, MTLSMode: mode, Protocol: protocol}
+				name := protocol
+				if name == "" {
+					name = "auto"
+				}
+				b.Run(fmt.Sprintf("%d/%s/%s", services, strings.ToLower(mode), strings.ToLower(name)), func(b *testing.B) {
+					cg := NewConfigGenTest(b, TestOptions{
+						ConfigString:        inboundBenchmarkConfig,
+						ConfigTemplateInput: in,
+					})
+					proxy := cg.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{Labels: map[string]string{"app": "benchmark"}}})
+					if len(proxy.ServiceInstances) != services {
+						b.Fatalf("expected %d service instances, got %d", services, len(proxy.ServiceInstances))
+					}
+					b.ResetTimer()
+					for n := 0; n < b.N; n++ {
+						listeners := NewListenerBuilder(proxy, cg.PushContext()).
+							buildSidecarInboundListeners(cg.ConfigGen).
+							buildVirtualInboundListener(cg.ConfigGen).
+							getListeners()
+						if len(listeners) == 0 {
+							b.Fatal("got no listeners")
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+// The policies resolving the conflicts between services claiming the same outbound listener.
+const (
+	// conflictPolicyFirst keeps the service processed first, in the creation order of the push context.
+	conflictPolicyFirst = "first"
+	// conflictPolicyOldest keeps the oldest service, regardless of the order of the service registries.
+	conflictPolicyOldest = "oldest"
+	// conflictPolicyHTTP keeps the HTTP service on protocol conflicts, and the oldest service otherwise.
+	conflictPolicyHTTP = "http"
+)
+
+// newServiceWins returns whether the new service replaces the current one under the policy. The current service is
+// kept if either service is unknown, or for unknown policies.
+func newServiceWins(policy string, current, incoming *model.Service, currentProtocol, newProtocol protocol.Instance) bool {
+	if current == nil || incoming == nil {
+		return false
+	}
+	switch policy {
+	case conflictPolicyHTTP:
+		if currentProtocol.IsHTTP() != newProtocol.IsHTTP() {
+			return newProtocol.IsHTTP()
+		}
+		return olderService(incoming, current)
+	case conflictPolicyOldest:
+		return olderService(incoming, current)
+	case conflictPolicyFirst:
+		return false
+	default:
+		return false
+	}
+}
+
+// olderService returns whether a was created before b, ordering the services created at the same time by hostname
+// and namespace so that the order is deterministic.
+func olderService(a, b *model.Service) bool {
+	if !a.CreationTime.Equal(b.CreationTime) {
+		return a.CreationTime.Before(b.CreationTime)
+	}
+	if a.Hostname != b.Hostname {
+		return a.Hostname < b.Hostname
+	}
+	return a.Attributes.Namespace < b.Attributes.Namespace
+}
+
+func firstService(services []*model.Service) *model.Service {
+	if len(services) == 0 {
+		return nil
+	}
+	return services[0]
+}
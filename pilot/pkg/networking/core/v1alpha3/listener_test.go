@@ -37,6 +37,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
 
+	"istio.io/api/annotation"
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/memory"
@@ -150,6 +151,37 @@ func TestInboundListenerConfig(t *testing.T) {
 		buildService("test1.com", wildcardIP, protocol.GRPC, tnow.Add(1*time.Second)))
 }
 
+func TestInboundListenerInterception(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		listeners   int
+	}{
+		{"default", nil, 1},
+		{"all ports", map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: "*"}, 1},
+		{"included", map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: "9090,8080"}, 1},
+		{"not included", map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: "9090"}, 0},
+		{"none included", map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: ""}, 0},
+		{"excluded", map[string]string{annotation.SidecarTrafficExcludeInboundPorts.Name: "8080"}, 0},
+		{"status port", map[string]string{annotation.SidecarStatusPort.Name: "8080"}, 0},
+		{"exclusion ignored for included ports", map[string]string{
+			annotation.SidecarTrafficIncludeInboundPorts.Name: "8080",
+			annotation.SidecarTrafficExcludeInboundPorts.Name: "8080",
+		}, 1},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := getProxy()
+			proxy.Metadata.Annotations = tt.annotations
+			p := registry.NewPlugins([]string{plugin.Authn})[0]
+			listeners := buildInboundListeners(t, p, proxy, nil, buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+			if len(listeners) != tt.listeners {
+				t.Fatalf("expected %d listeners, found %d", tt.listeners, len(listeners))
+			}
+		})
+	}
+}
+
 func TestOutboundListenerConflict_HTTPWithCurrentUnknown(t *testing.T) {
 	defaultValue := features.EnableProtocolSniffingForOutbound
 	features.EnableProtocolSniffingForOutbound = true
@@ -2896,3 +2928,84 @@ func TestFilterChainMatchEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestOutboundListenerConflictPolicy(t *testing.T) {
+	defer func(v string) { features.OutboundListenerConflictPolicy = v }(features.OutboundListenerConflictPolicy)
+
+	build := func(t *testing.T, services ...*model.Service) ([]*listener.Listener, []model.ListenerConflict) {
+		cg := NewConfigGenTest(t, TestOptions{Services: services})
+		listeners := cg.ConfigGen.buildSidecarOutboundListeners(cg.SetupProxy(getProxy()), cg.PushContext())
+		xdstest.ValidateListeners(t, listeners)
+		return listeners, cg.PushContext().ListenerConflicts()
+	}
+
+	t.Run("http wins protocol conflicts", func(t *testing.T) {
+		defer func(v bool) { features.EnableProtocolSniffingForOutbound = v }(features.EnableProtocolSniffingForOutbound)
+		features.EnableProtocolSniffingForOutbound = false
+		features.OutboundListenerConflictPolicy = conflictPolicyHTTP
+
+		listeners, conflicts := build(t,
+			buildService("test1.com", wildcardIP, protocol.TCP, tnow),
+			buildService("test2.com", wildcardIP, protocol.HTTP, tnow.Add(time.Second)))
+		if len(listeners) != 1 || !isHTTPListener(listeners[0]) {
+			t.Fatalf("expected a single HTTP listener, got %v", listeners)
+		}
+		if len(conflicts) != 1 || conflicts[0].Winner.Hostname != "test2.com" || conflicts[0].Loser.Hostname != "test1.com" {
+			t.Fatalf("unexpected conflicts %+v", conflicts)
+		}
+	})
+
+	t.Run("oldest breaks ties by hostname", func(t *testing.T) {
+		features.OutboundListenerConflictPolicy = conflictPolicyOldest
+
+		listeners, conflicts := build(t,
+			buildService("test2.com", "1.2.3.4", protocol.TCP, tnow),
+			buildService("test1.com", "1.2.3.4", protocol.TCP, tnow),
+			buildService("test3.com", "1.2.3.4", protocol.TCP, tnow))
+		if len(listeners) != 1 || len(listeners[0].FilterChains) != 1 {
+			t.Fatalf("expected a single listener with a single filter chain, got %v", listeners)
+		}
+		verifyOutboundTCPListenerHostname(t, listeners[0], "test1.com")
+		if len(conflicts) != 2 {
+			t.Fatalf("expected 2 conflicts, got %+v", conflicts)
+		}
+		for _, c := range conflicts {
+			if c.Policy != conflictPolicyOldest || c.Listener != "1.2.3.4:8080" {
+				t.Errorf("unexpected conflict %+v", c)
+			}
+		}
+	})
+}
+
+func TestNewServiceWins(t *testing.T) {
+	old := buildService("b.com", wildcardIP, protocol.TCP, tnow)
+	young := buildService("a.com", wildcardIP, protocol.HTTP, tnow.Add(time.Second))
+	twin := buildService("a.com", wildcardIP, protocol.TCP, tnow)
+
+	cases := []struct {
+		name              string
+		policy            string
+		current, incoming *model.Service
+		want              bool
+	}{
+		{"first keeps the current service", conflictPolicyFirst, old, twin, false},
+		{"oldest replaces a younger service", conflictPolicyOldest, young, old, true},
+		{"oldest keeps an older service", conflictPolicyOldest, old, young, false},
+		{"oldest breaks ties by hostname", conflictPolicyOldest, old, twin, true},
+		{"http prefers the http service", conflictPolicyHTTP, old, young, true},
+		{"http falls back to oldest", conflictPolicyHTTP, old, twin, true},
+		{"unknown service", conflictPolicyOldest, nil, old, false},
+		{"unknown policy", "newest", young, old, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var currentProtocol protocol.Instance
+			if tt.current != nil {
+				currentProtocol = tt.current.Ports[0].Protocol
+			}
+			if got := newServiceWins(tt.policy, tt.current, tt.incoming, currentProtocol, tt.incoming.Ports[0].Protocol); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
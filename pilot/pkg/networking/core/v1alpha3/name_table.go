@@ -23,6 +23,9 @@ import (
 
 const mcsServiceDomain = "clusterset.local"
 
+// dnsExpandFQDN is the DNS_SEARCH_DOMAIN_EXPANSION value limiting Kubernetes services to their FQDN.
+const dnsExpandFQDN = "fqdn"
+
 // BuildNameTable produces a table of hostnames and their associated IPs that can then
 // be used by the agent to resolve DNS. This logic is always active. However, local DNS resolution
 // will only be effective if DNS capture is enabled in the proxy
@@ -31,10 +34,13 @@ func (configgen *ConfigGeneratorImpl) BuildNameTable(node *model.Proxy, push *mo
 	if features.EnableMCSHost {
 		altServiceDomains = append(altServiceDomains, mcsServiceDomain)
 	}
+	altServiceDomains = append(altServiceDomains, node.Metadata.DNSAltDomains...)
 	return dnsServer.BuildNameTable(dnsServer.Config{
 		Node:                        node,
 		Push:                        push,
 		MulticlusterHeadlessEnabled: features.MulticlusterHeadlessEnabled,
+		RemoteServicesViaGateway:    features.MulticlusterRemoteServiceDNSEnabled,
 		AltServiceDomainSuffixes:    altServiceDomains,
+		FQDNOnly:                    node.Metadata.DNSSearchDomainExpansion == dnsExpandFQDN,
 	})
 }
@@ -15,6 +15,7 @@
 package v1alpha3
 
 import (
+	"strconv"
 	"time"
 
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
@@ -23,6 +24,7 @@ import (
 	redis "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/redis_proxy/v3"
 	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"google.golang.org/protobuf/types/known/durationpb"
 
 	networking "istio.io/api/networking/v1alpha3"
@@ -33,17 +35,25 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/pkg/log"
 )
 
 // redisOpTimeout is the default operation timeout for the Redis proxy filter.
 var redisOpTimeout = 5 * time.Second
 
-// buildInboundNetworkFilters generates a TCP proxy network filter on the inbound path
-func buildInboundNetworkFilters(push *model.PushContext, instance *model.ServiceInstance, clusterName string) []*listener.Filter {
-	statPrefix := clusterName
-	// If stat name is configured, build the stat prefix from configured pattern.
-	if len(push.Mesh.InboundClusterStatName) != 0 {
-		statPrefix = util.BuildStatPrefix(push.Mesh.InboundClusterStatName, string(instance.Service.Hostname), "", instance.ServicePort, instance.Service.Attributes)
+// TCPMaxConnectAttemptsAnnotation sets the number of times the TCP routes of a VirtualService try to
+// connect to an upstream host before giving up. Envoy retries immediately, without backoff.
+const TCPMaxConnectAttemptsAnnotation = "networking.istio.io/tcp-max-connect-attempts"
+
+// buildInboundNetworkFilters generates a TCP proxy network filter on the inbound path.
+// If statPrefix is empty, it is derived from the cluster name or the configured stat name pattern.
+func buildInboundNetworkFilters(push *model.PushContext, instance *model.ServiceInstance, clusterName, statPrefix string) []*listener.Filter {
+	if statPrefix == "" {
+		statPrefix = clusterName
+		// If stat name is configured, build the stat prefix from configured pattern.
+		if len(push.Mesh.InboundClusterStatName) != 0 {
+			statPrefix = util.BuildStatPrefix(push.Mesh.InboundClusterStatName, string(instance.Service.Hostname), "", instance.ServicePort, instance.Service.Attributes)
+		}
 	}
 	tcpProxy := &tcp.TcpProxy{
 		StatPrefix:       statPrefix,
@@ -68,7 +78,7 @@ func setAccessLogAndBuildTCPFilter(push *model.PushContext, config *tcp.TcpProxy
 // buildOutboundNetworkFiltersWithSingleDestination takes a single cluster name
 // and builds a stack of network filters.
 func buildOutboundNetworkFiltersWithSingleDestination(push *model.PushContext, node *model.Proxy,
-	statPrefix, clusterName string, port *model.Port) []*listener.Filter {
+	statPrefix, clusterName string, port *model.Port, configMeta config.Meta) []*listener.Filter {
 	tcpProxy := &tcp.TcpProxy{
 		StatPrefix:       statPrefix,
 		ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: clusterName},
@@ -79,6 +89,7 @@ func buildOutboundNetworkFiltersWithSingleDestination(push *model.PushContext, n
 	if err == nil {
 		tcpProxy.IdleTimeout = durationpb.New(idleTimeout)
 	}
+	tcpProxy.MaxConnectAttempts = maxConnectAttempts(configMeta)
 
 	tcpFilter := setAccessLogAndBuildTCPFilter(push, tcpProxy)
 	return buildNetworkFiltersStack(port, tcpFilter, statPrefix, clusterName)
@@ -103,6 +114,7 @@ func buildOutboundNetworkFiltersWithWeightedClusters(node *model.Proxy, routes [
 	if err == nil {
 		proxyConfig.IdleTimeout = durationpb.New(idleTimeout)
 	}
+	proxyConfig.MaxConnectAttempts = maxConnectAttempts(configMeta)
 
 	for _, route := range routes {
 		service := push.ServiceForHostname(node, host.Name(route.Destination.Host))
@@ -166,11 +178,26 @@ func buildOutboundNetworkFilters(node *model.Proxy,
 			statPrefix = util.BuildStatPrefix(push.Mesh.OutboundClusterStatName, routes[0].Destination.Host,
 				routes[0].Destination.Subset, port, service.Attributes)
 		}
-		return buildOutboundNetworkFiltersWithSingleDestination(push, node, statPrefix, clusterName, port)
+		return buildOutboundNetworkFiltersWithSingleDestination(push, node, statPrefix, clusterName, port, configMeta)
 	}
 	return buildOutboundNetworkFiltersWithWeightedClusters(node, routes, push, port, configMeta)
 }
 
+// maxConnectAttempts returns the TCP proxy connection attempts configured by TCPMaxConnectAttemptsAnnotation,
+// or nil to use the Envoy default of a single attempt.
+func maxConnectAttempts(configMeta config.Meta) *wrappers.UInt32Value {
+	v, f := configMeta.Annotations[TCPMaxConnectAttemptsAnnotation]
+	if !f {
+		return nil
+	}
+	attempts, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || attempts == 0 {
+		log.Warnf("ignoring invalid %s %q of %s/%s", TCPMaxConnectAttemptsAnnotation, v, configMeta.Namespace, configMeta.Name)
+		return nil
+	}
+	return &wrappers.UInt32Value{Value: uint32(attempts)}
+}
+
 // buildMongoFilter builds an outbound Envoy MongoProxy filter.
 func buildMongoFilter(statPrefix string) *listener.Filter {
 	// TODO: add a watcher for /var/lib/istio/mongo/certs
@@ -194,7 +221,7 @@ func buildMongoFilter(statPrefix string) *listener.Filter {
 func buildOutboundAutoPassthroughFilterStack(push *model.PushContext, node *model.Proxy, port *model.Port) []*listener.Filter {
 	// First build tcp with access logs
 	// then add sni_cluster to the front
-	tcpProxy := buildOutboundNetworkFiltersWithSingleDestination(push, node, util.BlackHoleCluster, util.BlackHoleCluster, port)
+	tcpProxy := buildOutboundNetworkFiltersWithSingleDestination(push, node, util.BlackHoleCluster, util.BlackHoleCluster, port, config.Meta{})
 	filterstack := make([]*listener.Filter, 0)
 	filterstack = append(filterstack, &listener.Filter{
 		Name: util.SniClusterFilter,
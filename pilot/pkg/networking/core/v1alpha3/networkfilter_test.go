@@ -99,7 +99,7 @@ func TestInboundNetworkFilterStatPrefix(t *testing.T) {
 				},
 			}
 
-			listeners := buildInboundNetworkFilters(env.PushContext, instance, model.BuildInboundSubsetKey(int(instance.Endpoint.EndpointPort)))
+			listeners := buildInboundNetworkFilters(env.PushContext, instance, model.BuildInboundSubsetKey(int(instance.Endpoint.EndpointPort)), "")
 			tcp := &tcp.TcpProxy{}
 			listeners[0].GetTypedConfig().UnmarshalTo(tcp)
 			if tcp.StatPrefix != tt.expectedStatPrefix {
@@ -221,3 +221,39 @@ func TestOutboundNetworkFilterStatPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestOutboundNetworkFilterMaxConnectAttempts(t *testing.T) {
+	services := []*model.Service{
+		buildService("test.com", "10.10.0.0/24", protocol.TCP, tnow),
+	}
+	routes := []*networking.RouteDestination{{
+		Destination: &networking.Destination{Host: "test.com", Port: &networking.PortSelector{Number: 9999}},
+	}}
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		expected    uint32
+	}{
+		{"no annotation", nil, 0},
+		{"annotation", map[string]string{TCPMaxConnectAttemptsAnnotation: "3"}, 3},
+		{"invalid annotation", map[string]string{TCPMaxConnectAttemptsAnnotation: "0"}, 0},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			env := buildListenerEnv(services)
+			env.PushContext.InitContext(env, nil, nil)
+
+			proxy := getProxy()
+			proxy.IstioVersion = model.ParseIstioVersion(proxy.Metadata.IstioVersion)
+			proxy.SidecarScope = model.DefaultSidecarScopeForNamespace(env.PushContext, "not-default")
+
+			meta := config.Meta{Name: "test.com", Namespace: "ns", Annotations: tt.annotations}
+			listeners := buildOutboundNetworkFilters(proxy, routes, env.PushContext, &model.Port{Port: 9999}, meta)
+			tcp := &tcp.TcpProxy{}
+			listeners[0].GetTypedConfig().UnmarshalTo(tcp)
+			if got := tcp.GetMaxConnectAttempts().GetValue(); got != tt.expected {
+				t.Fatalf("expected %d max connect attempts, got %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	xdsfault "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
+	xdshttpfault "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	"google.golang.org/grpc/codes"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/labels"
+)
+
+const (
+	// FaultResponseRateLimitAnnotation throttles the responses of all HTTP routes of a VirtualService
+	// to the given bandwidth, in KiB/s.
+	FaultResponseRateLimitAnnotation = "networking.istio.io/fault-response-rate-limit"
+	// FaultResponseRateLimitPercentageAnnotation sets the percentage of requests throttled by
+	// FaultResponseRateLimitAnnotation. Defaults to 100.
+	FaultResponseRateLimitPercentageAnnotation = "networking.istio.io/fault-response-rate-limit-percentage"
+	// FaultSourceLabelsAnnotation restricts the faults of a VirtualService to proxies with the given
+	// labels, written as "key=value[,key=value...]". Routing is not affected.
+	FaultSourceLabelsAnnotation = "networking.istio.io/fault-source-labels"
+)
+
+// buildFault translates the fault injection of a route, including the faults configured through
+// annotations of the virtual service. Nil is returned if no fault applies to the proxy.
+func buildFault(node *model.Proxy, in *networking.HTTPFaultInjection, virtualService config.Config) *xdshttpfault.HTTPFault {
+	if v, f := virtualService.Annotations[FaultSourceLabelsAnnotation]; f {
+		selector, err := parseFaultSourceLabels(v)
+		if err != nil {
+			log.Warnf("ignoring faults of virtual service %s/%s: %v", virtualService.Namespace, virtualService.Name, err)
+			return nil
+		}
+		if !selector.SubsetOf(node.Metadata.Labels) {
+			return nil
+		}
+	}
+
+	rateLimit, err := faultResponseRateLimit(virtualService.Annotations)
+	if err != nil {
+		log.Warnf("ignoring %s of virtual service %s/%s: %v",
+			FaultResponseRateLimitAnnotation, virtualService.Namespace, virtualService.Name, err)
+	}
+
+	out := translateFault(in)
+	if rateLimit == nil {
+		return out
+	}
+	if out == nil {
+		out = &xdshttpfault.HTTPFault{}
+	}
+	out.ResponseRateLimit = rateLimit
+	return out
+}
+
+func faultResponseRateLimit(annotations map[string]string) (*xdsfault.FaultRateLimit, error) {
+	v, f := annotations[FaultResponseRateLimitAnnotation]
+	if !f {
+		return nil, nil
+	}
+	kbps, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || kbps == 0 {
+		return nil, fmt.Errorf("invalid rate limit %q", v)
+	}
+	percentage := 100.0
+	if p, f := annotations[FaultResponseRateLimitPercentageAnnotation]; f {
+		percentage, err = strconv.ParseFloat(p, 64)
+		if err != nil || percentage < 0 || percentage > 100 {
+			return nil, fmt.Errorf("invalid percentage %q", p)
+		}
+	}
+	return &xdsfault.FaultRateLimit{
+		LimitType: &xdsfault.FaultRateLimit_FixedLimit_{
+			FixedLimit: &xdsfault.FaultRateLimit_FixedLimit{LimitKbps: kbps},
+		},
+		Percentage: translatePercentToFractionalPercent(&networking.Percent{Value: percentage}),
+	}, nil
+}
+
+func parseFaultSourceLabels(v string) (labels.Instance, error) {
+	out := labels.Instance{}
+	for _, kv := range strings.Split(v, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid source label %q", kv)
+		}
+		out[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return out, nil
+}
+
+// parseGrpcStatus parses a gRPC status code, given either by name (such as "UNAVAILABLE") or number.
+func parseGrpcStatus(s string) (codes.Code, error) {
+	var code codes.Code
+	v := strings.ToUpper(strings.TrimSpace(s))
+	if _, err := strconv.ParseUint(v, 10, 32); err != nil {
+		v = strconv.Quote(v)
+	}
+	if err := code.UnmarshalJSON([]byte(v)); err != nil {
+		return 0, fmt.Errorf("invalid gRPC status %q", s)
+	}
+	return code, nil
+}
@@ -268,19 +268,32 @@ func BuildHTTPRoutesForVirtualService(
 		return nil, fmt.Errorf("in not a virtual service: %#v", virtualService)
 	}
 
+	upgrades, err := parseUpgradeTypes(virtualService.Annotations)
+	if err != nil {
+		log.Warnf("ignoring %s of virtual service %s/%s: %v", UpgradeTypesAnnotation, virtualService.Namespace, virtualService.Name, err)
+	}
+	appendRoute := func(out []*route.Route, r *route.Route, http *networking.HTTPRoute) []*route.Route {
+		if types, f := upgrades.forRoute(http.Name); f {
+			if connectRoute := applyUpgradeTypes(r, types); connectRoute != nil {
+				out = append(out, connectRoute)
+			}
+		}
+		return append(out, r)
+	}
+
 	out := make([]*route.Route, 0, len(vs.Http))
 
 	catchall := false
 	for _, http := range vs.Http {
 		if len(http.Match) == 0 {
 			if r := translateRoute(push, node, http, nil, listenPort, virtualService, serviceRegistry, gatewayNames); r != nil {
-				out = append(out, r)
+				out = appendRoute(out, r, http)
 			}
 			catchall = true
 		} else {
 			for _, match := range http.Match {
 				if r := translateRoute(push, node, http, match, listenPort, virtualService, serviceRegistry, gatewayNames); r != nil {
-					out = append(out, r)
+					out = appendRoute(out, r, http)
 					// This is a catch all path. Routes are matched in order, so we will never go beyond this match
 					// As an optimization, we can just top sending any more routes here.
 					if isCatchAllMatch(match) {
@@ -423,7 +436,7 @@ func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.H
 		if in.Mirror != nil {
 			if mp := mirrorPercent(in); mp != nil {
 				action.RequestMirrorPolicies = []*route.RouteAction_RequestMirrorPolicy{{
-					Cluster:         GetDestinationCluster(in.Mirror, serviceRegistry[host.Name(in.Mirror.Host)], port),
+					Cluster:         GetDestinationCluster(in.Mirror, mirrorService(push, node, in.Mirror, serviceRegistry), port),
 					RuntimeFraction: mp,
 					TraceSampled:    &wrappers.BoolValue{Value: false},
 				}}
@@ -488,14 +501,31 @@ func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.H
 	out.Decorator = &route.Decorator{
 		Operation: getRouteOperation(out, virtualService.Name, port),
 	}
-	if fault := in.Fault; fault != nil {
+	if fault := buildFault(node, in.Fault, virtualService); fault != nil {
 		out.TypedPerFilterConfig = make(map[string]*any.Any)
-		out.TypedPerFilterConfig[wellknown.Fault] = util.MessageToAny(translateFault(in.Fault))
+		out.TypedPerFilterConfig[wellknown.Fault] = util.MessageToAny(fault)
 	}
 
 	return out
 }
 
+// mirrorService returns the service of a mirror destination. The services passed in for a listener port only
+// include those exposing that port, while mirrored traffic commonly goes to a service on a different port, often
+// one that only exists in a remote cluster. Fall back to any service visible to the proxy so that the mirror
+// cluster is named after the port the service actually exposes.
+func mirrorService(push *model.PushContext, node *model.Proxy, mirror *networking.Destination,
+	serviceRegistry map[host.Name]*model.Service) *model.Service {
+	hostname := host.Name(mirror.Host)
+	if svc := serviceRegistry[hostname]; svc != nil {
+		return svc
+	}
+	svc := push.ServiceForHostname(node, hostname)
+	if svc == nil {
+		log.Debugf("mirror destination %s not found for proxy %s", hostname, node.ID)
+	}
+	return svc
+}
+
 // SortHeaderValueOption type and the functions below (Len, Less and Swap) are for sort.Stable for type HeaderValueOption
 type SortHeaderValueOption []*core.HeaderValueOption
 
@@ -999,6 +1029,16 @@ func translateFault(in *networking.HTTPFaultInjection) *xdshttpfault.HTTPFault {
 			out.Abort.ErrorType = &xdshttpfault.FaultAbort_HttpStatus{
 				HttpStatus: uint32(a.HttpStatus),
 			}
+		case *networking.HTTPFaultInjection_Abort_GrpcStatus:
+			code, err := parseGrpcStatus(a.GrpcStatus)
+			if err != nil {
+				log.Warnf("Invalid gRPC status abort fault: %v", err)
+				out.Abort = nil
+				break
+			}
+			out.Abort.ErrorType = &xdshttpfault.FaultAbort_GrpcStatus{
+				GrpcStatus: uint32(code),
+			}
 		default:
 			log.Warnf("Non-HTTP type abort faults are not yet supported")
 			out.Abort = nil
@@ -25,6 +25,9 @@ import (
 	"github.com/gogo/protobuf/types"
 
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 )
 
@@ -459,3 +462,125 @@ func TestSourceMatchHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestUpgradeTypes(t *testing.T) {
+	upgrades, err := parseUpgradeTypes(map[string]string{
+		UpgradeTypesAnnotation: "tunnel=connect; chat=websocket,spdy/3.1; *=",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, f := (*upgradeTypes)(nil).forRoute("tunnel"); f {
+		t.Errorf("expected no upgrade types without annotation")
+	}
+	for name, want := range map[string][]string{
+		"tunnel": {"CONNECT"},
+		"chat":   {"websocket", "spdy/3.1"},
+		"other":  nil,
+	} {
+		got, f := upgrades.forRoute(name)
+		if !f || !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: expected %v, got %v (found %v)", name, want, got, f)
+		}
+	}
+	if _, err := parseUpgradeTypes(map[string]string{UpgradeTypesAnnotation: "a=web socket"}); err == nil {
+		t.Errorf("expected error for invalid upgrade type")
+	}
+
+	newRoute := func(prefix string) *route.Route {
+		return &route.Route{
+			Match:  &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: prefix}},
+			Action: &route.Route_Route{Route: &route.RouteAction{}},
+		}
+	}
+
+	r := newRoute("/")
+	if c := applyUpgradeTypes(r, []string{"websocket", "spdy/3.1"}); c != nil {
+		t.Errorf("unexpected CONNECT route %v", c)
+	}
+	if got := r.GetRoute().UpgradeConfigs; len(got) != 2 || got[1].UpgradeType != "spdy/3.1" || got[1].Enabled != nil {
+		t.Errorf("unexpected upgrade configs %v", got)
+	}
+
+	r = newRoute("/")
+	c := applyUpgradeTypes(r, []string{"CONNECT"})
+	if c == nil || c.Match.GetConnectMatcher() == nil {
+		t.Fatalf("expected CONNECT route, got %v", c)
+	}
+	got := r.GetRoute().UpgradeConfigs
+	if len(got) != 2 || got[0].ConnectConfig == nil || got[1].UpgradeType != "websocket" || got[1].Enabled.GetValue() {
+		t.Errorf("unexpected upgrade configs %v", got)
+	}
+
+	if c := applyUpgradeTypes(newRoute("/api"), []string{"CONNECT"}); c != nil {
+		t.Errorf("unexpected CONNECT route for URI match %v", c)
+	}
+}
+
+func TestMirrorService(t *testing.T) {
+	remote := &model.Service{
+		Hostname: "remote.ns.svc.cluster.local",
+		Ports:    model.PortList{{Name: "http", Port: 9090}},
+	}
+	push := model.NewPushContext()
+	push.ServiceIndex.HostnameAndNamespace[remote.Hostname] = map[string]*model.Service{"ns": remote}
+	node := &model.Proxy{ID: "app.ns"}
+	mirror := &networking.Destination{Host: string(remote.Hostname)}
+
+	// Not part of the services for the listener port, since it is only exposed on another port.
+	svc := mirrorService(push, node, mirror, map[host.Name]*model.Service{})
+	if got := GetDestinationCluster(mirror, svc, 8080); got != "outbound|9090||remote.ns.svc.cluster.local" {
+		t.Errorf("unexpected mirror cluster %v", got)
+	}
+	if svc := mirrorService(push, node, &networking.Destination{Host: "unknown"}, nil); svc != nil {
+		t.Errorf("unexpected service %v", svc)
+	}
+}
+
+func TestBuildFault(t *testing.T) {
+	abort := &networking.HTTPFaultInjection{
+		Abort: &networking.HTTPFaultInjection_Abort{
+			ErrorType:  &networking.HTTPFaultInjection_Abort_GrpcStatus{GrpcStatus: "unavailable"},
+			Percentage: &networking.Percent{Value: 50},
+		},
+	}
+	node := &model.Proxy{Metadata: &model.NodeMetadata{Labels: map[string]string{"app": "load", "version": "v1"}}}
+	vs := func(annotations map[string]string) config.Config {
+		return config.Config{Meta: config.Meta{Name: "vs", Namespace: "ns", Annotations: annotations}}
+	}
+
+	got := buildFault(node, abort, vs(nil))
+	if got.GetAbort().GetGrpcStatus() != 14 || got.ResponseRateLimit != nil {
+		t.Errorf("unexpected fault %v", got)
+	}
+
+	got = buildFault(node, nil, vs(map[string]string{
+		FaultResponseRateLimitAnnotation:           "64",
+		FaultResponseRateLimitPercentageAnnotation: "25",
+		FaultSourceLabelsAnnotation:                "app=load",
+	}))
+	if got.GetResponseRateLimit().GetFixedLimit().GetLimitKbps() != 64 || got.GetAbort() != nil {
+		t.Errorf("unexpected fault %v", got)
+	}
+
+	if got := buildFault(node, abort, vs(map[string]string{FaultSourceLabelsAnnotation: "app=other"})); got != nil {
+		t.Errorf("expected no fault for other source, got %v", got)
+	}
+	if got := buildFault(node, nil, vs(map[string]string{FaultResponseRateLimitAnnotation: "fast"})); got != nil {
+		t.Errorf("expected no fault for invalid rate limit, got %v", got)
+	}
+}
+
+func TestParseGrpcStatus(t *testing.T) {
+	for in, want := range map[string]uint32{"UNAVAILABLE": 14, "deadline_exceeded": 4, "13": 13} {
+		got, err := parseGrpcStatus(in)
+		if err != nil || uint32(got) != want {
+			t.Errorf("%v: expected %v, got %v (%v)", in, want, got, err)
+		}
+	}
+	for _, in := range []string{"", "NOT_A_CODE", "100"} {
+		if _, err := parseGrpcStatus(in); err == nil {
+			t.Errorf("%v: expected error", in)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"fmt"
+	"strings"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+const (
+	// UpgradeTypesAnnotation configures the HTTP upgrade types allowed on the routes of a VirtualService.
+	// The value is a ";" separated list of "<http route name>=<type>[,<type>...]" entries. An entry
+	// without a route name, or with the name "*", applies to all routes without an entry of their own.
+	// For example: "tunnel=CONNECT;chat=websocket,spdy/3.1".
+	//
+	// Routes with an entry only allow the listed types, so websocket, which is otherwise allowed
+	// everywhere, must be listed explicitly. CONNECT requests are terminated by the proxy and their
+	// payload is forwarded to the destination as raw TCP.
+	UpgradeTypesAnnotation = "networking.istio.io/upgrade-types"
+
+	upgradeWebsocket = "websocket"
+	upgradeConnect   = "CONNECT"
+)
+
+// upgradeTypes holds the upgrade types allowed on each route of a VirtualService.
+type upgradeTypes struct {
+	byRoute map[string][]string
+}
+
+// parseUpgradeTypes parses the UpgradeTypesAnnotation. A nil result means the annotation is not set.
+func parseUpgradeTypes(annotations map[string]string) (*upgradeTypes, error) {
+	v, f := annotations[UpgradeTypesAnnotation]
+	if !f {
+		return nil, nil
+	}
+	out := &upgradeTypes{byRoute: map[string][]string{}}
+	for _, entry := range strings.Split(v, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, types := "*", entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			name, types = strings.TrimSpace(entry[:i]), entry[i+1:]
+		}
+		var parsed []string
+		for _, t := range strings.Split(types, ",") {
+			t = strings.TrimSpace(t)
+			if t == "" {
+				continue
+			}
+			if strings.EqualFold(t, upgradeConnect) {
+				t = upgradeConnect
+			} else if strings.ContainsAny(t, " \t=") {
+				return nil, fmt.Errorf("invalid upgrade type %q", t)
+			}
+			parsed = append(parsed, t)
+		}
+		if name == "" {
+			name = "*"
+		}
+		out.byRoute[name] = parsed
+	}
+	return out, nil
+}
+
+// forRoute returns the upgrade types allowed on the named route, and whether they are configured at all.
+func (u *upgradeTypes) forRoute(name string) ([]string, bool) {
+	if u == nil {
+		return nil, false
+	}
+	if t, f := u.byRoute[name]; f {
+		return t, true
+	}
+	t, f := u.byRoute["*"]
+	return t, f
+}
+
+// applyUpgradeTypes configures the upgrades allowed on a route, overriding the connection manager defaults.
+// CONNECT requests have no path and are not matched by regular route matches, so if CONNECT is allowed on
+// a route without a URI match, an additional route matching them is returned and must be placed in front
+// of the given one.
+func applyUpgradeTypes(r *route.Route, types []string) *route.Route {
+	action := r.GetRoute()
+	if action == nil {
+		// Redirects are never upgraded.
+		return nil
+	}
+	websocket, connect := false, false
+	for _, t := range types {
+		switch t {
+		case upgradeWebsocket:
+			websocket = true
+		case upgradeConnect:
+			connect = true
+			action.UpgradeConfigs = append(action.UpgradeConfigs, &route.RouteAction_UpgradeConfig{
+				UpgradeType:   upgradeConnect,
+				ConnectConfig: &route.RouteAction_UpgradeConfig_ConnectConfig{},
+			})
+			continue
+		}
+		action.UpgradeConfigs = append(action.UpgradeConfigs, &route.RouteAction_UpgradeConfig{UpgradeType: t})
+	}
+	if !websocket {
+		// Websocket upgrades are allowed by the connection manager, so they need to be disabled explicitly.
+		action.UpgradeConfigs = append(action.UpgradeConfigs, &route.RouteAction_UpgradeConfig{
+			UpgradeType: upgradeWebsocket,
+			Enabled:     &wrappers.BoolValue{Value: false},
+		})
+	}
+	if !connect || r.Match.GetPrefix() != "/" {
+		return nil
+	}
+	connectRoute := proto.Clone(r).(*route.Route)
+	connectRoute.Match.PathSpecifier = &route.RouteMatch_ConnectMatcher_{ConnectMatcher: &route.RouteMatch_ConnectMatcher{}}
+	connectRoute.Match.CaseSensitive = nil
+	connectRoute.Match.QueryParameters = nil
+	return connectRoute
+}
@@ -0,0 +1,362 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// Route sharding splits the outbound route configuration of a sidecar port which is larger than
+// features.RouteShardingThresholdBytes into several route configurations, each holding a group of virtual
+// hosts. The HTTP connection manager of the port selects the shard by the Host header with scoped routes,
+// so that a change only updates the shard holding the changed virtual host, and a rejected shard only
+// affects the hosts it holds.
+//
+// Sidecars which can reach more than features.ScopedRoutesOnDemandServiceThreshold services instead fetch the
+// scopes with scoped RDS. Each scope holds a single virtual host, and its route configuration is only fetched
+// once the sidecar receives traffic for one of its domains. The ports whose routing the scopes cannot reproduce,
+// such as those passing through traffic to unknown hosts, keep their route configuration.
+
+const (
+	routeShardSeparator   = "/shard-"
+	scopedRouteNamePrefix = "scoped/"
+)
+
+// routeShardName returns the name of a shard of a route configuration.
+func routeShardName(routeName string, shard int) string {
+	return routeName + routeShardSeparator + strconv.Itoa(shard)
+}
+
+// parseRouteShardName returns the route configuration and the index of a shard name.
+func parseRouteShardName(name string) (string, int, bool) {
+	i := strings.LastIndex(name, routeShardSeparator)
+	if i == -1 {
+		return "", 0, false
+	}
+	shard, err := strconv.Atoi(name[i+len(routeShardSeparator):])
+	if err != nil || shard < 0 {
+		return "", 0, false
+	}
+	return name[:i], shard, true
+}
+
+// exactDomains returns the domains of a virtual host which are not wildcards, and can be used as scope keys.
+func exactDomains(vh *route.VirtualHost) []string {
+	out := make([]string, 0, len(vh.Domains))
+	for _, d := range vh.Domains {
+		if !strings.Contains(d, "*") {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// onDemandScopedRoutes returns whether the outbound routes of a sidecar are fetched on demand with scoped RDS.
+func onDemandScopedRoutes(node *model.Proxy) bool {
+	return features.ScopedRoutesOnDemandServiceThreshold > 0 && node.SidecarScope != nil &&
+		len(node.SidecarScope.Services()) > features.ScopedRoutesOnDemandServiceThreshold
+}
+
+// scopedRouteName returns the name of the route configuration holding a single outbound virtual host, named
+// after the host and port it serves. The name does not depend on the listener, as scopes fetched with scoped
+// RDS are shared by all the listeners of a proxy.
+func scopedRouteName(vhostName string) string {
+	return scopedRouteNamePrefix + vhostName
+}
+
+// parseScopedRouteName returns the outbound route configuration and the virtual host of a scoped route name.
+func parseScopedRouteName(name string) (string, string, bool) {
+	if !strings.HasPrefix(name, scopedRouteNamePrefix) {
+		return "", "", false
+	}
+	vhostName := strings.TrimPrefix(name, scopedRouteNamePrefix)
+	i := strings.LastIndex(vhostName, ":")
+	if i == -1 {
+		return "", "", false
+	}
+	if _, err := strconv.Atoi(vhostName[i+1:]); err != nil {
+		return "", "", false
+	}
+	return vhostName[i+1:], vhostName, true
+}
+
+// routeConfigurationTemplate returns a copy of a route configuration without its virtual hosts.
+func routeConfigurationTemplate(rc *route.RouteConfiguration) *route.RouteConfiguration {
+	vhosts := rc.VirtualHosts
+	rc.VirtualHosts = nil
+	template := proto.Clone(rc).(*route.RouteConfiguration)
+	rc.VirtualHosts = vhosts
+	return template
+}
+
+// scopedRouteConfiguration returns the route configuration holding only the named virtual host of rc.
+func scopedRouteConfiguration(rc *route.RouteConfiguration, routeName, vhostName string) *route.RouteConfiguration {
+	for _, vh := range rc.VirtualHosts {
+		if vh.Name == vhostName {
+			out := routeConfigurationTemplate(rc)
+			out.Name = routeName
+			out.VirtualHosts = []*route.VirtualHost{vh}
+			return out
+		}
+	}
+	return emptyRouteConfiguration(routeName)
+}
+
+// shardRouteConfiguration partitions the virtual hosts of a route configuration, in order, into route
+// configurations of at most maxBytes, unless a single virtual host is larger. Virtual hosts without an exact
+// domain cannot be selected by the Host header and are dropped.
+func shardRouteConfiguration(rc *route.RouteConfiguration, maxBytes int) []*route.RouteConfiguration {
+	// Copy all settings but the virtual hosts into each shard.
+	template := routeConfigurationTemplate(rc)
+
+	var shards []*route.RouteConfiguration
+	var current *route.RouteConfiguration
+	size := 0
+	for _, vh := range rc.VirtualHosts {
+		if len(exactDomains(vh)) == 0 {
+			continue
+		}
+		vhSize := proto.Size(vh)
+		if current == nil || (len(current.VirtualHosts) > 0 && size+vhSize > maxBytes) {
+			current = proto.Clone(template).(*route.RouteConfiguration)
+			current.Name = routeShardName(rc.Name, len(shards))
+			shards = append(shards, current)
+			size = proto.Size(current)
+		}
+		current.VirtualHosts = append(current.VirtualHosts, vh)
+		size += vhSize
+	}
+	return shards
+}
+
+// scopedRoutesForShards returns the scoped routes selecting the shard of a request by its Host header.
+func scopedRoutesForShards(routeName string, shards []*route.RouteConfiguration) *hcm.ScopedRoutes {
+	scopes := make([]*route.ScopedRouteConfiguration, 0)
+	for _, shard := range shards {
+		for _, vh := range shard.VirtualHosts {
+			for _, domain := range exactDomains(vh) {
+				scopes = append(scopes, &route.ScopedRouteConfiguration{
+					Name:                   shard.Name + "/" + domain,
+					RouteConfigurationName: shard.Name,
+					Key:                    hostScopeKey(domain),
+				})
+			}
+		}
+	}
+	return &hcm.ScopedRoutes{
+		Name:            routeName,
+		ScopeKeyBuilder: hostScopeKeyBuilder(),
+		RdsConfigSource: adsConfigSource(),
+		ConfigSpecifier: &hcm.ScopedRoutes_ScopedRouteConfigurationsList{
+			ScopedRouteConfigurationsList: &hcm.ScopedRouteConfigurationsList{
+				ScopedRouteConfigurations: scopes,
+			},
+		},
+	}
+}
+
+// onDemandScopedRoutesConfig returns the scoped routes fetching their scopes, and the routes of the scopes
+// actually used, with scoped RDS.
+func onDemandScopedRoutesConfig() *hcm.ScopedRoutes {
+	return &hcm.ScopedRoutes{
+		Name:            util.OnDemandScopedRoutes,
+		ScopeKeyBuilder: hostScopeKeyBuilder(),
+		RdsConfigSource: adsConfigSource(),
+		ConfigSpecifier: &hcm.ScopedRoutes_ScopedRds{
+			ScopedRds: &hcm.ScopedRds{
+				ScopedRdsConfigSource: adsConfigSource(),
+			},
+		},
+	}
+}
+
+// hostScopeKeyBuilder builds the scope key of a request from its Host header.
+func hostScopeKeyBuilder() *hcm.ScopedRoutes_ScopeKeyBuilder {
+	return &hcm.ScopedRoutes_ScopeKeyBuilder{
+		Fragments: []*hcm.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder{{
+			Type: &hcm.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder_HeaderValueExtractor_{
+				HeaderValueExtractor: &hcm.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder_HeaderValueExtractor{
+					Name: ":authority",
+				},
+			},
+		}},
+	}
+}
+
+func hostScopeKey(domain string) *route.ScopedRouteConfiguration_Key {
+	return &route.ScopedRouteConfiguration_Key{
+		Fragments: []*route.ScopedRouteConfiguration_Key_Fragment{{
+			Type: &route.ScopedRouteConfiguration_Key_Fragment_StringKey{StringKey: domain},
+		}},
+	}
+}
+
+func adsConfigSource() *core.ConfigSource {
+	return &core.ConfigSource{
+		ConfigSourceSpecifier: &core.ConfigSource_Ads{
+			Ads: &core.AggregatedConfigSource{},
+		},
+		InitialFetchTimeout: durationpb.New(0),
+		ResourceApiVersion:  core.ApiVersion_V3,
+	}
+}
+
+// BuildScopedRoutes returns the scopes fetched on demand by the outbound HTTP listeners of a sidecar.
+func (configgen *ConfigGeneratorImpl) BuildScopedRoutes(node *model.Proxy, push *model.PushContext) []*route.ScopedRouteConfiguration {
+	scopes, _ := configgen.onDemandScopes(node, push)
+	return scopes
+}
+
+// onDemandScopes returns the scopes fetched on demand by the outbound HTTP listeners of a sidecar, and the names of
+// the outbound route configurations replaced by them.
+//
+// The scopes are fetched with scoped RDS, so they are shared by all the listeners of the proxy, and their keys
+// only match a Host header exactly. The scopes of each port are built from its own route configuration, which is
+// only replaced if the scopes select the same virtual host for each of its domains, and if it has no virtual host
+// for the hosts without a scope other than the one rejecting them. The listeners of the other ports keep their
+// route configuration, and its catch all virtual host.
+func (configgen *ConfigGeneratorImpl) onDemandScopes(node *model.Proxy,
+	push *model.PushContext) ([]*route.ScopedRouteConfiguration, map[string]struct{}) {
+	if node.Type != model.SidecarProxy || !onDemandScopedRoutes(node) {
+		return nil, nil
+	}
+	portSet := make(map[int]struct{})
+	for _, svc := range node.SidecarScope.Services() {
+		for _, port := range svc.Ports {
+			if port.Protocol.IsHTTP() || port.Protocol.IsUnsupported() {
+				portSet[port.Port] = struct{}{}
+			}
+		}
+	}
+	ports := make([]int, 0, len(portSet))
+	for port := range portSet {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	type portScopes struct {
+		routeName string
+		domains   map[string]string
+	}
+	efw := push.EnvoyFilters(node)
+	vHostCache := make(map[int][]*route.VirtualHost)
+	candidates := make([]portScopes, 0, len(ports))
+	// A domain served by different virtual hosts on several ports, such as the short name of a service with
+	// several ports, cannot be keyed by a single scope.
+	owners := make(map[string]string)
+	conflicts := make(map[string]struct{})
+	for _, port := range ports {
+		routeName := strconv.Itoa(port)
+		domains, ok := scopedDomains(configgen.buildSidecarOutboundRoute(node, push, routeName, vHostCache, efw))
+		if !ok {
+			continue
+		}
+		for domain, scopedRoute := range domains {
+			if owner, f := owners[domain]; f && owner != scopedRoute {
+				conflicts[domain] = struct{}{}
+			}
+			owners[domain] = scopedRoute
+		}
+		candidates = append(candidates, portScopes{routeName: routeName, domains: domains})
+	}
+
+	routes := make(map[string]struct{})
+	seen := make(map[string]struct{})
+	scopes := make([]*route.ScopedRouteConfiguration, 0)
+	for _, c := range candidates {
+		conflicting := false
+		for domain := range c.domains {
+			if _, f := conflicts[domain]; f {
+				conflicting = true
+				break
+			}
+		}
+		if conflicting {
+			continue
+		}
+		routes[c.routeName] = struct{}{}
+		domains := make([]string, 0, len(c.domains))
+		for domain := range c.domains {
+			domains = append(domains, domain)
+		}
+		sort.Strings(domains)
+		for _, domain := range domains {
+			if _, f := seen[domain]; f {
+				continue
+			}
+			seen[domain] = struct{}{}
+			scopes = append(scopes, &route.ScopedRouteConfiguration{
+				OnDemand:               true,
+				Name:                   domain,
+				RouteConfigurationName: c.domains[domain],
+				Key:                    hostScopeKey(domain),
+			})
+		}
+	}
+	return scopes, routes
+}
+
+// scopedDomains returns the scoped route configuration serving each domain of a route configuration, or false if
+// a virtual host other than the one rejecting unknown hosts cannot be selected by scopes, as it has a wildcard
+// domain or does not serve a single port.
+func scopedDomains(rc *route.RouteConfiguration) (map[string]string, bool) {
+	out := make(map[string]string)
+	for _, vh := range rc.VirtualHosts {
+		if vh.Name == util.BlackHole {
+			continue
+		}
+		routeName := scopedRouteName(vh.Name)
+		if _, _, ok := parseScopedRouteName(routeName); !ok {
+			return nil, false
+		}
+		domains := exactDomains(vh)
+		if len(domains) == 0 || len(domains) != len(vh.Domains) {
+			return nil, false
+		}
+		for _, domain := range domains {
+			out[domain] = routeName
+		}
+	}
+	return out, true
+}
+
+// buildSidecarOutboundScopedRoutes returns the scoped routes of a sidecar outbound route configuration which
+// is replaced by the scopes fetched on demand or exceeds the sharding threshold, or nil if it is served whole.
+func (configgen *ConfigGeneratorImpl) buildSidecarOutboundScopedRoutes(node *model.Proxy, push *model.PushContext,
+	routeName string, onDemandRoutes map[string]struct{}) *hcm.ScopedRoutes {
+	if _, f := onDemandRoutes[routeName]; f {
+		return onDemandScopedRoutesConfig()
+	}
+	if features.RouteShardingThresholdBytes <= 0 {
+		return nil
+	}
+	rc := configgen.buildSidecarOutboundRoute(node, push, routeName, make(map[int][]*route.VirtualHost), push.EnvoyFilters(node))
+	if proto.Size(rc) <= features.RouteShardingThresholdBytes {
+		return nil
+	}
+	return scopedRoutesForShards(routeName, shardRouteConfiguration(rc, features.RouteShardingThresholdBytes))
+}
@@ -0,0 +1,200 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/visibility"
+)
+
+func TestParseRouteShardName(t *testing.T) {
+	cases := []struct {
+		name  string
+		route string
+		shard int
+		ok    bool
+	}{
+		{routeShardName("80", 0), "80", 0, true},
+		{routeShardName("foo.bar:8080", 12), "foo.bar:8080", 12, true},
+		{"80", "", 0, false},
+		{"80/shard-x", "", 0, false},
+		{"80/shard--1", "", 0, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r, shard, ok := parseRouteShardName(tt.name)
+			if r != tt.route || shard != tt.shard || ok != tt.ok {
+				t.Fatalf("got %v %v %v, want %v %v %v", r, shard, ok, tt.route, tt.shard, tt.ok)
+			}
+		})
+	}
+}
+
+func TestShardRouteConfiguration(t *testing.T) {
+	vh := func(name string, domains ...string) *route.VirtualHost {
+		return &route.VirtualHost{Name: name, Domains: domains}
+	}
+	rc := &route.RouteConfiguration{
+		Name:             "80",
+		ValidateClusters: wrapperspb.Bool(false),
+		VirtualHosts: []*route.VirtualHost{
+			vh("a", "a.default.svc.cluster.local", "a"),
+			vh("wildcard", "*.example.com"),
+			vh("b", "b.default.svc.cluster.local"),
+			vh("c", "c.default.svc.cluster.local"),
+		},
+	}
+	// Room for two virtual hosts per shard.
+	maxBytes := proto.Size(rc.VirtualHosts[0]) + proto.Size(rc.VirtualHosts[2]) + 10
+	shards := shardRouteConfiguration(rc, maxBytes)
+
+	var got [][]string
+	for i, shard := range shards {
+		if shard.Name != routeShardName("80", i) {
+			t.Fatalf("shard %d has name %v", i, shard.Name)
+		}
+		if !proto.Equal(shard.ValidateClusters, rc.ValidateClusters) {
+			t.Fatalf("shard %d did not keep the route configuration settings", i)
+		}
+		var names []string
+		for _, v := range shard.VirtualHosts {
+			names = append(names, v.Name)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{{"a", "b"}, {"c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got shards %v, want %v", got, want)
+	}
+	if len(rc.VirtualHosts) != 4 {
+		t.Fatalf("route configuration was modified: %v", rc.VirtualHosts)
+	}
+
+	scoped := scopedRoutesForShards("80", shards)
+	keys := map[string]string{}
+	for _, s := range scoped.GetScopedRouteConfigurationsList().GetScopedRouteConfigurations() {
+		keys[s.Key.Fragments[0].GetStringKey()] = s.RouteConfigurationName
+	}
+	wantKeys := map[string]string{
+		"a.default.svc.cluster.local": "80/shard-0",
+		"a":                           "80/shard-0",
+		"b.default.svc.cluster.local": "80/shard-0",
+		"c.default.svc.cluster.local": "80/shard-1",
+	}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Fatalf("got scope keys %v, want %v", keys, wantKeys)
+	}
+}
+
+func TestParseScopedRouteName(t *testing.T) {
+	cases := []struct {
+		name  string
+		route string
+		vhost string
+		ok    bool
+	}{
+		{scopedRouteName("foo.default.svc.cluster.local:8080"), "8080", "foo.default.svc.cluster.local:8080", true},
+		{"foo.default.svc.cluster.local:8080", "", "", false},
+		{scopedRouteName("allow_any"), "", "", false},
+		{scopedRouteName("foo:bar"), "", "", false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r, vhost, ok := parseScopedRouteName(tt.name)
+			if r != tt.route || vhost != tt.vhost || ok != tt.ok {
+				t.Fatalf("got %v %v %v, want %v %v %v", r, vhost, ok, tt.route, tt.vhost, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBuildScopedRoutes(t *testing.T) {
+	services := []*model.Service{
+		buildHTTPService("a.default.svc.cluster.local", visibility.Public, "", "default", 8080, 80),
+		buildHTTPService("b.default.svc.cluster.local", visibility.Public, "", "default", 8080),
+		buildHTTPService("c.default.svc.cluster.local", visibility.Public, "", "default", 9090),
+		buildHTTPService("d.default.svc.cluster.local", visibility.Public, "", "default", 9090),
+	}
+	cg := NewConfigGenTest(t, TestOptions{Services: services})
+	proxy := cg.SetupProxy(nil)
+	if scopes := cg.ConfigGen.BuildScopedRoutes(proxy, cg.PushContext()); len(scopes) != 0 {
+		t.Fatalf("expected no scopes with on demand routes disabled, got %v", scopes)
+	}
+
+	old := features.ScopedRoutesOnDemandServiceThreshold
+	features.ScopedRoutesOnDemandServiceThreshold = 1
+	defer func() { features.ScopedRoutesOnDemandServiceThreshold = old }()
+
+	// Passthrough traffic has no scope, so every port keeps its route configuration.
+	if scopes, routes := cg.ConfigGen.onDemandScopes(proxy, cg.PushContext()); len(scopes) != 0 || len(routes) != 0 {
+		t.Fatalf("expected no scopes with passthrough traffic, got %v for %v", scopes, routes)
+	}
+
+	mesh := testMesh()
+	mesh.OutboundTrafficPolicy = &meshconfig.MeshConfig_OutboundTrafficPolicy{Mode: meshconfig.MeshConfig_OutboundTrafficPolicy_REGISTRY_ONLY}
+	cg = NewConfigGenTest(t, TestOptions{Services: services, MeshConfig: &mesh})
+	proxy = cg.SetupProxy(nil)
+	scopes, routes := cg.ConfigGen.onDemandScopes(proxy, cg.PushContext())
+	// The short name of a is served by ports 80 and 8080, which cannot share a scope for it.
+	if !reflect.DeepEqual(routes, map[string]struct{}{"9090": {}}) {
+		t.Fatalf("expected only port 9090 to be scoped, got %v", routes)
+	}
+	got := map[string]string{}
+	for _, s := range scopes {
+		if !s.OnDemand {
+			t.Fatalf("scope %v is not fetched on demand", s.Name)
+		}
+		got[s.Key.Fragments[0].GetStringKey()] = s.RouteConfigurationName
+	}
+	want := map[string]string{
+		"c":                                "scoped/c.default.svc.cluster.local:9090",
+		"c:9090":                           "scoped/c.default.svc.cluster.local:9090",
+		"c.default.svc.cluster.local":      "scoped/c.default.svc.cluster.local:9090",
+		"c.default.svc.cluster.local:9090": "scoped/c.default.svc.cluster.local:9090",
+		"d.default.svc.cluster.local:9090": "scoped/d.default.svc.cluster.local:9090",
+	}
+	for key, routeName := range want {
+		if got[key] != routeName {
+			t.Errorf("scope %v: got route %q, want %q", key, got[key], routeName)
+		}
+	}
+	for key := range got {
+		if strings.HasPrefix(key, "a") || strings.HasPrefix(key, "b") {
+			t.Errorf("unexpected scope %v for a port keeping its route configuration", key)
+		}
+	}
+
+	for port, scoped := range map[string]bool{"9090": true, "8080": false, "80": false} {
+		sr := cg.ConfigGen.buildSidecarOutboundScopedRoutes(proxy, cg.PushContext(), port, routes)
+		if (sr.GetScopedRds() != nil) != scoped {
+			t.Errorf("port %v: got scoped routes %v, want scoped RDS %v", port, sr, scoped)
+		}
+	}
+
+	res := cg.ConfigGen.BuildHTTPRoutes(proxy, cg.PushContext(), []string{"scoped/d.default.svc.cluster.local:9090"})
+	if len(res) != 1 || len(res[0].VirtualHosts) != 1 || res[0].VirtualHosts[0].Name != "d.default.svc.cluster.local:9090" {
+		t.Fatalf("unexpected scoped route: %v", res)
+	}
+}
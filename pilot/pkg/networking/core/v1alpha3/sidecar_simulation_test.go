@@ -34,6 +34,7 @@ import (
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/simulation"
 	"istio.io/istio/pilot/pkg/xds"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pilot/test/xdstest"
 	cluster2 "istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
@@ -1064,6 +1065,143 @@ spec:
 	})
 }
 
+func TestInboundTproxy(t *testing.T) {
+	// Inbound clusters default to localhost without inbound passthrough, which TPROXY must override.
+	defaultValue := features.EnableInboundPassthrough
+	features.EnableInboundPassthrough = false
+	defer func() { features.EnableInboundPassthrough = defaultValue }()
+
+	svc := `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+spec:
+  hosts:
+  - foo.bar
+  endpoints:
+  - address: 1.1.1.1
+  location: MESH_INTERNAL
+  resolution: STATIC
+  ports:
+  - name: tcp
+    number: 70
+    protocol: TCP
+  - name: http
+    number: 80
+    protocol: HTTP
+  - name: auto
+    number: 81
+---
+`
+	sidecar := `
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: sidecar
+spec:
+  ingress:
+  - port:
+      number: 9080
+      protocol: HTTP
+      name: http
+    defaultEndpoint: 127.0.0.1:8080
+---
+`
+	for _, mode := range []string{"DISABLE", "PERMISSIVE", "STRICT"} {
+		t.Run(mode, func(t *testing.T) {
+			calls := []simulation.Expect{
+				{
+					Name:   "mtls tcp",
+					Call:   simulation.Call{Port: 70, Protocol: simulation.TCP, TLS: simulation.MTLS, CallMode: simulation.CallModeInbound},
+					Result: simulation.Result{ClusterMatched: "inbound|70||"},
+				},
+				{
+					Name:   "mtls auto",
+					Call:   simulation.Call{Port: 81, Protocol: simulation.HTTP, TLS: simulation.MTLS, CallMode: simulation.CallModeInbound},
+					Result: simulation.Result{ClusterMatched: "inbound|81||"},
+				},
+				{
+					Name: "passthrough mtls",
+					Call: simulation.Call{
+						Address: "1.2.3.4", Port: 82, Protocol: simulation.HTTP, TLS: simulation.MTLS,
+						CallMode: simulation.CallModeInbound,
+					},
+					Result: simulation.Result{ClusterMatched: "InboundPassthroughClusterIpv4"},
+				},
+			}
+			plaintext := simulation.Result{ClusterMatched: "inbound|70||"}
+			if mode == "STRICT" {
+				plaintext = simulation.Result{Error: simulation.ErrNoFilterChain}
+			}
+			calls = append(calls, simulation.Expect{
+				Name:   "plaintext tcp",
+				Call:   simulation.Call{Port: 70, Protocol: simulation.TCP, CallMode: simulation.CallModeInbound},
+				Result: plaintext,
+			})
+			proxy := &model.Proxy{Metadata: &model.NodeMetadata{InterceptionMode: model.InterceptionTproxy}}
+			runSimulationTest(t, proxy, xds.FakeOptions{}, simulationTest{
+				config: svc + fmt.Sprintf(`apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: istio-system
+spec:
+  mtls:
+    mode: %s
+`, mode),
+				calls: calls,
+			})
+
+			// The source address of inbound connections is preserved on all inbound paths.
+			s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: svc})
+			sim := simulation.NewSimulation(t, s, s.SetupProxy(&model.Proxy{
+				Metadata: &model.NodeMetadata{InterceptionMode: model.InterceptionTproxy},
+			}))
+			assertSourcePreserved(t, sim)
+		})
+	}
+
+	t.Run("sidecar localhost endpoint", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: svc + sidecar})
+		sim := simulation.NewSimulation(t, s, s.SetupProxy(&model.Proxy{
+			Metadata: &model.NodeMetadata{InterceptionMode: model.InterceptionTproxy},
+		}))
+		assertSourcePreserved(t, sim)
+		got := xdstest.ExtractClusterEndpoints(sim.Clusters)["inbound|9080||"]
+		if want := []string{"1.1.1.1:8080"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected endpoints %v, got %v", want, got)
+		}
+	})
+}
+
+// assertSourcePreserved asserts inbound connections of a TPROXY proxy keep their source address: the virtual inbound
+// listener is transparent and restores the source address, and no inbound cluster connects to localhost, which does
+// not accept connections from remote addresses.
+func assertSourcePreserved(t *testing.T, sim *simulation.Simulation) {
+	t.Helper()
+	l := xdstest.ExtractListener(model.VirtualInboundListenerName, sim.Listeners)
+	if l == nil {
+		t.Fatalf("virtual inbound listener not found in %v", xdstest.ExtractListenerNames(sim.Listeners))
+	}
+	if !l.Transparent.GetValue() {
+		t.Errorf("expected transparent virtual inbound listener")
+	}
+	if _, f := xdstest.ExtractListenerFilters(l)[xdsfilters.OriginalSrcFilterName]; !f {
+		t.Errorf("expected listener filter %s", xdsfilters.OriginalSrcFilterName)
+	}
+	for name, eps := range xdstest.ExtractClusterEndpoints(sim.Clusters) {
+		if !strings.HasPrefix(name, "inbound|") && !strings.HasPrefix(name, "InboundPassthroughCluster") {
+			continue
+		}
+		for _, ep := range eps {
+			if strings.HasPrefix(ep, "127.") || strings.HasPrefix(ep, "::1") {
+				t.Errorf("inbound cluster %s connects to localhost %s", name, ep)
+			}
+		}
+	}
+}
+
 func TestHeadlessServices(t *testing.T) {
 	ports := `
   - name: http
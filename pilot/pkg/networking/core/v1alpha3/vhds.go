@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"strings"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// Sidecars which can reach more than features.VirtualHostDiscoveryServiceThreshold services receive their
+// outbound route configurations without the virtual hosts matched by exact domains. The on demand filter
+// requests the virtual host of a host with VHDS, named <route configuration>/<host>, the first time a request
+// does not match any virtual host.
+
+// vhdsEnabled returns whether a sidecar discovers its outbound virtual hosts on demand with VHDS.
+func vhdsEnabled(node *model.Proxy) bool {
+	return features.VirtualHostDiscoveryServiceThreshold > 0 && node.SidecarScope != nil &&
+		len(node.SidecarScope.Services()) > features.VirtualHostDiscoveryServiceThreshold
+}
+
+// virtualHostResourceName returns the VHDS resource name of a virtual host, or of a host, of a route configuration.
+func virtualHostResourceName(routeName, host string) string {
+	return routeName + "/" + host
+}
+
+// parseVirtualHostResourceName returns the route configuration and the host of a VHDS resource name.
+func parseVirtualHostResourceName(name string) (string, string, bool) {
+	i := strings.Index(name, "/")
+	if i <= 0 || i == len(name)-1 {
+		return "", "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+// hasWildcardDomain returns whether a virtual host has a wildcard domain other than the catch all one.
+func hasWildcardDomain(vh *route.VirtualHost) bool {
+	for _, d := range vh.Domains {
+		if d != "*" && strings.Contains(d, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// vhdsRouteConfiguration returns the route configuration sent with RDS to a sidecar discovering its virtual hosts
+// with VHDS. It only keeps the virtual hosts with a wildcard domain, which cannot be requested by host. The catch
+// all virtual host is dropped, as it would match requests before their virtual host is requested.
+func vhdsRouteConfiguration(rc *route.RouteConfiguration) *route.RouteConfiguration {
+	out := routeConfigurationTemplate(rc)
+	out.VirtualHosts = make([]*route.VirtualHost, 0)
+	for _, vh := range rc.VirtualHosts {
+		if hasWildcardDomain(vh) {
+			out.VirtualHosts = append(out.VirtualHosts, vh)
+		}
+	}
+	out.Vhds = &route.Vhds{ConfigSource: adsConfigSource()}
+	return out
+}
+
+// BuildVirtualHosts returns the virtual hosts requested with VHDS by a sidecar. Each virtual host is named after
+// its route configuration and its name, and aliased by the requested names it serves. A requested host without a
+// virtual host is returned without a resource, so the proxy fails the request instead of waiting for it.
+func (configgen *ConfigGeneratorImpl) BuildVirtualHosts(node *model.Proxy, push *model.PushContext,
+	resourceNames []string) []*discovery.Resource {
+	if node.Type != model.SidecarProxy || !vhdsEnabled(node) {
+		return nil
+	}
+	efw := push.EnvoyFilters(node)
+	vHostCache := make(map[int][]*route.VirtualHost)
+	// Virtual hosts served by VHDS, by route configuration and domain.
+	vhostsByRoute := make(map[string]map[string]*route.VirtualHost)
+	resources := make(map[string]*discovery.Resource)
+	out := make([]*discovery.Resource, 0, len(resourceNames))
+	for _, name := range resourceNames {
+		routeName, host, ok := parseVirtualHostResourceName(name)
+		if !ok {
+			continue
+		}
+		vhosts, f := vhostsByRoute[routeName]
+		if !f {
+			vhosts = make(map[string]*route.VirtualHost)
+			rc := configgen.buildSidecarOutboundRoute(node, push, routeName, vHostCache, efw)
+			for _, vh := range rc.VirtualHosts {
+				if hasWildcardDomain(vh) {
+					continue
+				}
+				for _, d := range exactDomains(vh) {
+					vhosts[d] = vh
+				}
+			}
+			vhostsByRoute[routeName] = vhosts
+		}
+		vh, f := vhosts[host]
+		if !f {
+			out = append(out, &discovery.Resource{Name: name, Aliases: []string{name}})
+			continue
+		}
+		resourceName := virtualHostResourceName(routeName, vh.Name)
+		if r, f := resources[resourceName]; f {
+			r.Aliases = append(r.Aliases, name)
+			continue
+		}
+		r := &discovery.Resource{
+			Name:     resourceName,
+			Aliases:  []string{name},
+			Resource: util.MessageToAny(vh),
+		}
+		resources[resourceName] = r
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"reflect"
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+func TestVhdsRouteConfiguration(t *testing.T) {
+	rc := &route.RouteConfiguration{
+		Name: "80",
+		VirtualHosts: []*route.VirtualHost{
+			{Name: "a.default.svc.cluster.local:80", Domains: []string{"a.default.svc.cluster.local", "a"}},
+			{Name: "*.example.com:80", Domains: []string{"*.example.com", "*.example.com:80"}},
+			{Name: "allow_any", Domains: []string{"*"}},
+		},
+	}
+	out := vhdsRouteConfiguration(rc)
+	var got []string
+	for _, vh := range out.VirtualHosts {
+		got = append(got, vh.Name)
+	}
+	if !reflect.DeepEqual(got, []string{"*.example.com:80"}) {
+		t.Fatalf("got virtual hosts %v", got)
+	}
+	if out.Vhds.GetConfigSource().GetAds() == nil {
+		t.Fatalf("expected VHDS from ADS, got %v", out.Vhds)
+	}
+	if len(rc.VirtualHosts) != 3 {
+		t.Fatalf("route configuration was modified: %v", rc.VirtualHosts)
+	}
+}
+
+func TestParseVirtualHostResourceName(t *testing.T) {
+	cases := []struct {
+		name  string
+		route string
+		host  string
+		ok    bool
+	}{
+		{"80/foo.default", "80", "foo.default", true},
+		{"foo.bar:8080/foo.bar:8080", "foo.bar:8080", "foo.bar:8080", true},
+		{"80", "", "", false},
+		{"/foo", "", "", false},
+		{"80/", "", "", false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r, h, ok := parseVirtualHostResourceName(tt.name)
+			if r != tt.route || h != tt.host || ok != tt.ok {
+				t.Fatalf("got %v %v %v, want %v %v %v", r, h, ok, tt.route, tt.host, tt.ok)
+			}
+		})
+	}
+}
@@ -64,6 +64,9 @@ const (
 	Passthrough = "allow_any"
 	// PassthroughFilterChain to catch traffic that doesn't match other filter chains.
 	PassthroughFilterChain = "PassthroughFilterChain"
+	// OnDemandScopedRoutes is the name of the scoped routes of sidecar outbound HTTP listeners fetching their
+	// routes on demand.
+	OnDemandScopedRoutes = "outbound-on-demand"
 
 	// Inbound pass through cluster need to the bind the loopback ip address for the security and loop avoidance.
 	InboundPassthroughClusterIpv4 = "InboundPassthroughClusterIpv4"
@@ -76,6 +79,10 @@ const (
 	// regarding the virtual service or destination rule used for each
 	IstioMetadataKey = "istio"
 
+	// ProvenanceMetadataKey is the field of the istio metadata listing the configs which produced a listener,
+	// cluster or route, if features.EnableConfigProvenanceMetadata is enabled.
+	ProvenanceMetadataKey = "provenance"
+
 	// EnvoyTransportSocketMetadataKey is the key under which metadata is added to an endpoint
 	// which determines the endpoint level transport socket configuration.
 	EnvoyTransportSocketMetadataKey = "envoy.transport_socket_match"
@@ -374,8 +381,7 @@ func AddConfigInfoMetadata(metadata *core.Metadata, config config.Meta) *core.Me
 			FilterMetadata: map[string]*pstruct.Struct{},
 		}
 	}
-	s := "/apis/" + config.GroupVersionKind.Group + "/" + config.GroupVersionKind.Version + "/namespaces/" + config.Namespace + "/" +
-		strcase.CamelCaseToKebabCase(config.GroupVersionKind.Kind) + "/" + config.Name
+	s := configPath(config)
 	if _, ok := metadata.FilterMetadata[IstioMetadataKey]; !ok {
 		metadata.FilterMetadata[IstioMetadataKey] = &pstruct.Struct{
 			Fields: map[string]*pstruct.Value{},
@@ -386,9 +392,50 @@ func AddConfigInfoMetadata(metadata *core.Metadata, config config.Meta) *core.Me
 			StringValue: s,
 		},
 	}
+	return AddConfigProvenanceMetadata(metadata, config)
+}
+
+// AddConfigProvenanceMetadata adds the config to the provenance list of the given core.Metadata struct, which
+// lists all the configs that produced the owning listener, cluster or route. If metadata is not initialized,
+// build a new metadata.
+// As it grows the configuration of proxies, this is a no-op unless features.EnableConfigProvenanceMetadata is set.
+func AddConfigProvenanceMetadata(metadata *core.Metadata, config config.Meta) *core.Metadata {
+	if !features.EnableConfigProvenanceMetadata {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = &core.Metadata{}
+	}
+	if metadata.FilterMetadata == nil {
+		metadata.FilterMetadata = map[string]*pstruct.Struct{}
+	}
+	if _, ok := metadata.FilterMetadata[IstioMetadataKey]; !ok {
+		metadata.FilterMetadata[IstioMetadataKey] = &pstruct.Struct{
+			Fields: map[string]*pstruct.Value{},
+		}
+	}
+	fields := metadata.FilterMetadata[IstioMetadataKey].Fields
+	list := fields[ProvenanceMetadataKey].GetListValue()
+	if list == nil {
+		list = &pstruct.ListValue{}
+		fields[ProvenanceMetadataKey] = &pstruct.Value{Kind: &pstruct.Value_ListValue{ListValue: list}}
+	}
+	s := configPath(config)
+	for _, v := range list.Values {
+		if v.GetStringValue() == s {
+			return metadata
+		}
+	}
+	list.Values = append(list.Values, &pstruct.Value{Kind: &pstruct.Value_StringValue{StringValue: s}})
 	return metadata
 }
 
+// configPath returns the path of a config, as in the Kubernetes API.
+func configPath(config config.Meta) string {
+	return "/apis/" + config.GroupVersionKind.Group + "/" + config.GroupVersionKind.Version + "/namespaces/" + config.Namespace + "/" +
+		strcase.CamelCaseToKebabCase(config.GroupVersionKind.Kind) + "/" + config.Name
+}
+
 // AddSubsetToMetadata will insert the subset name supplied. This should be called after the initial
 // "istio" metadata has been created for the cluster. If the "istio" metadata field is not already
 // defined, the subset information will not be added (to prevent adding this information where not
@@ -479,10 +526,12 @@ func MergeAnyWithAny(dst *any.Any, src *any.Any) (*any.Any, error) {
 	return retVal, nil
 }
 
-// BuildLbEndpointMetadata adds metadata values to a lb endpoint
-func BuildLbEndpointMetadata(networkID network.ID, tlsMode, workloadname, namespace string,
+// BuildLbEndpointMetadata adds metadata values to a lb endpoint.
+// The capture of the endpoint is only added if it is in the mesh, to keep the EDS of other endpoints small.
+func BuildLbEndpointMetadata(networkID network.ID, tlsMode string, capture model.WorkloadCapture, workloadname, namespace string,
 	clusterID cluster.ID, labels labels.Instance) *core.Metadata {
-	if networkID == "" && (tlsMode == "" || tlsMode == model.DisabledTLSModeLabel) && !features.EndpointTelemetryLabel {
+	inMesh := capture != "" && capture != model.CaptureNone
+	if networkID == "" && (tlsMode == "" || tlsMode == model.DisabledTLSModeLabel) && !inMesh && !features.EndpointTelemetryLabel {
 		return nil
 	}
 
@@ -490,12 +539,17 @@ func BuildLbEndpointMetadata(networkID network.ID, tlsMode, workloadname, namesp
 		FilterMetadata: map[string]*pstruct.Struct{},
 	}
 
-	if tlsMode != "" && tlsMode != model.DisabledTLSModeLabel {
-		metadata.FilterMetadata[EnvoyTransportSocketMetadataKey] = &pstruct.Struct{
-			Fields: map[string]*pstruct.Value{
-				model.TLSModeLabelShortname: {Kind: &pstruct.Value_StringValue{StringValue: tlsMode}},
-			},
+	if (tlsMode != "" && tlsMode != model.DisabledTLSModeLabel) || inMesh {
+		transportSocket := &pstruct.Struct{
+			Fields: map[string]*pstruct.Value{},
 		}
+		if tlsMode != "" && tlsMode != model.DisabledTLSModeLabel {
+			transportSocket.Fields[model.TLSModeLabelShortname] = &pstruct.Value{Kind: &pstruct.Value_StringValue{StringValue: tlsMode}}
+		}
+		if inMesh {
+			transportSocket.Fields[model.CaptureLabelShortname] = &pstruct.Value{Kind: &pstruct.Value_StringValue{StringValue: string(capture)}}
+		}
+		metadata.FilterMetadata[EnvoyTransportSocketMetadataKey] = transportSocket
 	}
 
 	// Add compressed telemetry metadata. Note this is a short term solution to make server workload metadata
@@ -650,6 +704,32 @@ func MeshConfigToEnvoyForwardClientCertDetails(c meshconfig.Topology_ForwardClie
 	return http_conn.HttpConnectionManager_ForwardClientCertDetails(c - 1)
 }
 
+// ParseNAT64Prefix parses a NAT64 prefix, such as the well-known prefix 64:ff9b::/96.
+// Only /96 prefixes are supported, where the IPv4 address forms the last 32 bits.
+func ParseNAT64Prefix(prefix string) (*net.IPNet, error) {
+	_, cidr, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if ones, bits := cidr.Mask.Size(); cidr.IP.To4() != nil || bits != 128 || ones != 96 {
+		return nil, fmt.Errorf("NAT64 prefix %s must be an IPv6 /96 prefix", prefix)
+	}
+	return cidr, nil
+}
+
+// NAT64Address synthesizes the IPv6 address for an IPv4 address behind a NAT64 gateway, per RFC 6052.
+// Other addresses are returned unchanged.
+func NAT64Address(prefix *net.IPNet, addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() == nil {
+		return addr
+	}
+	out := make(net.IP, net.IPv6len)
+	copy(out, prefix.IP.To16())
+	copy(out[12:], ip.To4())
+	return out.String()
+}
+
 // ByteCount returns a human readable byte format
 // Inspired by https://yourbasic.org/golang/formatting-byte-size-to-human-readable-format/
 func ByteCount(b int) string {
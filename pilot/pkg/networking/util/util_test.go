@@ -541,6 +541,46 @@ func TestAddConfigInfoMetadata(t *testing.T) {
 	}
 }
 
+func TestAddConfigProvenanceMetadata(t *testing.T) {
+	dr := config.Meta{
+		Name:             "svcA",
+		Namespace:        "default",
+		GroupVersionKind: collections.IstioNetworkingV1Alpha3Destinationrules.Resource().GroupVersionKind(),
+	}
+	ef := config.Meta{
+		Name:             "filter",
+		Namespace:        "istio-system",
+		GroupVersionKind: collections.IstioNetworkingV1Alpha3Envoyfilters.Resource().GroupVersionKind(),
+	}
+	provenance := func(md *core.Metadata) []string {
+		var out []string
+		for _, v := range md.GetFilterMetadata()[IstioMetadataKey].GetFields()[ProvenanceMetadataKey].GetListValue().GetValues() {
+			out = append(out, v.GetStringValue())
+		}
+		return out
+	}
+
+	if md := AddConfigProvenanceMetadata(nil, dr); md != nil {
+		t.Fatalf("expected no metadata with provenance disabled, got %v", md)
+	}
+
+	features.EnableConfigProvenanceMetadata = true
+	defer func() { features.EnableConfigProvenanceMetadata = false }()
+	md := BuildConfigInfoMetadata(dr)
+	md = AddConfigProvenanceMetadata(md, ef)
+	md = AddConfigProvenanceMetadata(md, dr)
+	want := []string{
+		"/apis/networking.istio.io/v1alpha3/namespaces/default/destination-rule/svcA",
+		"/apis/networking.istio.io/v1alpha3/namespaces/istio-system/envoy-filter/filter",
+	}
+	if got := provenance(md); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got provenance %v, want %v", got, want)
+	}
+	if got := md.FilterMetadata[IstioMetadataKey].Fields["config"].GetStringValue(); got != want[0] {
+		t.Fatalf("got config %v, want %v", got, want[0])
+	}
+}
+
 func TestAddSubsetToMetadata(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -1137,6 +1177,7 @@ func TestEndpointMetadata(t *testing.T) {
 		name         string
 		network      network.ID
 		tlsMode      string
+		capture      model.WorkloadCapture
 		workloadName string
 		clusterID    cluster.ID
 		namespace    string
@@ -1192,6 +1233,62 @@ func TestEndpointMetadata(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:         "tls mode and capture",
+			tlsMode:      model.IstioMutualTLSModeLabel,
+			capture:      model.CaptureTunnel,
+			network:      "",
+			workloadName: "",
+			clusterID:    "",
+			want: &core.Metadata{
+				FilterMetadata: map[string]*structpb.Struct{
+					EnvoyTransportSocketMetadataKey: {
+						Fields: map[string]*structpb.Value{
+							model.TLSModeLabelShortname: {
+								Kind: &structpb.Value_StringValue{
+									StringValue: model.IstioMutualTLSModeLabel,
+								},
+							},
+							model.CaptureLabelShortname: {
+								Kind: &structpb.Value_StringValue{
+									StringValue: string(model.CaptureTunnel),
+								},
+							},
+						},
+					},
+					IstioMetadataKey: {
+						Fields: map[string]*structpb.Value{
+							"workload": {
+								Kind: &structpb.Value_StringValue{
+									StringValue: ";;;;",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:         "no capture",
+			tlsMode:      model.DisabledTLSModeLabel,
+			capture:      model.CaptureNone,
+			network:      "",
+			workloadName: "",
+			clusterID:    "",
+			want: &core.Metadata{
+				FilterMetadata: map[string]*structpb.Struct{
+					IstioMetadataKey: {
+						Fields: map[string]*structpb.Value{
+							"workload": {
+								Kind: &structpb.Value_StringValue{
+									StringValue: ";;;;",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:         "network and tls mode",
 			tlsMode:      model.IstioMutualTLSModeLabel,
@@ -1318,7 +1415,7 @@ func TestEndpointMetadata(t *testing.T) {
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := BuildLbEndpointMetadata(tt.network, tt.tlsMode, tt.workloadName, tt.namespace, tt.clusterID, tt.labels); !reflect.DeepEqual(got, tt.want) {
+			if got := BuildLbEndpointMetadata(tt.network, tt.tlsMode, tt.capture, tt.workloadName, tt.namespace, tt.clusterID, tt.labels); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Unexpected Endpoint metadata got %v, want %v", got, tt.want)
 			}
 		})
@@ -1343,3 +1440,30 @@ func TestByteCount(t *testing.T) {
 		})
 	}
 }
+
+func TestNAT64Address(t *testing.T) {
+	prefix, err := ParseNAT64Prefix("64:ff9b::/96")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		in  string
+		out string
+	}{
+		{"192.0.2.33", "64:ff9b::c000:221"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"not-an-ip", "not-an-ip"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := NAT64Address(prefix, tt.in); got != tt.out {
+				t.Fatalf("got %v wanted %v", got, tt.out)
+			}
+		})
+	}
+	for _, invalid := range []string{"64:ff9b::/64", "10.0.0.0/8", "invalid"} {
+		if _, err := ParseNAT64Prefix(invalid); err == nil {
+			t.Errorf("expected error for %v", invalid)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	networkinglisters "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/kube"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("networkpolicy", "Kubernetes NetworkPolicy evaluation", 0)
+
+// Controller evaluates the Kubernetes NetworkPolicies of a single cluster.
+type Controller struct {
+	clusterID cluster.ID
+
+	policyInformer cache.SharedIndexInformer
+	policies       networkinglisters.NetworkPolicyLister
+
+	namespaceInformer cache.SharedIndexInformer
+	namespaces        listerv1.NamespaceLister
+
+	mu sync.Mutex
+	// sourceKeys caches the source keys by workload. It is cleared, and its generation incremented, whenever the
+	// policies or the namespace labels change.
+	sourceKeys map[string]string
+	generation uint64
+}
+
+// maxSourceKeys bounds the number of workloads whose source key is cached.
+const maxSourceKeys = 10000
+
+var _ model.NetworkPolicyEvaluator = &Controller{}
+
+// NewController creates a Controller for the cluster of the given client.
+func NewController(client kube.Client, clusterID cluster.ID) *Controller {
+	policies := client.KubeInformer().Networking().V1().NetworkPolicies()
+	namespaces := client.KubeInformer().Core().V1().Namespaces()
+	return &Controller{
+		clusterID:         clusterID,
+		policyInformer:    policies.Informer(),
+		policies:          policies.Lister(),
+		namespaceInformer: namespaces.Informer(),
+		namespaces:        namespaces.Lister(),
+	}
+}
+
+// AddEventHandler registers a handler called whenever a change may affect the result of Allowed.
+func (c *Controller) AddEventHandler(handler func()) {
+	f := func() {
+		c.clearSourceKeys()
+		handler()
+	}
+	c.policyInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			f()
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			f()
+		},
+		DeleteFunc: func(obj interface{}) {
+			f()
+		},
+	})
+	// Namespace labels are used by namespace selectors; other namespace changes are irrelevant.
+	c.namespaceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			f()
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			oldNs, ok1 := old.(*v1.Namespace)
+			curNs, ok2 := cur.(*v1.Namespace)
+			if ok1 && ok2 && reflect.DeepEqual(oldNs.Labels, curNs.Labels) {
+				return
+			}
+			f()
+		},
+		DeleteFunc: func(obj interface{}) {
+			f()
+		},
+	})
+}
+
+// HasSynced returns true once the NetworkPolicies and Namespaces have been loaded.
+func (c *Controller) HasSynced() bool {
+	return c.policyInformer.HasSynced() && c.namespaceInformer.HasSynced()
+}
+
+// Allowed implements model.NetworkPolicyEvaluator. Traffic is allowed only if it is permitted by both
+// the egress policies of the source and the ingress policies of the destination.
+func (c *Controller) Allowed(clusterID cluster.ID, src, dst model.NetworkPolicyWorkload, port uint32) bool {
+	if clusterID != c.clusterID {
+		// Policies of other clusters are not known.
+		return true
+	}
+	return c.allowed(dst, src, port, networkingv1.PolicyTypeIngress) &&
+		c.allowed(src, dst, port, networkingv1.PolicyTypeEgress)
+}
+
+// allowed checks whether the policies of the given type selecting subject permit traffic with peer.
+// As in Kubernetes, a workload not selected by any policy of that type is not isolated.
+func (c *Controller) allowed(subject, peer model.NetworkPolicyWorkload, port uint32, policyType networkingv1.PolicyType) bool {
+	if subject.Namespace == "" {
+		return true
+	}
+	policies, err := c.policies.NetworkPolicies(subject.Namespace).List(klabels.Everything())
+	if err != nil {
+		log.Debugf("failed to list network policies in %s: %v", subject.Namespace, err)
+		return true
+	}
+	isolated := false
+	for _, policy := range policies {
+		if !hasPolicyType(policy, policyType) || !selectorMatches(&policy.Spec.PodSelector, subject.Labels) {
+			continue
+		}
+		isolated = true
+		if policyType == networkingv1.PolicyTypeIngress {
+			for _, rule := range policy.Spec.Ingress {
+				if c.peersMatch(policy.Namespace, rule.From, peer) && portsMatch(rule.Ports, port) {
+					return true
+				}
+			}
+		} else {
+			for _, rule := range policy.Spec.Egress {
+				if c.peersMatch(policy.Namespace, rule.To, peer) && portsMatch(rule.Ports, port) {
+					return true
+				}
+			}
+		}
+	}
+	return !isolated
+}
+
+// SourceKey implements model.NetworkPolicyEvaluator. The traffic allowed from a workload only depends on the
+// egress policies selecting it and on the peers of ingress rules matching it, so the key lists both.
+func (c *Controller) SourceKey(clusterID cluster.ID, src model.NetworkPolicyWorkload) string {
+	if clusterID != c.clusterID || src.Namespace == "" {
+		// All the traffic is allowed.
+		return ""
+	}
+	workload := src.Namespace + "/" + src.IP + "/" + src.Labels.String()
+	c.mu.Lock()
+	key, f := c.sourceKeys[workload]
+	generation := c.generation
+	c.mu.Unlock()
+	if f {
+		return key
+	}
+
+	policies, err := c.policies.List(klabels.Everything())
+	if err != nil {
+		log.Debugf("failed to list network policies: %v", err)
+		return workload
+	}
+	var selected []string
+	for _, policy := range policies {
+		name := policy.Namespace + "/" + policy.Name
+		if policy.Namespace == src.Namespace && hasPolicyType(policy, networkingv1.PolicyTypeEgress) &&
+			selectorMatches(&policy.Spec.PodSelector, src.Labels) {
+			selected = append(selected, "egress/"+name)
+		}
+		if !hasPolicyType(policy, networkingv1.PolicyTypeIngress) {
+			continue
+		}
+		for i, rule := range policy.Spec.Ingress {
+			for j, peer := range rule.From {
+				if c.peerMatches(policy.Namespace, peer, src) {
+					selected = append(selected, fmt.Sprintf("ingress/%s/%d/%d", name, i, j))
+				}
+			}
+		}
+	}
+	sort.Strings(selected)
+	key = strings.Join(selected, ",")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The key is not cached if the policies changed while it was computed.
+	if c.generation == generation {
+		if c.sourceKeys == nil || len(c.sourceKeys) >= maxSourceKeys {
+			c.sourceKeys = make(map[string]string)
+		}
+		c.sourceKeys[workload] = key
+	}
+	return key
+}
+
+func (c *Controller) clearSourceKeys() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sourceKeys = nil
+	c.generation++
+}
+
+func hasPolicyType(policy *networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		// Ingress is always implied, egress only if there are egress rules.
+		return policyType == networkingv1.PolicyTypeIngress || len(policy.Spec.Egress) > 0
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Controller) peersMatch(policyNamespace string, peers []networkingv1.NetworkPolicyPeer, w model.NetworkPolicyWorkload) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if c.peerMatches(policyNamespace, peer, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Controller) peerMatches(policyNamespace string, peer networkingv1.NetworkPolicyPeer, w model.NetworkPolicyWorkload) bool {
+	if peer.IPBlock != nil {
+		return ipBlockMatches(peer.IPBlock, w.IP)
+	}
+	if peer.NamespaceSelector == nil {
+		if w.Namespace != policyNamespace {
+			return false
+		}
+	} else {
+		ns, err := c.namespaces.Get(w.Namespace)
+		if err != nil {
+			// Unknown namespace; don't risk pruning a reachable endpoint.
+			return true
+		}
+		if !selectorMatches(peer.NamespaceSelector, ns.Labels) {
+			return false
+		}
+	}
+	return peer.PodSelector == nil || selectorMatches(peer.PodSelector, w.Labels)
+}
+
+func selectorMatches(selector *metav1.LabelSelector, lbls map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		// Kubernetes rejects invalid selectors, so this should not happen.
+		return true
+	}
+	return s.Matches(klabels.Set(lbls))
+}
+
+func ipBlockMatches(block *networkingv1.IPBlock, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil || !cidr.Contains(addr) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, e, err := net.ParseCIDR(except); err == nil && e.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func portsMatch(ports []networkingv1.NetworkPolicyPort, port uint32) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		if p.Protocol != nil && *p.Protocol != v1.ProtocolTCP {
+			continue
+		}
+		if p.Port == nil {
+			return true
+		}
+		if p.Port.Type == intstr.String {
+			// Named ports refer to container ports, which are not known here. Assume a match.
+			return true
+		}
+		start := uint32(p.Port.IntVal)
+		end := start
+		if p.EndPort != nil {
+			end = uint32(*p.EndPort)
+		}
+		if port >= start && port <= end {
+			return true
+		}
+	}
+	return false
+}
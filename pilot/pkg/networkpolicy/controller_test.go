@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestAllowed(t *testing.T) {
+	port := intstr.FromInt(8080)
+	objects := []*networkingv1.NetworkPolicy{
+		{
+			// Only allow ingress to "db" from "app" pods in the same namespace, on 8080.
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}}}},
+					Ports: []networkingv1.NetworkPolicyPort{{Port: &port}},
+				}},
+			},
+		},
+		{
+			// Allow ingress to "api" from namespaces labeled team=a.
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}}},
+				}},
+			},
+		},
+		{
+			// Deny all egress from "sandbox" pods.
+			ObjectMeta: metav1.ObjectMeta{Name: "sandbox", Namespace: "dev"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "sandbox"}},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			},
+		},
+	}
+	client := kube.NewFakeClient(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"team": "a"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev", Labels: map[string]string{"team": "b"}}},
+		objects[0], objects[1], objects[2],
+	)
+	c := NewController(client, "cluster")
+	stop := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+	})
+	client.RunAndWait(stop)
+	cache.WaitForCacheSync(stop, c.HasSynced)
+
+	workload := func(ns, app string) model.NetworkPolicyWorkload {
+		return model.NetworkPolicyWorkload{Namespace: ns, Labels: map[string]string{"app": app}, IP: "10.0.0.1"}
+	}
+	cases := []struct {
+		name    string
+		cluster string
+		src     model.NetworkPolicyWorkload
+		dst     model.NetworkPolicyWorkload
+		port    uint32
+		allowed bool
+	}{
+		{"selected pod and port", "cluster", workload("prod", "app"), workload("prod", "db"), 8080, true},
+		{"wrong port", "cluster", workload("prod", "app"), workload("prod", "db"), 9090, false},
+		{"wrong pod", "cluster", workload("prod", "other"), workload("prod", "db"), 8080, false},
+		{"wrong namespace", "cluster", workload("dev", "app"), workload("prod", "db"), 8080, false},
+		{"namespace selector", "cluster", workload("prod", "other"), workload("prod", "api"), 80, true},
+		{"namespace selector mismatch", "cluster", workload("dev", "other"), workload("prod", "api"), 80, false},
+		{"not isolated", "cluster", workload("dev", "other"), workload("prod", "web"), 80, true},
+		{"egress denied", "cluster", workload("dev", "sandbox"), workload("prod", "web"), 80, false},
+		{"other cluster", "remote", workload("prod", "other"), workload("prod", "db"), 8080, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Allowed(cluster.ID(tt.cluster), tt.src, tt.dst, tt.port); got != tt.allowed {
+				t.Fatalf("expected allowed=%v, got %v", tt.allowed, got)
+			}
+		})
+	}
+}
+
+func TestSourceKey(t *testing.T) {
+	client := kube.NewFakeClient(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"team": "a"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev", Labels: map[string]string{"team": "b"}}},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}}}},
+				}},
+			},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "sandbox", Namespace: "dev"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "sandbox"}},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			},
+		},
+	)
+	c := NewController(client, "cluster")
+	stop := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+	})
+	client.RunAndWait(stop)
+	cache.WaitForCacheSync(stop, c.HasSynced)
+
+	key := func(ns, app, ip string) string {
+		return c.SourceKey("cluster", model.NetworkPolicyWorkload{Namespace: ns, Labels: map[string]string{"app": app}, IP: ip})
+	}
+	// Workloads selected by the same policies share their key, whatever their IP, labels or namespace.
+	if key("prod", "web", "10.0.0.1") != key("dev", "other", "10.0.0.2") {
+		t.Fatalf("expected the workloads not selected by any policy to share their key")
+	}
+	if key("prod", "app", "10.0.0.1") != key("prod", "app", "10.0.0.2") {
+		t.Fatalf("expected the workloads selected by the same peer to share their key")
+	}
+	for _, other := range []string{key("prod", "app", "10.0.0.1"), key("dev", "sandbox", "10.0.0.1")} {
+		if other == key("prod", "web", "10.0.0.1") {
+			t.Fatalf("expected the workloads selected by a policy to have their own key")
+		}
+	}
+}
+
+func TestIPBlockMatches(t *testing.T) {
+	block := &networkingv1.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}}
+	for ip, want := range map[string]bool{
+		"10.0.0.1": true,
+		"10.0.1.1": false,
+		"10.1.0.1": false,
+		"invalid":  false,
+	} {
+		if got := ipBlockMatches(block, ip); got != want {
+			t.Errorf("%v: expected %v, got %v", ip, want, got)
+		}
+	}
+}
@@ -16,7 +16,9 @@ package utils
 
 import (
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -62,13 +64,38 @@ func BuildInboundTLS(mTLSMode model.MutualTLSMode, node *model.Proxy,
 		ctx.CommonTlsContext.AlpnProtocols = util.ALPNHttp
 	}
 
-	// Set Minimum TLS version to match the default client version and allowed strong cipher suites for sidecars.
-	ctx.CommonTlsContext.TlsParams = &tls.TlsParameters{
-		TlsMinimumProtocolVersion: tls.TlsParameters_TLSv1_2,
-		CipherSuites:              SupportedCiphers,
+	if profile := authn_model.MeshTLSProfile(); profile != nil {
+		ctx.CommonTlsContext.TlsParams = profile.TLSParameters()
+	} else {
+		// Set Minimum TLS version to match the default client version and allowed strong cipher suites for sidecars.
+		ctx.CommonTlsContext.TlsParams = &tls.TlsParameters{
+			TlsMinimumProtocolVersion: tls.TlsParameters_TLSv1_2,
+			CipherSuites:              SupportedCiphers,
+		}
 	}
+	applyMTLSTuning(ctx)
+	ctx.CommonTlsContext.TlsParams = authn_model.EnforceFIPS(ctx.CommonTlsContext.TlsParams)
 
 	authn_model.ApplyToCommonTLSContext(ctx.CommonTlsContext, node, []string{}, /*subjectAltNames*/
 		trustDomainAliases, ctx.RequireClientCertificate.Value)
 	return ctx
 }
+
+// applyMTLSTuning applies the cipher suites, curves and session resumption settings of the istio mTLS
+// connections configured in istiod.
+func applyMTLSTuning(ctx *tls.DownstreamTlsContext) {
+	if len(features.MTLSCipherSuites) > 0 {
+		ctx.CommonTlsContext.TlsParams.CipherSuites = features.MTLSCipherSuites
+	}
+	if len(features.MTLSECDHCurves) > 0 {
+		ctx.CommonTlsContext.TlsParams.EcdhCurves = features.MTLSECDHCurves
+	}
+	if features.MTLSSessionTimeout > 0 {
+		ctx.SessionTimeout = durationpb.New(features.MTLSSessionTimeout)
+	}
+	if features.MTLSDisableSessionTickets {
+		ctx.SessionTicketKeysType = &tls.DownstreamTlsContext_DisableStatelessSessionResumption{
+			DisableStatelessSessionResumption: true,
+		}
+	}
+}
@@ -17,6 +17,7 @@ package builder
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	tcppb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
@@ -27,6 +28,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 
 	"istio.io/api/annotation"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -63,6 +65,9 @@ type Builder struct {
 	denyPolicies  []model.AuthorizationPolicy
 	allowPolicies []model.AuthorizationPolicy
 	auditPolicies []model.AuthorizationPolicy
+
+	// shared, if set, keeps the filters built for ALLOW/DENY/AUDIT actions to share them with other workloads.
+	shared *model.AuthorizationPolicies
 }
 
 // New returns a new builder for the given workload with the authorization policy.
@@ -86,13 +91,17 @@ func New(trustDomainBundle trustdomain.Bundle, in *plugin.InputParams, option Op
 	if len(policies.Deny) == 0 && len(policies.Allow) == 0 && len(policies.Audit) == 0 {
 		return nil
 	}
-	return &Builder{
+	b := &Builder{
 		denyPolicies:      policies.Deny,
 		allowPolicies:     policies.Allow,
 		auditPolicies:     policies.Audit,
 		trustDomainBundle: trustDomainBundle,
 		option:            option,
 	}
+	if features.EnableAuthzFilterSharing {
+		b.shared = in.Push.AuthzPolicies
+	}
+	return b
 }
 
 // BuildHTTP returns the HTTP filters built from the authorization policy.
@@ -106,6 +115,29 @@ func (b Builder) BuildHTTP() []*httppb.HttpFilter {
 		return nil
 	}
 
+	if b.shared == nil {
+		return b.buildLocalHTTP()
+	}
+	built, shared := b.shared.SharedFilters(b.sharedKey("HTTP"), func() interface{} {
+		return b.buildLocalHTTP()
+	})
+	if shared {
+		b.option.Logger.AppendDebugf("reused the HTTP filters built for the same policies")
+	}
+	// The filters may be patched by EnvoyFilters, so each workload gets its own. The typed configs are only ever
+	// replaced, not modified, so they are shared.
+	var filters []*httppb.HttpFilter
+	for _, f := range built.([]*httppb.HttpFilter) {
+		filters = append(filters, &httppb.HttpFilter{
+			Name:       f.Name,
+			ConfigType: &httppb.HttpFilter_TypedConfig{TypedConfig: f.GetTypedConfig()},
+		})
+	}
+	return filters
+}
+
+// buildLocalHTTP returns the HTTP filters built for ALLOW/DENY/AUDIT actions.
+func (b Builder) buildLocalHTTP() []*httppb.HttpFilter {
 	var filters []*httppb.HttpFilter
 	if configs := b.build(b.auditPolicies, rbacpb.RBAC_LOG, false); configs != nil {
 		b.option.Logger.AppendDebugf("built %d HTTP filters for AUDIT action", len(configs.http))
@@ -132,6 +164,27 @@ func (b Builder) BuildTCP() []*tcppb.Filter {
 		return nil
 	}
 
+	if b.shared == nil {
+		return b.buildLocalTCP()
+	}
+	built, shared := b.shared.SharedFilters(b.sharedKey("TCP"), func() interface{} {
+		return b.buildLocalTCP()
+	})
+	if shared {
+		b.option.Logger.AppendDebugf("reused the TCP filters built for the same policies")
+	}
+	var filters []*tcppb.Filter
+	for _, f := range built.([]*tcppb.Filter) {
+		filters = append(filters, &tcppb.Filter{
+			Name:       f.Name,
+			ConfigType: &tcppb.Filter_TypedConfig{TypedConfig: f.GetTypedConfig()},
+		})
+	}
+	return filters
+}
+
+// buildLocalTCP returns the TCP filters built for ALLOW/DENY/AUDIT actions.
+func (b Builder) buildLocalTCP() []*tcppb.Filter {
 	var filters []*tcppb.Filter
 	if configs := b.build(b.auditPolicies, rbacpb.RBAC_LOG, true); configs != nil {
 		b.option.Logger.AppendDebugf("built %d TCP filters for AUDIT action", len(configs.tcp))
@@ -148,6 +201,25 @@ func (b Builder) BuildTCP() []*tcppb.Filter {
 	return filters
 }
 
+// sharedKey identifies the filters of the filter type built for ALLOW/DENY/AUDIT actions, which only depend on the
+// policies and the trust domains.
+func (b Builder) sharedKey(filterType string) string {
+	var sb strings.Builder
+	sb.WriteString(filterType)
+	sb.WriteString("~")
+	sb.WriteString(strings.Join(b.trustDomainBundle.TrustDomains, ","))
+	for _, policies := range [][]model.AuthorizationPolicy{b.auditPolicies, b.denyPolicies, b.allowPolicies} {
+		sb.WriteString("~")
+		for _, policy := range policies {
+			sb.WriteString(policy.Namespace)
+			sb.WriteString("/")
+			sb.WriteString(policy.Name)
+			sb.WriteString(",")
+		}
+	}
+	return sb.String()
+}
+
 type builtConfigs struct {
 	http []*httppb.HttpFilter
 	tcp  []*tcppb.Filter
@@ -26,6 +26,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	"istio.io/istio/pilot/pkg/security/trustdomain"
@@ -333,6 +334,44 @@ func TestGenerator_GenerateTCP(t *testing.T) {
 	}
 }
 
+func TestBuilder_SharedFilters(t *testing.T) {
+	in := inputParams(t, "tcp/allow-both-http-tcp-in.yaml", nil)
+	other := &plugin.InputParams{
+		Node: &model.Proxy{
+			ID:              "other-node",
+			ConfigNamespace: "foo",
+			Metadata:        &model.NodeMetadata{Labels: map[string]string{"app": "other"}},
+		},
+		Push: in.Push,
+	}
+	build := func(in *plugin.InputParams) ([]*httppb.HttpFilter, []*tcppb.Filter) {
+		g := New(trustdomain.Bundle{}, in, Option{Logger: &AuthzLogger{}})
+		if g == nil {
+			t.Fatalf("failed to create generator")
+		}
+		return g.BuildHTTP(), g.BuildTCP()
+	}
+
+	http1, tcp1 := build(in)
+	http2, tcp2 := build(other)
+	if len(http1) != 1 || len(http2) != 1 || len(tcp1) != 1 || len(tcp2) != 1 {
+		t.Fatalf("expected one HTTP and one TCP filter for each workload, got %v, %v, %v, %v", http1, http2, tcp1, tcp2)
+	}
+	if http1[0] == http2[0] || tcp1[0] == tcp2[0] {
+		t.Errorf("expected each workload to get its own filters")
+	}
+	if http1[0].GetTypedConfig() != http2[0].GetTypedConfig() || tcp1[0].GetTypedConfig() != tcp2[0].GetTypedConfig() {
+		t.Errorf("expected the typed configs to be shared by the workloads with the same policies")
+	}
+
+	features.EnableAuthzFilterSharing = false
+	defer func() { features.EnableAuthzFilterSharing = true }()
+	http3, _ := build(other)
+	if http3[0].GetTypedConfig() == http1[0].GetTypedConfig() {
+		t.Errorf("expected the filters not to be shared when disabled")
+	}
+}
+
 func verify(t *testing.T, gots []proto.Message, baseDir string, wants []string, forTCP bool) {
 	t.Helper()
 
@@ -0,0 +1,229 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"istio.io/istio/pkg/config/security"
+)
+
+// ConditionTarget is the part of the Envoy RBAC policy a custom condition is compiled into.
+type ConditionTarget int
+
+const (
+	// TargetPermission compiles the condition into the permissions, which match the request or connection.
+	TargetPermission ConditionTarget = iota
+	// TargetPrincipal compiles the condition into the principals, which match the downstream.
+	TargetPrincipal
+	// TargetExpression compiles the condition into the CEL condition of the policy, evaluated by Envoy.
+	TargetExpression
+)
+
+// ConditionCompiler compiles the custom conditions of authorization policies, such as CEL expressions over request
+// attributes, into Envoy RBAC config. It is registered for the condition keys starting with a prefix. Like the
+// built-in conditions, a custom condition matches if any of its values matches and none of its notValues matches,
+// and policies with the CUSTOM action delegate to ext_authz the requests matching the compiled rules.
+type ConditionCompiler interface {
+	// Target returns the part of the policy the conditions are compiled into.
+	Target() ConditionTarget
+	// Validate checks the values of a condition when the policy is validated.
+	Validate(key string, values []string) error
+	// Permission compiles a value of a condition into a permission. It is called if the target is TargetPermission.
+	Permission(key, value string, forTCP bool) (*rbacpb.Permission, error)
+	// Principal compiles a value of a condition into a principal. It is called if the target is TargetPrincipal.
+	Principal(key, value string, forTCP bool) (*rbacpb.Principal, error)
+	// Expression compiles a value of a condition into a CEL expression. It is called if the target is
+	// TargetExpression.
+	Expression(key, value string, forTCP bool) (*exprpb.Expr, error)
+}
+
+var (
+	conditionCompilersMu sync.RWMutex
+	conditionCompilers   = map[string]ConditionCompiler{}
+
+	// builtinAttributes are the keys and key prefixes of the built-in conditions.
+	builtinAttributes = []string{
+		attrRequestHeader, attrSrcIP, attrRemoteIP, attrSrcNamespace, attrSrcPrincipal, attrRequestPrincipal,
+		attrRequestAudiences, attrRequestPresenter, attrRequestClaims, attrDestIP, attrDestPort, attrConnSNI, attrEnvoyFilter,
+	}
+)
+
+// RegisterConditionCompiler registers the compiler of the conditions whose key starts with prefix, for example
+// "custom.cel". It is meant to be called from init functions, and panics if the prefix overlaps a built-in
+// attribute or the prefix of another compiler.
+func RegisterConditionCompiler(prefix string, c ConditionCompiler) {
+	overlaps := func(a, b string) bool {
+		return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+	}
+	if prefix == "" {
+		panic("authorization condition compiler registered without prefix")
+	}
+	for _, attr := range builtinAttributes {
+		if overlaps(prefix, attr) {
+			panic(fmt.Sprintf("authorization condition prefix %s overlaps the built-in attribute %s", prefix, attr))
+		}
+	}
+	conditionCompilersMu.Lock()
+	defer conditionCompilersMu.Unlock()
+	for p := range conditionCompilers {
+		if overlaps(prefix, p) {
+			panic(fmt.Sprintf("authorization condition prefix %s overlaps the registered prefix %s", prefix, p))
+		}
+	}
+	conditionCompilers[prefix] = c
+	security.RegisterCustomAttribute(prefix, c.Validate)
+}
+
+// conditionCompilerFor returns the compiler registered for a condition key, or nil.
+func conditionCompilerFor(key string) ConditionCompiler {
+	conditionCompilersMu.RLock()
+	defer conditionCompilersMu.RUnlock()
+	for prefix, c := range conditionCompilers {
+		if strings.HasPrefix(key, prefix) {
+			return c
+		}
+	}
+	return nil
+}
+
+// customGenerator adapts a ConditionCompiler to the generators of the built-in conditions.
+type customGenerator struct {
+	c ConditionCompiler
+}
+
+func (g customGenerator) permission(key, value string, forTCP bool) (*rbacpb.Permission, error) {
+	return g.c.Permission(key, value, forTCP)
+}
+
+func (g customGenerator) principal(key, value string, forTCP bool) (*rbacpb.Principal, error) {
+	return g.c.Principal(key, value, forTCP)
+}
+
+func (g customGenerator) expression(key, value string, forTCP bool) (*exprpb.Expr, error) {
+	return g.c.Expression(key, value, forTCP)
+}
+
+func generateCondition(rl ruleList, forTCP bool, action rbacpb.RBAC_Action) (*exprpb.Expr, error) {
+	var and []*exprpb.Expr
+	for _, r := range rl.rules {
+		ret, err := r.expression(forTCP, action)
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, ret...)
+	}
+	if len(and) == 0 {
+		return nil, nil
+	}
+	condition := exprFold("_&&_", and)
+	// The sub-expressions are compiled independently, the IDs must be unique in the whole expression.
+	var id int64
+	renumberExpr(condition, &id)
+	return condition, nil
+}
+
+func (r rule) expression(forTCP bool, action rbacpb.RBAC_Action) ([]*exprpb.Expr, error) {
+	g, ok := r.g.(customGenerator)
+	if !ok {
+		return nil, fmt.Errorf("attribute %s is not compiled into an expression", r.key)
+	}
+	var expressions []*exprpb.Expr
+	var or []*exprpb.Expr
+	for _, value := range r.values {
+		e, err := g.expression(r.key, value, forTCP)
+		if err := r.checkError(action, err); err != nil {
+			return nil, err
+		}
+		if e != nil {
+			or = append(or, e)
+		}
+	}
+	if len(or) > 0 {
+		expressions = append(expressions, exprFold("_||_", or))
+	}
+
+	or = nil
+	for _, notValue := range r.notValues {
+		e, err := g.expression(r.key, notValue, forTCP)
+		if err := r.checkError(action, err); err != nil {
+			return nil, err
+		}
+		if e != nil {
+			or = append(or, e)
+		}
+	}
+	if len(or) > 0 {
+		expressions = append(expressions, exprCall("!_", exprFold("_||_", or)))
+	}
+	return expressions, nil
+}
+
+// exprCall returns the call of a CEL function.
+func exprCall(function string, args ...*exprpb.Expr) *exprpb.Expr {
+	return &exprpb.Expr{
+		ExprKind: &exprpb.Expr_CallExpr{CallExpr: &exprpb.Expr_Call{Function: function, Args: args}},
+	}
+}
+
+// exprFold joins non-empty expressions with a binary logical operator, such as "_&&_".
+func exprFold(operator string, exprs []*exprpb.Expr) *exprpb.Expr {
+	ret := exprs[0]
+	for _, e := range exprs[1:] {
+		ret = exprCall(operator, ret, e)
+	}
+	return ret
+}
+
+// renumberExpr assigns unique IDs to an expression and its sub-expressions.
+func renumberExpr(e *exprpb.Expr, id *int64) {
+	if e == nil {
+		return
+	}
+	*id++
+	e.Id = *id
+	switch {
+	case e.GetSelectExpr() != nil:
+		renumberExpr(e.GetSelectExpr().GetOperand(), id)
+	case e.GetCallExpr() != nil:
+		renumberExpr(e.GetCallExpr().GetTarget(), id)
+		for _, arg := range e.GetCallExpr().GetArgs() {
+			renumberExpr(arg, id)
+		}
+	case e.GetListExpr() != nil:
+		for _, el := range e.GetListExpr().GetElements() {
+			renumberExpr(el, id)
+		}
+	case e.GetStructExpr() != nil:
+		for _, entry := range e.GetStructExpr().GetEntries() {
+			*id++
+			entry.Id = *id
+			renumberExpr(entry.GetMapKey(), id)
+			renumberExpr(entry.GetValue(), id)
+		}
+	case e.GetComprehensionExpr() != nil:
+		c := e.GetComprehensionExpr()
+		renumberExpr(c.GetIterRange(), id)
+		renumberExpr(c.GetAccuInit(), id)
+		renumberExpr(c.GetLoopCondition(), id)
+		renumberExpr(c.GetLoopStep(), id)
+		renumberExpr(c.GetResult(), id)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"istio.io/istio/pilot/pkg/security/authz/matcher"
+	"istio.io/istio/pkg/config/security"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+type testCompiler struct {
+	target ConditionTarget
+}
+
+func (c testCompiler) Target() ConditionTarget {
+	return c.target
+}
+
+func (c testCompiler) Validate(key string, values []string) error {
+	for _, v := range values {
+		if v == "invalid" {
+			return fmt.Errorf("invalid value for %s", key)
+		}
+	}
+	return nil
+}
+
+func (c testCompiler) Permission(_, value string, _ bool) (*rbacpb.Permission, error) {
+	return permissionHeader(matcher.HeaderMatcher("x-permission", value)), nil
+}
+
+func (c testCompiler) Principal(_, value string, _ bool) (*rbacpb.Principal, error) {
+	return principalHeader(matcher.HeaderMatcher("x-principal", value)), nil
+}
+
+func (c testCompiler) Expression(key, value string, forTCP bool) (*exprpb.Expr, error) {
+	if forTCP {
+		return nil, fmt.Errorf("%s is only supported for HTTP", key)
+	}
+	attr := &exprpb.Expr{ExprKind: &exprpb.Expr_IdentExpr{IdentExpr: &exprpb.Expr_Ident{Name: "request.path"}}}
+	v := &exprpb.Expr{ExprKind: &exprpb.Expr_ConstExpr{ConstExpr: &exprpb.Constant{
+		ConstantKind: &exprpb.Constant_StringValue{StringValue: value},
+	}}}
+	return exprCall("_==_", attr, v), nil
+}
+
+func init() {
+	RegisterConditionCompiler("test.permission.", testCompiler{target: TargetPermission})
+	RegisterConditionCompiler("test.principal.", testCompiler{target: TargetPrincipal})
+	RegisterConditionCompiler("test.expression.", testCompiler{target: TargetExpression})
+}
+
+func TestModel_CustomConditions(t *testing.T) {
+	rule := yamlRule(t, `
+when:
+- key: "test.permission.a"
+  values: ["perm-1"]
+- key: "test.principal.a"
+  notValues: ["principal-1"]
+- key: "test.expression.a"
+  values: ["/a", "/b"]
+  notValues: ["/c"]
+`)
+	m, err := New(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := m.Generate(false, rbacpb.RBAC_ALLOW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := protomarshal.ToYAML(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"x-permission", "perm-1", "x-principal", "principal-1", "/a", "/b", "/c"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%s not found in the policy:\n%s", want, got)
+		}
+	}
+
+	condition := policy.GetCondition().GetCallExpr()
+	if condition.GetFunction() != "_&&_" || len(condition.GetArgs()) != 2 ||
+		condition.GetArgs()[0].GetCallExpr().GetFunction() != "_||_" ||
+		condition.GetArgs()[1].GetCallExpr().GetFunction() != "!_" {
+		t.Errorf("unexpected condition:\n%s", got)
+	}
+	ids := map[int64]bool{}
+	var walk func(e *exprpb.Expr)
+	walk = func(e *exprpb.Expr) {
+		if ids[e.Id] {
+			t.Errorf("duplicated expression id %d", e.Id)
+		}
+		ids[e.Id] = true
+		for _, arg := range e.GetCallExpr().GetArgs() {
+			walk(arg)
+		}
+	}
+	walk(policy.GetCondition())
+
+	// The expression is not supported for TCP: the allow policy fails, a deny policy ignores the condition.
+	if _, err := m.Generate(true, rbacpb.RBAC_ALLOW); err == nil {
+		t.Errorf("expected the TCP allow policy to fail")
+	}
+	if policy, err := m.Generate(true, rbacpb.RBAC_DENY); err != nil || policy.GetCondition() != nil {
+		t.Errorf("expected the TCP deny policy to ignore the condition, got %v: %v", policy, err)
+	}
+}
+
+func TestRegisterConditionCompiler(t *testing.T) {
+	if err := security.ValidateAttribute("test.expression.a", []string{"/a"}); err != nil {
+		t.Errorf("expected custom attribute to be valid: %v", err)
+	}
+	if err := security.ValidateAttribute("test.expression.a", []string{"invalid"}); err == nil {
+		t.Errorf("expected custom attribute to be validated by its compiler")
+	}
+	if err := security.ValidateAttribute("test.unknown", []string{"a"}); err == nil {
+		t.Errorf("expected unknown attribute to be rejected")
+	}
+
+	for _, prefix := range []string{"source.", "request.headers[x-custom]", "test.expression.a.", ""} {
+		t.Run(prefix, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected prefix %q to be rejected", prefix)
+				}
+			}()
+			RegisterConditionCompiler(prefix, testCompiler{})
+		})
+	}
+}
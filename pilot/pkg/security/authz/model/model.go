@@ -73,6 +73,8 @@ type ruleList struct {
 type Model struct {
 	permissions []ruleList
 	principals  []ruleList
+	// conditions are the custom conditions compiled into the CEL condition of the policy.
+	conditions ruleList
 }
 
 // New returns a model representing a single authorization policy.
@@ -113,7 +115,18 @@ func New(r *authzpb.Rule) (*Model, error) {
 		case strings.HasPrefix(k, attrRequestClaims):
 			basePrincipal.appendLast(requestClaimGenerator{}, k, when.Values, when.NotValues)
 		default:
-			return nil, fmt.Errorf("unknown attribute %s", when.Key)
+			c := conditionCompilerFor(k)
+			if c == nil {
+				return nil, fmt.Errorf("unknown attribute %s", when.Key)
+			}
+			switch c.Target() {
+			case TargetPermission:
+				basePermission.appendLast(customGenerator{c}, k, when.Values, when.NotValues)
+			case TargetPrincipal:
+				basePrincipal.appendLast(customGenerator{c}, k, when.Values, when.NotValues)
+			default:
+				m.conditions.appendLast(customGenerator{c}, k, when.Values, when.NotValues)
+			}
 		}
 	}
 
@@ -191,9 +204,15 @@ func (m Model) Generate(forTCP bool, action rbacpb.RBAC_Action) (*rbacpb.Policy,
 		return nil, fmt.Errorf("must have at least 1 principal")
 	}
 
+	condition, err := generateCondition(m.conditions, forTCP, action)
+	if err != nil {
+		return nil, err
+	}
+
 	return &rbacpb.Policy{
 		Permissions: permissions,
 		Principals:  principals,
+		Condition:   condition,
 	}, nil
 }
 
@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/fips"
+	"istio.io/pkg/log"
+)
+
+const (
+	// TLSProfileCompat accepts the TLS versions and cipher suites of older clients.
+	TLSProfileCompat = "compat"
+	// TLSProfileModern only accepts TLS 1.2 and 1.3 with forward secret AEAD cipher suites.
+	TLSProfileModern = "modern"
+	// TLSProfileFIPS restricts the TLS versions, cipher suites and curves to the FIPS 140-2 approved ones.
+	TLSProfileFIPS = "fips"
+	// TLSProfileExperimentalPQ only accepts TLS 1.3, preferring the hybrid post-quantum key exchange. It requires
+	// an Envoy built with a BoringSSL supporting X25519Kyber768Draft00; other peers fall back to X25519.
+	TLSProfileExperimentalPQ = "experimental-pq"
+)
+
+// TLSProfile is a named set of TLS parameters: the TLS versions, cipher suites and curves of a connection.
+// Empty fields keep the Envoy defaults.
+type TLSProfile struct {
+	MinProtocolVersion tls.TlsParameters_TlsProtocol
+	MaxProtocolVersion tls.TlsParameters_TlsProtocol
+	CipherSuites       []string
+	ECDHCurves         []string
+}
+
+var tlsProfiles = map[string]*TLSProfile{
+	TLSProfileCompat: {
+		MinProtocolVersion: tls.TlsParameters_TLSv1_0,
+		MaxProtocolVersion: tls.TlsParameters_TLSv1_3,
+		CipherSuites: []string{
+			"ECDHE-ECDSA-AES128-GCM-SHA256",
+			"ECDHE-RSA-AES128-GCM-SHA256",
+			"ECDHE-ECDSA-AES256-GCM-SHA384",
+			"ECDHE-RSA-AES256-GCM-SHA384",
+			"ECDHE-ECDSA-CHACHA20-POLY1305",
+			"ECDHE-RSA-CHACHA20-POLY1305",
+			"ECDHE-ECDSA-AES128-SHA",
+			"ECDHE-RSA-AES128-SHA",
+			"AES128-GCM-SHA256",
+			"AES256-GCM-SHA384",
+			"AES128-SHA",
+			"AES256-SHA",
+		},
+		ECDHCurves: []string{"X25519", "P-256", "P-384"},
+	},
+	TLSProfileModern: {
+		MinProtocolVersion: tls.TlsParameters_TLSv1_2,
+		MaxProtocolVersion: tls.TlsParameters_TLSv1_3,
+		CipherSuites: []string{
+			"ECDHE-ECDSA-AES128-GCM-SHA256",
+			"ECDHE-RSA-AES128-GCM-SHA256",
+			"ECDHE-ECDSA-AES256-GCM-SHA384",
+			"ECDHE-RSA-AES256-GCM-SHA384",
+			"ECDHE-ECDSA-CHACHA20-POLY1305",
+			"ECDHE-RSA-CHACHA20-POLY1305",
+		},
+		ECDHCurves: []string{"X25519", "P-256"},
+	},
+	// Envoy does not allow configuring the TLS 1.3 cipher suites, so FIPS is capped at TLS 1.2.
+	TLSProfileFIPS: {
+		MinProtocolVersion: tls.TlsParameters_TLSv1_2,
+		MaxProtocolVersion: tls.TlsParameters_TLSv1_2,
+		CipherSuites: []string{
+			"ECDHE-ECDSA-AES128-GCM-SHA256",
+			"ECDHE-RSA-AES128-GCM-SHA256",
+			"ECDHE-ECDSA-AES256-GCM-SHA384",
+			"ECDHE-RSA-AES256-GCM-SHA384",
+		},
+		ECDHCurves: []string{"P-256", "P-384"},
+	},
+	// The cipher suites of TLS 1.3 are not configurable, so only the curves are set.
+	TLSProfileExperimentalPQ: {
+		MinProtocolVersion: tls.TlsParameters_TLSv1_3,
+		MaxProtocolVersion: tls.TlsParameters_TLSv1_3,
+		ECDHCurves:         []string{"X25519Kyber768Draft00", "X25519"},
+	},
+}
+
+// GetTLSProfile returns the TLS profile of the given name. An empty name returns nil, keeping the default TLS
+// parameters of each context.
+func GetTLSProfile(name string) (*TLSProfile, bool) {
+	if name == "" {
+		return nil, true
+	}
+	p, f := tlsProfiles[name]
+	return p, f
+}
+
+// MeshTLSProfile returns the TLS profile of the mesh set by PILOT_TLS_PROFILE, or nil if none. In FIPS mode, the
+// fips profile is the default.
+func MeshTLSProfile() *TLSProfile {
+	if features.TLSProfile == "" && fips.Enabled() {
+		return tlsProfiles[TLSProfileFIPS]
+	}
+	p, f := GetTLSProfile(features.TLSProfile)
+	if !f {
+		log.Warnf("ignoring unknown TLS profile %q", features.TLSProfile)
+	}
+	return p
+}
+
+// TLSParameters returns the TLS parameters of the profile, which can be modified by the caller.
+func (p *TLSProfile) TLSParameters() *tls.TlsParameters {
+	return &tls.TlsParameters{
+		TlsMinimumProtocolVersion: p.MinProtocolVersion,
+		TlsMaximumProtocolVersion: p.MaxProtocolVersion,
+		CipherSuites:              append([]string(nil), p.CipherSuites...),
+		EcdhCurves:                append([]string(nil), p.ECDHCurves...),
+	}
+}
+
+// EnforceFIPS restricts the TLS parameters to the FIPS approved ones in FIPS mode, refusing the non-compliant
+// versions, cipher suites and curves, and returns them. Outside of FIPS mode, the parameters are returned unchanged.
+func EnforceFIPS(params *tls.TlsParameters) *tls.TlsParameters {
+	if !fips.Enabled() {
+		return params
+	}
+	if params == nil {
+		return tlsProfiles[TLSProfileFIPS].TLSParameters()
+	}
+	params.CipherSuites = fipsCompliant(params.CipherSuites, fips.CipherSuites, fips.CompliantCipherSuite, "cipher suite")
+	params.EcdhCurves = fipsCompliant(params.EcdhCurves, fips.Curves, fips.CompliantCurve, "ECDH curve")
+	if params.TlsMinimumProtocolVersion < tls.TlsParameters_TLSv1_2 {
+		if params.TlsMinimumProtocolVersion != tls.TlsParameters_TLS_AUTO {
+			fips.RecordViolation("minimum TLS version", params.TlsMinimumProtocolVersion.String(), "older than TLS 1.2")
+		}
+		params.TlsMinimumProtocolVersion = tls.TlsParameters_TLSv1_2
+	}
+	if params.TlsMaximumProtocolVersion != tls.TlsParameters_TLSv1_2 {
+		if params.TlsMaximumProtocolVersion != tls.TlsParameters_TLS_AUTO {
+			fips.RecordViolation("maximum TLS version", params.TlsMaximumProtocolVersion.String(), "only TLS 1.2 is allowed")
+		}
+		params.TlsMaximumProtocolVersion = tls.TlsParameters_TLSv1_2
+	}
+	return params
+}
+
+// fipsCompliant returns the compliant values, or the approved defaults if there are none.
+func fipsCompliant(values, defaults []string, compliant func(string) bool, kind string) []string {
+	var out []string
+	for _, v := range values {
+		if compliant(v) {
+			out = append(out, v)
+		} else {
+			log.Warnf("refusing %s %s, which is not FIPS approved", kind, v)
+			fips.RecordViolation(kind, v, "not FIPS approved")
+		}
+	}
+	if len(out) == 0 {
+		return append([]string(nil), defaults...)
+	}
+	return out
+}
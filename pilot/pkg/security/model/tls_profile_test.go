@@ -0,0 +1,56 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"istio.io/istio/pkg/fips"
+)
+
+func TestEnforceFIPS(t *testing.T) {
+	params := &tls.TlsParameters{
+		TlsMinimumProtocolVersion: tls.TlsParameters_TLSv1_0,
+		CipherSuites:              []string{"ECDHE-RSA-AES256-GCM-SHA384", "ECDHE-RSA-CHACHA20-POLY1305"},
+		EcdhCurves:                []string{"X25519"},
+	}
+	if !fips.BoringCrypto() {
+		if got := EnforceFIPS(params); got != params || got.TlsMinimumProtocolVersion != tls.TlsParameters_TLSv1_0 {
+			t.Fatalf("expected the parameters to be unchanged outside of FIPS mode, got %v", got)
+		}
+	}
+
+	fips.Mode = true
+	defer func() { fips.Mode = false }()
+	expected := &tls.TlsParameters{
+		TlsMinimumProtocolVersion: tls.TlsParameters_TLSv1_2,
+		TlsMaximumProtocolVersion: tls.TlsParameters_TLSv1_2,
+		CipherSuites:              []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+		EcdhCurves:                []string{"P-256", "P-384"},
+	}
+	if diff := cmp.Diff(expected, EnforceFIPS(params), protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected FIPS parameters: %v", diff)
+	}
+	if diff := cmp.Diff(tlsProfiles[TLSProfileFIPS].TLSParameters(), EnforceFIPS(nil), protocmp.Transform()); diff != "" {
+		t.Fatalf("expected the fips profile without parameters: %v", diff)
+	}
+	if got := MeshTLSProfile(); got != tlsProfiles[TLSProfileFIPS] {
+		t.Fatalf("expected the fips profile to be the mesh default in FIPS mode, got %v", got)
+	}
+}
@@ -437,6 +437,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 						ClusterID: clusterID,
 					},
 					TLSMode: "mutual",
+					Capture: model.CaptureNone,
 				},
 			}
 
@@ -508,6 +509,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 					},
 					ServiceAccount: "spiffe://cluster.local/ns/nsa/sa/svcaccount",
 					TLSMode:        model.DisabledTLSModeLabel,
+					Capture:        model.CaptureNone,
 					WorkloadName:   "pod2",
 					Namespace:      "nsa",
 				},
@@ -574,6 +576,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 					},
 					ServiceAccount: "spiffe://cluster.local/ns/nsa/sa/svcaccount",
 					TLSMode:        model.DisabledTLSModeLabel,
+					Capture:        model.CaptureNone,
 					WorkloadName:   "pod3",
 					Namespace:      "nsa",
 				},
@@ -38,6 +38,7 @@ type EndpointBuilder struct {
 	serviceAccount string
 	locality       model.Locality
 	tlsMode        string
+	capture        model.WorkloadCapture
 	workloadName   string
 	namespace      string
 
@@ -65,6 +66,7 @@ func NewEndpointBuilder(c controllerInterface, pod *v1.Pod) *EndpointBuilder {
 		}
 	}
 	dm, _ := kubeUtil.GetDeployMetaFromPod(pod)
+	tlsMode := kube.PodTLSMode(pod)
 
 	return &EndpointBuilder{
 		controller:     c,
@@ -74,7 +76,8 @@ func NewEndpointBuilder(c controllerInterface, pod *v1.Pod) *EndpointBuilder {
 			Label:     locality,
 			ClusterID: c.Cluster(),
 		},
-		tlsMode:      kube.PodTLSMode(pod),
+		tlsMode:      tlsMode,
+		capture:      model.GetWorkloadCapture(tlsMode, podLabels),
 		workloadName: dm.Name,
 		namespace:    namespace,
 		hostname:     hostname,
@@ -84,6 +87,7 @@ func NewEndpointBuilder(c controllerInterface, pod *v1.Pod) *EndpointBuilder {
 
 func NewEndpointBuilderFromMetadata(c controllerInterface, proxy *model.Proxy) *EndpointBuilder {
 	locality := util.LocalityToString(proxy.Locality)
+	tlsMode := model.GetTLSModeFromEndpointLabels(proxy.Metadata.Labels)
 	return &EndpointBuilder{
 		controller:     c,
 		metaNetwork:    proxy.Metadata.Network,
@@ -93,7 +97,8 @@ func NewEndpointBuilderFromMetadata(c controllerInterface, proxy *model.Proxy) *
 			Label:     locality,
 			ClusterID: c.Cluster(),
 		},
-		tlsMode: model.GetTLSModeFromEndpointLabels(proxy.Metadata.Labels),
+		tlsMode: tlsMode,
+		capture: model.GetWorkloadCapture(tlsMode, proxy.Metadata.Labels),
 	}
 }
 
@@ -136,6 +141,8 @@ func (b *EndpointBuilder) buildIstioEndpoint(
 		ServiceAccount:        b.serviceAccount,
 		Locality:              b.locality,
 		TLSMode:               b.tlsMode,
+		Capture:               b.capture,
+		TunnelAbility:         b.capture.TunnelAbility(),
 		Address:               endpointAddress,
 		EndpointPort:          uint32(endpointPort),
 		ServicePortName:       svcPortName,
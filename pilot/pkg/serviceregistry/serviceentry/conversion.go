@@ -242,6 +242,7 @@ func convertEndpoint(service *model.Service, servicePort *networking.Port,
 	}
 
 	tlsMode := getTLSModeFromWorkloadEntry(endpoint)
+	capture := model.GetWorkloadCapture(tlsMode, endpoint.Labels)
 	sa := ""
 	if endpoint.ServiceAccount != "" {
 		sa = spiffe.MustGenSpiffeURI(service.Attributes.Namespace, endpoint.ServiceAccount)
@@ -258,6 +259,8 @@ func convertEndpoint(service *model.Service, servicePort *networking.Port,
 			LbWeight:       endpoint.Weight,
 			Labels:         endpoint.Labels,
 			TLSMode:        tlsMode,
+			Capture:        capture,
+			TunnelAbility:  capture.TunnelAbility(),
 			ServiceAccount: sa,
 			// Workload entry config name is used as workload name, which will appear in metric label.
 			// After VM auto registry is introduced, workload group annotation should be used for workload name.
@@ -308,6 +311,7 @@ func convertServiceEntryToInstances(cfg config.Config, services []*model.Service
 						ServicePortName: serviceEntryPort.Name,
 						Labels:          nil,
 						TLSMode:         model.DisabledTLSModeLabel,
+						Capture:         model.CaptureNone,
 					},
 					Service:     service,
 					ServicePort: convertPort(serviceEntryPort),
@@ -384,6 +388,7 @@ func convertWorkloadEntryToWorkloadInstance(cfg config.Config) *model.WorkloadIn
 		return nil
 	}
 	tlsMode := getTLSModeFromWorkloadEntry(we)
+	capture := model.GetWorkloadCapture(tlsMode, labels)
 	sa := ""
 	if we.ServiceAccount != "" {
 		sa = spiffe.MustGenSpiffeURI(cfg.Namespace, we.ServiceAccount)
@@ -399,6 +404,8 @@ func convertWorkloadEntryToWorkloadInstance(cfg config.Config) *model.WorkloadIn
 			LbWeight:       we.Weight,
 			Labels:         labels,
 			TLSMode:        tlsMode,
+			Capture:        capture,
+			TunnelAbility:  capture.TunnelAbility(),
 			ServiceAccount: sa,
 		},
 		PortMap:   we.Ports,
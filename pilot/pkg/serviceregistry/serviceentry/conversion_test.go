@@ -495,6 +495,7 @@ func makeInstance(cfg *config.Config, address string, port int,
 			ServicePortName: svcPort.Name,
 			Labels:          svcLabels,
 			TLSMode:         tlsMode,
+			Capture:         model.GetWorkloadCapture(tlsMode, svcLabels),
 		},
 		ServicePort: &model.Port{
 			Name:     svcPort.Name,
@@ -824,6 +825,7 @@ func TestConvertWorkloadEntryToWorkloadInstance(t *testing.T) {
 					Address:        "1.1.1.1",
 					ServiceAccount: "spiffe://cluster.local/ns/ns1/sa/scooby",
 					TLSMode:        "istio",
+					Capture:        model.CaptureSidecar,
 				},
 				PortMap: map[string]uint32{
 					"http": 80,
@@ -856,6 +858,7 @@ func TestConvertWorkloadEntryToWorkloadInstance(t *testing.T) {
 					Address:        "1.1.1.1",
 					ServiceAccount: "spiffe://cluster.local/ns/ns1/sa/scooby",
 					TLSMode:        "disabled",
+					Capture:        model.CaptureNone,
 				},
 				PortMap: map[string]uint32{
 					"http": 80,
@@ -910,6 +913,7 @@ func TestConvertWorkloadEntryToWorkloadInstance(t *testing.T) {
 					Address:        "1.1.1.1",
 					ServiceAccount: "spiffe://cluster.local/ns/ns1/sa/scooby",
 					TLSMode:        "istio",
+					Capture:        model.CaptureSidecar,
 				},
 				PortMap: map[string]uint32{
 					"http": 80,
@@ -944,6 +948,7 @@ func TestConvertWorkloadEntryToWorkloadInstance(t *testing.T) {
 					Address:        "1.1.1.1",
 					ServiceAccount: "spiffe://cluster.local/ns/ns1/sa/scooby",
 					TLSMode:        "istio",
+					Capture:        model.CaptureSidecar,
 				},
 				PortMap: map[string]uint32{
 					"http": 80,
@@ -89,6 +89,9 @@ type ServiceEntryStore struct { // nolint:golint
 	workloadHandlers []func(*model.WorkloadInstance, model.Event)
 
 	processServiceEntry bool
+	// vipAllocator allocates the addresses of the services without addresses, if set. Otherwise, they are
+	// allocated by autoAllocateIPs.
+	vipAllocator *VIPAllocator
 }
 
 type ServiceDiscoveryOption func(*ServiceEntryStore)
@@ -105,6 +108,13 @@ func WithClusterID(clusterID cluster.ID) ServiceDiscoveryOption {
 	}
 }
 
+// WithVIPAllocator allocates stable addresses to the services without addresses with the given allocator.
+func WithVIPAllocator(allocator *VIPAllocator) ServiceDiscoveryOption {
+	return func(o *ServiceEntryStore) {
+		o.vipAllocator = allocator
+	}
+}
+
 // NewServiceDiscovery creates a new ServiceEntry discovery service
 func NewServiceDiscovery(
 	configController model.ConfigStoreCache,
@@ -125,6 +135,12 @@ func NewServiceDiscovery(
 	for _, o := range options {
 		o(s)
 	}
+	if s.vipAllocator != nil {
+		// The addresses of existing hosts change when the allocations of another istiod are adopted.
+		s.vipAllocator.onChange = func() {
+			s.XdsUpdater.ConfigUpdate(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.ServiceUpdate}})
+		}
+	}
 
 	if configController != nil {
 		if s.processServiceEntry {
@@ -485,7 +501,11 @@ func (s *ServiceEntryStore) AppendWorkloadHandler(h func(*model.WorkloadInstance
 }
 
 // Run is used by some controllers to execute background jobs after init is done.
-func (s *ServiceEntryStore) Run(_ <-chan struct{}) {}
+func (s *ServiceEntryStore) Run(stop <-chan struct{}) {
+	if s.vipAllocator != nil {
+		go s.vipAllocator.Run(stop)
+	}
+}
 
 // HasSynced always returns true for SE
 func (s *ServiceEntryStore) HasSynced() bool {
@@ -500,6 +520,9 @@ func (s *ServiceEntryStore) Services() ([]*model.Service, error) {
 	s.maybeRefreshIndexes()
 	s.storeMutex.RLock()
 	defer s.storeMutex.RUnlock()
+	if s.vipAllocator != nil {
+		return s.vipAllocator.Allocate(s.services), nil
+	}
 	return autoAllocateIPs(s.services), nil
 }
 
@@ -621,6 +644,8 @@ func (s *ServiceEntryStore) edsUpdateByKeys(keys map[instancesKey]struct{}, push
 				Locality:        instance.Endpoint.Locality,
 				LbWeight:        instance.Endpoint.LbWeight,
 				TLSMode:         instance.Endpoint.TLSMode,
+				Capture:         instance.Endpoint.Capture,
+				TunnelAbility:   instance.Endpoint.TunnelAbility,
 				WorkloadName:    instance.Endpoint.WorkloadName,
 				Namespace:       instance.Endpoint.Namespace,
 			})
@@ -904,6 +929,7 @@ func selectorChanged(old, curr config.Config) bool {
 // allocation with deterministic collision resolution, the perf problem will go away. If the collision guarantee
 // cannot be made within the IP address space we have (which is about 64K services), then we may need to
 // have the sequential allocation algorithm as a fallback when too many collisions take place.
+// The hash based allocation is implemented by VIPAllocator, enabled with PILOT_AUTO_ALLOCATE_VIP_CIDR.
 func autoAllocateIPs(services []*model.Service) []*model.Service {
 	// i is everything from 240.240.0.(j) to 240.240.255.(j)
 	// j is everything from 240.240.(i).1 to 240.240.(i).254
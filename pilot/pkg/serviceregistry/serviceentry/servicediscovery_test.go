@@ -780,6 +780,7 @@ func TestServiceDiscoveryWorkloadInstance(t *testing.T) {
 			Labels:         map[string]string{"app": "wle"},
 			ServiceAccount: spiffe.MustGenSpiffeURI(selector.Name, "default"),
 			TLSMode:        model.IstioMutualTLSModeLabel,
+			Capture:        model.CaptureSidecar,
 		},
 	}
 
@@ -791,6 +792,7 @@ func TestServiceDiscoveryWorkloadInstance(t *testing.T) {
 			Labels:         map[string]string{"app": "wle"},
 			ServiceAccount: spiffe.MustGenSpiffeURI(selector.Name, "default"),
 			TLSMode:        model.IstioMutualTLSModeLabel,
+			Capture:        model.CaptureSidecar,
 		},
 	}
 
@@ -0,0 +1,427 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/pkg/log"
+)
+
+// VIPAllocationsConfigMap is the name of the ConfigMap persisting the allocations of the auto allocated VIPs.
+const VIPAllocationsConfigMap = "istio-vip-allocations"
+
+// vipReleaseGracePeriod is how long the address of a host which no longer exists is kept, before it can be reused.
+const vipReleaseGracePeriod = 10 * time.Minute
+
+// VIPStore persists the allocations of a VIPAllocator, keyed by the namespace and hostname of the services. The
+// store may be shared by several istiods.
+type VIPStore interface {
+	Load() (map[string]string, error)
+	// Update stores the allocations returned by merge from the ones currently stored, retrying if they were
+	// changed concurrently, and returns the allocations stored.
+	Update(merge func(stored map[string]string) map[string]string) (map[string]string, error)
+}
+
+// VIPAllocator allocates stable virtual IPs, out of a CIDR, to the hosts of the ServiceEntries without addresses.
+// Unlike the sequential allocation of autoAllocateIPs, the address of a host only depends on the hash of the host,
+// the collisions being resolved by probing the next addresses in the order of the hosts, so adding or removing a
+// ServiceEntry does not change the addresses of the others. A host keeps its address as long as it exists, and the
+// allocations are persisted so that they survive the restarts of istiod.
+//
+// The istiods sharing the store reconcile their allocations when they persist them: the allocations stored first
+// win, and the hosts whose address was taken by another istiod are allocated another one.
+//
+// The addresses of the hosts that no longer exist are not released right away, as the services may be missing
+// while the config store syncs: they are only reused by another host once the host has been missing for
+// vipReleaseGracePeriod.
+type VIPAllocator struct {
+	base  uint32
+	size  uint32
+	cidr  *net.IPNet
+	store VIPStore
+	// onChange is called when the allocations of existing hosts are changed by those of another istiod.
+	onChange func()
+	now      func() time.Time
+
+	mu sync.Mutex
+	// allocated is the address of each host.
+	allocated map[string]string
+	// hosts is the host of each allocated address.
+	hosts map[string]string
+	// missingSince is when each allocated host was first missing from the services.
+	missingSince map[string]time.Time
+	// released are the allocations reclaimed from missing hosts, to remove from the store.
+	released map[string]string
+	dirty    chan struct{}
+}
+
+// NewVIPAllocator returns an allocator of the addresses of the IPv4 CIDR, restoring the allocations of the store if
+// any.
+func NewVIPAllocator(cidr string, store VIPStore) (*VIPAllocator, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	ip := ipNet.IP.To4()
+	if ip == nil {
+		return nil, fmt.Errorf("%v is not an IPv4 CIDR", cidr)
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones < 2 || bits-ones > 24 {
+		return nil, fmt.Errorf("the CIDR %v must be between a /8 and a /30", cidr)
+	}
+	a := &VIPAllocator{
+		base:         binary.BigEndian.Uint32(ip),
+		size:         1 << uint(bits-ones),
+		cidr:         ipNet,
+		store:        store,
+		now:          time.Now,
+		allocated:    map[string]string{},
+		hosts:        map[string]string{},
+		missingSince: map[string]time.Time{},
+		released:     map[string]string{},
+		dirty:        make(chan struct{}, 1),
+	}
+	if store != nil {
+		allocations, err := store.Load()
+		if err != nil {
+			log.Warnf("failed to load the allocated VIPs, allocating them again: %v", err)
+		}
+		a.adopt(allocations)
+	}
+	return a, nil
+}
+
+// adopt replaces the allocations of the hosts by the ones of allocations, dropping the hosts whose address is
+// allocated to another host: they are allocated another address by the next Allocate. It returns whether the
+// address of a host was changed. The lock must be held, unless the allocator is not used yet.
+func (a *VIPAllocator) adopt(allocations map[string]string) bool {
+	changed := false
+	// Adopt the hosts in a fixed order, so that the invalid allocations are dropped alike by all the istiods.
+	keys := make([]string, 0, len(allocations))
+	for host := range allocations {
+		keys = append(keys, host)
+	}
+	sort.Strings(keys)
+	adopted := map[string]struct{}{}
+	for _, host := range keys {
+		addr := allocations[host]
+		if parsed := net.ParseIP(addr); parsed == nil || !a.cidr.Contains(parsed) {
+			continue
+		}
+		if owner, f := a.hosts[addr]; f {
+			if owner == host {
+				adopted[addr] = struct{}{}
+				continue
+			}
+			if _, f := adopted[addr]; f {
+				continue
+			}
+			delete(a.allocated, owner)
+			delete(a.missingSince, owner)
+			changed = true
+		}
+		if prev, f := a.allocated[host]; f {
+			delete(a.hosts, prev)
+			changed = true
+		}
+		a.allocated[host] = addr
+		a.hosts[addr] = host
+		adopted[addr] = struct{}{}
+	}
+	return changed
+}
+
+func vipKey(svc *model.Service) string {
+	return svc.Attributes.Namespace + "/" + string(svc.Hostname)
+}
+
+// needsVIP returns whether an address can be allocated to the service: it has no address, the hostname is not a
+// wildcard and its resolution is not NONE, as we would not know the original destination the application requested.
+func needsVIP(svc *model.Service) bool {
+	return svc.Address == constants.UnspecifiedIP && !svc.Hostname.IsWildCarded() && svc.Resolution != model.Passthrough
+}
+
+// Allocate sets the AutoAllocatedAddress of the services without addresses.
+func (a *VIPAllocator) Allocate(services []*model.Service) []*model.Service {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	present := make(map[string]struct{}, len(services))
+	var pending []*model.Service
+	for _, svc := range services {
+		if !needsVIP(svc) {
+			continue
+		}
+		key := vipKey(svc)
+		present[key] = struct{}{}
+		if addr, f := a.allocated[key]; f {
+			svc.AutoAllocatedAddress = addr
+			continue
+		}
+		pending = append(pending, svc)
+	}
+	for key := range a.allocated {
+		if _, f := present[key]; f {
+			delete(a.missingSince, key)
+		} else if _, f := a.missingSince[key]; !f {
+			a.missingSince[key] = now
+		}
+	}
+	if len(pending) == 0 {
+		return services
+	}
+	// Allocate the new hosts in a fixed order, so that the collisions are resolved alike by all the istiods.
+	sort.SliceStable(pending, func(i, j int) bool {
+		return vipKey(pending[i]) < vipKey(pending[j])
+	})
+	allocated := map[string]string{}
+	for _, svc := range pending {
+		key := vipKey(svc)
+		if addr, f := allocated[key]; f {
+			svc.AutoAllocatedAddress = addr
+			continue
+		}
+		addr, ok := a.allocate(key, now)
+		if !ok {
+			log.Errorf("out of IPs to allocate for service entries in %v", a.cidr)
+			break
+		}
+		allocated[key] = addr
+		svc.AutoAllocatedAddress = addr
+	}
+	if len(allocated) > 0 {
+		select {
+		case a.dirty <- struct{}{}:
+		default:
+		}
+	}
+	return services
+}
+
+// allocate returns the first free address from the hash of the host, reclaiming the address of a host which has
+// been missing for vipReleaseGracePeriod. The lock must be held.
+func (a *VIPAllocator) allocate(key string, now time.Time) (string, bool) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	// Skip the network and broadcast addresses.
+	usable := a.size - 2
+	start := h.Sum32() % usable
+	for i := uint32(0); i < usable; i++ {
+		offset := 1 + (start+i)%usable
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, a.base+offset)
+		addr := ip.String()
+		owner, taken := a.hosts[addr]
+		if taken {
+			since, missing := a.missingSince[owner]
+			if !missing || now.Sub(since) < vipReleaseGracePeriod {
+				continue
+			}
+			delete(a.allocated, owner)
+			delete(a.missingSince, owner)
+			a.released[owner] = addr
+		}
+		a.allocated[key] = addr
+		a.hosts[addr] = key
+		return addr, true
+	}
+	return "", false
+}
+
+// Allocations returns a copy of the address of each host.
+func (a *VIPAllocator) Allocations() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make(map[string]string, len(a.allocated))
+	for k, v := range a.allocated {
+		out[k] = v
+	}
+	return out
+}
+
+// Run persists the new allocations until the stop channel is closed.
+func (a *VIPAllocator) Run(stop <-chan struct{}) {
+	if a.store == nil {
+		return
+	}
+	for {
+		select {
+		case <-stop:
+			return
+		case <-a.dirty:
+			if err := a.persist(); err != nil {
+				log.Errorf("failed to persist the allocated VIPs: %v", err)
+			}
+		}
+	}
+}
+
+// persist merges the allocations with the ones stored by the other istiods, and adopts the result.
+func (a *VIPAllocator) persist() error {
+	a.mu.Lock()
+	released := make(map[string]string, len(a.released))
+	for k, v := range a.released {
+		released[k] = v
+	}
+	a.mu.Unlock()
+	stored, err := a.store.Update(func(stored map[string]string) map[string]string {
+		return a.merge(stored, released)
+	})
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	for host, addr := range released {
+		if a.released[host] == addr {
+			delete(a.released, host)
+		}
+	}
+	changed := a.adopt(stored)
+	a.mu.Unlock()
+	if changed {
+		log.Infof("adopted the VIPs allocated by another istiod")
+		if a.onChange != nil {
+			a.onChange()
+		}
+	}
+	return nil
+}
+
+// merge returns the stored allocations, without the ones released, plus the hosts allocated by this istiod whose
+// address is free in the store. The hosts already stored keep their stored address.
+func (a *VIPAllocator) merge(stored, released map[string]string) map[string]string {
+	out := make(map[string]string, len(stored))
+	used := make(map[string]struct{}, len(stored))
+	for host, addr := range stored {
+		if released[host] == addr {
+			continue
+		}
+		out[host] = addr
+		used[addr] = struct{}{}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for host, addr := range a.allocated {
+		if _, f := out[host]; f {
+			continue
+		}
+		if _, f := used[addr]; f {
+			continue
+		}
+		out[host] = addr
+		used[addr] = struct{}{}
+	}
+	return out
+}
+
+type configMapVIPStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+const vipAllocationsKey = "allocations"
+
+// NewConfigMapVIPStore returns a store of the allocations in a ConfigMap. All the istiods of a revision share it: the
+// updates are merged with the current content, and retried on concurrent writes.
+func NewConfigMapVIPStore(client kubernetes.Interface, namespace, name string) VIPStore {
+	return &configMapVIPStore{client: client, namespace: namespace, name: name}
+}
+
+func (c *configMapVIPStore) Load() (map[string]string, error) {
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(context.TODO(), c.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeVIPAllocations(cm)
+}
+
+func decodeVIPAllocations(cm *corev1.ConfigMap) (map[string]string, error) {
+	out := map[string]string{}
+	if data := cm.Data[vipAllocationsKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (c *configMapVIPStore) Update(merge func(stored map[string]string) map[string]string) (map[string]string, error) {
+	client := c.client.CoreV1().ConfigMaps(c.namespace)
+	var out map[string]string
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm, err := client.Get(context.TODO(), c.name, metav1.GetOptions{})
+		create := apierrors.IsNotFound(err)
+		if err != nil && !create {
+			return err
+		}
+		stored := map[string]string{}
+		if !create {
+			if stored, err = decodeVIPAllocations(cm); err != nil {
+				log.Warnf("ignoring the invalid VIP allocations of %v/%v: %v", c.namespace, c.name, err)
+				stored = map[string]string{}
+			}
+		}
+		out = merge(stored)
+		data, err := json.Marshal(out)
+		if err != nil {
+			return err
+		}
+		if create {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: c.name, Namespace: c.namespace},
+				Data:       map[string]string{vipAllocationsKey: string(data)},
+			}
+			_, err = client.Create(context.TODO(), cm, metav1.CreateOptions{})
+			return err
+		}
+		// The resourceVersion of the ConfigMap read makes the update fail if it was changed since.
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[vipAllocationsKey] = string(data)
+		_, err = client.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
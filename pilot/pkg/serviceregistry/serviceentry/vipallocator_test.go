@@ -0,0 +1,314 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+type memoryVIPStore struct {
+	mu          sync.Mutex
+	allocations map[string]string
+}
+
+func (m *memoryVIPStore) Load() (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.allocations, nil
+}
+
+func (m *memoryVIPStore) Update(merge func(stored map[string]string) map[string]string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := map[string]string{}
+	for k, v := range m.allocations {
+		stored[k] = v
+	}
+	m.allocations = merge(stored)
+	return m.allocations, nil
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func vipService(hostname string) *model.Service {
+	return &model.Service{
+		Hostname:   host.Name(hostname),
+		Resolution: model.DNSLB,
+		Address:    "0.0.0.0",
+		Attributes: model.ServiceAttributes{Namespace: "default"},
+	}
+}
+
+func vipServices(hostnames ...string) []*model.Service {
+	out := make([]*model.Service, 0, len(hostnames))
+	for _, h := range hostnames {
+		out = append(out, vipService(h))
+	}
+	return out
+}
+
+func addresses(services []*model.Service) map[string]string {
+	out := map[string]string{}
+	for _, svc := range services {
+		out[string(svc.Hostname)] = svc.AutoAllocatedAddress
+	}
+	return out
+}
+
+func TestVIPAllocator(t *testing.T) {
+	t.Run("stable", func(t *testing.T) {
+		a, err := NewVIPAllocator("240.241.0.0/16", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := addresses(a.Allocate(vipServices("a.com", "b.com", "c.com")))
+		_, cidr, _ := net.ParseCIDR("240.241.0.0/16")
+		seen := map[string]bool{}
+		for h, addr := range first {
+			if ip := net.ParseIP(addr); ip == nil || !cidr.Contains(ip) || addr == "240.241.0.0" || addr == "240.241.255.255" {
+				t.Fatalf("invalid address %q for %v", addr, h)
+			}
+			if seen[addr] {
+				t.Fatalf("duplicated address %v: %v", addr, first)
+			}
+			seen[addr] = true
+		}
+
+		// Removing and adding hosts does not change the addresses of the others.
+		got := addresses(a.Allocate(vipServices("b.com", "c.com", "d.com")))
+		if got["b.com"] != first["b.com"] || got["c.com"] != first["c.com"] {
+			t.Fatalf("addresses changed: %v, was %v", got, first)
+		}
+		// A host coming back keeps its address, if it was not reused.
+		got = addresses(a.Allocate(vipServices("a.com", "b.com", "c.com", "d.com")))
+		if got["a.com"] != first["a.com"] {
+			t.Fatalf("address of a.com changed: %v, was %v", got, first)
+		}
+	})
+
+	t.Run("skips services with addresses", func(t *testing.T) {
+		a, _ := NewVIPAllocator("240.241.0.0/16", nil)
+		wildcard := vipService("*.foo.com")
+		passthrough := vipService("passthrough.com")
+		passthrough.Resolution = model.Passthrough
+		withAddress := vipService("address.com")
+		withAddress.Address = "1.1.1.1"
+		for _, svc := range a.Allocate([]*model.Service{wildcard, passthrough, withAddress}) {
+			if svc.AutoAllocatedAddress != "" {
+				t.Fatalf("unexpected address for %v: %v", svc.Hostname, svc.AutoAllocatedAddress)
+			}
+		}
+	})
+
+	t.Run("collisions", func(t *testing.T) {
+		// A /30 has two usable addresses.
+		a, _ := NewVIPAllocator("10.0.0.0/30", nil)
+		clock := &fakeClock{now: time.Now()}
+		a.now = clock.Now
+		got := addresses(a.Allocate(vipServices("a.com", "b.com", "c.com")))
+		allocated := map[string]bool{}
+		for _, addr := range got {
+			if addr != "" {
+				allocated[addr] = true
+			}
+		}
+		want := map[string]bool{"10.0.0.1": true, "10.0.0.2": true}
+		if !reflect.DeepEqual(allocated, want) {
+			t.Fatalf("expected all the usable addresses to be allocated, got %v", got)
+		}
+
+		// The address of a removed host is reclaimed when needed, once it has been missing for the grace period.
+		var removed string
+		for h, addr := range got {
+			if addr != "" {
+				removed = h
+				break
+			}
+		}
+		var remaining []string
+		for h := range got {
+			if h != removed {
+				remaining = append(remaining, h)
+			}
+		}
+		next := addresses(a.Allocate(vipServices(remaining...)))
+		if a.Allocations()["default/"+removed] != got[removed] {
+			t.Fatalf("expected the address of %v to be kept during the grace period, got %v", removed, next)
+		}
+		clock.now = clock.now.Add(vipReleaseGracePeriod)
+		next = addresses(a.Allocate(vipServices(remaining...)))
+		for _, h := range remaining {
+			if next[h] == "" {
+				t.Fatalf("expected %v to be allocated an address, got %v", h, next)
+			}
+		}
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		store := &memoryVIPStore{}
+		a, _ := NewVIPAllocator("240.241.0.0/16", store)
+		stop := make(chan struct{})
+		defer close(stop)
+		go a.Run(stop)
+		first := addresses(a.Allocate(vipServices("a.com", "b.com")))
+		retry.UntilSuccessOrFail(t, func() error {
+			if allocations, _ := store.Load(); len(allocations) != 2 {
+				return fmt.Errorf("expected 2 allocations, got %v", allocations)
+			}
+			return nil
+		})
+
+		// A restarted allocator restores the addresses, whatever the order of the hosts.
+		restarted, _ := NewVIPAllocator("240.241.0.0/16", store)
+		if got := addresses(restarted.Allocate(vipServices("b.com", "a.com"))); !reflect.DeepEqual(got, first) {
+			t.Fatalf("got %v, want %v", got, first)
+		}
+		// The allocations out of a new CIDR are dropped.
+		moved, _ := NewVIPAllocator("240.242.0.0/16", store)
+		if got := moved.Allocations(); len(got) != 0 {
+			t.Fatalf("expected no allocations, got %v", got)
+		}
+	})
+
+	t.Run("invalid cidr", func(t *testing.T) {
+		for _, cidr := range []string{"foo", "2001:db8::/64", "10.0.0.0/31", "10.0.0.0/4"} {
+			if _, err := NewVIPAllocator(cidr, nil); err == nil {
+				t.Errorf("expected an error for %v", cidr)
+			}
+		}
+	})
+}
+
+// collidingHosts returns two hosts of the default namespace whose addresses collide in a /30.
+func collidingHosts() (string, string) {
+	slot := func(h string) uint32 {
+		f := fnv.New32a()
+		_, _ = f.Write([]byte("default/" + h))
+		return f.Sum32() % 2
+	}
+	for i := 0; ; i++ {
+		if h := fmt.Sprintf("b%d.com", i); slot(h) == slot("a.com") {
+			return "a.com", h
+		}
+	}
+}
+
+func TestVIPAllocatorReplicas(t *testing.T) {
+	store := &memoryVIPStore{}
+	first, second := collidingHosts()
+	a, _ := NewVIPAllocator("10.0.0.0/30", store)
+	b, _ := NewVIPAllocator("10.0.0.0/30", store)
+
+	// Each replica first sees a different host, and allocates it the same address.
+	if got, other := addresses(a.Allocate(vipServices(first))), addresses(b.Allocate(vipServices(second))); got[first] != other[second] {
+		t.Fatalf("expected the hosts to collide, got %v and %v", got, other)
+	}
+	changed := false
+	b.onChange = func() { changed = true }
+	if err := a.persist(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.persist(); err != nil {
+		t.Fatal(err)
+	}
+	// The allocation stored first wins.
+	if !changed || !reflect.DeepEqual(b.Allocations(), a.Allocations()) {
+		t.Fatalf("expected the second replica to adopt the allocations of the first, got %v and %v", b.Allocations(), a.Allocations())
+	}
+
+	// Once both replicas see both hosts, they agree on their addresses.
+	gotA := addresses(a.Allocate(vipServices(first, second)))
+	gotB := addresses(b.Allocate(vipServices(first, second)))
+	if !reflect.DeepEqual(gotA, gotB) || gotA[first] == gotA[second] || gotA[second] == "" {
+		t.Fatalf("expected the replicas to agree, got %v and %v", gotA, gotB)
+	}
+	for _, r := range []*VIPAllocator{b, a} {
+		if err := r.persist(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored, _ := store.Load()
+	if !reflect.DeepEqual(stored, a.Allocations()) || !reflect.DeepEqual(stored, b.Allocations()) {
+		t.Fatalf("expected the store and the replicas to agree, got %v, %v and %v", stored, a.Allocations(), b.Allocations())
+	}
+
+	// An address reclaimed after the grace period is released from the store.
+	clock := &fakeClock{now: time.Now()}
+	a.now = clock.Now
+	third := "c.com"
+	a.Allocate(vipServices(first))
+	clock.now = clock.now.Add(vipReleaseGracePeriod)
+	got := addresses(a.Allocate(vipServices(first, third)))
+	if got[third] != gotA[second] {
+		t.Fatalf("expected %v to reclaim the address of %v, got %v", third, second, got)
+	}
+	if err := a.persist(); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ = store.Load()
+	if _, f := stored["default/"+second]; f || stored["default/"+third] != got[third] {
+		t.Fatalf("expected the address of %v to be released, got %v", second, stored)
+	}
+}
+
+func TestConfigMapVIPStore(t *testing.T) {
+	store := NewConfigMapVIPStore(fake.NewSimpleClientset(), "istio-system", VIPAllocationsConfigMap)
+	if got, err := store.Load(); err != nil || len(got) != 0 {
+		t.Fatalf("expected no allocations, got %v, %v", got, err)
+	}
+	for _, add := range []map[string]string{
+		{"default/a.com": "240.240.0.1"},
+		{"default/b.com": "240.240.0.2"},
+	} {
+		merged, err := store.Update(func(stored map[string]string) map[string]string {
+			for k, v := range add {
+				stored[k] = v
+			}
+			return stored
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, merged) {
+			t.Fatalf("got %v, want %v", got, merged)
+		}
+	}
+	want := map[string]string{"default/a.com": "240.240.0.1", "default/b.com": "240.240.0.2"}
+	if got, _ := store.Load(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
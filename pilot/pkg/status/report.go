@@ -29,6 +29,8 @@ type DistributionReport struct {
 	Reporter            string         `json:"reporter"`
 	DataPlaneCount      int            `json:"dataPlaneCount"`
 	InProgressResources map[string]int `json:"inProgressResources"`
+	// DataPlaneVersions maps each config version to the number of dataplane streams that have acked it.
+	DataPlaneVersions map[string]int `json:"dataPlaneVersions,omitempty"`
 }
 
 func ReportFromYaml(content []byte) (DistributionReport, error) {
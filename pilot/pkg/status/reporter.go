@@ -16,6 +16,9 @@ package status
 
 import (
 	"context"
+	"hash/fnv"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +27,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/utils/clock"
@@ -54,22 +58,49 @@ type inProgressEntry struct {
 	completedIterations int
 }
 
-type Reporter struct {
+// reporterShards is the number of shards the per-connection nonce records are split into. Each shard has
+// its own lock and event queue, so that ACKs from tens of thousands of proxies do not contend on a single lock.
+const reporterShards = 16
+
+// reporterShard holds the nonce records for a subset of the connections, selected by hashing the connection id.
+type reporterShard struct {
 	mu sync.RWMutex
 	// map from connection id to latest nonce
 	status map[string]string
 	// map from nonce to connection ids for which it is current
 	// using map[string]struct to approximate a hashset
 	reverseStatus          map[string]map[string]struct{}
-	inProgressResources    map[string]*inProgressEntry
-	client                 v1.ConfigMapInterface
-	cm                     *corev1.ConfigMap
-	UpdateInterval         time.Duration
-	PodName                string
-	clock                  clock.Clock
-	ledger                 ledger.Ledger
 	distributionEventQueue chan distributionEvent
-	controller             *DistributionController
+}
+
+func newReporterShard(queueSize int) *reporterShard {
+	return &reporterShard{
+		status:                 make(map[string]string),
+		reverseStatus:          make(map[string]map[string]struct{}),
+		distributionEventQueue: make(chan distributionEvent, queueSize),
+	}
+}
+
+type Reporter struct {
+	// mu protects inProgressResources
+	mu                  sync.RWMutex
+	shards              []*reporterShard
+	inProgressResources map[string]*inProgressEntry
+	client              v1.ConfigMapInterface
+	cm                  *corev1.ConfigMap
+	UpdateInterval      time.Duration
+	// HeartbeatInterval is the longest time an unchanged report is not written. Every replica writes a report,
+	// so skipping unchanged ones keeps the ConfigMap writes of an idle mesh low. It must be shorter than the
+	// interval after which the leader considers a report stale. If zero, the report is written on every update.
+	HeartbeatInterval time.Duration
+	PodName           string
+	clock             clock.Clock
+	ledger            ledger.Ledger
+	controller        *DistributionController
+
+	// lastReport and lastWrite are the content and time of the last successful report write.
+	lastReport string
+	lastWrite  time.Time
 }
 
 var _ xds.DistributionStatusCache = &Reporter{}
@@ -86,11 +117,41 @@ func (r *Reporter) Init(ledger ledger.Ledger, stop <-chan struct{}) {
 	if r.clock == nil {
 		r.clock = clock.RealClock{}
 	}
-	r.distributionEventQueue = make(chan distributionEvent, 100_000)
-	r.status = make(map[string]string)
-	r.reverseStatus = make(map[string]map[string]struct{})
+	r.initShards(100_000 / reporterShards)
 	r.inProgressResources = make(map[string]*inProgressEntry)
-	go r.readFromEventQueue(stop)
+	for _, shard := range r.shards {
+		go shard.readFromEventQueue(stop)
+	}
+}
+
+func (r *Reporter) initShards(queueSize int) {
+	r.shards = make([]*reporterShard, reporterShards)
+	for i := range r.shards {
+		r.shards[i] = newReporterShard(queueSize)
+	}
+}
+
+// shardFor returns the shard holding the nonce records of the given connection.
+func (r *Reporter) shardFor(conID string) *reporterShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(conID))
+	return r.shards[h.Sum32()%uint32(len(r.shards))]
+}
+
+// versionCounts returns the number of connection and type pairs currently at each config version, along with
+// the total number of pairs tracked, aggregated across all shards.
+func (r *Reporter) versionCounts() (map[string]int, int) {
+	counts := map[string]int{}
+	total := 0
+	for _, shard := range r.shards {
+		shard.mu.RLock()
+		total += len(shard.status)
+		for nonce, dataplanes := range shard.reverseStatus {
+			counts[nonce] += len(dataplanes)
+		}
+		shard.mu.RUnlock()
+	}
+	return counts, total
 }
 
 // Starts the reporter, which watches dataplane ack's and resource changes so that it can update status leader
@@ -125,7 +186,9 @@ func (r *Reporter) Start(clientSet kubernetes.Interface, namespace string, podna
 						scope.Errorf("failed to properly clean up distribution report: %v", err)
 					}
 				}
-				close(r.distributionEventQueue)
+				for _, shard := range r.shards {
+					close(shard.distributionEventQueue)
+				}
 				return
 			case <-t:
 				// TODO, check if report is necessary?  May already be handled by client
@@ -137,29 +200,31 @@ func (r *Reporter) Start(clientSet kubernetes.Interface, namespace string, podna
 
 // build a distribution report to send to status leader
 func (r *Reporter) buildReport() (DistributionReport, []Resource) {
+	versions, dataPlaneCount := r.versionCounts()
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	var finishedResources []Resource
 	out := DistributionReport{
 		Reporter:            r.PodName,
-		DataPlaneCount:      len(r.status),
+		DataPlaneCount:      dataPlaneCount,
 		InProgressResources: map[string]int{},
+		DataPlaneVersions:   versions,
 	}
 	// for every resource in flight
 	for _, ipr := range r.inProgressResources {
 		res := ipr.Resource
 		key := res.String()
 		// for every version (nonce) of the config currently in play
-		for nonce, dataplanes := range r.reverseStatus {
+		for nonce, dataplanes := range versions {
 
 			// check to see if this version of the config contains this version of the resource
 			// it might be more optimal to provide for a full dump of the config at a certain version?
 			dpVersion, err := r.ledger.GetPreviousValue(nonce, res.ToModelKey())
 			if err == nil && dpVersion == res.Generation {
 				if _, ok := out.InProgressResources[key]; !ok {
-					out.InProgressResources[key] = len(dataplanes)
+					out.InProgressResources[key] = dataplanes
 				} else {
-					out.InProgressResources[key] += len(dataplanes)
+					out.InProgressResources[key] += dataplanes
 				}
 			} else if err != nil {
 				scope.Errorf("Encountered error retrieving version %s of key %s from Store: %v", nonce, key, err)
@@ -243,12 +308,28 @@ func (r *Reporter) writeReport(ctx context.Context) {
 		scope.Errorf("Error serializing Distribution Report: %v", err)
 		return
 	}
+	now := r.clock.Now()
+	if !r.reportChanged(string(reportbytes), now) {
+		return
+	}
 	r.cm.Data[dataField] = string(reportbytes)
 	// TODO: short circuit this write in the leader
 	_, err = CreateOrUpdateConfigMap(ctx, r.cm, r.client)
 	if err != nil {
 		scope.Errorf("Error writing Distribution Report: %v", err)
+		return
 	}
+	r.lastReport = string(reportbytes)
+	r.lastWrite = now
+}
+
+// reportChanged returns true if the report must be written, because it differs from the last one written or
+// the heartbeat interval has elapsed.
+func (r *Reporter) reportChanged(report string, now time.Time) bool {
+	if r.HeartbeatInterval <= 0 || r.lastWrite.IsZero() {
+		return true
+	}
+	return report != r.lastReport || now.Sub(r.lastWrite) >= r.HeartbeatInterval
 }
 
 // this is lifted with few modifications from kubeadm's apiclient
@@ -274,9 +355,10 @@ type distributionEvent struct {
 
 func (r *Reporter) QueryLastNonce(conID string, distributionType xds.EventType) (noncePrefix string) {
 	key := GenStatusReporterMapKey(conID, distributionType)
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.status[key]
+	shard := r.shardFor(conID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.status[key]
 }
 
 // Register that a dataplane has acknowledged a new version of the config.
@@ -291,19 +373,19 @@ func (r *Reporter) RegisterEvent(conID string, distributionType xds.EventType, n
 	}
 	d := distributionEvent{nonce: nonce, distributionType: distributionType, conID: conID}
 	select {
-	case r.distributionEventQueue <- d:
+	case r.shardFor(conID).distributionEventQueue <- d:
 		return
 	default:
 		scope.Errorf("Distribution Event Queue overwhelmed, status will be invalid.")
 	}
 }
 
-func (r *Reporter) readFromEventQueue(stop <-chan struct{}) {
+func (s *reporterShard) readFromEventQueue(stop <-chan struct{}) {
 	for {
 		select {
-		case ev := <-r.distributionEventQueue:
+		case ev := <-s.distributionEventQueue:
 			// TODO might need to batch this to prevent lock contention
-			r.processEvent(ev.conID, ev.distributionType, ev.nonce)
+			s.processEvent(ev.conID, ev.distributionType, ev.nonce)
 		case <-stop:
 			return
 		}
@@ -311,10 +393,14 @@ func (r *Reporter) readFromEventQueue(stop <-chan struct{}) {
 }
 
 func (r *Reporter) processEvent(conID string, distributionType xds.EventType, nonce string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	r.shardFor(conID).processEvent(conID, distributionType, nonce)
+}
+
+func (s *reporterShard) processEvent(conID string, distributionType xds.EventType, nonce string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	key := GenStatusReporterMapKey(conID, distributionType)
-	r.deleteKeyFromReverseMap(key)
+	s.deleteKeyFromReverseMap(key)
 	var version string
 	if len(nonce) > 12 {
 		version = nonce[:xds.VersionLen]
@@ -322,21 +408,21 @@ func (r *Reporter) processEvent(conID string, distributionType xds.EventType, no
 		version = nonce
 	}
 	// touch
-	r.status[key] = version
-	if _, ok := r.reverseStatus[version]; !ok {
-		r.reverseStatus[version] = make(map[string]struct{})
+	s.status[key] = version
+	if _, ok := s.reverseStatus[version]; !ok {
+		s.reverseStatus[version] = make(map[string]struct{})
 	}
-	r.reverseStatus[version][key] = struct{}{}
+	s.reverseStatus[version][key] = struct{}{}
 }
 
 // This is a helper function for keeping our reverseStatus map in step with status.
 // must have write lock before calling.
-func (r *Reporter) deleteKeyFromReverseMap(key string) {
-	if old, ok := r.status[key]; ok {
-		if keys, ok := r.reverseStatus[old]; ok {
+func (s *reporterShard) deleteKeyFromReverseMap(key string) {
+	if old, ok := s.status[key]; ok {
+		if keys, ok := s.reverseStatus[old]; ok {
 			delete(keys, key)
-			if len(r.reverseStatus[old]) < 1 {
-				delete(r.reverseStatus, old)
+			if len(s.reverseStatus[old]) < 1 {
+				delete(s.reverseStatus, old)
 			}
 		}
 	}
@@ -344,13 +430,53 @@ func (r *Reporter) deleteKeyFromReverseMap(key string) {
 
 // When a dataplane disconnects, we should no longer count it, nor expect it to ack config.
 func (r *Reporter) RegisterDisconnect(conID string, types []xds.EventType) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	shard := r.shardFor(conID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
 	for _, xdsType := range types {
 		key := GenStatusReporterMapKey(conID, xdsType)
-		r.deleteKeyFromReverseMap(key)
-		delete(r.status, key)
+		shard.deleteKeyFromReverseMap(key)
+		delete(shard.status, key)
+	}
+}
+
+// ClusterVersions aggregates the config versions acked by dataplanes across all istiod replicas, using the
+// distribution reports each replica writes. This is required when proxies are load balanced across replicas,
+// as each replica only tracks its own connections. Reports are owned by the pod that wrote them, so reports from
+// terminated replicas are garbage collected.
+func (r *Reporter) ClusterVersions(ctx context.Context) (*DistributionReport, error) {
+	if r.client == nil {
+		return nil, errors.New("distribution reports are not written by this instance")
+	}
+	cms, err := r.client.List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set(map[string]string{labelKey: "true"}).AsSelector().String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list distribution reports")
+	}
+	out := &DistributionReport{
+		InProgressResources: map[string]int{},
+		DataPlaneVersions:   map[string]int{},
+	}
+	var reporters []string
+	for _, cm := range cms.Items {
+		rpt, err := ReportFromYaml([]byte(cm.Data[dataField]))
+		if err != nil {
+			scope.Warnf("received malformed distributionReport %s, discarding: %v", cm.Name, err)
+			continue
+		}
+		reporters = append(reporters, rpt.Reporter)
+		out.DataPlaneCount += rpt.DataPlaneCount
+		for res, count := range rpt.InProgressResources {
+			out.InProgressResources[res] += count
+		}
+		for version, count := range rpt.DataPlaneVersions {
+			out.DataPlaneVersions[version] += count
+		}
 	}
+	sort.Strings(reporters)
+	out.Reporter = strings.Join(reporters, ",")
+	return out, nil
 }
 
 func (r *Reporter) SetController(controller *DistributionController) {
@@ -36,14 +36,18 @@ func TestStatusMaps(t *testing.T) {
 	r.processEvent("conD", typ, "d")
 	RegisterTestingT(t)
 	x := struct{}{}
-	Expect(r.status).To(Equal(map[string]string{"conA~": "a", "conB~": "a", "conC~": "c", "conD~": "d"}))
-	Expect(r.reverseStatus).To(Equal(map[string]map[string]struct{}{"a": {"conA~": x, "conB~": x}, "c": {"conC~": x}, "d": {"conD~": x}}))
+	status, reverseStatus := statusMaps(&r)
+	Expect(status).To(Equal(map[string]string{"conA~": "a", "conB~": "a", "conC~": "c", "conD~": "d"}))
+	Expect(reverseStatus).To(Equal(map[string]map[string]struct{}{"a": {"conA~": x, "conB~": x}, "c": {"conC~": x}, "d": {"conD~": x}}))
 	r.processEvent("conA", typ, "d")
-	Expect(r.status).To(Equal(map[string]string{"conA~": "d", "conB~": "a", "conC~": "c", "conD~": "d"}))
-	Expect(r.reverseStatus).To(Equal(map[string]map[string]struct{}{"a": {"conB~": x}, "c": {"conC~": x}, "d": {"conD~": x, "conA~": x}}))
+	status, reverseStatus = statusMaps(&r)
+	Expect(status).To(Equal(map[string]string{"conA~": "d", "conB~": "a", "conC~": "c", "conD~": "d"}))
+	Expect(reverseStatus).To(Equal(map[string]map[string]struct{}{"a": {"conB~": x}, "c": {"conC~": x}, "d": {"conD~": x, "conA~": x}}))
 	r.RegisterDisconnect("conA", []xds.EventType{typ})
-	Expect(r.status).To(Equal(map[string]string{"conB~": "a", "conC~": "c", "conD~": "d"}))
-	Expect(r.reverseStatus).To(Equal(map[string]map[string]struct{}{"a": {"conB~": x}, "c": {"conC~": x}, "d": {"conD~": x}}))
+	status, reverseStatus = statusMaps(&r)
+	Expect(status).To(Equal(map[string]string{"conB~": "a", "conC~": "c", "conD~": "d"}))
+	Expect(reverseStatus).To(Equal(map[string]map[string]struct{}{"a": {"conB~": x}, "c": {"conC~": x}, "d": {"conD~": x}}))
+	Expect(r.QueryLastNonce("conC", typ)).To(Equal("c"))
 }
 
 func initReporterWithoutStarting() (out Reporter) {
@@ -53,11 +57,30 @@ func initReporterWithoutStarting() (out Reporter) {
 	out.clock = clock.RealClock{} // TODO
 	out.UpdateInterval = 300 * time.Millisecond
 	out.cm = nil // TODO
-	out.reverseStatus = make(map[string]map[string]struct{})
-	out.status = make(map[string]string)
+	out.initShards(100)
 	return
 }
 
+// statusMaps merges the nonce records of all shards, for comparison in tests.
+func statusMaps(r *Reporter) (map[string]string, map[string]map[string]struct{}) {
+	status := map[string]string{}
+	reverseStatus := map[string]map[string]struct{}{}
+	for _, shard := range r.shards {
+		for k, v := range shard.status {
+			status[k] = v
+		}
+		for nonce, keys := range shard.reverseStatus {
+			if _, f := reverseStatus[nonce]; !f {
+				reverseStatus[nonce] = map[string]struct{}{}
+			}
+			for k := range keys {
+				reverseStatus[nonce][k] = struct{}{}
+			}
+		}
+	}
+	return status, reverseStatus
+}
+
 func TestBuildReport(t *testing.T) {
 	RegisterTestingT(t)
 	r := initReporterWithoutStarting()
@@ -117,6 +140,10 @@ func TestBuildReport(t *testing.T) {
 	rpt, prunes := r.buildReport()
 	r.removeCompletedResource(prunes)
 	Expect(rpt.DataPlaneCount).To(Equal(2))
+	Expect(rpt.DataPlaneVersions).To(Equal(map[string]int{
+		firstNoncePrefix:    1,
+		r.ledger.RootHash(): 1,
+	}))
 	Expect(rpt.InProgressResources).To(Equal(map[string]int{
 		myResources[0].String(): 2,
 		myResources[1].String(): 1,
@@ -124,3 +151,26 @@ func TestBuildReport(t *testing.T) {
 	}))
 	Expect(r.inProgressResources).NotTo(ContainElement(resources[0]))
 }
+
+func TestReportChanged(t *testing.T) {
+	r := initReporterWithoutStarting()
+	r.HeartbeatInterval = 30 * time.Second
+	start := time.Now()
+	if !r.reportChanged("a", start) {
+		t.Fatalf("expected first report to be written")
+	}
+	r.lastReport, r.lastWrite = "a", start
+	if r.reportChanged("a", start.Add(time.Second)) {
+		t.Fatalf("expected unchanged report to be skipped")
+	}
+	if !r.reportChanged("b", start.Add(time.Second)) {
+		t.Fatalf("expected changed report to be written")
+	}
+	if !r.reportChanged("a", start.Add(r.HeartbeatInterval)) {
+		t.Fatalf("expected unchanged report to be written after the heartbeat interval")
+	}
+	r.HeartbeatInterval = 0
+	if !r.reportChanged("a", start.Add(time.Second)) {
+		t.Fatalf("expected every report to be written without heartbeat interval")
+	}
+}
@@ -16,8 +16,10 @@ package xds
 
 import (
 	"fmt"
+	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -81,6 +83,13 @@ type Connection struct {
 	// Currently based on the node name and a counter.
 	ConID string
 
+	// InstanceID identifies the proxy process that opened the connection, as reported in the node
+	// metadata. Unlike ConID, it is stable across reconnects. Empty if the client did not report it.
+	InstanceID string
+
+	// Reconnects is the number of earlier connections of the same proxy instance seen by this Istiod.
+	Reconnects int
+
 	// proxy is the client to which this connection is established.
 	proxy *model.Proxy
 
@@ -100,8 +109,17 @@ type Connection struct {
 	// the proxy, should not be started until this channel is closed.
 	initialized chan struct{}
 
-	// stop can be used to end the connection manually via debug endpoints. Only to be used for testing.
-	stop chan struct{}
+	// stop can be used to end the connection manually via debug endpoints, or when it is replaced
+	// by a newer connection from the same proxy.
+	stop     chan struct{}
+	stopOnce sync.Once
+	// reconnectAfter is the delay the proxy is asked to wait before reconnecting, when the server stops the
+	// connection while draining. It is set before stop is closed.
+	reconnectAfter time.Duration
+
+	// superseded is set when a newer connection from the same proxy is established and the
+	// DuplicateConnectionNewest policy is in effect. Pushes to superseded connections are skipped.
+	superseded uatomic.Bool
 
 	// reqChan is used to receive discovery requests for this connection.
 	reqChan      chan *discovery.DiscoveryRequest
@@ -114,6 +132,31 @@ type Connection struct {
 	// (last push not ACKed). When we get an ACK from Envoy, if the type is populated here, we will trigger
 	// the push.
 	blockedPushes map[string]*model.PushRequest
+
+	// configSizes tracks the size of the complete responses sent for each type. Protected by the proxy lock.
+	configSizes map[string]*configSize
+
+	// pushLatencies accounts the time spent generating and sending the responses of each type, and their size.
+	// Protected by the proxy lock.
+	pushLatencies map[string]*pushLatency
+
+	// resumption holds the config the client reported to have when it opened the stream, by type. Entries
+	// are removed once the first response of the type is generated. Only accessed by the stream goroutine.
+	resumption map[string]resumedConfig
+
+	// droppedClusters are the clusters dropped from the last complete CDS response by the resource limits, whose
+	// routes are removed from the RDS responses. Only accessed by the stream goroutine.
+	droppedClusters map[string]struct{}
+
+	// edsDiff tracks the load assignments ACKed by the proxy, if EDS diff suppression is enabled.
+	edsDiff *edsDiffTracker
+
+	// ackedConfig tracks the clusters, listeners and routes ACKed by the proxy, if config diff tracking is enabled.
+	ackedConfig *ackedConfigTracker
+
+	// pushHistory holds the most recent pushes sent, oldest first, up to PILOT_PUSH_HISTORY_SIZE. Protected by
+	// the proxy lock.
+	pushHistory []*pushRecord
 }
 
 // Event represents a config or registry event that results in a push.
@@ -126,7 +169,7 @@ type Event struct {
 }
 
 func newConnection(peerAddr string, stream DiscoveryStream) *Connection {
-	return &Connection{
+	con := &Connection{
 		pushChannel:   make(chan *Event),
 		initialized:   make(chan struct{}),
 		stop:          make(chan struct{}),
@@ -136,7 +179,13 @@ func newConnection(peerAddr string, stream DiscoveryStream) *Connection {
 		Connect:       time.Now(),
 		stream:        stream,
 		blockedPushes: map[string]*model.PushRequest{},
+		configSizes:   map[string]*configSize{},
+		edsDiff:       newEdsDiffTracker(),
 	}
+	if features.EnableConfigDiffTracking {
+		con.ackedConfig = newAckedConfigTracker()
+	}
+	return con
 }
 
 func (s *DiscoveryServer) receive(con *Connection) {
@@ -196,6 +245,9 @@ func (s *DiscoveryServer) processRequest(req *discovery.DiscoveryRequest, con *C
 	if !s.shouldProcessRequest(con.proxy, req) {
 		return nil
 	}
+	if s.Failpoints.dropAck(con.proxy, req.TypeUrl, req.ResponseNonce) {
+		return nil
+	}
 
 	// For now, don't let xDS piggyback debug requests start watchers.
 	if strings.HasPrefix(req.TypeUrl, v3.DebugType) {
@@ -261,6 +313,9 @@ func (s *DiscoveryServer) Stream(stream DiscoveryStream) error {
 	if !s.IsServerReady() {
 		return status.Error(codes.Unavailable, "server is not ready to serve discovery information")
 	}
+	if err := s.shedConnection(stream); err != nil {
+		return err
+	}
 
 	ctx := stream.Context()
 	peerAddr := "0.0.0.0"
@@ -287,6 +342,9 @@ func (s *DiscoveryServer) Stream(stream DiscoveryStream) error {
 	}
 	con := newConnection(peerAddr, stream)
 	con.Identities = ids
+	if features.EnableXDSResumption {
+		con.resumption = resumptionTokenFromContext(ctx)
+	}
 
 	// Do not call: defer close(con.pushChannel). The push channel will be garbage collected
 	// when the connection is no longer used. Closing the channel can cause subtle race conditions
@@ -304,6 +362,8 @@ func (s *DiscoveryServer) Stream(stream DiscoveryStream) error {
 	// initialization is complete.
 	<-con.initialized
 
+	deadline, stopDeadline := streamDeadline(features.XDSStreamMaxDuration)
+	defer stopDeadline()
 	for {
 		select {
 		case req, ok := <-con.reqChan:
@@ -321,12 +381,33 @@ func (s *DiscoveryServer) Stream(stream DiscoveryStream) error {
 			if err != nil {
 				return err
 			}
+		case <-deadline:
+			return streamDeadlineExceeded(con)
 		case <-con.stop:
-			return nil
+			return con.stopStatus(stream)
 		}
 	}
 }
 
+// streamDeadline returns a channel receiving once a stream reaches its max duration, with up to 10% jitter so
+// that the proxies connected at the same time do not all reconnect at once, and a function to release it. The
+// channel never receives if the max duration is zero.
+func streamDeadline(maxDuration time.Duration) (<-chan time.Time, func()) {
+	if maxDuration <= 0 {
+		return nil, func() {}
+	}
+	jitter := time.Duration(rand.Int63n(int64(maxDuration)/10 + 1))
+	t := time.NewTimer(maxDuration + jitter)
+	return t.C, func() { t.Stop() }
+}
+
+// streamDeadlineExceeded returns the error closing a stream that reached its max duration. Proxies keep their
+// configuration and open a new stream.
+func streamDeadlineExceeded(con *Connection) error {
+	log.Infof("ADS: %q %s reached its max duration, closing the stream", con.PeerAddr, con.ConID)
+	return status.Error(codes.Unavailable, "stream reached its max duration; reconnect")
+}
+
 // shouldRespond determines whether this request needs to be responded back. It applies the ack/nack rules as per xds protocol
 // using WatchedResource for previous state and discovery request for the current state.
 func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.DiscoveryRequest) bool {
@@ -342,11 +423,18 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 		if s.StatusGen != nil {
 			s.StatusGen.OnNack(con.proxy, request)
 		}
+		s.Events.ProxyRejected(con.proxy, stype, request.ErrorDetail.GetMessage())
 		con.proxy.Lock()
 		if w, f := con.proxy.WatchedResources[request.TypeUrl]; f {
 			w.NonceNacked = request.ResponseNonce
 		}
 		con.proxy.Unlock()
+		if request.TypeUrl == v3.EndpointType && con.edsDiff != nil {
+			con.edsDiff.nack()
+		}
+		if con.ackedConfig != nil {
+			con.ackedConfig.nack(request.TypeUrl)
+		}
 		return false
 	}
 
@@ -402,10 +490,17 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 	previousResources := con.proxy.WatchedResources[request.TypeUrl].ResourceNames
 	con.proxy.WatchedResources[request.TypeUrl].VersionAcked = request.VersionInfo
 	con.proxy.WatchedResources[request.TypeUrl].NonceAcked = request.ResponseNonce
+	con.proxy.WatchedResources[request.TypeUrl].AckedHash = con.proxy.WatchedResources[request.TypeUrl].ResourcesHash
 	con.proxy.WatchedResources[request.TypeUrl].NonceNacked = ""
 	con.proxy.WatchedResources[request.TypeUrl].ResourceNames = request.ResourceNames
 	con.proxy.WatchedResources[request.TypeUrl].LastRequest = request
 	con.proxy.Unlock()
+	if request.TypeUrl == v3.EndpointType && con.edsDiff != nil {
+		con.edsDiff.ack(request.ResponseNonce)
+	}
+	if con.ackedConfig != nil {
+		con.ackedConfig.ack(request.TypeUrl, request.ResponseNonce)
+	}
 
 	// Envoy can send two DiscoveryRequests with same version and nonce
 	// when it detects a new resource. We should respond if they change.
@@ -437,7 +532,7 @@ func shouldUnsubscribe(request *discovery.DiscoveryRequest) bool {
 // resource names.
 func isWildcardTypeURL(typeURL string) bool {
 	switch typeURL {
-	case v3.SecretType, v3.EndpointType, v3.RouteType, v3.ExtensionConfigurationType:
+	case v3.SecretType, v3.EndpointType, v3.LbEndpointType, v3.RouteType, v3.VirtualHostType, v3.ExtensionConfigurationType:
 		// By XDS spec, these are not wildcard
 		return false
 	case v3.ClusterType, v3.ListenerType:
@@ -479,14 +574,15 @@ func (s *DiscoveryServer) initConnection(node *core.Node, con *Connection) error
 	con.ConID = connectionID(proxy.ID)
 	con.node = node
 	con.proxy = proxy
-	if features.EnableXDSIdentityCheck && con.Identities != nil {
-		// TODO: allow locking down, rejecting unauthenticated requests.
-		id, err := checkConnectionIdentity(con)
-		if err != nil {
-			log.Warnf("Unauthorized XDS: %v with identity %v: %v", con.PeerAddr, con.Identities, err)
-			return status.Newf(codes.PermissionDenied, "authorization failed: %v", err).Err()
-		}
-		con.proxy.VerifiedIdentity = id
+	con.InstanceID = proxy.Metadata.ProxyInstanceID
+	if err := s.verifyIdentity(con); err != nil {
+		return err
+	}
+	if err := s.checkAffinity(con); err != nil {
+		return err
+	}
+	if err := s.handleDuplicateConnection(con); err != nil {
+		return err
 	}
 
 	// Register the connection. this allows pushes to be triggered for the proxy. Note: the timing of
@@ -495,6 +591,13 @@ func (s *DiscoveryServer) initConnection(node *core.Node, con *Connection) error
 	// context between initializeProxy and addCon, we would not get any pushes triggered for the new
 	// push context, leading the proxy to have a stale state until the next full push.
 	s.addCon(con.ConID, con)
+	// The address may belong to a terminated proxy that was draining.
+	s.drainingEndpoints.clear(proxy)
+	con.Reconnects = s.proxyInstances.connect(proxy.ID, con.InstanceID, con.ConID, con.Connect)
+	if con.Reconnects > 0 {
+		log.Infof("ADS: proxy instance %s of %s reconnected (%d reconnects)", con.InstanceID, proxy.ID, con.Reconnects)
+		xdsReconnects.Increment()
+	}
 	// Register that initialization is complete. This triggers to calls that it is safe to access the
 	// proxy
 	defer close(con.initialized)
@@ -516,6 +619,8 @@ func (s *DiscoveryServer) closeConnection(con *Connection) {
 		return
 	}
 	s.removeCon(con.ConID)
+	s.promoteDuplicateConnection(con)
+	s.proxyInstances.disconnect(con.proxy.ID, con.InstanceID, con.ConID, time.Now())
 	if s.StatusGen != nil {
 		s.StatusGen.OnDisconnect(con)
 	}
@@ -525,6 +630,55 @@ func (s *DiscoveryServer) closeConnection(con *Connection) {
 	s.WorkloadEntryController.QueueUnregisterWorkload(con.proxy, con.Connect)
 }
 
+// IdentityCheckMode controls how the identity claimed by a proxy in its node ID and metadata is
+// verified against the identity it authenticated with.
+type IdentityCheckMode string
+
+const (
+	// IdentityCheckEnforce rejects connections with a mismatched identity.
+	IdentityCheckEnforce IdentityCheckMode = "enforce"
+	// IdentityCheckPermissive records mismatches, but allows the connection.
+	IdentityCheckPermissive IdentityCheckMode = "permissive"
+	// IdentityCheckDisabled skips the check.
+	IdentityCheckDisabled IdentityCheckMode = "disabled"
+)
+
+func identityCheckMode() IdentityCheckMode {
+	switch mode := IdentityCheckMode(features.XDSIdentityCheckMode); mode {
+	case IdentityCheckEnforce, IdentityCheckPermissive, IdentityCheckDisabled:
+		return mode
+	case "":
+	default:
+		log.Warnf("unknown XDS identity check mode %q, falling back to PILOT_ENABLE_XDS_IDENTITY_CHECK", mode)
+	}
+	if features.EnableXDSIdentityCheck {
+		return IdentityCheckEnforce
+	}
+	return IdentityCheckDisabled
+}
+
+// verifyIdentity checks the identity claimed by the proxy of the connection against its credentials,
+// according to the IdentityCheckMode.
+func (s *DiscoveryServer) verifyIdentity(con *Connection) error {
+	if s.IdentityCheckMode == IdentityCheckDisabled || con.Identities == nil {
+		// TODO: allow locking down, rejecting unauthenticated requests.
+		return nil
+	}
+	id, err := checkConnectionIdentity(con)
+	if err != nil {
+		if s.IdentityCheckMode == IdentityCheckEnforce {
+			log.Warnf("Unauthorized XDS: %v with identity %v: %v", con.PeerAddr, con.Identities, err)
+			xdsIdentityViolations.With(actionTag.Value("rejected")).Increment()
+			return status.Newf(codes.PermissionDenied, "authorization failed: %v", err).Err()
+		}
+		log.Warnf("Unauthorized XDS (permissive): %v with identity %v: %v", con.PeerAddr, con.Identities, err)
+		xdsIdentityViolations.With(actionTag.Value("allowed")).Increment()
+		return nil
+	}
+	con.proxy.VerifiedIdentity = id
+	return nil
+}
+
 func checkConnectionIdentity(con *Connection) (*spiffe.Identity, error) {
 	for _, rawID := range con.Identities {
 		spiffeID, err := spiffe.ParseIdentity(rawID)
@@ -537,9 +691,30 @@ func checkConnectionIdentity(con *Connection) (*spiffe.Identity, error) {
 		if con.proxy.Metadata.ServiceAccount != "" && spiffeID.ServiceAccount != con.proxy.Metadata.ServiceAccount {
 			continue
 		}
+		// The namespace is also encoded in the node ID; make sure it cannot be used to claim another namespace
+		// while the metadata matches the identity.
+		ns := nodeIDNamespace(con.proxy)
+		if ns == "" && con.proxy.Type == model.SidecarProxy {
+			return nil, fmt.Errorf("node %q does not include a namespace", con.proxy.ID)
+		}
+		if ns != "" && spiffeID.Namespace != ns {
+			continue
+		}
 		return &spiffeID, nil
 	}
-	return nil, fmt.Errorf("no identities (%v) matched %v/%v", con.Identities, con.proxy.ConfigNamespace, con.proxy.Metadata.ServiceAccount)
+	return nil, fmt.Errorf("no identities (%v) matched %v/%v (node %v)",
+		con.Identities, con.proxy.ConfigNamespace, con.proxy.Metadata.ServiceAccount, con.proxy.ID)
+}
+
+// nodeIDNamespace returns the namespace claimed by the node ID, which takes the form <name>.<namespace>.
+// Namespaces cannot contain dots, so the namespace is the segment after the last dot; the name may
+// contain any number of dots. An empty string is returned if the ID does not include a namespace.
+func nodeIDNamespace(proxy *model.Proxy) string {
+	i := strings.LastIndex(proxy.ID, ".")
+	if i < 0 {
+		return ""
+	}
+	return proxy.ID[i+1:]
 }
 
 func connectionID(node string) string {
@@ -574,6 +749,14 @@ func (s *DiscoveryServer) initializeProxy(node *core.Node, con *Connection) erro
 	if err := s.WorkloadEntryController.RegisterWorkload(proxy, con.Connect); err != nil {
 		return err
 	}
+	s.initProxyState(node, proxy)
+
+	recordXDSClients(proxy.Metadata.IstioVersion, 1)
+	return nil
+}
+
+// initProxyState computes the state of the proxy needed to generate its config.
+func (s *DiscoveryServer) initProxyState(node *core.Node, proxy *model.Proxy) {
 	s.computeProxyState(proxy, nil)
 
 	// Get the locality from the proxy's service instances.
@@ -602,9 +785,6 @@ func (s *DiscoveryServer) initializeProxy(node *core.Node, con *Connection) erro
 	if proxy.Metadata.Generator != "" {
 		proxy.XdsResourceGenerator = s.Generators[proxy.Metadata.Generator]
 	}
-
-	recordXDSClients(proxy.Metadata.IstioVersion, 1)
-	return nil
 }
 
 func (s *DiscoveryServer) updateProxy(proxy *model.Proxy, request *model.PushRequest) {
@@ -659,6 +839,10 @@ func (s *DiscoveryServer) computeProxyState(proxy *model.Proxy, request *model.P
 
 // shouldProcessRequest returns whether or not to continue with the request.
 func (s *DiscoveryServer) shouldProcessRequest(proxy *model.Proxy, req *discovery.DiscoveryRequest) bool {
+	if req.TypeUrl == v3.DrainingType {
+		s.handleDraining(proxy)
+		return false
+	}
 	if req.TypeUrl != v3.HealthInfoType {
 		return true
 	}
@@ -691,6 +875,12 @@ func (s *DiscoveryServer) DeltaAggregatedResources(stream discovery.AggregatedDi
 func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 	pushRequest := pushEv.pushRequest
 
+	if con.superseded.Load() {
+		log.Debugf("Skipping push to %v, superseded by a newer connection", con.ConID)
+		return nil
+	}
+	s.Failpoints.delay(FailpointDelayPush, con.proxy, "")
+
 	if pushRequest.Full {
 		// Update Proxy with current information.
 		s.updateProxy(con.proxy, pushRequest)
@@ -754,15 +944,21 @@ func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 
 // PushOrder defines the order that updates will be pushed in. Any types not listed here will be pushed in random
 // order after the types listed here
-var PushOrder = []string{v3.ClusterType, v3.EndpointType, v3.ListenerType, v3.RouteType, v3.SecretType}
+var PushOrder = []string{
+	v3.ClusterType, v3.EndpointType, v3.LbEndpointType, v3.ListenerType,
+	v3.ScopedRouteType, v3.RouteType, v3.VirtualHostType, v3.SecretType,
+}
 
 // KnownOrderedTypeUrls has typeUrls for which we know the order of push.
 var KnownOrderedTypeUrls = map[string]struct{}{
-	v3.ClusterType:  {},
-	v3.EndpointType: {},
-	v3.ListenerType: {},
-	v3.RouteType:    {},
-	v3.SecretType:   {},
+	v3.ClusterType:     {},
+	v3.EndpointType:    {},
+	v3.LbEndpointType:  {},
+	v3.ListenerType:    {},
+	v3.ScopedRouteType: {},
+	v3.RouteType:       {},
+	v3.VirtualHostType: {},
+	v3.SecretType:      {},
 }
 
 // orderWatchedResources orders the resources in accordance with known push order.
@@ -819,13 +1015,13 @@ func (s *DiscoveryServer) ProxyUpdate(clusterID cluster.ID, ip string) {
 		return
 	}
 	if log.DebugEnabled() {
-		currentlyPending := s.pushQueue.Pending()
+		currentlyPending := s.pendingPushes()
 		if currentlyPending != 0 {
 			log.Debugf("Starting new push while %v were still pending", currentlyPending)
 		}
 	}
 
-	s.pushQueue.Enqueue(connection, &model.PushRequest{
+	s.enqueue(connection, &model.PushRequest{
 		Full:   true,
 		Push:   s.globalPushContext(),
 		Start:  time.Now(),
@@ -868,14 +1064,14 @@ func (s *DiscoveryServer) AdsPushAll(version string, req *model.PushRequest) {
 func (s *DiscoveryServer) startPush(req *model.PushRequest) {
 	// Push config changes, iterating over connected envoys.
 	if log.DebugEnabled() {
-		currentlyPending := s.pushQueue.Pending()
+		currentlyPending := s.pendingPushes()
 		if currentlyPending != 0 {
 			log.Infof("Starting new push while %v were still pending", currentlyPending)
 		}
 	}
 	req.Start = time.Now()
 	for _, p := range s.AllClients() {
-		s.pushQueue.Enqueue(p, req)
+		s.enqueue(p, req)
 	}
 }
 
@@ -883,6 +1079,10 @@ func (s *DiscoveryServer) addCon(conID string, con *Connection) {
 	s.adsClientsMutex.Lock()
 	defer s.adsClientsMutex.Unlock()
 	s.adsClients[conID] = con
+	if s.connectionsByProxy[con.proxy.ID] == nil {
+		s.connectionsByProxy[con.proxy.ID] = map[string]*Connection{}
+	}
+	s.connectionsByProxy[con.proxy.ID][conID] = con
 }
 
 func (s *DiscoveryServer) removeCon(conID string) {
@@ -894,6 +1094,10 @@ func (s *DiscoveryServer) removeCon(conID string) {
 		totalXDSInternalErrors.Increment()
 	} else {
 		delete(s.adsClients, conID)
+		delete(s.connectionsByProxy[con.proxy.ID], conID)
+		if len(s.connectionsByProxy[con.proxy.ID]) == 0 {
+			delete(s.connectionsByProxy, con.proxy.ID)
+		}
 		recordXDSClients(con.proxy.Metadata.IstioVersion, -1)
 	}
 }
@@ -902,7 +1106,7 @@ func (s *DiscoveryServer) removeCon(conID string) {
 func (conn *Connection) send(res *discovery.DiscoveryResponse) error {
 	sendHandler := func() error {
 		start := time.Now()
-		defer func() { recordSendTime(time.Since(start)) }()
+		defer func() { recordSendTime(res.TypeUrl, time.Since(start)) }()
 		return conn.stream.Send(res)
 	}
 	err := istiogrpc.Send(conn.stream.Context(), sendHandler)
@@ -999,6 +1203,9 @@ func (conn *Connection) Watched(typeUrl string) *model.WatchedResource {
 	return nil
 }
 
+// Stop ends the connection. It is safe to call multiple times.
 func (conn *Connection) Stop() {
-	conn.stop <- struct{}{}
+	conn.stopOnce.Do(func() {
+		close(conn.stop)
+	})
 }
@@ -21,6 +21,7 @@ import (
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
 	"google.golang.org/genproto/googleapis/rpc/status"
 
 	networking "istio.io/api/networking/v1alpha3"
@@ -98,6 +99,40 @@ func TestAdsReconnectAfterRestart(t *testing.T) {
 	})
 }
 
+func TestAdsMoveBetweenReplicas(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{Replicas: 1})
+	replica := s.Replicas[0]
+	proxy := &model.Proxy{}
+
+	ads := s.Connect(proxy, []string{v3.ClusterType}, []string{v3.ClusterType})
+	retry.UntilOrFail(t, func() bool {
+		return len(s.Discovery.Clients()) == 1 && len(replica.Discovery.Clients()) == 0
+	})
+
+	// Config changes reach every replica
+	s.Discovery.MemRegistry.AddService("replicated.default.svc.cluster.local", &model.Service{
+		Hostname: "replicated.default.svc.cluster.local",
+		Address:  "10.11.0.2",
+		Ports: []*model.Port{{
+			Name:     "http",
+			Port:     80,
+			Protocol: protocol.HTTP,
+		}},
+		Attributes: model.ServiceAttributes{
+			Name:      "replicated",
+			Namespace: "default",
+		},
+	})
+	retry.UntilOrFail(t, func() bool {
+		return replica.PushContext().ServiceForHostname(nil, "replicated.default.svc.cluster.local") != nil
+	})
+
+	replica.MoveConnection(ads, proxy, []string{v3.ClusterType}, []string{v3.ClusterType})
+	retry.UntilOrFail(t, func() bool {
+		return len(s.Discovery.Clients()) == 0 && len(replica.Discovery.Clients()) == 1
+	})
+}
+
 func TestAdsUnsubscribe(t *testing.T) {
 	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
 
@@ -130,6 +165,55 @@ func TestAdsReconnect(t *testing.T) {
 	ads2.ExpectResponse(t)
 }
 
+func TestAdsDuplicateConnectionPolicy(t *testing.T) {
+	t.Run("reject", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+		s.Discovery.DuplicateConnectionPolicy = xds.DuplicateConnectionReject
+		ads := s.ConnectADS().WithType(v3.ClusterType)
+		ads.RequestResponseAck(t, nil)
+
+		ads2 := s.ConnectADS().WithType(v3.ClusterType)
+		ads2.Request(t, nil)
+		ads2.ExpectError(t)
+
+		// The original connection is unaffected
+		xds.AdsPushAll(s.Discovery)
+		ads.ExpectResponse(t)
+	})
+	t.Run("close-old", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+		s.Discovery.DuplicateConnectionPolicy = xds.DuplicateConnectionCloseOld
+		s.Discovery.DuplicateConnectionGracePeriod = 0
+		ads := s.ConnectADS().WithType(v3.ClusterType)
+		ads.RequestResponseAck(t, nil)
+
+		ads2 := s.ConnectADS().WithType(v3.ClusterType)
+		ads2.RequestResponseAck(t, nil)
+		ads.ExpectError(t)
+
+		xds.AdsPushAll(s.Discovery)
+		ads2.ExpectResponse(t)
+	})
+	t.Run("newest", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+		s.Discovery.DuplicateConnectionPolicy = xds.DuplicateConnectionNewest
+		ads := s.ConnectADS().WithType(v3.ClusterType)
+		ads.RequestResponseAck(t, nil)
+
+		ads2 := s.ConnectADS().WithType(v3.ClusterType)
+		ads2.RequestResponseAck(t, nil)
+
+		// Only the newest connection receives pushes
+		xds.AdsPushAll(s.Discovery)
+		ads2.ExpectResponse(t)
+		ads.ExpectNoResponse(t)
+
+		// Once the newest connection closes, the older one takes over
+		ads2.Cleanup()
+		ads.ExpectResponse(t)
+	})
+}
+
 // Regression for connection with a bad ID
 func TestAdsBadId(t *testing.T) {
 	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
@@ -152,13 +236,7 @@ func TestAdsClusterUpdate(t *testing.T) {
 		})
 		version = res.VersionInfo
 		nonce = res.Nonce
-		got := xdstest.MapKeys(xdstest.ExtractLoadAssignments(xdstest.UnmarshalClusterLoadAssignment(t, res.Resources)))
-		if len(got) != 1 {
-			t.Fatalf("expected 1 response, got %v", len(got))
-		}
-		if got[0] != clusterName {
-			t.Fatalf("expected cluster %v got %v", clusterName, got[0])
-		}
+		xds.MatchResources(t, res, xds.MatchResource(clusterName, nil))
 	}
 
 	cluster1 := "outbound|80||local.default.svc.cluster.local"
@@ -169,7 +247,8 @@ func TestAdsClusterUpdate(t *testing.T) {
 
 // nolint: lll
 func TestAdsPushScoping(t *testing.T) {
-	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	// The updates of each case are only pushed when the virtual clock is moved past the debounce window.
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{VirtualTime: true})
 
 	const (
 		svcSuffix = ".testPushScoping.com"
@@ -647,8 +726,6 @@ func TestAdsPushScoping(t *testing.T) {
 
 	for _, c := range svcCases {
 		t.Run(c.desc, func(t *testing.T) {
-			// Let events from previous tests complete
-			time.Sleep(time.Millisecond * 100)
 			adscConn.WaitClear()
 			var wantUpdates []string
 			wantUpdates = append(wantUpdates, c.expectUpdates...)
@@ -727,6 +804,7 @@ func TestAdsPushScoping(t *testing.T) {
 			default:
 				t.Fatalf("wrong event for case %v", c)
 			}
+			s.AdvanceDebounce()
 
 			timeout := time.Second
 			upd, _ := adscConn.Wait(timeout, wantUpdates...) // XXX slow for unexpect ...
@@ -745,7 +823,7 @@ func TestAdsPushScoping(t *testing.T) {
 }
 
 func TestAdsUpdate(t *testing.T) {
-	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{VirtualTime: true})
 	ads := s.ConnectADS()
 
 	s.Discovery.MemRegistry.AddService("adsupdate.default.svc.cluster.local", &model.Service{
@@ -764,7 +842,7 @@ func TestAdsUpdate(t *testing.T) {
 		},
 	})
 	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
-	time.Sleep(time.Millisecond * 200)
+	s.AdvanceDebounce()
 	s.Discovery.MemRegistry.SetEndpoints("adsupdate.default.svc.cluster.local", "default",
 		newEndpointWithAccount("10.2.0.1", "hello-sa", "v1"))
 
@@ -787,8 +865,7 @@ func TestAdsUpdate(t *testing.T) {
 	// will trigger recompute and push for all clients - including some that may be closing
 	// This reproduced the 'push on closed connection' bug.
 	xds.AdsPushAll(s.Discovery)
-	res1 := ads.ExpectResponse(t)
-	xdstest.UnmarshalClusterLoadAssignment(t, res1.GetResources())
+	ads.ExpectResources(t, xds.MatchResource(cluster, nil))
 }
 
 func TestEnvoyRDSProtocolError(t *testing.T) {
@@ -886,13 +963,101 @@ func TestBlockedPush(t *testing.T) {
 	})
 }
 
+func TestSuppressUnchangedPushes(t *testing.T) {
+	original := features.SuppressUnchangedPushes
+	features.SuppressUnchangedPushes = true
+	t.Cleanup(func() {
+		features.SuppressUnchangedPushes = original
+	})
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	res := ads.RequestResponseAck(t, nil)
+
+	// A full push not changing the clusters is not sent.
+	xds.AdsPushAll(s.Discovery)
+	ads.ExpectNoResponse(t)
+
+	// A request of the proxy is always answered.
+	ads.Request(t, &discovery.DiscoveryRequest{ResponseNonce: res.Nonce, VersionInfo: res.VersionInfo})
+	ads.ExpectNoResponse(t)
+	ads.Request(t, &discovery.DiscoveryRequest{})
+	res = ads.ExpectResponse(t)
+	ads.Request(t, &discovery.DiscoveryRequest{ResponseNonce: res.Nonce, VersionInfo: res.VersionInfo})
+
+	// A new service changes the clusters.
+	s.Discovery.MemRegistry.AddHTTPService("suppressed.default.svc.cluster.local", "10.10.0.1", 80)
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+	got := ads.ExpectResponse(t)
+	if len(got.Resources) != len(res.Resources)+1 {
+		t.Fatalf("expected the cluster of the new service to be added to %d clusters, got %d",
+			len(res.Resources), len(got.Resources))
+	}
+
+	// A rejected response is sent again, as the proxy still runs the config it ACKed before.
+	ads.Request(t, &discovery.DiscoveryRequest{ResponseNonce: got.Nonce, ErrorDetail: &status.Status{Message: "rejected"}})
+	xds.AdsPushAll(s.Discovery)
+	ads.ExpectResponse(t)
+}
+
+func TestFailpoints(t *testing.T) {
+	original := features.EnableFlowControl
+	t.Cleanup(func() {
+		features.EnableFlowControl = original
+	})
+	features.EnableFlowControl = true
+	t.Run("drop ack", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+		ads := s.ConnectADS().WithType(v3.ClusterType)
+		ads.RequestResponseAck(t, nil)
+
+		if err := s.Discovery.Failpoints.Enable(xds.FailpointDropAck, xds.Failpoint{Type: "CDS"}); err != nil {
+			t.Fatal(err)
+		}
+		xds.AdsPushAll(s.Discovery)
+		res := ads.ExpectResponse(t)
+		ads.Request(t, &discovery.DiscoveryRequest{ResponseNonce: res.Nonce})
+
+		// The ACK was lost, so the push is blocked
+		xds.AdsPushAll(s.Discovery)
+		ads.ExpectNoResponse(t)
+
+		// Once ACKs go through again, the blocked push is sent
+		s.Discovery.Failpoints.Disable(xds.FailpointDropAck)
+		ads.Request(t, &discovery.DiscoveryRequest{ResponseNonce: res.Nonce})
+		ads.ExpectResponse(t)
+	})
+	t.Run("corrupt nonce", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+		ads := s.ConnectADS().WithType(v3.ClusterType)
+		ads.RequestResponseAck(t, nil)
+
+		if err := s.Discovery.Failpoints.Enable(xds.FailpointCorruptNonce, xds.Failpoint{}); err != nil {
+			t.Fatal(err)
+		}
+		xds.AdsPushAll(s.Discovery)
+		res := ads.ExpectResponse(t)
+
+		// The ACK holds an unknown nonce, so it is ignored and the push is blocked
+		ads.Request(t, &discovery.DiscoveryRequest{ResponseNonce: res.Nonce})
+		xds.AdsPushAll(s.Discovery)
+		ads.ExpectNoResponse(t)
+	})
+	t.Run("unknown", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+		if err := s.Discovery.Failpoints.Enable("unknown", xds.Failpoint{}); err == nil {
+			t.Fatal("expected unknown failpoint to be rejected")
+		}
+	})
+}
+
 func TestEnvoyRDSUpdatedRouteRequest(t *testing.T) {
 	expectRoutes := func(resp *discovery.DiscoveryResponse, expected ...string) {
 		t.Helper()
-		got := xdstest.MapKeys(xdstest.ExtractRouteConfigurations(xdstest.UnmarshalRouteConfiguration(t, resp.Resources)))
-		if !reflect.DeepEqual(expected, got) {
-			t.Fatalf("expected routes %v got %v", expected, got)
+		matchers := make([]xds.ResourceMatcher, 0, len(expected))
+		for _, name := range expected {
+			matchers = append(matchers, xds.MatchResource(name, nil))
 		}
+		xds.MatchResources(t, resp, matchers...)
 	}
 	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
 	ads := s.ConnectADS().WithType(v3.RouteType)
@@ -1020,3 +1185,65 @@ func TestEdsCache(t *testing.T) {
 	assertEndpoints(ads)
 	t.Logf("endpoints: %+v", ads.GetEndpoints())
 }
+
+// namedGenerator generates a single resource, holding the name of the generator.
+type namedGenerator string
+
+func (g namedGenerator) Generate(_ *model.Proxy, _ *model.PushContext, w *model.WatchedResource,
+	_ *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	return model.Resources{{Name: string(g), Resource: &any.Any{TypeUrl: w.TypeUrl, Value: []byte(g)}}}, model.DefaultXdsLogDetails, nil
+}
+
+func TestRegisterGenerator(t *testing.T) {
+	const customType = "type.googleapis.com/example.CustomConfig"
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+		DiscoveryServerModifier: func(s *xds.DiscoveryServer) {
+			for key, gen := range map[string]model.XdsResourceGenerator{
+				customType:                      namedGenerator("first"),
+				"proprietary/" + customType:     namedGenerator("proprietary"),
+				"proprietary/" + v3.ClusterType: namedGenerator("proprietary-clusters"),
+			} {
+				if err := s.RegisterGenerator(key, gen); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// The last registration wins.
+			if err := s.RegisterGenerator(customType, namedGenerator("second")); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.RegisterGenerator("", namedGenerator("empty")); err == nil {
+				t.Fatal("expected an error for an empty type URL")
+			}
+			if err := s.RegisterGenerator(customType, nil); err == nil {
+				t.Fatal("expected an error for a nil generator")
+			}
+		},
+	})
+
+	cases := []struct {
+		name      string
+		generator string
+		typeURL   string
+		want      string
+	}{
+		{"custom type", "", customType, "second"},
+		{"custom type scoped to a generator", "proprietary", customType, "proprietary"},
+		{"built-in type scoped to a generator", "proprietary", v3.ClusterType, "proprietary-clusters"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ads := s.ConnectADS().WithType(tt.typeURL).WithMetadata(model.NodeMetadata{Generator: tt.generator})
+			res := ads.RequestResponseAck(t, &discovery.DiscoveryRequest{})
+			if len(res.Resources) != 1 || res.Resources[0].TypeUrl != tt.typeURL {
+				t.Fatalf("unexpected resources %v", res.Resources)
+			}
+			if got := string(res.Resources[0].Value); got != tt.want {
+				t.Fatalf("expected the resource of the generator %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if err := s.Discovery.RegisterGenerator(customType, namedGenerator("late")); err == nil {
+		t.Fatal("expected an error registering a generator after the server is started")
+	}
+}
@@ -16,14 +16,19 @@ package xds
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	sds "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
+	"github.com/google/go-cmp/cmp"
 	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
 
 	"istio.io/istio/pilot/pkg/model"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
@@ -150,6 +155,73 @@ func (a *AdsTest) ExpectResponse(t test.Failer) *discovery.DiscoveryResponse {
 	return nil
 }
 
+// ResourceMatcher describes a resource expected in a response. See MatchResource.
+type ResourceMatcher struct {
+	name     string
+	expected proto.Message
+	opts     []cmp.Option
+}
+
+// MatchResource expects a resource with the given name. If expected is set, the resource must equal it. opts
+// customize the comparison, for example protocmp.IgnoreFields to ignore fields which are not relevant to the test.
+func MatchResource(name string, expected proto.Message, opts ...cmp.Option) ResourceMatcher {
+	return ResourceMatcher{name: name, expected: expected, opts: opts}
+}
+
+// ExpectResources waits until a response is received, checks it holds exactly the resources matched by matchers, and
+// returns it.
+func (a *AdsTest) ExpectResources(t test.Failer, matchers ...ResourceMatcher) *discovery.DiscoveryResponse {
+	t.Helper()
+	resp := a.ExpectResponse(t)
+	MatchResources(t, resp, matchers...)
+	return resp
+}
+
+// MatchResources checks a response holds exactly the resources matched by matchers.
+func MatchResources(t test.Failer, resp *discovery.DiscoveryResponse, matchers ...ResourceMatcher) {
+	t.Helper()
+	got := make(map[string]proto.Message, len(resp.Resources))
+	for _, r := range resp.Resources {
+		m, err := r.UnmarshalNew()
+		if err != nil {
+			t.Fatalf("failed to unmarshal %v: %v", r.TypeUrl, err)
+		}
+		got[resourceName(m)] = m
+	}
+	names := make([]string, 0, len(got))
+	for name := range got {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(got) != len(matchers) {
+		t.Fatalf("expected %d resources, got %v", len(matchers), names)
+	}
+	for _, m := range matchers {
+		res, f := got[m.name]
+		if !f {
+			t.Fatalf("expected resource %v, got %v", m.name, names)
+		}
+		if m.expected == nil {
+			continue
+		}
+		opts := append([]cmp.Option{protocmp.Transform()}, m.opts...)
+		if diff := cmp.Diff(m.expected, res, opts...); diff != "" {
+			t.Fatalf("unexpected resource %v (-want +got):\n%v", m.name, diff)
+		}
+	}
+}
+
+// resourceName returns the name of an xDS resource. Load assignments are named by their cluster.
+func resourceName(m proto.Message) string {
+	msg := m.ProtoReflect()
+	for _, field := range []protoreflect.Name{"name", "cluster_name"} {
+		if fd := msg.Descriptor().Fields().ByName(field); fd != nil && fd.Kind() == protoreflect.StringKind {
+			return msg.Get(fd).String()
+		}
+	}
+	return ""
+}
+
 // ExpectError waits until an error is received and returns it
 func (a *AdsTest) ExpectError(t test.Failer) error {
 	t.Helper()
@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"hash/fnv"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/host"
+)
+
+const (
+	// affinityByNode assigns the proxies of a node to the same istiod replica.
+	affinityByNode = "node"
+	// affinityByNamespace assigns the proxies of a namespace to the same istiod replica.
+	affinityByNamespace = "namespace"
+)
+
+// connectionAffinity assigns the proxies sharing a scope, a node or a namespace, to the same istiod replica, by
+// rendezvous hashing of the scope over the replicas. The proxies of a scope share most of their config, so
+// serving them from a single replica improves the hit rate of its XDS cache. When a replica is added or removed,
+// only the scopes assigned to it move.
+type connectionAffinity struct {
+	scope string
+	// service is the istiod service, whose endpoints are the replicas.
+	service host.Name
+	// port is the XDS port of the istiod service.
+	port int
+	// local are the addresses of this replica.
+	local map[string]bool
+}
+
+// InitConnectionAffinity enables the connection affinity configured by PILOT_CONNECTION_AFFINITY, with the
+// replicas being the endpoints of the istiod service on the XDS port.
+func (s *DiscoveryServer) InitConnectionAffinity(scope string, service host.Name, port int) {
+	if scope != affinityByNode && scope != affinityByNamespace {
+		if scope != "" {
+			log.Warnf("ignoring unknown connection affinity %q", scope)
+		}
+		return
+	}
+	s.affinity = &connectionAffinity{scope: scope, service: service, port: port, local: localAddresses()}
+}
+
+// localAddresses returns the IP addresses of the network interfaces.
+func localAddresses() map[string]bool {
+	out := map[string]bool{}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Warnf("failed to list the local addresses: %v", err)
+		return out
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			out[ipNet.IP.String()] = true
+		}
+	}
+	return out
+}
+
+// key returns the scope of the proxy, or an empty string if it has none.
+func (a *connectionAffinity) key(proxy *model.Proxy) string {
+	if a.scope == affinityByNode && proxy.Metadata.NodeName != "" {
+		return "node/" + proxy.Metadata.NodeName
+	}
+	// Proxies without node name fall back to the affinity by namespace.
+	if proxy.ConfigNamespace != "" {
+		return "namespace/" + proxy.ConfigNamespace
+	}
+	return ""
+}
+
+// replicas returns the sorted addresses of the istiod replicas, and whether this replica is one of them.
+func (a *connectionAffinity) replicas(push *model.PushContext) ([]string, bool) {
+	var out []string
+	isReplica := false
+	for _, svc := range push.ServiceIndex.HostnameAndNamespace[a.service] {
+		for _, instance := range push.ServiceInstancesByPort(svc, a.port, nil) {
+			ep := instance.Endpoint
+			out = append(out, net.JoinHostPort(ep.Address, strconv.Itoa(int(ep.EndpointPort))))
+			if a.local[ep.Address] {
+				isReplica = true
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, isReplica
+}
+
+// owner returns the address of the replica serving the scope: the one with the highest hash of scope and address.
+func owner(key string, replicas []string) string {
+	var best string
+	var bestScore uint64
+	for _, r := range replicas {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(r))
+		if score := h.Sum64(); best == "" || score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best
+}
+
+// isLocal returns true if the address is one of this replica.
+func (a *connectionAffinity) isLocal(address string) bool {
+	ip, _, err := net.SplitHostPort(address)
+	return err == nil && a.local[ip]
+}
+
+// checkAffinity returns an error rejecting the connection if the proxy is assigned to another replica, with the
+// address of this replica in the trailer. Only the clients able to follow the hint, which set the affinity header,
+// are rejected, and only if this replica is one of the endpoints of the istiod service: otherwise its view of the
+// replicas is not the one of the others.
+func (s *DiscoveryServer) checkAffinity(con *Connection) error {
+	if s.affinity == nil {
+		return nil
+	}
+	stream := con.grpcStream()
+	if !acceptsAffinityHint(stream) {
+		return nil
+	}
+	key := s.affinity.key(con.proxy)
+	if key == "" {
+		return nil
+	}
+	replicas, isReplica := s.affinity.replicas(s.globalPushContext())
+	if !isReplica {
+		return nil
+	}
+	target := owner(key, replicas)
+	if target == "" || s.affinity.isLocal(target) {
+		return nil
+	}
+	xdsAffinityRedirects.Increment()
+	stream.SetTrailer(metadata.Pairs(v3.AffinityTrailer, target))
+	return status.Errorf(codes.Unavailable, "the proxies of %s are served by %s", key, target)
+}
+
+// acceptsAffinityHint returns true if the client of the stream follows the affinity hints.
+func acceptsAffinityHint(stream grpc.ServerStream) bool {
+	md, ok := metadata.FromIncomingContext(stream.Context())
+	if !ok {
+		return false
+	}
+	values := md.Get(v3.AffinityHeader)
+	return len(values) > 0 && strings.EqualFold(values[0], "true")
+}
+
+// grpcStream returns the stream of the connection, SotW or delta.
+func (conn *Connection) grpcStream() grpc.ServerStream {
+	if conn.deltaStream != nil {
+		return conn.deltaStream
+	}
+	return conn.stream
+}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestAffinityOwner(t *testing.T) {
+	replicas := []string{"10.0.0.1:15012", "10.0.0.2:15012", "10.0.0.3:15012"}
+	if got := owner("namespace/default", nil); got != "" {
+		t.Fatalf("expected no owner without replicas, got %q", got)
+	}
+
+	assigned := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("node/node-%d", i)
+		assigned[key] = owner(key, replicas)
+		counts[assigned[key]]++
+	}
+	for _, r := range replicas {
+		if counts[r] < 50 {
+			t.Fatalf("expected the scopes to be spread over the replicas, got %v", counts)
+		}
+	}
+
+	// Removing a replica only moves the scopes it served.
+	for key, was := range assigned {
+		now := owner(key, replicas[:2])
+		if was != replicas[2] && now != was {
+			t.Fatalf("%s moved from %s to %s", key, was, now)
+		}
+	}
+}
+
+func TestAffinityKey(t *testing.T) {
+	proxy := func(node, namespace string) *model.Proxy {
+		return &model.Proxy{ConfigNamespace: namespace, Metadata: &model.NodeMetadata{NodeName: node}}
+	}
+	cases := []struct {
+		scope    string
+		proxy    *model.Proxy
+		expected string
+	}{
+		{affinityByNode, proxy("node-1", "default"), "node/node-1"},
+		{affinityByNode, proxy("", "default"), "namespace/default"},
+		{affinityByNamespace, proxy("node-1", "default"), "namespace/default"},
+		{affinityByNamespace, proxy("node-1", ""), ""},
+	}
+	for _, tt := range cases {
+		a := &connectionAffinity{scope: tt.scope}
+		if got := a.key(tt.proxy); got != tt.expected {
+			t.Errorf("%s affinity of %+v: got %q, expected %q", tt.scope, tt.proxy.Metadata, got, tt.expected)
+		}
+	}
+}
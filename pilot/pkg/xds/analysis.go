@@ -0,0 +1,62 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sync"
+	"time"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+)
+
+// analysisResults holds the messages of the last analysis of the configs by istiod. Only the replica elected
+// leader of the analysis controller analyzes the configs: the others never record any.
+type analysisResults struct {
+	mu         sync.RWMutex
+	analyzedAt time.Time
+	messages   diag.Messages
+}
+
+// AnalysisReport is the result of the last analysis of the configs, served at /debug/analyzez.
+type AnalysisReport struct {
+	AnalyzedAt time.Time     `json:"analyzedAt"`
+	Messages   diag.Messages `json:"messages"`
+}
+
+// RecordAnalysis records the messages of an analysis of the configs, to serve them at /debug/analyzez.
+func (s *DiscoveryServer) RecordAnalysis(messages diag.Messages) {
+	s.analysis.mu.Lock()
+	defer s.analysis.mu.Unlock()
+	s.analysis.analyzedAt = time.Now()
+	s.analysis.messages = messages
+}
+
+// report returns the messages of the last analysis about the resources of the namespace, if set, at or above the
+// level. It returns false if the configs were not analyzed yet.
+func (r *analysisResults) report(namespace string, level diag.Level) (AnalysisReport, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.analyzedAt.IsZero() {
+		return AnalysisReport{}, false
+	}
+	out := AnalysisReport{AnalyzedAt: r.analyzedAt, Messages: diag.Messages{}}
+	for _, m := range r.messages.FilterOutLowerThan(level) {
+		if namespace != "" && (m.Resource == nil || m.Resource.Origin.Namespace().String() != namespace) {
+			continue
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	return out, true
+}
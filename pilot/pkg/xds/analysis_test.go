@@ -0,0 +1,70 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pkg/config/resource"
+)
+
+func TestAnalysisResults(t *testing.T) {
+	r := &analysisResults{}
+	if _, f := r.report("", diag.Info); f {
+		t.Fatalf("expected no report before the first analysis")
+	}
+
+	message := func(level diag.Level, code, namespace, name string) diag.Message {
+		res := &resource.Instance{Origin: &rt.Origin{FullName: resource.NewFullName(resource.Namespace(namespace), resource.LocalName(name))}}
+		return diag.NewMessage(diag.NewMessageType(level, code, "test"), res)
+	}
+	s := &DiscoveryServer{analysis: r}
+	s.RecordAnalysis(diag.Messages{
+		message(diag.Error, "IST0001", "a", "vs"),
+		message(diag.Warning, "IST0002", "a", "dr"),
+		message(diag.Info, "IST0003", "b", "gw"),
+		diag.NewMessage(diag.NewMessageType(diag.Warning, "IST0004", "test"), nil),
+	})
+
+	codes := func(namespace string, level diag.Level) []string {
+		report, f := r.report(namespace, level)
+		if !f {
+			t.Fatalf("expected a report after an analysis")
+		}
+		out := []string{}
+		for _, m := range report.Messages {
+			out = append(out, m.Type.Code())
+		}
+		return out
+	}
+	cases := []struct {
+		namespace string
+		level     diag.Level
+		expected  []string
+	}{
+		{level: diag.Info, expected: []string{"IST0001", "IST0002", "IST0003", "IST0004"}},
+		{level: diag.Warning, expected: []string{"IST0001", "IST0002", "IST0004"}},
+		{namespace: "a", level: diag.Info, expected: []string{"IST0001", "IST0002"}},
+		{namespace: "b", level: diag.Error, expected: []string{}},
+	}
+	for _, tt := range cases {
+		if got := codes(tt.namespace, tt.level); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("namespace %q, level %v: got %v, expected %v", tt.namespace, tt.level, got, tt.expected)
+		}
+	}
+}
@@ -21,15 +21,20 @@ import (
 
 	bootstrapv3 "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	gogoproto "github.com/gogo/protobuf/proto"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
+	"github.com/pmezard/go-difflib/difflib"
 
+	"istio.io/api/annotation"
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/envoyfilter"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/util/runtime"
 	"istio.io/istio/pkg/bootstrap"
+	"istio.io/istio/pkg/config/mesh"
 )
 
 // Bootstrap generator produces an Envoy bootstrap from node descriptors.
@@ -44,26 +49,79 @@ func (e *BootstrapGenerator) Generate(proxy *model.Proxy, push *model.PushContex
 	_ *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
 	// The model.Proxy information is incomplete, re-parse the discovery request.
 	node := bootstrap.ConvertXDSNodeToNode(proxy.XdsNode)
+	bs, err := renderBootstrap(node)
+	if err != nil {
+		return nil, model.DefaultXdsLogDetails, err
+	}
+	bs = e.applyPatches(bs, proxy, push)
+	return model.Resources{
+		&discovery.Resource{
+			Resource: util.MessageToAny(bs),
+		},
+	}, model.DefaultXdsLogDetails, nil
+}
 
+// renderBootstrap renders the bootstrap template of the node.
+func renderBootstrap(node *model.Node) (*bootstrapv3.Bootstrap, error) {
 	var buf bytes.Buffer
 	templateFile := bootstrap.GetEffectiveTemplatePath(node.Metadata.ProxyConfig)
 	err := bootstrap.New(bootstrap.Config{
 		Node: node,
 	}).WriteTo(templateFile, io.Writer(&buf))
 	if err != nil {
-		return nil, model.DefaultXdsLogDetails, fmt.Errorf("failed to generate bootstrap config: %v", err)
+		return nil, fmt.Errorf("failed to generate bootstrap config: %v", err)
 	}
 
 	bs := &bootstrapv3.Bootstrap{}
 	if err = jsonpb.Unmarshal(io.Reader(&buf), bs); err != nil {
 		log.Warnf("failed to unmarshal bootstrap from JSON %q: %v", buf.String(), err)
 	}
-	bs = e.applyPatches(bs, proxy, push)
-	return model.Resources{
-		&discovery.Resource{
-			Resource: util.MessageToAny(bs),
-		},
-	}, model.DefaultXdsLogDetails, nil
+	return bs, nil
+}
+
+// currentProxyConfig returns the proxy config the node would get if it was restarted now: the default proxy
+// config of the mesh config, overridden by the proxy.istio.io/config annotation of the workload. Settings chosen
+// by the agent rather than by the mesh config are kept as reported.
+func currentProxyConfig(mc *meshconfig.MeshConfig, node *model.Node) (*model.NodeMetaProxyConfig, error) {
+	reported := node.Metadata.ProxyConfig
+	mc = gogoproto.Clone(mc).(*meshconfig.MeshConfig)
+	if mc.DefaultConfig == nil {
+		def := mesh.DefaultProxyConfig()
+		mc.DefaultConfig = &def
+	}
+	if anno := node.Metadata.Annotations[annotation.ProxyConfig.Name]; anno != "" {
+		var err error
+		if mc, err = mesh.ApplyProxyConfig(anno, *mc); err != nil {
+			return nil, err
+		}
+	}
+	pc := mc.DefaultConfig
+	pc.ServiceCluster = reported.ServiceCluster
+	if pc.Concurrency == nil {
+		pc.Concurrency = reported.Concurrency
+	}
+	return (*model.NodeMetaProxyConfig)(pc), nil
+}
+
+// diffBootstraps returns a unified diff between the JSON forms of two bootstraps, or an empty string if they
+// are identical.
+func diffBootstraps(fromName string, from *bootstrapv3.Bootstrap, toName string, to *bootstrapv3.Bootstrap) (string, error) {
+	m := &jsonpb.Marshaler{Indent: "  "}
+	a, err := m.MarshalToString(from)
+	if err != nil {
+		return "", err
+	}
+	b, err := m.MarshalToString(to)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		FromFile: fromName,
+		A:        difflib.SplitLines(a),
+		ToFile:   toName,
+		B:        difflib.SplitLines(b),
+		Context:  3,
+	})
 }
 
 func (e *BootstrapGenerator) applyPatches(bs *bootstrapv3.Bootstrap, proxy *model.Proxy, push *model.PushContext) *bootstrapv3.Bootstrap {
@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"strings"
+	"testing"
+
+	bootstrapv3 "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	"github.com/gogo/protobuf/types"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestCurrentProxyConfig(t *testing.T) {
+	mc := mesh.DefaultMeshConfig()
+	mc.DefaultConfig.DrainDuration = types.DurationProto(10e9)
+	node := &model.Node{
+		Metadata: &model.BootstrapNodeMetadata{
+			NodeMetadata: model.NodeMetadata{
+				ProxyConfig: &model.NodeMetaProxyConfig{
+					ServiceCluster: "app.ns",
+					Concurrency:    &types.Int32Value{Value: 4},
+				},
+				Annotations: map[string]string{
+					annotation.ProxyConfig.Name: "terminationDrainDuration: 20s",
+				},
+			},
+		},
+	}
+	mc.DefaultConfig.Concurrency = nil
+	pc, err := currentProxyConfig(&mc, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.DrainDuration.Seconds != 10 {
+		t.Errorf("expected drain duration of the mesh config, got %v", pc.DrainDuration)
+	}
+	if pc.TerminationDrainDuration.Seconds != 20 {
+		t.Errorf("expected termination drain duration of the annotation, got %v", pc.TerminationDrainDuration)
+	}
+	if pc.ServiceCluster != "app.ns" || pc.Concurrency.GetValue() != 4 {
+		t.Errorf("expected settings of the agent to be kept, got %v", pc)
+	}
+	if mc.DefaultConfig.TerminationDrainDuration.GetSeconds() == 20 {
+		t.Errorf("mesh config was modified")
+	}
+
+	if _, err := currentProxyConfig(&mc, &model.Node{Metadata: &model.BootstrapNodeMetadata{
+		NodeMetadata: model.NodeMetadata{
+			ProxyConfig: &model.NodeMetaProxyConfig{},
+			Annotations: map[string]string{annotation.ProxyConfig.Name: "drainDuration: [}"},
+		},
+	}}); err == nil {
+		t.Errorf("expected invalid annotation to be rejected")
+	}
+}
+
+func TestDiffBootstraps(t *testing.T) {
+	a := &bootstrapv3.Bootstrap{}
+	diff, err := diffBootstraps("reported", a, "current", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Fatalf("expected no diff, got %s", diff)
+	}
+	b := &bootstrapv3.Bootstrap{EnableDispatcherStats: true}
+	diff, err = diffBootstraps("reported", a, "current", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, `+  "enableDispatcherStats": true`) {
+		t.Fatalf("unexpected diff: %s", diff)
+	}
+}
@@ -14,10 +14,13 @@
 package xds_test
 
 import (
+	"strings"
 	"testing"
 
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
 )
 
 func TestCDS(t *testing.T) {
@@ -25,3 +28,96 @@ func TestCDS(t *testing.T) {
 	ads := s.ConnectADS().WithType(v3.ClusterType)
 	ads.RequestResponseAck(t, nil)
 }
+
+func TestCDSCache(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hosts:
+  - foo.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: bar
+  namespace: default
+spec:
+  hosts:
+  - bar.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: foo
+  namespace: default
+spec:
+  host: foo.com
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+`})
+	generate := func() {
+		t.Helper()
+		proxy := s.SetupProxy(nil)
+		if _, _, err := s.Discovery.Generators[v3.ClusterType].Generate(proxy, s.PushContext(), nil, &model.PushRequest{Full: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const (
+		foo   = "outbound|80||foo.com"
+		fooV1 = "outbound|80|v1|foo.com"
+		bar   = "outbound|80||bar.com"
+	)
+	cached := func(clusters ...string) int {
+		n := 0
+		for _, k := range s.Discovery.Cache.Keys() {
+			for _, c := range clusters {
+				if strings.HasPrefix(k, "cds://"+c+"~") {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	generate()
+	if got := cached(foo, fooV1, bar); got != 3 {
+		t.Fatalf("expected the clusters to be cached, got %v", s.Discovery.Cache.Keys())
+	}
+	misses := s.Discovery.Cache.Stats()["cds"].Misses
+	if misses == 0 {
+		t.Fatalf("expected cds misses, got %+v", s.Discovery.Cache.Stats())
+	}
+
+	generate()
+	stats := s.Discovery.Cache.Stats()["cds"]
+	if stats.Hits == 0 || stats.Misses != misses {
+		t.Fatalf("expected the clusters to be read from the cache, got %+v", stats)
+	}
+
+	// A change of the DestinationRule of foo.com only invalidates the clusters of foo.com.
+	s.Discovery.Cache.Clear(map[model.ConfigKey]struct{}{
+		{Kind: gvk.DestinationRule, Name: "foo", Namespace: "default"}: {},
+	})
+	if got := cached(foo, fooV1); got != 0 {
+		t.Fatalf("expected the clusters of foo.com to be invalidated, got %v", s.Discovery.Cache.Keys())
+	}
+	if got := cached(bar); got != 1 {
+		t.Fatalf("expected the cluster of bar.com to stay cached, got %v", s.Discovery.Cache.Keys())
+	}
+}
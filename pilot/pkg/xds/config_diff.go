@@ -0,0 +1,228 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// configDiffTypes are the types compared by /debug/config_diff.
+var configDiffTypes = []string{v3.ClusterType, v3.ListenerType, v3.RouteType}
+
+// ackedConfig holds the hash of the resources of a type ACKed by a proxy.
+type ackedConfig struct {
+	acked map[string]string
+	// pending holds the resources sent and not yet ACKed, up to pendingNonce.
+	pending      map[string]string
+	pendingNonce string
+	pendingFull  bool
+}
+
+// ackedConfigTracker tracks the clusters, listeners and routes ACKed by a proxy over SotW, so that they can be
+// compared with the current view of Pilot.
+type ackedConfigTracker struct {
+	mu    sync.Mutex
+	types map[string]*ackedConfig
+}
+
+func newAckedConfigTracker() *ackedConfigTracker {
+	return &ackedConfigTracker{types: map[string]*ackedConfig{}}
+}
+
+func isConfigDiffType(typeURL string) bool {
+	for _, t := range configDiffTypes {
+		if t == typeURL {
+			return true
+		}
+	}
+	return false
+}
+
+// sent records the resources of a response, until the proxy ACKs or rejects it.
+func (t *ackedConfigTracker) sent(typeURL, nonce string, res model.Resources, incremental bool) {
+	if !isConfigDiffType(typeURL) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, f := t.types[typeURL]
+	if !f {
+		c = &ackedConfig{acked: map[string]string{}, pending: map[string]string{}}
+		t.types[typeURL] = c
+	}
+	if !incremental {
+		c.pending = map[string]string{}
+		c.pendingFull = true
+	}
+	for _, r := range res {
+		c.pending[r.Name] = hashBytes(r.Resource.GetValue())
+	}
+	c.pendingNonce = nonce
+}
+
+// ack applies the pending resources, once the proxy ACKed the last response holding them.
+func (t *ackedConfigTracker) ack(typeURL, nonce string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, f := t.types[typeURL]
+	if !f || nonce != c.pendingNonce {
+		return
+	}
+	if c.pendingFull {
+		c.acked = c.pending
+	} else {
+		for name, h := range c.pending {
+			c.acked[name] = h
+		}
+	}
+	c.pending = map[string]string{}
+	c.pendingFull = false
+}
+
+// nack forgets the pending resources: the proxy keeps the ones it ACKed before.
+func (t *ackedConfigTracker) nack(typeURL string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, f := t.types[typeURL]; f {
+		c.pending = map[string]string{}
+		c.pendingFull = false
+	}
+}
+
+// ackedResources returns a copy of the resources of a type ACKed by the proxy.
+func (t *ackedConfigTracker) ackedResources(typeURL string) map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, f := t.types[typeURL]
+	if !f {
+		return map[string]string{}
+	}
+	out := make(map[string]string, len(c.acked))
+	for name, h := range c.acked {
+		out[name] = h
+	}
+	return out
+}
+
+// ConfigDiff compares the resources of a type ACKed by a proxy with the current view of Pilot.
+type ConfigDiff struct {
+	TypeURL      string `json:"typeUrl"`
+	VersionSent  string `json:"versionSent,omitempty"`
+	VersionAcked string `json:"versionAcked,omitempty"`
+	NonceSent    string `json:"nonceSent,omitempty"`
+	NonceAcked   string `json:"nonceAcked,omitempty"`
+	// Pending is set if the proxy did not ACK the last response yet.
+	Pending bool `json:"pending,omitempty"`
+	// Rejected is set if the proxy rejected the last response.
+	Rejected bool `json:"rejected,omitempty"`
+	// Tracked is set if the resources ACKed by the proxy are tracked. Otherwise, the resources are not compared.
+	Tracked bool `json:"tracked"`
+	// Missing are the resources of the view of Pilot the proxy did not ACK.
+	Missing []string `json:"missing,omitempty"`
+	// Stale are the resources the proxy ACKed with a content differing from the view of Pilot.
+	Stale []string `json:"stale,omitempty"`
+	// Removed are the resources the proxy ACKed which are no longer in the view of Pilot.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ConfigDiffResponse is the response of /debug/config_diff.
+type ConfigDiffResponse struct {
+	ProxyID string        `json:"proxyID"`
+	Version string        `json:"version"`
+	Types   []*ConfigDiff `json:"types"`
+	// InSync is set if the proxy ACKed the view of Pilot for all the types.
+	InSync bool `json:"inSync"`
+}
+
+// configDiffz compares the config of the proxy with the current view of Pilot, generated as for config_dump.
+func (s *DiscoveryServer) configDiffz(w http.ResponseWriter, req *http.Request) {
+	con := s.getDebugConnection(w, req)
+	if con == nil {
+		return
+	}
+	writeJSON(w, s.configDiff(con))
+}
+
+func (s *DiscoveryServer) configDiff(con *Connection) *ConfigDiffResponse {
+	push := s.globalPushContext()
+	clusters, _ := s.ConfigGenerator.BuildClusters(con.proxy, push)
+	views := map[string]map[string]string{
+		v3.ClusterType:  resourceHashes(clusters),
+		v3.ListenerType: {},
+		v3.RouteType:    {},
+	}
+	for _, l := range s.ConfigGenerator.BuildListeners(con.proxy, push) {
+		views[v3.ListenerType][l.Name] = hashBytes(util.MessageToAny(l).GetValue())
+	}
+	for _, r := range s.ConfigGenerator.BuildHTTPRoutes(con.proxy, push, con.Routes()) {
+		views[v3.RouteType][r.Name] = hashBytes(util.MessageToAny(r).GetValue())
+	}
+
+	out := &ConfigDiffResponse{ProxyID: con.proxy.ID, Version: push.PushVersion, InSync: true}
+	for _, typeURL := range configDiffTypes {
+		diff := &ConfigDiff{TypeURL: typeURL}
+		con.proxy.RLock()
+		if wr := con.proxy.WatchedResources[typeURL]; wr != nil {
+			diff.VersionSent = wr.VersionSent
+			diff.VersionAcked = wr.VersionAcked
+			diff.NonceSent = wr.NonceSent
+			diff.NonceAcked = wr.NonceAcked
+			diff.Pending = wr.NonceSent != "" && wr.NonceSent != wr.NonceAcked
+			diff.Rejected = wr.NonceNacked != ""
+		}
+		con.proxy.RUnlock()
+		if con.ackedConfig != nil {
+			diff.Tracked = true
+			acked := con.ackedConfig.ackedResources(typeURL)
+			view := views[typeURL]
+			for name, h := range view {
+				ackedHash, f := acked[name]
+				switch {
+				case !f:
+					diff.Missing = append(diff.Missing, name)
+				case ackedHash != h:
+					diff.Stale = append(diff.Stale, name)
+				}
+			}
+			for name := range acked {
+				if _, f := view[name]; !f {
+					diff.Removed = append(diff.Removed, name)
+				}
+			}
+			sort.Strings(diff.Missing)
+			sort.Strings(diff.Stale)
+			sort.Strings(diff.Removed)
+		}
+		if diff.Pending || diff.Rejected || len(diff.Missing)+len(diff.Stale)+len(diff.Removed) > 0 {
+			out.InSync = false
+		}
+		out.Types = append(out.Types, diff)
+	}
+	return out
+}
+
+func resourceHashes(res model.Resources) map[string]string {
+	out := make(map[string]string, len(res))
+	for _, r := range res {
+		out[r.Name] = hashBytes(r.Resource.GetValue())
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestAckedConfigTracker(t *testing.T) {
+	c := func(name string, version int) *discovery.Resource {
+		return &discovery.Resource{Name: name, Resource: util.MessageToAny(&cluster.Cluster{
+			Name:        name,
+			AltStatName: fmt.Sprint(version),
+		})}
+	}
+	acked := func(tr *ackedConfigTracker) map[string]string {
+		return tr.ackedResources(v3.ClusterType)
+	}
+
+	tr := newAckedConfigTracker()
+	tr.sent(v3.ClusterType, "1", model.Resources{c("a", 1), c("b", 1)}, false)
+	if got := acked(tr); len(got) != 0 {
+		t.Fatalf("expected nothing ACKed, got %v", got)
+	}
+	tr.ack(v3.ClusterType, "1")
+	first := acked(tr)
+	if len(first) != 2 {
+		t.Fatalf("expected a and b to be ACKed, got %v", first)
+	}
+
+	// A rejected response keeps the resources ACKed before.
+	tr.sent(v3.ClusterType, "2", model.Resources{c("a", 2)}, false)
+	tr.nack(v3.ClusterType)
+	if got := acked(tr); !reflect.DeepEqual(got, first) {
+		t.Fatalf("got %v, want %v", got, first)
+	}
+
+	// A complete response replaces the resources it does not hold.
+	tr.sent(v3.ClusterType, "3", model.Resources{c("a", 2)}, false)
+	tr.ack(v3.ClusterType, "3")
+	got := acked(tr)
+	if _, f := got["b"]; f || len(got) != 1 || got["a"] == first["a"] {
+		t.Fatalf("expected only the new a to be ACKed, got %v", got)
+	}
+
+	// Only the types compared by config_diff are tracked.
+	tr.sent(v3.EndpointType, "4", model.Resources{c("a", 1)}, false)
+	tr.ack(v3.EndpointType, "4")
+	if got := tr.ackedResources(v3.EndpointType); len(got) != 0 {
+		t.Fatalf("expected endpoints not to be tracked, got %v", got)
+	}
+}
+
+func TestConfigDiff(t *testing.T) {
+	defer func(old bool) { features.EnableConfigDiffTracking = old }(features.EnableConfigDiffTracking)
+	features.EnableConfigDiffTracking = true
+
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	ads.RequestResponseAck(t, nil)
+
+	var diff *ConfigDiff
+	retry.UntilSuccessOrFail(t, func() error {
+		con := s.Discovery.getProxyConnection("test.default")
+		if con == nil {
+			return fmt.Errorf("proxy not connected")
+		}
+		res := s.Discovery.configDiff(con)
+		diff = res.Types[0]
+		if diff.TypeURL != v3.ClusterType {
+			return fmt.Errorf("unexpected type %v", diff.TypeURL)
+		}
+		if diff.Pending || diff.NonceAcked == "" {
+			return fmt.Errorf("clusters not ACKed yet: %+v", diff)
+		}
+		return nil
+	})
+	if !diff.Tracked || len(diff.Missing)+len(diff.Stale)+len(diff.Removed) > 0 {
+		t.Fatalf("expected the ACKed clusters to match the view of Pilot, got %+v", diff)
+	}
+
+	// The listeners were never requested, so the proxy misses them all.
+	res := s.Discovery.configDiff(s.Discovery.getProxyConnection("test.default"))
+	if res.InSync || len(res.Types[1].Missing) == 0 {
+		t.Fatalf("expected the listeners to be missing, got %+v", res.Types[1])
+	}
+}
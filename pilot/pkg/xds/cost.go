@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/pkg/monitoring"
+)
+
+// noConfigKind is the kind the pushes not triggered by a config change, such as the initial push to a proxy,
+// are charged to.
+const noConfigKind = "none"
+
+var (
+	namespaceTag = monitoring.MustCreateLabel("namespace")
+	kindTag      = monitoring.MustCreateLabel("kind")
+	revisionTag  = monitoring.MustCreateLabel("revision")
+
+	pushCostSeconds = monitoring.NewSum(
+		"pilot_xds_push_cost_seconds",
+		"Total time in seconds spent generating the configs pushed to the proxies of a namespace, by kind of the "+
+			"configs triggering the pushes.",
+		monitoring.WithLabels(namespaceTag, kindTag, revisionTag),
+	)
+
+	pushCostBytes = monitoring.NewSum(
+		"pilot_xds_push_cost_bytes",
+		"Total size of the configs pushed to the proxies of a namespace, by kind of the configs triggering the pushes.",
+		monitoring.WithLabels(namespaceTag, kindTag, revisionTag),
+		monitoring.WithUnit(monitoring.Bytes),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(pushCostSeconds, pushCostBytes)
+}
+
+type pushCostKey struct {
+	namespace string
+	kind      string
+}
+
+type pushCost struct {
+	pushes  int
+	seconds float64
+	bytes   float64
+}
+
+// pushCosts accounts the time spent generating the configs pushed to proxies, and their size, by namespace of
+// the proxies and kind of the configs triggering the pushes, so that the cost of the control plane can be
+// charged back to the teams owning them. Generation does not block, so its time approximates the CPU time.
+type pushCosts struct {
+	mu    sync.Mutex
+	since time.Time
+	costs map[pushCostKey]*pushCost
+}
+
+func newPushCosts() *pushCosts {
+	return &pushCosts{since: time.Now(), costs: map[pushCostKey]*pushCost{}}
+}
+
+// record charges a push of a config to a proxy of the namespace, by the control plane of the given revision.
+// The cost of a push triggered by several kinds of configs is split evenly between them, each of them counting
+// the push.
+func (c *pushCosts) record(namespace, revision string, req *model.PushRequest, d time.Duration, bytes int) {
+	kinds := pushKinds(req)
+	share := 1 / float64(len(kinds))
+	seconds, size := d.Seconds()*share, float64(bytes)*share
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, kind := range kinds {
+		key := pushCostKey{namespace: namespace, kind: kind}
+		cost, f := c.costs[key]
+		if !f {
+			cost = &pushCost{}
+			c.costs[key] = cost
+		}
+		cost.pushes++
+		cost.seconds += seconds
+		cost.bytes += size
+		if features.EnablePushCostMetrics {
+			pushCostSeconds.With(namespaceTag.Value(namespace), kindTag.Value(kind), revisionTag.Value(revision)).Record(seconds)
+			pushCostBytes.With(namespaceTag.Value(namespace), kindTag.Value(kind), revisionTag.Value(revision)).Record(size)
+		}
+	}
+}
+
+// revision returns the revision of the control plane, "default" if it is not set.
+func (s *DiscoveryServer) revision() string {
+	if s.Revision == "" {
+		return "default"
+	}
+	return s.Revision
+}
+
+// pushKinds returns the distinct kinds of the configs triggering a push, in order.
+func pushKinds(req *model.PushRequest) []string {
+	if req == nil || len(req.ConfigsUpdated) == 0 {
+		return []string{noConfigKind}
+	}
+	seen := map[string]struct{}{}
+	kinds := make([]string, 0, 1)
+	for key := range req.ConfigsUpdated {
+		if _, f := seen[key.Kind.Kind]; f {
+			continue
+		}
+		seen[key.Kind.Kind] = struct{}{}
+		kinds = append(kinds, key.Kind.Kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// PushCost is the cost of the pushes to the proxies of a namespace triggered by a kind of config. Namespace or
+// Kind is empty if the costs are aggregated over it.
+type PushCost struct {
+	Namespace string  `json:"namespace,omitempty"`
+	Kind      string  `json:"kind,omitempty"`
+	Pushes    int     `json:"pushes"`
+	Seconds   float64 `json:"seconds"`
+	Bytes     float64 `json:"bytes"`
+}
+
+// PushCostReport is the cost of the pushes of the control plane since it started, from the most expensive.
+type PushCostReport struct {
+	Revision     string     `json:"revision"`
+	Since        time.Time  `json:"since"`
+	TotalSeconds float64    `json:"total_seconds"`
+	TotalBytes   float64    `json:"total_bytes"`
+	Costs        []PushCost `json:"costs"`
+}
+
+// report returns the costs of the pushes to the proxies of the namespace, or of all of them if empty. If by is
+// "namespace" or "kind", the costs are aggregated by it.
+func (c *pushCosts) report(namespace, by string) PushCostReport {
+	out := PushCostReport{Since: c.since, Costs: []PushCost{}}
+	byKey := map[pushCostKey]int{}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, cost := range c.costs {
+		if namespace != "" && key.namespace != namespace {
+			continue
+		}
+		switch by {
+		case "namespace":
+			key.kind = ""
+		case "kind":
+			key.namespace = ""
+		}
+		i, f := byKey[key]
+		if !f {
+			i = len(out.Costs)
+			byKey[key] = i
+			out.Costs = append(out.Costs, PushCost{Namespace: key.namespace, Kind: key.kind})
+		}
+		out.Costs[i].Pushes += cost.pushes
+		out.Costs[i].Seconds += cost.seconds
+		out.Costs[i].Bytes += cost.bytes
+		out.TotalSeconds += cost.seconds
+		out.TotalBytes += cost.bytes
+	}
+	sort.Slice(out.Costs, func(i, j int) bool {
+		a, b := out.Costs[i], out.Costs[j]
+		if a.Seconds != b.Seconds {
+			return a.Seconds > b.Seconds
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Kind < b.Kind
+	})
+	return out
+}
@@ -0,0 +1,67 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestPushCosts(t *testing.T) {
+	c := newPushCosts()
+	vs := &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{
+		{Kind: gvk.VirtualService, Name: "a", Namespace: "ns"}: {},
+		{Kind: gvk.VirtualService, Name: "b", Namespace: "ns"}: {},
+	}}
+	mixed := &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{
+		{Kind: gvk.VirtualService, Name: "a", Namespace: "ns"}:  {},
+		{Kind: gvk.DestinationRule, Name: "a", Namespace: "ns"}: {},
+	}}
+	c.record("a", "default", vs, 4*time.Second, 400)
+	c.record("a", "default", mixed, 2*time.Second, 200)
+	c.record("b", "default", nil, time.Second, 100)
+
+	report := c.report("", "")
+	if report.TotalSeconds != 7 || report.TotalBytes != 700 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	expected := []PushCost{
+		{Namespace: "a", Kind: gvk.VirtualService.Kind, Pushes: 2, Seconds: 5, Bytes: 500},
+		{Namespace: "a", Kind: gvk.DestinationRule.Kind, Pushes: 1, Seconds: 1, Bytes: 100},
+		{Namespace: "b", Kind: noConfigKind, Pushes: 1, Seconds: 1, Bytes: 100},
+	}
+	if !reflect.DeepEqual(report.Costs, expected) {
+		t.Fatalf("got %+v, expected %+v", report.Costs, expected)
+	}
+
+	expected = []PushCost{
+		{Namespace: "a", Pushes: 3, Seconds: 6, Bytes: 600},
+		{Namespace: "b", Pushes: 1, Seconds: 1, Bytes: 100},
+	}
+	if got := c.report("", "namespace").Costs; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("by namespace: got %+v, expected %+v", got, expected)
+	}
+	expected = []PushCost{
+		{Kind: gvk.VirtualService.Kind, Pushes: 2, Seconds: 5, Bytes: 500},
+		{Kind: gvk.DestinationRule.Kind, Pushes: 1, Seconds: 1, Bytes: 100},
+	}
+	if got := c.report("a", "kind").Costs; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("by kind in namespace a: got %+v, expected %+v", got, expected)
+	}
+}
@@ -19,33 +19,50 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/pprof"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
-	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/any"
 	"google.golang.org/protobuf/types/known/anypb"
+	corev1 "k8s.io/api/core/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
 
+	networkingapi "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
 	"istio.io/istio/pilot/pkg/networking/util"
+	authnmodel "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/bootstrap"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/interception"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/dependency"
+	"istio.io/istio/pkg/fips"
+	"istio.io/istio/pkg/kube/inject"
 	istiolog "istio.io/pkg/log"
 )
 
@@ -96,7 +113,11 @@ type AdsClient struct {
 	ConnectionID string              `json:"connectionId"`
 	ConnectedAt  time.Time           `json:"connectedAt"`
 	PeerAddress  string              `json:"address"`
+	InstanceID   string              `json:"instanceId,omitempty"`
+	Reconnects   int                 `json:"reconnects,omitempty"`
 	Watches      map[string][]string `json:"watches,omitempty"`
+	// Pushes are the most recent pushes sent to the client, oldest first, with their reasons.
+	Pushes []PushRecord `json:"pushes,omitempty"`
 }
 
 // AdsClients is collection of AdsClient connected to this Istiod.
@@ -169,6 +190,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 
 	if features.EnableUnsafeAdminEndpoints {
 		s.addDebugHandler(mux, internalMux, "/debug/force_disconnect", "Disconnects a proxy from this Pilot", s.ForceDisconnect)
+		s.addDebugHandler(mux, internalMux, "/debug/failpoints", "Lists, enables (POST) and disables (DELETE) xDS failpoints", s.failpointsz)
+		s.addDebugHandler(mux, internalMux, "/debug/readonly", "Read-only mode of istiod, set with a POST of enabled=true|false", s.readOnlyz)
 	}
 
 	s.addDebugHandler(mux, internalMux, "/debug/edsz", "Status and debug interface for EDS", s.Edsz)
@@ -176,6 +199,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/adsz", "Status and debug interface for ADS", s.adsz)
 	s.addDebugHandler(mux, internalMux, "/debug/adsz?push=true", "Initiates push of the current state to all connected endpoints", s.adsz)
 
+	s.addDebugHandler(mux, internalMux, "/debug/events", "Stream of the push, connection and NACK events, as Server-Sent Events", s.eventsz)
+
 	s.addDebugHandler(mux, internalMux, "/debug/syncz", "Synchronization status of all Envoys connected to this Pilot instance", s.Syncz)
 	s.addDebugHandler(mux, internalMux, "/debug/config_distribution", "Version status of all Envoys connected to this Pilot instance", s.distributedVersions)
 
@@ -184,30 +209,55 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/endpointShardz", "Info about the endpoint shards", s.endpointShardz)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez", "Info about the internal XDS caches", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez?sizes=true", "Info about the size of the internal XDS caches", s.cachez)
+	s.addDebugHandler(mux, internalMux, "/debug/cachez?stats=true", "Hits and misses of the internal XDS caches by type", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/configz", "Debug support for config", s.configz)
 	s.addDebugHandler(mux, internalMux, "/debug/sidecarz", "Debug sidecar scope for a proxy", s.sidecarz)
+	s.addDebugHandler(mux, internalMux, "/debug/interceptionz", "Inbound ports of a proxy intercepted by iptables, and whether they get listeners", s.interceptionz)
+	s.addDebugHandler(mux, internalMux, "/debug/automtlsz", "Why auto mTLS chose mTLS or plaintext for the clusters of a proxy to a host", s.automtlsz)
+	s.addDebugHandler(mux, internalMux, "/debug/sidecar_preview", "Sidecar scope a workload with the given labels would get", s.sidecarPreview)
+	s.addDebugHandler(mux, internalMux, "/debug/push_scopez", "Sidecar scopes and proxies a change to a config is pushed to", s.pushScopez)
+	s.addDebugHandler(mux, internalMux, "/debug/bootstrapz", "Bootstrap of a proxy at the current mesh config, and its diff to the running one", s.Bootstrapz)
 	s.addDebugHandler(mux, internalMux, "/debug/resourcesz", "Debug support for watched resources", s.resourcez)
 	s.addDebugHandler(mux, internalMux, "/debug/instancesz", "Debug support for service instances", s.instancesz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/authorizationz", "Internal authorization policies", s.Authorizationz)
 	s.addDebugHandler(mux, internalMux, "/debug/telemetryz", "Debug Telemetry configuration", s.telemetryz)
 	s.addDebugHandler(mux, internalMux, "/debug/config_dump", "ConfigDump in the form of the Envoy admin config dump API for passed in proxyID", s.ConfigDump)
+	s.addDebugHandler(mux, internalMux, "/debug/config_diff",
+		"Diff of the clusters, listeners and routes ACKed by the passed in proxyID with the current view of Pilot", s.configDiffz)
 	s.addDebugHandler(mux, internalMux, "/debug/push_status", "Last PushContext Details", s.PushStatusHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/pushcontext", "Debug support for current push context", s.PushContextHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/connections", "Info about the connected XDS clients", s.ConnectionsHandler)
-
-	s.addDebugHandler(mux, internalMux, "/debug/inject", "Active inject template", s.InjectTemplateHandler(webhook))
+	s.addDebugHandler(mux, internalMux, "/debug/sizez", "Largest configs sent to connected proxies, and their growth", s.Sizez)
+	s.addDebugHandler(mux, internalMux, "/debug/costz", "Time spent generating and bytes pushed, by namespace and config kind", s.Costz)
+	s.addDebugHandler(mux, internalMux, "/debug/push_latencyz", "Time spent generating and sending, and bytes pushed, by type", s.PushLatencyz)
+	s.addDebugHandler(mux, internalMux, "/debug/listener_conflicts", "Services claiming the same outbound listener, and the ones winning", s.listenerConflictsz)
+	s.addDebugHandler(mux, internalMux, "/debug/ineffective_policies", "Policies with HTTP rules selecting workloads which serve no HTTP traffic", s.ineffectivePoliciesz)
+	s.addDebugHandler(mux, internalMux, "/debug/dependenciesz", "Health of the external dependencies contacted by istiod", s.Dependenciesz)
+	s.addDebugHandler(mux, internalMux, "/debug/analyzez", "Messages of the last analysis of the configs by this istiod", s.Analyzez)
+
+	s.addDebugHandler(mux, internalMux, "/debug/inject", "Active inject templates, or the injection of a posted pod", s.InjectTemplateHandler(webhook))
 	s.addDebugHandler(mux, internalMux, "/debug/mesh", "Active mesh config", s.MeshHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
 	s.addDebugHandler(mux, internalMux, "/debug/exportz", "List endpoints that been exported via MCS", s.exportz)
+	s.addDebugHandler(mux, internalMux, "/debug/route_aliases", "Naming schemes of route configurations, and the former names they replace", s.routeAliasesz)
+	s.addDebugHandler(mux, internalMux, "/debug/fipsz", "FIPS mode, the TLS parameters enforced and the non-compliant settings refused", s.fipsz)
+	s.addDebugHandler(mux, internalMux, "/debug/state_archive", "Archive of the control plane state and a sample of proxy config dumps, for offline analysis", s.StateArchiveHandler)
 
 	s.addDebugHandler(mux, internalMux, "/debug/list", "List all supported debug commands in json", s.List)
+
+	// Handlers added by extensions may be registered at any time, so they are dispatched dynamically.
+	if internalMux != nil {
+		internalMux.HandleFunc(DebugExtensionPrefix, s.serveDebugExtension)
+	}
+	mux.HandleFunc(DebugExtensionPrefix, s.allowAuthenticatedOrLocalhost(http.HandlerFunc(s.serveDebugExtension)))
 }
 
 func (s *DiscoveryServer) addDebugHandler(mux *http.ServeMux, internalMux *http.ServeMux,
 	path string, help string, handler func(http.ResponseWriter, *http.Request)) {
 	s.debugHandlers[path] = help
+	handler = s.advertiseReadOnly(handler)
 	// Add handler without auth. This mux is never exposed on an HTTP server and only used internally
 	if internalMux != nil {
 		internalMux.HandleFunc(path, handler)
@@ -241,9 +291,9 @@ func (s *DiscoveryServer) allowAuthenticatedOrLocalhost(next http.Handler) http.
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-		// TODO: Check that the identity contains istio-system namespace, else block or restrict to only info that
-		// is visible to the authenticated SA. Will require changes in docs and istioctl too.
-		next.ServeHTTP(w, req)
+		// The identities are kept in the request, for the handlers restricting the information to the namespaces
+		// visible to the authenticated SA. See DebugScopeFor.
+		next.ServeHTTP(w, req.WithContext(withDebugIdentities(req.Context(), ids)))
 	}
 }
 
@@ -257,12 +307,14 @@ func isRequestFromLocalhost(r *http.Request) bool {
 	return userIP.IsLoopback()
 }
 
-// Syncz dumps the synchronization status of all Envoys connected to this Pilot instance
-func (s *DiscoveryServer) Syncz(w http.ResponseWriter, _ *http.Request) {
+// Syncz dumps the synchronization status of all Envoys connected to this Pilot instance, in the namespaces
+// readable by the caller.
+func (s *DiscoveryServer) Syncz(w http.ResponseWriter, req *http.Request) {
+	scope := s.DebugScopeFor(req)
 	syncz := make([]SyncStatus, 0)
 	for _, con := range s.Clients() {
 		node := con.proxy
-		if node != nil {
+		if node != nil && scope.Allows(node.ConfigNamespace) {
 			syncz = append(syncz, SyncStatus{
 				ProxyID:       node.ID,
 				IstioVersion:  node.Metadata.IstioVersion,
@@ -287,6 +339,17 @@ func (s *DiscoveryServer) registryz(w http.ResponseWriter, req *http.Request) {
 	if err != nil {
 		return
 	}
+	scope := s.DebugScopeFor(req)
+	if !scope.all {
+		push := s.globalPushContext()
+		visible := make([]*model.Service, 0, len(all))
+		for _, svc := range all {
+			if scope.allowsService(push, svc) {
+				visible = append(visible, svc)
+			}
+		}
+		all = visible
+	}
 	writeJSON(w, all)
 }
 
@@ -294,14 +357,34 @@ func (s *DiscoveryServer) registryz(w http.ResponseWriter, req *http.Request) {
 // Legacy registry provides are synced to the new data structure as well, during
 // the full push.
 func (s *DiscoveryServer) endpointShardz(w http.ResponseWriter, req *http.Request) {
+	scope := s.DebugScopeFor(req)
 	w.Header().Add("Content-Type", "application/json")
 	s.mutex.RLock()
-	out, _ := json.MarshalIndent(s.EndpointShardsByService, " ", " ")
+	shards := s.EndpointShardsByService
+	if !scope.all {
+		shards = map[string]map[string]*EndpointShards{}
+		for hostname, byNamespace := range s.EndpointShardsByService {
+			for ns, eps := range byNamespace {
+				if !scope.Allows(ns) {
+					continue
+				}
+				if shards[hostname] == nil {
+					shards[hostname] = map[string]*EndpointShards{}
+				}
+				shards[hostname][ns] = eps
+			}
+		}
+	}
+	out, _ := json.MarshalIndent(shards, " ", " ")
 	s.mutex.RUnlock()
 	_, _ = w.Write(out)
 }
 
+// cachez dumps the keys of the XDS cache, which are shared by the proxies of all the namespaces.
 func (s *DiscoveryServer) cachez(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	if err := req.ParseForm(); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte("Failed to parse request\n"))
@@ -323,6 +406,10 @@ func (s *DiscoveryServer) cachez(w http.ResponseWriter, req *http.Request) {
 		writeJSON(w, res)
 		return
 	}
+	if req.Form.Get("stats") != "" {
+		writeJSON(w, s.Cache.Stats())
+		return
+	}
 	keys := s.Cache.Keys()
 	sort.Strings(keys)
 	writeJSON(w, keys)
@@ -335,9 +422,14 @@ type endpointzResponse struct {
 
 // Endpoint debugging
 func (s *DiscoveryServer) endpointz(w http.ResponseWriter, req *http.Request) {
+	scope := s.DebugScopeFor(req)
+	push := s.globalPushContext()
 	if _, f := req.URL.Query()["brief"]; f {
 		svc, _ := s.Env.ServiceDiscovery.Services()
 		for _, ss := range svc {
+			if !scope.allowsService(push, ss) {
+				continue
+			}
 			for _, p := range ss.Ports {
 				all := s.Env.ServiceDiscovery.InstancesByPort(ss, p.Port, nil)
 				for _, svc := range all {
@@ -353,6 +445,9 @@ func (s *DiscoveryServer) endpointz(w http.ResponseWriter, req *http.Request) {
 	svc, _ := s.Env.ServiceDiscovery.Services()
 	resp := make([]endpointzResponse, 0)
 	for _, ss := range svc {
+		if !scope.allowsService(push, ss) {
+			continue
+		}
 		for _, p := range ss.Ports {
 			all := s.Env.ServiceDiscovery.InstancesByPort(ss, p.Port, nil)
 			resp = append(resp, endpointzResponse{
@@ -371,7 +466,13 @@ func (s *DiscoveryServer) distributedVersions(w http.ResponseWriter, req *http.R
 			"PILOT_ENABLE_CONFIG_DISTRIBUTION_TRACKING environment variable to true to enable.")
 		return
 	}
+	scope := s.DebugScopeFor(req)
 	if resourceID := req.URL.Query().Get("resource"); resourceID != "" {
+		if !scope.Allows(resourceNamespace(resourceID)) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprintf(w, "not authorized to read resource %q\n", resourceID)
+			return
+		}
 		proxyNamespace := req.URL.Query().Get("proxy_namespace")
 		knownVersions := make(map[string]string)
 		var results []SyncedVersions
@@ -379,7 +480,8 @@ func (s *DiscoveryServer) distributedVersions(w http.ResponseWriter, req *http.R
 			// wrap this in independent scope so that panic's don't bypass Unlock...
 			con.proxy.RLock()
 
-			if con.proxy != nil && (proxyNamespace == "" || proxyNamespace == con.proxy.ConfigNamespace) {
+			if con.proxy != nil && scope.Allows(con.proxy.ConfigNamespace) &&
+				(proxyNamespace == "" || proxyNamespace == con.proxy.ConfigNamespace) {
 				// read nonces from our statusreporter to allow for skipped nonces, etc.
 				results = append(results, SyncedVersions{
 					ProxyID: con.proxy.ID,
@@ -396,9 +498,94 @@ func (s *DiscoveryServer) distributedVersions(w http.ResponseWriter, req *http.R
 
 		writeJSON(w, results)
 	} else {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		_, _ = fmt.Fprintf(w, "querystring parameter 'resource' is required\n")
+		s.distributionSummary(w, req, scope)
+	}
+}
+
+// ProxyDistribution is the config distribution state of a single proxy, relative to the latest config.
+type ProxyDistribution struct {
+	ProxyID        string  `json:"proxy"`
+	AckedVersion   string  `json:"acked_version,omitempty"`
+	VersionsBehind int     `json:"versions_behind"`
+	StaleSeconds   float64 `json:"stale_seconds"`
+	Stale          bool    `json:"stale"`
+}
+
+// DistributionSummary summarizes how up to date all proxies connected to this instance are.
+type DistributionSummary struct {
+	LatestVersion string              `json:"latest_version"`
+	TotalProxies  int                 `json:"total_proxies"`
+	StaleProxies  int                 `json:"stale_proxies"`
+	Proxies       []ProxyDistribution `json:"proxies,omitempty"`
+}
+
+// distributionSummary reports, for each connected proxy, whether it is within max_versions versions or max_seconds
+// seconds of the latest config. If neither threshold is set, any proxy behind the latest version is stale. Only the
+// proxies of the namespaces in the scope are reported.
+func (s *DiscoveryServer) distributionSummary(w http.ResponseWriter, req *http.Request, scope DebugScope) {
+	maxVersions, maxSeconds := -1, -1.0
+	if v := req.URL.Query().Get("max_versions"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid max_versions %q\n", v)
+			return
+		}
+		maxVersions = n
+	}
+	if v := req.URL.Query().Get("max_seconds"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid max_seconds %q\n", v)
+			return
+		}
+		maxSeconds = n
+	}
+	if maxVersions < 0 && maxSeconds < 0 {
+		maxVersions = 0
+	}
+	proxyNamespace := req.URL.Query().Get("proxy_namespace")
+	// Only list the stale proxies, unless asked otherwise, to keep the response small for alerting.
+	listAll := req.URL.Query().Get("all") == "true"
+
+	now := time.Now()
+	out := DistributionSummary{LatestVersion: s.ledgerHistory.latest()}
+	for _, con := range s.Clients() {
+		if con.proxy == nil {
+			continue
+		}
+		con.proxy.RLock()
+		proxyID, configNamespace := con.proxy.ID, con.proxy.ConfigNamespace
+		con.proxy.RUnlock()
+		if !scope.Allows(configNamespace) || (proxyNamespace != "" && proxyNamespace != configNamespace) {
+			continue
+		}
+		pd := ProxyDistribution{ProxyID: proxyID}
+		// The proxy is only as up to date as its most out of date type.
+		for _, typeURL := range []string{v3.ClusterType, v3.ListenerType, v3.RouteType} {
+			version := s.StatusReporter.QueryLastNonce(con.ConID, typeURL)
+			if version == "" {
+				continue
+			}
+			behind, staleFor := s.ledgerHistory.staleness(version, now)
+			if behind >= pd.VersionsBehind {
+				pd.AckedVersion = truncateVersion(version)
+				pd.VersionsBehind = behind
+				pd.StaleSeconds = staleFor.Seconds()
+			}
+		}
+		pd.Stale = (maxVersions >= 0 && pd.VersionsBehind > maxVersions) ||
+			(maxSeconds >= 0 && pd.VersionsBehind > 0 && pd.StaleSeconds > maxSeconds)
+		out.TotalProxies++
+		if pd.Stale {
+			out.StaleProxies++
+		}
+		if pd.Stale || listAll {
+			out.Proxies = append(out.Proxies, pd)
+		}
 	}
+	writeJSON(w, out)
 }
 
 // VersionLen is the Config Version and is only used as the nonce prefix, but we can reconstruct
@@ -442,10 +629,14 @@ func (k kubernetesConfig) MarshalJSON() ([]byte, error) {
 
 // Config debugging.
 func (s *DiscoveryServer) configz(w http.ResponseWriter, req *http.Request) {
+	scope := s.DebugScopeFor(req)
 	configs := make([]kubernetesConfig, 0)
 	s.Env.IstioConfigStore.Schemas().ForEach(func(schema collection.Schema) bool {
 		cfg, _ := s.Env.IstioConfigStore.List(schema.Resource().GroupVersionKind(), "")
 		for _, c := range cfg {
+			if !scope.Allows(c.Namespace) {
+				continue
+			}
 			configs = append(configs, kubernetesConfig{c})
 		}
 		return false
@@ -462,8 +653,291 @@ func (s *DiscoveryServer) sidecarz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, con.proxy.SidecarScope)
 }
 
+// Interception describes the traffic of a proxy intercepted and redirected to it.
+type Interception struct {
+	Mode    model.TrafficInterceptionMode `json:"mode"`
+	Inbound *interception.InboundPorts    `json:"inbound"`
+	// Ports lists the ports of the service instances of the proxy, and whether they are intercepted. Inbound
+	// listeners and clusters are generated only for intercepted ports.
+	Ports []InterceptedPort `json:"ports"`
+}
+
+// InterceptedPort is an inbound port of the service instances of a proxy.
+type InterceptedPort struct {
+	Port        int      `json:"port"`
+	Services    []string `json:"services"`
+	Intercepted bool     `json:"intercepted"`
+}
+
+// interceptionz returns the inbound ports intercepted for a proxy, as computed by istiod from the traffic
+// annotations of its workload, the same way the injector configures iptables for them.
+func (s *DiscoveryServer) interceptionz(w http.ResponseWriter, req *http.Request) {
+	con := s.getDebugConnection(w, req)
+	if con == nil {
+		return
+	}
+	con.proxy.RLock()
+	defer con.proxy.RUnlock()
+	out := Interception{
+		Mode:    con.proxy.GetInterceptionMode(),
+		Inbound: con.proxy.InboundInterception(),
+		Ports:   []InterceptedPort{},
+	}
+	byPort := map[int]int{}
+	for _, si := range con.proxy.ServiceInstances {
+		port := int(si.Endpoint.EndpointPort)
+		i, f := byPort[port]
+		if !f {
+			i = len(out.Ports)
+			byPort[port] = i
+			out.Ports = append(out.Ports, InterceptedPort{
+				Port:        port,
+				Intercepted: out.Mode != model.InterceptionNone && out.Inbound.Captures(port),
+			})
+		}
+		out.Ports[i].Services = append(out.Ports[i].Services, string(si.Service.Hostname))
+	}
+	sort.Slice(out.Ports, func(i, j int) bool {
+		return out.Ports[i].Port < out.Ports[j].Port
+	})
+	writeJSON(w, out)
+}
+
+// AutoMtlsCluster explains the TLS settings of an outbound cluster of a proxy. If auto mTLS is decided per
+// endpoint, Endpoints explains the decision for each of them.
+type AutoMtlsCluster struct {
+	*v1alpha3.AutoMtlsExplanation
+	Endpoints []AutoMtlsEndpoint `json:"endpoints,omitempty"`
+}
+
+// AutoMtlsEndpoint explains whether auto mTLS is used for an endpoint of a cluster.
+type AutoMtlsEndpoint struct {
+	Address string `json:"address"`
+	Port    uint32 `json:"port"`
+	Mtls    bool   `json:"mtls"`
+	// Reason is why the endpoint is sent plaintext.
+	Reason string `json:"reason,omitempty"`
+}
+
+// automtlsz explains, for each outbound cluster of a proxy to the given host, why auto mTLS chose mTLS or
+// plaintext: the DestinationRule, the PeerAuthentications and the sidecars of the endpoints it decides from.
+func (s *DiscoveryServer) automtlsz(w http.ResponseWriter, req *http.Request) {
+	con := s.getDebugConnection(w, req)
+	if con == nil {
+		return
+	}
+	hostname := req.URL.Query().Get("host")
+	if hostname == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a host in the query string\n"))
+		return
+	}
+	push := s.globalPushContext()
+	con.proxy.RLock()
+	defer con.proxy.RUnlock()
+	svc := push.ServiceForHostname(con.proxy, host.Name(hostname))
+	if svc == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(fmt.Sprintf("Host %s is not visible to the proxy\n", hostname)))
+		return
+	}
+	subsets := []string{""}
+	if dr := push.DestinationRule(con.proxy, svc); dr != nil {
+		for _, subset := range dr.Spec.(*networkingapi.DestinationRule).Subsets {
+			subsets = append(subsets, subset.Name)
+		}
+	}
+	out := []AutoMtlsCluster{}
+	for _, port := range svc.Ports {
+		if port.Protocol == protocol.UDP {
+			continue
+		}
+		for _, subset := range subsets {
+			c := AutoMtlsCluster{AutoMtlsExplanation: v1alpha3.ExplainAutoMtls(con.proxy, push, svc, port, subset)}
+			if c.PerEndpoint {
+				c.Endpoints = s.autoMtlsEndpoints(NewEndpointBuilder(c.Cluster, con.proxy, push), port)
+			}
+			out = append(out, c)
+		}
+	}
+	writeJSON(w, out)
+}
+
+// autoMtlsEndpoints explains whether auto mTLS is used for each endpoint of an EDS cluster, selecting and
+// checking the endpoints the same way as buildLocalityLbEndpointsFromShards.
+func (s *DiscoveryServer) autoMtlsEndpoints(b EndpointBuilder, svcPort *model.Port) []AutoMtlsEndpoint {
+	s.mutex.RLock()
+	shards, f := s.EndpointShardsByService[string(b.hostname)][b.service.Attributes.Namespace]
+	s.mutex.RUnlock()
+	if !f {
+		return nil
+	}
+	checker := newMtlsChecker(b.push, b.port, b.destinationRule)
+	epLabels := getSubSetLabels(b.DestinationRule(), b.subsetName)
+	out := []AutoMtlsEndpoint{}
+	shards.mutex.RLock()
+	defer shards.mutex.RUnlock()
+	for clusterID, endpoints := range shards.Shards {
+		if b.clusterLocal && cluster.ID(clusterID) != b.clusterID {
+			continue
+		}
+		for _, ep := range endpoints {
+			if !ep.IsDiscoverableFromProxy(b.proxy) || svcPort.Name != ep.ServicePortName || !epLabels.HasSubsetOf(ep.Labels) {
+				continue
+			}
+			reason := checker.mtlsDisabledReason(ep)
+			out = append(out, AutoMtlsEndpoint{
+				Address: ep.Address,
+				Port:    ep.EndpointPort,
+				Mtls:    reason == "",
+				Reason:  reason,
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Address != out[j].Address {
+			return out[i].Address < out[j].Address
+		}
+		return out[i].Port < out[j].Port
+	})
+	return out
+}
+
+// sidecarPreview returns the SidecarScope, including the matched Sidecar, of a hypothetical sidecar workload
+// in the given namespace with the given labels, to validate Sidecar resources before deploying workloads.
+func (s *DiscoveryServer) sidecarPreview(w http.ResponseWriter, req *http.Request) {
+	namespace := req.URL.Query().Get("namespace")
+	if namespace == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a namespace in the query string\n"))
+		return
+	}
+	if _, ok := s.namespaceParam(w, req); !ok {
+		return
+	}
+	workloadLabels, err := klabels.ConvertSelectorToLabelsMap(req.URL.Query().Get("labels"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(fmt.Sprintf("Invalid labels: %v\n", err)))
+		return
+	}
+	writeJSON(w, s.globalPushContext().SidecarScopeForWorkload(namespace, labels.Instance(workloadLabels)))
+}
+
+// PushScope lists the sidecar scopes and connected proxies a change to a config is pushed to.
+type PushScope struct {
+	Config string `json:"config"`
+	// SidecarScopes are the precomputed sidecar scopes depending on the config, as <namespace>/<name>.
+	SidecarScopes []string `json:"sidecarScopes"`
+	// Proxies are the connected proxies which would be pushed on a change to the config.
+	Proxies []string `json:"proxies"`
+}
+
+// pushScopez explains the push scoping decision for a change to the given config. The callers restricted to some
+// namespaces may only query the configs of these namespaces, and only get the sidecar scopes and proxies in them.
+func (s *DiscoveryServer) pushScopez(w http.ResponseWriter, req *http.Request) {
+	key, err := parseConfigKey(req.URL.Query().Get("config"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	scope := s.DebugScopeFor(req)
+	if !scope.Allows(key.Namespace) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("the authenticated identity may not read the configs of this namespace\n"))
+		return
+	}
+	out := PushScope{Config: key.String(), SidecarScopes: []string{}, Proxies: []string{}}
+	for _, sc := range s.globalPushContext().SidecarScopesDependingOn(key) {
+		if scope.Allows(sc.Namespace) {
+			out.SidecarScopes = append(out.SidecarScopes, sc.Namespace+"/"+sc.Name)
+		}
+	}
+	sort.Strings(out.SidecarScopes)
+
+	pushRequest := &model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{key: {}}}
+	for _, con := range s.Clients() {
+		if con.proxy == nil || !scope.Allows(con.proxy.ConfigNamespace) {
+			continue
+		}
+		con.proxy.RLock()
+		if s.ProxyNeedsPush(con.proxy, pushRequest) {
+			out.Proxies = append(out.Proxies, con.proxy.ID)
+		}
+		con.proxy.RUnlock()
+	}
+	sort.Strings(out.Proxies)
+	writeJSON(w, out)
+}
+
+// parseConfigKey parses a config given as <kind>/<namespace>/<name>. The name of a ServiceEntry key is the
+// hostname of the service.
+func parseConfigKey(id string) (model.ConfigKey, error) {
+	parts := strings.SplitN(id, "/", 3)
+	if len(parts) != 3 {
+		return model.ConfigKey{}, fmt.Errorf("invalid config %q, expected <kind>/<namespace>/<name>", id)
+	}
+	for _, schema := range collections.Pilot.All() {
+		if strings.EqualFold(schema.Resource().Kind(), parts[0]) {
+			return model.ConfigKey{Kind: schema.Resource().GroupVersionKind(), Namespace: parts[1], Name: parts[2]}, nil
+		}
+	}
+	return model.ConfigKey{}, fmt.Errorf("unknown config kind %q", parts[0])
+}
+
+// BootstrapDebug compares the bootstrap a proxy was started with to the one it would get if restarted now.
+type BootstrapDebug struct {
+	Proxy    string           `json:"proxy"`
+	Reported jsonMarshalProto `json:"reported"`
+	Current  jsonMarshalProto `json:"current"`
+	// Diff is a unified diff from the reported to the current bootstrap, empty if they are identical.
+	Diff string `json:"diff"`
+}
+
+// Bootstrapz renders the bootstrap of a proxy from the proxy config it reported when connecting, and from the
+// current mesh config. A difference means the proxy needs a restart to pick up the mesh config changes.
+// It is mapped to /debug/bootstrapz.
+func (s *DiscoveryServer) Bootstrapz(w http.ResponseWriter, req *http.Request) {
+	con := s.getDebugConnection(w, req)
+	if con == nil {
+		return
+	}
+	node := bootstrap.ConvertXDSNodeToNode(con.proxy.XdsNode)
+	reported, err := renderBootstrap(node)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	pc, err := currentProxyConfig(s.Env.EffectiveMesh(con.proxy.ConfigNamespace), node)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	node.Metadata.ProxyConfig = pc
+	current, err := renderBootstrap(node)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	diff, err := diffBootstraps("reported", reported, "current", current)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	writeJSON(w, BootstrapDebug{
+		Proxy:    con.proxy.ID,
+		Reported: jsonMarshalProto{reported},
+		Current:  jsonMarshalProto{current},
+		Diff:     diff,
+	})
+}
+
 // Resource debugging.
-func (s *DiscoveryServer) resourcez(w http.ResponseWriter, _ *http.Request) {
+func (s *DiscoveryServer) resourcez(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	schemas := make([]config.GroupVersionKind, 0)
 	s.Env.Schemas().ForEach(func(schema collection.Schema) bool {
 		schemas = append(schemas, schema.Resource().GroupVersionKind())
@@ -480,6 +954,9 @@ type AuthorizationDebug struct {
 
 // Authorizationz dumps the internal authorization policies.
 func (s *DiscoveryServer) Authorizationz(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	info := AuthorizationDebug{
 		AuthorizationPolicies: s.globalPushContext().AuthzPolicies,
 	}
@@ -487,42 +964,225 @@ func (s *DiscoveryServer) Authorizationz(w http.ResponseWriter, req *http.Reques
 }
 
 func (s *DiscoveryServer) telemetryz(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	writeJSON(w, s.globalPushContext().Telemetry)
 }
 
-// ConnectionsHandler implements interface for displaying current connections.
-// It is mapped to /debug/connections.
+// ConnectionsHandler implements interface for displaying current connections, of the proxies in the namespaces
+// readable by the caller. It is mapped to /debug/connections.
 func (s *DiscoveryServer) ConnectionsHandler(w http.ResponseWriter, req *http.Request) {
+	scope := s.DebugScopeFor(req)
 	adsClients := &AdsClients{}
-	connections := s.Clients()
-	adsClients.Total = len(connections)
-
-	for _, c := range connections {
+	for _, c := range s.Clients() {
+		if !scope.all && (c.proxy == nil || !scope.Allows(c.proxy.ConfigNamespace)) {
+			continue
+		}
+		adsClients.Total++
 		adsClient := AdsClient{
 			ConnectionID: c.ConID,
 			ConnectedAt:  c.Connect,
 			PeerAddress:  c.PeerAddr,
+			InstanceID:   c.InstanceID,
+			Reconnects:   c.Reconnects,
+		}
+		if c.proxy != nil {
+			adsClient.Pushes = c.PushHistory()
 		}
 		adsClients.Connected = append(adsClients.Connected, adsClient)
 	}
 	writeJSON(w, adsClients)
 }
 
+// ConfigSize is the marshaled size of the config of one type last sent to a proxy.
+type ConfigSize struct {
+	ProxyID       string `json:"proxy"`
+	Type          string `json:"type"`
+	Bytes         int    `json:"bytes"`
+	PreviousBytes int    `json:"previous_bytes"`
+	Growth        int    `json:"growth"`
+}
+
+// ConfigSizeReport lists the largest configs sent to connected proxies, and the configs that grew the most
+// compared to the previous push.
+type ConfigSizeReport struct {
+	TotalBytes    int          `json:"total_bytes"`
+	Largest       []ConfigSize `json:"largest"`
+	LargestGrowth []ConfigSize `json:"largest_growth"`
+}
+
+// Sizez reports the k (10 by default) largest configs of connected proxies in the namespaces readable by the
+// caller, optionally restricted to one type. It is mapped to /debug/sizez.
+func (s *DiscoveryServer) Sizez(w http.ResponseWriter, req *http.Request) {
+	k := 10
+	if v := req.URL.Query().Get("k"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid k %q\n", v)
+			return
+		}
+		k = n
+	}
+	typeFilter := req.URL.Query().Get("type")
+	scope := s.DebugScopeFor(req)
+
+	out := ConfigSizeReport{}
+	var sizes, growth []ConfigSize
+	for _, con := range s.Clients() {
+		if con.proxy == nil || !scope.Allows(con.proxy.ConfigNamespace) {
+			continue
+		}
+		con.proxy.RLock()
+		for typeURL, cs := range con.configSizes {
+			shortType := v3.GetShortType(typeURL)
+			if typeFilter != "" && !strings.EqualFold(typeFilter, shortType) && typeFilter != typeURL {
+				continue
+			}
+			size := ConfigSize{ProxyID: con.proxy.ID, Type: shortType, Bytes: cs.current}
+			out.TotalBytes += cs.current
+			sizes = append(sizes, size)
+			if cs.pushes > 1 {
+				size.PreviousBytes = cs.previous
+				size.Growth = cs.current - cs.previous
+				if size.Growth > 0 {
+					growth = append(growth, size)
+				}
+			}
+		}
+		con.proxy.RUnlock()
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Bytes != sizes[j].Bytes {
+			return sizes[i].Bytes > sizes[j].Bytes
+		}
+		return sizes[i].ProxyID < sizes[j].ProxyID
+	})
+	sort.Slice(growth, func(i, j int) bool {
+		if growth[i].Growth != growth[j].Growth {
+			return growth[i].Growth > growth[j].Growth
+		}
+		return growth[i].ProxyID < growth[j].ProxyID
+	})
+	if len(sizes) > k {
+		sizes = sizes[:k]
+	}
+	if len(growth) > k {
+		growth = growth[:k]
+	}
+	out.Largest, out.LargestGrowth = sizes, growth
+	writeJSON(w, out)
+}
+
+// Costz reports the time spent generating and the bytes of the configs pushed since istiod started, by namespace
+// of the proxies and kind of the configs triggering the pushes, optionally restricted to a namespace and
+// aggregated by namespace or kind. The callers restricted to some namespaces must set the namespace. It is mapped to
+// /debug/costz.
+func (s *DiscoveryServer) Costz(w http.ResponseWriter, req *http.Request) {
+	by := req.URL.Query().Get("by")
+	if by != "" && by != "namespace" && by != "kind" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "invalid by %q, expected namespace or kind\n", by)
+		return
+	}
+	namespace, ok := s.namespaceParam(w, req)
+	if !ok {
+		return
+	}
+	out := s.pushCosts.report(namespace, by)
+	out.Revision = s.revision()
+	writeJSON(w, out)
+}
+
+// PushLatencyz reports the time spent generating and sending the responses pushed since istiod started, and their
+// size, by type. With a proxyID, it reports the responses pushed to the proxy since it connected.
+func (s *DiscoveryServer) PushLatencyz(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Query().Get("proxyID") == "" {
+		if s.AllowMeshWide(w, req) {
+			writeJSON(w, s.pushLatencies.report())
+		}
+		return
+	}
+	con := s.getDebugConnection(w, req)
+	if con == nil {
+		return
+	}
+	out, _ := s.pushLatencyReport(con.proxy.ID)
+	writeJSON(w, out)
+}
+
+// Dependenciesz reports the health of the external dependencies istiod contacts: JWKS endpoints, external CA,
+// API servers of the remote clusters and XDS config sources, optionally of a kind or only the unhealthy ones.
+func (s *DiscoveryServer) Dependenciesz(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
+	kind := dependency.Kind(req.URL.Query().Get("kind"))
+	unhealthy := req.URL.Query().Get("unhealthy") != ""
+	out := []dependency.Status{}
+	for _, st := range dependency.Default.Statuses() {
+		if (kind != "" && st.Kind != kind) || (unhealthy && st.Healthy) {
+			continue
+		}
+		out = append(out, st)
+	}
+	writeJSON(w, out)
+}
+
+// Analyzez returns the messages of the last analysis of the configs, optionally about the resources of a
+// namespace or at or above a level. The callers restricted to some namespaces must set the namespace.
+func (s *DiscoveryServer) Analyzez(w http.ResponseWriter, req *http.Request) {
+	if !features.EnableAnalysis {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("config analysis is not enabled, see PILOT_ENABLE_ANALYSIS\n"))
+		return
+	}
+	level := diag.Info
+	if l := req.URL.Query().Get("level"); l != "" {
+		var f bool
+		if level, f = diag.GetUppercaseStringToLevelMap()[strings.ToUpper(l)]; !f {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid level %q, expected one of %v\n", l, diag.GetAllLevelStrings())
+			return
+		}
+	}
+	namespace, ok := s.namespaceParam(w, req)
+	if !ok {
+		return
+	}
+	out, f := s.analysis.report(namespace, level)
+	if !f {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("configs not analyzed yet: only the istiod elected leader of the analysis controller analyzes them\n"))
+		return
+	}
+	writeJSON(w, out)
+}
+
 // adsz implements a status and debug interface for ADS.
 // It is mapped to /debug/adsz
 func (s *DiscoveryServer) adsz(w http.ResponseWriter, req *http.Request) {
 	if s.handlePushRequest(w, req) {
 		return
 	}
+	writeJSON(w, s.adsClients(s.DebugScopeFor(req)))
+}
 
+// adsClients lists the connected proxies of the namespaces of the scope, with the resources they watch.
+func (s *DiscoveryServer) adsClients(scope DebugScope) *AdsClients {
 	adsClients := &AdsClients{}
-	connections := s.Clients()
-	adsClients.Total = len(connections)
 	for _, c := range s.Clients() {
+		if !scope.Allows(c.proxy.ConfigNamespace) {
+			continue
+		}
+		adsClients.Total++
 		adsClient := AdsClient{
 			ConnectionID: c.ConID,
 			ConnectedAt:  c.Connect,
 			PeerAddress:  c.PeerAddr,
+			InstanceID:   c.InstanceID,
+			Reconnects:   c.Reconnects,
 			Watches:      map[string][]string{},
 		}
 		c.proxy.RLock()
@@ -536,7 +1196,7 @@ func (s *DiscoveryServer) adsz(w http.ResponseWriter, req *http.Request) {
 		c.proxy.RUnlock()
 		adsClients.Connected = append(adsClients.Connected, adsClient)
 	}
-	writeJSON(w, adsClients)
+	return adsClients
 }
 
 // ConfigDump returns information in the form of the Envoy admin API config dump for the specified proxy
@@ -560,6 +1220,8 @@ func (s *DiscoveryServer) ConfigDump(w http.ResponseWriter, req *http.Request) {
 func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, error) {
 	dynamicActiveClusters := make([]*adminapi.ClustersConfigDump_DynamicCluster, 0)
 	clusters, _ := s.ConfigGenerator.BuildClusters(conn.proxy, s.globalPushContext())
+	// The resources are dumped in the order they are sent, so that dumps can be compared.
+	sortResources(v3.ClusterType, clusters)
 
 	for _, cs := range clusters {
 		dynamicActiveClusters = append(dynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{Cluster: cs.Resource})
@@ -574,6 +1236,9 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 
 	dynamicActiveListeners := make([]*adminapi.ListenersConfigDump_DynamicListener, 0)
 	listeners := s.ConfigGenerator.BuildListeners(conn.proxy, s.globalPushContext())
+	sort.SliceStable(listeners, func(i, j int) bool {
+		return listeners[i].Name < listeners[j].Name
+	})
 	for _, cs := range listeners {
 		listener, err := anypb.New(cs)
 		if err != nil {
@@ -593,6 +1258,9 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 	}
 
 	routes := s.ConfigGenerator.BuildHTTPRoutes(conn.proxy, s.globalPushContext(), conn.Routes())
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Name < routes[j].Name
+	})
 	routeConfigAny := util.MessageToAny(&adminapi.RoutesConfigDump{})
 	if len(routes) > 0 {
 		dynamicRouteConfig := make([]*adminapi.RoutesConfigDump_DynamicRouteConfig, 0)
@@ -618,13 +1286,6 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 				if err := secretAny.GetResource().UnmarshalTo(secret); err != nil {
 					istiolog.Warnf("failed to unmarshal secret: %v", err)
 				}
-				if secret.GetTlsCertificate() != nil {
-					secret.GetTlsCertificate().PrivateKey = &core.DataSource{
-						Specifier: &core.DataSource_InlineBytes{
-							InlineBytes: []byte("[redacted]"),
-						},
-					}
-				}
 				secretsDump.DynamicActiveSecrets = append(secretsDump.DynamicActiveSecrets, &adminapi.SecretsConfigDump_DynamicSecret{
 					Name:   secret.Name,
 					Secret: util.MessageToAny(secret),
@@ -633,8 +1294,21 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 		}
 	}
 
+	scopedRoutesDump := &adminapi.ScopedRoutesConfigDump{}
+	if scopes := s.ConfigGenerator.BuildScopedRoutes(conn.proxy, s.globalPushContext()); len(scopes) > 0 {
+		scopedRouteConfigs := make([]*any.Any, 0, len(scopes))
+		for _, scope := range scopes {
+			scopedRouteConfigs = append(scopedRouteConfigs, util.MessageToAny(scope))
+		}
+		scopedRoutesDump.DynamicScopedRouteConfigs = []*adminapi.ScopedRoutesConfigDump_DynamicScopedRouteConfigs{{
+			Name:               util.OnDemandScopedRoutes,
+			VersionInfo:        versionInfo(),
+			ScopedRouteConfigs: scopedRouteConfigs,
+		}}
+	}
+
 	bootstrapAny := util.MessageToAny(&adminapi.BootstrapConfigDump{})
-	scopedRoutesAny := util.MessageToAny(&adminapi.ScopedRoutesConfigDump{})
+	scopedRoutesAny := util.MessageToAny(scopedRoutesDump)
 	// The config dump must have all configs with connections specified in
 	// https://www.envoyproxy.io/docs/envoy/latest/api-v2/admin/v2alpha/config_dump.proto
 	configDump := &adminapi.ConfigDump{
@@ -646,6 +1320,8 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 			util.MessageToAny(secretsDump),
 		},
 	}
+	// The private keys, tokens and the values of sensitive headers must never leave istiod.
+	s.redaction.redact(configDump)
 	return configDump, nil
 }
 
@@ -653,25 +1329,138 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 // Replaces dumping the template at startup.
 func (s *DiscoveryServer) InjectTemplateHandler(webhook func() map[string]string) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
-		// TODO: we should split the inject template into smaller modules (separate one for dump core, etc),
-		// and allow pods to select which patches will be selected. When this happen, this should return
-		// all inject templates or take a param to select one.
 		if webhook == nil {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
+		if req.Method == http.MethodPost {
+			s.previewInjection(w, req)
+			return
+		}
+		if !s.AllowMeshWide(w, req) {
+			return
+		}
 
-		writeJSON(w, webhook())
+		templates := webhook()
+		if name := req.URL.Query().Get("template"); name != "" {
+			template, f := templates[name]
+			if !f {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(fmt.Sprintf("template %q not found", name)))
+				return
+			}
+			templates = map[string]string{name: template}
+		}
+		writeJSON(w, templates)
+	}
+}
+
+// previewInjection dry-runs the sidecar injection of the pod posted in YAML or JSON, returning the injected
+// pod along with the templates, values and proxy config it was rendered with.
+func (s *DiscoveryServer) previewInjection(w http.ResponseWriter, req *http.Request) {
+	if s.InjectDryRun == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	pod := &corev1.Pod{}
+	if err := yaml.Unmarshal(body, pod); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(fmt.Sprintf("invalid pod: %v", err)))
+		return
+	}
+	namespace, ok := s.namespaceParam(w, req)
+	if !ok {
+		return
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	result, err := s.InjectDryRun(pod, namespace)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
 	}
+	writeJSON(w, result)
 }
 
-// MeshHandler dumps the mesh config
+// MeshHandler dumps the mesh config. With ?effective=<namespace>, the mesh config overlay of the namespace
+// is applied. The mesh config holds the settings of all the namespaces, so it is only served to mesh-wide callers.
 func (s *DiscoveryServer) MeshHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.AllowMeshWide(w, r) {
+		return
+	}
+	if ns := r.URL.Query().Get("effective"); ns != "" {
+		writeJSONProto(w, s.Env.EffectiveMesh(ns))
+		return
+	}
 	writeJSONProto(w, s.Env.Mesh())
 }
 
-// PushStatusHandler dumps the last PushContext
+// RouteAliases lists the naming schemes of route configurations, and the changes to them.
+type RouteAliases struct {
+	Schemes []model.RouteNameScheme `json:"schemes"`
+	Aliases []model.RouteNameAlias  `json:"aliases"`
+}
+
+// routeAliasesz lists the naming schemes of route configurations, and the changes to them. With ?name=<route name>,
+// the current name of a route configuration referenced by a possibly former name is returned instead.
+func (s *DiscoveryServer) routeAliasesz(w http.ResponseWriter, req *http.Request) {
+	if name := req.URL.Query().Get("name"); name != "" {
+		writeJSON(w, map[string]string{"name": name, "current": model.ResolveRouteNameAlias(name)})
+		return
+	}
+	aliases := model.RouteNameAliases
+	if aliases == nil {
+		aliases = []model.RouteNameAlias{}
+	}
+	writeJSON(w, RouteAliases{Schemes: model.RouteNameSchemes, Aliases: aliases})
+}
+
+// FIPSStatus is the FIPS compliance status of istiod.
+type FIPSStatus struct {
+	// Enabled is true if the generated TLS parameters and the certificates issued are restricted to FIPS.
+	Enabled bool `json:"enabled"`
+	// BoringCrypto is true if istiod is built with the BoringCrypto FIPS module.
+	BoringCrypto bool `json:"boringCrypto"`
+	// TLSProfile is the name of the TLS profile of the mesh.
+	TLSProfile   string   `json:"tlsProfile,omitempty"`
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+	Curves       []string `json:"curves,omitempty"`
+	// Violations are the non-compliant settings refused since istiod started.
+	Violations []fips.Violation `json:"violations"`
+}
+
+// fipsz reports whether FIPS mode is enforced, and the non-compliant settings refused.
+func (s *DiscoveryServer) fipsz(w http.ResponseWriter, _ *http.Request) {
+	status := FIPSStatus{
+		Enabled:      fips.Enabled(),
+		BoringCrypto: fips.BoringCrypto(),
+		TLSProfile:   features.TLSProfile,
+		Violations:   fips.Violations(),
+	}
+	if status.Enabled {
+		if status.TLSProfile == "" {
+			status.TLSProfile = authnmodel.TLSProfileFIPS
+		}
+		status.CipherSuites = fips.CipherSuites
+		status.Curves = fips.Curves
+	}
+	writeJSON(w, status)
+}
+
+// PushStatusHandler dumps the last PushContext. The push status covers the whole mesh, so it is only served to
+// mesh-wide callers.
 func (s *DiscoveryServer) PushStatusHandler(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	if model.LastPushStatus == nil {
 		return
 	}
@@ -685,6 +1474,31 @@ func (s *DiscoveryServer) PushStatusHandler(w http.ResponseWriter, req *http.Req
 	_, _ = w.Write(out)
 }
 
+// listenerConflictsz lists the conflicts between services claiming the same outbound listener, found while
+// generating the listeners of the proxies with the current push context. The conflicting services may be in any
+// namespace, so they are only served to mesh-wide callers.
+func (s *DiscoveryServer) listenerConflictsz(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
+	writeJSON(w, s.globalPushContext().ListenerConflicts())
+}
+
+// ineffectivePoliciesz lists the authorization policies and request authentications of the current push context with
+// rules which cannot take effect on the workloads they select, optionally in a namespace.
+func (s *DiscoveryServer) ineffectivePoliciesz(w http.ResponseWriter, req *http.Request) {
+	scope := s.DebugScopeFor(req)
+	namespace := req.URL.Query().Get("namespace")
+	out := []model.IneffectivePolicy{}
+	for _, p := range s.globalPushContext().IneffectivePolicyList() {
+		if (namespace != "" && p.Namespace != namespace) || !scope.Allows(p.Namespace) {
+			continue
+		}
+		out = append(out, p)
+	}
+	writeJSON(w, out)
+}
+
 // PushContextDebug holds debug information for push context.
 type PushContextDebug struct {
 	AuthorizationPolicies *model.AuthorizationPolicies
@@ -692,7 +1506,10 @@ type PushContextDebug struct {
 }
 
 // PushContextHandler dumps the current PushContext
-func (s *DiscoveryServer) PushContextHandler(w http.ResponseWriter, _ *http.Request) {
+func (s *DiscoveryServer) PushContextHandler(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	gateways := s.globalPushContext().NetworkManager().AllGateways()
 	byNetwork := make(map[string][]*model.NetworkGateway)
 	for _, gateway := range gateways {
@@ -722,6 +1539,13 @@ func (s *DiscoveryServer) Debug(w http.ResponseWriter, req *http.Request) {
 			Help: v,
 		})
 	}
+	for k, v := range s.debugExtensionHelp() {
+		deps = append(deps, debugEndpoint{
+			Name: k,
+			Href: k,
+			Help: v,
+		})
+	}
 
 	sort.Slice(deps, func(i, j int) bool {
 		return deps[i].Name < deps[j].Name
@@ -734,8 +1558,16 @@ func (s *DiscoveryServer) Debug(w http.ResponseWriter, req *http.Request) {
 }
 
 // List all the supported debug commands in json.
+// With schema=true, the parameters and an example request are included for each command.
 func (s *DiscoveryServer) List(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Query().Get("schema") == "true" {
+		s.listSchemas(w)
+		return
+	}
 	var cmdNames []string
+	for k := range s.debugExtensionHelp() {
+		cmdNames = append(cmdNames, strings.Replace(k, "/debug/", "", -1))
+	}
 	for k := range s.debugHandlers {
 		key := strings.Replace(k, "/debug/", "", -1)
 		// exclude current list command
@@ -752,6 +1584,25 @@ func (s *DiscoveryServer) List(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, cmdNames)
 }
 
+func (s *DiscoveryServer) listSchemas(w http.ResponseWriter) {
+	var cmds []DebugCommand
+	for k, help := range s.debugExtensionHelp() {
+		cmds = append(cmds, debugCommand(strings.TrimPrefix(k, "/debug/"), help))
+	}
+	for k, help := range s.debugHandlers {
+		key := strings.TrimPrefix(k, "/debug/")
+		// Paths with a query, such as adsz?push=true, are covered by the parameters of their command.
+		if key == "list" || strings.Contains(key, "pprof") || strings.Contains(key, "?") {
+			continue
+		}
+		cmds = append(cmds, debugCommand(key, help))
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].Name < cmds[j].Name
+	})
+	writeJSON(w, cmds)
+}
+
 // Ndsz implements a status and debug interface for NDS.
 // It is mapped to /debug/Ndsz on the monitor port (15014).
 func (s *DiscoveryServer) Ndsz(w http.ResponseWriter, req *http.Request) {
@@ -813,24 +1664,32 @@ func (s *DiscoveryServer) getProxyConnection(proxyID string) *Connection {
 }
 
 func (s *DiscoveryServer) instancesz(w http.ResponseWriter, req *http.Request) {
+	scope := s.DebugScopeFor(req)
 	instances := map[string][]*model.ServiceInstance{}
 	for _, con := range s.Clients() {
-		con.proxy.RLock()
-		if con.proxy != nil {
-			instances[con.proxy.ID] = con.proxy.ServiceInstances
+		if con.proxy == nil || !scope.Allows(con.proxy.ConfigNamespace) {
+			continue
 		}
+		con.proxy.RLock()
+		instances[con.proxy.ID] = con.proxy.ServiceInstances
 		con.proxy.RUnlock()
 	}
 	writeJSON(w, instances)
 }
 
-func (s *DiscoveryServer) networkz(w http.ResponseWriter, _ *http.Request) {
+func (s *DiscoveryServer) networkz(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	// Merge the gateways from the service registries with those configured statically with MeshNetworks.
 	mgr := model.NewNetworkManager(s.Env)
 	writeJSON(w, mgr.AllGateways())
 }
 
-func (s *DiscoveryServer) exportz(w http.ResponseWriter, _ *http.Request) {
+func (s *DiscoveryServer) exportz(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	aggregateController, ok := s.Env.ServiceDiscovery.(*aggregate.Controller)
 	if !ok {
 		writeJSON(w, nil)
@@ -862,7 +1721,10 @@ func (s *DiscoveryServer) exportz(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, jsonMap)
 }
 
-func (s *DiscoveryServer) clusterz(w http.ResponseWriter, _ *http.Request) {
+func (s *DiscoveryServer) clusterz(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
 	if s.ListRemoteClusters == nil {
 		w.WriteHeader(400)
 		return
@@ -879,6 +1741,9 @@ func (s *DiscoveryServer) handlePushRequest(w http.ResponseWriter, req *http.Req
 		return true
 	}
 	if req.Form.Get("push") != "" {
+		if !s.AllowMeshWide(w, req) {
+			return true
+		}
 		AdsPushAll(s)
 		_, _ = fmt.Fprintf(w, "Pushed to %d servers\n", s.adsClientCount())
 		return true
@@ -903,6 +1768,11 @@ func (s *DiscoveryServer) getDebugConnection(w http.ResponseWriter, req *http.Re
 			_, _ = w.Write([]byte("Proxy not connected to this Pilot instance. It may be connected to another instance.\n"))
 			return nil
 		}
+		if !s.DebugScopeFor(req).Allows(con.proxy.ConfigNamespace) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("the authenticated identity may not read the proxies of this namespace\n"))
+			return nil
+		}
 	} else {
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte("You must provide a proxyID in the query string\n"))
@@ -0,0 +1,148 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/sets"
+	"istio.io/istio/pkg/spiffe"
+)
+
+type debugIdentitiesKey struct{}
+
+// withDebugIdentities returns the context of a debug request authenticated with the identities.
+func withDebugIdentities(ctx context.Context, ids []string) context.Context {
+	return context.WithValue(ctx, debugIdentitiesKey{}, ids)
+}
+
+// DebugScope is the set of namespaces a debug request may read. The handlers registered with AddDebugHandler get
+// the scope of their requests with DebugScopeFor, and must restrict their responses to it.
+type DebugScope struct {
+	// all is true for the requests from localhost or the internal mux, and for the identities of the system namespace
+	// or with the admin role.
+	all        bool
+	namespaces sets.Set
+}
+
+// MeshWide returns whether all the namespaces are readable in the scope.
+func (d DebugScope) MeshWide() bool {
+	return d.all
+}
+
+// Allows returns whether the namespace is readable in the scope.
+func (d DebugScope) Allows(namespace string) bool {
+	return d.all || d.namespaces.Contains(namespace)
+}
+
+// allowsService returns whether the service is visible to one of the namespaces of the scope.
+func (d DebugScope) allowsService(push *model.PushContext, svc *model.Service) bool {
+	if d.all {
+		return true
+	}
+	for ns := range d.namespaces {
+		if push.IsServiceVisible(svc, ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDebugAdmin returns whether the identity has the mesh-wide admin role of the debug interface, set by
+// PILOT_DEBUG_ADMIN_IDENTITIES.
+func isDebugAdmin(identity spiffe.Identity) bool {
+	for _, admin := range features.DebugAdminIdentities {
+		parts := strings.Split(admin, "/")
+		if len(parts) != 2 || parts[0] != identity.Namespace {
+			continue
+		}
+		if parts[1] == "*" || parts[1] == identity.ServiceAccount {
+			return true
+		}
+	}
+	return false
+}
+
+// DebugScopeFor returns the scope of the debug request: the requests authenticated with identities outside of the
+// system namespace and without the admin role may only read the namespaces of these identities.
+func (s *DiscoveryServer) DebugScopeFor(req *http.Request) DebugScope {
+	ids, ok := req.Context().Value(debugIdentitiesKey{}).([]string)
+	if !ok {
+		return DebugScope{all: true}
+	}
+	scope := DebugScope{namespaces: sets.NewSet()}
+	for _, id := range ids {
+		identity, err := spiffe.ParseIdentity(id)
+		if err != nil {
+			continue
+		}
+		if identity.Namespace == s.systemNamespace || isDebugAdmin(identity) {
+			return DebugScope{all: true}
+		}
+		scope.namespaces.Insert(identity.Namespace)
+	}
+	return scope
+}
+
+// debugCaller describes the caller of a debug handler for logging: its authenticated identities, or the
+// remote address for the requests from localhost or the internal mux.
+func debugCaller(req *http.Request) string {
+	if ids, ok := req.Context().Value(debugIdentitiesKey{}).([]string); ok {
+		return fmt.Sprintf("%v (%v)", ids, req.RemoteAddr)
+	}
+	if req.RemoteAddr == "" {
+		return "internal"
+	}
+	return req.RemoteAddr
+}
+
+// resourceNamespace returns the namespace of a ledger resource key, which ends with <namespace>/<name>.
+func resourceNamespace(key string) string {
+	parts := strings.Split(key, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// AllowMeshWide returns whether the debug request may read or act on all the namespaces. Otherwise, it writes a
+// Forbidden response.
+func (s *DiscoveryServer) AllowMeshWide(w http.ResponseWriter, req *http.Request) bool {
+	if s.DebugScopeFor(req).all {
+		return true
+	}
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = w.Write([]byte("the authenticated identity may only read the information of its namespace\n"))
+	return false
+}
+
+// namespaceParam returns the namespace query parameter of the debug request, restricting what the handler reports.
+// The callers which may not read all the namespaces must set it to a namespace of their scope. Otherwise, it writes
+// a Forbidden response and returns false.
+func (s *DiscoveryServer) namespaceParam(w http.ResponseWriter, req *http.Request) (string, bool) {
+	namespace := req.URL.Query().Get("namespace")
+	scope := s.DebugScopeFor(req)
+	if scope.all || (namespace != "" && scope.Allows(namespace)) {
+		return namespace, true
+	}
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = w.Write([]byte("the authenticated identity must set the namespace parameter to one of its namespaces\n"))
+	return "", false
+}
@@ -0,0 +1,258 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestDebugScope(t *testing.T) {
+	s := &DiscoveryServer{systemNamespace: "istio-system"}
+	cases := []struct {
+		name    string
+		ids     []string
+		allowed []string
+		denied  []string
+	}{
+		{"unauthenticated", nil, []string{"foo", "istio-system"}, nil},
+		{"system namespace", []string{"spiffe://cluster.local/ns/istio-system/sa/istiod"}, []string{"foo", "bar"}, nil},
+		{
+			"workload namespaces",
+			[]string{"spiffe://cluster.local/ns/foo/sa/a", "spiffe://cluster.local/ns/bar/sa/b"},
+			[]string{"foo", "bar"},
+			[]string{"baz", "istio-system"},
+		},
+		{"not spiffe", []string{"someone"}, nil, []string{"foo", "istio-system"}},
+		{"admin service account", []string{"spiffe://cluster.local/ns/ops/sa/admin"}, []string{"foo", "bar"}, nil},
+		{"admin namespace", []string{"spiffe://cluster.local/ns/tools/sa/any"}, []string{"foo", "bar"}, nil},
+		{"not admin", []string{"spiffe://cluster.local/ns/ops/sa/viewer"}, []string{"ops"}, []string{"foo"}},
+	}
+	defer func(v []string) { features.DebugAdminIdentities = v }(features.DebugAdminIdentities)
+	features.DebugAdminIdentities = []string{"ops/admin", "tools/*"}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/config_distribution", nil)
+			if tt.ids != nil {
+				req = req.WithContext(withDebugIdentities(req.Context(), tt.ids))
+			}
+			scope := s.DebugScopeFor(req)
+			for _, ns := range tt.allowed {
+				if !scope.Allows(ns) {
+					t.Errorf("expected namespace %v to be allowed", ns)
+				}
+			}
+			for _, ns := range tt.denied {
+				if scope.Allows(ns) {
+					t.Errorf("expected namespace %v to be denied", ns)
+				}
+			}
+		})
+	}
+}
+
+func TestDistributedVersionsScope(t *testing.T) {
+	defer func(v bool) { features.EnableDistributionTracking = v }(features.EnableDistributionTracking)
+	features.EnableDistributionTracking = true
+	s := &DiscoveryServer{systemNamespace: "istio-system"}
+
+	for resource, want := range map[string]int{
+		"networking.istio.io/v1alpha3/VirtualService/foo/route": http.StatusOK,
+		"networking.istio.io/v1alpha3/VirtualService/bar/route": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/config_distribution?resource="+resource, nil)
+		req = req.WithContext(withDebugIdentities(req.Context(), []string{"spiffe://cluster.local/ns/foo/sa/a"}))
+		rr := httptest.NewRecorder()
+		s.distributedVersions(rr, req)
+		if rr.Code != want {
+			t.Errorf("%v: expected code %d, got %d", resource, want, rr.Code)
+		}
+	}
+}
+
+const debugScopingConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: foo-svc
+  namespace: foo
+spec:
+  hosts:
+  - foo.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  exportTo:
+  - "."
+  endpoints:
+  - address: 10.0.0.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: bar-svc
+  namespace: bar
+spec:
+  hosts:
+  - bar.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  exportTo:
+  - "."
+  endpoints:
+  - address: 10.0.0.2
+`
+
+func TestDebugNamespaceScoping(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: debugScopingConfig})
+	for _, ns := range []string{"foo", "bar"} {
+		ads := s.ConnectADS().WithID("sidecar~1.1.1.1~" + ns + "-proxy." + ns + "~" + ns + ".svc.cluster.local").
+			WithMetadata(model.NodeMetadata{Namespace: ns}).WithType(v3.ClusterType)
+		ads.RequestResponseAck(t, &discovery.DiscoveryRequest{})
+	}
+
+	get := func(handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(withDebugIdentities(req.Context(), []string{"spiffe://cluster.local/ns/foo/sa/a"}))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	t.Run("adsz", func(t *testing.T) {
+		out := &AdsClients{}
+		if err := json.Unmarshal(get(s.Discovery.adsz, "/debug/adsz").Body.Bytes(), out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Total != 1 {
+			t.Fatalf("expected only the proxy of foo, got %+v", out)
+		}
+		if rr := get(s.Discovery.adsz, "/debug/adsz?push=true"); rr.Code != http.StatusForbidden {
+			t.Fatalf("expected mesh-wide pushes to be forbidden, got %d", rr.Code)
+		}
+	})
+	t.Run("configz", func(t *testing.T) {
+		var out []map[string]interface{}
+		if err := json.Unmarshal(get(s.Discovery.configz, "/debug/configz").Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 1 || out[0]["metadata"].(map[string]interface{})["namespace"] != "foo" {
+			t.Fatalf("expected only the configs of foo, got %v", out)
+		}
+	})
+	t.Run("endpointz", func(t *testing.T) {
+		var out []endpointzResponse
+		if err := json.Unmarshal(get(s.Discovery.endpointz, "/debug/endpointz").Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range out {
+			if e.Service == "bar.example.com:http" {
+				t.Fatalf("expected the services not exported to foo to be hidden, got %v", out)
+			}
+		}
+	})
+	t.Run("syncz", func(t *testing.T) {
+		var out []SyncStatus
+		if err := json.Unmarshal(get(s.Discovery.Syncz, "/debug/syncz").Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 1 || out[0].ProxyID != "foo-proxy.foo" {
+			t.Fatalf("expected only the proxy of foo, got %+v", out)
+		}
+	})
+	t.Run("registryz", func(t *testing.T) {
+		var out []map[string]interface{}
+		if err := json.Unmarshal(get(s.Discovery.registryz, "/debug/registryz").Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, svc := range out {
+			switch svc["hostname"] {
+			case "foo.example.com":
+				found = true
+			case "bar.example.com":
+				t.Fatalf("expected the services not exported to foo to be hidden, got %v", out)
+			}
+		}
+		if !found {
+			t.Fatalf("expected the services of foo, got %v", out)
+		}
+	})
+	t.Run("connections", func(t *testing.T) {
+		for _, handler := range []http.HandlerFunc{s.Discovery.ConnectionsHandler, s.Discovery.instancesz} {
+			body := get(handler, "/debug/connections").Body.String()
+			if strings.Contains(body, "bar-proxy") || !strings.Contains(body, "foo-proxy") {
+				t.Fatalf("expected only the proxy of foo, got %v", body)
+			}
+		}
+	})
+	t.Run("namespace parameter", func(t *testing.T) {
+		for path, want := range map[string]int{
+			"/debug/costz":                                       http.StatusForbidden,
+			"/debug/costz?namespace=bar":                         http.StatusForbidden,
+			"/debug/costz?namespace=foo":                         http.StatusOK,
+			"/debug/sidecar_preview?namespace=bar":               http.StatusForbidden,
+			"/debug/push_scopez?config=VirtualService/bar/route": http.StatusForbidden,
+		} {
+			handler := s.Discovery.Costz
+			switch {
+			case strings.HasPrefix(path, "/debug/sidecar_preview"):
+				handler = s.Discovery.sidecarPreview
+			case strings.HasPrefix(path, "/debug/push_scopez"):
+				handler = s.Discovery.pushScopez
+			}
+			if rr := get(handler, path); rr.Code != want {
+				t.Errorf("%v: expected code %d, got %d", path, want, rr.Code)
+			}
+		}
+	})
+	t.Run("mesh-wide", func(t *testing.T) {
+		for path, handler := range map[string]http.HandlerFunc{
+			"/debug/push_status":        s.Discovery.PushStatusHandler,
+			"/debug/pushcontext":        s.Discovery.PushContextHandler,
+			"/debug/cachez":             s.Discovery.cachez,
+			"/debug/telemetryz":         s.Discovery.telemetryz,
+			"/debug/mesh":               s.Discovery.MeshHandler,
+			"/debug/listener_conflicts": s.Discovery.listenerConflictsz,
+			"/debug/resourcesz":         s.Discovery.resourcez,
+		} {
+			if rr := get(handler, path); rr.Code != http.StatusForbidden {
+				t.Errorf("%v: expected mesh-wide information to be forbidden, got %d", path, rr.Code)
+			}
+		}
+	})
+	t.Run("proxy handlers", func(t *testing.T) {
+		if rr := get(s.Discovery.ConfigDump, "/debug/config_dump?proxyID=foo-proxy.foo"); rr.Code != http.StatusOK {
+			t.Fatalf("expected the proxy of foo to be readable, got %d", rr.Code)
+		}
+		if rr := get(s.Discovery.ConfigDump, "/debug/config_dump?proxyID=bar-proxy.bar"); rr.Code != http.StatusForbidden {
+			t.Fatalf("expected the proxy of bar to be forbidden, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// defaultArchiveSample is the number of proxies whose config dump is included in a state archive by default.
+const defaultArchiveSample = 10
+
+// StateArchive writes a gzipped tar archive of the state of this istiod to out, for offline analysis. It holds the
+// mesh config and networks (mesh.json, meshnetworks.json), all Istio configs as Kubernetes objects (configs.json),
+// the services and endpoint shards of the registries (registry/), the status of the last push (push_status.json),
+// the connected proxies (connections.json) and the config dump of the first sample of them
+// (proxies/<proxy ID>/config_dump.json).
+// Unlike collecting the same data from the proxies, this needs no access to the nodes of the cluster.
+func (s *DiscoveryServer) StateArchive(out io.Writer, sample int) error {
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		return nil
+	}
+	addJSON := func(name string, obj interface{}) error {
+		by, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %v", name, err)
+		}
+		return add(name, by)
+	}
+	addProto := func(name string, msg proto.Message) error {
+		buf := &bytes.Buffer{}
+		if err := (&jsonpb.Marshaler{Indent: "  "}).Marshal(buf, msg); err != nil {
+			return fmt.Errorf("failed to marshal %s: %v", name, err)
+		}
+		return add(name, buf.Bytes())
+	}
+
+	if err := addProto("mesh.json", s.Env.Mesh()); err != nil {
+		return err
+	}
+	if networks := s.Env.Networks(); networks != nil {
+		if err := addProto("meshnetworks.json", networks); err != nil {
+			return err
+		}
+	}
+
+	configs := make([]kubernetesConfig, 0)
+	s.Env.IstioConfigStore.Schemas().ForEach(func(schema collection.Schema) bool {
+		cfg, _ := s.Env.IstioConfigStore.List(schema.Resource().GroupVersionKind(), "")
+		for _, c := range cfg {
+			configs = append(configs, kubernetesConfig{c})
+		}
+		return false
+	})
+	if err := addJSON("configs.json", configs); err != nil {
+		return err
+	}
+
+	services, err := s.Env.ServiceDiscovery.Services()
+	if err != nil {
+		return fmt.Errorf("failed to list services: %v", err)
+	}
+	if err := addJSON("registry/services.json", services); err != nil {
+		return err
+	}
+	s.mutex.RLock()
+	shards, err := json.MarshalIndent(s.EndpointShardsByService, "", "  ")
+	s.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal endpoint shards: %v", err)
+	}
+	if err := add("registry/endpoint_shards.json", shards); err != nil {
+		return err
+	}
+
+	status, err := s.globalPushContext().StatusJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal push status: %v", err)
+	}
+	if err := add("push_status.json", status); err != nil {
+		return err
+	}
+
+	if err := addJSON("connections.json", s.adsClients(DebugScope{all: true})); err != nil {
+		return err
+	}
+	clients := s.Clients()
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ConID < clients[j].ConID
+	})
+	if len(clients) > sample {
+		clients = clients[:sample]
+	}
+	for _, con := range clients {
+		dump, err := s.configDump(con)
+		if err != nil {
+			return fmt.Errorf("failed to dump config of %s: %v", con.proxy.ID, err)
+		}
+		if err := addProto("proxies/"+strings.ReplaceAll(con.proxy.ID, "/", "_")+"/config_dump.json", dump); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// StateArchiveHandler serves StateArchive, including the config dump of sample (10 by default) proxies.
+// It is mapped to /debug/state_archive.
+func (s *DiscoveryServer) StateArchiveHandler(w http.ResponseWriter, req *http.Request) {
+	// The archive holds the state of all the namespaces.
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
+	sample := defaultArchiveSample
+	if v := req.URL.Query().Get("sample"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid sample %q\n", v)
+			return
+		}
+		sample = n
+	}
+	// Build the archive before writing it, so that failures are reported with an error status.
+	buf := &bytes.Buffer{}
+	if err := s.StateArchive(buf, sample); err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="istiod-state-%s.tar.gz"`, time.Now().UTC().Format("20060102T150405Z")))
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,245 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/sets"
+)
+
+const (
+	// DebugEventPush is published for every response sent to a proxy.
+	DebugEventPush = "push"
+	// DebugEventConnect is published when a proxy connects.
+	DebugEventConnect = "connect"
+	// DebugEventDisconnect is published when a proxy disconnects.
+	DebugEventDisconnect = "disconnect"
+	// DebugEventNack is published when a proxy rejects a response.
+	DebugEventNack = "nack"
+
+	// debugEventsBuffer is the number of events buffered for a subscriber. The events published while the buffer
+	// of a slow subscriber is full are dropped for this subscriber.
+	debugEventsBuffer = 256
+	// debugEventsKeepalive is the interval of the comments sent to idle subscribers, to keep the stream open
+	// through proxies and load balancers.
+	debugEventsKeepalive = 15 * time.Second
+)
+
+// DebugEvent is an event streamed by /debug/events.
+type DebugEvent struct {
+	Type         string                `json:"type"`
+	Time         time.Time             `json:"time"`
+	ProxyID      string                `json:"proxyID,omitempty"`
+	ConnectionID string                `json:"connectionID,omitempty"`
+	Namespace    string                `json:"namespace,omitempty"`
+	TypeURL      string                `json:"typeURL,omitempty"`
+	Version      string                `json:"version,omitempty"`
+	Nonce        string                `json:"nonce,omitempty"`
+	Resources    int                   `json:"resources,omitempty"`
+	Incremental  bool                  `json:"incremental,omitempty"`
+	Reasons      []model.TriggerReason `json:"reasons,omitempty"`
+	Error        string                `json:"error,omitempty"`
+}
+
+// debugEventSubscriber is a client of /debug/events.
+type debugEventSubscriber struct {
+	events  chan DebugEvent
+	dropped *atomic.Int64
+}
+
+// debugEvents fans out the push, connection and NACK events to the clients of /debug/events.
+type debugEvents struct {
+	mu          sync.RWMutex
+	subscribers map[*debugEventSubscriber]struct{}
+	// active is the number of subscribers, checked before building the events so that
+	// publishing is free when nobody is watching.
+	active *atomic.Int32
+}
+
+func newDebugEvents() *debugEvents {
+	return &debugEvents{
+		subscribers: map[*debugEventSubscriber]struct{}{},
+		active:      atomic.NewInt32(0),
+	}
+}
+
+func (d *debugEvents) subscribe() *debugEventSubscriber {
+	sub := &debugEventSubscriber{
+		events:  make(chan DebugEvent, debugEventsBuffer),
+		dropped: atomic.NewInt64(0),
+	}
+	d.mu.Lock()
+	d.subscribers[sub] = struct{}{}
+	d.active.Store(int32(len(d.subscribers)))
+	d.mu.Unlock()
+	return sub
+}
+
+func (d *debugEvents) unsubscribe(sub *debugEventSubscriber) {
+	d.mu.Lock()
+	delete(d.subscribers, sub)
+	d.active.Store(int32(len(d.subscribers)))
+	d.mu.Unlock()
+}
+
+// enabled returns whether any client is watching the events.
+func (d *debugEvents) enabled() bool {
+	return d != nil && d.active.Load() > 0
+}
+
+// publish sends the event to all the subscribers, without blocking on the slow ones.
+func (d *debugEvents) publish(ev DebugEvent) {
+	if !d.enabled() {
+		return
+	}
+	ev.Time = time.Now()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for sub := range d.subscribers {
+		select {
+		case sub.events <- ev:
+		default:
+			sub.dropped.Inc()
+		}
+	}
+}
+
+// publishPush publishes the push of a response of resources to a connection.
+func (d *debugEvents) publishPush(con *Connection, typeURL, version, nonce string, resources int, incremental bool,
+	req *model.PushRequest) {
+	if !d.enabled() {
+		return
+	}
+	ev := DebugEvent{
+		Type:         DebugEventPush,
+		ConnectionID: con.ConID,
+		TypeURL:      typeURL,
+		Version:      version,
+		Nonce:        nonce,
+		Resources:    resources,
+		Incremental:  incremental,
+	}
+	if con.proxy != nil {
+		ev.ProxyID = con.proxy.ID
+		ev.Namespace = con.proxy.ConfigNamespace
+	}
+	if req != nil {
+		ev.Reasons = req.Reason
+	}
+	d.publish(ev)
+}
+
+// publishConnection publishes a connection event of the type.
+func (d *debugEvents) publishConnection(eventType string, con *Connection) {
+	if !d.enabled() {
+		return
+	}
+	ev := DebugEvent{
+		Type:         eventType,
+		ConnectionID: con.ConID,
+	}
+	if con.proxy != nil {
+		ev.ProxyID = con.proxy.ID
+		ev.Namespace = con.proxy.ConfigNamespace
+	}
+	d.publish(ev)
+}
+
+// debugEventFilter selects the events streamed to a client of /debug/events.
+type debugEventFilter struct {
+	scope   DebugScope
+	types   sets.Set
+	proxyID string
+}
+
+func (f debugEventFilter) matches(ev DebugEvent) bool {
+	if len(f.types) > 0 && !f.types.Contains(ev.Type) {
+		return false
+	}
+	if f.proxyID != "" && f.proxyID != ev.ProxyID {
+		return false
+	}
+	return f.scope.Allows(ev.Namespace)
+}
+
+// eventsz streams the push, connection and NACK events as Server-Sent Events, until the client goes away.
+// The events may be filtered by a comma separated list of types and by proxy ID.
+func (s *DiscoveryServer) eventsz(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("streaming is not supported by the connection\n"))
+		return
+	}
+	filter := debugEventFilter{
+		scope:   s.DebugScopeFor(req),
+		types:   sets.NewSet(),
+		proxyID: req.URL.Query().Get("proxyID"),
+	}
+	if types := req.URL.Query().Get("types"); types != "" {
+		filter.types.Insert(strings.Split(types, ",")...)
+	}
+
+	sub := s.debugEvents.subscribe()
+	defer s.debugEvents.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(debugEventsKeepalive)
+	defer keepalive.Stop()
+	reported := int64(0)
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case ev := <-sub.events:
+			if !filter.matches(ev) {
+				continue
+			}
+			if dropped := sub.dropped.Load(); dropped > reported {
+				// Let the client know it missed events, rather than silently skipping them.
+				if _, err := fmt.Fprintf(w, ": dropped %d events\n\n", dropped-reported); err != nil {
+					return
+				}
+				reported = dropped
+			}
+			by, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, by); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestDebugEvents(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, nil, false, nil)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The subscription is registered before the headers are sent.
+	resp, err := http.Get(server.URL + "/debug/events?types=connect,push,nack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	events := make(chan xds.DebugEvent, 100)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if !strings.HasPrefix(scanner.Text(), "data: ") {
+				continue
+			}
+			var ev xds.DebugEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(scanner.Text(), "data: ")), &ev); err == nil {
+				events <- ev
+			}
+		}
+	}()
+
+	ads := s.ConnectADS()
+	ads.RequestResponseNack(t, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+	node, _ := model.ParseServiceNodeWithMetadata(ads.ID, &model.NodeMetadata{})
+
+	expected := []string{xds.DebugEventConnect, xds.DebugEventPush, xds.DebugEventNack}
+	timeout := time.After(5 * time.Second)
+	for _, want := range expected {
+		select {
+		case ev := <-events:
+			if ev.Type != want {
+				t.Fatalf("expected %s event, got %+v", want, ev)
+			}
+			if ev.ProxyID != node.ID {
+				t.Fatalf("expected event of proxy %s, got %+v", node.ID, ev)
+			}
+			if want != xds.DebugEventConnect && ev.TypeURL != v3.ClusterType {
+				t.Fatalf("expected event of type %s, got %+v", v3.ClusterType, ev)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s event", want)
+		}
+	}
+}
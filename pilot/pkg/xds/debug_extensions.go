@@ -0,0 +1,71 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DebugExtensionPrefix is the path under which the debug handlers registered with AddDebugHandler are served.
+const DebugExtensionPrefix = "/debug/ext/"
+
+var debugExtensionName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+type debugExtension struct {
+	help    string
+	handler http.Handler
+}
+
+// AddDebugHandler registers a debug handler served at /debug/ext/<name>, for extensions and downstream
+// distributions. The handler is listed next to the built-in debug handlers and requires the same
+// authentication. Handlers can be added at any time, before or after the debug server is started.
+func (s *DiscoveryServer) AddDebugHandler(name, help string, handler http.Handler) error {
+	if !debugExtensionName.MatchString(name) {
+		return fmt.Errorf("invalid debug handler name %q", name)
+	}
+	s.debugExtensionsMutex.Lock()
+	defer s.debugExtensionsMutex.Unlock()
+	if _, f := s.debugExtensions[name]; f {
+		return fmt.Errorf("debug handler %q already registered", name)
+	}
+	s.debugExtensions[name] = debugExtension{help: help, handler: handler}
+	return nil
+}
+
+// debugExtensionHelp returns the help of each registered extension, keyed by path.
+func (s *DiscoveryServer) debugExtensionHelp() map[string]string {
+	s.debugExtensionsMutex.RLock()
+	defer s.debugExtensionsMutex.RUnlock()
+	out := make(map[string]string, len(s.debugExtensions))
+	for name, ext := range s.debugExtensions {
+		out[DebugExtensionPrefix+name] = ext.help
+	}
+	return out
+}
+
+func (s *DiscoveryServer) serveDebugExtension(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, DebugExtensionPrefix)
+	s.debugExtensionsMutex.RLock()
+	ext, f := s.debugExtensions[name]
+	s.debugExtensionsMutex.RUnlock()
+	if !f {
+		http.NotFound(w, req)
+		return
+	}
+	ext.handler.ServeHTTP(w, req)
+}
@@ -0,0 +1,171 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+// DebugCommand describes a debug command, so that clients such as istioctl can build requests for it
+// without hardcoding knowledge of each endpoint. It is returned by /debug/list?schema=true.
+type DebugCommand struct {
+	Name string `json:"name"`
+	Help string `json:"help"`
+	// Params are the query parameters accepted by the command.
+	Params []DebugParam `json:"params,omitempty"`
+	// RequiresProxyID is true if the command operates on a single proxy, selected with the proxyID parameter.
+	RequiresProxyID bool `json:"requiresProxyID,omitempty"`
+	// Mutating is true if the command, or one of its parameters, changes the state of istiod or of connected proxies.
+	Mutating bool `json:"mutating,omitempty"`
+	// Example is an example request URL.
+	Example string `json:"example"`
+}
+
+// DebugParam describes a query parameter of a debug command.
+type DebugParam struct {
+	Name     string `json:"name"`
+	Help     string `json:"help"`
+	Required bool   `json:"required,omitempty"`
+}
+
+var (
+	proxyIDParam = DebugParam{Name: "proxyID", Help: "The proxy to inspect, as <pod>.<namespace>", Required: true}
+	pushParam    = DebugParam{Name: "push", Help: "If set, trigger a full push to all proxies instead"}
+)
+
+// debugCommandSchemas holds the parameters of the built-in debug commands which accept any, keyed by command name.
+var debugCommandSchemas = map[string]DebugCommand{
+	"adsz": {
+		Params:   []DebugParam{pushParam},
+		Mutating: true,
+	},
+	"edsz": {
+		Params:          []DebugParam{proxyIDParam, pushParam},
+		RequiresProxyID: true,
+		Mutating:        true,
+	},
+	"ndsz": {
+		Params:          []DebugParam{proxyIDParam, pushParam},
+		RequiresProxyID: true,
+		Mutating:        true,
+	},
+	"config_dump": {
+		Params:          []DebugParam{proxyIDParam},
+		RequiresProxyID: true,
+	},
+	"sidecarz": {
+		Params:          []DebugParam{proxyIDParam},
+		RequiresProxyID: true,
+	},
+	"interceptionz": {
+		Params:          []DebugParam{proxyIDParam},
+		RequiresProxyID: true,
+	},
+	"automtlsz": {
+		Params: []DebugParam{
+			proxyIDParam,
+			{Name: "host", Help: "The hostname of the destination service", Required: true},
+		},
+		RequiresProxyID: true,
+	},
+	"sidecar_preview": {
+		Params: []DebugParam{
+			{Name: "namespace", Help: "The namespace of the workload", Required: true},
+			{Name: "labels", Help: "The labels of the workload, as <key>=<value>,..."},
+		},
+	},
+	"push_scopez": {
+		Params: []DebugParam{
+			{Name: "config", Help: "The changed config, as <kind>/<namespace>/<name>", Required: true},
+		},
+	},
+	"bootstrapz": {
+		Params:          []DebugParam{proxyIDParam},
+		RequiresProxyID: true,
+	},
+	"force_disconnect": {
+		Params:          []DebugParam{proxyIDParam},
+		RequiresProxyID: true,
+		Mutating:        true,
+	},
+	"cachez": {
+		Params: []DebugParam{{Name: "sizes", Help: "If set, report the size of the caches"}},
+	},
+	"endpointz": {
+		Params: []DebugParam{{Name: "brief", Help: "If set, only list the endpoint addresses"}},
+	},
+	"inject": {
+		Params: []DebugParam{
+			{Name: "template", Help: "Only return the named template. Pods posted to this command are injected instead"},
+			{Name: "namespace", Help: "The namespace of a posted pod which does not specify one. Defaults to default"},
+		},
+	},
+	"mesh": {
+		Params: []DebugParam{{Name: "effective", Help: "Apply the mesh config overlay of this namespace"}},
+	},
+	"sizez": {
+		Params: []DebugParam{
+			{Name: "k", Help: "The number of configs to list. Defaults to 10"},
+			{Name: "type", Help: "Only report configs of this type, such as RDS"},
+		},
+	},
+	"costz": {
+		Params: []DebugParam{
+			{Name: "namespace", Help: "Only report the pushes to the proxies of this namespace"},
+			{Name: "by", Help: "Aggregate the costs by namespace or kind"},
+		},
+	},
+	"ineffective_policies": {
+		Params: []DebugParam{{Name: "namespace", Help: "Only return the policies of this namespace"}},
+	},
+	"push_latencyz": {
+		Params: []DebugParam{{Name: "proxyID", Help: "Only report the responses pushed to this proxy since it connected"}},
+	},
+	"dependenciesz": {
+		Params: []DebugParam{
+			{Name: "kind", Help: "Only report the dependencies of this kind: jwks, external_ca, remote_cluster or config_source"},
+			{Name: "unhealthy", Help: "If set, only report the dependencies whose last call failed"},
+		},
+	},
+	"analyzez": {
+		Params: []DebugParam{
+			{Name: "namespace", Help: "Only return the messages about the resources of this namespace"},
+			{Name: "level", Help: "Only return the messages at or above this level: Info, Warning or Error"},
+		},
+	},
+	"route_aliases": {
+		Params: []DebugParam{{Name: "name", Help: "Return the current name of the route configuration with this name"}},
+	},
+	"state_archive": {
+		Params: []DebugParam{{Name: "sample", Help: "The number of proxies to include the config dump of. Defaults to 10"}},
+	},
+	"config_distribution": {
+		Params: []DebugParam{
+			{Name: "resource", Help: "The resource to report the acked version of, as <group>/<version>/<kind>/<namespace>/<name>"},
+			{Name: "proxy_namespace", Help: "Only report proxies in this namespace"},
+			{Name: "max_versions", Help: "Without resource, the number of versions a proxy may be behind before it is stale"},
+			{Name: "max_seconds", Help: "Without resource, the number of seconds a proxy may be behind before it is stale"},
+			{Name: "all", Help: "Without resource, list all proxies rather than only stale ones, if true"},
+		},
+	},
+}
+
+// debugCommand returns the description of a debug command.
+func debugCommand(name, help string) DebugCommand {
+	cmd := debugCommandSchemas[name]
+	cmd.Name = name
+	cmd.Help = help
+	cmd.Example = "/debug/" + name
+	if cmd.RequiresProxyID {
+		cmd.Example += "?proxyID=<pod>.<namespace>"
+	}
+	return cmd
+}
@@ -15,18 +15,27 @@
 package xds_test
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	corev1 "k8s.io/api/core/v1"
 
 	"istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/test/util/retry"
 )
 
 func TestSyncz(t *testing.T) {
@@ -213,3 +222,349 @@ func TestDebugHandlers(t *testing.T) {
 		t.Errorf("Error in generatating debug endpoint list")
 	}
 }
+
+func TestDebugExtensions(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("extension"))
+	})
+	if err := s.Discovery.AddDebugHandler("my-ext", "An extension", handler); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Discovery.AddDebugHandler("my-ext", "Again", handler); err == nil {
+		t.Fatalf("expected duplicate registration to fail")
+	}
+	if err := s.Discovery.AddDebugHandler("../edsz", "Invalid", handler); err == nil {
+		t.Fatalf("expected invalid name to fail")
+	}
+
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, nil, false, nil)
+	get := func(path, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remote
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := get("/debug/ext/my-ext", "127.0.0.1:1234"); rr.Code != 200 || rr.Body.String() != "extension" {
+		t.Fatalf("unexpected response %v: %v", rr.Code, rr.Body.String())
+	}
+	if rr := get("/debug/ext/unknown", "127.0.0.1:1234"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %v", rr.Code)
+	}
+	// Remote requests need to be authenticated, as for the built-in handlers.
+	if rr := get("/debug/ext/my-ext", "10.0.0.1:1234"); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %v", rr.Code)
+	}
+
+	var cmds []string
+	if err := json.Unmarshal(get("/debug/list", "127.0.0.1:1234").Body.Bytes(), &cmds); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, c := range cmds {
+		if c == "ext/my-ext" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected extension to be listed, got %v", cmds)
+	}
+}
+
+func TestListSchemas(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, nil, false, nil)
+	req := httptest.NewRequest("GET", "/debug/list?schema=true", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var cmds []xds.DebugCommand
+	if err := json.Unmarshal(rr.Body.Bytes(), &cmds); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]xds.DebugCommand{}
+	for _, c := range cmds {
+		byName[c.Name] = c
+	}
+	if _, f := byName["adsz?push=true"]; f {
+		t.Errorf("expected query variants to be omitted")
+	}
+	dump, f := byName["config_dump"]
+	if !f || !dump.RequiresProxyID || dump.Mutating || dump.Example != "/debug/config_dump?proxyID=<pod>.<namespace>" {
+		t.Errorf("unexpected config_dump schema: %+v", dump)
+	}
+	if adsz := byName["adsz"]; !adsz.Mutating || len(adsz.Params) != 1 || adsz.Params[0].Name != "push" {
+		t.Errorf("unexpected adsz schema: %+v", adsz)
+	}
+}
+
+func TestInjectTemplateHandler(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	s.Discovery.InjectDryRun = func(pod *corev1.Pod, namespace string) (*inject.DryRunResult, error) {
+		pod.Namespace = namespace
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "istio-proxy"})
+		return &inject.DryRunResult{Injected: true, Templates: []string{"sidecar"}, Pod: pod}, nil
+	}
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, nil, false, func() map[string]string {
+		return map[string]string{"sidecar": "sidecar template", "gvisor": "gvisor template"}
+	})
+	serve := func(method, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:1234"
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var templates map[string]string
+	if err := json.Unmarshal(serve("GET", "/debug/inject?template=gvisor", "").Body.Bytes(), &templates); err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates["gvisor"] != "gvisor template" {
+		t.Errorf("unexpected templates: %v", templates)
+	}
+	if rr := serve("GET", "/debug/inject?template=unknown", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected not found for unknown template, got %v", rr.Code)
+	}
+
+	rr := serve("POST", "/debug/inject?namespace=test", "metadata:\n  name: hello\nspec:\n  containers:\n  - name: hello\n")
+	var result inject.DryRunResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("%v: %s", err, rr.Body.String())
+	}
+	if !result.Injected || result.Pod.Name != "hello" || result.Pod.Namespace != "test" || len(result.Pod.Spec.Containers) != 2 {
+		t.Errorf("unexpected dry run result: %+v", result)
+	}
+	if rr := serve("POST", "/debug/inject", "spec: [invalid"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request for invalid pod, got %v", rr.Code)
+	}
+}
+
+func TestSizez(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	ads := s.ConnectADS()
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ListenerType})
+
+	sizez := func(query string) xds.ConfigSizeReport {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.Discovery.Sizez(rr, httptest.NewRequest("GET", "/debug/sizez"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+		}
+		out := xds.ConfigSizeReport{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	report := sizez("")
+	if len(report.Largest) != 2 || report.TotalBytes != report.Largest[0].Bytes+report.Largest[1].Bytes {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Largest[0].Bytes < report.Largest[1].Bytes {
+		t.Fatalf("expected configs ordered by size: %+v", report.Largest)
+	}
+	if report := sizez("?type=cds&k=1"); len(report.Largest) != 1 || report.Largest[0].Type != "CDS" {
+		t.Fatalf("expected only clusters: %+v", report)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Discovery.Sizez(rr, httptest.NewRequest("GET", "/debug/sizez?k=0", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid k to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestConnectionsPushHistory(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	ads.RequestResponseAck(t, nil)
+
+	s.Discovery.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.DestinationRule, Name: "dr", Namespace: "default"}: {}},
+		Reason:         []model.TriggerReason{model.ConfigUpdate},
+	})
+	ads.ExpectResponse(t)
+
+	retry.UntilSuccessOrFail(t, func() error {
+		rr := httptest.NewRecorder()
+		s.Discovery.ConnectionsHandler(rr, httptest.NewRequest("GET", "/debug/connections", nil))
+		out := xds.AdsClients{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+			return err
+		}
+		if len(out.Connected) != 1 {
+			return fmt.Errorf("expected one connection, got %+v", out)
+		}
+		pushes := out.Connected[0].Pushes
+		if len(pushes) != 2 {
+			return fmt.Errorf("expected the initial response and the push, got %+v", pushes)
+		}
+		if pushes[0].Reasons[model.ProxyRequest] != 1 {
+			return fmt.Errorf("expected the initial response to be a proxy request, got %+v", pushes[0])
+		}
+		push := pushes[1]
+		if !push.Full || push.Reasons[model.ConfigUpdate] != 1 || !reflect.DeepEqual(push.ConfigKinds, []string{"DestinationRule"}) ||
+			!reflect.DeepEqual(push.Configs, []string{"DestinationRule/default/dr"}) || !reflect.DeepEqual(push.Types, []string{"CDS"}) {
+			return fmt.Errorf("unexpected push record %+v", push)
+		}
+		return nil
+	})
+}
+
+func TestStateArchive(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	for i := 0; i < 2; i++ {
+		ads := s.ConnectADS()
+		ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+	}
+
+	rr := httptest.NewRecorder()
+	s.Discovery.StateArchiveHandler(rr, httptest.NewRequest("GET", "/debug/state_archive?sample=1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+	gz, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]bool{}
+	dumps := 0
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[h.Name] = true
+		if strings.HasPrefix(h.Name, "proxies/") {
+			dumps++
+		}
+	}
+	for _, f := range []string{"mesh.json", "configs.json", "registry/services.json", "push_status.json", "connections.json"} {
+		if !files[f] {
+			t.Errorf("archive is missing %s: %v", f, files)
+		}
+	}
+	if dumps != 1 {
+		t.Errorf("expected the config dump of 1 proxy, got %d: %v", dumps, files)
+	}
+
+	rr = httptest.NewRecorder()
+	s.Discovery.StateArchiveHandler(rr, httptest.NewRequest("GET", "/debug/state_archive?sample=-1", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid sample to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestAutoMtlsz(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: mixed
+  namespace: default
+spec:
+  hosts:
+  - mixed.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  location: MESH_INTERNAL
+  endpoints:
+  - address: 10.0.0.1
+    labels:
+      app: onboarded
+      security.istio.io/tlsMode: istio
+  - address: 10.0.0.2
+    labels:
+      app: disabled
+      security.istio.io/tlsMode: istio
+  - address: 10.0.0.3
+    labels:
+      app: plaintext
+---
+apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: istio-system
+spec:
+  mtls:
+    mode: DISABLE
+---
+apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: onboarded
+  namespace: istio-system
+spec:
+  selector:
+    matchLabels:
+      app: onboarded
+  mtls:
+    mode: STRICT
+`})
+	ads := s.ConnectADS()
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, nil, false, nil)
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/debug/automtlsz"+query, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := get("?proxyID=test.default"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a missing host to be rejected, got %d", rr.Code)
+	}
+	if rr := get("?proxyID=test.default&host=unknown.example.com"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown host not to be found, got %d", rr.Code)
+	}
+
+	rr := get("?proxyID=test.default&host=mixed.example.com")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+	var clusters []xds.AutoMtlsCluster
+	if err := json.Unmarshal(rr.Body.Bytes(), &clusters); err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("expected a single cluster, got %s", rr.Body.String())
+	}
+	c := clusters[0]
+	if c.Cluster != "outbound|80||mixed.example.com" || c.Mode != "ISTIO_MUTUAL" || !c.PerEndpoint ||
+		c.ServiceMTLSMode != model.MTLSDisable.String() || len(c.PeerAuthentications) != 1 {
+		t.Fatalf("unexpected cluster explanation: %s", rr.Body.String())
+	}
+	got := map[string]bool{}
+	for _, ep := range c.Endpoints {
+		got[ep.Address] = ep.Mtls
+		if ep.Mtls != (ep.Reason == "") {
+			t.Errorf("expected a reason only for plaintext endpoints: %+v", ep)
+		}
+	}
+	expected := map[string]bool{"10.0.0.1": true, "10.0.0.2": false, "10.0.0.3": false}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got endpoints %v, expected %v", got, expected)
+	}
+}
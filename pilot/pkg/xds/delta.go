@@ -54,6 +54,9 @@ func (s *DiscoveryServer) StreamDeltas(stream DeltaDiscoveryStream) error {
 	if !s.IsServerReady() {
 		return errors.New("server is not ready to serve discovery information")
 	}
+	if err := s.shedConnection(stream); err != nil {
+		return err
+	}
 
 	ctx := stream.Context()
 	peerAddr := "0.0.0.0"
@@ -97,6 +100,8 @@ func (s *DiscoveryServer) StreamDeltas(stream DeltaDiscoveryStream) error {
 	// initialization is complete.
 	<-con.initialized
 
+	deadline, stopDeadline := streamDeadline(features.XDSStreamMaxDuration)
+	defer stopDeadline()
 	for {
 		select {
 		case req, ok := <-con.deltaReqChan:
@@ -117,8 +122,10 @@ func (s *DiscoveryServer) StreamDeltas(stream DeltaDiscoveryStream) error {
 			if err != nil {
 				return err
 			}
+		case <-deadline:
+			return streamDeadlineExceeded(con)
 		case <-con.stop:
-			return nil
+			return con.stopStatus(stream)
 		}
 	}
 }
@@ -127,6 +134,7 @@ func (s *DiscoveryServer) StreamDeltas(stream DeltaDiscoveryStream) error {
 // for large configs. The method will hold a lock on con.pushMutex.
 func (s *DiscoveryServer) pushConnectionDelta(con *Connection, pushEv *Event) error {
 	pushRequest := pushEv.pushRequest
+	s.Failpoints.delay(FailpointDelayPush, con.proxy, "")
 
 	if pushRequest.Full {
 		// Update Proxy with current information.
@@ -241,7 +249,7 @@ func (s *DiscoveryServer) receiveDelta(con *Connection) {
 func (conn *Connection) sendDelta(res *discovery.DeltaDiscoveryResponse) error {
 	sendHandler := func() error {
 		start := time.Now()
-		defer func() { recordSendTime(time.Since(start)) }()
+		defer func() { recordSendTime(res.TypeUrl, time.Since(start)) }()
 		return conn.deltaStream.Send(res)
 	}
 	err := istiogrpc.Send(conn.deltaStream.Context(), sendHandler)
@@ -275,6 +283,9 @@ func (s *DiscoveryServer) processDeltaRequest(req *discovery.DeltaDiscoveryReque
 	if !s.shouldProcessRequest(con.proxy, deltaToSotwRequest(req)) {
 		return nil
 	}
+	if s.Failpoints.dropAck(con.proxy, req.TypeUrl, req.ResponseNonce) {
+		return nil
+	}
 	if strings.HasPrefix(req.TypeUrl, v3.DebugType) {
 		return s.pushXds(con, s.globalPushContext(), versionInfo(), &model.WatchedResource{
 			TypeUrl: req.TypeUrl, ResourceNames: req.ResourceNamesSubscribe,
@@ -328,6 +339,7 @@ func (s *DiscoveryServer) shouldRespondDelta(con *Connection, request *discovery
 		if s.StatusGen != nil {
 			s.StatusGen.OnNack(con.proxy, deltaToSotwRequest(request))
 		}
+		s.Events.ProxyRejected(con.proxy, stype, request.ErrorDetail.GetMessage())
 		con.proxy.Lock()
 		con.proxy.WatchedResources[request.TypeUrl].NonceNacked = request.ResponseNonce
 		con.proxy.Unlock()
@@ -416,8 +428,21 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 
 	t0 := time.Now()
 
-	res, logdata, err := gen.Generate(con.proxy, push, w, req)
-	if err != nil || res == nil {
+	var res model.Resources
+	var removed []string
+	var logdata model.XdsLogDetails
+	var err error
+	s.Failpoints.delay(FailpointSlowGenerator, con.proxy, w.TypeUrl)
+	deltaGen, computesDeltas := gen.(model.XdsDeltaResourceGenerator)
+	if computesDeltas {
+		res, removed, logdata, err = deltaGen.GenerateDeltas(con.proxy, push, w, req)
+	} else {
+		res, logdata, err = gen.Generate(con.proxy, push, w, req)
+	}
+	if err != nil {
+		s.reportPushError(con, w.TypeUrl, err)
+	}
+	if err != nil || (res == nil && removed == nil) {
 		// If we have nothing to send, report that we got an ACK for this version.
 		if s.StatusReporter != nil {
 			s.StatusReporter.RegisterEvent(con.ConID, w.TypeUrl, push.LedgerVersion)
@@ -427,13 +452,13 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 	defer func() { recordPushTime(w.TypeUrl, time.Since(t0)) }()
 
 	originalNames := extractNames(res)
-	if subscribe != nil {
+	if subscribe != nil && !computesDeltas {
 		// If subscribe is set, client is requesting specific resources. We should just give it the
 		// new resources it needs, rather than the entire set of known resources.
 		subres := sets.NewSet(subscribe...)
 		filteredResponse := []*discovery.Resource{}
 		for _, r := range res {
-			if subres.Contains(r.Name) {
+			if subres.Contains(r.Name) || requestedByAlias(subres, r) {
 				filteredResponse = append(filteredResponse, r)
 			} else {
 				log.Debugf("ADS:%v SKIP %v", v3.GetShortType(w.TypeUrl), r.Name)
@@ -441,6 +466,7 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 		}
 		res = filteredResponse
 	}
+	sortResources(w.TypeUrl, res)
 	resp := &discovery.DeltaDiscoveryResponse{
 		ControlPlane:      ControlPlane(),
 		TypeUrl:           w.TypeUrl,
@@ -448,11 +474,15 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 		Nonce:             nonce(push.LedgerVersion),
 		Resources:         res,
 	}
-	// We take the set of watched resources and anything not in the response is sent as RemovedResources
-	// This is similar to SotW, but done on the server side instead of the client.
-	cur := sets.NewSet(w.ResourceNames...)
-	cur.Delete(originalNames...)
-	resp.RemovedResources = cur.SortedList()
+	if computesDeltas {
+		resp.RemovedResources = removed
+	} else {
+		// We take the set of watched resources and anything not in the response is sent as RemovedResources
+		// This is similar to SotW, but done on the server side instead of the client.
+		cur := sets.NewSet(w.ResourceNames...)
+		cur.Delete(originalNames...)
+		resp.RemovedResources = cur.SortedList()
+	}
 	if len(resp.RemovedResources) > 0 {
 		log.Infof("ADS:%v REMOVE %v", v3.GetShortType(w.TypeUrl), resp.RemovedResources)
 	}
@@ -465,11 +495,21 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 
 	configSize := ResourceSize(res)
 	configSizeBytes.With(typeTag.Value(w.TypeUrl)).Record(float64(configSize))
-
-	if err := con.sendDelta(resp); err != nil {
+	generated := time.Since(t0)
+	s.pushCosts.record(con.proxy.ConfigNamespace, s.revision(), req, generated, configSize)
+
+	restoreNonce := s.Failpoints.corruptNonce(con, w.TypeUrl, &resp.Nonce)
+	sendStart := time.Now()
+	err = con.sendDelta(resp)
+	restoreNonce()
+	s.recordPushLatency(con, w.TypeUrl, generated, time.Since(sendStart), configSize)
+	if err != nil {
 		recordSendError(w.TypeUrl, con.ConID, err)
+		s.reportPushError(con, w.TypeUrl, err)
 		return err
 	}
+	s.debugEvents.publishPush(con, w.TypeUrl, resp.SystemVersionInfo, resp.Nonce, len(res), logdata.Incremental, req)
+	con.recordPush(w.TypeUrl, req)
 
 	ptype := "PUSH"
 	info := ""
@@ -492,8 +532,8 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 			// Add additional information to logs when debug mode enabled.
 			debug = " nonce:" + resp.Nonce + " version:" + resp.SystemVersionInfo
 		}
-		log.Infof("%s: %s for node:%s resources:%d size:%v%s%s", v3.GetShortType(w.TypeUrl), ptype, con.proxy.ID, len(res),
-			util.ByteCount(ResourceSize(res)), info, debug)
+		log.Infof("%s: %s for node:%s resources:%d size:%v%s%s%s", v3.GetShortType(w.TypeUrl), ptype, con.proxy.ID, len(res),
+			util.ByteCount(ResourceSize(res)), info, pushReasons(req), debug)
 	}
 
 	return nil
@@ -532,14 +572,25 @@ func deltaWatchedResources(existing []string, request *discovery.DeltaDiscoveryR
 	return res.SortedList()
 }
 
+// extractNames returns the names of the resources, including the aliases they were requested by.
 func extractNames(res []*discovery.Resource) []string {
 	names := []string{}
 	for _, r := range res {
 		names = append(names, r.Name)
+		names = append(names, r.Aliases...)
 	}
 	return names
 }
 
+func requestedByAlias(names sets.Set, r *discovery.Resource) bool {
+	for _, alias := range r.Aliases {
+		if names.Contains(alias) {
+			return true
+		}
+	}
+	return false
+}
+
 // TODO: remove, just for development
 func debugRequest(req *discovery.DeltaDiscoveryRequest) {
 	debug, _ := (&jsonpb.Marshaler{Indent: " "}).MarshalToString(&discovery.DeltaDiscoveryRequest{
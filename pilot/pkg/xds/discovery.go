@@ -15,6 +15,7 @@
 package xds
 
 import (
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -23,8 +24,11 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/atomic"
 	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
 
 	"istio.io/istio/pilot/pkg/controller/workloadentry"
+	"istio.io/istio/pilot/pkg/events"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/apigen"
@@ -37,6 +41,7 @@ import (
 	"istio.io/istio/pilot/pkg/util/sets"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/kube/inject"
 	"istio.io/istio/pkg/security"
 )
 
@@ -50,6 +55,9 @@ var (
 	periodicRefreshMetrics = 10 * time.Second
 )
 
+// maxLedgerHistory is the number of config ledger versions tracked to determine how up to date proxies are.
+const maxLedgerHistory = 100
+
 type debounceOptions struct {
 	// debounceAfter is the delay added to events to wait
 	// after a registry/config event for debouncing.
@@ -66,6 +74,13 @@ type debounceOptions struct {
 
 	// enableEDSDebounce indicates whether EDS pushes should be debounced.
 	enableEDSDebounce bool
+
+	// clock measures the debounce windows. If nil, the wall clock is used.
+	clock clock.Clock
+
+	// received, if set, counts the updates taken by the debouncer, once their debounce window is started. Tests
+	// moving a virtual clock use it to know that the windows of the updates sent so far are measured.
+	received *atomic.Int64
 }
 
 // DiscoveryServer is Pilot's gRPC implementation for Envoy's xds APIs
@@ -91,6 +106,9 @@ type DiscoveryServer struct {
 	// may also choose to not send any updates.
 	ProxyNeedsPush func(proxy *model.Proxy, req *model.PushRequest) bool
 
+	// InjectDryRun injects a pod without admitting it, for the /debug/inject handler. Optional.
+	InjectDryRun func(pod *corev1.Pod, namespace string) (*inject.DryRunResult, error)
+
 	concurrentPushLimit chan struct{}
 
 	// InboundUpdates describes the number of configuration updates the discovery server has received
@@ -118,15 +136,47 @@ type DiscoveryServer struct {
 	// pushQueue is the buffer that used after debounce and before the real xds push.
 	pushQueue *PushQueue
 
+	// tenants are the push queues of the tenants isolated by PILOT_TENANT_ISOLATION, or nil if they are not.
+	tenants *tenantPushQueues
+
+	// shedder rejects new connections while the push queue is overloaded.
+	shedder connectionShedder
+
 	// debugHandlers is the list of all the supported debug handlers.
 	debugHandlers map[string]string
 
+	// debugExtensions are the debug handlers registered through AddDebugHandler, keyed by name.
+	debugExtensions      map[string]debugExtension
+	debugExtensionsMutex sync.RWMutex
+
 	// adsClients reflect active gRPC channels, for both ADS and EDS.
 	adsClients      map[string]*Connection
 	adsClientsMutex sync.RWMutex
+	// connectionsByProxy indexes adsClients by proxy ID, to find overlapping connections. Protected by adsClientsMutex.
+	connectionsByProxy map[string]map[string]*Connection
+
+	// IdentityCheckMode controls whether the identity claimed by a proxy is verified against its credentials.
+	IdentityCheckMode IdentityCheckMode
+
+	// DuplicateConnectionPolicy controls how overlapping connections with the same node ID are handled.
+	DuplicateConnectionPolicy DuplicateConnectionPolicy
+	// DuplicateConnectionGracePeriod is how long older connections are kept open with DuplicateConnectionCloseOld.
+	DuplicateConnectionGracePeriod time.Duration
+
+	// proxyInstances tracks proxy instances across reconnects.
+	proxyInstances *proxyInstances
+
+	// drainingEndpoints tracks the addresses of terminating proxies, which are excluded from EDS.
+	drainingEndpoints *drainingEndpoints
 
 	StatusReporter DistributionStatusCache
 
+	// Events reports notable conditions as Kubernetes Events. It is nil if disabled.
+	Events *events.Recorder
+
+	// Failpoints inject failures, to test the resilience of the server and its clients.
+	Failpoints *Failpoints
+
 	// Authenticators for XDS requests. Should be same/subset of the CA authenticators.
 	Authenticators []security.Authenticator
 
@@ -137,10 +187,16 @@ type DiscoveryServer struct {
 	// serverReady indicates caches have been synced up and server is ready to process requests.
 	serverReady atomic.Bool
 
+	// started is set once Start is called, after which the generators can no longer be registered.
+	started atomic.Bool
+
 	debounceOptions debounceOptions
 
 	instanceID string
 
+	// systemNamespace is the namespace of istiod, whose identities may read all the debug information.
+	systemNamespace string
+
 	// Cache for XDS resources
 	Cache model.XdsCache
 
@@ -149,6 +205,36 @@ type DiscoveryServer struct {
 
 	// ListRemoteClusters collects debug information about other clusters this istiod reads from.
 	ListRemoteClusters func() []cluster.DebugInfo
+
+	// ledgerHistory tracks the recent config ledger versions, to determine how up to date proxies are.
+	ledgerHistory *versionHistory
+
+	// Revision is the revision of the control plane, the push costs are reported for.
+	Revision string
+
+	// pushCosts accounts the cost of the pushes by namespace of the proxies and kind of the triggering configs.
+	pushCosts *pushCosts
+
+	// pushLatencies accounts the time spent generating and sending the pushes, and their size, by type.
+	pushLatencies *pushLatencies
+
+	// analysis holds the messages of the last analysis of the configs, if this istiod analyzes them.
+	analysis *analysisResults
+
+	// affinity assigns the proxies to the istiod replicas, if connection affinity is enabled.
+	affinity *connectionAffinity
+
+	// debugEvents streams the push, connection and NACK events to the clients of /debug/events.
+	debugEvents *debugEvents
+
+	// redaction strips the secrets from the config dumps of the debug interface.
+	redaction *redactionPolicy
+
+	// readOnly holds the config updates received while istiod is read-only.
+	readOnly readOnlyState
+
+	// policyStatus writes the Effective condition of the policies, if this istiod writes the status of the configs.
+	policyStatus policyStatus
 }
 
 // EndpointShards holds the set of endpoint shards of a service. Registries update
@@ -184,22 +270,47 @@ func NewDiscoveryServer(env *model.Environment, plugins []string, instanceID str
 		pushChannel:             make(chan *model.PushRequest, 10),
 		pushQueue:               NewPushQueue(),
 		debugHandlers:           map[string]string{},
+		debugExtensions:         map[string]debugExtension{},
 		adsClients:              map[string]*Connection{},
+		connectionsByProxy:      map[string]map[string]*Connection{},
+		proxyInstances:          newProxyInstances(),
 		debounceOptions: debounceOptions{
 			debounceAfter:     features.DebounceAfter,
 			debounceMax:       features.DebounceMax,
 			enableEDSDebounce: features.EnableEDSDebounce,
 		},
-		Cache:      model.DisabledCache{},
-		instanceID: instanceID,
+		Cache:                          model.DisabledCache{},
+		instanceID:                     instanceID,
+		systemNamespace:                systemNameSpace,
+		ledgerHistory:                  newVersionHistory(maxLedgerHistory),
+		IdentityCheckMode:              identityCheckMode(),
+		DuplicateConnectionPolicy:      parseDuplicateConnectionPolicy(features.DuplicateConnectionPolicy),
+		DuplicateConnectionGracePeriod: features.DuplicateConnectionGracePeriod,
+		Failpoints:                     NewFailpoints(),
+		pushCosts:                      newPushCosts(),
+		pushLatencies:                  newPushLatencies(),
+		analysis:                       &analysisResults{},
+		debugEvents:                    newDebugEvents(),
+		redaction:                      newRedactionPolicy(features.DebugRedactedHeaders),
 	}
 
+	out.shedder = newConnectionShedder(out.pendingPushes)
+	out.drainingEndpoints = newDrainingEndpoints(features.DrainingEndpointTTL, out.drainingEndpointsChanged)
+
 	out.initJwksResolver()
 
 	out.initGenerators(env, systemNameSpace)
 
+	tenantOf := tenantFunc(features.TenantIsolation)
+	if tenantOf != nil {
+		out.tenants = newTenantPushQueues(tenantOf, out.pushQueue, out.concurrentPushLimit)
+	}
 	if features.EnableXDSCaching {
-		out.Cache = model.NewXdsCache()
+		if tenantOf != nil {
+			out.Cache = model.NewTenantXdsCache(tenantOf, model.NewXdsCache)
+		} else {
+			out.Cache = model.NewXdsCache()
+		}
 	}
 
 	out.ConfigGenerator = core.NewConfigGenerator(plugins, out.Cache)
@@ -234,6 +345,7 @@ func (s *DiscoveryServer) closeJwksResolver() {
 func (s *DiscoveryServer) Register(rpcs *grpc.Server) {
 	// Register v3 server
 	discovery.RegisterAggregatedDiscoveryServiceServer(rpcs, s)
+	s.registerFetch(rpcs)
 }
 
 var processStartTime = time.Now()
@@ -249,6 +361,7 @@ func (s *DiscoveryServer) IsServerReady() bool {
 }
 
 func (s *DiscoveryServer) Start(stopCh <-chan struct{}) {
+	s.started.Store(true)
 	go s.WorkloadEntryController.Run(stopCh)
 	go s.handleUpdates(stopCh)
 	go s.periodicRefreshMetrics(stopCh)
@@ -295,6 +408,7 @@ func (s *DiscoveryServer) periodicRefreshMetrics(stopCh <-chan struct{}) {
 				}
 			}
 			model.LastPushMutex.Unlock()
+			s.recordTenantMetrics()
 		case <-stopCh:
 			return
 		}
@@ -335,6 +449,8 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 	if err != nil {
 		return
 	}
+	s.reportRejectedConfigs(push, oldPushContext)
+	go s.updatePolicyStatus(push)
 	initContextTime := time.Since(t0)
 	log.Debugf("InitContext %v for push took %s", versionLocal, initContextTime)
 	pushContextInitTime.Record(initContextTime.Seconds())
@@ -364,10 +480,18 @@ func (s *DiscoveryServer) globalPushContext() *model.PushContext {
 	return s.Env.PushContext
 }
 
+// GlobalPushContext returns the push context of the last push.
+func (s *DiscoveryServer) GlobalPushContext() *model.PushContext {
+	return s.globalPushContext()
+}
+
 // ConfigUpdate implements ConfigUpdater interface, used to request pushes.
 // It replaces the 'clear cache' from v1.
 func (s *DiscoveryServer) ConfigUpdate(req *model.PushRequest) {
 	inboundConfigUpdates.Increment()
+	if s.holdUpdate(req) {
+		return
+	}
 	s.InboundUpdates.Inc()
 	s.pushChannel <- req
 }
@@ -387,6 +511,11 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 	var startDebounce time.Time
 	var lastConfigUpdateTime time.Time
 
+	clk := opts.clock
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+
 	pushCounter := 0
 	debouncedEvents := 0
 
@@ -403,8 +532,8 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 	}
 
 	pushWorker := func() {
-		eventDelay := time.Since(startDebounce)
-		quietTime := time.Since(lastConfigUpdateTime)
+		eventDelay := clk.Since(startDebounce)
+		quietTime := clk.Since(lastConfigUpdateTime)
 		// it has been too long or quiet enough
 		if eventDelay >= opts.debounceMax || quietTime >= opts.debounceAfter {
 			if req != nil {
@@ -419,7 +548,7 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 				debouncedEvents = 0
 			}
 		} else {
-			timeChan = time.After(opts.debounceAfter - quietTime)
+			timeChan = clk.After(opts.debounceAfter - quietTime)
 		}
 	}
 
@@ -436,17 +565,23 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 			if !opts.enableEDSDebounce && !r.Full {
 				// trigger push now, just for EDS
 				go pushFn(r)
+				if opts.received != nil {
+					opts.received.Inc()
+				}
 				continue
 			}
 
-			lastConfigUpdateTime = time.Now()
+			lastConfigUpdateTime = clk.Now()
 			if debouncedEvents == 0 {
-				timeChan = time.After(opts.debounceAfter)
+				timeChan = clk.After(opts.debounceAfter)
 				startDebounce = lastConfigUpdateTime
 			}
 			debouncedEvents++
 
 			req = req.Merge(r)
+			if opts.received != nil {
+				opts.received.Inc()
+			}
 		case <-timeChan:
 			if free {
 				pushWorker()
@@ -472,36 +607,41 @@ func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQu
 			if shuttingdown {
 				return
 			}
-			recordPushTriggers(push.Reason...)
-			// Signals that a push is done by reading from the semaphore, allowing another send on it.
-			doneFunc := func() {
-				queue.MarkDone(client)
-				<-semaphore
-			}
+			sendPush(client, push, semaphore, queue)
+		}
+	}
+}
 
-			proxiesQueueTime.Record(time.Since(push.Start).Seconds())
-			var closed <-chan struct{}
-			if client.stream != nil {
-				closed = client.stream.Context().Done()
-			} else {
-				closed = client.deltaStream.Context().Done()
-			}
-			go func() {
-				pushEv := &Event{
-					pushRequest: push,
-					done:        doneFunc,
-				}
+// sendPush hands a push dequeued from the queue to the connection, releasing the semaphore once it is done.
+func sendPush(client *Connection, push *model.PushRequest, semaphore chan struct{}, queue *PushQueue) {
+	recordPushTriggers(push.Reason...)
+	// Signals that a push is done by reading from the semaphore, allowing another send on it.
+	doneFunc := func() {
+		queue.MarkDone(client)
+		<-semaphore
+	}
 
-				select {
-				case client.pushChannel <- pushEv:
-					return
-				case <-closed: // grpc stream was closed
-					doneFunc()
-					log.Infof("Client closed connection %v", client.ConID)
-				}
-			}()
-		}
+	proxiesQueueTime.Record(time.Since(push.Start).Seconds())
+	var closed <-chan struct{}
+	if client.stream != nil {
+		closed = client.stream.Context().Done()
+	} else {
+		closed = client.deltaStream.Context().Done()
 	}
+	go func() {
+		pushEv := &Event{
+			pushRequest: push,
+			done:        doneFunc,
+		}
+
+		select {
+		case client.pushChannel <- pushEv:
+			return
+		case <-closed: // grpc stream was closed
+			doneFunc()
+			log.Infof("Client closed connection %v", client.ConID)
+		}
+	}()
 }
 
 // initPushContext creates a global push context and stores it on the environment. Note: while this
@@ -516,6 +656,7 @@ func (s *DiscoveryServer) initPushContext(req *model.PushRequest, oldPushContext
 		log.Errorf("XDS: Failed to update services: %v", err)
 		// We can't push if we can't read the data - stick with previous version.
 		pushContextErrors.Increment()
+		s.Events.PushFailed(nil, fmt.Sprintf("failed to update services: %v", err))
 		return nil, err
 	}
 
@@ -528,11 +669,16 @@ func (s *DiscoveryServer) initPushContext(req *model.PushRequest, oldPushContext
 	// Ensure we drop the cache in the lock to avoid races, where we drop the cache, fill it back up, then update push context
 	s.dropCacheForRequest(req)
 	s.updateMutex.Unlock()
+	s.ledgerHistory.add(push.LedgerVersion, time.Now())
 
 	return push, nil
 }
 
 func (s *DiscoveryServer) sendPushes(stopCh <-chan struct{}) {
+	if s.tenants != nil {
+		s.tenants.start(stopCh)
+		return
+	}
 	doSendPushes(stopCh, s.concurrentPushLimit, s.pushQueue)
 }
 
@@ -543,10 +689,15 @@ func (s *DiscoveryServer) initGenerators(env *model.Environment, systemNameSpace
 	s.Generators[v3.ClusterType] = &CdsGenerator{Server: s}
 	s.Generators[v3.ListenerType] = &LdsGenerator{Server: s}
 	s.Generators[v3.RouteType] = &RdsGenerator{Server: s}
+	s.Generators[v3.ScopedRouteType] = &SrdsGenerator{Server: s}
+	s.Generators[v3.VirtualHostType] = &VhdsGenerator{Server: s}
 	s.Generators[v3.EndpointType] = edsGen
+	s.Generators[v3.LbEndpointType] = &LedsGenerator{Server: s}
 	s.Generators[v3.NameTableType] = &NdsGenerator{Server: s}
 	s.Generators[v3.ExtensionConfigurationType] = &EcdsGenerator{Server: s}
 	s.Generators[v3.ProxyConfigType] = &PcdsGenerator{Server: s, TrustBundle: env.TrustBundle}
+	s.Generators[v3.WatermarkType] = &WatermarkGenerator{Server: s}
+	s.Generators[v3.ResumptionTokenType] = &ResumptionTokenGenerator{Server: s}
 
 	s.Generators["grpc"] = &grpcgen.GrpcConfigGenerator{}
 	s.Generators["grpc/"+v3.EndpointType] = edsGen
@@ -567,6 +718,10 @@ func (s *DiscoveryServer) initGenerators(env *model.Environment, systemNameSpace
 // shutdown shuts down DiscoveryServer components.
 func (s *DiscoveryServer) Shutdown() {
 	s.closeJwksResolver()
+	if s.tenants != nil {
+		s.tenants.shutDown()
+		return
+	}
 	s.pushQueue.ShutDown()
 }
 
@@ -26,6 +26,7 @@ import (
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	uatomic "go.uber.org/atomic"
 	"google.golang.org/grpc"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	"istio.io/istio/pilot/pkg/model"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
@@ -326,6 +327,38 @@ func TestDebounce(t *testing.T) {
 	}
 }
 
+func TestDebounceVirtualTime(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	opts := debounceOptions{
+		debounceAfter:     time.Minute,
+		debounceMax:       time.Hour,
+		enableEDSDebounce: true,
+		clock:             clock,
+		received:          uatomic.NewInt64(0),
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	updateCh := make(chan *model.PushRequest)
+	pushes := uatomic.NewInt32(0)
+	go debounce(updateCh, stopCh, opts, func(req *model.PushRequest) {
+		pushes.Inc()
+	}, uatomic.NewInt64(0))
+
+	updateCh <- &model.PushRequest{Full: true}
+	retry.UntilOrFail(t, func() bool {
+		return opts.received.Load() == 1
+	}, retry.Delay(time.Millisecond))
+	clock.Step(opts.debounceAfter / 2)
+	if pushes.Load() != 0 {
+		t.Fatalf("pushed before the debounce window elapsed")
+	}
+
+	clock.Step(opts.debounceAfter / 2)
+	retry.UntilOrFail(t, func() bool {
+		return pushes.Load() == 1
+	}, retry.Delay(time.Millisecond))
+}
+
 func TestShouldRespond(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -477,3 +510,23 @@ func TestShouldRespond(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamDeadline(t *testing.T) {
+	deadline, stop := streamDeadline(0)
+	stop()
+	if deadline != nil {
+		t.Fatalf("expected no deadline without a max duration")
+	}
+
+	start := time.Now()
+	deadline, stop = streamDeadline(50 * time.Millisecond)
+	defer stop()
+	select {
+	case <-deadline:
+		if d := time.Since(start); d < 50*time.Millisecond {
+			t.Fatalf("deadline fired after %v, before the max duration", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("deadline did not fire")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sync"
+	"time"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/spiffe"
+)
+
+// drainingKey identifies an endpoint address. Addresses are only unique within a cluster.
+type drainingKey struct {
+	cluster cluster.ID
+	address string
+}
+
+// drainingEndpoint is an address reported as draining by a terminating proxy.
+type drainingEndpoint struct {
+	proxyID string
+	// services are the services the proxy was an endpoint of, used to trigger pushes.
+	services map[model.ConfigKey]struct{}
+	expiry   *time.Timer
+}
+
+// drainingEndpoints tracks the addresses of proxies that announced they are shutting down. Their
+// endpoints are removed from EDS right away, instead of waiting for the platform to notice. Entries
+// expire after a TTL so an address is not excluded forever if the proxy never goes away.
+type drainingEndpoints struct {
+	mu        sync.Mutex
+	addresses map[drainingKey]*drainingEndpoint
+	ttl       time.Duration
+	// onChange is called with the services whose endpoints changed.
+	onChange func(services map[model.ConfigKey]struct{})
+}
+
+func newDrainingEndpoints(ttl time.Duration, onChange func(map[model.ConfigKey]struct{})) *drainingEndpoints {
+	return &drainingEndpoints{
+		addresses: map[drainingKey]*drainingEndpoint{},
+		ttl:       ttl,
+		onChange:  onChange,
+	}
+}
+
+// proxyServices returns the keys of the services the proxy is an endpoint of.
+func proxyServices(proxy *model.Proxy) map[model.ConfigKey]struct{} {
+	out := map[model.ConfigKey]struct{}{}
+	for _, si := range proxy.ServiceInstances {
+		out[model.ConfigKey{
+			Kind:      gvk.ServiceEntry,
+			Name:      string(si.Service.Hostname),
+			Namespace: si.Service.Attributes.Namespace,
+		}] = struct{}{}
+	}
+	return out
+}
+
+// proxyEndpoints returns the keys of the registry endpoints of the proxy that belong to its verified identity.
+// The addresses reported by the proxy are not trusted: an endpoint is only included if the registry assigns it
+// the namespace and service account the proxy authenticated with, so a proxy cannot drain the endpoints of
+// another workload by claiming its IP.
+func proxyEndpoints(proxy *model.Proxy) []drainingKey {
+	id := proxy.VerifiedIdentity
+	if id == nil || proxy.Metadata == nil {
+		return nil
+	}
+	var out []drainingKey
+	seen := map[drainingKey]struct{}{}
+	for _, si := range proxy.ServiceInstances {
+		ep := si.Endpoint
+		if ep == nil || ep.Address == "" || ep.Namespace != id.Namespace {
+			continue
+		}
+		sa, err := spiffe.ParseIdentity(ep.ServiceAccount)
+		if err != nil || sa.Namespace != id.Namespace || sa.ServiceAccount != id.ServiceAccount {
+			continue
+		}
+		key := drainingKey{cluster: proxy.Metadata.ClusterID, address: ep.Address}
+		if _, f := seen[key]; !f {
+			seen[key] = struct{}{}
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// mark records the endpoints of the proxy as draining.
+func (d *drainingEndpoints) mark(proxy *model.Proxy) {
+	keys := proxyEndpoints(proxy)
+	if len(keys) == 0 {
+		log.Debugf("ADS: proxy %s is draining but has no endpoint matching its identity", proxy.ID)
+		return
+	}
+	services := proxyServices(proxy)
+	d.mu.Lock()
+	changed := false
+	for _, key := range keys {
+		if existing, f := d.addresses[key]; f && existing.proxyID == proxy.ID {
+			// Repeated notification, for example after a reconnect. Keep the original expiry.
+			continue
+		}
+		d.removeLocked(key)
+		key := key
+		entry := &drainingEndpoint{proxyID: proxy.ID, services: services}
+		entry.expiry = time.AfterFunc(d.ttl, func() {
+			d.expire(key, entry)
+		})
+		d.addresses[key] = entry
+		changed = true
+	}
+	d.mu.Unlock()
+	if changed && len(services) > 0 {
+		d.onChange(services)
+	}
+}
+
+// clear removes the draining state of endpoints now used by a different proxy, as happens when the
+// platform reuses the IP of a terminated pod.
+func (d *drainingEndpoints) clear(proxy *model.Proxy) {
+	keys := proxyEndpoints(proxy)
+	if len(keys) == 0 {
+		return
+	}
+	services := map[model.ConfigKey]struct{}{}
+	d.mu.Lock()
+	for _, key := range keys {
+		if existing, f := d.addresses[key]; f && existing.proxyID != proxy.ID {
+			for k := range existing.services {
+				services[k] = struct{}{}
+			}
+			d.removeLocked(key)
+		}
+	}
+	d.mu.Unlock()
+	if len(services) > 0 {
+		d.onChange(services)
+	}
+}
+
+func (d *drainingEndpoints) expire(key drainingKey, entry *drainingEndpoint) {
+	d.mu.Lock()
+	if d.addresses[key] != entry {
+		d.mu.Unlock()
+		return
+	}
+	delete(d.addresses, key)
+	d.mu.Unlock()
+	if len(entry.services) > 0 {
+		d.onChange(entry.services)
+	}
+}
+
+func (d *drainingEndpoints) removeLocked(key drainingKey) {
+	if existing, f := d.addresses[key]; f {
+		existing.expiry.Stop()
+		delete(d.addresses, key)
+	}
+}
+
+func (d *drainingEndpoints) isDraining(ep *model.IstioEndpoint) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, f := d.addresses[drainingKey{cluster: ep.Locality.ClusterID, address: ep.Address}]
+	return f
+}
+
+func (d *drainingEndpoints) empty() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.addresses) == 0
+}
+
+// filter removes the draining endpoints.
+func (d *drainingEndpoints) filter(endpoints []*LocLbEndpointsAndOptions) []*LocLbEndpointsAndOptions {
+	if d.empty() {
+		return endpoints
+	}
+	filtered := make([]*LocLbEndpointsAndOptions, 0, len(endpoints))
+	for _, ep := range endpoints {
+		remaining := &LocLbEndpointsAndOptions{
+			llbEndpoints: endpoint.LocalityLbEndpoints{
+				Locality: ep.llbEndpoints.Locality,
+				Priority: ep.llbEndpoints.Priority,
+			},
+		}
+		for i, lbEp := range ep.llbEndpoints.LbEndpoints {
+			istioEndpoint := ep.istioEndpoints[i]
+			if d.isDraining(istioEndpoint) {
+				continue
+			}
+			remaining.istioEndpoints = append(remaining.istioEndpoints, istioEndpoint)
+			remaining.emplace(lbEp, ep.tunnelMetadata[i])
+		}
+		remaining.refreshWeight()
+		filtered = append(filtered, remaining)
+	}
+	return filtered
+}
+
+// handleDraining processes the notification sent by an agent when its workload starts terminating.
+func (s *DiscoveryServer) handleDraining(proxy *model.Proxy) {
+	if !features.RemoveDrainingEndpoints {
+		return
+	}
+	log.Infof("ADS: proxy %s is draining, removing its endpoints", proxy.ID)
+	s.drainingEndpoints.mark(proxy)
+}
+
+// drainingEndpointsChanged triggers an incremental push of the endpoints of the given services.
+func (s *DiscoveryServer) drainingEndpointsChanged(services map[model.ConfigKey]struct{}) {
+	s.ConfigUpdate(&model.PushRequest{
+		Full:           false,
+		ConfigsUpdated: services,
+		Reason:         []model.TriggerReason{model.EndpointUpdate},
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/spiffe"
+)
+
+func TestDrainingEndpoints(t *testing.T) {
+	changes := make(chan map[model.ConfigKey]struct{}, 10)
+	d := newDrainingEndpoints(time.Hour, func(services map[model.ConfigKey]struct{}) {
+		changes <- services
+	})
+	expectChange := func() {
+		t.Helper()
+		select {
+		case services := <-changes:
+			key := model.ConfigKey{Kind: gvk.ServiceEntry, Name: "svc.ns.svc.cluster.local", Namespace: "ns"}
+			if _, f := services[key]; !f || len(services) != 1 {
+				t.Fatalf("unexpected services pushed: %v", services)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected a push")
+		}
+	}
+	expectNoChange := func() {
+		t.Helper()
+		select {
+		case services := <-changes:
+			t.Fatalf("unexpected push: %v", services)
+		default:
+		}
+	}
+	// proxyAs returns a proxy connected with the identity of sa, whose registry endpoint at ip has the
+	// service account registered.
+	proxyAs := func(id, ip, sa, registered string) *model.Proxy {
+		return &model.Proxy{
+			ID:               id,
+			IPAddresses:      []string{ip},
+			Metadata:         &model.NodeMetadata{ClusterID: "c1"},
+			VerifiedIdentity: &spiffe.Identity{TrustDomain: "cluster.local", Namespace: "ns", ServiceAccount: sa},
+			ServiceInstances: []*model.ServiceInstance{{
+				Service: &model.Service{
+					Hostname:   "svc.ns.svc.cluster.local",
+					Attributes: model.ServiceAttributes{Namespace: "ns"},
+				},
+				Endpoint: &model.IstioEndpoint{
+					Address:        ip,
+					Namespace:      "ns",
+					ServiceAccount: "spiffe://cluster.local/ns/ns/sa/" + registered,
+				},
+			}},
+		}
+	}
+	proxy := func(id, ip string) *model.Proxy {
+		return proxyAs(id, ip, "default", "default")
+	}
+	addresses := func(eps []*LocLbEndpointsAndOptions) []string {
+		var out []string
+		for _, ep := range eps {
+			for _, e := range ep.istioEndpoints {
+				out = append(out, e.Address)
+			}
+		}
+		return out
+	}
+	eps := func() []*LocLbEndpointsAndOptions {
+		out := &LocLbEndpointsAndOptions{}
+		for _, ep := range []struct {
+			cluster cluster.ID
+			ip      string
+		}{{"c1", "10.0.0.1"}, {"c1", "10.0.0.2"}, {"c2", "10.0.0.1"}} {
+			out.istioEndpoints = append(out.istioEndpoints, &model.IstioEndpoint{
+				Address:  ep.ip,
+				Locality: model.Locality{ClusterID: ep.cluster},
+			})
+			out.emplace(&endpoint.LbEndpoint{LoadBalancingWeight: &wrappers.UInt32Value{Value: 1}}, nil)
+		}
+		return []*LocLbEndpointsAndOptions{out}
+	}
+
+	// A proxy cannot drain an endpoint registered for another identity, nor one without a verified identity.
+	d.mark(proxyAs("x.ns", "10.0.0.1", "attacker", "default"))
+	unverified := proxy("x.ns", "10.0.0.1")
+	unverified.VerifiedIdentity = nil
+	d.mark(unverified)
+	expectNoChange()
+	if !d.empty() {
+		t.Fatalf("expected no draining endpoint")
+	}
+
+	d.mark(proxy("a.ns", "10.0.0.1"))
+	expectChange()
+	// The same address in another cluster is a different endpoint.
+	if got := addresses(d.filter(eps())); len(got) != 2 || got[0] != "10.0.0.2" || got[1] != "10.0.0.1" {
+		t.Fatalf("expected draining endpoint to be removed, got %v", got)
+	}
+
+	// A repeated notification, or a reconnect of the same proxy, changes nothing.
+	d.mark(proxy("a.ns", "10.0.0.1"))
+	d.clear(proxy("a.ns", "10.0.0.1"))
+	expectNoChange()
+
+	// The address is reused by a new proxy.
+	d.clear(proxy("b.ns", "10.0.0.1"))
+	expectChange()
+	if got := addresses(d.filter(eps())); len(got) != 3 {
+		t.Fatalf("expected all endpoints, got %v", got)
+	}
+
+	// Entries expire.
+	d.ttl = time.Millisecond
+	d.mark(proxy("c.ns", "10.0.0.2"))
+	expectChange()
+	expectChange()
+	if !d.empty() {
+		t.Fatalf("expected draining endpoint to expire")
+	}
+}
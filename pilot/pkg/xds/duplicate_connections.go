@@ -0,0 +1,135 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// DuplicateConnectionPolicy controls how overlapping connections with the same node ID are handled.
+// These are expected during Envoy hot restart, but may also be caused by misbehaving clients.
+type DuplicateConnectionPolicy string
+
+const (
+	// DuplicateConnectionAllow serves all connections independently.
+	DuplicateConnectionAllow DuplicateConnectionPolicy = "allow"
+	// DuplicateConnectionReject refuses a new connection while another one is active.
+	DuplicateConnectionReject DuplicateConnectionPolicy = "reject"
+	// DuplicateConnectionCloseOld closes older connections after a grace period.
+	DuplicateConnectionCloseOld DuplicateConnectionPolicy = "close-old"
+	// DuplicateConnectionNewest keeps all connections open, but only pushes to the newest one.
+	DuplicateConnectionNewest DuplicateConnectionPolicy = "newest"
+)
+
+func parseDuplicateConnectionPolicy(p string) DuplicateConnectionPolicy {
+	switch policy := DuplicateConnectionPolicy(p); policy {
+	case DuplicateConnectionAllow, DuplicateConnectionReject, DuplicateConnectionCloseOld, DuplicateConnectionNewest:
+		return policy
+	default:
+		log.Warnf("unknown duplicate connection policy %q, using %q", p, DuplicateConnectionAllow)
+		return DuplicateConnectionAllow
+	}
+}
+
+// duplicateConnections returns the other registered connections with the same proxy ID as con.
+func (s *DiscoveryServer) duplicateConnections(con *Connection) []*Connection {
+	s.adsClientsMutex.RLock()
+	defer s.adsClientsMutex.RUnlock()
+	var out []*Connection
+	for _, c := range s.connectionsByProxy[con.proxy.ID] {
+		if c != con {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (s *DiscoveryServer) hasConnection(conID string) bool {
+	s.adsClientsMutex.RLock()
+	defer s.adsClientsMutex.RUnlock()
+	_, f := s.adsClients[conID]
+	return f
+}
+
+// handleDuplicateConnection applies the DuplicateConnectionPolicy to a new connection, before it is
+// registered. An error is returned if the connection should be refused.
+func (s *DiscoveryServer) handleDuplicateConnection(con *Connection) error {
+	existing := s.duplicateConnections(con)
+	if len(existing) == 0 {
+		return nil
+	}
+	policy := s.DuplicateConnectionPolicy
+	log.Infof("ADS: %s overlaps %d active connection(s) of the same proxy, policy %s", con.ConID, len(existing), policy)
+	switch policy {
+	case DuplicateConnectionReject:
+		xdsDuplicateConnections.With(actionTag.Value("rejected")).Increment()
+		return status.Errorf(codes.AlreadyExists, "proxy %s already has an active connection", con.proxy.ID)
+	case DuplicateConnectionCloseOld:
+		xdsDuplicateConnections.With(actionTag.Value("closed")).Increment()
+		for _, old := range existing {
+			old := old
+			time.AfterFunc(s.DuplicateConnectionGracePeriod, func() {
+				// Keep the old connection if the one replacing it is already gone.
+				if !s.hasConnection(con.ConID) {
+					return
+				}
+				log.Infof("ADS: closing %s, replaced by %s", old.ConID, con.ConID)
+				old.Stop()
+			})
+		}
+	case DuplicateConnectionNewest:
+		xdsDuplicateConnections.With(actionTag.Value("superseded")).Increment()
+		for _, old := range existing {
+			old.superseded.Store(true)
+		}
+	default:
+		xdsDuplicateConnections.With(actionTag.Value("allowed")).Increment()
+	}
+	if s.StatusGen != nil {
+		s.StatusGen.OnDuplicate(con)
+	}
+	return nil
+}
+
+// promoteDuplicateConnection resumes pushes to the newest remaining connection of a proxy, once the
+// connection that superseded it is closed. The promoted connection missed pushes while superseded,
+// so a full push is triggered.
+func (s *DiscoveryServer) promoteDuplicateConnection(closed *Connection) {
+	if closed.superseded.Load() {
+		// The closed connection was not the one receiving pushes.
+		return
+	}
+	var newest *Connection
+	for _, c := range s.duplicateConnections(closed) {
+		if newest == nil || c.Connect.After(newest.Connect) {
+			newest = c
+		}
+	}
+	if newest == nil || !newest.superseded.CAS(true, false) {
+		return
+	}
+	log.Infof("ADS: resuming pushes to %s, replacing closed connection %s", newest.ConID, closed.ConID)
+	s.enqueue(newest, &model.PushRequest{
+		Full:   true,
+		Push:   s.globalPushContext(),
+		Start:  time.Now(),
+		Reason: []model.TriggerReason{model.ProxyUpdate},
+	})
+}
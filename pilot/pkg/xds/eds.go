@@ -315,6 +315,13 @@ func (s *DiscoveryServer) generateEndpoints(b EndpointBuilder) *endpoint.Cluster
 		return buildEmptyClusterLoadAssignment(b.clusterName)
 	}
 
+	// Remove endpoints of proxies that announced they are shutting down.
+	llbOpts = s.drainingEndpoints.filter(llbOpts)
+
+	// Prune endpoints the proxy cannot reach due to network policies, if enabled. This must run before the
+	// network filter, which replaces remote endpoints with gateways.
+	llbOpts = b.EndpointsByNetworkPolicyFilter(llbOpts)
+
 	// Apply the Split Horizon EDS filter, if applicable.
 	llbOpts = b.EndpointsByNetworkFilter(llbOpts)
 
@@ -326,6 +333,7 @@ func (s *DiscoveryServer) generateEndpoints(b EndpointBuilder) *endpoint.Cluster
 		llbOpts = b.EndpointsWithMTLSFilter(llbOpts)
 	}
 	llbOpts = b.ApplyTunnelSetting(llbOpts, b.tunnelType)
+	llbOpts = b.EndpointsWithNAT64(llbOpts)
 
 	l := b.createClusterLoadAssignment(llbOpts)
 
@@ -387,6 +395,7 @@ func (eds *EdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w
 
 	cached := 0
 	regenerated := 0
+	cache := model.CacheForProxy(eds.Server.Cache, proxy)
 	for _, clusterName := range w.ResourceNames {
 		if edsUpdatedServices != nil {
 			_, _, hostname, _ := model.ParseSubsetKey(clusterName)
@@ -397,7 +406,7 @@ func (eds *EdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w
 			}
 		}
 		builder := NewEndpointBuilder(clusterName, proxy, push)
-		if marshalledEndpoint, token, f := eds.Server.Cache.Get(builder); f && !features.EnableUnsafeAssertions {
+		if marshalledEndpoint, token, f := cache.Get(builder); f && !features.EnableUnsafeAssertions {
 			// We skip cache if assertions are enabled, so that the cache will assert our eviction logic is correct
 			resources = append(resources, marshalledEndpoint)
 			cached++
@@ -406,6 +415,9 @@ func (eds *EdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w
 			if l == nil {
 				continue
 			}
+			if builder.leds {
+				l = ledsClusterLoadAssignment(l)
+			}
 			regenerated++
 
 			if len(l.Endpoints) == 0 {
@@ -416,7 +428,7 @@ func (eds *EdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w
 				Resource: util.MessageToAny(l),
 			}
 			resources = append(resources, resource)
-			eds.Server.Cache.Add(builder, token, resource)
+			cache.Add(builder, token, resource)
 		}
 	}
 	return resources, model.XdsLogDetails{
@@ -0,0 +1,178 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// loadAssignmentDigest summarizes a load assignment sent to a proxy.
+type loadAssignmentDigest struct {
+	// hash of the whole load assignment.
+	hash string
+	// endpoints holds the hash of each endpoint, by address.
+	endpoints map[string]string
+}
+
+// endpointDiff counts the endpoint level changes of a load assignment.
+type endpointDiff struct {
+	added, removed, updated int
+}
+
+// edsDiffTracker tracks the load assignments ACKed by a proxy over SotW, so incremental pushes can skip the load
+// assignments the proxy already has.
+type edsDiffTracker struct {
+	mu sync.Mutex
+	// acked holds the load assignment last ACKed for each cluster.
+	acked map[string]loadAssignmentDigest
+	// pending holds the load assignments sent and not yet ACKed, up to pendingNonce.
+	pending      map[string]loadAssignmentDigest
+	pendingNonce string
+	// pendingFull is set if the pending load assignments were sent in a complete response.
+	pendingFull bool
+}
+
+func newEdsDiffTracker() *edsDiffTracker {
+	return &edsDiffTracker{
+		acked:   map[string]loadAssignmentDigest{},
+		pending: map[string]loadAssignmentDigest{},
+	}
+}
+
+func digestLoadAssignment(res *discovery.Resource) (loadAssignmentDigest, bool) {
+	cla := &endpoint.ClusterLoadAssignment{}
+	if err := res.Resource.UnmarshalTo(cla); err != nil {
+		return loadAssignmentDigest{}, false
+	}
+	d := loadAssignmentDigest{
+		hash:      hashBytes(res.Resource.GetValue()),
+		endpoints: map[string]string{},
+	}
+	for _, llb := range cla.Endpoints {
+		for _, ep := range llb.LbEndpoints {
+			d.endpoints[endpointKey(ep)] = endpointHash(ep)
+		}
+	}
+	return d, true
+}
+
+func hashBytes(b []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// diff returns the endpoint level changes from the ACKed load assignment of a cluster.
+func (t *edsDiffTracker) diff(cluster string, d loadAssignmentDigest) endpointDiff {
+	acked := t.acked[cluster].endpoints
+	out := endpointDiff{}
+	for ep, h := range d.endpoints {
+		ackedHash, f := acked[ep]
+		switch {
+		case !f:
+			out.added++
+		case ackedHash != h:
+			out.updated++
+		}
+	}
+	for ep := range acked {
+		if _, f := d.endpoints[ep]; !f {
+			out.removed++
+		}
+	}
+	return out
+}
+
+// filter drops the load assignments identical to the ones last ACKed by the proxy, and records the endpoint
+// level changes of the others. Only incremental responses may be filtered, as a complete response must hold
+// every watched cluster.
+func (t *edsDiffTracker) filter(res model.Resources, incremental bool) (model.Resources, map[string]loadAssignmentDigest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	digests := make(map[string]loadAssignmentDigest, len(res))
+	out := make(model.Resources, 0, len(res))
+	for _, r := range res {
+		d, ok := digestLoadAssignment(r)
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		if acked, f := t.acked[r.Name]; incremental && f && acked.hash == d.hash {
+			edsSuppressedSends.Increment()
+			continue
+		}
+		diff := t.diff(r.Name, d)
+		edsEndpointChanges.With(changeTag.Value("added")).RecordInt(int64(diff.added))
+		edsEndpointChanges.With(changeTag.Value("removed")).RecordInt(int64(diff.removed))
+		edsEndpointChanges.With(changeTag.Value("updated")).RecordInt(int64(diff.updated))
+		if diff != (endpointDiff{}) {
+			log.Debugf("EDS: cluster %s changed, added:%d removed:%d updated:%d", r.Name, diff.added, diff.removed, diff.updated)
+		}
+		digests[r.Name] = d
+		out = append(out, r)
+	}
+	return out, digests
+}
+
+// sent records the load assignments of a response, until the proxy ACKs or rejects it.
+func (t *edsDiffTracker) sent(nonce string, digests map[string]loadAssignmentDigest, incremental bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !incremental {
+		// A complete response replaces the load assignments of the clusters it does not hold.
+		t.pending = map[string]loadAssignmentDigest{}
+		t.pendingFull = true
+	}
+	for cluster, d := range digests {
+		t.pending[cluster] = d
+	}
+	t.pendingNonce = nonce
+}
+
+// ack applies the pending load assignments, once the proxy ACKed the last response holding them.
+func (t *edsDiffTracker) ack(nonce string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if nonce != t.pendingNonce {
+		return
+	}
+	if t.pendingFull {
+		t.acked = t.pending
+	} else {
+		for cluster, d := range t.pending {
+			t.acked[cluster] = d
+		}
+	}
+	t.pending = map[string]loadAssignmentDigest{}
+	t.pendingFull = false
+}
+
+// nack forgets the pending load assignments, as it is unknown which of them the proxy applied.
+func (t *edsDiffTracker) nack() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for cluster := range t.pending {
+		delete(t.acked, cluster)
+	}
+	t.pending = map[string]loadAssignmentDigest{}
+	t.pendingFull = false
+}
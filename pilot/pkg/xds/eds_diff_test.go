@@ -0,0 +1,75 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+func TestEdsDiffTracker(t *testing.T) {
+	cla := func(name string, ips ...string) *discovery.Resource {
+		l := &endpoint.ClusterLoadAssignment{ClusterName: name, Endpoints: []*endpoint.LocalityLbEndpoints{{}}}
+		for _, ip := range ips {
+			l.Endpoints[0].LbEndpoints = append(l.Endpoints[0].LbEndpoints, &endpoint.LbEndpoint{
+				HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+					Endpoint: &endpoint.Endpoint{Address: util.BuildAddress(ip, 80)},
+				},
+			})
+		}
+		return &discovery.Resource{Name: name, Resource: util.MessageToAny(l)}
+	}
+	names := func(res model.Resources) []string {
+		out := []string{}
+		for _, r := range res {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	tr := newEdsDiffTracker()
+	full := model.Resources{cla("a", "1.1.1.1"), cla("b", "2.2.2.2")}
+	out, digests := tr.filter(full, false)
+	if len(out) != 2 {
+		t.Fatalf("complete responses must not be filtered, got %v", names(out))
+	}
+	tr.sent("1", digests, false)
+
+	// Not ACKed yet, so nothing is suppressed.
+	if out, _ := tr.filter(model.Resources{cla("a", "1.1.1.1")}, true); len(out) != 1 {
+		t.Fatalf("expected unacked load assignment to be sent, got %v", names(out))
+	}
+
+	tr.ack("1")
+	out, digests = tr.filter(model.Resources{cla("a", "1.1.1.1"), cla("b", "2.2.2.2", "3.3.3.3")}, true)
+	if got := names(out); len(got) != 1 || got[0] != "b" {
+		t.Fatalf("expected only the changed load assignment, got %v", got)
+	}
+	if diff := tr.diff("b", digests["b"]); diff != (endpointDiff{added: 1}) {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+
+	// A rejected response forgets what the proxy has for the clusters it held.
+	tr.sent("2", digests, true)
+	tr.nack()
+	if out, _ := tr.filter(model.Resources{cla("a", "1.1.1.1"), cla("b", "2.2.2.2")}, true); len(out) != 1 || out[0].Name != "b" {
+		t.Fatalf("expected the rejected cluster to be sent again, got %v", names(out))
+	}
+}
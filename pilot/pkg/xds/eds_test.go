@@ -35,8 +35,10 @@ import (
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
+	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/adsc"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
@@ -325,6 +327,91 @@ func TestEdsWeightedServiceEntry(t *testing.T) {
 	}
 }
 
+func TestEdsPerEndpointAutoMtls(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: mixed
+  namespace: default
+spec:
+  hosts:
+  - mixed.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  location: MESH_INTERNAL
+  endpoints:
+  - address: 1.1.1.1
+    labels:
+      app: onboarded
+      security.istio.io/tlsMode: istio
+  - address: 2.2.2.2
+    labels:
+      app: disabled
+      security.istio.io/tlsMode: istio
+  - address: 3.3.3.3
+    labels:
+      app: plaintext
+---
+apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: istio-system
+spec:
+  mtls:
+    mode: DISABLE
+---
+apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: onboarded
+  namespace: istio-system
+spec:
+  selector:
+    matchLabels:
+      app: onboarded
+  mtls:
+    mode: STRICT
+`})
+	proxy := s.SetupProxy(nil)
+	clusterName := "outbound|80||mixed.example.com"
+
+	// mTLS is disabled for the mesh, but the cluster still selects the transport socket of each endpoint.
+	c := xdstest.ExtractCluster(clusterName, s.Clusters(proxy))
+	if c == nil {
+		t.Fatalf("cluster %s not found", clusterName)
+	}
+	if len(c.TransportSocketMatches) != 2 || c.TransportSocket != nil {
+		t.Fatalf("expected auto mTLS transport socket matches, got %v", c)
+	}
+
+	expected := map[string]string{
+		"1.1.1.1": model.IstioMutualTLSModeLabel,
+		"2.2.2.2": "",
+		"3.3.3.3": "",
+	}
+	got := map[string]string{}
+	for _, cla := range s.Endpoints(proxy) {
+		if cla.ClusterName != clusterName {
+			continue
+		}
+		for _, llb := range cla.Endpoints {
+			for _, lbEp := range llb.LbEndpoints {
+				tlsMode := lbEp.GetMetadata().GetFilterMetadata()[util.EnvoyTransportSocketMetadataKey].
+					GetFields()[model.TLSModeLabelShortname].GetStringValue()
+				got[lbEp.GetEndpoint().GetAddress().GetSocketAddress().GetAddress()] = tlsMode
+			}
+		}
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected endpoint tlsMode %v, got %v", expected, got)
+	}
+}
+
 var (
 	watchEds = []string{v3.ClusterType, v3.EndpointType}
 	watchAll = []string{v3.ClusterType, v3.EndpointType, v3.ListenerType, v3.RouteType}
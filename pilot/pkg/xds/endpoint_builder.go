@@ -15,6 +15,7 @@
 package xds
 
 import (
+	"net"
 	"sort"
 	"strconv"
 	"strings"
@@ -25,10 +26,12 @@ import (
 	"github.com/golang/protobuf/ptypes/wrappers"
 
 	networkingapi "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/security/authn/factory"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
@@ -57,6 +60,19 @@ func GetTunnelBuilderType(_ string, proxy *model.Proxy, _ *model.PushContext) ne
 	return networking.NoTunnel
 }
 
+// nat64Prefix is the parsed PILOT_NAT64_PREFIX, or nil if unset.
+var nat64Prefix = func() *net.IPNet {
+	if features.NAT64Prefix == "" {
+		return nil
+	}
+	prefix, err := util.ParseNAT64Prefix(features.NAT64Prefix)
+	if err != nil {
+		log.Errorf("invalid PILOT_NAT64_PREFIX: %v", err)
+		return nil
+	}
+	return prefix
+}()
+
 type EndpointBuilder struct {
 	// These fields define the primary key for an endpoint, and can be used as a cache key
 	clusterName     string
@@ -68,6 +84,14 @@ type EndpointBuilder struct {
 	service         *model.Service
 	clusterLocal    bool
 	tunnelType      networking.TunnelType
+	// nat64Prefix is set if IPv4 endpoints should be rewritten to NAT64 addresses.
+	nat64Prefix *net.IPNet
+	// networkPolicySource is the workload of the proxy, set if endpoints are filtered by network policy.
+	networkPolicySource *model.NetworkPolicyWorkload
+	// networkPolicyKey identifies the network policies selecting networkPolicySource.
+	networkPolicyKey string
+	// leds is set if large load assignments reference their endpoints through LEDS collections.
+	leds bool
 
 	// These fields are provided for convenience only
 	subsetName string
@@ -93,6 +117,7 @@ func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.Push
 		clusterLocal:    push.IsClusterLocal(svc),
 		destinationRule: dr,
 		tunnelType:      GetTunnelBuilderType(clusterName, proxy, push),
+		leds:            ledsEnabled(proxy),
 
 		push:       push,
 		proxy:      proxy,
@@ -100,11 +125,22 @@ func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.Push
 		hostname:   hostname,
 		port:       port,
 	}
-	if b.push.NetworkManager().IsMultiNetworkEnabled() || model.IsDNSSrvSubsetKey(clusterName) {
-		// We only need this for multi-network, or for clusters meant for use with AUTO_PASSTHROUGH
+	if features.EnablePerEndpointAutoMtls || b.push.NetworkManager().IsMultiNetworkEnabled() || model.IsDNSSrvSubsetKey(clusterName) {
+		// We only need this for per endpoint auto mTLS, multi-network, or for clusters meant for use with AUTO_PASSTHROUGH
 		// As an optimization, we skip this logic entirely for everything else.
 		b.mtlsChecker = newMtlsChecker(push, port, dr)
 	}
+	if nat64Prefix != nil && proxy.IsIPv6Only() && svc != nil && svc.Attributes.ServiceRegistry == provider.External {
+		b.nat64Prefix = nat64Prefix
+	}
+	if push.NetworkPolicies() != nil && len(proxy.IPAddresses) > 0 && proxy.ConfigNamespace != "" {
+		b.networkPolicySource = &model.NetworkPolicyWorkload{
+			Namespace: proxy.ConfigNamespace,
+			Labels:    proxy.Metadata.Labels,
+			IP:        proxy.IPAddresses[0],
+		}
+		b.networkPolicyKey = push.NetworkPolicies().SourceKey(b.clusterID, *b.networkPolicySource)
+	}
 	return b
 }
 
@@ -142,6 +178,17 @@ func (b EndpointBuilder) Key() string {
 		sort.Strings(nv)
 		params = append(params, nv...)
 	}
+	if b.nat64Prefix != nil {
+		params = append(params, b.nat64Prefix.String())
+	}
+	if b.networkPolicySource != nil {
+		// Endpoints are filtered for the network policies selecting the workload, and shared by the workloads
+		// they select alike.
+		params = append(params, "networkpolicy", b.networkPolicyKey)
+	}
+	if b.leds {
+		params = append(params, "leds")
+	}
 	return "eds://" + strings.Join(params, "~")
 }
 
@@ -318,13 +365,17 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 			if ep.EnvoyEndpoint == nil {
 				ep.EnvoyEndpoint = buildEnvoyLbEndpoint(ep)
 			}
-			locLbEps.append(ep, ep.EnvoyEndpoint, ep.TunnelAbility)
+			lbEp := ep.EnvoyEndpoint
 
 			// detect if mTLS is possible for this endpoint, used later during ep filtering
 			// this must be done while converting IstioEndpoints because we still have workload labels
 			if b.mtlsChecker != nil {
 				b.mtlsChecker.computeForEndpoint(ep)
+				if features.EnablePerEndpointAutoMtls && b.mtlsChecker.isMtlsDisabled(lbEp) {
+					lbEp = withoutIstioMtlsMetadata(lbEp)
+				}
 			}
+			locLbEps.append(ep, lbEp, ep.TunnelAbility)
 		}
 	}
 	shards.mutex.Unlock()
@@ -401,11 +452,25 @@ func buildEnvoyLbEndpoint(e *model.IstioEndpoint) *endpoint.LbEndpoint {
 	// Istio telemetry depends on the metadata value being set for endpoints in the mesh.
 	// Istio endpoint level tls transport socket configuration depends on this logic
 	// Do not removepilot/pkg/xds/fake.go
-	ep.Metadata = util.BuildLbEndpointMetadata(e.Network, e.TLSMode, e.WorkloadName, e.Namespace, e.Locality.ClusterID, e.Labels)
+	ep.Metadata = util.BuildLbEndpointMetadata(e.Network, e.TLSMode, e.GetCapture(), e.WorkloadName, e.Namespace, e.Locality.ClusterID, e.Labels)
 
 	return ep
 }
 
+// withoutIstioMtlsMetadata returns the lbEp, or a copy of it whose metadata no longer selects the Istio mTLS transport
+// socket of auto mTLS clusters. The endpoint is then sent plaintext, while the other endpoints of the cluster are
+// still sent mTLS.
+func withoutIstioMtlsMetadata(lbEp *endpoint.LbEndpoint) *endpoint.LbEndpoint {
+	md := lbEp.GetMetadata().GetFilterMetadata()[util.EnvoyTransportSocketMetadataKey]
+	if md.GetFields()[model.TLSModeLabelShortname].GetStringValue() != model.IstioMutualTLSModeLabel {
+		return lbEp
+	}
+	// The lbEp is cached in the IstioEndpoint and shared by all the clusters, so it must not be modified.
+	lbEp = proto.Clone(lbEp).(*endpoint.LbEndpoint)
+	delete(lbEp.Metadata.FilterMetadata[util.EnvoyTransportSocketMetadataKey].Fields, model.TLSModeLabelShortname)
+	return lbEp
+}
+
 // TODO this logic is probably done elsewhere in XDS, possible code-reuse + perf improvements
 type mtlsChecker struct {
 	push            *model.PushContext
@@ -440,7 +505,7 @@ func newMtlsChecker(push *model.PushContext, svcPort int, dr *config.Config) *mt
 }
 
 // mTLSDisabled returns true if the given lbEp has mTLS disabled due to any of:
-// - disabled tlsMode
+// - endpoint not captured by the mesh
 // - DestinationRule disabling mTLS on the entire host or the port
 // - PeerAuthentication disabling mTLS at any applicable level (mesh, ns, workload, port)
 func (c *mtlsChecker) isMtlsDisabled(lbEp *endpoint.LbEndpoint) bool {
@@ -454,21 +519,30 @@ func (c *mtlsChecker) isMtlsDisabled(lbEp *endpoint.LbEndpoint) bool {
 // computeForEndpoint checks destination rule, peer authentication and metadata to determine if mTLS was turned off.
 // This must be done during conversion from IstioEndpoint since we still have workload metadata.
 func (c *mtlsChecker) computeForEndpoint(ep *model.IstioEndpoint) {
+	if c.mtlsDisabledReason(ep) != "" {
+		c.mtlsDisabledHosts[lbEpKey(ep.EnvoyEndpoint)] = struct{}{}
+	}
+}
+
+// mtlsDisabledReason returns why mTLS is turned off for the endpoint, or an empty string if it is not.
+func (c *mtlsChecker) mtlsDisabledReason(ep *model.IstioEndpoint) string {
 	if drMode := c.mtlsModeForDestinationRule(ep); drMode != nil {
 		switch *drMode {
 		case networkingapi.ClientTLSSettings_DISABLE:
-			c.mtlsDisabledHosts[lbEpKey(ep.EnvoyEndpoint)] = struct{}{}
-			return
+			return "disabled by the DestinationRule"
 		case networkingapi.ClientTLSSettings_ISTIO_MUTUAL:
 			// don't mark this EP disabled, even if PA or tlsMode meta mark disabled
-			return
+			return ""
 		}
 	}
 
-	if envoytransportSocketMetadata(ep.EnvoyEndpoint, model.TLSModeLabelShortname) != model.IstioMutualTLSModeLabel ||
-		c.mtlsDisabledByPeerAuthentication(ep) {
-		c.mtlsDisabledHosts[lbEpKey(ep.EnvoyEndpoint)] = struct{}{}
+	if ep.GetCapture() == model.CaptureNone {
+		return "no sidecar: the endpoint is not labeled with tlsMode " + model.IstioMutualTLSModeLabel
 	}
+	if c.mtlsDisabledByPeerAuthentication(ep) {
+		return "disabled by a PeerAuthentication of the endpoint"
+	}
+	return ""
 }
 
 func (c *mtlsChecker) mtlsDisabledByPeerAuthentication(ep *model.IstioEndpoint) bool {
@@ -21,6 +21,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/wrappers"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -152,7 +153,7 @@ func (b *EndpointBuilder) EndpointsByNetworkFilter(endpoints []*LocLbEndpointsAn
 				},
 			}
 			// TODO: figure out a way to extract locality data from the gateway public endpoints in meshNetworks
-			gwEp.Metadata = util.BuildLbEndpointMetadata(gw.Network, model.IstioMutualTLSModeLabel,
+			gwEp.Metadata = util.BuildLbEndpointMetadata(gw.Network, model.IstioMutualTLSModeLabel, model.CaptureSidecar,
 				"", "", b.clusterID, labels.Instance{})
 			// Currently gateway endpoint does not support tunnel.
 			lbEndpoints.append(gwIstioEp, gwEp, networking.MakeTunnelAbility())
@@ -204,6 +205,83 @@ func (b *EndpointBuilder) gatewaysByNetwork() map[network.ID][]*model.NetworkGat
 	return byNetwork
 }
 
+// EndpointsByNetworkPolicyFilter removes endpoints that platform network policies prevent the proxy
+// from reaching, or moves them to a lower priority, depending on PILOT_NETWORK_POLICY_EDS_FILTER.
+func (b *EndpointBuilder) EndpointsByNetworkPolicyFilter(endpoints []*LocLbEndpointsAndOptions) []*LocLbEndpointsAndOptions {
+	if b.networkPolicySource == nil {
+		return endpoints
+	}
+	evaluator := b.push.NetworkPolicies()
+	deprioritize := features.NetworkPolicyEDSFilter == "deprioritize"
+
+	filtered := make([]*LocLbEndpointsAndOptions, 0, len(endpoints))
+	for _, ep := range endpoints {
+		allowed := &LocLbEndpointsAndOptions{
+			llbEndpoints: endpoint.LocalityLbEndpoints{
+				Locality: ep.llbEndpoints.Locality,
+				Priority: ep.llbEndpoints.Priority,
+			},
+		}
+		denied := &LocLbEndpointsAndOptions{
+			llbEndpoints: endpoint.LocalityLbEndpoints{
+				Locality: ep.llbEndpoints.Locality,
+				Priority: ep.llbEndpoints.Priority + 1,
+			},
+		}
+		for i, lbEp := range ep.llbEndpoints.LbEndpoints {
+			istioEndpoint := ep.istioEndpoints[i]
+			target := allowed
+			// Network policies are only enforced within a cluster.
+			if istioEndpoint.Locality.ClusterID == b.clusterID && !evaluator.Allowed(b.clusterID, *b.networkPolicySource,
+				model.NetworkPolicyWorkload{
+					Namespace: istioEndpoint.Namespace,
+					Labels:    istioEndpoint.Labels,
+					IP:        istioEndpoint.Address,
+				}, istioEndpoint.EndpointPort) {
+				if !deprioritize {
+					continue
+				}
+				target = denied
+			}
+			target.istioEndpoints = append(target.istioEndpoints, istioEndpoint)
+			target.emplace(lbEp, ep.tunnelMetadata[i])
+		}
+		allowed.refreshWeight()
+		filtered = append(filtered, allowed)
+		if len(denied.llbEndpoints.LbEndpoints) > 0 {
+			denied.refreshWeight()
+			filtered = append(filtered, denied)
+		}
+	}
+
+	return filtered
+}
+
+// EndpointsWithNAT64 rewrites IPv4 endpoint addresses to addresses synthesized from the NAT64 prefix,
+// allowing IPv6-only proxies to reach IPv4-only ServiceEntry endpoints.
+func (b *EndpointBuilder) EndpointsWithNAT64(endpoints []*LocLbEndpointsAndOptions) []*LocLbEndpointsAndOptions {
+	if b.nat64Prefix == nil {
+		return endpoints
+	}
+	for _, ep := range endpoints {
+		for i, lbEp := range ep.llbEndpoints.LbEndpoints {
+			addr := lbEp.GetEndpoint().GetAddress().GetSocketAddress()
+			if addr == nil {
+				continue
+			}
+			synthesized := util.NAT64Address(b.nat64Prefix, addr.Address)
+			if synthesized == addr.Address {
+				continue
+			}
+			// Endpoints may be shared with other proxies, so copy before modifying.
+			lbEp = proto.Clone(lbEp).(*endpoint.LbEndpoint)
+			lbEp.GetEndpoint().GetAddress().GetSocketAddress().Address = synthesized
+			ep.llbEndpoints.LbEndpoints[i] = lbEp
+		}
+	}
+	return endpoints
+}
+
 // EndpointsWithMTLSFilter removes all endpoints that do not handle mTLS. This is determined by looking at
 // auto-mTLS, DestinationRule, and PeerAuthentication to determine if we would send mTLS to these endpoints.
 // Note there is no guarantee these destinations *actually* handle mTLS; just that we are configured to send mTLS to them.
@@ -235,13 +313,3 @@ func (b *EndpointBuilder) EndpointsWithMTLSFilter(endpoints []*LocLbEndpointsAnd
 
 	return filtered
 }
-
-func envoytransportSocketMetadata(ep *endpoint.LbEndpoint, key string) string {
-	if ep.Metadata != nil &&
-		ep.Metadata.FilterMetadata[util.EnvoyTransportSocketMetadataKey] != nil &&
-		ep.Metadata.FilterMetadata[util.EnvoyTransportSocketMetadataKey].Fields != nil &&
-		ep.Metadata.FilterMetadata[util.EnvoyTransportSocketMetadataKey].Fields[key] != nil {
-		return ep.Metadata.FilterMetadata[util.EnvoyTransportSocketMetadataKey].Fields[key].GetStringValue()
-	}
-	return ""
-}
@@ -0,0 +1,50 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// reportPushError reports a failure to generate or send the configuration of a proxy as a Kubernetes Event.
+// Connections closing down are expected, and not reported.
+func (s *DiscoveryServer) reportPushError(con *Connection, typeURL string, err error) {
+	if s.Events == nil || !isUnexpectedError(err) {
+		return
+	}
+	s.Events.PushFailed(con.proxy, fmt.Sprintf("%s: %v", v3.GetShortType(typeURL), err))
+}
+
+// reportRejectedConfigs reports the configs rejected by a new push context as Kubernetes Events. Configs already
+// rejected for the same reason by the previous push context were already reported.
+func (s *DiscoveryServer) reportRejectedConfigs(push, oldPushContext *model.PushContext) {
+	if s.Events == nil {
+		return
+	}
+	previous := oldPushContext.RejectedConfigs()
+	for key, reason := range push.RejectedConfigs() {
+		if r, f := previous[key]; f && r == reason {
+			continue
+		}
+		cfg := s.Env.Get(key.Kind, key.Name, key.Namespace)
+		if cfg == nil {
+			continue
+		}
+		s.Events.ConfigRejected(*cfg, reason)
+	}
+}
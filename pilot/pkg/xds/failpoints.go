@@ -0,0 +1,196 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	uatomic "go.uber.org/atomic"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// Failpoints inject failures in the xDS server, to test its resilience to partial failures. They are enabled
+// by tests, or through the /debug/failpoints endpoint if unsafe admin endpoints are enabled.
+const (
+	// FailpointDelayPush delays the pushes to the matching proxies by Failpoint.Delay.
+	FailpointDelayPush = "delay-push"
+	// FailpointDropAck ignores the requests of the matching proxies ACKing a response, as if they were lost.
+	FailpointDropAck = "drop-ack"
+	// FailpointCorruptNonce sends the responses to the matching proxies with a nonce the server does not know of.
+	FailpointCorruptNonce = "corrupt-nonce"
+	// FailpointSlowGenerator delays the generation of the matching types by Failpoint.Delay.
+	FailpointSlowGenerator = "slow-generator"
+)
+
+var knownFailpoints = map[string]struct{}{
+	FailpointDelayPush:     {},
+	FailpointDropAck:       {},
+	FailpointCorruptNonce:  {},
+	FailpointSlowGenerator: {},
+}
+
+// Failpoint configures an enabled failpoint.
+type Failpoint struct {
+	// Proxy restricts the failpoint to the proxies with an ID containing it. Empty matches all proxies.
+	Proxy string `json:"proxy,omitempty"`
+	// Type restricts the failpoint to an xDS type, such as CDS. Empty matches all types.
+	Type string `json:"type,omitempty"`
+	// Delay is the delay injected by the delay failpoints.
+	Delay time.Duration `json:"delay,omitempty"`
+}
+
+// Failpoints holds the enabled failpoints. A nil Failpoints has none enabled.
+type Failpoints struct {
+	// active is set if any failpoint is enabled, so the common case does not take the lock.
+	active uatomic.Bool
+	mu     sync.RWMutex
+	points map[string]Failpoint
+}
+
+func NewFailpoints() *Failpoints {
+	return &Failpoints{points: map[string]Failpoint{}}
+}
+
+// Enable enables a failpoint, replacing its previous configuration.
+func (f *Failpoints) Enable(name string, fp Failpoint) error {
+	if _, f := knownFailpoints[name]; !f {
+		return fmt.Errorf("unknown failpoint %q", name)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.points[name] = fp
+	f.active.Store(true)
+	log.Warnf("failpoint %s enabled: %+v", name, fp)
+	return nil
+}
+
+// Disable disables a failpoint, or all failpoints if name is empty.
+func (f *Failpoints) Disable(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if name == "" {
+		f.points = map[string]Failpoint{}
+	} else {
+		delete(f.points, name)
+	}
+	f.active.Store(len(f.points) > 0)
+}
+
+// List returns the enabled failpoints.
+func (f *Failpoints) List() map[string]Failpoint {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make(map[string]Failpoint, len(f.points))
+	for name, fp := range f.points {
+		out[name] = fp
+	}
+	return out
+}
+
+// get returns a failpoint if it is enabled for a proxy and type. An empty typeURL matches any type.
+func (f *Failpoints) get(name string, proxy *model.Proxy, typeURL string) (Failpoint, bool) {
+	if f == nil || !f.active.Load() {
+		return Failpoint{}, false
+	}
+	f.mu.RLock()
+	fp, ok := f.points[name]
+	f.mu.RUnlock()
+	if !ok {
+		return Failpoint{}, false
+	}
+	if fp.Proxy != "" && (proxy == nil || !strings.Contains(proxy.ID, fp.Proxy)) {
+		return Failpoint{}, false
+	}
+	if fp.Type != "" && typeURL != "" && !strings.EqualFold(fp.Type, v3.GetShortType(typeURL)) {
+		return Failpoint{}, false
+	}
+	return fp, true
+}
+
+// delay sleeps for the delay of a failpoint, if it is enabled for a proxy and type.
+func (f *Failpoints) delay(name string, proxy *model.Proxy, typeURL string) {
+	if fp, ok := f.get(name, proxy, typeURL); ok && fp.Delay > 0 {
+		time.Sleep(fp.Delay)
+	}
+}
+
+// dropAck returns whether a request ACKing or NACKing a response should be dropped.
+func (f *Failpoints) dropAck(proxy *model.Proxy, typeURL, nonce string) bool {
+	if nonce == "" {
+		return false
+	}
+	if _, ok := f.get(FailpointDropAck, proxy, typeURL); ok {
+		log.Infof("failpoint %s: dropping request of %s for %s", FailpointDropAck, proxy.ID, v3.GetShortType(typeURL))
+		return true
+	}
+	return false
+}
+
+// corruptNonce replaces the nonce of a response, if the failpoint is enabled. The returned function restores the
+// original nonce as the one sent to the proxy, once the response is sent.
+func (f *Failpoints) corruptNonce(con *Connection, typeURL string, nonce *string) func() {
+	if _, ok := f.get(FailpointCorruptNonce, con.proxy, typeURL); !ok {
+		return func() {}
+	}
+	original := *nonce
+	*nonce = "corrupt-" + original
+	return func() {
+		con.proxy.Lock()
+		defer con.proxy.Unlock()
+		if w := con.proxy.WatchedResources[typeURL]; w != nil {
+			w.NonceSent = original
+		}
+	}
+}
+
+// failpointsz lists the enabled failpoints. POST enables the failpoint named by the name parameter, configured by
+// the proxy, type and delay parameters. DELETE disables the failpoint named by the name parameter, or all of them.
+func (s *DiscoveryServer) failpointsz(w http.ResponseWriter, req *http.Request) {
+	if !s.AllowMeshWide(w, req) {
+		return
+	}
+	_ = req.ParseForm()
+	name := req.Form.Get("name")
+	switch req.Method {
+	case http.MethodPost:
+		fp := Failpoint{
+			Proxy: req.Form.Get("proxy"),
+			Type:  req.Form.Get("type"),
+		}
+		if d := req.Form.Get("delay"); d != "" {
+			delay, err := time.ParseDuration(d)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(fmt.Sprintf("invalid delay: %v", err)))
+				return
+			}
+			fp.Delay = delay
+		}
+		if err := s.Failpoints.Enable(name, fp); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+	case http.MethodDelete:
+		s.Failpoints.Disable(name)
+	}
+	writeJSON(w, s.Failpoints.List())
+}
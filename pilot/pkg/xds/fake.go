@@ -17,17 +17,20 @@ package xds
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"strings"
 	"time"
 
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/test/bufconn"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/config/kube/ingress"
@@ -81,8 +84,16 @@ type FakeOptions struct {
 	// By default, set to 0s to speed up tests
 	DebounceTime time.Duration
 
+	// VirtualTime makes the debounce windows measured by a fake clock, only moved by AdvanceTime and
+	// AdvanceDebounce, so that tests control when config updates are pushed rather than sleeping.
+	VirtualTime bool
+
 	// EnableFakeXDSUpdater will use a XDSUpdater that can be used to watch events
 	EnableFakeXDSUpdater bool
+
+	// Replicas is the number of additional istiod instances to run, sharing the config store and service
+	// registries of the server, to test behaviors across replicas.
+	Replicas int
 }
 
 type FakeDiscoveryServer struct {
@@ -93,6 +104,11 @@ type FakeDiscoveryServer struct {
 	kubeClient   kubelib.Client
 	KubeRegistry *kube.FakeController
 	XdsUpdater   model.XDSUpdater
+	// Clock is the virtual clock of the debounce windows, if FakeOptions.VirtualTime is set. It is shared
+	// by all replicas.
+	Clock *clocktesting.FakeClock
+	// Replicas are the additional istiod instances, if FakeOptions.Replicas is set.
+	Replicas []*FakeDiscoveryServer
 }
 
 func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServer {
@@ -114,6 +130,25 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 		s.JwtKeyResolver.Close()
 		s.pushQueue.ShutDown()
 	})
+	servers := []*DiscoveryServer{s}
+	for i := 0; i < opts.Replicas; i++ {
+		r := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()},
+			[]string{plugin.AuthzCustom, plugin.Authn, plugin.Authz}, fmt.Sprintf("pilot-123-%d", i+1), "istio-system")
+		t.Cleanup(func() {
+			r.JwtKeyResolver.Close()
+			r.pushQueue.ShutDown()
+		})
+		servers = append(servers, r)
+	}
+	// The updates of the shared registries and config store go to every replica.
+	var updater model.XDSUpdater = s
+	if opts.Replicas > 0 {
+		replicated := replicatedXDSUpdater{}
+		for _, server := range servers {
+			replicated = append(replicated, server)
+		}
+		updater = replicated
+	}
 
 	serviceHandler := func(svc *model.Service, _ model.Event) {
 		pushReq := &model.PushRequest{
@@ -125,7 +160,7 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 			}: {}},
 			Reason: []model.TriggerReason{model.ServiceUpdate},
 		}
-		s.ConfigUpdate(pushReq)
+		updater.ConfigUpdate(pushReq)
 	}
 
 	k8sObjects := getKubernetesObjects(t, opts)
@@ -134,18 +169,18 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 	var registries []serviceregistry.Instance
 	if opts.NetworksWatcher != nil {
 		opts.NetworksWatcher.AddNetworksHandler(func() {
-			s.ConfigUpdate(&model.PushRequest{
+			updater.ConfigUpdate(&model.PushRequest{
 				Full:   true,
 				Reason: []model.TriggerReason{model.NetworksTrigger},
 			})
 		})
 	}
-	var xdsUpdater model.XDSUpdater = s
+	xdsUpdater := updater
 	if opts.EnableFakeXDSUpdater {
 		evChan := make(chan FakeXdsEvent, 1000)
 		xdsUpdater = &FakeXdsUpdater{
 			Events:   evChan,
-			Delegate: s,
+			Delegate: updater,
 		}
 	}
 	for k8sCluster, objs := range k8sObjects {
@@ -176,7 +211,9 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 	}
 
 	sc := kubesecrets.NewMulticluster(defaultKubeClient, "", "", stop)
-	s.Generators[v3.SecretType] = NewSecretGen(sc, s.Cache)
+	for _, server := range servers {
+		server.Generators[v3.SecretType] = NewSecretGen(sc, server.Cache)
+	}
 	defaultKubeClient.RunAndWait(stop)
 
 	ingr := ingress.NewController(defaultKubeClient, mesh.NewFixedWatcher(m), kube.Options{
@@ -196,13 +233,36 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 		SkipRun:             true,
 	})
 	cg.ServiceEntryRegistry.AppendServiceHandler(serviceHandler)
-	s.updateMutex.Lock()
-	s.Env = cg.Env()
-	// Disable debounce to reduce test times
-	s.debounceOptions.debounceAfter = opts.DebounceTime
-	s.MemRegistry = cg.MemRegistry
-	s.MemRegistry.EDSUpdater = s
-	s.updateMutex.Unlock()
+	var fakeClock *clocktesting.FakeClock
+	if opts.VirtualTime {
+		fakeClock = clocktesting.NewFakeClock(time.Now())
+	}
+	for i, server := range servers {
+		env := cg.Env()
+		if i > 0 {
+			// Replicas share the registries and config store, but build their own push contexts.
+			env = &model.Environment{
+				ServiceDiscovery: env.ServiceDiscovery,
+				IstioConfigStore: env.IstioConfigStore,
+				Watcher:          env.Watcher,
+				NetworksWatcher:  env.NetworksWatcher,
+				DomainSuffix:     env.DomainSuffix,
+				PushContext:      model.NewPushContext(),
+			}
+			env.Init()
+		}
+		server.updateMutex.Lock()
+		server.Env = env
+		// Disable debounce to reduce test times
+		server.debounceOptions.debounceAfter = opts.DebounceTime
+		if fakeClock != nil {
+			server.debounceOptions.clock = fakeClock
+			server.debounceOptions.received = atomic.NewInt64(0)
+		}
+		server.MemRegistry = cg.MemRegistry
+		server.updateMutex.Unlock()
+	}
+	cg.MemRegistry.EDSUpdater = updater
 
 	// Setup config handlers
 	// TODO code re-use from server.go
@@ -216,7 +276,7 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 			}: {}},
 			Reason: []model.TriggerReason{model.ConfigUpdate},
 		}
-		s.ConfigUpdate(pushReq)
+		updater.ConfigUpdate(pushReq)
 	}
 	schemas := collections.Pilot.All()
 	if features.EnableServiceApis {
@@ -243,28 +303,36 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 		cg.ServiceEntryRegistry.AppendWorkloadHandler(k8s.WorkloadInstanceHandler)
 		k8s.AppendWorkloadHandler(cg.ServiceEntryRegistry.WorkloadInstanceHandler)
 	}
-	s.WorkloadEntryController = workloadentry.NewController(cg.Store(), "test", keepalive.Infinity)
-
-	if opts.DiscoveryServerModifier != nil {
-		opts.DiscoveryServerModifier(s)
-	}
+	listeners := make([]*bufconn.Listener, 0, len(servers))
+	for i, server := range servers {
+		instance := "test"
+		if i > 0 {
+			instance = fmt.Sprintf("test-%d", i)
+		}
+		server.WorkloadEntryController = workloadentry.NewController(cg.Store(), instance, keepalive.Infinity)
 
-	// Start in memory gRPC listener
-	buffer := 1024 * 1024
-	listener := bufconn.Listen(buffer)
-	grpcServer := grpc.NewServer()
-	s.Register(grpcServer)
-	go func() {
-		if err := grpcServer.Serve(listener); err != nil && !(err == grpc.ErrServerStopped || err.Error() == "closed") {
-			t.Fatal(err)
+		if opts.DiscoveryServerModifier != nil {
+			opts.DiscoveryServerModifier(server)
 		}
-	}()
-	t.Cleanup(func() {
-		grpcServer.Stop()
-	})
-	// Start the discovery server
-	s.Start(stop)
-	cg.ServiceEntryRegistry.XdsUpdater = s
+
+		// Start in memory gRPC listener
+		buffer := 1024 * 1024
+		listener := bufconn.Listen(buffer)
+		grpcServer := grpc.NewServer()
+		server.Register(grpcServer)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil && !(err == grpc.ErrServerStopped || err.Error() == "closed") {
+				t.Fatal(err)
+			}
+		}()
+		t.Cleanup(func() {
+			grpcServer.Stop()
+		})
+		listeners = append(listeners, listener)
+		// Start the discovery server
+		server.Start(stop)
+	}
+	cg.ServiceEntryRegistry.XdsUpdater = updater
 	cache.WaitForCacheSync(stop,
 		cg.Registry.HasSynced,
 		cg.Store().HasSynced)
@@ -272,31 +340,111 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 
 	// Send an update. This ensures that even if there are no configs provided, the push context is
 	// initialized.
-	s.ConfigUpdate(&model.PushRequest{Full: true})
+	updater.ConfigUpdate(&model.PushRequest{Full: true})
 
 	// Now that handlers are added, get everything started
 	cg.Run()
 
-	// Wait until initial updates are committed
-	c := s.InboundUpdates.Load()
-	retry.UntilOrFail(t, func() bool {
-		return s.CommittedUpdates.Load() >= c
-	}, retry.Delay(time.Millisecond))
+	fakes := make([]*FakeDiscoveryServer, 0, len(servers))
+	for i, server := range servers {
+		// Wait until initial updates are committed
+		c := server.InboundUpdates.Load()
+		retry.UntilOrFail(t, func() bool {
+			if fakeClock != nil {
+				// Nothing is pushed until the virtual clock moves past the debounce window.
+				fakeClock.Step(server.debounceOptions.debounceMax)
+			}
+			return server.CommittedUpdates.Load() >= c
+		}, retry.Delay(time.Millisecond))
+
+		// Mark ourselves ready
+		server.CachesSynced()
+
+		fakes = append(fakes, &FakeDiscoveryServer{
+			t:             t,
+			Discovery:     server,
+			Listener:      listeners[i],
+			ConfigGenTest: cg,
+			kubeClient:    defaultKubeClient,
+			KubeRegistry:  defaultKubeController,
+			XdsUpdater:    xdsUpdater,
+			Clock:         fakeClock,
+		})
+	}
 
-	// Mark ourselves ready
-	s.CachesSynced()
+	fake := fakes[0]
+	fake.Replicas = fakes[1:]
+	return fake
+}
+
+// MoveConnection closes the connection of a proxy, and connects it to this server instead, as when a proxy is
+// moved to another istiod replica. watch and wait are used as in Connect.
+func (f *FakeDiscoveryServer) MoveConnection(old *adsc.ADSC, p *model.Proxy, watch []string, wait []string) *adsc.ADSC {
+	f.t.Helper()
+	old.Close()
+	return f.Connect(p, watch, wait)
+}
 
-	fake := &FakeDiscoveryServer{
-		t:             t,
-		Discovery:     s,
-		Listener:      listener,
-		ConfigGenTest: cg,
-		kubeClient:    defaultKubeClient,
-		KubeRegistry:  defaultKubeController,
-		XdsUpdater:    xdsUpdater,
+// replicatedXDSUpdater forwards the updates of the shared registries and config store to every istiod replica.
+type replicatedXDSUpdater []model.XDSUpdater
+
+var _ model.XDSUpdater = replicatedXDSUpdater{}
+
+func (r replicatedXDSUpdater) EDSUpdate(shard, hostname string, namespace string, entry []*model.IstioEndpoint) {
+	for _, u := range r {
+		u.EDSUpdate(shard, hostname, namespace, entry)
 	}
+}
 
-	return fake
+func (r replicatedXDSUpdater) EDSCacheUpdate(shard, hostname string, namespace string, entry []*model.IstioEndpoint) {
+	for _, u := range r {
+		u.EDSCacheUpdate(shard, hostname, namespace, entry)
+	}
+}
+
+func (r replicatedXDSUpdater) SvcUpdate(shard, hostname string, namespace string, event model.Event) {
+	for _, u := range r {
+		u.SvcUpdate(shard, hostname, namespace, event)
+	}
+}
+
+func (r replicatedXDSUpdater) ConfigUpdate(req *model.PushRequest) {
+	for _, u := range r {
+		// Each replica sets the push context of its requests, so they can not be shared.
+		cp := *req
+		u.ConfigUpdate(&cp)
+	}
+}
+
+func (r replicatedXDSUpdater) ProxyUpdate(clusterID cluster.ID, ip string) {
+	for _, u := range r {
+		u.ProxyUpdate(clusterID, ip)
+	}
+}
+
+// AdvanceTime moves the virtual clock forward. It first waits for the debouncers of the servers sharing the clock to
+// take the config updates sent so far, so that their debounce window starts before the clock moves.
+func (f *FakeDiscoveryServer) AdvanceTime(d time.Duration) {
+	if f.Clock == nil {
+		f.t.Fatal("AdvanceTime requires FakeOptions.VirtualTime")
+	}
+	for _, server := range append([]*FakeDiscoveryServer{f}, f.Replicas...) {
+		ds := server.Discovery
+		retry.UntilOrFail(f.t, func() bool {
+			return ds.debounceOptions.received.Load() >= ds.InboundUpdates.Load()
+		}, retry.Delay(time.Millisecond))
+	}
+	f.Clock.Step(d)
+}
+
+// AdvanceDebounce moves the virtual clock past the debounce window, and waits for the config updates sent so far
+// to be pushed.
+func (f *FakeDiscoveryServer) AdvanceDebounce() {
+	c := f.Discovery.InboundUpdates.Load()
+	f.AdvanceTime(f.Discovery.debounceOptions.debounceAfter)
+	retry.UntilOrFail(f.t, func() bool {
+		return f.Discovery.CommittedUpdates.Load() >= c
+	}, retry.Delay(time.Millisecond))
 }
 
 func (f *FakeDiscoveryServer) KubeClient() kubelib.Client {
@@ -306,7 +454,7 @@ func (f *FakeDiscoveryServer) KubeClient() kubelib.Client {
 func (f *FakeDiscoveryServer) PushContext() *model.PushContext {
 	f.Discovery.updateMutex.RLock()
 	defer f.Discovery.updateMutex.RUnlock()
-	return f.Env().PushContext
+	return f.Discovery.Env.PushContext
 }
 
 // ConnectADS starts an ADS connection to the server. It will automatically be cleaned up when the test ends
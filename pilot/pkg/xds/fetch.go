@@ -0,0 +1,129 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"time"
+
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	listenerservice "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	routeservice "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// fetchServer implements the unary Fetch methods of the CDS, EDS, LDS and RDS services. Streaming clients are
+// expected to use ADS, so the other methods are left unimplemented.
+type fetchServer struct {
+	clusterservice.UnimplementedClusterDiscoveryServiceServer
+	endpointservice.UnimplementedEndpointDiscoveryServiceServer
+	listenerservice.UnimplementedListenerDiscoveryServiceServer
+	routeservice.UnimplementedRouteDiscoveryServiceServer
+
+	s *DiscoveryServer
+}
+
+func (f *fetchServer) FetchClusters(ctx context.Context, req *discovery.DiscoveryRequest) (*discovery.DiscoveryResponse, error) {
+	return f.s.Fetch(ctx, v3.ClusterType, req)
+}
+
+func (f *fetchServer) FetchEndpoints(ctx context.Context, req *discovery.DiscoveryRequest) (*discovery.DiscoveryResponse, error) {
+	return f.s.Fetch(ctx, v3.EndpointType, req)
+}
+
+func (f *fetchServer) FetchListeners(ctx context.Context, req *discovery.DiscoveryRequest) (*discovery.DiscoveryResponse, error) {
+	return f.s.Fetch(ctx, v3.ListenerType, req)
+}
+
+func (f *fetchServer) FetchRoutes(ctx context.Context, req *discovery.DiscoveryRequest) (*discovery.DiscoveryResponse, error) {
+	return f.s.Fetch(ctx, v3.RouteType, req)
+}
+
+// registerFetch adds the Fetch handlers to the grpc server.
+func (s *DiscoveryServer) registerFetch(rpcs *grpc.Server) {
+	f := &fetchServer{s: s}
+	clusterservice.RegisterClusterDiscoveryServiceServer(rpcs, f)
+	endpointservice.RegisterEndpointDiscoveryServiceServer(rpcs, f)
+	listenerservice.RegisterListenerDiscoveryServiceServer(rpcs, f)
+	routeservice.RegisterRouteDiscoveryServiceServer(rpcs, f)
+}
+
+// Fetch generates a point in time snapshot of the config of the given type for the node of the request,
+// without opening a stream. Clients are authenticated and authorized the same way as ADS clients, but are not
+// registered as connected proxies: they do not receive pushes, and workload auto registration is not triggered.
+func (s *DiscoveryServer) Fetch(ctx context.Context, typeURL string, req *discovery.DiscoveryRequest) (*discovery.DiscoveryResponse, error) {
+	if !s.IsServerReady() {
+		return nil, status.Error(codes.Unavailable, "server is not ready to serve discovery information")
+	}
+	if req.TypeUrl != "" && req.TypeUrl != typeURL {
+		return nil, status.Errorf(codes.InvalidArgument, "unexpected type %s, expected %s", req.TypeUrl, typeURL)
+	}
+	if req.Node == nil || req.Node.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing node information")
+	}
+	peerAddr := "0.0.0.0"
+	if peerInfo, ok := peer.FromContext(ctx); ok {
+		peerAddr = peerInfo.Addr.String()
+	}
+	ids, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	proxy, err := s.initProxyMetadata(req.Node)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	con := newConnection(peerAddr, nil)
+	con.Identities = ids
+	con.node = req.Node
+	con.proxy = proxy
+	if err := s.verifyIdentity(con); err != nil {
+		return nil, err
+	}
+	s.initProxyState(req.Node, proxy)
+
+	w := &model.WatchedResource{TypeUrl: typeURL, ResourceNames: req.ResourceNames, LastRequest: req}
+	gen := s.findGenerator(typeURL, con)
+	if gen == nil {
+		return nil, status.Errorf(codes.Unimplemented, "no generator for %s", typeURL)
+	}
+	push := s.globalPushContext()
+	res, _, err := gen.Generate(proxy, push, w, &model.PushRequest{
+		Full:   true,
+		Push:   push,
+		Start:  time.Now(),
+		Reason: []model.TriggerReason{model.ProxyRequest},
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	log.Debugf("%s: FETCH for node:%s resources:%d", v3.GetShortType(typeURL), proxy.ID, len(res))
+	return &discovery.DiscoveryResponse{
+		ControlPlane: ControlPlane(),
+		TypeUrl:      typeURL,
+		VersionInfo:  push.PushVersion,
+		Nonce:        nonce(push.LedgerVersion),
+		Resources:    model.ResourcesToAny(res),
+	}, nil
+}
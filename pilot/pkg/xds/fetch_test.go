@@ -0,0 +1,68 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestFetch(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	conn, err := grpc.Dial("buffcon", grpc.WithInsecure(), grpc.WithBlock(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return s.Listener.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := clusterservice.NewClusterDiscoveryServiceClient(conn)
+	node := &core.Node{
+		Id:       "sidecar~1.1.1.1~test.default~default.svc.cluster.local",
+		Metadata: model.NodeMetadata{}.ToStruct(),
+	}
+
+	resp, err := client.FetchClusters(context.Background(), &discovery.DiscoveryRequest{Node: node})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TypeUrl != v3.ClusterType || len(resp.Resources) == 0 {
+		t.Fatalf("expected clusters, got type %q with %d resources", resp.TypeUrl, len(resp.Resources))
+	}
+	if clients := s.Discovery.AllClients(); len(clients) != 0 {
+		t.Fatalf("expected fetch not to register a connection, got %d", len(clients))
+	}
+
+	_, err = client.FetchClusters(context.Background(), &discovery.DiscoveryRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected request without node to be rejected, got %v", err)
+	}
+	_, err = client.FetchClusters(context.Background(), &discovery.DiscoveryRequest{Node: node, TypeUrl: v3.ListenerType})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected request for another type to be rejected, got %v", err)
+	}
+}
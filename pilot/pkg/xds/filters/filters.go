@@ -22,6 +22,7 @@ import (
 	fault "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
 	grpcstats "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_stats/v3"
 	grpcweb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_web/v3"
+	ondemand "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/on_demand/v3"
 	router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	httpinspector "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/http_inspector/v3"
@@ -50,6 +51,9 @@ const (
 	RawBufferTransportProtocol = "raw_buffer"
 
 	MxFilterName = "istio.metadata_exchange"
+
+	// OnDemandFilterName is the HTTP filter fetching the route of a scope the first time it is used.
+	OnDemandFilterName = "envoy.filters.http.on_demand"
 )
 
 // Define static filters to be reused across the codebase. This avoids duplicate marshaling/unmarshaling
@@ -73,6 +77,12 @@ var (
 			TypedConfig: util.MessageToAny(&router.Router{}),
 		},
 	}
+	OnDemand = &hcm.HttpFilter{
+		Name: OnDemandFilterName,
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: util.MessageToAny(&ondemand.OnDemand{}),
+		},
+	}
 	GrpcWeb = &hcm.HttpFilter{
 		Name: wellknown.GRPCWeb,
 		ConfigType: &hcm.HttpFilter_TypedConfig{
@@ -0,0 +1,232 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// Load assignments with more than features.LedsEndpointThreshold endpoints reference the endpoints of each of
+// their localities through a LEDS glob collection, named
+// xdstp:///envoy.config.endpoint.v3.LbEndpoint/<cluster>/<priority|locality>/*. Each endpoint is a resource of
+// its collection, named after its address, so a scale event only sends the endpoints which changed.
+
+const ledsCollectionPrefix = "xdstp:///envoy.config.endpoint.v3.LbEndpoint/"
+
+// ledsEnabled returns whether large load assignments of a proxy reference their endpoints through LEDS.
+func ledsEnabled(proxy *model.Proxy) bool {
+	// gRPC proxies do not support LEDS.
+	return features.LedsEndpointThreshold > 0 && proxy.Metadata.Generator == ""
+}
+
+// ledsCollectionName returns the name of the LEDS collection holding the endpoints of a locality of a cluster.
+func ledsCollectionName(clusterName string, llb *endpoint.LocalityLbEndpoints) string {
+	locality := strconv.Itoa(int(llb.Priority)) + "|" + util.LocalityToString(llb.Locality)
+	return ledsCollectionPrefix + url.PathEscape(clusterName) + "/" + url.PathEscape(locality) + "/*"
+}
+
+// parseLedsCollectionName returns the cluster of a LEDS collection name.
+func parseLedsCollectionName(name string) (string, bool) {
+	if !strings.HasPrefix(name, ledsCollectionPrefix) || !strings.HasSuffix(name, "/*") {
+		return "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(name, ledsCollectionPrefix), "/")
+	if len(parts) != 3 {
+		return "", false
+	}
+	clusterName, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", false
+	}
+	return clusterName, true
+}
+
+// ledsResourceName returns the name of an endpoint in a LEDS collection.
+func ledsResourceName(collection string, ep *endpoint.LbEndpoint) string {
+	return strings.TrimSuffix(collection, "*") + url.PathEscape(endpointKey(ep))
+}
+
+// endpointKey identifies an endpoint of a load assignment by its address.
+func endpointKey(ep *endpoint.LbEndpoint) string {
+	address := ep.GetEndpoint().GetAddress()
+	if sa := address.GetSocketAddress(); sa != nil {
+		return sa.Address + ":" + strconv.Itoa(int(sa.GetPortValue()))
+	}
+	return address.GetPipe().GetPath()
+}
+
+// ledsClusterLoadAssignment replaces the endpoints of a load assignment with more than
+// features.LedsEndpointThreshold endpoints by references to the LEDS collections of its localities.
+func ledsClusterLoadAssignment(l *endpoint.ClusterLoadAssignment) *endpoint.ClusterLoadAssignment {
+	count := 0
+	for _, llb := range l.Endpoints {
+		count += len(llb.LbEndpoints)
+	}
+	if count <= features.LedsEndpointThreshold {
+		return l
+	}
+	out := util.CloneClusterLoadAssignment(l)
+	for _, llb := range out.Endpoints {
+		llb.LbConfig = &endpoint.LocalityLbEndpoints_LedsClusterLocalityConfig{
+			LedsClusterLocalityConfig: &endpoint.LedsClusterLocalityConfig{
+				LedsConfig: &core.ConfigSource{
+					ConfigSourceSpecifier: &core.ConfigSource_Ads{
+						Ads: &core.AggregatedConfigSource{},
+					},
+					ResourceApiVersion: core.ApiVersion_V3,
+				},
+				LedsCollectionName: ledsCollectionName(l.ClusterName, llb),
+			},
+		}
+		llb.LbEndpoints = nil
+	}
+	return out
+}
+
+// LedsGenerator generates the endpoints of the LEDS collections watched by a proxy.
+type LedsGenerator struct {
+	Server *DiscoveryServer
+}
+
+var (
+	_ model.XdsResourceGenerator      = &LedsGenerator{}
+	_ model.XdsDeltaResourceGenerator = &LedsGenerator{}
+)
+
+// endpoints returns the endpoints of the watched collections, with the hash of each endpoint, and the collections
+// generated. Unless updatedOnly is nil, only the collections of the updated services are generated.
+func (c LedsGenerator) endpoints(proxy *model.Proxy, push *model.PushContext, collections []string,
+	updatedOnly map[string]struct{}) (model.Resources, map[string]string, map[string]struct{}) {
+	watched := make(map[string][]string)
+	for _, collection := range collections {
+		if clusterName, ok := parseLedsCollectionName(collection); ok {
+			watched[clusterName] = append(watched[clusterName], collection)
+		}
+	}
+	resources := model.Resources{}
+	hashes := make(map[string]string)
+	generated := make(map[string]struct{})
+	for clusterName, clusterCollections := range watched {
+		if updatedOnly != nil {
+			_, _, hostname, _ := model.ParseSubsetKey(clusterName)
+			if _, f := updatedOnly[string(hostname)]; !f {
+				continue
+			}
+		}
+		l := c.Server.generateEndpoints(NewEndpointBuilder(clusterName, proxy, push))
+		if l == nil {
+			continue
+		}
+		byCollection := make(map[string]*endpoint.LocalityLbEndpoints, len(l.Endpoints))
+		for _, llb := range l.Endpoints {
+			byCollection[ledsCollectionName(clusterName, llb)] = llb
+		}
+		for _, collection := range clusterCollections {
+			generated[collection] = struct{}{}
+			llb := byCollection[collection]
+			if llb == nil {
+				continue
+			}
+			for _, ep := range llb.LbEndpoints {
+				name := ledsResourceName(collection, ep)
+				resources = append(resources, &discovery.Resource{
+					Name:     name,
+					Resource: util.MessageToAny(ep),
+				})
+				hashes[name] = endpointHash(ep)
+			}
+		}
+	}
+	return resources, hashes, generated
+}
+
+func (c LedsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource,
+	req *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	if !edsNeedsPush(req.ConfigsUpdated) {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	res, _, _ := c.endpoints(proxy, push, w.ResourceNames, nil)
+	return res, model.DefaultXdsLogDetails, nil
+}
+
+// GenerateDeltas only sends the endpoints which changed since the last push, and removes the endpoints which
+// are no longer part of the collections generated.
+func (c LedsGenerator) GenerateDeltas(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource,
+	req *model.PushRequest) (model.Resources, []string, model.XdsLogDetails, error) {
+	if !edsNeedsPush(req.ConfigsUpdated) {
+		return nil, nil, model.DefaultXdsLogDetails, nil
+	}
+	var updatedOnly map[string]struct{}
+	if !req.Full {
+		updatedOnly = model.ConfigNamesOfKind(req.ConfigsUpdated, gvk.ServiceEntry)
+	}
+	res, hashes, generated := c.endpoints(proxy, push, w.ResourceNames, updatedOnly)
+
+	previous := w.SentResourceHashes
+	current := make(map[string]string, len(previous))
+	changed := model.Resources{}
+	for _, r := range res {
+		h := hashes[r.Name]
+		current[r.Name] = h
+		if previous[r.Name] != h {
+			changed = append(changed, r)
+		}
+	}
+	var removed []string
+	for name, h := range previous {
+		if _, f := current[name]; f {
+			continue
+		}
+		collection := name[:strings.LastIndex(name, "/")+1] + "*"
+		if _, f := generated[collection]; f {
+			removed = append(removed, name)
+			continue
+		}
+		// Keep the endpoints of collections which were not regenerated, and forget those no longer watched.
+		for _, watched := range w.ResourceNames {
+			if watched == collection {
+				current[name] = h
+				break
+			}
+		}
+	}
+	sort.Strings(removed)
+	w.SentResourceHashes = current
+
+	return changed, removed, model.XdsLogDetails{
+		Incremental:    updatedOnly != nil,
+		AdditionalInfo: fmt.Sprintf("changed:%d/%d removed:%d", len(changed), len(res), len(removed)),
+	}, nil
+}
+
+func endpointHash(ep *endpoint.LbEndpoint) string {
+	// Marshal deterministically, as endpoint metadata holds maps.
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(ep)
+	return hashBytes(b)
+}
@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"reflect"
+	"testing"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestLeds(t *testing.T) {
+	old := features.LedsEndpointThreshold
+	features.LedsEndpointThreshold = 2
+	defer func() { features.LedsEndpointThreshold = old }()
+
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: leds
+  namespace: default
+spec:
+  hosts:
+  - leds.example.com
+  addresses:
+  - 240.240.0.1
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+  - address: 10.0.0.2
+  - address: 10.0.0.3
+`})
+	proxy := s.SetupProxy(nil)
+	clusterName := "outbound|80||leds.example.com"
+
+	eds := &EdsGenerator{Server: s.Discovery}
+	res, _, _ := eds.Generate(proxy, s.PushContext(), &model.WatchedResource{ResourceNames: []string{clusterName}},
+		&model.PushRequest{Full: true})
+	if len(res) != 1 {
+		t.Fatalf("expected one load assignment, got %v", res)
+	}
+	cla := &endpoint.ClusterLoadAssignment{}
+	if err := res[0].Resource.UnmarshalTo(cla); err != nil {
+		t.Fatal(err)
+	}
+	if len(cla.Endpoints) != 1 || len(cla.Endpoints[0].LbEndpoints) != 0 {
+		t.Fatalf("expected endpoints to be referenced through LEDS, got %v", cla.Endpoints)
+	}
+	collection := cla.Endpoints[0].GetLedsClusterLocalityConfig().GetLedsCollectionName()
+	if got, ok := parseLedsCollectionName(collection); !ok || got != clusterName {
+		t.Fatalf("unexpected collection %q for cluster %q", collection, got)
+	}
+
+	leds := &LedsGenerator{Server: s.Discovery}
+	w := &model.WatchedResource{ResourceNames: []string{collection}}
+	changed, removed, _, _ := leds.GenerateDeltas(proxy, s.PushContext(), w, &model.PushRequest{Full: true})
+	if len(changed) != 3 || len(removed) != 0 {
+		t.Fatalf("expected all endpoints on the first push, got %v, removed %v", changed, removed)
+	}
+
+	// Nothing changed, so nothing is sent, and an endpoint no longer in the collection is removed.
+	stale := ledsResourceName(collection, &endpoint.LbEndpoint{})
+	w.SentResourceHashes[stale] = "stale"
+	changed, removed, _, _ = leds.GenerateDeltas(proxy, s.PushContext(), w, &model.PushRequest{Full: true})
+	if len(changed) != 0 || !reflect.DeepEqual(removed, []string{stale}) {
+		t.Fatalf("expected only %v to be removed, got %v, removed %v", stale, changed, removed)
+	}
+	if len(w.SentResourceHashes) != 3 {
+		t.Fatalf("unexpected sent resources %v", w.SentResourceHashes)
+	}
+}
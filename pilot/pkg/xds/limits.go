@@ -0,0 +1,313 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/host"
+)
+
+// resourceLimits bounds the config generated for a single proxy. Zero means unlimited.
+type resourceLimits struct {
+	maxClusters     int
+	maxListeners    int
+	maxVirtualHosts int
+	// maxBytes bounds the marshaled size of the clusters and of the listeners of a proxy.
+	maxBytes int
+}
+
+var configuredResourceLimits = resourceLimits{
+	maxClusters:     features.MaxClustersPerProxy,
+	maxListeners:    features.MaxListenersPerProxy,
+	maxVirtualHosts: features.MaxVirtualHostsPerRoute,
+	maxBytes:        features.MaxConfigBytesPerType,
+}
+
+// apply truncates generated config exceeding the limits, so that the proxy receives a smaller but valid config
+// instead of one it may fail to load. Each truncation is reported in the push status, naming the proxy and the
+// offending resource.
+//
+// The clusters of a service are dropped together, from the last service by hostname, so the clusters kept do not
+// depend on the order of generation. The clusters dropped are recorded on the connection, and the routes to them are
+// removed from the route configurations sent next, so that no route targets a cluster the proxy does not have.
+func (l resourceLimits) apply(con *Connection, push *model.PushContext, typeURL string, res model.Resources) model.Resources {
+	proxy := con.proxy
+	switch typeURL {
+	case v3.ClusterType:
+		out, dropped := truncateClusters(res, l.maxClusters, l.maxBytes)
+		con.droppedClusters = dropped
+		if len(dropped) > 0 {
+			reportTruncation(proxy, push, typeURL, "", fmt.Sprintf("generated %d clusters (%d bytes), truncated to %d (limits: %d clusters, %d bytes). "+
+				"Use a Sidecar resource to restrict the services visible to the proxy",
+				len(res), ResourceSize(res), len(out), l.maxClusters, l.maxBytes))
+		}
+		return out
+	case v3.ListenerType:
+		if out, dropped := truncateResources(res, l.maxListeners, l.maxBytes, keepListener); dropped > 0 {
+			reportTruncation(proxy, push, typeURL, "", fmt.Sprintf("generated %d listeners (%d bytes), truncated to %d (limits: %d listeners, %d bytes). "+
+				"Use a Sidecar resource to restrict the ports the proxy listens on",
+				len(res), ResourceSize(res), len(out), l.maxListeners, l.maxBytes))
+			return out
+		}
+	case v3.RouteType:
+		if l.maxVirtualHosts > 0 {
+			res = l.truncateVirtualHosts(proxy, push, res)
+		}
+		if len(con.droppedClusters) > 0 {
+			res = removeRoutesToClusters(proxy, push, res, con.droppedClusters)
+		}
+	}
+	return res
+}
+
+// truncateClusters drops the outbound clusters of services, starting from the last hostname, until there are at
+// most max clusters and their size is at most maxBytes. It returns the clusters kept and the names of the ones
+// dropped, if any.
+func truncateClusters(res model.Resources, max, maxBytes int) (model.Resources, map[string]struct{}) {
+	count, size := len(res), ResourceSize(res)
+	exceeded := func() bool {
+		return (max > 0 && count > max) || (maxBytes > 0 && size > maxBytes)
+	}
+	if !exceeded() {
+		return res, nil
+	}
+	byHost := map[host.Name][]*discovery.Resource{}
+	for _, r := range res {
+		if keepCluster(r.Name) {
+			continue
+		}
+		_, _, hostname, _ := model.ParseSubsetKey(r.Name)
+		byHost[hostname] = append(byHost[hostname], r)
+	}
+	hosts := make([]string, 0, len(byHost))
+	for h := range byHost {
+		hosts = append(hosts, string(h))
+	}
+	sort.Strings(hosts)
+	dropped := map[string]struct{}{}
+	for i := len(hosts) - 1; i >= 0 && exceeded(); i-- {
+		for _, r := range byHost[host.Name(hosts[i])] {
+			dropped[r.Name] = struct{}{}
+			count--
+			size -= len(r.Resource.GetValue())
+		}
+	}
+	out := make(model.Resources, 0, count)
+	for _, r := range res {
+		if _, f := dropped[r.Name]; !f {
+			out = append(out, r)
+		}
+	}
+	return out, dropped
+}
+
+// truncateResources drops resources, starting from the last one by name, until there are at most max of them and
+// their size is at most maxBytes. Resources for which keep returns true are never dropped.
+func truncateResources(res model.Resources, max, maxBytes int, keep func(name string) bool) (model.Resources, int) {
+	count, size := len(res), ResourceSize(res)
+	exceeded := func() bool {
+		return (max > 0 && count > max) || (maxBytes > 0 && size > maxBytes)
+	}
+	if !exceeded() {
+		return res, 0
+	}
+	order := make([]int, len(res))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return res[order[i]].Name < res[order[j]].Name
+	})
+	drop := make([]bool, len(res))
+	for k := len(order) - 1; k >= 0 && exceeded(); k-- {
+		i := order[k]
+		if keep(res[i].Name) {
+			continue
+		}
+		drop[i] = true
+		count--
+		size -= len(res[i].Resource.GetValue())
+	}
+	out := make(model.Resources, 0, count)
+	for i, r := range res {
+		if !drop[i] {
+			out = append(out, r)
+		}
+	}
+	return out, len(res) - len(out)
+}
+
+// keepCluster protects the clusters needed for the proxy to serve its own workload and pass through traffic.
+func keepCluster(name string) bool {
+	return !strings.HasPrefix(name, string(model.TrafficDirectionOutbound)+"|")
+}
+
+// keepListener protects the listeners capturing all traffic of a sidecar.
+func keepListener(name string) bool {
+	return name == model.VirtualOutboundListenerName || name == model.VirtualInboundListenerName
+}
+
+// truncateVirtualHosts limits the number of virtual hosts of each route configuration. The catch all virtual host,
+// if any, is kept so that the behavior for unknown hosts does not change.
+func (l resourceLimits) truncateVirtualHosts(proxy *model.Proxy, push *model.PushContext, res model.Resources) model.Resources {
+	var out model.Resources
+	for i, r := range res {
+		rc := &route.RouteConfiguration{}
+		if err := r.Resource.UnmarshalTo(rc); err != nil || len(rc.VirtualHosts) <= l.maxVirtualHosts {
+			if out != nil {
+				out = append(out, r)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make(model.Resources, 0, len(res)), res[:i]...)
+		}
+		total := len(rc.VirtualHosts)
+		kept := make([]*route.VirtualHost, 0, l.maxVirtualHosts)
+		var catchAll *route.VirtualHost
+		for _, vh := range rc.VirtualHosts {
+			if catchAll == nil && isCatchAllVirtualHost(vh) {
+				catchAll = vh
+				continue
+			}
+			kept = append(kept, vh)
+		}
+		limit := l.maxVirtualHosts
+		if catchAll != nil {
+			limit--
+		}
+		if limit < len(kept) {
+			kept = kept[:limit]
+		}
+		if catchAll != nil {
+			kept = append(kept, catchAll)
+		}
+		rc.VirtualHosts = kept
+		reportTruncation(proxy, push, v3.RouteType, rc.Name, fmt.Sprintf("route configuration %s has %d virtual hosts, truncated to %d. "+
+			"Use a Sidecar resource or the exportTo field of services and virtual services to reduce the hosts visible to the proxy",
+			rc.Name, total, len(kept)))
+		out = append(out, &discovery.Resource{Name: r.Name, Resource: util.MessageToAny(rc)})
+	}
+	if out == nil {
+		return res
+	}
+	return out
+}
+
+// removeRoutesToClusters removes the routes to the clusters dropped from the route configurations, and the virtual
+// hosts left without routes. The routes splitting traffic keep the weights of the clusters remaining, with their
+// total weight updated to their sum.
+func removeRoutesToClusters(proxy *model.Proxy, push *model.PushContext, res model.Resources,
+	dropped map[string]struct{}) model.Resources {
+	out := make(model.Resources, 0, len(res))
+	for _, r := range res {
+		rc := &route.RouteConfiguration{}
+		if err := r.Resource.UnmarshalTo(rc); err != nil {
+			out = append(out, r)
+			continue
+		}
+		removed := 0
+		vhosts := make([]*route.VirtualHost, 0, len(rc.VirtualHosts))
+		for _, vh := range rc.VirtualHosts {
+			routes := make([]*route.Route, 0, len(vh.Routes))
+			for _, rt := range vh.Routes {
+				if keepRoute(rt, dropped) {
+					routes = append(routes, rt)
+				} else {
+					removed++
+				}
+			}
+			if len(routes) == 0 && len(vh.Routes) > 0 {
+				continue
+			}
+			vh.Routes = routes
+			vhosts = append(vhosts, vh)
+		}
+		if removed == 0 {
+			out = append(out, r)
+			continue
+		}
+		rc.VirtualHosts = vhosts
+		reportTruncation(proxy, push, v3.RouteType, rc.Name+"/clusters", fmt.Sprintf("removed %d routes of route configuration %s "+
+			"to the clusters dropped by the cluster limits", removed, rc.Name))
+		out = append(out, &discovery.Resource{Name: r.Name, Resource: util.MessageToAny(rc)})
+	}
+	return out
+}
+
+// keepRoute returns whether the route still has a destination, after removing the clusters dropped from its
+// weighted clusters. The route is modified in place.
+func keepRoute(rt *route.Route, dropped map[string]struct{}) bool {
+	action := rt.GetRoute()
+	if action == nil {
+		return true
+	}
+	if c := action.GetCluster(); c != "" {
+		_, f := dropped[c]
+		return !f
+	}
+	weighted := action.GetWeightedClusters()
+	if weighted == nil {
+		return true
+	}
+	clusters := make([]*route.WeightedCluster_ClusterWeight, 0, len(weighted.Clusters))
+	total := uint32(0)
+	for _, c := range weighted.Clusters {
+		if _, f := dropped[c.Name]; f {
+			continue
+		}
+		clusters = append(clusters, c)
+		total += c.GetWeight().GetValue()
+	}
+	if len(clusters) == len(weighted.Clusters) {
+		return true
+	}
+	if len(clusters) == 0 || total == 0 {
+		return false
+	}
+	weighted.Clusters = clusters
+	// The weights must add up to the total weight, which defaults to 100 when unset as done by the route generator.
+	weighted.TotalWeight = &wrappers.UInt32Value{Value: total}
+	return true
+}
+
+func isCatchAllVirtualHost(vh *route.VirtualHost) bool {
+	for _, d := range vh.Domains {
+		if d == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func reportTruncation(proxy *model.Proxy, push *model.PushContext, typeURL, resource, msg string) {
+	key := proxy.ID + "/" + v3.GetShortType(typeURL)
+	if resource != "" {
+		key += "/" + resource
+	}
+	log.Warnf("%s: config of %s exceeds limits: %s", v3.GetShortType(typeURL), proxy.ID, msg)
+	push.AddMetric(model.ProxyStatusConfigTruncated, key, proxy.ID, msg)
+}
@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"reflect"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestResourceLimits(t *testing.T) {
+	proxy := &model.Proxy{ID: "app.ns"}
+	names := func(res model.Resources) []string {
+		var out []string
+		for _, r := range res {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	t.Run("clusters", func(t *testing.T) {
+		var res model.Resources
+		for _, name := range []string{"outbound|80||a", "outbound|80||b", "inbound|8080||", "outbound|80||c", "PassthroughCluster"} {
+			res = append(res, &discovery.Resource{Name: name, Resource: util.MessageToAny(&cluster.Cluster{Name: name})})
+		}
+		push := model.NewPushContext()
+		got := resourceLimits{maxClusters: 3}.apply(&Connection{proxy: proxy}, push, v3.ClusterType, res)
+		want := []string{"outbound|80||a", "inbound|8080||", "PassthroughCluster"}
+		if !reflect.DeepEqual(names(got), want) {
+			t.Fatalf("got %v, want %v", names(got), want)
+		}
+		if _, f := push.ProxyStatus[model.ProxyStatusConfigTruncated.Name()]["app.ns/CDS"]; !f {
+			t.Fatalf("expected truncation to be reported, got %v", push.ProxyStatus)
+		}
+
+		push = model.NewPushContext()
+		if got := (resourceLimits{maxClusters: 5}).apply(&Connection{proxy: proxy}, push, v3.ClusterType, res); len(got) != 5 {
+			t.Fatalf("expected no truncation, got %v", names(got))
+		}
+		if len(push.ProxyStatus) != 0 {
+			t.Fatalf("expected no truncation to be reported, got %v", push.ProxyStatus)
+		}
+	})
+
+	t.Run("virtual hosts", func(t *testing.T) {
+		rc := &route.RouteConfiguration{
+			Name: "80",
+			VirtualHosts: []*route.VirtualHost{
+				{Name: "a:80", Domains: []string{"a"}},
+				{Name: "b:80", Domains: []string{"b"}},
+				{Name: "c:80", Domains: []string{"c"}},
+				{Name: "allow_any", Domains: []string{"*"}},
+			},
+		}
+		res := model.Resources{&discovery.Resource{Name: "80", Resource: util.MessageToAny(rc)}}
+		push := model.NewPushContext()
+		got := resourceLimits{maxVirtualHosts: 2}.apply(&Connection{proxy: proxy}, push, v3.RouteType, res)
+		out := &route.RouteConfiguration{}
+		if err := got[0].Resource.UnmarshalTo(out); err != nil {
+			t.Fatal(err)
+		}
+		var vhosts []string
+		for _, vh := range out.VirtualHosts {
+			vhosts = append(vhosts, vh.Name)
+		}
+		if want := []string{"a:80", "allow_any"}; !reflect.DeepEqual(vhosts, want) {
+			t.Fatalf("got %v, want %v", vhosts, want)
+		}
+		if len(rc.VirtualHosts) != 4 {
+			t.Fatalf("generated resource was modified")
+		}
+		if _, f := push.ProxyStatus[model.ProxyStatusConfigTruncated.Name()]["app.ns/RDS/80"]; !f {
+			t.Fatalf("expected truncation to be reported, got %v", push.ProxyStatus)
+		}
+	})
+	t.Run("clusters of a service are dropped together regardless of generation order", func(t *testing.T) {
+		var res model.Resources
+		for _, name := range []string{"outbound|80|v1|b", "outbound|80||c", "outbound|80||a", "outbound|80||b", "outbound|80|v2|b"} {
+			res = append(res, &discovery.Resource{Name: name, Resource: util.MessageToAny(&cluster.Cluster{Name: name})})
+		}
+		got := resourceLimits{maxClusters: 3}.apply(&Connection{proxy: proxy}, model.NewPushContext(), v3.ClusterType, res)
+		if want := []string{"outbound|80||a"}; !reflect.DeepEqual(names(got), want) {
+			t.Fatalf("got %v, want %v", names(got), want)
+		}
+	})
+
+	t.Run("routes to dropped clusters", func(t *testing.T) {
+		var clusters model.Resources
+		for _, name := range []string{"outbound|80||a", "outbound|80||b", "outbound|80||c", "PassthroughCluster"} {
+			clusters = append(clusters, &discovery.Resource{Name: name, Resource: util.MessageToAny(&cluster.Cluster{Name: name})})
+		}
+		toCluster := func(name string) *route.Route {
+			return &route.Route{Action: &route.Route_Route{Route: &route.RouteAction{
+				ClusterSpecifier: &route.RouteAction_Cluster{Cluster: name},
+			}}}
+		}
+		split := &route.Route{Action: &route.Route_Route{Route: &route.RouteAction{
+			ClusterSpecifier: &route.RouteAction_WeightedClusters{WeightedClusters: &route.WeightedCluster{
+				Clusters: []*route.WeightedCluster_ClusterWeight{
+					{Name: "outbound|80||a", Weight: &wrappers.UInt32Value{Value: 30}},
+					{Name: "outbound|80||c", Weight: &wrappers.UInt32Value{Value: 70}},
+				},
+				TotalWeight: &wrappers.UInt32Value{Value: 100},
+			}},
+		}}}
+		// The route generator does not set the total weight, which defaults to 100.
+		defaultSplit := &route.Route{Action: &route.Route_Route{Route: &route.RouteAction{
+			ClusterSpecifier: &route.RouteAction_WeightedClusters{WeightedClusters: &route.WeightedCluster{
+				Clusters: []*route.WeightedCluster_ClusterWeight{
+					{Name: "outbound|80||a", Weight: &wrappers.UInt32Value{Value: 40}},
+					{Name: "outbound|80||b", Weight: &wrappers.UInt32Value{Value: 60}},
+				},
+			}},
+		}}}
+		rc := &route.RouteConfiguration{
+			Name: "80",
+			VirtualHosts: []*route.VirtualHost{
+				{Name: "a:80", Domains: []string{"a"}, Routes: []*route.Route{toCluster("outbound|80||a")}},
+				{Name: "b:80", Domains: []string{"b"}, Routes: []*route.Route{toCluster("outbound|80||b")}},
+				{Name: "c:80", Domains: []string{"c"}, Routes: []*route.Route{toCluster("outbound|80||c")}},
+				{Name: "default-split:80", Domains: []string{"default-split"}, Routes: []*route.Route{defaultSplit}},
+				{Name: "split:80", Domains: []string{"split"}, Routes: []*route.Route{split}},
+				{Name: "allow_any", Domains: []string{"*"}, Routes: []*route.Route{toCluster("PassthroughCluster")}},
+			},
+		}
+		routes := model.Resources{&discovery.Resource{Name: "80", Resource: util.MessageToAny(rc)}}
+
+		con := &Connection{proxy: proxy}
+		push := model.NewPushContext()
+		limits := resourceLimits{maxClusters: 2}
+		kept := map[string]bool{}
+		for _, r := range limits.apply(con, push, v3.ClusterType, clusters) {
+			kept[r.Name] = true
+		}
+		got := limits.apply(con, push, v3.RouteType, routes)
+		out := &route.RouteConfiguration{}
+		if err := got[0].Resource.UnmarshalTo(out); err != nil {
+			t.Fatal(err)
+		}
+		var vhosts []string
+		for _, vh := range out.VirtualHosts {
+			vhosts = append(vhosts, vh.Name)
+			for _, rt := range vh.Routes {
+				targets := []string{rt.GetRoute().GetCluster()}
+				if w := rt.GetRoute().GetWeightedClusters(); w != nil {
+					targets = nil
+					total := uint32(0)
+					for _, c := range w.Clusters {
+						targets = append(targets, c.Name)
+						total += c.Weight.GetValue()
+					}
+					if w.TotalWeight == nil || w.TotalWeight.GetValue() != total {
+						t.Fatalf("total weight %d of %v does not match the weights %d", w.TotalWeight.GetValue(), vh.Name, total)
+					}
+				}
+				for _, c := range targets {
+					if !kept[c] {
+						t.Fatalf("route of %v targets the dropped cluster %v", vh.Name, c)
+					}
+				}
+			}
+		}
+		if want := []string{"a:80", "default-split:80", "split:80", "allow_any"}; !reflect.DeepEqual(vhosts, want) {
+			t.Fatalf("got %v, want %v", vhosts, want)
+		}
+		if _, f := push.ProxyStatus[model.ProxyStatusConfigTruncated.Name()]["app.ns/RDS/80/clusters"]; !f {
+			t.Fatalf("expected the removed routes to be reported, got %v", push.ProxyStatus)
+		}
+	})
+}
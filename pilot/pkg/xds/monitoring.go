@@ -30,6 +30,9 @@ var (
 	nodeTag    = monitoring.MustCreateLabel("node")
 	typeTag    = monitoring.MustCreateLabel("type")
 	versionTag = monitoring.MustCreateLabel("version")
+	actionTag  = monitoring.MustCreateLabel("action")
+	changeTag  = monitoring.MustCreateLabel("change")
+	tenantTag  = monitoring.MustCreateLabel("tenant")
 
 	// pilot_total_xds_rejects should be used instead. This is for backwards compatibility
 	cdsReject = monitoring.NewGauge(
@@ -86,6 +89,29 @@ var (
 		monitoring.WithLabels(typeTag),
 	)
 
+	xdsResumedResponses = monitoring.NewSum(
+		"pilot_xds_resumed_responses",
+		"Total number of XDS responses skipped because a reconnecting proxy resumed with identical config.",
+		monitoring.WithLabels(typeTag),
+	)
+
+	xdsSuppressedPushes = monitoring.NewSum(
+		"pilot_xds_suppressed_pushes",
+		"Total number of complete XDS responses not sent on pushes, as they are identical to the last one sent.",
+		monitoring.WithLabels(typeTag),
+	)
+
+	edsSuppressedSends = monitoring.NewSum(
+		"pilot_eds_suppressed_sends",
+		"Total number of load assignments not sent on incremental pushes, as the proxy already ACKed them.",
+	)
+
+	edsEndpointChanges = monitoring.NewSum(
+		"pilot_eds_endpoint_changes",
+		"Total number of endpoints added, removed or updated in the load assignments sent, compared to the ones ACKed.",
+		monitoring.WithLabels(changeTag),
+	)
+
 	monServices = monitoring.NewGauge(
 		"pilot_services",
 		"Total services known to pilot.",
@@ -101,6 +127,59 @@ var (
 	xdsClientTrackerMutex = &sync.Mutex{}
 	xdsClientTracker      = make(map[string]float64)
 
+	xdsReconnects = monitoring.NewSum(
+		"pilot_xds_reconnects",
+		"Number of XDS connections from a proxy instance that was previously connected.",
+	)
+
+	xdsDuplicateConnections = monitoring.NewSum(
+		"pilot_xds_duplicate_connections",
+		"Number of XDS connections opened while another connection with the same node ID was active, "+
+			"by the action taken.",
+		monitoring.WithLabels(actionTag),
+	)
+
+	xdsConnectionsShed = monitoring.NewSum(
+		"pilot_xds_connections_shed",
+		"Number of new XDS connections rejected because the push queue was overloaded.",
+	)
+
+	xdsAffinityRedirects = monitoring.NewSum(
+		"pilot_xds_affinity_redirects",
+		"Number of XDS connections rejected because the proxy is assigned to another istiod replica.",
+	)
+
+	tenantPushes = monitoring.NewSum(
+		"pilot_tenant_pushes",
+		"Number of pushes queued for the proxies of each tenant isolated by PILOT_TENANT_ISOLATION.",
+		monitoring.WithLabels(tenantTag),
+	)
+
+	tenantPendingPushes = monitoring.NewGauge(
+		"pilot_tenant_pending_pushes",
+		"Number of pushes pending in the push queue of each tenant.",
+		monitoring.WithLabels(tenantTag),
+	)
+
+	tenantProxies = monitoring.NewGauge(
+		"pilot_tenant_proxies",
+		"Number of proxies of each tenant connected.",
+		monitoring.WithLabels(tenantTag),
+	)
+
+	tenantCacheEntries = monitoring.NewGauge(
+		"pilot_tenant_xds_cache_entries",
+		"Number of entries in the XDS cache of each tenant.",
+		monitoring.WithLabels(tenantTag),
+	)
+
+	xdsIdentityViolations = monitoring.NewSum(
+		"pilot_xds_identity_violations",
+		"Number of XDS connections whose claimed node identity did not match the authenticated identity, "+
+			"by the action taken.",
+		monitoring.WithLabels(actionTag),
+	)
+
 	xdsResponseWriteTimeouts = monitoring.NewSum(
 		"pilot_xds_write_timeout",
 		"Pilot XDS response write timeouts.",
@@ -135,6 +214,7 @@ var (
 		"pilot_xds_send_time",
 		"Total time in seconds Pilot takes to send generated configuration.",
 		[]float64{.01, .1, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(typeTag),
 	)
 
 	// only supported dimension is millis, unfortunately. default to unitdimensionless.
@@ -247,8 +327,8 @@ func incrementXDSRejects(xdsType string, node, errCode string) {
 	}
 }
 
-func recordSendTime(duration time.Duration) {
-	sendTime.Record(duration.Seconds())
+func recordSendTime(xdsType string, duration time.Duration) {
+	sendTime.With(typeTag.Value(v3.GetMetricType(xdsType))).Record(duration.Seconds())
 }
 
 func recordPushTime(xdsType string, duration time.Duration) {
@@ -263,9 +343,22 @@ func init() {
 		ldsReject,
 		rdsReject,
 		xdsExpiredNonce,
+		xdsResumedResponses,
+		xdsSuppressedPushes,
+		edsSuppressedSends,
+		edsEndpointChanges,
 		totalXDSRejects,
 		monServices,
 		xdsClients,
+		xdsReconnects,
+		xdsDuplicateConnections,
+		xdsIdentityViolations,
+		xdsConnectionsShed,
+		xdsAffinityRedirects,
+		tenantPushes,
+		tenantPendingPushes,
+		tenantProxies,
+		tenantCacheEntries,
 		xdsResponseWriteTimeouts,
 		pushes,
 		pushTime,
@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sync"
+
+	"github.com/gogo/protobuf/types"
+
+	"istio.io/api/meta/v1alpha1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// policyStatus writes the Effective condition of the authorization policies and request authentications, while
+// this istiod is elected to write the status of the configs.
+type policyStatus struct {
+	mu sync.Mutex
+	// store is set while this istiod writes the status of the configs.
+	store model.ConfigStore
+	// ineffective are the policies whose Effective condition is False, with the reason.
+	ineffective map[model.ConfigKey]string
+}
+
+// WritePolicyStatus writes the Effective condition of the policies to the store until stop is closed, setting it to
+// False on the policies which cannot take effect, and back to True once they can. It must only run in the istiod
+// elected to write the status of the configs.
+func (s *DiscoveryServer) WritePolicyStatus(store model.ConfigStore, stop <-chan struct{}) {
+	s.policyStatus.mu.Lock()
+	s.policyStatus.store = store
+	// The conditions may have been written by the previously elected istiod.
+	s.policyStatus.ineffective = map[model.ConfigKey]string{}
+	for _, kind := range []gvk.GroupVersionKind{gvk.AuthorizationPolicy, gvk.RequestAuthentication} {
+		configs, err := store.List(kind, model.NamespaceAll)
+		if err != nil {
+			log.Warnf("failed to list %s to write their status: %v", kind.Kind, err)
+			continue
+		}
+		for _, cfg := range configs {
+			if c := status.GetConditionFromSpec(cfg, status.ConditionEffective); c != nil && c.Status == status.StatusFalse {
+				s.policyStatus.ineffective[model.ConfigKey{Kind: kind, Name: cfg.Name, Namespace: cfg.Namespace}] = c.Message
+			}
+		}
+	}
+	s.policyStatus.mu.Unlock()
+	s.updatePolicyStatus(s.globalPushContext())
+
+	<-stop
+	s.policyStatus.mu.Lock()
+	s.policyStatus.store = nil
+	s.policyStatus.mu.Unlock()
+}
+
+// updatePolicyStatus writes the Effective condition of the policies whose effectiveness changed with the push
+// context, if this istiod writes the status of the configs.
+func (s *DiscoveryServer) updatePolicyStatus(push *model.PushContext) {
+	s.policyStatus.mu.Lock()
+	defer s.policyStatus.mu.Unlock()
+	// The push contexts are handled in order: a newer one supersedes this one.
+	if s.policyStatus.store == nil || push == nil || push != s.globalPushContext() {
+		return
+	}
+	current := push.IneffectivePolicies()
+	for key, reason := range current {
+		if r, f := s.policyStatus.ineffective[key]; f && r == reason {
+			continue
+		}
+		if s.policyStatus.writeCondition(key, status.StatusFalse, reason) {
+			s.policyStatus.ineffective[key] = reason
+		}
+	}
+	for key := range s.policyStatus.ineffective {
+		if _, f := current[key]; f {
+			continue
+		}
+		if s.policyStatus.writeCondition(key, status.StatusTrue, "") {
+			delete(s.policyStatus.ineffective, key)
+		}
+	}
+}
+
+// writeCondition sets the Effective condition of the policy. It returns false if it failed to, in which case it
+// should be retried. Deleted policies need no condition.
+func (p *policyStatus) writeCondition(key model.ConfigKey, value, message string) bool {
+	cfg := p.store.Get(key.Kind, key.Name, key.Namespace)
+	if cfg == nil {
+		return true
+	}
+	condition := &v1alpha1.IstioCondition{
+		Type:               status.ConditionEffective,
+		Status:             value,
+		LastProbeTime:      types.TimestampNow(),
+		LastTransitionTime: types.TimestampNow(),
+		Message:            message,
+	}
+	if value == status.StatusFalse {
+		condition.Reason = "HTTPRulesOnNonHTTPWorkloads"
+	}
+	if _, err := p.store.UpdateStatus(status.UpdateConfigCondition(*cfg, condition)); err != nil {
+		log.Warnf("failed to write the status of %s %s/%s: %v", key.Kind.Kind, key.Namespace, key.Name, err)
+		return false
+	}
+	return true
+}
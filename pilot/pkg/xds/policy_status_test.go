@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const ineffectivePolicyConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: passthrough
+  namespace: istio-system
+spec:
+  selector:
+    istio: ingressgateway
+  servers:
+  - port:
+      number: 443
+      name: https
+      protocol: HTTPS
+    hosts:
+    - "*.example.com"
+    tls:
+      mode: PASSTHROUGH
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: methods
+  namespace: istio-system
+spec:
+  selector:
+    matchLabels:
+      istio: ingressgateway
+  rules:
+  - to:
+    - operation:
+        methods: ["GET"]
+`
+
+func TestPolicyStatus(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: ineffectivePolicyConfig})
+
+	rr := httptest.NewRecorder()
+	s.Discovery.ineffectivePoliciesz(rr, httptest.NewRequest("GET", "/debug/ineffective_policies", nil))
+	var policies []model.IneffectivePolicy
+	if err := json.Unmarshal(rr.Body.Bytes(), &policies); err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 1 || policies[0].Name != "methods" || policies[0].Kind != gvk.AuthorizationPolicy.Kind {
+		t.Fatalf("expected the policy to be reported, got %+v", policies)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.Discovery.WritePolicyStatus(s.Store(), stop)
+	expectEffective := func(expected string) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			cfg := s.Store().Get(gvk.AuthorizationPolicy, "methods", "istio-system")
+			if cfg == nil {
+				return fmt.Errorf("policy not found")
+			}
+			c := status.GetConditionFromSpec(*cfg, status.ConditionEffective)
+			if c == nil || c.Status != expected {
+				return fmt.Errorf("expected Effective %s, got %v", expected, c)
+			}
+			return nil
+		})
+	}
+	expectEffective(status.StatusFalse)
+
+	// Once the gateway terminates TLS, the policy takes effect.
+	gw := s.Store().Get(gvk.Gateway, "passthrough", "istio-system").DeepCopy()
+	gw.Spec.(*networking.Gateway).Servers[0].Tls.Mode = networking.ServerTLSSettings_SIMPLE
+	if _, err := s.Store().Update(gw); err != nil {
+		t.Fatal(err)
+	}
+	expectEffective(status.StatusTrue)
+}
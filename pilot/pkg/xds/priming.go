@@ -0,0 +1,158 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/schema/gvk"
+	istioversion "istio.io/pkg/version"
+)
+
+// primingProxyIP is the address of the proxies configs are generated for when priming. No workload has it, so
+// they get no inbound config.
+const primingProxyIP = "127.0.0.1"
+
+// primingProfile is a typical proxy the configs are generated for when priming.
+type primingProfile struct {
+	nodeType  model.NodeType
+	namespace string
+	labels    labels.Instance
+}
+
+// Prime computes the initial push context and warms the XDS cache with the clusters and endpoints of the most
+// common proxies: a gateway for each Gateway selector, and a sidecar without Sidecar selecting it in each of the
+// namespaces with the most services. It is called once the caches are synced and before the server is marked
+// ready, so that the first wave of proxies reconnecting after a restart is served from warm caches. Priming is
+// best effort: proxies in another locality miss the cached clusters.
+func (s *DiscoveryServer) Prime(clusterID cluster.ID) {
+	if !features.EnableStartupPriming {
+		return
+	}
+	t0 := time.Now()
+	push := s.globalPushContext()
+	if err := push.InitContext(s.Env, nil, nil); err != nil {
+		log.Warnf("priming: failed to initialize the push context: %v", err)
+		return
+	}
+	profiles := s.primingProfiles(features.StartupPrimingNamespaces)
+	resources := 0
+	for _, p := range profiles {
+		resources += s.prime(push, s.primingProxy(p, clusterID, push))
+	}
+	log.Infof("primed the push context and %d resources for %d proxy profiles in %v",
+		resources, len(profiles), time.Since(t0))
+}
+
+// primingProfiles returns the gateways selected by the Gateways, and the default sidecars of the namespaces with
+// the most services, up to maxNamespaces.
+func (s *DiscoveryServer) primingProfiles(maxNamespaces int) []primingProfile {
+	var out []primingProfile
+	gateways, err := s.Env.List(gvk.Gateway, model.NamespaceAll)
+	if err != nil {
+		log.Warnf("priming: failed to list gateways: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, gw := range gateways {
+		selector := labels.Instance(gw.Spec.(*networking.Gateway).GetSelector())
+		key := gw.Namespace + "/" + selector.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, primingProfile{nodeType: model.Router, namespace: gw.Namespace, labels: selector})
+	}
+
+	services, err := s.Env.Services()
+	if err != nil {
+		log.Warnf("priming: failed to list services: %v", err)
+	}
+	byNamespace := map[string]int{}
+	for _, svc := range services {
+		byNamespace[svc.Attributes.Namespace]++
+	}
+	namespaces := make([]string, 0, len(byNamespace))
+	for ns := range byNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		if byNamespace[namespaces[i]] != byNamespace[namespaces[j]] {
+			return byNamespace[namespaces[i]] > byNamespace[namespaces[j]]
+		}
+		return namespaces[i] < namespaces[j]
+	})
+	if len(namespaces) > maxNamespaces {
+		namespaces = namespaces[:maxNamespaces]
+	}
+	for _, ns := range namespaces {
+		out = append(out, primingProfile{nodeType: model.SidecarProxy, namespace: ns})
+	}
+	return out
+}
+
+// primingProxy returns a proxy of the profile, initialized like a connected proxy.
+func (s *DiscoveryServer) primingProxy(p primingProfile, clusterID cluster.ID, push *model.PushContext) *model.Proxy {
+	proxy := &model.Proxy{
+		Type:            p.nodeType,
+		ID:              "priming." + p.namespace,
+		ConfigNamespace: p.namespace,
+		IPAddresses:     []string{primingProxyIP},
+		DNSDomain:       p.namespace + ".svc." + s.Env.DomainSuffix,
+		IstioVersion:    model.ParseIstioVersion(istioversion.Info.Version),
+		Metadata: &model.NodeMetadata{
+			Namespace:    p.namespace,
+			Labels:       p.labels,
+			ClusterID:    clusterID,
+			IstioVersion: istioversion.Info.Version,
+		},
+	}
+	proxy.SetSidecarScope(push)
+	proxy.SetGatewaysForProxy(push)
+	proxy.DiscoverIPVersions()
+	return proxy
+}
+
+// prime generates the clusters of the proxy, and the endpoints of its EDS clusters, which caches them. It returns
+// the number of resources generated.
+func (s *DiscoveryServer) prime(push *model.PushContext, proxy *model.Proxy) int {
+	req := &model.PushRequest{Full: true, Push: push, Start: time.Now()}
+	clusters, _, err := s.Generators[v3.ClusterType].Generate(proxy, push, &model.WatchedResource{TypeUrl: v3.ClusterType}, req)
+	if err != nil {
+		log.Warnf("priming: failed to generate the clusters of %s: %v", proxy.ID, err)
+		return 0
+	}
+	var edsClusters []string
+	for _, r := range clusters {
+		c := &clusterv3.Cluster{}
+		if err := r.Resource.UnmarshalTo(c); err == nil && c.GetType() == clusterv3.Cluster_EDS {
+			edsClusters = append(edsClusters, c.Name)
+		}
+	}
+	endpoints, _, err := s.Generators[v3.EndpointType].Generate(proxy, push,
+		&model.WatchedResource{TypeUrl: v3.EndpointType, ResourceNames: edsClusters}, req)
+	if err != nil {
+		log.Warnf("priming: failed to generate the endpoints of %s: %v", proxy.ID, err)
+	}
+	return len(clusters) + len(endpoints)
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+const primingConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: gateway
+  namespace: istio-system
+spec:
+  selector:
+    istio: ingressgateway
+  servers:
+  - port:
+      number: 80
+      name: http
+      protocol: HTTP
+    hosts:
+    - "*.example.com"
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: a1
+  namespace: a
+spec:
+  hosts:
+  - a1.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: a2
+  namespace: a
+spec:
+  hosts:
+  - a2.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: b
+  namespace: b
+spec:
+  hosts:
+  - b.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.3
+`
+
+func TestPriming(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: primingConfig})
+	ds := s.Discovery
+
+	profiles := ds.primingProfiles(1)
+	expected := []primingProfile{
+		{nodeType: model.Router, namespace: "istio-system", labels: map[string]string{"istio": "ingressgateway"}},
+		{nodeType: model.SidecarProxy, namespace: "a"},
+	}
+	if !reflect.DeepEqual(profiles, expected) {
+		t.Fatalf("got profiles %+v, expected %+v", profiles, expected)
+	}
+
+	push := ds.globalPushContext()
+	for _, p := range profiles {
+		proxy := ds.primingProxy(p, "Kubernetes", push)
+		if n := ds.prime(push, proxy); n == 0 {
+			t.Fatalf("expected the configs of %+v to be generated", p)
+		}
+	}
+}
@@ -210,6 +210,8 @@ func TestCheckConnectionIdentity(t *testing.T) {
 		identity  []string
 		sa        string
 		namespace string
+		id        string
+		typ       model.NodeType
 		success   bool
 	}{
 		{
@@ -247,11 +249,67 @@ func TestCheckConnectionIdentity(t *testing.T) {
 			namespace: "namespace",
 			success:   false,
 		},
+		{
+			name: "match node id",
+			identity: []string{
+				spiffe.Identity{"cluster.local", "namespace", "serviceaccount"}.String(),
+			},
+			sa:        "serviceaccount",
+			namespace: "namespace",
+			id:        "pod.namespace",
+			success:   true,
+		},
+		{
+			name: "no match node id namespace",
+			identity: []string{
+				spiffe.Identity{"cluster.local", "namespace", "serviceaccount"}.String(),
+			},
+			sa:        "serviceaccount",
+			namespace: "namespace",
+			id:        "pod.other",
+			success:   false,
+		},
+		{
+			name: "match multi-dot node id",
+			identity: []string{
+				spiffe.Identity{"cluster.local", "namespace", "serviceaccount"}.String(),
+			},
+			sa:        "serviceaccount",
+			namespace: "namespace",
+			id:        "pod.name.namespace",
+			typ:       model.SidecarProxy,
+			success:   true,
+		},
+		{
+			name: "no match multi-dot node id namespace",
+			identity: []string{
+				spiffe.Identity{"cluster.local", "namespace", "serviceaccount"}.String(),
+			},
+			sa:        "serviceaccount",
+			namespace: "namespace",
+			id:        "pod.namespace.other",
+			typ:       model.SidecarProxy,
+			success:   false,
+		},
+		{
+			name: "sidecar node id without namespace",
+			identity: []string{
+				spiffe.Identity{"cluster.local", "namespace", "serviceaccount"}.String(),
+			},
+			sa:        "serviceaccount",
+			namespace: "namespace",
+			id:        "pod",
+			typ:       model.SidecarProxy,
+			success:   false,
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			con := &Connection{
-				proxy:      &model.Proxy{ConfigNamespace: tt.namespace, Metadata: &model.NodeMetadata{ServiceAccount: tt.sa}},
+				proxy: &model.Proxy{
+					Type: tt.typ, ID: tt.id, ConfigNamespace: tt.namespace,
+					Metadata: &model.NodeMetadata{ServiceAccount: tt.sa},
+				},
 				Identities: tt.identity,
 			}
 			if _, err := checkConnectionIdentity(con); (err == nil) != tt.success {
@@ -260,3 +318,39 @@ func TestCheckConnectionIdentity(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyIdentityModes(t *testing.T) {
+	identity := []string{spiffe.Identity{"cluster.local", "namespace", "serviceaccount"}.String()}
+	cases := []struct {
+		name string
+		id   string
+		mode IdentityCheckMode
+		err  bool
+	}{
+		{"enforce multi-dot", "pod.x.victimns", IdentityCheckEnforce, true},
+		{"enforce dotless", "pod", IdentityCheckEnforce, true},
+		{"enforce match", "pod.x.namespace", IdentityCheckEnforce, false},
+		{"permissive multi-dot", "pod.x.victimns", IdentityCheckPermissive, false},
+		{"permissive dotless", "pod", IdentityCheckPermissive, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &DiscoveryServer{IdentityCheckMode: tt.mode}
+			con := &Connection{
+				proxy: &model.Proxy{
+					Type: model.SidecarProxy, ID: tt.id, ConfigNamespace: "namespace",
+					Metadata: &model.NodeMetadata{ServiceAccount: "serviceaccount"},
+				},
+				Identities: identity,
+			}
+			err := s.verifyIdentity(con)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected err=%v, got %v", tt.err, err)
+			}
+			// Permissive mode lets the connection through, but must not record an unverified identity.
+			if tt.mode == IdentityCheckPermissive && tt.id != "pod.x.namespace" && con.proxy.VerifiedIdentity != nil {
+				t.Fatalf("unexpected verified identity %v", con.proxy.VerifiedIdentity)
+			}
+		})
+	}
+}
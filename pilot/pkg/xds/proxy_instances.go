@@ -0,0 +1,119 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sync"
+	"time"
+)
+
+// proxyInstanceRetention is how long a disconnected proxy instance is remembered. A reconnect
+// after this period is treated as a new instance.
+const proxyInstanceRetention = 10 * time.Minute
+
+// proxyInstance records the connection history of a single proxy process, identified by the
+// instance ID the agent reports in its node metadata.
+type proxyInstance struct {
+	// firstConnected is the time the first stream of this instance was established.
+	firstConnected time.Time
+	// lastConnected is the time the most recent stream was established.
+	lastConnected time.Time
+	// lastDisconnected is the time the most recent stream closed. Zero while connected.
+	lastDisconnected time.Time
+	// connections is the total number of streams opened by this instance.
+	connections int
+	// conID is the ID of the current (or last) connection of this instance.
+	conID string
+}
+
+// proxyInstances tracks proxy instances across reconnects, so that a flapping proxy can be
+// distinguished from a new client.
+type proxyInstances struct {
+	mu        sync.Mutex
+	instances map[string]*proxyInstance
+	// lastPrune is the time expired instances were last removed.
+	lastPrune time.Time
+}
+
+func newProxyInstances() *proxyInstances {
+	return &proxyInstances{instances: map[string]*proxyInstance{}}
+}
+
+// proxyInstanceKey builds the tracking key. The proxy ID is included as instance IDs are reported
+// by the client and should not allow one proxy to impersonate the history of another.
+func proxyInstanceKey(proxyID, instanceID string) string {
+	return proxyID + "/" + instanceID
+}
+
+// connect records a new stream for the instance, returning the number of times it has reconnected.
+func (p *proxyInstances) connect(proxyID, instanceID, conID string, now time.Time) int {
+	if instanceID == "" {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pruneLocked(now)
+	key := proxyInstanceKey(proxyID, instanceID)
+	inst, f := p.instances[key]
+	if !f {
+		inst = &proxyInstance{firstConnected: now}
+		p.instances[key] = inst
+	}
+	inst.lastConnected = now
+	inst.lastDisconnected = time.Time{}
+	inst.connections++
+	inst.conID = conID
+	return inst.connections - 1
+}
+
+// disconnect records the close of a stream. Closing a stream that was already superseded by a
+// newer connection of the same instance is ignored.
+func (p *proxyInstances) disconnect(proxyID, instanceID, conID string, now time.Time) {
+	if instanceID == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	inst, f := p.instances[proxyInstanceKey(proxyID, instanceID)]
+	if !f || inst.conID != conID {
+		return
+	}
+	inst.lastDisconnected = now
+}
+
+// get returns a copy of the instance record, if known.
+func (p *proxyInstances) get(proxyID, instanceID string) (proxyInstance, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	inst, f := p.instances[proxyInstanceKey(proxyID, instanceID)]
+	if !f {
+		return proxyInstance{}, false
+	}
+	return *inst, true
+}
+
+// pruneLocked drops instances that have been disconnected longer than the retention period.
+// To bound the cost, this runs at most once per minute.
+func (p *proxyInstances) pruneLocked(now time.Time) {
+	if now.Sub(p.lastPrune) < time.Minute {
+		return
+	}
+	p.lastPrune = now
+	for k, inst := range p.instances {
+		if !inst.lastDisconnected.IsZero() && now.Sub(inst.lastDisconnected) > proxyInstanceRetention {
+			delete(p.instances, k)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProxyInstances(t *testing.T) {
+	p := newProxyInstances()
+	start := time.Unix(1000, 0)
+
+	if r := p.connect("sidecar~a", "", "con-1", start); r != 0 {
+		t.Fatalf("expected untracked connection, got %v reconnects", r)
+	}
+	if r := p.connect("sidecar~a", "inst", "con-2", start); r != 0 {
+		t.Fatalf("expected new instance, got %v reconnects", r)
+	}
+	// A new stream is opened before the old one is cleaned up; closing the old stream must not mark
+	// the instance disconnected.
+	if r := p.connect("sidecar~a", "inst", "con-3", start.Add(time.Second)); r != 1 {
+		t.Fatalf("expected 1 reconnect, got %v", r)
+	}
+	p.disconnect("sidecar~a", "inst", "con-2", start.Add(2*time.Second))
+	inst, f := p.get("sidecar~a", "inst")
+	if !f || !inst.lastDisconnected.IsZero() || inst.conID != "con-3" || !inst.firstConnected.Equal(start) {
+		t.Fatalf("unexpected instance state: %+v", inst)
+	}
+	// The same instance ID from another proxy is tracked separately.
+	if r := p.connect("sidecar~b", "inst", "con-4", start); r != 0 {
+		t.Fatalf("expected new instance for other proxy, got %v reconnects", r)
+	}
+
+	p.disconnect("sidecar~a", "inst", "con-3", start.Add(3*time.Second))
+	p.connect("sidecar~c", "other", "con-5", start.Add(3*time.Second+proxyInstanceRetention+time.Minute))
+	if _, f := p.get("sidecar~a", "inst"); f {
+		t.Fatalf("expected disconnected instance to be pruned")
+	}
+	if _, f := p.get("sidecar~b", "inst"); !f {
+		t.Fatalf("expected connected instance to be retained")
+	}
+}
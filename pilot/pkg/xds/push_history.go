@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// maxPushRecordConfigs bounds the number of changed configs kept for each push.
+const maxPushRecordConfigs = 10
+
+// PushRecord describes why a push was sent to a connection.
+type PushRecord struct {
+	Time time.Time `json:"time"`
+	Full bool      `json:"full"`
+	// Reasons counts the triggers merged into the push, such as config, endpoint or proxyrequest.
+	Reasons map[model.TriggerReason]int `json:"reasons,omitempty"`
+	// ConfigKinds are the kinds of the changed configs, if known.
+	ConfigKinds []string `json:"configKinds,omitempty"`
+	// Configs are the first changed configs, as kind/namespace/name.
+	Configs []string `json:"configs,omitempty"`
+	// Types are the types of the responses sent for the push.
+	Types []string `json:"types"`
+}
+
+type pushRecord struct {
+	PushRecord
+	// req identifies the push, so the responses of all its types are recorded together. It is only kept on
+	// the most recent record, to not retain older push contexts.
+	req *model.PushRequest
+}
+
+func newPushRecord(req *model.PushRequest) *pushRecord {
+	rec := &pushRecord{
+		PushRecord: PushRecord{
+			Time:    time.Now(),
+			Full:    req.Full,
+			Reasons: map[model.TriggerReason]int{},
+		},
+		req: req,
+	}
+	for _, r := range req.Reason {
+		rec.Reasons[r]++
+	}
+	rec.ConfigKinds = configKinds(req)
+	configs := make([]string, 0, len(req.ConfigsUpdated))
+	for key := range req.ConfigsUpdated {
+		configs = append(configs, key.String())
+	}
+	sort.Strings(configs)
+	if len(configs) > maxPushRecordConfigs {
+		configs = configs[:maxPushRecordConfigs]
+	}
+	rec.Configs = configs
+	return rec
+}
+
+// configKinds returns the sorted kinds of the configs changed by a push.
+func configKinds(req *model.PushRequest) []string {
+	kinds := map[string]struct{}{}
+	for key := range req.ConfigsUpdated {
+		kinds[key.Kind.Kind] = struct{}{}
+	}
+	out := make([]string, 0, len(kinds))
+	for k := range kinds {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// pushReasons formats the reasons and changed config kinds of a push for the push logs.
+func pushReasons(req *model.PushRequest) string {
+	if req == nil {
+		return ""
+	}
+	seen := map[model.TriggerReason]struct{}{}
+	reasons := make([]string, 0, len(req.Reason))
+	for _, r := range req.Reason {
+		if _, f := seen[r]; !f {
+			seen[r] = struct{}{}
+			reasons = append(reasons, string(r))
+		}
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	sort.Strings(reasons)
+	out := " reason:" + strings.Join(reasons, ",")
+	if kinds := configKinds(req); len(kinds) > 0 {
+		out += " kinds:" + strings.Join(kinds, ",")
+	}
+	return out
+}
+
+// recordPush adds a response sent for a push to the push history of the connection.
+func (conn *Connection) recordPush(typeURL string, req *model.PushRequest) {
+	size := features.PushHistorySize
+	if size <= 0 || req == nil {
+		return
+	}
+	conn.proxy.Lock()
+	defer conn.proxy.Unlock()
+	if n := len(conn.pushHistory); n > 0 {
+		last := conn.pushHistory[n-1]
+		if last.req == req {
+			last.Types = append(last.Types, v3.GetShortType(typeURL))
+			return
+		}
+		last.req = nil
+	}
+	if len(conn.pushHistory) >= size {
+		drop := len(conn.pushHistory) - size + 1
+		copy(conn.pushHistory, conn.pushHistory[drop:])
+		conn.pushHistory = conn.pushHistory[:len(conn.pushHistory)-drop]
+	}
+	rec := newPushRecord(req)
+	rec.Types = []string{v3.GetShortType(typeURL)}
+	conn.pushHistory = append(conn.pushHistory, rec)
+}
+
+// PushHistory returns the most recent pushes sent to the connection, oldest first.
+func (conn *Connection) PushHistory() []PushRecord {
+	conn.proxy.RLock()
+	defer conn.proxy.RUnlock()
+	out := make([]PushRecord, 0, len(conn.pushHistory))
+	for _, rec := range conn.pushHistory {
+		r := rec.PushRecord
+		r.Types = append([]string(nil), rec.Types...)
+		out = append(out, r)
+	}
+	return out
+}
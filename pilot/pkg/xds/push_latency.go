@@ -0,0 +1,193 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/pkg/monitoring"
+)
+
+var generateTime = monitoring.NewDistribution(
+	"pilot_xds_generate_time",
+	"Time in seconds Pilot takes to generate the configuration of a type for a proxy.",
+	[]float64{.001, .01, .1, 1, 3, 5, 10, 20, 30},
+	monitoring.WithLabels(typeTag),
+)
+
+func init() {
+	monitoring.MustRegister(generateTime)
+}
+
+// pushLatency accumulates the time spent generating and sending the responses of a type, and their size.
+type pushLatency struct {
+	pushes      int
+	generate    time.Duration
+	maxGenerate time.Duration
+	send        time.Duration
+	maxSend     time.Duration
+	bytes       int
+	maxBytes    int
+}
+
+func (l *pushLatency) add(generate, send time.Duration, bytes int) {
+	l.pushes++
+	l.generate += generate
+	l.send += send
+	l.bytes += bytes
+	if generate > l.maxGenerate {
+		l.maxGenerate = generate
+	}
+	if send > l.maxSend {
+		l.maxSend = send
+	}
+	if bytes > l.maxBytes {
+		l.maxBytes = bytes
+	}
+}
+
+func (l *pushLatency) merge(o *pushLatency) {
+	l.pushes += o.pushes
+	l.generate += o.generate
+	l.send += o.send
+	l.bytes += o.bytes
+	if o.maxGenerate > l.maxGenerate {
+		l.maxGenerate = o.maxGenerate
+	}
+	if o.maxSend > l.maxSend {
+		l.maxSend = o.maxSend
+	}
+	if o.maxBytes > l.maxBytes {
+		l.maxBytes = o.maxBytes
+	}
+}
+
+// pushLatencies accounts the time spent generating and sending the responses pushed to the proxies, and their
+// size, by type, to find the types dominating the cost of the pushes.
+type pushLatencies struct {
+	mu     sync.Mutex
+	since  time.Time
+	byType map[string]*pushLatency
+}
+
+func newPushLatencies() *pushLatencies {
+	return &pushLatencies{since: time.Now(), byType: map[string]*pushLatency{}}
+}
+
+// recordPushLatency records a response of the type pushed to the connection, mesh-wide and for the connection.
+func (s *DiscoveryServer) recordPushLatency(con *Connection, typeURL string, generate, send time.Duration, bytes int) {
+	generateTime.With(typeTag.Value(v3.GetMetricType(typeURL))).Record(generate.Seconds())
+
+	s.pushLatencies.mu.Lock()
+	l, f := s.pushLatencies.byType[typeURL]
+	if !f {
+		l = &pushLatency{}
+		s.pushLatencies.byType[typeURL] = l
+	}
+	l.add(generate, send, bytes)
+	s.pushLatencies.mu.Unlock()
+
+	con.proxy.Lock()
+	defer con.proxy.Unlock()
+	if con.pushLatencies == nil {
+		con.pushLatencies = map[string]*pushLatency{}
+	}
+	l, f = con.pushLatencies[typeURL]
+	if !f {
+		l = &pushLatency{}
+		con.pushLatencies[typeURL] = l
+	}
+	l.add(generate, send, bytes)
+}
+
+// PushLatency summarizes the responses of a type pushed to the proxies.
+type PushLatency struct {
+	Type               string  `json:"type"`
+	Pushes             int     `json:"pushes"`
+	GenerateSeconds    float64 `json:"generate_seconds"`
+	MaxGenerateSeconds float64 `json:"max_generate_seconds"`
+	SendSeconds        float64 `json:"send_seconds"`
+	MaxSendSeconds     float64 `json:"max_send_seconds"`
+	Bytes              int     `json:"bytes"`
+	MaxBytes           int     `json:"max_bytes"`
+}
+
+// PushLatencyReport summarizes the responses pushed since istiod started, or since the proxy connected, by type,
+// from the type taking the most time.
+type PushLatencyReport struct {
+	Since time.Time     `json:"since"`
+	Proxy string        `json:"proxy,omitempty"`
+	Types []PushLatency `json:"types"`
+}
+
+func newPushLatencyReport(since time.Time, proxy string, byType map[string]*pushLatency) PushLatencyReport {
+	out := PushLatencyReport{Since: since, Proxy: proxy, Types: []PushLatency{}}
+	for typeURL, l := range byType {
+		out.Types = append(out.Types, PushLatency{
+			Type:               v3.GetShortType(typeURL),
+			Pushes:             l.pushes,
+			GenerateSeconds:    l.generate.Seconds(),
+			MaxGenerateSeconds: l.maxGenerate.Seconds(),
+			SendSeconds:        l.send.Seconds(),
+			MaxSendSeconds:     l.maxSend.Seconds(),
+			Bytes:              l.bytes,
+			MaxBytes:           l.maxBytes,
+		})
+	}
+	sort.Slice(out.Types, func(i, j int) bool {
+		a, b := out.Types[i], out.Types[j]
+		if a.GenerateSeconds+a.SendSeconds != b.GenerateSeconds+b.SendSeconds {
+			return a.GenerateSeconds+a.SendSeconds > b.GenerateSeconds+b.SendSeconds
+		}
+		return a.Type < b.Type
+	})
+	return out
+}
+
+// report returns the summary of the responses pushed to all the proxies.
+func (p *pushLatencies) report() PushLatencyReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return newPushLatencyReport(p.since, "", p.byType)
+}
+
+// pushLatencyReport returns the summary of the responses pushed to the connections of the proxy, since the oldest
+// of them connected. It returns false if the proxy is not connected.
+func (s *DiscoveryServer) pushLatencyReport(proxyID string) (PushLatencyReport, bool) {
+	byType := map[string]*pushLatency{}
+	var since time.Time
+	found := false
+	for _, con := range s.Clients() {
+		if con.proxy == nil || con.proxy.ID != proxyID {
+			continue
+		}
+		found = true
+		if since.IsZero() || con.Connect.Before(since) {
+			since = con.Connect
+		}
+		con.proxy.RLock()
+		for typeURL, l := range con.pushLatencies {
+			if byType[typeURL] == nil {
+				byType[typeURL] = &pushLatency{}
+			}
+			byType[typeURL].merge(l)
+		}
+		con.proxy.RUnlock()
+	}
+	return newPushLatencyReport(since, proxyID, byType), found
+}
@@ -0,0 +1,78 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestPushLatencies(t *testing.T) {
+	l := &pushLatency{}
+	l.add(2*time.Second, time.Second, 100)
+	l.add(time.Second, 3*time.Second, 300)
+	expected := pushLatency{
+		pushes: 2, generate: 3 * time.Second, maxGenerate: 2 * time.Second,
+		send: 4 * time.Second, maxSend: 3 * time.Second, bytes: 400, maxBytes: 300,
+	}
+	if *l != expected {
+		t.Fatalf("got %+v, expected %+v", *l, expected)
+	}
+
+	report := newPushLatencyReport(time.Time{}, "", map[string]*pushLatency{
+		v3.ClusterType:  {pushes: 1, generate: time.Second},
+		v3.ListenerType: l,
+	})
+	if len(report.Types) != 2 || report.Types[0].Type != "LDS" || report.Types[0].GenerateSeconds != 3 ||
+		report.Types[0].MaxSendSeconds != 3 || report.Types[0].MaxBytes != 300 {
+		t.Fatalf("expected the types taking the most time first, got %+v", report.Types)
+	}
+}
+
+func TestPushLatencyz(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	ads.RequestResponseAck(t, nil)
+
+	get := func(query string) (int, PushLatencyReport) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.Discovery.PushLatencyz(rr, httptest.NewRequest("GET", "/debug/push_latencyz"+query, nil))
+		out := PushLatencyReport{}
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, out
+	}
+	for _, query := range []string{"", "?proxyID=test.default"} {
+		code, report := get(query)
+		if code != http.StatusOK {
+			t.Fatalf("%q: got code %d", query, code)
+		}
+		if len(report.Types) != 1 || report.Types[0].Type != "CDS" || report.Types[0].Pushes != 1 || report.Types[0].Bytes == 0 {
+			t.Fatalf("%q: expected the clusters pushed to be reported, got %+v", query, report)
+		}
+	}
+	if code, _ := get("?proxyID=unknown.default"); code != http.StatusNotFound {
+		t.Fatalf("expected an unknown proxy not to be found, got code %d", code)
+	}
+}
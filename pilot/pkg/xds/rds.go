@@ -15,9 +15,17 @@
 package xds
 
 import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	istio_route "istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -64,13 +72,159 @@ func (c RdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *m
 	if !rdsNeedsPush(req) {
 		return nil, model.DefaultXdsLogDetails, nil
 	}
-	rawRoutes := c.Server.ConfigGenerator.BuildHTTPRoutes(proxy, push, w.ResourceNames)
-	resources := model.Resources{}
-	for _, c := range rawRoutes {
-		resources = append(resources, &discovery.Resource{
-			Name:     c.Name,
-			Resource: util.MessageToAny(c),
-		})
+	if !features.EnableRDSCaching || proxy.Type != model.SidecarProxy || proxy.SidecarScope == nil {
+		rawRoutes := c.Server.ConfigGenerator.BuildHTTPRoutes(proxy, push, w.ResourceNames)
+		resources := model.Resources{}
+		for _, c := range rawRoutes {
+			resources = append(resources, &discovery.Resource{
+				Name:     c.Name,
+				Resource: util.MessageToAny(c),
+			})
+		}
+		return resources, model.DefaultXdsLogDetails, nil
+	}
+
+	// Serve the cached routes, and only build the missing ones.
+	cache := model.CacheForProxy(c.Server.Cache, proxy)
+	base := newRdsCache(proxy, push)
+	cached := make(map[string]*discovery.Resource, len(w.ResourceNames))
+	tokens := make(map[string]model.CacheToken, len(w.ResourceNames))
+	var missing []string
+	for _, name := range w.ResourceNames {
+		res, token, f := cache.Get(base.forRoute(name))
+		if f {
+			cached[name] = res
+			continue
+		}
+		tokens[name] = token
+		missing = append(missing, name)
+	}
+	built := map[string]*discovery.Resource{}
+	if len(missing) > 0 {
+		for _, rc := range c.Server.ConfigGenerator.BuildHTTPRoutes(proxy, push, missing) {
+			res := &discovery.Resource{Name: rc.Name, Resource: util.MessageToAny(rc)}
+			built[rc.Name] = res
+			if token, f := tokens[rc.Name]; f {
+				cache.Add(base.forRoute(rc.Name), token, res)
+			}
+		}
+	}
+
+	resources := make(model.Resources, 0, len(w.ResourceNames))
+	for _, name := range w.ResourceNames {
+		if res, f := cached[name]; f {
+			resources = append(resources, res)
+		} else if res, f := built[name]; f {
+			resources = append(resources, res)
+		}
+	}
+	if len(cached) == 0 {
+		return resources, model.DefaultXdsLogDetails, nil
+	}
+	return resources, model.XdsLogDetails{AdditionalInfo: fmt.Sprintf("cached:%v/%v", len(cached), len(w.ResourceNames))}, nil
+}
+
+// rdsCache is the cache entry of a route configuration of a sidecar. A route only depends on the configs its
+// sidecar scope depends on, which are the dependent configs of the entry, and on the few proxy attributes used to
+// build the virtual hosts. The hash of the dependencies of the scope is part of the key, so that a scope importing
+// new configs does not read the routes built before.
+type rdsCache struct {
+	routeName    string
+	proxyType    model.NodeType
+	scope        *model.SidecarScope
+	dependencies []model.ConfigKey
+	scopeHash    string
+	clusterID    string
+	dnsDomain    string
+	// dnsAutoAllocate is set if the proxy resolves the addresses auto allocated to the ServiceEntries.
+	dnsAutoAllocate bool
+	istioVersion    string
+	cacheable       bool
+}
+
+var _ model.XdsCacheEntry = rdsCache{}
+
+func newRdsCache(proxy *model.Proxy, push *model.PushContext) rdsCache {
+	scope := proxy.SidecarScope
+	dependencies := scope.ConfigDependencies()
+	h := fnv.New64a()
+	for _, key := range dependencies {
+		_, _ = h.Write([]byte(key.String()))
+		_, _ = h.Write([]byte{0})
+	}
+	entry := rdsCache{
+		proxyType:    proxy.Type,
+		scope:        scope,
+		dependencies: dependencies,
+		scopeHash:    strconv.FormatUint(h.Sum64(), 16),
+		dnsDomain:    proxy.DNSDomain,
+		cacheable:    routesCacheable(proxy, push),
 	}
-	return resources, model.DefaultXdsLogDetails, nil
+	if proxy.Metadata != nil {
+		entry.clusterID = proxy.Metadata.ClusterID.String()
+		entry.dnsAutoAllocate = proxy.Metadata.DNSCapture && proxy.Metadata.DNSAutoAllocate
+	}
+	if proxy.IstioVersion != nil {
+		entry.istioVersion = fmt.Sprintf("%d.%d.%d", proxy.IstioVersion.Major, proxy.IstioVersion.Minor, proxy.IstioVersion.Patch)
+	}
+	return entry
+}
+
+func (r rdsCache) forRoute(name string) rdsCache {
+	r.routeName = name
+	return r
+}
+
+// routesCacheable returns whether the routes of the proxy are only determined by the key of the entries: they are
+// not if a VirtualService matches the source of the requests or restricts its faults to some workloads, or if an
+// EnvoyFilter patches the routes of the proxy.
+func routesCacheable(proxy *model.Proxy, push *model.PushContext) bool {
+	for _, el := range proxy.SidecarScope.EgressListeners {
+		for _, vs := range el.VirtualServices() {
+			if _, f := vs.Annotations[istio_route.FaultSourceLabelsAnnotation]; f {
+				return false
+			}
+			spec, ok := vs.Spec.(*networking.VirtualService)
+			if !ok {
+				continue
+			}
+			for _, route := range spec.Http {
+				for _, match := range route.Match {
+					if len(match.SourceLabels) > 0 || match.SourceNamespace != "" {
+						return false
+					}
+				}
+			}
+		}
+	}
+	if efw := push.EnvoyFilters(proxy); efw != nil {
+		for _, applyTo := range []networking.EnvoyFilter_ApplyTo{
+			networking.EnvoyFilter_ROUTE_CONFIGURATION, networking.EnvoyFilter_VIRTUAL_HOST, networking.EnvoyFilter_HTTP_ROUTE,
+		} {
+			if len(efw.Patches[applyTo]) > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r rdsCache) Key() string {
+	return "rds://" + strings.Join([]string{
+		r.routeName, string(r.proxyType), r.scope.Namespace + "/" + r.scope.Name, r.scopeHash,
+		r.clusterID, r.dnsDomain, strconv.FormatBool(r.dnsAutoAllocate), r.istioVersion,
+	}, "~")
+}
+
+// DependentTypes invalidates the routes on any EnvoyFilter change, as a new EnvoyFilter may patch them.
+func (r rdsCache) DependentTypes() []config.GroupVersionKind {
+	return []config.GroupVersionKind{gvk.EnvoyFilter}
+}
+
+func (r rdsCache) DependentConfigs() []model.ConfigKey {
+	return r.dependencies
+}
+
+func (r rdsCache) Cacheable() bool {
+	return r.cacheable
 }
@@ -15,12 +15,16 @@ package xds_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
 )
 
 func TestRDS(t *testing.T) {
@@ -57,6 +61,102 @@ func TestRDS(t *testing.T) {
 	}
 }
 
+func TestRDSCache(t *testing.T) {
+	defer func(old bool) { features.EnableRDSCaching = old }(features.EnableRDSCaching)
+	features.EnableRDSCaching = true
+
+	const serviceEntry = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hosts:
+  - foo.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+`
+	rdsKeys := func(s *xds.FakeDiscoveryServer) []string {
+		var keys []string
+		for _, k := range s.Discovery.Cache.Keys() {
+			if strings.HasPrefix(k, "rds://") {
+				keys = append(keys, k)
+			}
+		}
+		return keys
+	}
+	generate := func(s *xds.FakeDiscoveryServer) {
+		t.Helper()
+		proxy := s.SetupProxy(nil)
+		res, _, err := s.Discovery.Generators[v3.RouteType].Generate(proxy, s.PushContext(),
+			&model.WatchedResource{ResourceNames: []string{"80"}}, &model.PushRequest{Full: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != 1 || res[0].Name != "80" {
+			t.Fatalf("expected route 80, got %v", res)
+		}
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: serviceEntry})
+		generate(s)
+		if keys := rdsKeys(s); len(keys) != 1 {
+			t.Fatalf("expected the route to be cached, got %v", keys)
+		}
+		// The route is served from the cache.
+		generate(s)
+		if keys := rdsKeys(s); len(keys) != 1 {
+			t.Fatalf("expected a single entry, got %v", keys)
+		}
+
+		// An update of a config outside of the sidecar scope keeps the entry.
+		s.Discovery.Cache.Clear(map[model.ConfigKey]struct{}{
+			{Kind: gvk.ServiceEntry, Name: "bar.com", Namespace: "default"}: {},
+		})
+		if keys := rdsKeys(s); len(keys) != 1 {
+			t.Fatalf("expected the route to stay cached, got %v", keys)
+		}
+
+		// An update of a service of the sidecar scope invalidates the entry.
+		s.Discovery.Cache.Clear(map[model.ConfigKey]struct{}{
+			{Kind: gvk.ServiceEntry, Name: "foo.com", Namespace: "default"}: {},
+		})
+		if keys := rdsKeys(s); len(keys) != 0 {
+			t.Fatalf("expected the route to be invalidated, got %v", keys)
+		}
+	})
+
+	t.Run("source match", func(t *testing.T) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: serviceEntry + `
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hosts:
+  - foo.com
+  http:
+  - match:
+    - sourceLabels:
+        app: a
+    route:
+    - destination:
+        host: foo.com
+`})
+		generate(s)
+		if keys := rdsKeys(s); len(keys) != 0 {
+			t.Fatalf("expected the route not to be cached, got %v", keys)
+		}
+	})
+}
+
 const (
 	app3Ip    = "10.2.0.1"
 	gatewayIP = "10.3.0.1"
@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// readOnlyHeader is set on the responses of the debug handlers while istiod is read-only.
+const readOnlyHeader = "X-Istiod-Read-Only"
+
+// readOnlyState tracks the read-only mode of istiod. While read-only, full pushes, which rebuild the push
+// context from the config stores and registries, are held and merged. Proxies keep being served from the
+// current push context. Incremental endpoint updates are not held.
+type readOnlyState struct {
+	mu      sync.Mutex
+	enabled bool
+	since   time.Time
+	// held merges the full pushes received since istiod became read-only.
+	held      *model.PushRequest
+	heldCount int
+}
+
+// ReadOnlyStatus describes the read-only mode of istiod. It is returned by /debug/readonly.
+type ReadOnlyStatus struct {
+	ReadOnly bool       `json:"readOnly"`
+	Since    *time.Time `json:"since,omitempty"`
+	// HeldUpdates is the number of config updates held since istiod became read-only.
+	HeldUpdates int `json:"heldUpdates,omitempty"`
+	// HeldConfigs are the changed configs held, as kind/namespace/name. Empty if unknown.
+	HeldConfigs []string `json:"heldConfigs,omitempty"`
+}
+
+// SetReadOnly enables or disables the read-only mode. When it is disabled, the updates held are pushed.
+func (s *DiscoveryServer) SetReadOnly(enabled bool) {
+	s.readOnly.mu.Lock()
+	if s.readOnly.enabled == enabled {
+		s.readOnly.mu.Unlock()
+		return
+	}
+	s.readOnly.enabled = enabled
+	held, count := s.readOnly.held, s.readOnly.heldCount
+	s.readOnly.held, s.readOnly.heldCount = nil, 0
+	if enabled {
+		s.readOnly.since = time.Now()
+	}
+	s.readOnly.mu.Unlock()
+
+	if enabled {
+		log.Warnf("Istiod is read-only: config changes are held, proxies are served the current config")
+		return
+	}
+	log.Infof("Istiod is no longer read-only, pushing %d held config updates", count)
+	if held != nil {
+		held.Start = time.Now()
+		s.ConfigUpdate(held)
+	}
+}
+
+// ReadOnly returns the status of the read-only mode.
+func (s *DiscoveryServer) ReadOnly() ReadOnlyStatus {
+	s.readOnly.mu.Lock()
+	defer s.readOnly.mu.Unlock()
+	if !s.readOnly.enabled {
+		return ReadOnlyStatus{}
+	}
+	since := s.readOnly.since
+	out := ReadOnlyStatus{
+		ReadOnly:    true,
+		Since:       &since,
+		HeldUpdates: s.readOnly.heldCount,
+	}
+	if s.readOnly.held != nil {
+		for key := range s.readOnly.held.ConfigsUpdated {
+			out.HeldConfigs = append(out.HeldConfigs, key.String())
+		}
+		sort.Strings(out.HeldConfigs)
+	}
+	return out
+}
+
+// holdUpdate holds a full push while istiod is read-only, and returns true if it did.
+func (s *DiscoveryServer) holdUpdate(req *model.PushRequest) bool {
+	if !req.Full {
+		return false
+	}
+	s.readOnly.mu.Lock()
+	defer s.readOnly.mu.Unlock()
+	if !s.readOnly.enabled {
+		return false
+	}
+	s.readOnly.held = s.readOnly.held.Merge(req)
+	s.readOnly.heldCount++
+	return true
+}
+
+// advertiseReadOnly marks the responses of a debug handler while istiod is read-only.
+func (s *DiscoveryServer) advertiseReadOnly(handler func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		s.readOnly.mu.Lock()
+		enabled := s.readOnly.enabled
+		s.readOnly.mu.Unlock()
+		if enabled {
+			w.Header().Set(readOnlyHeader, "true")
+		}
+		handler(w, req)
+	}
+}
+
+// readOnlyz reports the read-only mode of istiod. A POST with enabled=true|false sets it.
+// It is mapped to /debug/readonly.
+func (s *DiscoveryServer) readOnlyz(w http.ResponseWriter, req *http.Request) {
+	_ = req.ParseForm()
+	switch req.Method {
+	case http.MethodGet:
+		if req.Form.Get("enabled") != "" {
+			w.Header().Set("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_, _ = w.Write([]byte("the read-only mode is set with POST\n"))
+			return
+		}
+	case http.MethodPost:
+		v := req.Form.Get("enabled")
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid enabled %q\n", v)
+			return
+		}
+		if !s.AllowMeshWide(w, req) {
+			return
+		}
+		log.Infof("Read-only mode set to %v by %v", enabled, debugCaller(req))
+		s.SetReadOnly(enabled)
+		if enabled {
+			w.Header().Set(readOnlyHeader, "true")
+		} else {
+			w.Header().Del(readOnlyHeader)
+		}
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.ReadOnly())
+}
@@ -0,0 +1,78 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestReadOnly(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	initial := ads.RequestResponseAck(t, nil)
+
+	readOnlyz := func(method, query string) (*httptest.ResponseRecorder, ReadOnlyStatus) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.Discovery.advertiseReadOnly(s.Discovery.readOnlyz)(rr, httptest.NewRequest(method, "/debug/readonly"+query, nil))
+		out := ReadOnlyStatus{}
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr, out
+	}
+
+	// A GET never changes the mode.
+	if rr, _ := readOnlyz("GET", "?enabled=true"); rr.Code != http.StatusMethodNotAllowed || s.Discovery.ReadOnly().ReadOnly {
+		t.Fatalf("expected a GET to be rejected, got %d", rr.Code)
+	}
+	if rr, status := readOnlyz("POST", "?enabled=true"); !status.ReadOnly || rr.Header().Get(readOnlyHeader) != "true" {
+		t.Fatalf("expected istiod to be read-only, got %+v", status)
+	}
+
+	// A config change is held, and the proxy keeps its config.
+	s.Discovery.MemRegistry.AddHTTPService("frozen.default.svc.cluster.local", "10.10.0.1", 80)
+	s.Discovery.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.ServiceEntry, Name: "frozen.default.svc.cluster.local", Namespace: "default"}: {}},
+		Reason:         []model.TriggerReason{model.ServiceUpdate},
+	})
+	ads.ExpectNoResponse(t)
+	_, status := readOnlyz("GET", "")
+	if status.HeldUpdates != 1 || len(status.HeldConfigs) != 1 || status.HeldConfigs[0] != "ServiceEntry/default/frozen.default.svc.cluster.local" {
+		t.Fatalf("expected the update to be held, got %+v", status)
+	}
+	if rr, _ := readOnlyz("POST", "?enabled=maybe"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid value to be rejected, got %d", rr.Code)
+	}
+
+	// The held updates are pushed when istiod is writable again.
+	if rr, status := readOnlyz("POST", "?enabled=false"); status.ReadOnly || rr.Header().Get(readOnlyHeader) != "" {
+		t.Fatalf("expected istiod to be writable, got %+v", status)
+	}
+	if got := ads.ExpectResponse(t); len(got.Resources) != len(initial.Resources)+1 {
+		t.Fatalf("expected the cluster of the new service to be added to %d clusters, got %d",
+			len(initial.Resources), len(got.Resources))
+	}
+}
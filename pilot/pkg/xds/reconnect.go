@@ -0,0 +1,61 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Drain closes the streams of all the connected proxies, asking each of them to wait for a random delay within
+// the window before reconnecting, so that they spread their reconnections over the window instead of racing back
+// to the remaining istiod replicas at once. It is called when istiod shuts down.
+func (s *DiscoveryServer) Drain(window time.Duration) {
+	clients := s.AllClients()
+	log.Infof("draining %d XDS connections over %v", len(clients), window)
+	for _, con := range clients {
+		con.stopWithReconnectHint(reconnectDelay(window))
+	}
+}
+
+// reconnectDelay returns a random delay of at least a millisecond within the window.
+func reconnectDelay(window time.Duration) time.Duration {
+	if window <= time.Millisecond {
+		return time.Millisecond
+	}
+	return time.Millisecond + time.Duration(rand.Int63n(int64(window-time.Millisecond)))
+}
+
+// stopWithReconnectHint ends the connection, asking the proxy to wait for the delay before reconnecting.
+func (conn *Connection) stopWithReconnectHint(delay time.Duration) {
+	conn.stopOnce.Do(func() {
+		conn.reconnectAfter = delay
+		close(conn.stop)
+	})
+}
+
+// stopStatus returns the status ending the stream of a stopped connection. Connections stopped while draining
+// end with an Unavailable status and a hint of when to reconnect in the trailer.
+func (conn *Connection) stopStatus(stream grpc.ServerStream) error {
+	if conn.reconnectAfter <= 0 {
+		return nil
+	}
+	setRetryPushback(stream, conn.reconnectAfter)
+	return status.Errorf(codes.Unavailable, "server draining; reconnect in %v", conn.reconnectAfter)
+}
@@ -0,0 +1,75 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServerStream records the trailer set by the server.
+type fakeServerStream struct {
+	grpc.ServerStream
+	trailer metadata.MD
+}
+
+func (f *fakeServerStream) SetTrailer(md metadata.MD) {
+	f.trailer = metadata.Join(f.trailer, md)
+}
+
+func TestReconnectHint(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := reconnectDelay(time.Minute); d < time.Millisecond || d >= time.Minute {
+			t.Fatalf("delay %v out of the window", d)
+		}
+	}
+	if d := reconnectDelay(0); d != time.Millisecond {
+		t.Fatalf("expected a minimal delay without window, got %v", d)
+	}
+
+	con := &Connection{stop: make(chan struct{})}
+	if err := con.stopStatus(nil); err != nil {
+		t.Fatalf("expected a connection not drained to end without error, got %v", err)
+	}
+	con.stopWithReconnectHint(time.Second)
+	// stopping again keeps the first hint
+	con.stopWithReconnectHint(time.Minute)
+	con.Stop()
+	select {
+	case <-con.stop:
+	default:
+		t.Fatalf("expected the connection to be stopped")
+	}
+	if con.reconnectAfter != time.Second {
+		t.Fatalf("expected a reconnection hint of 1s, got %v", con.reconnectAfter)
+	}
+}
+
+func TestStopStatus(t *testing.T) {
+	con := &Connection{stop: make(chan struct{}), reconnectAfter: 1500 * time.Millisecond}
+	stream := &fakeServerStream{}
+	err := con.stopStatus(stream)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected an Unavailable status, got %v", err)
+	}
+	if got := stream.trailer.Get("grpc-retry-pushback-ms"); len(got) != 1 || got[0] != "1500" {
+		t.Fatalf("expected a retry pushback of 1500ms, got %v", got)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/util/sets"
+)
+
+// redactedValue replaces the redacted values in the debug output.
+const redactedValue = "[redacted]"
+
+// sensitiveFields are the fields always redacted from the debug output: private keys, passwords and tokens.
+var sensitiveFields = map[protoreflect.FullName]struct{}{
+	"envoy.extensions.transport_sockets.tls.v3.TlsCertificate.private_key":                                    {},
+	"envoy.extensions.transport_sockets.tls.v3.TlsCertificate.password":                                       {},
+	"envoy.extensions.transport_sockets.tls.v3.TlsSessionTicketKeys.keys":                                     {},
+	"envoy.extensions.transport_sockets.tls.v3.GenericSecret.secret":                                          {},
+	"envoy.config.core.v3.GrpcService.GoogleGrpc.SslCredentials.private_key":                                  {},
+	"envoy.config.core.v3.GrpcService.GoogleGrpc.CallCredentials.access_token":                                {},
+	"envoy.config.core.v3.GrpcService.GoogleGrpc.CallCredentials.google_refresh_token":                        {},
+	"envoy.config.core.v3.GrpcService.GoogleGrpc.CallCredentials.ServiceAccountJWTAccessCredentials.json_key": {},
+	"envoy.config.core.v3.GrpcService.GoogleGrpc.CallCredentials.GoogleIAMCredentials.authorization_token":    {},
+}
+
+// redactionPolicy strips the secrets from the config served by the debug interface: the sensitive fields, and
+// the values of the headers identified by the user, set by or matched in the config.
+type redactionPolicy struct {
+	headers sets.Set
+}
+
+func newRedactionPolicy(headers []string) *redactionPolicy {
+	p := &redactionPolicy{headers: sets.NewSet()}
+	for _, h := range headers {
+		p.headers.Insert(strings.ToLower(h))
+	}
+	return p
+}
+
+// redact redacts the message in place, including the messages packed in Any.
+func (p *redactionPolicy) redact(msg proto.Message) {
+	if msg == nil {
+		return
+	}
+	p.redactMessage(msg.ProtoReflect())
+}
+
+func (p *redactionPolicy) redactMessage(m protoreflect.Message) {
+	if !m.IsValid() {
+		return
+	}
+	switch msg := m.Interface().(type) {
+	case *core.HeaderValue:
+		if p.headers.Contains(strings.ToLower(msg.Key)) {
+			msg.Value = redactedValue
+		}
+		return
+	case *route.HeaderMatcher:
+		if !p.headers.Contains(strings.ToLower(msg.Name)) {
+			return
+		}
+		switch msg.HeaderMatchSpecifier.(type) {
+		case *route.HeaderMatcher_ExactMatch, *route.HeaderMatcher_PrefixMatch, *route.HeaderMatcher_SuffixMatch,
+			*route.HeaderMatcher_ContainsMatch, *route.HeaderMatcher_SafeRegexMatch:
+			msg.HeaderMatchSpecifier = &route.HeaderMatcher_ExactMatch{ExactMatch: redactedValue}
+		}
+		return
+	case *anypb.Any:
+		inner, err := msg.UnmarshalNew()
+		if err != nil {
+			// Types unknown to istiod cannot hold secrets it generated.
+			return
+		}
+		p.redact(inner)
+		if value, err := (proto.MarshalOptions{Deterministic: true}).Marshal(inner); err == nil {
+			msg.Value = value
+		}
+		return
+	}
+
+	var sensitive []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if _, f := sensitiveFields[fd.FullName()]; f {
+			sensitive = append(sensitive, fd)
+			return true
+		}
+		if fd.Kind() != protoreflect.MessageKind {
+			return true
+		}
+		switch {
+		case fd.IsList():
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				p.redactMessage(l.Get(i).Message())
+			}
+		case fd.IsMap():
+			if fd.MapValue().Kind() == protoreflect.MessageKind {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					p.redactMessage(mv.Message())
+					return true
+				})
+			}
+		default:
+			p.redactMessage(v.Message())
+		}
+		return true
+	})
+	// The fields are replaced once the iteration is over, as the message must not be modified while iterated.
+	for _, fd := range sensitive {
+		redactField(m, fd)
+	}
+}
+
+// redactField replaces the value of a sensitive field.
+func redactField(m protoreflect.Message, fd protoreflect.FieldDescriptor) {
+	if fd.IsList() {
+		l := m.Mutable(fd).List()
+		for i := 0; i < l.Len(); i++ {
+			if v, ok := redactedScalar(fd, l.Get(i)); ok {
+				l.Set(i, v)
+			}
+		}
+		return
+	}
+	if v, ok := redactedScalar(fd, m.Get(fd)); ok {
+		m.Set(fd, v)
+	}
+}
+
+// redactedScalar returns the redacted form of a value of a sensitive field, if it must be replaced. The inline
+// data sources are redacted in place, while the ones read from a file are kept: only the path is in the config.
+func redactedScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) (protoreflect.Value, bool) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(redactedValue), true
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(redactedValue)), true
+	case protoreflect.MessageKind:
+		ds, ok := v.Message().Interface().(*core.DataSource)
+		if !ok {
+			return v, false
+		}
+		switch ds.Specifier.(type) {
+		case *core.DataSource_InlineBytes, *core.DataSource_InlineString:
+			ds.Specifier = &core.DataSource_InlineBytes{InlineBytes: []byte(redactedValue)}
+		}
+		return v, false
+	}
+	return v, false
+}
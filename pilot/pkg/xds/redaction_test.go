@@ -0,0 +1,133 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+func inlineBytes(b string) *core.DataSource {
+	return &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: []byte(b)}}
+}
+
+func TestRedaction(t *testing.T) {
+	p := newRedactionPolicy([]string{"Authorization"})
+
+	t.Run("secrets", func(t *testing.T) {
+		secret := &tls.Secret{
+			Name: "default",
+			Type: &tls.Secret_TlsCertificate{TlsCertificate: &tls.TlsCertificate{
+				CertificateChain: inlineBytes("cert"),
+				PrivateKey:       inlineBytes("key"),
+				Password:         &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: "password"}},
+			}},
+		}
+		dump := &adminapi.SecretsConfigDump{DynamicActiveSecrets: []*adminapi.SecretsConfigDump_DynamicSecret{{
+			Name:   "default",
+			Secret: util.MessageToAny(secret),
+		}}}
+		p.redact(dump)
+
+		got := &tls.Secret{}
+		if err := dump.DynamicActiveSecrets[0].Secret.UnmarshalTo(got); err != nil {
+			t.Fatal(err)
+		}
+		cert := got.GetTlsCertificate()
+		if string(cert.PrivateKey.GetInlineBytes()) != redactedValue || string(cert.Password.GetInlineBytes()) != redactedValue {
+			t.Fatalf("expected the private key and password to be redacted, got %v", cert)
+		}
+		if string(cert.CertificateChain.GetInlineBytes()) != "cert" {
+			t.Fatalf("expected the certificate chain to be kept, got %v", cert.CertificateChain)
+		}
+	})
+
+	t.Run("file data sources", func(t *testing.T) {
+		cert := &tls.TlsCertificate{
+			PrivateKey: &core.DataSource{Specifier: &core.DataSource_Filename{Filename: "/etc/certs/key.pem"}},
+		}
+		p.redact(cert)
+		if cert.PrivateKey.GetFilename() != "/etc/certs/key.pem" {
+			t.Fatalf("expected the path of the private key to be kept, got %v", cert.PrivateKey)
+		}
+	})
+
+	t.Run("tokens", func(t *testing.T) {
+		grpc := &core.GrpcService{TargetSpecifier: &core.GrpcService_GoogleGrpc_{GoogleGrpc: &core.GrpcService_GoogleGrpc{
+			CallCredentials: []*core.GrpcService_GoogleGrpc_CallCredentials{{
+				CredentialSpecifier: &core.GrpcService_GoogleGrpc_CallCredentials_AccessToken{AccessToken: "token"},
+			}},
+		}}}
+		p.redact(grpc)
+		if got := grpc.GetGoogleGrpc().CallCredentials[0].GetAccessToken(); got != redactedValue {
+			t.Fatalf("expected the access token to be redacted, got %q", got)
+		}
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		rc := &route.RouteConfiguration{
+			RequestHeadersToAdd: []*core.HeaderValueOption{
+				{Header: &core.HeaderValue{Key: "authorization", Value: "Bearer token"}},
+				{Header: &core.HeaderValue{Key: "x-request-source", Value: "mesh"}},
+			},
+			VirtualHosts: []*route.VirtualHost{{
+				Name: "vh",
+				Routes: []*route.Route{{
+					Match: &route.RouteMatch{Headers: []*route.HeaderMatcher{
+						{Name: "Authorization", HeaderMatchSpecifier: &route.HeaderMatcher_PrefixMatch{PrefixMatch: "Bearer "}},
+						{Name: "authorization", HeaderMatchSpecifier: &route.HeaderMatcher_PresentMatch{PresentMatch: true}},
+					}},
+				}},
+			}},
+		}
+		// The headers are found in the messages packed in Any, at any depth.
+		l := &listener.Listener{FilterChains: []*listener.FilterChain{{
+			Filters: []*listener.Filter{{
+				Name: "envoy.filters.network.http_connection_manager",
+				ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(&hcm.HttpConnectionManager{
+					RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{RouteConfig: rc},
+				})},
+			}},
+		}}}
+		p.redact(l)
+
+		got := &hcm.HttpConnectionManager{}
+		if err := l.FilterChains[0].Filters[0].GetTypedConfig().UnmarshalTo(got); err != nil {
+			t.Fatal(err)
+		}
+		headers := got.GetRouteConfig().RequestHeadersToAdd
+		if headers[0].Header.Value != redactedValue {
+			t.Fatalf("expected the authorization header to be redacted, got %v", headers[0].Header)
+		}
+		if headers[1].Header.Value != "mesh" {
+			t.Fatalf("expected other headers to be kept, got %v", headers[1].Header)
+		}
+		matches := got.GetRouteConfig().VirtualHosts[0].Routes[0].Match.Headers
+		if matches[0].GetExactMatch() != redactedValue {
+			t.Fatalf("expected the authorization header match to be redacted, got %v", matches[0])
+		}
+		if !matches[1].GetPresentMatch() {
+			t.Fatalf("expected the presence match to be kept, got %v", matches[1])
+		}
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/grpc/metadata"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// resumptionTokenVersion is bumped whenever the token format changes. Tokens of other versions are ignored.
+const resumptionTokenVersion = 1
+
+// resumptionToken records, for each type, the version and a hash of the last complete response ACKed by a
+// proxy. The hash is computed from the generated resources, so the token can be verified by any Istiod
+// replica: if a reconnecting proxy still has the version listed in the token, and the replica generates
+// identical resources, the initial response for that type can be skipped. The token does not record the
+// individual resources, so the initial response of a type whose config changed is sent complete.
+type resumptionToken struct {
+	Version int                      `json:"v"`
+	Types   map[string]resumedConfig `json:"types"`
+}
+
+type resumedConfig struct {
+	Version string `json:"version"`
+	Hash    string `json:"hash"`
+}
+
+func (t resumptionToken) encode() (string, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeResumptionToken(s string) (map[string]resumedConfig, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	t := resumptionToken{}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, err
+	}
+	if t.Version != resumptionTokenVersion {
+		return nil, fmt.Errorf("unsupported token version %d", t.Version)
+	}
+	return t.Types, nil
+}
+
+// resumptionTokenFromContext returns the token presented by the client when opening the stream, if any.
+func resumptionTokenFromContext(ctx context.Context) map[string]resumedConfig {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	values := md.Get(v3.ResumptionTokenHeader)
+	if len(values) == 0 || values[0] == "" {
+		return nil
+	}
+	types, err := decodeResumptionToken(values[0])
+	if err != nil {
+		log.Debugf("ignoring invalid resumption token: %v", err)
+		return nil
+	}
+	return types
+}
+
+// hashResources returns a hash of the names and content of the resources, in order.
+func hashResources(res model.Resources) string {
+	h := fnv.New64a()
+	for _, r := range res {
+		_, _ = h.Write([]byte(r.Name))
+		_, _ = h.Write([]byte{0})
+		if r.Resource != nil {
+			_, _ = h.Write([]byte(r.Resource.TypeUrl))
+			_, _ = h.Write(r.Resource.Value)
+		}
+		_, _ = h.Write([]byte{0})
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// resume reports whether the response for the watched type can be skipped, because the client reconnected
+// with a token showing that it already runs identical resources. Only the first response of each type is
+// eligible.
+func (conn *Connection) resume(w *model.WatchedResource, hash string) bool {
+	r, f := conn.resumption[w.TypeUrl]
+	if !f {
+		return false
+	}
+	delete(conn.resumption, w.TypeUrl)
+	return w.LastRequest != nil && w.LastRequest.VersionInfo == r.Version && r.Hash == hash
+}
+
+// unchangedPush reports whether the complete response of hash can be skipped, because it is identical to the
+// last response of the type ACKed by the proxy, and no later response is pending. The responses to the requests
+// of the proxy are always sent, as the proxy may be waiting for them.
+func unchangedPush(w *model.WatchedResource, hash string, req *model.PushRequest) bool {
+	if req == nil || (len(req.Reason) == 1 && req.Reason[0] == model.ProxyRequest) {
+		return false
+	}
+	return w.NonceSent != "" && w.NonceAcked == w.NonceSent && w.AckedHash == hash
+}
+
+// ResumptionTokenGenerator generates the resumption token of a proxy, from the responses ACKed on its
+// connection so far. Clusters, endpoints, listeners and routes are pushed before any other type, so the
+// token issued with a full push covers them.
+type ResumptionTokenGenerator struct {
+	Server *DiscoveryServer
+}
+
+var _ model.XdsResourceGenerator = &ResumptionTokenGenerator{}
+
+// Generate returns a Struct holding the encoded token.
+func (g ResumptionTokenGenerator) Generate(proxy *model.Proxy, _ *model.PushContext, w *model.WatchedResource,
+	req *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	if req != nil && !req.Full {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	t := resumptionToken{Version: resumptionTokenVersion, Types: map[string]resumedConfig{}}
+	proxy.RLock()
+	for typeURL, wr := range proxy.WatchedResources {
+		if wr == w || wr.AckedHash == "" || wr.VersionAcked == "" {
+			continue
+		}
+		t.Types[typeURL] = resumedConfig{Version: wr.VersionAcked, Hash: wr.AckedHash}
+	}
+	proxy.RUnlock()
+	token, err := t.encode()
+	if err != nil {
+		return nil, model.DefaultXdsLogDetails, err
+	}
+	out := &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"token": {Kind: &structpb.Value_StringValue{StringValue: token}},
+		},
+	}
+	return model.Resources{&discovery.Resource{Resource: util.MessageToAny(out)}}, model.DefaultXdsLogDetails, nil
+}
@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestResumptionToken(t *testing.T) {
+	res := func(value string) model.Resources {
+		st := &structpb.Struct{Fields: map[string]*structpb.Value{
+			"v": {Kind: &structpb.Value_StringValue{StringValue: value}},
+		}}
+		return model.Resources{&discovery.Resource{Name: "outbound|80||foo", Resource: util.MessageToAny(st)}}
+	}
+	hash := hashResources(res("a"))
+	if hash == hashResources(res("b")) {
+		t.Fatalf("expected different resources to have different hashes")
+	}
+
+	proxy := &model.Proxy{WatchedResources: map[string]*model.WatchedResource{
+		v3.ClusterType:         {TypeUrl: v3.ClusterType, VersionSent: "v2", ResourcesHash: "other", VersionAcked: "v1", AckedHash: hash},
+		v3.EndpointType:        {TypeUrl: v3.EndpointType, VersionSent: "v2", VersionAcked: "v2"},
+		v3.ListenerType:        {TypeUrl: v3.ListenerType, VersionSent: "v2", ResourcesHash: hash},
+		v3.ResumptionTokenType: {TypeUrl: v3.ResumptionTokenType},
+	}}
+	out, _, err := ResumptionTokenGenerator{}.Generate(proxy, nil, proxy.WatchedResources[v3.ResumptionTokenType],
+		&model.PushRequest{Full: true})
+	if err != nil || len(out) != 1 {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	st := &structpb.Struct{}
+	if err := out[0].Resource.UnmarshalTo(st); err != nil {
+		t.Fatal(err)
+	}
+	types, err := decodeResumptionToken(st.Fields["token"].GetStringValue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The clusters are resumed from the response ACKed, not the one in flight. Endpoints were not sent
+	// completely, and listeners were not ACKed, so they can't be resumed.
+	if len(types) != 1 || types[v3.ClusterType] != (resumedConfig{Version: "v1", Hash: hash}) {
+		t.Fatalf("unexpected token content: %v", types)
+	}
+	if _, err := decodeResumptionToken("not-a-token"); err == nil {
+		t.Fatalf("expected invalid token to be rejected")
+	}
+
+	cases := []struct {
+		name    string
+		version string
+		hash    string
+		resume  bool
+	}{
+		{"identical", "v1", hash, true},
+		{"proxy has another version", "v0", hash, false},
+		{"config changed", "v1", hashResources(res("b")), false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			con := &Connection{resumption: map[string]resumedConfig{v3.ClusterType: types[v3.ClusterType]}}
+			w := &model.WatchedResource{TypeUrl: v3.ClusterType, LastRequest: &discovery.DiscoveryRequest{VersionInfo: tt.version}}
+			if got := con.resume(w, tt.hash); got != tt.resume {
+				t.Fatalf("expected resume=%v, got %v", tt.resume, got)
+			}
+			// Only the first response can be skipped.
+			if con.resume(w, tt.hash) {
+				t.Fatalf("expected only the first response to be resumed")
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// sheddingPollInterval is the interval at which a delayed connection checks whether the overload is over.
+const sheddingPollInterval = 100 * time.Millisecond
+
+// connectionShedder rejects new connections while the push queue is overloaded, so that the proxies already
+// connected get their pushes first. Without it, proxies reconnecting at once, for instance after an istiod
+// restart, keep the queue full with initial pushes, time out and reconnect again.
+type connectionShedder struct {
+	// maxPending is the number of proxies waiting in the push queue above which new connections are shed.
+	// Zero disables shedding.
+	maxPending int
+	// maxDelay is how long a new connection waits for the overload to end before it is rejected.
+	maxDelay time.Duration
+	// backoff is the base retry delay hinted to the rejected clients. A jitter of up to the same duration is added.
+	backoff time.Duration
+	pending func() int
+}
+
+func newConnectionShedder(pending func() int) connectionShedder {
+	return connectionShedder{
+		maxPending: features.MaxPushQueueForNewConnections,
+		maxDelay:   features.ShedConnectionMaxDelay,
+		backoff:    features.ShedConnectionBackoff,
+		pending:    pending,
+	}
+}
+
+func (c connectionShedder) overloaded() bool {
+	return c.maxPending > 0 && c.pending() >= c.maxPending
+}
+
+// admit returns true if a new connection can be served, waiting up to maxDelay for the overload to end.
+// Otherwise, it returns the delay the client should wait before retrying.
+func (c connectionShedder) admit(ctx context.Context) (bool, time.Duration) {
+	if !c.overloaded() {
+		return true, 0
+	}
+	if c.maxDelay > 0 {
+		timeout := time.NewTimer(c.maxDelay)
+		defer timeout.Stop()
+		ticker := time.NewTicker(sheddingPollInterval)
+		defer ticker.Stop()
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				break wait
+			case <-timeout.C:
+				break wait
+			case <-ticker.C:
+				if !c.overloaded() {
+					return true, 0
+				}
+			}
+		}
+	}
+	backoff := c.backoff
+	if backoff > 0 {
+		backoff += time.Duration(rand.Int63n(int64(backoff)))
+	}
+	return false, backoff
+}
+
+// shedConnection admits a new connection, or returns the retryable error rejecting it, with a backoff hint.
+func (s *DiscoveryServer) shedConnection(stream grpc.ServerStream) error {
+	ok, backoff := s.shedder.admit(stream.Context())
+	if ok {
+		return nil
+	}
+	xdsConnectionsShed.Increment()
+	setRetryPushback(stream, backoff)
+	return status.Errorf(codes.Unavailable, "server overloaded with %d pending pushes; retry in %v",
+		s.pendingPushes(), backoff)
+}
+
+// setRetryPushback hints the client of a stream closed by the server to wait before reconnecting.
+func setRetryPushback(stream grpc.ServerStream, d time.Duration) {
+	if d > 0 {
+		stream.SetTrailer(metadata.Pairs(v3.RetryPushbackTrailer, strconv.FormatInt(d.Milliseconds(), 10)))
+	}
+}
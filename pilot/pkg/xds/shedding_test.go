@@ -0,0 +1,68 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+func TestConnectionShedder(t *testing.T) {
+	pending := atomic.NewInt32(0)
+	shedder := connectionShedder{
+		maxPending: 10,
+		backoff:    time.Second,
+		pending:    func() int { return int(pending.Load()) },
+	}
+
+	if ok, _ := shedder.admit(context.Background()); !ok {
+		t.Fatalf("expected a connection to be admitted without overload")
+	}
+
+	pending.Store(10)
+	ok, backoff := shedder.admit(context.Background())
+	if ok {
+		t.Fatalf("expected a connection to be shed during overload")
+	}
+	if backoff < time.Second || backoff >= 2*time.Second {
+		t.Fatalf("expected a backoff between 1s and 2s, got %v", backoff)
+	}
+
+	// a delayed connection is admitted once the overload ends
+	shedder.maxDelay = 5 * time.Second
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		pending.Store(5)
+	}()
+	if ok, _ := shedder.admit(context.Background()); !ok {
+		t.Fatalf("expected a delayed connection to be admitted once the overload ended")
+	}
+
+	// a delayed connection is shed if the client goes away
+	pending.Store(10)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if ok, _ := shedder.admit(ctx); ok {
+		t.Fatalf("expected a delayed connection to be shed during overload")
+	}
+
+	shedder.maxPending = 0
+	if ok, _ := shedder.admit(context.Background()); !ok {
+		t.Fatalf("expected a connection to be admitted with shedding disabled")
+	}
+}
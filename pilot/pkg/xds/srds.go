@@ -0,0 +1,46 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// SrdsGenerator generates the route scopes of sidecars fetching their outbound routes on demand.
+type SrdsGenerator struct {
+	Server *DiscoveryServer
+}
+
+var _ model.XdsResourceGenerator = &SrdsGenerator{}
+
+func (c SrdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource,
+	req *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	// Scopes are built from the routes, so they change whenever the routes do.
+	if !rdsNeedsPush(req) {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	scopes := c.Server.ConfigGenerator.BuildScopedRoutes(proxy, push)
+	resources := model.Resources{}
+	for _, s := range scopes {
+		resources = append(resources, &discovery.Resource{
+			Name:     s.Name,
+			Resource: util.MessageToAny(s),
+		})
+	}
+	return resources, model.DefaultXdsLogDetails, nil
+}
@@ -35,6 +35,10 @@ const (
 	// TypeURLDisconnect generate disconnect event.
 	TypeURLDisconnect = "istio.io/disconnect"
 
+	// TypeURLDuplicate generate an event when a proxy opens a connection while another
+	// connection with the same node ID is active.
+	TypeURLDuplicate = "istio.io/duplicate"
+
 	// TypeURLNACK will receive messages of type DiscoveryRequest, containing
 	// the 'NACK' from envoy on rejected configs. Only ID is set in metadata.
 	// This includes all the info that envoy (client) provides.
@@ -183,19 +187,34 @@ func (sg *StatusGen) debugConfigDump(proxyID string) (model.Resources, error) {
 }
 
 func (sg *StatusGen) OnConnect(con *Connection) {
+	sg.Server.debugEvents.publishConnection(DebugEventConnect, con)
 	sg.pushStatusEvent(TypeURLConnect, []proto.Message{con.node})
 }
 
 func (sg *StatusGen) OnDisconnect(con *Connection) {
+	sg.Server.debugEvents.publishConnection(DebugEventDisconnect, con)
 	sg.pushStatusEvent(TypeURLDisconnect, []proto.Message{con.node})
 }
 
+func (sg *StatusGen) OnDuplicate(con *Connection) {
+	sg.pushStatusEvent(TypeURLDuplicate, []proto.Message{con.node})
+}
+
 func (sg *StatusGen) OnNack(node *model.Proxy, dr *discovery.DiscoveryRequest) {
 	// Make sure we include the ID - the DR may not include metadata
 	if dr.Node == nil {
 		dr.Node = &core.Node{}
 	}
 	dr.Node.Id = node.ID
+	sg.Server.debugEvents.publish(DebugEvent{
+		Type:      DebugEventNack,
+		ProxyID:   node.ID,
+		Namespace: node.ConfigNamespace,
+		TypeURL:   dr.TypeUrl,
+		Version:   dr.VersionInfo,
+		Nonce:     dr.ResponseNonce,
+		Error:     dr.ErrorDetail.GetMessage(),
+	})
 	sg.pushStatusEvent(TypeURLNACK, []proto.Message{dr})
 }
 
@@ -0,0 +1,302 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+const (
+	// tenantByRevision isolates the proxies of each control plane revision, from their istio.io/rev label.
+	tenantByRevision = "revision"
+	// tenantByNamespace isolates the proxies of each namespace.
+	tenantByNamespace = "namespace"
+
+	revisionLabel = "istio.io/rev"
+)
+
+// tenantFunc returns the function returning the tenant of a proxy for the isolation scope, or nil if the scope is
+// unknown or empty.
+//
+// Tenants only have their own XDS cache and push queue. The PushContext is still built once per push for all the
+// tenants, so a config change of one tenant triggers the rebuild of the PushContext used by the others.
+func tenantFunc(scope string) func(proxy *model.Proxy) string {
+	switch scope {
+	case tenantByRevision:
+		return func(proxy *model.Proxy) string {
+			if proxy.Metadata != nil && proxy.Metadata.Labels[revisionLabel] != "" {
+				return proxy.Metadata.Labels[revisionLabel]
+			}
+			return model.DefaultTenant
+		}
+	case tenantByNamespace:
+		return func(proxy *model.Proxy) string {
+			if proxy.ConfigNamespace != "" {
+				return proxy.ConfigNamespace
+			}
+			return model.DefaultTenant
+		}
+	case "":
+	default:
+		log.Warnf("ignoring unknown tenant isolation %q", scope)
+	}
+	return nil
+}
+
+const (
+	// tenantIdleTimeout is how long the queue and cache of a tenant without proxies are kept, before they are
+	// garbage collected.
+	tenantIdleTimeout = 5 * time.Minute
+	// maxTenantLabels bounds the number of tenants reported with their own metric label. The tenants beyond it are
+	// reported as otherTenant, so that the cardinality of the metrics does not grow with the number of namespaces.
+	maxTenantLabels = 100
+	otherTenant     = "other"
+)
+
+// tenantQueue is the push queue of a tenant, so that the pushes of a tenant under a config storm do not delay those
+// of the others.
+type tenantQueue struct {
+	queue *PushQueue
+	// idleSince is when the tenant was first seen without proxies nor pending pushes, or zero if it is active.
+	idleSince time.Time
+}
+
+// tenantPushQueues are the push queues of the tenants, created on first use. The queue of the default tenant is the
+// push queue of the server.
+//
+// All the queues share the PILOT_PUSH_THROTTLE semaphore of the server, so that the concurrency of the pushes is not
+// multiplied by the number of tenants. The worker of each queue only waits for the semaphore once it has a push to
+// send, and the waiters are served in turn, so the tenants get their share of the pushes however long the queue of
+// another is.
+type tenantPushQueues struct {
+	tenantOf  func(proxy *model.Proxy) string
+	semaphore chan struct{}
+
+	mu     sync.Mutex
+	queues map[string]*tenantQueue
+	// labels are the tenants reported with their own metric label.
+	labels map[string]struct{}
+	// stop is set once the pushes are started, after which the workers of the new queues are started on creation.
+	stop <-chan struct{}
+}
+
+func newTenantPushQueues(tenantOf func(proxy *model.Proxy) string, defaultQueue *PushQueue,
+	semaphore chan struct{}) *tenantPushQueues {
+	return &tenantPushQueues{
+		tenantOf:  tenantOf,
+		semaphore: semaphore,
+		queues:    map[string]*tenantQueue{model.DefaultTenant: {queue: defaultQueue}},
+		labels:    map[string]struct{}{model.DefaultTenant: {}},
+	}
+}
+
+// get returns the queue of the tenant, creating it if needed. The lock must be held.
+func (t *tenantPushQueues) get(tenant string) *PushQueue {
+	if q, f := t.queues[tenant]; f {
+		q.idleSince = time.Time{}
+		return q.queue
+	}
+	q := &tenantQueue{queue: NewPushQueue()}
+	t.queues[tenant] = q
+	if t.stop != nil {
+		go sendTenantPushes(t.stop, t.semaphore, q.queue)
+	}
+	return q.queue
+}
+
+// enqueue queues a push in the queue of the tenant, creating it if needed.
+func (t *tenantPushQueues) enqueue(tenant string, con *Connection, req *model.PushRequest) {
+	// The lock is held while enqueuing, so that the queue cannot be garbage collected in between.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(tenant).Enqueue(con, req)
+}
+
+// has returns whether the tenant has a queue.
+func (t *tenantPushQueues) has(tenant string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, f := t.queues[tenant]
+	return f
+}
+
+// label returns the metric label of the tenant.
+func (t *tenantPushQueues) label(tenant string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, f := t.labels[tenant]; f {
+		return tenant
+	}
+	if len(t.labels) >= maxTenantLabels {
+		return otherTenant
+	}
+	t.labels[tenant] = struct{}{}
+	return tenant
+}
+
+// start starts the workers sending the pushes of each queue.
+func (t *tenantPushQueues) start(stop <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stop = stop
+	for _, q := range t.queues {
+		go sendTenantPushes(stop, t.semaphore, q.queue)
+	}
+}
+
+// sendTenantPushes sends the pushes of the queue of a tenant. Unlike doSendPushes, the semaphore shared by the
+// tenants is only taken once a push is dequeued, so that the idle tenants do not hold it.
+func sendTenantPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQueue) {
+	for {
+		client, push, shuttingdown := queue.Dequeue()
+		if shuttingdown {
+			return
+		}
+		select {
+		case semaphore <- struct{}{}:
+		case <-stopCh:
+			queue.MarkDone(client)
+			return
+		}
+		sendPush(client, push, semaphore, queue)
+	}
+}
+
+// pending returns the number of pending pushes of each tenant.
+func (t *tenantPushQueues) pending() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]int, len(t.queues))
+	for tenant, q := range t.queues {
+		out[tenant] = q.queue.Pending()
+	}
+	return out
+}
+
+// gc shuts down and removes the queues of the tenants which have had no proxies, as counted by proxies, nor pending
+// pushes for tenantIdleTimeout. It returns the tenants removed.
+func (t *tenantPushQueues) gc(proxies map[string]int, now time.Time) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var removed []string
+	for tenant, q := range t.queues {
+		if tenant == model.DefaultTenant {
+			continue
+		}
+		if proxies[tenant] > 0 || q.queue.Pending() > 0 {
+			q.idleSince = time.Time{}
+			continue
+		}
+		if q.idleSince.IsZero() {
+			q.idleSince = now
+			continue
+		}
+		if now.Sub(q.idleSince) < tenantIdleTimeout {
+			continue
+		}
+		q.queue.ShutDown()
+		delete(t.queues, tenant)
+		removed = append(removed, tenant)
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+func (t *tenantPushQueues) shutDown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, q := range t.queues {
+		q.queue.ShutDown()
+	}
+}
+
+// enqueue queues a push to the connection, in the queue of its tenant if the tenants are isolated.
+func (s *DiscoveryServer) enqueue(con *Connection, req *model.PushRequest) {
+	if s.tenants == nil {
+		s.pushQueue.Enqueue(con, req)
+		return
+	}
+	tenant := model.DefaultTenant
+	if con.proxy != nil {
+		tenant = s.tenants.tenantOf(con.proxy)
+	}
+	tenantPushes.With(tenantTag.Value(s.tenants.label(tenant))).Increment()
+	s.tenants.enqueue(tenant, con, req)
+}
+
+// pendingPushes returns the number of pending pushes, of all the tenants.
+func (s *DiscoveryServer) pendingPushes() int {
+	if s.tenants == nil {
+		return s.pushQueue.Pending()
+	}
+	total := 0
+	for _, n := range s.tenants.pending() {
+		total += n
+	}
+	return total
+}
+
+// recordTenantMetrics records the pending pushes, connected proxies and cache entries of each tenant, and garbage
+// collects the queues and caches of the tenants gone.
+func (s *DiscoveryServer) recordTenantMetrics() {
+	if s.tenants == nil {
+		return
+	}
+	pending := map[string]int{}
+	for tenant, n := range s.tenants.pending() {
+		pending[s.tenants.label(tenant)] += n
+	}
+	proxies := map[string]int{}
+	for _, con := range s.Clients() {
+		proxies[s.tenants.tenantOf(con.proxy)]++
+	}
+	removed := s.tenants.gc(proxies, time.Now())
+	entries := map[string]int{}
+	if c, ok := s.Cache.(*model.TenantXdsCache); ok {
+		for _, tenant := range c.Tenants() {
+			if tenant != model.DefaultTenant && proxies[tenant] == 0 && !s.tenants.has(tenant) {
+				c.Remove(tenant)
+				continue
+			}
+			entries[s.tenants.label(tenant)] += len(c.ForTenant(tenant).Keys())
+		}
+	}
+	proxiesByLabel := map[string]int{}
+	for tenant, n := range proxies {
+		proxiesByLabel[s.tenants.label(tenant)] += n
+	}
+	// The gauges of the tenants removed are reset, as their labels cannot be unregistered.
+	for _, tenant := range removed {
+		label := s.tenants.label(tenant)
+		for _, m := range []map[string]int{pending, proxiesByLabel, entries} {
+			if _, f := m[label]; !f {
+				m[label] = 0
+			}
+		}
+	}
+	for label, n := range pending {
+		tenantPendingPushes.With(tenantTag.Value(label)).Record(float64(n))
+	}
+	for label, n := range proxiesByLabel {
+		tenantProxies.With(tenantTag.Value(label)).Record(float64(n))
+	}
+	for label, n := range entries {
+		tenantCacheEntries.With(tenantTag.Value(label)).Record(float64(n))
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestTenantFunc(t *testing.T) {
+	proxy := &model.Proxy{ConfigNamespace: "team-a", Metadata: &model.NodeMetadata{Labels: map[string]string{revisionLabel: "canary"}}}
+	bare := &model.Proxy{Metadata: &model.NodeMetadata{}}
+	if tenantFunc("") != nil || tenantFunc("cluster") != nil {
+		t.Fatalf("expected no isolation for empty or unknown scopes")
+	}
+	if got := tenantFunc(tenantByRevision)(proxy); got != "canary" {
+		t.Fatalf("expected the revision tenant, got %q", got)
+	}
+	if got := tenantFunc(tenantByNamespace)(proxy); got != "team-a" {
+		t.Fatalf("expected the namespace tenant, got %q", got)
+	}
+	if got := tenantFunc(tenantByRevision)(bare); got != model.DefaultTenant {
+		t.Fatalf("expected the default tenant, got %q", got)
+	}
+}
+
+func TestTenantXdsCache(t *testing.T) {
+	c := model.NewTenantXdsCache(tenantFunc(tenantByNamespace), model.NewLenientXdsCache)
+	a := &model.Proxy{ConfigNamespace: "a", Metadata: &model.NodeMetadata{}}
+	b := &model.Proxy{ConfigNamespace: "b", Metadata: &model.NodeMetadata{}}
+	ep := EndpointBuilder{clusterName: "outbound|1||foo.com", service: &model.Service{Hostname: "foo.com"}}
+
+	ca := model.CacheForProxy(c, a)
+	_, tok, _ := ca.Get(ep)
+	ca.Add(ep, tok, any1)
+	if _, _, f := model.CacheForProxy(c, b).Get(ep); f {
+		t.Fatalf("expected the entries of a tenant to be invisible to the others")
+	}
+	if got, _, _ := model.CacheForProxy(c, a).Get(ep); got != any1 {
+		t.Fatalf("expected the entry of the tenant, got %v", got)
+	}
+	if !reflect.DeepEqual(c.Tenants(), []string{"a", "b", model.DefaultTenant}) {
+		t.Fatalf("unexpected tenants %v", c.Tenants())
+	}
+	if !reflect.DeepEqual(c.Keys(), []string{"a/" + ep.Key()}) {
+		t.Fatalf("unexpected keys %v", c.Keys())
+	}
+
+	c.Clear(map[model.ConfigKey]struct{}{{Kind: gvk.ServiceEntry, Name: "foo.com"}: {}})
+	if _, _, f := model.CacheForProxy(c, a).Get(ep); f {
+		t.Fatalf("expected a config change to clear the entries of all the tenants")
+	}
+}
+
+func TestTenantPushQueues(t *testing.T) {
+	s := &DiscoveryServer{pushQueue: NewPushQueue()}
+	s.tenants = newTenantPushQueues(tenantFunc(tenantByNamespace), s.pushQueue, make(chan struct{}, 1))
+	defer s.tenants.shutDown()
+
+	storm := []*Connection{
+		{ConID: "a-1", proxy: &model.Proxy{ConfigNamespace: "a"}},
+		{ConID: "a-2", proxy: &model.Proxy{ConfigNamespace: "a"}},
+	}
+	quiet := &Connection{ConID: "b-1", proxy: &model.Proxy{ConfigNamespace: "b"}}
+	for _, con := range storm {
+		s.enqueue(con, &model.PushRequest{Full: true})
+	}
+	s.enqueue(quiet, &model.PushRequest{Full: true})
+
+	if got := s.tenants.pending(); got["a"] != 2 || got["b"] != 1 || got[model.DefaultTenant] != 0 {
+		t.Fatalf("unexpected pending pushes %v", got)
+	}
+	if got := s.pendingPushes(); got != 3 {
+		t.Fatalf("expected 3 pending pushes, got %d", got)
+	}
+	// The quiet tenant is served by its own queue, without waiting for the pushes of the other.
+	con, _, _ := s.tenants.queues["b"].queue.Dequeue()
+	if con != quiet {
+		t.Fatalf("expected the connection of the quiet tenant, got %v", con.ConID)
+	}
+}
+
+func TestTenantPushQueuesGC(t *testing.T) {
+	s := &DiscoveryServer{pushQueue: NewPushQueue()}
+	s.tenants = newTenantPushQueues(tenantFunc(tenantByNamespace), s.pushQueue, make(chan struct{}, 1))
+	defer s.tenants.shutDown()
+
+	s.enqueue(&Connection{ConID: "a-1", proxy: &model.Proxy{ConfigNamespace: "a"}}, &model.PushRequest{Full: true})
+	s.enqueue(&Connection{ConID: "b-1", proxy: &model.Proxy{ConfigNamespace: "b"}}, &model.PushRequest{Full: true})
+	_, _, _ = s.tenants.queues["b"].queue.Dequeue()
+
+	now := time.Now()
+	// a still has a pending push, and b only just became idle.
+	if removed := s.tenants.gc(map[string]int{}, now); len(removed) != 0 {
+		t.Fatalf("expected no queue to be removed, got %v", removed)
+	}
+	if removed := s.tenants.gc(map[string]int{}, now.Add(tenantIdleTimeout)); !reflect.DeepEqual(removed, []string{"b"}) {
+		t.Fatalf("expected the idle queue of b to be removed, got %v", removed)
+	}
+	if s.tenants.has("b") || !s.tenants.has("a") || !s.tenants.has(model.DefaultTenant) {
+		t.Fatalf("unexpected queues %v", s.tenants.pending())
+	}
+	// A tenant with proxies is kept, however long its queue is empty.
+	_, _, _ = s.tenants.queues["a"].queue.Dequeue()
+	proxies := map[string]int{"a": 1}
+	s.tenants.gc(proxies, now)
+	if removed := s.tenants.gc(proxies, now.Add(2*tenantIdleTimeout)); len(removed) != 0 {
+		t.Fatalf("expected no queue to be removed, got %v", removed)
+	}
+}
+
+func TestTenantLabels(t *testing.T) {
+	q := newTenantPushQueues(tenantFunc(tenantByNamespace), NewPushQueue(), make(chan struct{}, 1))
+	for i := 0; i < 2*maxTenantLabels; i++ {
+		q.label(strconv.Itoa(i))
+	}
+	if len(q.labels) != maxTenantLabels {
+		t.Fatalf("expected %d labels, got %d", maxTenantLabels, len(q.labels))
+	}
+	if got := q.label("new"); got != otherTenant {
+		t.Fatalf("expected the tenants beyond the limit to be reported as %q, got %q", otherTenant, got)
+	}
+	if got := q.label(model.DefaultTenant); got != model.DefaultTenant {
+		t.Fatalf("expected the default tenant to keep its label, got %q", got)
+	}
+}
+
+func TestTenantPushesShareThrottle(t *testing.T) {
+	q := newTenantPushQueues(tenantFunc(tenantByNamespace), NewPushQueue(), make(chan struct{}, 1))
+	stop := make(chan struct{})
+	defer close(stop)
+	defer q.shutDown()
+	q.start(stop)
+
+	events := make(chan *Event)
+	for _, ns := range []string{"a", "b", "c"} {
+		con := newConnection("", &fakeStream{})
+		con.ConID = ns
+		con.proxy = &model.Proxy{ConfigNamespace: ns}
+		go func(con *Connection) {
+			for {
+				select {
+				case ev := <-con.pushChannel:
+					events <- ev
+				case <-stop:
+					return
+				}
+			}
+		}(con)
+		q.enqueue(ns, con, &model.PushRequest{Full: true, Start: time.Now()})
+	}
+	// The throttle is shared: the pushes of the tenants are handed out one at a time.
+	for i := 0; i < 3; i++ {
+		var ev *Event
+		select {
+		case ev = <-events:
+		case <-time.After(time.Second):
+			t.Fatalf("expected push %d", i)
+		}
+		select {
+		case <-events:
+			t.Fatalf("expected a single push in flight")
+		case <-time.After(50 * time.Millisecond):
+		}
+		ev.done()
+	}
+}
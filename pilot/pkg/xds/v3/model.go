@@ -30,14 +30,39 @@ const (
 	RouteType                  = resource.RouteType
 	SecretType                 = resource.SecretType
 	ExtensionConfigurationType = resource.ExtensionConfigType
+	ScopedRouteType            = envoyTypePrefix + "config.route.v3.ScopedRouteConfiguration"
+	VirtualHostType            = envoyTypePrefix + "config.route.v3.VirtualHost"
+	LbEndpointType             = envoyTypePrefix + "config.endpoint.v3.LbEndpoint"
 
 	NameTableType   = apiTypePrefix + "istio.networking.nds.v1.NameTable"
 	HealthInfoType  = apiTypePrefix + "istio.v1.HealthInformation"
 	ProxyConfigType = apiTypePrefix + "istio.mesh.v1alpha1.ProxyConfig"
+	// DrainingType is sent by the agent when the workload starts terminating, so its endpoints can be removed.
+	DrainingType = apiTypePrefix + "istio.v1.Draining"
+	// WatermarkType identifies the PushContext used for the latest push, to track data plane config freshness.
+	WatermarkType = "istio.io/watermark"
+	// ResumptionTokenType carries the token a proxy presents on reconnect to skip resending unchanged config.
+	ResumptionTokenType = "istio.io/resumption-token"
 	// DebugType requests debug info from istio, a secured implementation for istio debug interface.
 	DebugType     = "istio.io/debug"
 	BootstrapType = apiTypePrefix + "envoy.config.bootstrap.v3.Bootstrap"
 
+	// ResumptionTokenHeader is the gRPC metadata key used by the agent to present the last resumption token
+	// it received when opening a new stream.
+	ResumptionTokenHeader = "x-istio-resumption-token"
+
+	// RetryPushbackTrailer is the gRPC trailer istiod sets on the streams it closes, with the number of
+	// milliseconds the client should wait before reconnecting.
+	RetryPushbackTrailer = "grpc-retry-pushback-ms"
+
+	// AffinityHeader is the gRPC metadata key set by the clients able to follow an affinity hint, connecting to
+	// the istiod replica named by the AffinityTrailer.
+	AffinityHeader = "x-istio-affinity"
+
+	// AffinityTrailer is the gRPC trailer istiod sets on the streams it rejects because another replica serves
+	// the proxy, with the address of that replica.
+	AffinityTrailer = "istio-affinity-address"
+
 	// nolint
 	HttpProtocolOptionsType = "envoy.extensions.upstreams.http.v3.HttpProtocolOptions"
 )
@@ -51,6 +76,12 @@ func GetShortType(typeURL string) string {
 		return "LDS"
 	case RouteType:
 		return "RDS"
+	case ScopedRouteType:
+		return "SRDS"
+	case VirtualHostType:
+		return "VHDS"
+	case LbEndpointType:
+		return "LEDS"
 	case EndpointType:
 		return "EDS"
 	case SecretType:
@@ -61,6 +92,8 @@ func GetShortType(typeURL string) string {
 		return "PCDS"
 	case ExtensionConfigurationType:
 		return "ECDS"
+	case WatermarkType:
+		return "WM"
 	default:
 		return typeURL
 	}
@@ -75,6 +108,12 @@ func GetMetricType(typeURL string) string {
 		return "lds"
 	case RouteType:
 		return "rds"
+	case ScopedRouteType:
+		return "srds"
+	case VirtualHostType:
+		return "vhds"
+	case LbEndpointType:
+		return "leds"
 	case EndpointType:
 		return "eds"
 	case SecretType:
@@ -87,6 +126,8 @@ func GetMetricType(typeURL string) string {
 		return "ecds"
 	case BootstrapType:
 		return "bds"
+	case WatermarkType:
+		return "watermark"
 	default:
 		return typeURL
 	}
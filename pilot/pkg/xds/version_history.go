@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sync"
+	"time"
+)
+
+// ledgerVersion is a config ledger version, and the time the push context for it was created.
+type ledgerVersion struct {
+	version string
+	created time.Time
+}
+
+// versionHistory tracks the most recent config ledger versions, in order, so that the version acked by a proxy
+// can be compared against the latest one.
+type versionHistory struct {
+	mu       sync.RWMutex
+	versions []ledgerVersion
+	max      int
+}
+
+func newVersionHistory(max int) *versionHistory {
+	return &versionHistory{max: max}
+}
+
+// add records a new ledger version. Consecutive pushes for the same version are ignored.
+func (h *versionHistory) add(version string, created time.Time) {
+	version = truncateVersion(version)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := len(h.versions); n > 0 && h.versions[n-1].version == version {
+		return
+	}
+	h.versions = append(h.versions, ledgerVersion{version: version, created: created})
+	if len(h.versions) > h.max {
+		h.versions = h.versions[len(h.versions)-h.max:]
+	}
+}
+
+// latest returns the most recent ledger version, or an empty string if none has been recorded.
+func (h *versionHistory) latest() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.versions) == 0 {
+		return ""
+	}
+	return h.versions[len(h.versions)-1].version
+}
+
+// staleness returns how many versions the given version is behind the latest, and for how long a newer
+// version has been available. Versions that are too old to be tracked are reported as behind by the
+// full length of the history, and stale since the oldest tracked version.
+func (h *versionHistory) staleness(version string, now time.Time) (int, time.Duration) {
+	version = truncateVersion(version)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n := len(h.versions)
+	if n == 0 {
+		return 0, 0
+	}
+	idx := -1
+	for i := n - 1; i >= 0; i-- {
+		if h.versions[i].version == version {
+			idx = i
+			break
+		}
+	}
+	if idx == n-1 {
+		return 0, 0
+	}
+	// The proxy has been stale since the version following the one it acked was created.
+	return n - 1 - idx, now.Sub(h.versions[idx+1].created)
+}
+
+func truncateVersion(version string) string {
+	if len(version) > VersionLen {
+		return version[:VersionLen]
+	}
+	return version
+}
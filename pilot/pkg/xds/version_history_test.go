@@ -0,0 +1,57 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVersionHistory(t *testing.T) {
+	h := newVersionHistory(3)
+	start := time.Unix(1000, 0)
+	h.add("v1", start)
+	h.add("v1", start.Add(time.Second))
+	h.add("v2", start.Add(10*time.Second))
+	h.add("v3", start.Add(20*time.Second))
+	now := start.Add(30 * time.Second)
+
+	cases := []struct {
+		version  string
+		behind   int
+		staleFor time.Duration
+	}{
+		{"v3", 0, 0},
+		{"v2", 1, 10 * time.Second},
+		{"v1", 2, 20 * time.Second},
+		{"unknown", 3, 30 * time.Second},
+	}
+	for _, tt := range cases {
+		t.Run(tt.version, func(t *testing.T) {
+			behind, staleFor := h.staleness(tt.version, now)
+			if behind != tt.behind || staleFor != tt.staleFor {
+				t.Fatalf("expected (%v, %v), got (%v, %v)", tt.behind, tt.staleFor, behind, staleFor)
+			}
+		})
+	}
+
+	h.add("v4", start.Add(25*time.Second))
+	if h.latest() != "v4" {
+		t.Fatalf("expected latest v4, got %v", h.latest())
+	}
+	if behind, _ := h.staleness("v1", now); behind != 3 {
+		t.Fatalf("expected evicted version to be 3 behind, got %v", behind)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"reflect"
+	"testing"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestDeltaVHDS(t *testing.T) {
+	old := features.VirtualHostDiscoveryServiceThreshold
+	features.VirtualHostDiscoveryServiceThreshold = 1
+	defer func() { features.VirtualHostDiscoveryServiceThreshold = old }()
+
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: vhds
+  namespace: default
+spec:
+  hosts:
+  - a.example.com
+  - b.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+`})
+	ads := s.ConnectDeltaADS().WithType(v3.VirtualHostType)
+	res := ads.RequestResponseAck(&discovery.DeltaDiscoveryRequest{
+		ResourceNamesSubscribe: []string{"80/a.example.com", "80/missing.example.com"},
+	})
+
+	got := map[string][]string{}
+	for _, r := range res.Resources {
+		got[r.Name] = r.Aliases
+	}
+	want := map[string][]string{
+		"80/a.example.com:80":    {"80/a.example.com"},
+		"80/missing.example.com": {"80/missing.example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got virtual hosts %v, want %v", got, want)
+	}
+	for _, r := range res.Resources {
+		if (r.Resource == nil) != (r.Name == "80/missing.example.com") {
+			t.Fatalf("unexpected resource body for %v", r.Name)
+		}
+	}
+	if len(res.RemovedResources) != 0 {
+		t.Fatalf("unexpected removed resources %v", res.RemovedResources)
+	}
+}
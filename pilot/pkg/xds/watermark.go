@@ -0,0 +1,53 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// WatermarkGenerator generates a small resource identifying the PushContext used for a push. The agent
+// exposes it as a metric, allowing external monitoring to verify data plane config freshness end to end.
+type WatermarkGenerator struct {
+	Server *DiscoveryServer
+}
+
+var _ model.XdsResourceGenerator = &WatermarkGenerator{}
+
+// Generate returns a Struct with the PushContext version and the time the push was triggered.
+func (w WatermarkGenerator) Generate(proxy *model.Proxy, push *model.PushContext, _ *model.WatchedResource,
+	req *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	// The PushContext only changes on full pushes.
+	if req != nil && !req.Full {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	start := time.Now()
+	if req != nil && !req.Start.IsZero() {
+		start = req.Start
+	}
+	wm := &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"version":   {Kind: &structpb.Value_StringValue{StringValue: push.PushVersion}},
+			"timestamp": {Kind: &structpb.Value_NumberValue{NumberValue: float64(start.UnixNano()) / float64(time.Second)}},
+		},
+	}
+	return model.Resources{&discovery.Resource{Resource: util.MessageToAny(wm)}}, model.DefaultXdsLogDetails, nil
+}
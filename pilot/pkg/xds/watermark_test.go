@@ -0,0 +1,46 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestWatermark(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	ads := s.ConnectADS().WithType(v3.WatermarkType)
+	res := ads.RequestResponseAck(t, nil)
+	if len(res.Resources) != 1 {
+		t.Fatalf("expected 1 watermark resource, got %d", len(res.Resources))
+	}
+
+	var wm structpb.Struct
+	// nolint: staticcheck
+	if err := ptypes.UnmarshalAny(res.Resources[0], &wm); err != nil {
+		t.Fatalf("failed to unmarshal watermark: %v", err)
+	}
+	if got, want := wm.Fields["version"].GetStringValue(), s.PushContext().PushVersion; got != want {
+		t.Fatalf("expected version %q, got %q", want, got)
+	}
+	if wm.Fields["timestamp"].GetNumberValue() <= 0 {
+		t.Fatalf("expected a timestamp, got %v", wm.Fields["timestamp"])
+	}
+}
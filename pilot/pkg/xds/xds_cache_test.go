@@ -216,4 +216,15 @@ func TestXdsCache(t *testing.T) {
 			t.Fatalf("expected no keys, got: %v", c.Keys())
 		}
 	})
+
+	t.Run("stats", func(t *testing.T) {
+		c := model.NewLenientXdsCache()
+		addWithToken(c, ep1, any1)
+		c.Get(ep1)
+		c.Get(ep2)
+		want := map[string]model.CacheStats{"eds": {Hits: 1, Misses: 2}}
+		if got := c.Stats(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected stats: %v, want %v", got, want)
+		}
+	})
 }
@@ -16,12 +16,15 @@ package xds
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
@@ -60,6 +63,29 @@ func init() {
 	controlPlane = &corev3.ControlPlane{Identifier: string(byVersion)}
 }
 
+// RegisterGenerator registers the generator of the resources of a type URL, so that extensions can serve new types
+// without changing the built-in generators. The key follows the Generators conventions: a type URL is served by gen
+// for all the clients, while "<generator>/<type URL>" only serves the clients with this Generator metadata, taking
+// precedence over the former. Registering a key that is already registered, including a built-in one, replaces its
+// generator: the last registration wins.
+// Generators must be registered before Start, as they are looked up without locking once connections are accepted.
+func (s *DiscoveryServer) RegisterGenerator(typeURL string, gen model.XdsResourceGenerator) error {
+	if typeURL == "" {
+		return fmt.Errorf("empty type URL")
+	}
+	if gen == nil {
+		return fmt.Errorf("nil generator for %v", typeURL)
+	}
+	if s.started.Load() {
+		return fmt.Errorf("cannot register the generator for %v after the server is started", typeURL)
+	}
+	if _, f := s.Generators[typeURL]; f {
+		log.Infof("replacing the generator for %v", typeURL)
+	}
+	s.Generators[typeURL] = gen
+	return nil
+}
+
 func (s *DiscoveryServer) findGenerator(typeURL string, con *Connection) model.XdsResourceGenerator {
 	if g, f := s.Generators[con.proxy.Metadata.Generator+"/"+typeURL]; f {
 		return g
@@ -83,6 +109,26 @@ func (s *DiscoveryServer) findGenerator(typeURL string, con *Connection) model.X
 	return g
 }
 
+// stableOrderTypes are the types whose resources are sent sorted by name. Envoy does not depend on the order of
+// these resources, while a stable order makes the responses identical across pushes and Istiod replicas, for the
+// hashes of the responses, the caches and the tools comparing configs.
+var stableOrderTypes = map[string]struct{}{
+	v3.ClusterType:  {},
+	v3.ListenerType: {},
+	v3.RouteType:    {},
+	v3.EndpointType: {},
+}
+
+// sortResources sorts the resources of the type by name in place, if their order does not matter.
+func sortResources(typeURL string, res model.Resources) {
+	if _, f := stableOrderTypes[typeURL]; !f {
+		return
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+}
+
 // Push an XDS resource for the given connection. Configuration will be generated
 // based on the passed in generator. Based on the updates field, generators may
 // choose to send partial or even no response if there are no changes.
@@ -98,7 +144,11 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 
 	t0 := time.Now()
 
+	s.Failpoints.delay(FailpointSlowGenerator, con.proxy, w.TypeUrl)
 	res, logdata, err := gen.Generate(con.proxy, push, w, req)
+	if err != nil {
+		s.reportPushError(con, w.TypeUrl, err)
+	}
 	if err != nil || res == nil {
 		// If we have nothing to send, report that we got an ACK for this version.
 		if s.StatusReporter != nil {
@@ -107,6 +157,45 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 		return err
 	}
 	defer func() { recordPushTime(w.TypeUrl, time.Since(t0)) }()
+	sortResources(w.TypeUrl, res)
+	if !logdata.Incremental {
+		res = configuredResourceLimits.apply(con, push, w.TypeUrl, res)
+	}
+
+	hash := ""
+	if (features.EnableXDSResumption || features.SuppressUnchangedPushes) && !logdata.Incremental {
+		hash = hashResources(res)
+		if features.EnableXDSResumption && con.resume(w, hash) {
+			// The proxy reconnected with identical config; no need to send it again.
+			con.proxy.Lock()
+			w.VersionSent = w.LastRequest.VersionInfo
+			w.VersionAcked = w.LastRequest.VersionInfo
+			w.ResourcesHash = hash
+			w.AckedHash = hash
+			con.proxy.Unlock()
+			xdsResumedResponses.With(typeTag.Value(v3.GetMetricType(w.TypeUrl))).Increment()
+			if s.StatusReporter != nil {
+				s.StatusReporter.RegisterEvent(con.ConID, w.TypeUrl, push.LedgerVersion)
+			}
+			log.Infof("%s: RESUME for node:%s resources:%d version:%s", v3.GetShortType(w.TypeUrl), con.proxy.ID, len(res), w.VersionSent)
+			return nil
+		}
+		if features.SuppressUnchangedPushes && unchangedPush(w, hash, req) {
+			xdsSuppressedPushes.With(typeTag.Value(v3.GetMetricType(w.TypeUrl))).Increment()
+			log.Debugf("%s: SUPPRESS for node:%s, the resources did not change", v3.GetShortType(w.TypeUrl), con.proxy.ID)
+			return nil
+		}
+	}
+
+	var edsDigests map[string]loadAssignmentDigest
+	trackEdsDiff := features.EnableEDSDiffSuppression && w.TypeUrl == v3.EndpointType && con.edsDiff != nil
+	if trackEdsDiff {
+		res, edsDigests = con.edsDiff.filter(res, logdata.Incremental)
+		if logdata.Incremental && len(res) == 0 {
+			log.Debugf("EDS: SUPPRESS for node:%s, the load assignments did not change", con.proxy.ID)
+			return nil
+		}
+	}
 
 	resp := &discovery.DiscoveryResponse{
 		ControlPlane: ControlPlane(),
@@ -118,11 +207,36 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 
 	configSize := ResourceSize(res)
 	configSizeBytes.With(typeTag.Value(w.TypeUrl)).Record(float64(configSize))
+	generated := time.Since(t0)
+	s.pushCosts.record(con.proxy.ConfigNamespace, s.revision(), req, generated, configSize)
 
-	if err := con.send(resp); err != nil {
+	restoreNonce := s.Failpoints.corruptNonce(con, w.TypeUrl, &resp.Nonce)
+	sendStart := time.Now()
+	err = con.send(resp)
+	restoreNonce()
+	s.recordPushLatency(con, w.TypeUrl, generated, time.Since(sendStart), configSize)
+	if err != nil {
 		recordSendError(w.TypeUrl, con.ConID, err)
+		s.reportPushError(con, w.TypeUrl, err)
 		return err
 	}
+	if trackEdsDiff {
+		con.edsDiff.sent(resp.Nonce, edsDigests, logdata.Incremental)
+	}
+	if con.ackedConfig != nil {
+		con.ackedConfig.sent(w.TypeUrl, resp.Nonce, res, logdata.Incremental)
+	}
+	if features.EnableXDSResumption || features.SuppressUnchangedPushes {
+		// An incremental response has no hash, and invalidates the one of the last complete response.
+		con.proxy.Lock()
+		w.ResourcesHash = hash
+		con.proxy.Unlock()
+	}
+	if !logdata.Incremental {
+		con.recordConfigSize(w.TypeUrl, configSize)
+	}
+	s.debugEvents.publishPush(con, w.TypeUrl, resp.VersionInfo, resp.Nonce, len(res), logdata.Incremental, req)
+	con.recordPush(w.TypeUrl, req)
 
 	ptype := "PUSH"
 	info := ""
@@ -145,19 +259,39 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 			// Add additional information to logs when debug mode enabled.
 			debug = " nonce:" + resp.Nonce + " version:" + resp.VersionInfo
 		}
-		log.Infof("%s: %s for node:%s resources:%d size:%v%s%s", v3.GetShortType(w.TypeUrl), ptype, con.proxy.ID, len(res),
-			util.ByteCount(ResourceSize(res)), info, debug)
+		log.Infof("%s: %s for node:%s resources:%d size:%v%s%s%s", v3.GetShortType(w.TypeUrl), ptype, con.proxy.ID, len(res),
+			util.ByteCount(ResourceSize(res)), info, pushReasons(req), debug)
 	}
 
 	return nil
 }
 
+// configSize is the marshaled size of the two most recent complete responses of a type.
+type configSize struct {
+	current  int
+	previous int
+	pushes   int
+}
+
+func (conn *Connection) recordConfigSize(typeURL string, size int) {
+	conn.proxy.Lock()
+	defer conn.proxy.Unlock()
+	cs, f := conn.configSizes[typeURL]
+	if !f {
+		cs = &configSize{}
+		conn.configSizes[typeURL] = cs
+	}
+	cs.previous = cs.current
+	cs.current = size
+	cs.pushes++
+}
+
 func ResourceSize(r model.Resources) int {
 	// Approximate size by looking at the Any marshaled size. This avoids high cost
 	// proto.Size, at the expense of slightly under counting.
 	size := 0
 	for _, r := range r {
-		size += len(r.Resource.Value)
+		size += len(r.Resource.GetValue())
 	}
 	return size
 }
@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+const stableOrderConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: zeta
+  namespace: default
+spec:
+  hosts:
+  - zeta.example.com
+  addresses:
+  - 240.0.0.3
+  ports:
+  - number: 9090
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.3
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: alpha
+  namespace: default
+spec:
+  hosts:
+  - alpha.example.com
+  addresses:
+  - 240.0.0.1
+  ports:
+  - number: 8080
+    name: http
+    protocol: HTTP
+  - number: 3306
+    name: tcp
+    protocol: TCP
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: mid
+  namespace: default
+spec:
+  hosts:
+  - mid.example.com
+  addresses:
+  - 240.0.0.2
+  ports:
+  - number: 7070
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.2
+`
+
+// responseNames returns the names of the resources of a response, in order.
+func responseNames(t *testing.T, res *discovery.DiscoveryResponse) []string {
+	t.Helper()
+	names := make([]string, 0, len(res.Resources))
+	for _, r := range res.Resources {
+		msg, err := r.UnmarshalNew()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch m := msg.(type) {
+		case *endpoint.ClusterLoadAssignment:
+			names = append(names, m.ClusterName)
+		case interface{ GetName() string }:
+			names = append(names, m.GetName())
+		default:
+			t.Fatalf("unexpected resource %T", msg)
+		}
+	}
+	return names
+}
+
+func TestStableResourceOrder(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: stableOrderConfig})
+
+	for _, typeURL := range []string{v3.ClusterType, v3.ListenerType} {
+		t.Run(v3.GetShortType(typeURL), func(t *testing.T) {
+			ads := s.ConnectADS().WithType(typeURL)
+			first := ads.RequestResponseAck(t, nil)
+			names := responseNames(t, first)
+			if len(names) < 3 || !sort.StringsAreSorted(names) {
+				t.Fatalf("expected the resources to be sorted by name, got %v", names)
+			}
+
+			// Every push sends the resources in the same order.
+			xds.AdsPushAll(s.Discovery)
+			second := ads.ExpectResponse(t)
+			if !reflect.DeepEqual(responseNames(t, second), names) {
+				t.Fatalf("expected the same order on every push, got %v then %v", names, responseNames(t, second))
+			}
+			for i := range first.Resources {
+				if !reflect.DeepEqual(first.Resources[i].Value, second.Resources[i].Value) {
+					t.Fatalf("expected identical resources on every push, %s differs", names[i])
+				}
+			}
+		})
+	}
+
+	t.Run("routes", func(t *testing.T) {
+		ads := s.ConnectADS().WithType(v3.RouteType)
+		res := ads.RequestResponseAck(t, &discovery.DiscoveryRequest{ResourceNames: []string{"9090", "8080", "7070"}})
+		if names := responseNames(t, res); !reflect.DeepEqual(names, []string{"7070", "8080", "9090"}) {
+			t.Fatalf("expected the routes to be sorted by name, got %v", names)
+		}
+	})
+
+	t.Run("endpoints", func(t *testing.T) {
+		ads := s.ConnectADS().WithType(v3.EndpointType)
+		requested := []string{
+			"outbound|9090||zeta.example.com",
+			"outbound|8080||alpha.example.com",
+			"outbound|7070||mid.example.com",
+		}
+		res := ads.RequestResponseAck(t, &discovery.DiscoveryRequest{ResourceNames: requested})
+		if names := responseNames(t, res); len(names) != len(requested) || !sort.StringsAreSorted(names) {
+			t.Fatalf("expected the load assignments to be sorted by name, got %v", names)
+		}
+	})
+}
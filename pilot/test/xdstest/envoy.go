@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xdstest
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	testenvoy "istio.io/istio/pkg/test/envoy"
+	"istio.io/pkg/env"
+)
+
+var (
+	envoyValidate = env.RegisterBoolVar("XDSTEST_ENVOY_VALIDATE", false,
+		"If enabled, ValidateWithEnvoy checks the generated configuration with envoy --mode validate. "+
+			"The Envoy binary is read from ENVOY_PATH, or searched for under LOCAL_OUT.").Get()
+	envoyPath = env.RegisterStringVar("ENVOY_PATH", "", "Specifies the path to an Envoy binary.").Get()
+)
+
+// EnvoyConfig is the configuration of a proxy, as generated by istiod.
+type EnvoyConfig struct {
+	// Bootstrap is the bootstrap configuration of the proxy. If unset, a minimal bootstrap is used.
+	Bootstrap *bootstrap.Bootstrap
+	Listeners []*listener.Listener
+	Clusters  []*cluster.Cluster
+	Routes    []*route.RouteConfiguration
+	Endpoints []*endpoint.ClusterLoadAssignment
+}
+
+// ValidateWithEnvoy asserts the configuration is accepted by Envoy, by running envoy --mode validate on it.
+// This catches invalid configurations the proto validation of ValidateListeners and friends cannot, such as
+// unknown extensions or conflicting fields, which would otherwise only be caught as NACKs on a real cluster.
+//
+// As Envoy does not validate configurations received over xDS in validate mode, the resources are made static:
+// routes are inlined into the listeners referring to them, and EDS clusters are given their endpoints.
+//
+// Running Envoy is slow, so this is skipped unless XDSTEST_ENVOY_VALIDATE is set, which is done in CI.
+func ValidateWithEnvoy(t testing.TB, cfg EnvoyConfig) {
+	t.Helper()
+	if !envoyValidate {
+		t.Skip("XDSTEST_ENVOY_VALIDATE is not set")
+	}
+	binary := envoyPath
+	if binary == "" {
+		b, err := testenvoy.FindBinary()
+		if err != nil {
+			t.Fatalf("failed to find Envoy binary: %v", err)
+		}
+		binary = b
+	}
+
+	b := staticBootstrap(t, cfg)
+	js, err := (&jsonpb.Marshaler{OrigName: true}).MarshalToString(b)
+	if err != nil {
+		t.Fatalf("failed to marshal bootstrap: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "envoy-validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "envoy.json")
+	if err := ioutil.WriteFile(path, []byte(js), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// #nosec G204
+	out, err := exec.Command(binary, "--mode", "validate", "--config-path", path,
+		"--log-level", "warn", "--disable-hot-restart").CombinedOutput()
+	if err != nil {
+		t.Errorf("configuration rejected by Envoy: %v\n%s\nconfiguration:\n%s", err, out, js)
+	}
+}
+
+// staticBootstrap returns the bootstrap of a config, with its listeners and clusters added as static resources.
+func staticBootstrap(t testing.TB, cfg EnvoyConfig) *bootstrap.Bootstrap {
+	b := minimalBootstrap()
+	if cfg.Bootstrap != nil {
+		b = proto.Clone(cfg.Bootstrap).(*bootstrap.Bootstrap)
+	}
+	if b.StaticResources == nil {
+		b.StaticResources = &bootstrap.Bootstrap_StaticResources{}
+	}
+
+	routes := ExtractRouteConfigurations(cfg.Routes)
+	for _, l := range cfg.Listeners {
+		b.StaticResources.Listeners = append(b.StaticResources.Listeners, inlineRoutes(t, l, routes))
+	}
+
+	endpoints := map[string]*endpoint.ClusterLoadAssignment{}
+	for _, cla := range cfg.Endpoints {
+		endpoints[cla.ClusterName] = cla
+	}
+	for _, c := range cfg.Clusters {
+		b.StaticResources.Clusters = append(b.StaticResources.Clusters, staticCluster(c, endpoints))
+	}
+	return b
+}
+
+// minimalBootstrap returns a bootstrap with an ADS server, which the generated configuration refers to for secrets.
+func minimalBootstrap() *bootstrap.Bootstrap {
+	return &bootstrap.Bootstrap{
+		Node: &core.Node{Id: "sidecar~1.1.1.1~validate.default~default.svc.cluster.local", Cluster: "validate.default"},
+		DynamicResources: &bootstrap.Bootstrap_DynamicResources{
+			AdsConfig: &core.ApiConfigSource{
+				ApiType:             core.ApiConfigSource_GRPC,
+				TransportApiVersion: core.ApiVersion_V3,
+				GrpcServices: []*core.GrpcService{{
+					TargetSpecifier: &core.GrpcService_EnvoyGrpc_{EnvoyGrpc: &core.GrpcService_EnvoyGrpc{ClusterName: "xds-grpc"}},
+				}},
+			},
+		},
+		StaticResources: &bootstrap.Bootstrap_StaticResources{
+			Clusters: []*cluster.Cluster{{
+				Name:                 "xds-grpc",
+				ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STATIC},
+				Http2ProtocolOptions: &core.Http2ProtocolOptions{},
+				LoadAssignment: &endpoint.ClusterLoadAssignment{
+					ClusterName: "xds-grpc",
+					Endpoints: []*endpoint.LocalityLbEndpoints{{
+						LbEndpoints: []*endpoint.LbEndpoint{{
+							HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{
+								Address: util.BuildAddress("127.0.0.1", 15010),
+							}},
+						}},
+					}},
+				},
+			}},
+		},
+	}
+}
+
+// inlineRoutes returns a copy of a listener, with the routes its HTTP connection managers fetch over RDS inlined.
+func inlineRoutes(t testing.TB, l *listener.Listener, routes map[string]*route.RouteConfiguration) *listener.Listener {
+	l = proto.Clone(l).(*listener.Listener)
+	chains := l.FilterChains
+	if l.DefaultFilterChain != nil {
+		chains = append(chains, l.DefaultFilterChain)
+	}
+	for _, fc := range chains {
+		for _, f := range fc.Filters {
+			if f.Name != wellknown.HTTPConnectionManager || f.GetTypedConfig() == nil {
+				continue
+			}
+			h := &hcm.HttpConnectionManager{}
+			if err := f.GetTypedConfig().UnmarshalTo(h); err != nil {
+				t.Fatalf("failed to unmarshal hcm: %v", err)
+			}
+			rds := h.GetRds()
+			if rds == nil {
+				continue
+			}
+			rc := routes[rds.RouteConfigName]
+			if rc == nil {
+				// Envoy would wait for the route over RDS; validate the listener with an empty route instead.
+				rc = &route.RouteConfiguration{Name: rds.RouteConfigName}
+			}
+			h.RouteSpecifier = &hcm.HttpConnectionManager_RouteConfig{RouteConfig: rc}
+			f.ConfigType = &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(h)}
+		}
+	}
+	return l
+}
+
+// staticCluster returns a copy of a cluster, with its endpoints inlined if it is an EDS cluster.
+func staticCluster(c *cluster.Cluster, endpoints map[string]*endpoint.ClusterLoadAssignment) *cluster.Cluster {
+	if c.GetType() != cluster.Cluster_EDS {
+		return c
+	}
+	c = proto.Clone(c).(*cluster.Cluster)
+	c.ClusterDiscoveryType = &cluster.Cluster_Type{Type: cluster.Cluster_STATIC}
+	c.EdsClusterConfig = nil
+	c.LoadAssignment = endpoints[c.Name]
+	if c.LoadAssignment == nil {
+		c.LoadAssignment = &endpoint.ClusterLoadAssignment{ClusterName: c.Name}
+	}
+	return c
+}
@@ -113,7 +113,22 @@ type Config struct {
 	// TODO: mirror Generator, allow adding handler per type
 	ResponseHandler ResponseHandler
 
+	// ErrorHandler, if set, is called with the error closing the stream or failing to reopen it.
+	ErrorHandler func(err error)
+
 	GrpcOpts []grpc.DialOption
+
+	// Delta uses the incremental xDS protocol instead of state of the world. Responses are merged into the
+	// resources previously received, so the handlers and Received still see the complete state of each type.
+	Delta bool
+
+	// CacheDir is a directory where the received clusters, endpoints, listeners and routes are persisted.
+	// If set, New loads the resources persisted by a previous client before connecting, so they can be
+	// served while the XDS server is unreachable.
+	CacheDir string
+
+	// CacheFormat is the format of the persisted resources, CacheFormatProto (the default) or CacheFormatJSON.
+	CacheFormat string
 }
 
 // ADSC implements a basic client for ADS, for use in stress tests and tools
@@ -122,6 +137,8 @@ type ADSC struct {
 	// Stream is the GRPC connection stream, allowing direct GRPC send operations.
 	// Set after Dial is called.
 	stream discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+	// deltaStream is the incremental GRPC stream, used instead of stream in delta mode.
+	deltaStream discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesClient
 	// xds client used to create a stream
 	client discovery.AggregatedDiscoveryServiceClient
 	conn   *grpc.ClientConn
@@ -157,6 +174,12 @@ type ADSC struct {
 	// All received endpoints, keyed by cluster name
 	eds map[string]*endpoint.ClusterLoadAssignment
 
+	// All received virtual hosts, keyed by virtual host name
+	virtualHosts map[string]*route.VirtualHost
+
+	// The virtual hosts requested with VHDS, as <route configuration>/<host>
+	vhdsResources []string
+
 	// Metadata has the node metadata to send to pilot.
 	// If nil, the defaults will be used.
 	Metadata *pstruct.Struct
@@ -197,6 +220,19 @@ type ADSC struct {
 	sync     map[string]time.Time
 	syncCh   chan string
 	Locality *core.Locality
+
+	// deltaStates holds the subscriptions and the received resources of each type in delta mode,
+	// keyed by type URL. It is kept across reconnects, to resume with the known resource versions.
+	deltaStates map[string]*deltaState
+	deltaMutex  sync.Mutex
+}
+
+// deltaState is the incremental xDS bookkeeping of a type.
+type deltaState struct {
+	// subscribed are the resource names subscribed to. Empty means all the resources of the type.
+	subscribed map[string]struct{}
+	// resources are the received resources, keyed by name.
+	resources map[string]*discovery.Resource
 }
 
 type ResponseHandler interface {
@@ -287,6 +323,13 @@ func New(discoveryAddr string, opts *Config) (*ADSC, error) {
 	adsc.nodeID = fmt.Sprintf("%s~%s~%s.%s~%s.svc.cluster.local", opts.NodeType, opts.IP,
 		opts.Workload, opts.Namespace, opts.Namespace)
 
+	if opts.CacheDir != "" {
+		// A missing or corrupted cache only delays the config until the server responds.
+		if err := adsc.loadCache(); err != nil {
+			adscLog.Warnf("Failed to load the cached config: %v", err)
+		}
+	}
+
 	if err := adsc.Dial(); err != nil {
 		return nil, err
 	}
@@ -402,6 +445,9 @@ func (a *ADSC) Close() {
 func (a *ADSC) Run() error {
 	var err error
 	a.client = discovery.NewAggregatedDiscoveryServiceClient(a.conn)
+	if a.cfg.Delta {
+		return a.runDelta()
+	}
 	a.stream, err = a.client.StreamAggregatedResources(context.Background())
 	if err != nil {
 		return err
@@ -451,125 +497,160 @@ func (a *ADSC) reconnect() {
 	if err == nil {
 		a.cfg.BackoffPolicy.Reset()
 	} else {
+		if a.cfg.ErrorHandler != nil {
+			a.cfg.ErrorHandler(err)
+		}
 		time.AfterFunc(a.cfg.BackoffPolicy.NextBackOff(), a.reconnect)
 	}
 }
 
 func (a *ADSC) handleRecv() {
 	for {
-		var err error
 		msg, err := a.stream.Recv()
 		if err != nil {
-			a.RecvWg.Done()
-			adscLog.Infof("Connection closed for node %v with err: %v", a.nodeID, err)
-			a.errChan <- err
-			// if 'reconnect' enabled - schedule a new Run
-			if a.cfg.BackoffPolicy != nil {
-				time.AfterFunc(a.cfg.BackoffPolicy.NextBackOff(), a.reconnect)
-			} else {
-				a.Close()
-				a.WaitClear()
-				a.Updates <- ""
-				a.XDSUpdates <- nil
-				close(a.errChan)
-			}
+			a.handleStreamClosed(err)
 			return
 		}
 
-		// Group-value-kind - used for high level api generator.
-		gvk := strings.SplitN(msg.TypeUrl, "/", 3)
+		if !a.handleResponse(msg) {
+			continue
+		}
+
+		a.mutex.Lock()
+		a.markSynced(msg.TypeUrl)
+		a.Received[msg.TypeUrl] = msg
+		a.ack(msg)
+		a.mutex.Unlock()
+		a.persist(msg)
 
-		adscLog.Info("Received ", a.url, " type ", msg.TypeUrl,
-			" cnt=", len(msg.Resources), " nonce=", msg.Nonce)
-		if a.cfg.ResponseHandler != nil {
-			a.cfg.ResponseHandler.HandleResponse(a, msg)
+		select {
+		case a.XDSUpdates <- msg:
+		default:
 		}
+	}
+}
+
+// handleStreamClosed schedules a reconnect after the stream failed, or closes the client if reconnecting is disabled.
+func (a *ADSC) handleStreamClosed(err error) {
+	a.RecvWg.Done()
+	adscLog.Infof("Connection closed for node %v with err: %v", a.nodeID, err)
+	if a.cfg.ErrorHandler != nil {
+		a.cfg.ErrorHandler(err)
+	}
+	a.errChan <- err
+	// if 'reconnect' enabled - schedule a new Run
+	if a.cfg.BackoffPolicy != nil {
+		time.AfterFunc(a.cfg.BackoffPolicy.NextBackOff(), a.reconnect)
+	} else {
+		a.Close()
+		a.WaitClear()
+		a.Updates <- ""
+		a.XDSUpdates <- nil
+		close(a.errChan)
+	}
+}
+
+// handleResponse processes the resources of a response. It returns false if the response must not be
+// recorded and acked.
+func (a *ADSC) handleResponse(msg *discovery.DiscoveryResponse) bool {
+	// Group-value-kind - used for high level api generator.
+	gvk := strings.SplitN(msg.TypeUrl, "/", 3)
+
+	adscLog.Info("Received ", a.url, " type ", msg.TypeUrl,
+		" cnt=", len(msg.Resources), " nonce=", msg.Nonce)
+	if a.cfg.ResponseHandler != nil {
+		a.cfg.ResponseHandler.HandleResponse(a, msg)
+	}
 
-		if msg.TypeUrl == collections.IstioMeshV1Alpha1MeshConfig.Resource().GroupVersionKind().String() &&
-			len(msg.Resources) > 0 {
-			rsc := msg.Resources[0]
-			m := &v1alpha1.MeshConfig{}
-			err = proto.Unmarshal(rsc.Value, m)
+	if msg.TypeUrl == collections.IstioMeshV1Alpha1MeshConfig.Resource().GroupVersionKind().String() &&
+		len(msg.Resources) > 0 {
+		rsc := msg.Resources[0]
+		m := &v1alpha1.MeshConfig{}
+		err := proto.Unmarshal(rsc.Value, m)
+		if err != nil {
+			adscLog.Warn("Failed to unmarshal mesh config", err)
+		}
+		a.Mesh = m
+		if a.LocalCacheDir != "" {
+			strResponse, err := ConvertGolangProtoToJSONByGolangJSONPB(m)
 			if err != nil {
-				adscLog.Warn("Failed to unmarshal mesh config", err)
+				return false
 			}
-			a.Mesh = m
-			if a.LocalCacheDir != "" {
-				strResponse, err := ConvertGolangProtoToJSONByGolangJSONPB(m)
-				if err != nil {
-					continue
-				}
-				err = ioutil.WriteFile(a.LocalCacheDir+"_mesh.json", []byte(strResponse), 0o644)
-				if err != nil {
-					continue
-				}
+			err = ioutil.WriteFile(a.LocalCacheDir+"_mesh.json", []byte(strResponse), 0o644)
+			if err != nil {
+				return false
 			}
-			continue
 		}
-
-		// Process the resources.
-		listeners := []*listener.Listener{}
-		clusters := []*cluster.Cluster{}
-		routes := []*route.RouteConfiguration{}
-		eds := []*endpoint.ClusterLoadAssignment{}
-		a.VersionInfo[msg.TypeUrl] = msg.VersionInfo
-		switch msg.TypeUrl {
-		case v3.ListenerType:
-			for _, rsc := range msg.Resources {
-				valBytes := rsc.Value
-				ll := &listener.Listener{}
-				_ = proto.Unmarshal(valBytes, ll)
-				listeners = append(listeners, ll)
-			}
-			a.handleLDS(listeners)
-		case v3.ClusterType:
-			for _, rsc := range msg.Resources {
-				valBytes := rsc.Value
-				cl := &cluster.Cluster{}
-				_ = proto.Unmarshal(valBytes, cl)
-				clusters = append(clusters, cl)
-			}
-			a.handleCDS(clusters)
-		case v3.EndpointType:
-			for _, rsc := range msg.Resources {
-				valBytes := rsc.Value
-				el := &endpoint.ClusterLoadAssignment{}
-				_ = proto.Unmarshal(valBytes, el)
-				eds = append(eds, el)
-			}
-			a.handleEDS(eds)
-		case v3.RouteType:
-			for _, rsc := range msg.Resources {
-				valBytes := rsc.Value
-				rl := &route.RouteConfiguration{}
-				_ = proto.Unmarshal(valBytes, rl)
-				routes = append(routes, rl)
-			}
-			a.handleRDS(routes)
-		default:
-			a.handleMCP(gvk, msg.Resources)
+		return false
+	}
+
+	// Process the resources.
+	listeners := []*listener.Listener{}
+	clusters := []*cluster.Cluster{}
+	routes := []*route.RouteConfiguration{}
+	eds := []*endpoint.ClusterLoadAssignment{}
+	a.VersionInfo[msg.TypeUrl] = msg.VersionInfo
+	switch msg.TypeUrl {
+	case v3.ListenerType:
+		for _, rsc := range msg.Resources {
+			valBytes := rsc.Value
+			ll := &listener.Listener{}
+			_ = proto.Unmarshal(valBytes, ll)
+			listeners = append(listeners, ll)
 		}
+		a.handleLDS(listeners)
+	case v3.ClusterType:
+		for _, rsc := range msg.Resources {
+			valBytes := rsc.Value
+			cl := &cluster.Cluster{}
+			_ = proto.Unmarshal(valBytes, cl)
+			clusters = append(clusters, cl)
+		}
+		a.handleCDS(clusters)
+	case v3.EndpointType:
+		for _, rsc := range msg.Resources {
+			valBytes := rsc.Value
+			el := &endpoint.ClusterLoadAssignment{}
+			_ = proto.Unmarshal(valBytes, el)
+			eds = append(eds, el)
+		}
+		a.handleEDS(eds)
+	case v3.RouteType:
+		for _, rsc := range msg.Resources {
+			valBytes := rsc.Value
+			rl := &route.RouteConfiguration{}
+			_ = proto.Unmarshal(valBytes, rl)
+			routes = append(routes, rl)
+		}
+		a.handleRDS(routes)
+	case v3.VirtualHostType:
+		vhosts := []*route.VirtualHost{}
+		for _, rsc := range msg.Resources {
+			vh := &route.VirtualHost{}
+			_ = proto.Unmarshal(rsc.Value, vh)
+			vhosts = append(vhosts, vh)
+		}
+		a.handleVHDS(vhosts)
+	default:
+		a.handleMCP(gvk, msg.Resources)
+	}
 
-		// If we got no resource - still save to the store with empty name/namespace, to notify sync
-		// This scheme also allows us to chunk large responses !
+	return true
+}
 
-		// TODO: add hook to inject nacks
+// markSynced records the first response of a config type, to notify sync. It must be called with the lock held.
+func (a *ADSC) markSynced(typeURL string) {
+	// If we got no resource - still save to the store with empty name/namespace, to notify sync
+	// This scheme also allows us to chunk large responses !
 
-		a.mutex.Lock()
-		if len(gvk) == 3 {
-			gt := config.GroupVersionKind{Group: gvk[0], Version: gvk[1], Kind: gvk[2]}
-			if _, exist := a.sync[gt.String()]; !exist {
-				a.sync[gt.String()] = time.Now()
-				a.syncCh <- gt.String()
-			}
-		}
-		a.Received[msg.TypeUrl] = msg
-		a.ack(msg)
-		a.mutex.Unlock()
+	// TODO: add hook to inject nacks
 
-		select {
-		case a.XDSUpdates <- msg:
-		default:
+	gvk := strings.SplitN(typeURL, "/", 3)
+	if len(gvk) == 3 {
+		gt := config.GroupVersionKind{Group: gvk[0], Version: gvk[1], Kind: gvk[2]}
+		if _, exist := a.sync[gt.String()]; !exist {
+			a.sync[gt.String()] = time.Now()
+			a.syncCh <- gt.String()
 		}
 	}
 }
@@ -866,8 +947,11 @@ func (a *ADSC) node() *core.Node {
 	return n
 }
 
-// Raw send of a request.
+// Raw send of a request. In delta mode, the request is converted to a subscription to its resources.
 func (a *ADSC) Send(req *discovery.DiscoveryRequest) error {
+	if a.cfg.Delta {
+		return a.sendDelta(req.TypeUrl, req.ResourceNames)
+	}
 	if a.sendNodeMeta {
 		req.Node = a.node()
 		a.sendNodeMeta = false
@@ -897,7 +981,7 @@ func (a *ADSC) handleEDS(eds []*endpoint.ClusterLoadAssignment) {
 	}
 	if a.InitialLoad == 0 {
 		// first load - Envoy loads listeners after endpoints
-		_ = a.stream.Send(&discovery.DiscoveryRequest{
+		_ = a.request(&discovery.DiscoveryRequest{
 			Node:    a.node(),
 			TypeUrl: v3.ListenerType,
 		})
@@ -954,6 +1038,35 @@ func (a *ADSC) handleRDS(configurations []*route.RouteConfiguration) {
 	}
 }
 
+func (a *ADSC) handleVHDS(vhosts []*route.VirtualHost) {
+	vhds := map[string]*route.VirtualHost{}
+	for _, vh := range vhosts {
+		vhds[vh.Name] = vh
+	}
+	adscLog.Infof("VHDS: %d", len(vhosts))
+
+	a.mutex.Lock()
+	a.virtualHosts = vhds
+	a.mutex.Unlock()
+
+	select {
+	case a.Updates <- v3.VirtualHostType:
+	default:
+	}
+}
+
+// WatchVirtualHosts requests the virtual hosts of the given hosts of a route configuration with VHDS, in
+// addition to the ones already requested.
+func (a *ADSC) WatchVirtualHosts(routeName string, hosts ...string) {
+	a.mutex.Lock()
+	for _, h := range hosts {
+		a.vhdsResources = append(a.vhdsResources, routeName+"/"+h)
+	}
+	rsc := append([]string{}, a.vhdsResources...)
+	a.mutex.Unlock()
+	a.sendRsc(v3.VirtualHostType, rsc)
+}
+
 // WaitClear will clear the waiting events, so next call to Wait will get
 // the next push type.
 func (a *ADSC) WaitClear() {
@@ -1075,7 +1188,7 @@ func XdsInitialRequests() []*discovery.DiscoveryRequest {
 // it will start watching RDS and LDS.
 func (a *ADSC) Watch() {
 	a.watchTime = time.Now()
-	_ = a.stream.Send(&discovery.DiscoveryRequest{
+	_ = a.request(&discovery.DiscoveryRequest{
 		Node:    a.node(),
 		TypeUrl: v3.ClusterType,
 	})
@@ -1097,14 +1210,14 @@ func ConfigInitialRequests() []*discovery.DiscoveryRequest {
 
 // WatchConfig will use the new experimental API watching, similar with MCP.
 func (a *ADSC) WatchConfig() {
-	_ = a.stream.Send(&discovery.DiscoveryRequest{
+	_ = a.request(&discovery.DiscoveryRequest{
 		ResponseNonce: time.Now().String(),
 		Node:          a.node(),
 		TypeUrl:       collections.IstioMeshV1Alpha1MeshConfig.Resource().GroupVersionKind().String(),
 	})
 
 	for _, sch := range collections.Pilot.All() {
-		_ = a.stream.Send(&discovery.DiscoveryRequest{
+		_ = a.request(&discovery.DiscoveryRequest{
 			ResponseNonce: time.Now().String(),
 			Node:          a.node(),
 			TypeUrl:       sch.Resource().GroupVersionKind().String(),
@@ -1132,6 +1245,21 @@ func (a *ADSC) WaitConfigSync(max time.Duration) bool {
 	}
 }
 
+// request sends a request on the stream of the configured protocol. Requests made before Run, while
+// loading the cache, are dropped: Run sends the initial requests.
+func (a *ADSC) request(req *discovery.DiscoveryRequest) error {
+	if a.cfg.Delta {
+		if a.deltaStream == nil {
+			return nil
+		}
+		return a.sendDelta(req.TypeUrl, req.ResourceNames)
+	}
+	if a.stream == nil {
+		return nil
+	}
+	return a.stream.Send(req)
+}
+
 func (a *ADSC) sendRsc(typeurl string, rsc []string) {
 	ex := a.Received[typeurl]
 	version := ""
@@ -1140,7 +1268,7 @@ func (a *ADSC) sendRsc(typeurl string, rsc []string) {
 		version = ex.VersionInfo
 		nonce = ex.Nonce
 	}
-	_ = a.stream.Send(&discovery.DiscoveryRequest{
+	_ = a.request(&discovery.DiscoveryRequest{
 		ResponseNonce: nonce,
 		VersionInfo:   version,
 		Node:          a.node(),
@@ -1161,6 +1289,9 @@ func (a *ADSC) ack(msg *discovery.DiscoveryResponse) {
 			resources = append(resources, r)
 		}
 	}
+	if msg.TypeUrl == v3.VirtualHostType {
+		resources = append(resources, a.vhdsResources...)
+	}
 
 	_ = a.stream.Send(&discovery.DiscoveryRequest{
 		ResponseNonce: msg.Nonce,
@@ -1206,6 +1337,13 @@ func (a *ADSC) GetRoutes() map[string]*route.RouteConfiguration {
 	return a.routes
 }
 
+// GetVirtualHosts returns all the virtual hosts received with VHDS.
+func (a *ADSC) GetVirtualHosts() map[string]*route.VirtualHost {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.virtualHosts
+}
+
 // GetEndpoints returns all the routes.
 func (a *ADSC) GetEndpoints() map[string]*endpoint.ClusterLoadAssignment {
 	a.mutex.Lock()
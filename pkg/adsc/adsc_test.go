@@ -28,6 +28,7 @@ import (
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/gogo/protobuf/types"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/grpc"
@@ -37,6 +38,7 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/memory"
 	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/schema/collections"
 )
 
@@ -48,8 +50,13 @@ func (t *testAdscRunServer) StreamAggregatedResources(stream xdsapi.AggregatedDi
 	return StreamHandler(stream)
 }
 
-func (t *testAdscRunServer) DeltaAggregatedResources(xdsapi.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
-	return nil
+var DeltaStreamHandler func(stream xdsapi.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error
+
+func (t *testAdscRunServer) DeltaAggregatedResources(stream xdsapi.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	if DeltaStreamHandler == nil {
+		return nil
+	}
+	return DeltaStreamHandler(stream)
 }
 
 func TestADSC_Run(t *testing.T) {
@@ -147,6 +154,150 @@ func TestADSC_Run(t *testing.T) {
 	}
 }
 
+func TestADSC_RunDelta(t *testing.T) {
+	resource := func(name, version string) *xdsapi.Resource {
+		return &xdsapi.Resource{
+			Name:     name,
+			Version:  version,
+			Resource: &any.Any{TypeUrl: "foo", Value: []byte(name + version)},
+		}
+	}
+	requests := make(chan *xdsapi.DeltaDiscoveryRequest, 10)
+	DeltaStreamHandler = func(stream xdsapi.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+		for i := 0; i < 2; i++ {
+			req, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			requests <- req
+			if i == 0 {
+				_ = stream.Send(&xdsapi.DeltaDiscoveryResponse{
+					TypeUrl:           "foo",
+					SystemVersionInfo: "1",
+					Nonce:             "n1",
+					Resources:         []*xdsapi.Resource{resource("a", "1"), resource("b", "1")},
+				})
+			}
+		}
+		_ = stream.Send(&xdsapi.DeltaDiscoveryResponse{
+			TypeUrl:           "foo",
+			SystemVersionInfo: "2",
+			Nonce:             "n2",
+			Resources:         []*xdsapi.Resource{resource("b", "2"), resource("c", "1")},
+			RemovedResources:  []string{"a"},
+		})
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		requests <- req
+		return nil
+	}
+	defer func() { DeltaStreamHandler = nil }()
+
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Unable to listen with tcp err %v", err)
+	}
+	xds := grpc.NewServer()
+	xdsapi.RegisterAggregatedDiscoveryServiceServer(xds, new(testAdscRunServer))
+	go func() {
+		if err := xds.Serve(l); err != nil {
+			log.Println(err)
+		}
+	}()
+	defer xds.GracefulStop()
+
+	adsc := &ADSC{
+		Received:    make(map[string]*xdsapi.DiscoveryResponse),
+		Updates:     make(chan string),
+		XDSUpdates:  make(chan *xdsapi.DiscoveryResponse),
+		RecvWg:      sync.WaitGroup{},
+		VersionInfo: map[string]string{},
+		url:         l.Addr().String(),
+		cfg: &Config{
+			Delta:                    true,
+			InitialDiscoveryRequests: []*xdsapi.DiscoveryRequest{{TypeUrl: "foo"}},
+		},
+	}
+	if err := adsc.Dial(); err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	if err := adsc.Run(); err != nil {
+		t.Fatalf("ADSC: failed running %v", err)
+	}
+	adsc.RecvWg.Wait()
+
+	initial := <-requests
+	if initial.TypeUrl != "foo" || len(initial.ResourceNamesSubscribe) != 0 || initial.Node == nil {
+		t.Errorf("expected a wildcard subscription with the node, got %v", initial)
+	}
+	if ack := <-requests; ack.ResponseNonce != "n1" {
+		t.Errorf("expected an ack of n1, got %v", ack)
+	}
+	if ack := <-requests; ack.ResponseNonce != "n2" {
+		t.Errorf("expected an ack of n2, got %v", ack)
+	}
+
+	expected := &xdsapi.DiscoveryResponse{
+		TypeUrl:     "foo",
+		VersionInfo: "2",
+		Nonce:       "n2",
+		Resources:   []*any.Any{resource("b", "2").Resource, resource("c", "1").Resource},
+	}
+	if !cmp.Equal(adsc.Received["foo"], expected, protocmp.Transform()) {
+		t.Errorf("expected merged response %v got %v", expected, adsc.Received["foo"])
+	}
+	if got := adsc.VersionInfo["foo"]; got != "2" {
+		t.Errorf("expected system version 2, got %v", got)
+	}
+	if got, want := adsc.GetResourceVersions("foo"), map[string]string{"b": "2", "c": "1"}; !cmp.Equal(got, want) {
+		t.Errorf("expected resource versions %v, got %v", want, got)
+	}
+}
+
+func TestADSC_Cache(t *testing.T) {
+	for _, format := range []string{CacheFormatProto, CacheFormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			static := &cluster.Cluster{Name: "static", ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STATIC}}
+			eds := &cluster.Cluster{Name: "eds", ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS}}
+			resources := []*any.Any{}
+			for _, c := range []*cluster.Cluster{static, eds} {
+				a, err := ptypes.MarshalAny(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resources = append(resources, a)
+			}
+
+			first := &ADSC{cfg: &Config{CacheDir: dir, CacheFormat: format}}
+			first.persist(&xdsapi.DiscoveryResponse{
+				TypeUrl:     v3.ClusterType,
+				VersionInfo: "v1",
+				Nonce:       "nonce",
+				Resources:   resources,
+			})
+
+			// A new client loads the clusters before connecting to the (unreachable) server.
+			second, err := New("127.0.0.1:1", &Config{CacheDir: dir, CacheFormat: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer second.Close()
+			if got := second.GetClusters(); len(got) != 1 || !cmp.Equal(got["static"], static, protocmp.Transform()) {
+				t.Errorf("expected the static cluster to be loaded, got %v", got)
+			}
+			if got := second.GetEdsClusters(); len(got) != 1 || !cmp.Equal(got["eds"], eds, protocmp.Transform()) {
+				t.Errorf("expected the eds cluster to be loaded, got %v", got)
+			}
+			if got := second.Received[v3.ClusterType]; got.GetVersionInfo() != "v1" || got.GetNonce() != "" {
+				t.Errorf("expected the version but not the nonce to be loaded, got %v", got)
+			}
+		})
+	}
+}
+
 func TestADSC_Save(t *testing.T) {
 	tests := []struct {
 		desc         string
@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adsc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// Formats of the resources persisted in Config.CacheDir.
+const (
+	CacheFormatProto = "proto"
+	CacheFormatJSON  = "json"
+)
+
+// cachedTypes are the types persisted in Config.CacheDir, in the order they are loaded.
+var cachedTypes = []string{v3.ClusterType, v3.EndpointType, v3.ListenerType, v3.RouteType}
+
+func isCachedType(typeURL string) bool {
+	for _, t := range cachedTypes {
+		if t == typeURL {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *ADSC) cacheFile(typeURL string) string {
+	ext := ".pb"
+	if a.cfg.CacheFormat == CacheFormatJSON {
+		ext = ".json"
+	}
+	return filepath.Join(a.cfg.CacheDir, strings.ToLower(v3.GetShortType(typeURL))+ext)
+}
+
+// persist saves the resources of a response to the cache directory, replacing the previous ones of the type.
+func (a *ADSC) persist(msg *discovery.DiscoveryResponse) {
+	if a.cfg.CacheDir == "" || !isCachedType(msg.TypeUrl) {
+		return
+	}
+	// The nonce is specific to the stream, and is not persisted.
+	cached := &discovery.DiscoveryResponse{
+		TypeUrl:     msg.TypeUrl,
+		VersionInfo: msg.VersionInfo,
+		Resources:   msg.Resources,
+	}
+	var b []byte
+	var err error
+	if a.cfg.CacheFormat == CacheFormatJSON {
+		var s string
+		s, err = (&jsonpb.Marshaler{Indent: "  "}).MarshalToString(cached)
+		b = []byte(s)
+	} else {
+		b, err = proto.Marshal(cached)
+	}
+	if err != nil {
+		adscLog.Warnf("Failed to marshal %s for the cache: %v", v3.GetShortType(msg.TypeUrl), err)
+		return
+	}
+
+	// Write to a temporary file first, so a crash does not leave a truncated cache behind.
+	file := a.cacheFile(msg.TypeUrl)
+	if err := os.MkdirAll(a.cfg.CacheDir, 0o755); err != nil {
+		adscLog.Warnf("Failed to create the cache directory: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(file+".tmp", b, 0o644); err != nil {
+		adscLog.Warnf("Failed to write %s: %v", file, err)
+		return
+	}
+	if err := os.Rename(file+".tmp", file); err != nil {
+		adscLog.Warnf("Failed to write %s: %v", file, err)
+	}
+}
+
+// loadCache handles the resources persisted in the cache directory as if they were received from the server.
+func (a *ADSC) loadCache() error {
+	for _, typeURL := range cachedTypes {
+		file := a.cacheFile(typeURL)
+		b, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		msg := &discovery.DiscoveryResponse{}
+		if a.cfg.CacheFormat == CacheFormatJSON {
+			err = jsonpb.UnmarshalString(string(b), msg)
+		} else {
+			err = proto.Unmarshal(b, msg)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		if msg.TypeUrl != typeURL {
+			return fmt.Errorf("%s holds %s resources", file, msg.TypeUrl)
+		}
+		adscLog.Infof("Loaded %d %s resources from %s", len(msg.Resources), v3.GetShortType(typeURL), file)
+		a.handleResponse(msg)
+		a.mutex.Lock()
+		a.Received[typeURL] = msg
+		a.mutex.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adsc
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// runDelta creates a new incremental stream, resumes the subscriptions of a previous stream and sends the
+// initial requests.
+func (a *ADSC) runDelta() error {
+	var err error
+	a.deltaStream, err = a.client.DeltaAggregatedResources(context.Background())
+	if err != nil {
+		return err
+	}
+	a.sendNodeMeta = true
+	a.InitialLoad = 0
+
+	// Resume the subscriptions of the previous stream, with the versions of the resources already
+	// received so the server only sends what changed.
+	a.deltaMutex.Lock()
+	resumed := make([]*discovery.DeltaDiscoveryRequest, 0, len(a.deltaStates))
+	for typeURL, st := range a.deltaStates {
+		req := &discovery.DeltaDiscoveryRequest{
+			TypeUrl:                 typeURL,
+			ResourceNamesSubscribe:  st.subscribedNames(),
+			InitialResourceVersions: map[string]string{},
+		}
+		for name, r := range st.resources {
+			req.InitialResourceVersions[name] = r.Version
+		}
+		resumed = append(resumed, req)
+	}
+	a.deltaMutex.Unlock()
+	for _, req := range resumed {
+		_ = a.sendDeltaRequest(req)
+	}
+
+	for _, r := range a.cfg.InitialDiscoveryRequests {
+		if r.TypeUrl == v3.ClusterType {
+			a.watchTime = time.Now()
+		}
+		_ = a.sendDelta(r.TypeUrl, r.ResourceNames)
+	}
+
+	a.RecvWg.Add(1)
+
+	go a.handleDeltaRecv()
+	return nil
+}
+
+func (a *ADSC) handleDeltaRecv() {
+	for {
+		delta, err := a.deltaStream.Recv()
+		if err != nil {
+			a.handleStreamClosed(err)
+			return
+		}
+
+		// The handlers expect the complete state of the type, as in a state of the world response.
+		msg := a.applyDelta(delta)
+		recorded := a.handleResponse(msg)
+
+		a.mutex.Lock()
+		if recorded {
+			a.markSynced(msg.TypeUrl)
+			a.Received[msg.TypeUrl] = msg
+		}
+		_ = a.deltaStream.Send(&discovery.DeltaDiscoveryRequest{
+			TypeUrl:       delta.TypeUrl,
+			ResponseNonce: delta.Nonce,
+		})
+		a.mutex.Unlock()
+
+		if recorded {
+			a.persist(msg)
+			select {
+			case a.XDSUpdates <- msg:
+			default:
+			}
+		}
+	}
+}
+
+// applyDelta merges the added and removed resources of an incremental response into the resources of its
+// type, and returns the resulting state as a state of the world response.
+func (a *ADSC) applyDelta(delta *discovery.DeltaDiscoveryResponse) *discovery.DiscoveryResponse {
+	a.deltaMutex.Lock()
+	defer a.deltaMutex.Unlock()
+	st := a.deltaState(delta.TypeUrl)
+	for _, r := range delta.Resources {
+		st.resources[r.Name] = r
+	}
+	for _, name := range delta.RemovedResources {
+		delete(st.resources, name)
+	}
+	adscLog.Debugf("Delta %s: added=%d removed=%d total=%d", delta.TypeUrl,
+		len(delta.Resources), len(delta.RemovedResources), len(st.resources))
+
+	names := make([]string, 0, len(st.resources))
+	for name := range st.resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resources := make([]*any.Any, 0, len(names))
+	for _, name := range names {
+		resources = append(resources, st.resources[name].Resource)
+	}
+	return &discovery.DiscoveryResponse{
+		TypeUrl:     delta.TypeUrl,
+		VersionInfo: delta.SystemVersionInfo,
+		Nonce:       delta.Nonce,
+		Resources:   resources,
+	}
+}
+
+// sendDelta updates the subscription of a type to the given resource names, subscribing to the new names and
+// unsubscribing from the ones no longer requested. An empty list on the first request of a type subscribes to
+// all its resources.
+func (a *ADSC) sendDelta(typeURL string, names []string) error {
+	a.deltaMutex.Lock()
+	_, watched := a.deltaStates[typeURL]
+	st := a.deltaState(typeURL)
+	req := &discovery.DeltaDiscoveryRequest{TypeUrl: typeURL}
+	want := map[string]struct{}{}
+	for _, name := range names {
+		want[name] = struct{}{}
+		if _, f := st.subscribed[name]; !f {
+			st.subscribed[name] = struct{}{}
+			req.ResourceNamesSubscribe = append(req.ResourceNamesSubscribe, name)
+		}
+	}
+	for name := range st.subscribed {
+		if _, f := want[name]; !f {
+			delete(st.subscribed, name)
+			// The server does not notify the removal of resources we unsubscribe from.
+			delete(st.resources, name)
+			req.ResourceNamesUnsubscribe = append(req.ResourceNamesUnsubscribe, name)
+		}
+	}
+	a.deltaMutex.Unlock()
+
+	if watched && len(req.ResourceNamesSubscribe) == 0 && len(req.ResourceNamesUnsubscribe) == 0 {
+		return nil
+	}
+	sort.Strings(req.ResourceNamesUnsubscribe)
+	return a.sendDeltaRequest(req)
+}
+
+func (a *ADSC) sendDeltaRequest(req *discovery.DeltaDiscoveryRequest) error {
+	if a.sendNodeMeta {
+		req.Node = a.node()
+		a.sendNodeMeta = false
+	}
+	return a.deltaStream.Send(req)
+}
+
+// deltaState returns the bookkeeping of a type, creating it if needed. It must be called with deltaMutex held.
+func (a *ADSC) deltaState(typeURL string) *deltaState {
+	if a.deltaStates == nil {
+		a.deltaStates = map[string]*deltaState{}
+	}
+	st, f := a.deltaStates[typeURL]
+	if !f {
+		st = &deltaState{
+			subscribed: map[string]struct{}{},
+			resources:  map[string]*discovery.Resource{},
+		}
+		a.deltaStates[typeURL] = st
+	}
+	return st
+}
+
+func (st *deltaState) subscribedNames() []string {
+	names := make([]string, 0, len(st.subscribed))
+	for name := range st.subscribed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetResourceVersions returns the versions of the resources of a type received in delta mode, keyed by
+// resource name.
+func (a *ADSC) GetResourceVersions(typeURL string) map[string]string {
+	a.deltaMutex.Lock()
+	defer a.deltaMutex.Unlock()
+	versions := map[string]string{}
+	if st, f := a.deltaStates[typeURL]; f {
+		for name, r := range st.resources {
+			versions[name] = r.Version
+		}
+	}
+	return versions
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net"
 	"os"
 	"path"
@@ -108,6 +109,12 @@ func (cfg Config) toTemplateParams() (map[string]interface{}, error) {
 	// Support passing extra info from node environment as metadata
 	opts = append(opts, getNodeMetadataOptions(cfg.Node)...)
 
+	overloadOpts, err := getOverloadOptions(cfg.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, overloadOpts...)
+
 	// Check if nodeIP carries IPv4 or IPv6 and set up proxy accordingly
 	if isIPv6Proxy(cfg.Metadata.InstanceIPs) {
 		opts = append(opts,
@@ -262,6 +269,49 @@ func getNodeMetadataOptions(node *model.Node) []option.Instance {
 	return opts
 }
 
+const (
+	defaultOverloadHeapShedThreshold = 0.95
+	// defaultOverloadMaxDownstreamConnections effectively disables the limit, without Envoy warning about
+	// a missing one.
+	defaultOverloadMaxDownstreamConnections = math.MaxInt32
+)
+
+// getOverloadOptions returns the overload manager settings of the proxy. They are read from the node metadata,
+// so they can be set for the whole mesh with the proxyMetadata of the default proxy config, and overridden for
+// a workload with its proxy.istio.io/config annotation.
+func getOverloadOptions(meta *model.BootstrapNodeMetadata) ([]option.Instance, error) {
+	var maxHeap uint64
+	if meta.OverloadMaxHeapBytes != "" {
+		v, err := strconv.ParseUint(meta.OverloadMaxHeapBytes, 10, 64)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("invalid OVERLOAD_MAX_HEAP_BYTES %q: must be a positive number of bytes", meta.OverloadMaxHeapBytes)
+		}
+		maxHeap = v
+	}
+	threshold := defaultOverloadHeapShedThreshold
+	if meta.OverloadHeapShedThreshold != "" {
+		v, err := strconv.ParseFloat(meta.OverloadHeapShedThreshold, 64)
+		if err != nil || v <= 0 || v > 1 {
+			return nil, fmt.Errorf("invalid OVERLOAD_HEAP_SHED_THRESHOLD %q: must be in (0, 1]", meta.OverloadHeapShedThreshold)
+		}
+		threshold = v
+	}
+	var maxConnections int64 = defaultOverloadMaxDownstreamConnections
+	if meta.OverloadMaxDownstreamConnections != "" {
+		v, err := strconv.ParseInt(meta.OverloadMaxDownstreamConnections, 10, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid OVERLOAD_MAX_DOWNSTREAM_CONNECTIONS %q: must be a positive number",
+				meta.OverloadMaxDownstreamConnections)
+		}
+		maxConnections = v
+	}
+	return []option.Instance{
+		option.OverloadMaxHeapBytes(maxHeap),
+		option.OverloadHeapShedThreshold(threshold),
+		option.OverloadMaxDownstreamConnections(maxConnections),
+	}, nil
+}
+
 func getLocalityOptions(l *core.Locality) []option.Instance {
 	return []option.Instance{option.Region(l.Region), option.Zone(l.Zone), option.SubZone(l.SubZone)}
 }
@@ -461,6 +511,7 @@ type MetadataOptions struct {
 	annotationFilePath  string
 	EnvoyStatusPort     int
 	EnvoyPrometheusPort int
+	InstanceID          string
 }
 
 // GetNodeMetaData function uses an environment variable contract
@@ -556,6 +607,7 @@ func GetNodeMetaData(options MetadataOptions) (*model.Node, error) {
 	meta.PilotSubjectAltName = options.PilotSubjectAltName
 	meta.OutlierLogPath = options.OutlierLogPath
 	meta.ProvCert = options.ProvCert
+	meta.ProxyInstanceID = options.InstanceID
 
 	return &model.Node{
 		ID:          options.ID,
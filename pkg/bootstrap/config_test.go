@@ -26,6 +26,7 @@ import (
 	"k8s.io/kubectl/pkg/util/fieldpath"
 
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/bootstrap/option"
 )
 
 func TestParseDownwardApi(t *testing.T) {
@@ -147,3 +148,69 @@ func TestConvertNodeMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestGetOverloadOptions(t *testing.T) {
+	cases := []struct {
+		name    string
+		meta    model.NodeMetadata
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "default",
+			want: map[string]interface{}{
+				"overload_heap_shed_threshold":        0.95,
+				"overload_max_downstream_connections": int64(2147483647),
+			},
+		},
+		{
+			name: "configured",
+			meta: model.NodeMetadata{
+				OverloadMaxHeapBytes:             "1073741824",
+				OverloadHeapShedThreshold:        "0.98",
+				OverloadMaxDownstreamConnections: "10000",
+			},
+			want: map[string]interface{}{
+				"overload_max_heap_bytes":             uint64(1073741824),
+				"overload_heap_shed_threshold":        0.98,
+				"overload_max_downstream_connections": int64(10000),
+			},
+		},
+		{
+			name:    "invalid heap",
+			meta:    model.NodeMetadata{OverloadMaxHeapBytes: "1Gi"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid threshold",
+			meta:    model.NodeMetadata{OverloadHeapShedThreshold: "95"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid connections",
+			meta:    model.NodeMetadata{OverloadMaxDownstreamConnections: "-1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := getOverloadOptions(&model.BootstrapNodeMetadata{NodeMetadata: tt.meta})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := option.NewTemplateParams(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
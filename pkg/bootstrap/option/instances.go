@@ -220,6 +220,18 @@ func EnvoyPrometheusPort(value int) Instance {
 	return newOption("envoy_prometheus_port", value)
 }
 
+func OverloadMaxHeapBytes(value uint64) Instance {
+	return newOptionOrSkipIfZero("overload_max_heap_bytes", value)
+}
+
+func OverloadHeapShedThreshold(value float64) Instance {
+	return newOption("overload_heap_shed_threshold", value)
+}
+
+func OverloadMaxDownstreamConnections(value int64) Instance {
+	return newOption("overload_max_downstream_connections", value)
+}
+
 func STSPort(value int) Instance {
 	return newOption("sts_port", value)
 }
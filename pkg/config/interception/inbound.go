@@ -0,0 +1,144 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package interception models which traffic of a workload is intercepted and redirected to its proxy.
+// It is shared by the sidecar injector, which configures the interception with istio-iptables, and by
+// istiod, which generates the configuration of the proxy for the intercepted traffic, so that both agree.
+package interception
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"istio.io/api/annotation"
+)
+
+const (
+	// EnvoyPrometheusPort is the port Envoy serves its merged Prometheus stats on.
+	EnvoyPrometheusPort = 15090
+	// EnvoyStatusPort is the port Envoy serves the readiness of the proxy on.
+	EnvoyStatusPort = 15021
+	// DefaultStatusPort is the default port of the status server of the agent, set with the
+	// status.sidecar.istio.io/port annotation.
+	DefaultStatusPort = 15020
+
+	// AllPorts is the value of the includeInboundPorts annotation intercepting all the ports.
+	AllPorts = "*"
+)
+
+// InboundPorts is the set of inbound ports of a workload which are intercepted and redirected to its proxy,
+// as configured by the traffic.sidecar.istio.io/includeInboundPorts and excludeInboundPorts annotations.
+type InboundPorts struct {
+	// All is set if all the ports are intercepted, except the Exclude ones.
+	All bool `json:"all"`
+	// Include lists the intercepted ports, if All is not set. Exclude is ignored then, as by istio-iptables.
+	Include []int `json:"include,omitempty"`
+	// Exclude lists the ports which are not intercepted, including the ports of the proxy itself.
+	Exclude []int `json:"exclude,omitempty"`
+}
+
+// NewInboundPorts returns the inbound ports of a workload intercepted with the given values of the
+// includeInboundPorts and excludeInboundPorts annotations and status port of the agent.
+func NewInboundPorts(include, exclude, statusPort string) (*InboundPorts, error) {
+	p := &InboundPorts{}
+	include = strings.TrimSpace(include)
+	if include == AllPorts {
+		p.All = true
+	} else {
+		ports, err := ParsePorts(include)
+		if err != nil {
+			return nil, fmt.Errorf("includeInboundPorts invalid: %v", err)
+		}
+		p.Include = ports
+	}
+	ports, err := ParsePorts(ExcludedInboundPorts(statusPort, exclude))
+	if err != nil {
+		return nil, fmt.Errorf("excludeInboundPorts invalid: %v", err)
+	}
+	p.Exclude = ports
+	return p, nil
+}
+
+// InboundPortsFromAnnotations returns the inbound ports intercepted for a workload with the given annotations.
+// Unset annotations take their default values of the injection template: all the ports are intercepted, except
+// the ports of the proxy and agent.
+func InboundPortsFromAnnotations(annotations map[string]string) (*InboundPorts, error) {
+	include := AllPorts
+	if v, f := annotations[annotation.SidecarTrafficIncludeInboundPorts.Name]; f {
+		include = v
+	}
+	statusPort := strconv.Itoa(DefaultStatusPort)
+	if v, f := annotations[annotation.SidecarStatusPort.Name]; f {
+		statusPort = v
+	}
+	return NewInboundPorts(include, annotations[annotation.SidecarTrafficExcludeInboundPorts.Name], statusPort)
+}
+
+// Captures returns true if traffic to the port is intercepted.
+func (p *InboundPorts) Captures(port int) bool {
+	if !p.All {
+		return containsPort(p.Include, port)
+	}
+	return !containsPort(p.Exclude, port)
+}
+
+// ExcludedInboundPorts returns the ports excluded from inbound interception, in the format of the istio-iptables
+// -d flag, for the given status port of the agent and value of the excludeInboundPorts annotation.
+// The ports of the proxy are always excluded, as well as the status port unless it is empty or 0.
+func ExcludedInboundPorts(statusPort, exclude string) string {
+	ports := []string{strconv.Itoa(EnvoyPrometheusPort), strconv.Itoa(EnvoyStatusPort)}
+	statusPort = strings.TrimSpace(statusPort)
+	excluded := false
+	for _, port := range strings.Split(exclude, ",") {
+		port = strings.TrimSpace(port)
+		if port == "" {
+			continue
+		}
+		if port == statusPort {
+			excluded = true
+		}
+		ports = append(ports, port)
+	}
+	if statusPort != "" && statusPort != "0" && !excluded {
+		ports = append(ports, statusPort)
+	}
+	return strings.Join(ports, ",")
+}
+
+// ParsePorts parses a comma separated list of ports.
+func ParsePorts(ports string) ([]int, error) {
+	ports = strings.TrimSpace(ports)
+	out := make([]int, 0)
+	if ports == "" {
+		return out, nil
+	}
+	for _, s := range strings.Split(ports, ",") {
+		port, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing port %q: %v", s, err)
+		}
+		out = append(out, int(port))
+	}
+	return out, nil
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
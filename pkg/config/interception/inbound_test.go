@@ -0,0 +1,129 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interception_test
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pkg/config/interception"
+)
+
+func TestExcludedInboundPorts(t *testing.T) {
+	cases := []struct {
+		statusPort string
+		exclude    string
+		expected   string
+	}{
+		{"15020", "", "15090,15021,15020"},
+		{"", "", "15090,15021"},
+		{"0", "", "15090,15021"},
+		{"0", "123", "15090,15021,123"},
+		{"15020", "4,5,6", "15090,15021,4,5,6,15020"},
+		{"5", "4,5,6", "15090,15021,4,5,6"},
+		{"15020", " 4, ,5 ", "15090,15021,4,5,15020"},
+	}
+	for _, tt := range cases {
+		if got := interception.ExcludedInboundPorts(tt.statusPort, tt.exclude); got != tt.expected {
+			t.Errorf("ExcludedInboundPorts(%q, %q): got %q, expected %q", tt.statusPort, tt.exclude, got, tt.expected)
+		}
+	}
+}
+
+func TestInboundPortsFromAnnotations(t *testing.T) {
+	cases := []struct {
+		name          string
+		annotations   map[string]string
+		expected      *interception.InboundPorts
+		captured      []int
+		notCaptured   []int
+		expectedError bool
+	}{
+		{
+			name:        "default",
+			expected:    &interception.InboundPorts{All: true, Exclude: []int{15090, 15021, 15020}},
+			captured:    []int{80, 8080},
+			notCaptured: []int{15090, 15021, 15020},
+		},
+		{
+			name: "excluded ports",
+			annotations: map[string]string{
+				annotation.SidecarTrafficExcludeInboundPorts.Name: "80",
+				annotation.SidecarStatusPort.Name:                 "0",
+			},
+			expected:    &interception.InboundPorts{All: true, Exclude: []int{15090, 15021, 80}},
+			captured:    []int{8080, 15020},
+			notCaptured: []int{80, 15090},
+		},
+		{
+			name: "included ports",
+			annotations: map[string]string{
+				annotation.SidecarTrafficIncludeInboundPorts.Name: "80,15020",
+				annotation.SidecarTrafficExcludeInboundPorts.Name: "80",
+			},
+			expected: &interception.InboundPorts{
+				Include: []int{80, 15020},
+				Exclude: []int{15090, 15021, 80, 15020},
+			},
+			// istio-iptables ignores the excluded ports once the included ones are listed.
+			captured:    []int{80, 15020},
+			notCaptured: []int{8080, 15090},
+		},
+		{
+			name:        "no ports",
+			annotations: map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: ""},
+			expected:    &interception.InboundPorts{Include: []int{}, Exclude: []int{15090, 15021, 15020}},
+			notCaptured: []int{80, 8080},
+		},
+		{
+			name:          "invalid included ports",
+			annotations:   map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: "http"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid excluded ports",
+			annotations:   map[string]string{annotation.SidecarTrafficExcludeInboundPorts.Name: "*"},
+			expectedError: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interception.InboundPortsFromAnnotations(tt.annotations)
+			if tt.expectedError {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("got %+v, expected %+v", got, tt.expected)
+			}
+			for _, port := range tt.captured {
+				if !got.Captures(port) {
+					t.Errorf("expected port %d to be captured", port)
+				}
+			}
+			for _, port := range tt.notCaptured {
+				if got.Captures(port) {
+					t.Errorf("expected port %d not to be captured", port)
+				}
+			}
+		})
+	}
+}
@@ -82,6 +82,35 @@ func AddUserMeshConfig(client kube.Client, watcher mesh.Watcher, namespace, key,
 	}
 }
 
+// NamespaceMeshConfigAnnotation is the namespace annotation holding a mesh config overlay for the namespace.
+const NamespaceMeshConfigAnnotation = "mesh.istio.io/config"
+
+// AddNamespaceMeshConfig keeps the mesh config overlays of the NamespaceMeshConfigAnnotation of the namespaces
+// of the cluster up to date in the watcher. The shared namespace informer is started with the client.
+func AddNamespaceMeshConfig(client kube.Client, watcher mesh.Watcher) {
+	informer := client.KubeInformer().Core().V1().Namespaces().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ns, ok := obj.(*v1.Namespace); ok {
+				watcher.HandleNamespaceMeshConfig(ns.Name, ns.Annotations[NamespaceMeshConfigAnnotation])
+			}
+		},
+		UpdateFunc: func(_, cur interface{}) {
+			if ns, ok := cur.(*v1.Namespace); ok {
+				watcher.HandleNamespaceMeshConfig(ns.Name, ns.Annotations[NamespaceMeshConfigAnnotation])
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(*v1.Namespace); ok {
+				watcher.HandleNamespaceMeshConfig(ns.Name, "")
+			}
+		},
+	})
+}
+
 func meshConfigMapData(cm *v1.ConfigMap, key string) string {
 	if cm == nil {
 		return ""
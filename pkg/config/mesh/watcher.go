@@ -22,6 +22,7 @@ import (
 	"unsafe"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/gogo/protobuf/proto"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/pkg/filewatcher"
@@ -43,6 +44,13 @@ type Watcher interface {
 	// HandleUserMeshConfig keeps track of user mesh config overrides. These are merged with the standard
 	// mesh config, which takes precedence.
 	HandleUserMeshConfig(string)
+
+	// HandleNamespaceMeshConfig keeps track of the mesh config overlay of a namespace. An empty overlay
+	// removes it.
+	HandleNamespaceMeshConfig(namespace, yaml string)
+
+	// EffectiveMesh returns the mesh config with the overlay of the given namespace applied.
+	EffectiveMesh(namespace string) *meshconfig.MeshConfig
 }
 
 // MultiWatcher is a struct wrapping the internal injector to let users know that both
@@ -61,6 +69,8 @@ type InternalWatcher struct {
 
 	userMeshConfig string
 	revMeshConfig  string
+	// Mesh config overlays by namespace, applied on top of the merged mesh config.
+	namespaceMeshConfig map[string]string
 }
 
 // NewFixedWatcher creates a new Watcher that always returns the given mesh config. It will never
@@ -167,6 +177,49 @@ func (w *InternalWatcher) merged() *meshconfig.MeshConfig {
 	return &mc
 }
 
+// HandleNamespaceMeshConfig keeps track of the mesh config overlay of a namespace. The overlay takes precedence
+// over both the user and the revision mesh config, for that namespace only.
+func (w *InternalWatcher) HandleNamespaceMeshConfig(namespace, yaml string) {
+	w.mutex.Lock()
+	if w.namespaceMeshConfig[namespace] == yaml {
+		w.mutex.Unlock()
+		return
+	}
+	if yaml == "" {
+		delete(w.namespaceMeshConfig, namespace)
+	} else {
+		if w.namespaceMeshConfig == nil {
+			w.namespaceMeshConfig = map[string]string{}
+		}
+		w.namespaceMeshConfig[namespace] = yaml
+	}
+	handlers := append([]func(){}, w.handlers...)
+	w.mutex.Unlock()
+
+	for i := len(handlers) - 1; i >= 0; i-- {
+		handlers[i]()
+	}
+}
+
+// EffectiveMesh returns the mesh config with the overlay of the given namespace applied. An invalid
+// overlay is ignored.
+func (w *InternalWatcher) EffectiveMesh(namespace string) *meshconfig.MeshConfig {
+	mc := w.Mesh()
+	w.mutex.Lock()
+	overlay := w.namespaceMeshConfig[namespace]
+	w.mutex.Unlock()
+	if overlay == "" || mc == nil {
+		return mc
+	}
+	// ApplyMeshConfig merges into the nested proxy config, which must not be shared with the current mesh config.
+	effective, err := ApplyMeshConfig(overlay, *proto.Clone(mc).(*meshconfig.MeshConfig))
+	if err != nil {
+		log.Errorf("mesh config overlay of namespace %s invalid, ignoring it %v %s", namespace, err, overlay)
+		return mc
+	}
+	return effective
+}
+
 // HandleMeshConfig calls all handlers for a given mesh configuration update. This must be called
 // with a lock on w.Mutex, or updates may be applied out of order.
 func (w *InternalWatcher) HandleMeshConfig(meshConfig *meshconfig.MeshConfig) {
@@ -77,6 +77,41 @@ func watcherShouldNotifyHandlers(t *testing.T, multi bool) {
 	}
 }
 
+func TestNamespaceMeshConfigOverlay(t *testing.T) {
+	g := NewWithT(t)
+
+	m := mesh.DefaultMeshConfig()
+	w := mesh.NewFixedWatcher(&m)
+	notified := 0
+	w.AddMeshHandler(func() {
+		notified++
+	})
+
+	w.HandleNamespaceMeshConfig("ns1", "ingressClass: foo\ndefaultConfig:\n  concurrency: 4")
+	g.Expect(notified).To(Equal(1))
+	effective := w.EffectiveMesh("ns1")
+	g.Expect(effective.IngressClass).To(Equal("foo"))
+	g.Expect(effective.DefaultConfig.Concurrency.GetValue()).To(Equal(int32(4)))
+	// Fields not in the overlay are kept from the mesh config.
+	g.Expect(effective.DefaultConfig.DiscoveryAddress).To(Equal(m.DefaultConfig.DiscoveryAddress))
+	// The mesh config and the other namespaces are not affected.
+	g.Expect(w.Mesh().IngressClass).To(Equal(m.IngressClass))
+	g.Expect(w.Mesh().DefaultConfig.Concurrency).To(Equal(m.DefaultConfig.Concurrency))
+	g.Expect(w.EffectiveMesh("ns2")).To(Equal(w.Mesh()))
+
+	// Unchanged overlays do not notify.
+	w.HandleNamespaceMeshConfig("ns1", "ingressClass: foo\ndefaultConfig:\n  concurrency: 4")
+	g.Expect(notified).To(Equal(1))
+
+	// Invalid overlays are ignored.
+	w.HandleNamespaceMeshConfig("ns1", "ingressClass: [}")
+	g.Expect(w.EffectiveMesh("ns1")).To(Equal(w.Mesh()))
+
+	w.HandleNamespaceMeshConfig("ns1", "")
+	g.Expect(notified).To(Equal(3))
+	g.Expect(w.EffectiveMesh("ns1")).To(Equal(w.Mesh()))
+}
+
 func newWatcher(t testing.TB, filename string, multi bool) mesh.Watcher {
 	t.Helper()
 	w, err := mesh.NewFileWatcher(filewatcher.NewWatcher(), filename, multi)
@@ -20,6 +20,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-multierror"
 
@@ -54,6 +55,33 @@ const (
 	attrExperimental     = "experimental.envoy.filters."
 )
 
+var (
+	customAttributesMu sync.RWMutex
+	// customAttributes are the validators of the attributes of custom conditions, keyed by prefix.
+	customAttributes = map[string]func(key string, values []string) error{}
+)
+
+// RegisterCustomAttribute accepts the conditions whose key starts with prefix, and validates their values with
+// validate. It is used by the compilers of custom authorization conditions.
+func RegisterCustomAttribute(prefix string, validate func(key string, values []string) error) {
+	customAttributesMu.Lock()
+	defer customAttributesMu.Unlock()
+	customAttributes[prefix] = validate
+}
+
+// validateCustomAttribute validates a condition with the validator of its custom attribute, and returns false if
+// the key is not a custom attribute.
+func validateCustomAttribute(key string, values []string) (bool, error) {
+	customAttributesMu.RLock()
+	defer customAttributesMu.RUnlock()
+	for prefix, validate := range customAttributes {
+		if strings.HasPrefix(key, prefix) {
+			return true, validate(key, values)
+		}
+	}
+	return false, nil
+}
+
 // ParseJwksURI parses the input URI and returns the corresponding hostname, port, and whether SSL is used.
 // URI must start with "http://" or "https://", which corresponding to "http" or "https" scheme.
 // Port number is extracted from URI if available (i.e from postfix :<port>, eg. ":80"), or assigned
@@ -129,6 +157,9 @@ func ValidateAttribute(key string, values []string) error {
 	case isEqual(key, attrDestName, attrDestUser):
 		return fmt.Errorf("deprecated attribute %s: only supported in v1alpha1", key)
 	default:
+		if found, err := validateCustomAttribute(key, values); found {
+			return err
+		}
 		return fmt.Errorf("unknown attribute: %s", key)
 	}
 	return nil
@@ -1552,6 +1552,10 @@ func ValidateProxyConfig(config *meshconfig.ProxyConfig) (errs error) {
 	// but that requires additional test validation
 	if config.DiscoveryAddress == "" {
 		errs = multierror.Append(errs, errors.New("discovery address must be set to the proxy discovery service"))
+	} else if strings.HasPrefix(config.DiscoveryAddress, UnixAddressPrefix) {
+		if err := ValidateUnixAddress(strings.TrimPrefix(config.DiscoveryAddress, UnixAddressPrefix)); err != nil {
+			errs = multierror.Append(errs, multierror.Prefix(err, "invalid discovery address:"))
+		}
 	} else if err := ValidateProxyAddress(config.DiscoveryAddress); err != nil {
 		errs = multierror.Append(errs, multierror.Prefix(err, "invalid discovery address:"))
 	}
@@ -529,6 +529,16 @@ func TestValidateProxyConfig(t *testing.T) {
 			in:      modify(valid, func(c *meshconfig.ProxyConfig) { c.DiscoveryAddress = "10.0.0.100" }),
 			isValid: false,
 		},
+		{
+			name:    "discovery address unix socket",
+			in:      modify(valid, func(c *meshconfig.ProxyConfig) { c.DiscoveryAddress = "unix:///var/run/istiod/xds.sock" }),
+			isValid: true,
+		},
+		{
+			name:    "discovery address unix socket invalid",
+			in:      modify(valid, func(c *meshconfig.ProxyConfig) { c.DiscoveryAddress = "unix://var/run/istiod/" }),
+			isValid: false,
+		},
 		{
 			name:    "proxy admin port invalid",
 			in:      modify(valid, func(c *meshconfig.ProxyConfig) { c.ProxyAdminPort = 0 }),
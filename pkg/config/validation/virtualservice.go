@@ -232,9 +232,12 @@ func validateHTTPRouteConflict(http *networking.HTTPRoute, routeType HTTPRouteTy
 	}
 
 	// This is to check delegate conflict
-	if routeType == DelegateRoute {
-		if http.Delegate != nil {
+	if routeType == DelegateRoute && http.Delegate != nil {
+		if features.VirtualServiceDelegateMaxDepth <= 1 {
 			errs = appendErrors(errs, errors.New("delegate HTTP route cannot contain delegate"))
+		} else {
+			// A delegate delegating further is the root of the next level.
+			return validateHTTPRouteConflict(http, RootRoute)
 		}
 	}
 
@@ -0,0 +1,170 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dependency tracks the health of the external dependencies istiod contacts, so a change of
+// the control plane behavior can be traced to an upstream outage.
+package dependency
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind is the kind of an external dependency.
+type Kind string
+
+const (
+	// JWKS is a remote JWKS or OpenID discovery endpoint of a JWT issuer.
+	JWKS Kind = "jwks"
+	// ExternalCA is an external CA istiod forwards the certificate signing requests to.
+	ExternalCA Kind = "external_ca"
+	// RemoteCluster is the API server of a remote cluster.
+	RemoteCluster Kind = "remote_cluster"
+	// ConfigSource is an XDS config source of the mesh config.
+	ConfigSource Kind = "config_source"
+)
+
+type key struct {
+	kind Kind
+	name string
+}
+
+type status struct {
+	firstAttempt  time.Time
+	lastAttempt   time.Time
+	lastSuccess   time.Time
+	lastError     string
+	lastErrorTime time.Time
+	latency       time.Duration
+	failures      int
+}
+
+// Registry records the outcome of the calls made to the external dependencies.
+type Registry struct {
+	mu   sync.Mutex
+	deps map[key]*status
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{deps: map[key]*status{}}
+}
+
+// Default is the Registry the dependencies of istiod are reported to.
+var Default = NewRegistry()
+
+// Report records the outcome of a call to a dependency started at start. A zero start leaves the latency
+// unchanged, for the calls whose duration is not measured, like the responses received on a stream.
+func Report(kind Kind, name string, start time.Time, err error) {
+	Default.Report(kind, name, start, err)
+}
+
+// Remove forgets a dependency that is no longer used.
+func Remove(kind Kind, name string) {
+	Default.Remove(kind, name)
+}
+
+// Report records the outcome of a call to a dependency started at start. A zero start leaves the latency
+// unchanged, for the calls whose duration is not measured, like the responses received on a stream.
+func (r *Registry) Report(kind Kind, name string, start time.Time, err error) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.deps[key{kind, name}]
+	if s == nil {
+		s = &status{firstAttempt: now}
+		r.deps[key{kind, name}] = s
+	}
+	s.lastAttempt = now
+	if !start.IsZero() {
+		s.latency = now.Sub(start)
+		if start.Before(s.firstAttempt) {
+			s.firstAttempt = start
+		}
+	}
+	if err != nil {
+		s.lastError = err.Error()
+		s.lastErrorTime = now
+		s.failures++
+		return
+	}
+	s.lastSuccess = now
+	s.failures = 0
+}
+
+// Remove forgets a dependency that is no longer used.
+func (r *Registry) Remove(kind Kind, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.deps, key{kind, name})
+}
+
+// Status is the health of a dependency.
+type Status struct {
+	Kind Kind   `json:"kind"`
+	Name string `json:"name"`
+	// Healthy is true if the last call succeeded.
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures,omitempty"`
+	LastAttempt         time.Time  `json:"last_attempt"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorTime       *time.Time `json:"last_error_time,omitempty"`
+	// LatencySeconds is the duration of the last call.
+	LatencySeconds float64 `json:"latency_seconds"`
+	// StalenessSeconds is the time since the last successful call, or since the first call if none succeeded.
+	StalenessSeconds float64 `json:"staleness_seconds"`
+}
+
+// Statuses returns the health of the dependencies, the unhealthy ones first.
+func (r *Registry) Statuses() []Status {
+	now := time.Now()
+	r.mu.Lock()
+	out := make([]Status, 0, len(r.deps))
+	for k, s := range r.deps {
+		st := Status{
+			Kind:                k.kind,
+			Name:                k.name,
+			Healthy:             s.failures == 0,
+			ConsecutiveFailures: s.failures,
+			LastAttempt:         s.lastAttempt,
+			LastError:           s.lastError,
+			LatencySeconds:      s.latency.Seconds(),
+		}
+		if !s.lastSuccess.IsZero() {
+			lastSuccess := s.lastSuccess
+			st.LastSuccess = &lastSuccess
+			st.StalenessSeconds = now.Sub(lastSuccess).Seconds()
+		} else {
+			st.StalenessSeconds = now.Sub(s.firstAttempt).Seconds()
+		}
+		if !s.lastErrorTime.IsZero() {
+			lastErrorTime := s.lastErrorTime
+			st.LastErrorTime = &lastErrorTime
+		}
+		out = append(out, st)
+	}
+	r.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Healthy != out[j].Healthy {
+			return !out[i].Healthy
+		}
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,55 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependency
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	start := time.Now().Add(-time.Second)
+	r.Report(JWKS, "https://example.com/jwks", start, nil)
+	r.Report(RemoteCluster, "remote", start, errors.New("connection refused"))
+	r.Report(RemoteCluster, "remote", time.Time{}, errors.New("connection refused"))
+
+	got := r.Statuses()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 dependencies, got %+v", got)
+	}
+	remote, jwks := got[0], got[1]
+	if remote.Name != "remote" || remote.Healthy || remote.ConsecutiveFailures != 2 ||
+		remote.LastError != "connection refused" || remote.LastSuccess != nil || remote.LastErrorTime == nil {
+		t.Fatalf("expected the failing cluster first, got %+v", remote)
+	}
+	if remote.LatencySeconds < 1 || remote.StalenessSeconds < 1 {
+		t.Fatalf("expected the latency of the first call to be kept and the cluster to be stale since then, got %+v", remote)
+	}
+	if jwks.Name != "https://example.com/jwks" || !jwks.Healthy || jwks.LastSuccess == nil || jwks.StalenessSeconds >= 1 {
+		t.Fatalf("expected the JWKS endpoint to be healthy, got %+v", jwks)
+	}
+
+	r.Report(RemoteCluster, "remote", time.Now(), nil)
+	if got := r.Statuses(); !got[0].Healthy || !got[1].Healthy || got[1].LastError == "" {
+		t.Fatalf("expected the cluster to recover and keep its last error, got %+v", got)
+	}
+
+	r.Remove(RemoteCluster, "remote")
+	if got := r.Statuses(); len(got) != 1 || got[0].Kind != JWKS {
+		t.Fatalf("expected the removed cluster to be forgotten, got %+v", got)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"sort"
+
+	"github.com/miekg/dns"
+)
+
+// CacheEntry is a single host in the DNS proxy lookup table, as served to clients.
+type CacheEntry struct {
+	Host  string   `json:"host"`
+	A     []string `json:"a,omitempty"`
+	AAAA  []string `json:"aaaa,omitempty"`
+	CNAME string   `json:"cname,omitempty"`
+}
+
+// CacheDump is a point in time view of the DNS proxy lookup table, for debugging.
+type CacheDump struct {
+	Hosts []CacheEntry `json:"hosts"`
+}
+
+// DumpCache returns the current contents of the lookup table, or nil if it has not been built yet.
+func (h *LocalDNSServer) DumpCache() *CacheDump {
+	lp := h.lookupTable.Load()
+	if lp == nil {
+		return nil
+	}
+	table := lp.(*LookupTable)
+	out := &CacheDump{Hosts: make([]CacheEntry, 0, len(table.allHosts))}
+	for hostname := range table.allHosts {
+		entry := CacheEntry{Host: hostname}
+		for _, rr := range table.name4[hostname] {
+			entry.A = append(entry.A, rr.(*dns.A).A.String())
+		}
+		for _, rr := range table.name6[hostname] {
+			entry.AAAA = append(entry.AAAA, rr.(*dns.AAAA).AAAA.String())
+		}
+		if cn := table.cname[hostname]; len(cn) > 0 {
+			entry.CNAME = cn[0].(*dns.CNAME).Target
+		}
+		out.Hosts = append(out.Hosts, entry)
+	}
+	sort.Slice(out.Hosts, func(i, j int) bool {
+		return out.Hosts[i].Host < out.Hosts[j].Host
+	})
+	return out
+}
+
+// FlushCache discards the lookup table and rebuilds it from the last NameTable received from istiod.
+// It returns false if no NameTable has been received yet.
+func (h *LocalDNSServer) FlushCache() bool {
+	nt := h.NameTable()
+	if nt == nil {
+		return false
+	}
+	h.UpdateLookupTable(nt)
+	log.Infof("flushed dns proxy cache")
+	return true
+}
@@ -15,6 +15,7 @@
 package client
 
 import (
+	"math/rand"
 	"net"
 	"os"
 	"strings"
@@ -52,6 +53,9 @@ type LocalDNSServer struct {
 	proxyDomain      string
 	proxyDomainParts []string
 	addr             string
+
+	// queryLogSampleRate is the fraction, between 0 and 1, of DNS queries logged at info level.
+	queryLogSampleRate float64
 }
 
 // LookupTable is borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hostsfile.go
@@ -182,6 +186,12 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 	log.Debugf("updated lookup table with %d hosts", len(lookupTable.allHosts))
 }
 
+// SetQueryLogSampleRate sets the fraction of DNS queries that are logged, along with their
+// response code and source. A rate of 0 disables query logging; 1 logs every query.
+func (h *LocalDNSServer) SetQueryLogSampleRate(rate float64) {
+	h.queryLogSampleRate = rate
+}
+
 // upstrem sends the requeset to the upstream server, with associated logs and metrics
 func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string) *dns.Msg {
 	upstreamRequests.Increment()
@@ -209,6 +219,7 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 		response = new(dns.Msg)
 		response.SetReply(req)
 		response.Rcode = dns.RcodeServerFailure
+		h.recordResponse(proxy, "", sourceLocal, response)
 		_ = w.WriteMsg(response)
 		return
 	}
@@ -219,12 +230,14 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 		if strings.HasSuffix(h.addr, ":53") {
 			response = h.upstream(proxy, req, hostname)
 			response.Truncate(size(proxy.protocol, req))
+			h.recordResponse(proxy, hostname, sourceUpstream, response)
 			_ = w.WriteMsg(response)
 		} else {
 			log.Debugf("dns request for host %q before lookup table is loaded", hostname)
 			response = new(dns.Msg)
 			response.SetReply(req)
 			response.Rcode = dns.RcodeServerFailure
+			h.recordResponse(proxy, hostname, sourceLocal, response)
 			_ = w.WriteMsg(response)
 		}
 		return
@@ -237,6 +250,7 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 	// clients usually do not do more than one query either.
 	answers, hostFound := lookupTable.lookupHost(req.Question[0].Qtype, hostname)
 
+	source := sourceLocal
 	if hostFound {
 		response = new(dns.Msg)
 		response.SetReply(req)
@@ -253,15 +267,32 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 		roundRobinResponse(response)
 		log.Debugf("response for hostname %q (found=true): %v", hostname, response)
 	} else {
+		source = sourceUpstream
 		response = h.upstream(proxy, req, hostname)
 	}
 	// Compress the response - we don't know if the incoming response was compressed or not. If it was,
 	// but we don't compress on the outbound, we will run into issues. For example, if the compressed
 	// size is 450 bytes but uncompressed 1000 bytes now we are outside of the non-eDNS UDP size limits
 	response.Truncate(size(proxy.protocol, req))
+	h.recordResponse(proxy, hostname, source, response)
 	_ = w.WriteMsg(response)
 }
 
+// recordResponse records the response metrics and, if sampled, logs the query.
+func (h *LocalDNSServer) recordResponse(proxy *dnsProxy, hostname, source string, response *dns.Msg) {
+	rcode := dns.RcodeToString[response.Rcode]
+	responses.With(sourceTag.Value(source), rcodeTag.Value(rcode)).Increment()
+	if h.queryLogSampleRate <= 0 || rand.Float64() >= h.queryLogSampleRate {
+		return
+	}
+	qtype := ""
+	if len(response.Question) > 0 {
+		qtype = dns.TypeToString[response.Question[0].Qtype]
+	}
+	log.WithLabels("protocol", proxy.protocol, "type", qtype, "rcode", rcode, "source", source, "answers", len(response.Answer)).
+		Infof("dns query for %q", hostname)
+}
+
 // IsReady returns true if DNS lookup table is updated atleast once.
 func (h *LocalDNSServer) IsReady() bool {
 	return h.lookupTable.Load() != nil
@@ -348,9 +379,11 @@ func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg,
 	for _, upstream := range h.resolvConfServers {
 		cResponse, _, err := upstreamClient.Exchange(req, upstream)
 		if err == nil {
+			upstreamServerRequests.With(upstreamTag.Value(upstream), rcodeTag.Value(dns.RcodeToString[cResponse.Rcode])).Increment()
 			response = cResponse
 			break
 		} else {
+			upstreamServerRequests.With(upstreamTag.Value(upstream), rcodeTag.Value(rcodeError)).Increment()
 			scope.Infof("upstream failure: %v", err)
 		}
 	}
@@ -383,6 +416,13 @@ func generateAltHosts(hostname string, nameinfo *dnsProto.NameTable_NameInfo, pr
 	proxyDomainParts []string) map[string]struct{} {
 	out := make(map[string]struct{})
 	out[hostname+"."] = struct{}{}
+	// istiod leaves out the short name when the proxy only resolves fully qualified names.
+	if nameinfo.Shortname == "" {
+		for _, altHost := range nameinfo.AltHosts {
+			out[altHost+"."] = struct{}{}
+		}
+		return out
+	}
 	// do not generate alt hostnames if the service is in a different domain (i.e. cluster) than the proxy
 	// as we have no way to resolve conflicts on name.namespace entries across clusters of different domains
 	if proxyDomain == "" || !strings.HasSuffix(hostname, proxyDomain) {
@@ -513,3 +513,29 @@ func equalsDNSrecords(got []dns.RR, want []dns.RR) bool {
 	}
 	return reflect.DeepEqual(got, want)
 }
+
+func TestDumpAndFlushCache(t *testing.T) {
+	d := initDNS(t)
+	dump := d.DumpCache()
+	if dump == nil {
+		t.Fatal("expected cache dump")
+	}
+	found := false
+	for _, e := range dump.Hosts {
+		if e.Host == "productpage.ns1.svc.cluster.local." {
+			found = true
+			if !reflect.DeepEqual(e.A, []string{"9.9.9.9"}) {
+				t.Fatalf("unexpected A records for productpage: %v", e.A)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("productpage not found in cache dump")
+	}
+	if !d.FlushCache() {
+		t.Fatal("expected flush to succeed")
+	}
+	if got := d.DumpCache(); len(got.Hosts) != len(dump.Hosts) {
+		t.Fatalf("expected %d hosts after flush, got %d", len(dump.Hosts), len(got.Hosts))
+	}
+}
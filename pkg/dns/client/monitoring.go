@@ -19,16 +19,32 @@ import (
 )
 
 var (
+	upstreamTag = monitoring.MustCreateLabel("upstream")
+	rcodeTag    = monitoring.MustCreateLabel("rcode")
+	sourceTag   = monitoring.MustCreateLabel("source")
+
 	requests = monitoring.NewSum(
 		"dns_requests_total",
 		"Total number of DNS requests.",
 	)
 
+	responses = monitoring.NewSum(
+		"dns_responses_total",
+		"Total number of DNS responses, by result code and whether they were served locally or by upstream.",
+		monitoring.WithLabels(sourceTag, rcodeTag),
+	)
+
 	upstreamRequests = monitoring.NewSum(
 		"dns_upstream_requests_total",
 		"Total number of DNS requests forwarded to upstream.",
 	)
 
+	upstreamServerRequests = monitoring.NewSum(
+		"dns_upstream_server_requests_total",
+		"Total number of DNS requests sent to each upstream server, by result code.",
+		monitoring.WithLabels(upstreamTag, rcodeTag),
+	)
+
 	failures = monitoring.NewSum(
 		"dns_upstream_failures_total",
 		"Total number of DNS requests forwarded to upstream.",
@@ -41,9 +57,19 @@ var (
 	)
 )
 
+const (
+	sourceLocal    = "local"
+	sourceUpstream = "upstream"
+
+	// rcodeError is reported for upstream exchanges that failed without a DNS response.
+	rcodeError = "error"
+)
+
 func registerStats() {
 	monitoring.MustRegister(requests)
+	monitoring.MustRegister(responses)
 	monitoring.MustRegister(upstreamRequests)
+	monitoring.MustRegister(upstreamServerRequests)
 	monitoring.MustRegister(failures)
 	monitoring.MustRegister(requestDuration)
 }
@@ -16,10 +16,12 @@ package server
 
 import (
 	"net"
+	"sort"
 	"strings"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/constants"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 )
@@ -33,9 +35,17 @@ type Config struct {
 	// same-network endpoints in any cluster.
 	MulticlusterHeadlessEnabled bool
 
+	// RemoteServicesViaGateway if true, a service that only exists in clusters on other networks will resolve
+	// to the network gateways of those clusters, rather than to a cluster IP that is not reachable from the proxy.
+	RemoteServicesViaGateway bool
+
 	// AltServiceDomainSuffixes provides a list of alternate domain suffixes (e.g. 'clusterset.local') used
 	// for generating alternate hosts for each service. Applies only to Kubernetes services.
 	AltServiceDomainSuffixes []string
+
+	// FQDNOnly if true, Kubernetes services are only resolvable by their fully qualified name. The short name
+	// and namespace are left out of the table so the agent does not expand them into search domain variants.
+	FQDNOnly bool
 }
 
 // BuildNameTable produces a table of hostnames and their associated IPs that can then
@@ -77,10 +87,12 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 						shortName := instance.Endpoint.HostName + "." + instance.Endpoint.SubDomain
 						host := shortName + "." + parts[1] // Add cluster domain.
 						nameInfo := &dnsProto.NameTable_NameInfo{
-							Ips:       address,
-							Registry:  string(svc.Attributes.ServiceRegistry),
-							Namespace: svc.Attributes.Namespace,
-							Shortname: shortName,
+							Ips:      address,
+							Registry: string(svc.Attributes.ServiceRegistry),
+						}
+						if !cfg.FQDNOnly {
+							nameInfo.Namespace = svc.Attributes.Namespace
+							nameInfo.Shortname = shortName
 						}
 
 						if _, f := out.Table[host]; !f || sameCluster {
@@ -119,7 +131,12 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 			if addr := net.ParseIP(svcAddress); addr == nil {
 				continue
 			}
-			addressList = append(addressList, svcAddress)
+			if cfg.RemoteServicesViaGateway {
+				addressList = remoteNetworkGateways(cfg.Node, cfg.Push, svc)
+			}
+			if len(addressList) == 0 {
+				addressList = append(addressList, svcAddress)
+			}
 		}
 
 		nameInfo := &dnsProto.NameTable_NameInfo{
@@ -129,8 +146,10 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 		if svc.Attributes.ServiceRegistry == provider.Kubernetes {
 			// The agent will take care of resolving a, a.ns, a.ns.svc, etc.
 			// No need to provide a DNS entry for each variant.
-			nameInfo.Namespace = svc.Attributes.Namespace
-			nameInfo.Shortname = svc.Attributes.Name
+			if !cfg.FQDNOnly {
+				nameInfo.Namespace = svc.Attributes.Namespace
+				nameInfo.Shortname = svc.Attributes.Name
+			}
 
 			// Generate hostnames for any alt domain suffixes for the service.
 			for _, domain := range cfg.AltServiceDomainSuffixes {
@@ -142,3 +161,43 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 	}
 	return out
 }
+
+// remoteNetworkGateways returns the network gateway addresses to use for a service that has no cluster IP in the
+// proxy's cluster, and is only present in clusters on other networks. If the service is present in the proxy's
+// cluster or network, or the network of any of its clusters is unknown, nil is returned.
+func remoteNetworkGateways(node *model.Proxy, push *model.PushContext, svc *model.Service) []string {
+	mgr := push.NetworkManager()
+	if mgr == nil || node.Metadata == nil || node.Metadata.ClusterID == "" {
+		return nil
+	}
+	svc.Mutex.RLock()
+	clusters := make([]cluster.ID, 0, len(svc.ClusterVIPs))
+	for c := range svc.ClusterVIPs {
+		clusters = append(clusters, c)
+	}
+	svc.Mutex.RUnlock()
+
+	var out []string
+	seen := map[string]struct{}{}
+	for _, c := range clusters {
+		if node.InCluster(c) {
+			return nil
+		}
+		gateways := mgr.GatewaysForCluster(c)
+		if len(gateways) == 0 {
+			return nil
+		}
+		for _, gw := range gateways {
+			if node.InNetwork(gw.Network) {
+				// The service is reachable directly on the proxy's own network.
+				return nil
+			}
+			if _, f := seen[gw.Addr]; !f {
+				seen[gw.Addr] = struct{}{}
+				out = append(out, gw.Addr)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
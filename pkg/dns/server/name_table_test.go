@@ -175,6 +175,7 @@ func TestNameTable(t *testing.T) {
 		push                       *model.PushContext
 		enableMultiClusterHeadless bool
 		altServiceDomains          []string
+		fqdnOnly                   bool
 		expectedNameTable          *dnsProto.NameTable
 	}{
 		{
@@ -392,6 +393,38 @@ func TestNameTable(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:              "fqdn only",
+			proxy:             proxy,
+			push:              push,
+			fqdnOnly:          true,
+			altServiceDomains: []string{"clusterset.local"},
+			expectedNameTable: &dnsProto.NameTable{
+				Table: map[string]*dnsProto.NameTable_NameInfo{
+					"pod1.headless-svc.testns.svc.cluster.local": {
+						Ips:      []string{"1.2.3.4"},
+						Registry: "Kubernetes",
+					},
+					"pod2.headless-svc.testns.svc.cluster.local": {
+						Ips:      []string{"9.6.7.8"},
+						Registry: "Kubernetes",
+					},
+					"pod3.headless-svc.testns.svc.cluster.local": {
+						Ips:      []string{"19.6.7.8"},
+						Registry: "Kubernetes",
+					},
+					"pod4.headless-svc.testns.svc.cluster.local": {
+						Ips:      []string{"9.16.7.8"},
+						Registry: "Kubernetes",
+					},
+					"headless-svc.testns.svc.cluster.local": {
+						Ips:      []string{"1.2.3.4", "9.6.7.8", "19.6.7.8", "9.16.7.8"},
+						Registry: "Kubernetes",
+						AltHosts: []string{"headless-svc.testns.svc.clusterset.local"},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -400,6 +433,7 @@ func TestNameTable(t *testing.T) {
 				Push:                        tt.push,
 				MulticlusterHeadlessEnabled: tt.enableMultiClusterHeadless,
 				AltServiceDomainSuffixes:    tt.altServiceDomains,
+				FQDNOnly:                    tt.fqdnOnly,
 			}), tt.expectedNameTable); diff != "" {
 				t.Fatalf("got diff: %v", diff)
 			}
@@ -0,0 +1,146 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fips detects the FIPS deployments and checks the compliance of the cryptographic settings generated for
+// them: TLS versions, cipher suites, curves and the keys of the certificates issued by the CA.
+package fips
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"sort"
+	"sync"
+
+	"istio.io/pkg/env"
+)
+
+// Mode, if true, enables FIPS mode in binaries built without BoringCrypto.
+var Mode = env.RegisterBoolVar("FIPS_MODE", false,
+	"If true, the cryptographic settings are restricted to the FIPS 140-2 approved ones: TLS 1.2, AES-GCM ECDHE "+
+		"cipher suites, P-256 and P-384 curves, and RSA keys of at least 2048 bits or ECDSA P-256 and P-384 keys "+
+		"for the certificates issued by the CA. Always enabled in binaries built with the boringcrypto tag.").Get()
+
+// MinRSAKeySize is the smallest RSA key size approved.
+const MinRSAKeySize = 2048
+
+// CipherSuites are the approved cipher suites, by order of preference.
+var CipherSuites = []string{
+	"ECDHE-ECDSA-AES128-GCM-SHA256",
+	"ECDHE-RSA-AES128-GCM-SHA256",
+	"ECDHE-ECDSA-AES256-GCM-SHA384",
+	"ECDHE-RSA-AES256-GCM-SHA384",
+}
+
+// Curves are the approved ECDH curves, by order of preference.
+var Curves = []string{"P-256", "P-384"}
+
+// Enabled returns true if the cryptographic settings must be FIPS compliant.
+func Enabled() bool {
+	return boringCrypto || Mode
+}
+
+// BoringCrypto returns true if the binary is built with the BoringCrypto FIPS module.
+func BoringCrypto() bool {
+	return boringCrypto
+}
+
+// CompliantCipherSuite returns true if the cipher suite is approved.
+func CompliantCipherSuite(name string) bool {
+	return contains(CipherSuites, name)
+}
+
+// CompliantCurve returns true if the ECDH curve is approved.
+func CompliantCurve(name string) bool {
+	return contains(Curves, name)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPublicKey returns an error if the key of a certificate is not approved: only RSA keys of at least 2048 bits
+// and ECDSA P-256 and P-384 keys are.
+func CheckPublicKey(pub crypto.PublicKey) error {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < MinRSAKeySize {
+			return fmt.Errorf("RSA key of %d bits is smaller than the FIPS minimum of %d", k.N.BitLen(), MinRSAKeySize)
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() && k.Curve != elliptic.P384() {
+			return fmt.Errorf("ECDSA key on curve %s is not FIPS approved", k.Curve.Params().Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("key of type %T is not FIPS approved", pub)
+	}
+}
+
+// Violation is a non-compliant setting refused, and how many times it was.
+type Violation struct {
+	// Kind is the kind of setting, such as cipher suite or key.
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// maxViolations bounds the number of distinct violations recorded.
+const maxViolations = 1000
+
+var (
+	violationsMu sync.Mutex
+	violations   = map[string]*Violation{}
+)
+
+// RecordViolation records that a non-compliant setting was refused.
+func RecordViolation(kind, value, reason string) {
+	key := kind + "/" + value
+	violationsMu.Lock()
+	defer violationsMu.Unlock()
+	if v, f := violations[key]; f {
+		v.Count++
+		return
+	}
+	if len(violations) >= maxViolations {
+		return
+	}
+	violations[key] = &Violation{Kind: kind, Value: value, Reason: reason, Count: 1}
+}
+
+// Violations returns the refused settings, sorted by kind and value.
+func Violations() []Violation {
+	violationsMu.Lock()
+	defer violationsMu.Unlock()
+	out := make([]Violation, 0, len(violations))
+	for _, v := range violations {
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
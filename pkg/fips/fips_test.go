@@ -0,0 +1,81 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fips
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+func TestCheckPublicKey(t *testing.T) {
+	rsaKey := func(bits int) *rsa.PublicKey {
+		k, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &k.PublicKey
+	}
+	ecKey := func(c elliptic.Curve) *ecdsa.PublicKey {
+		k, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &k.PublicKey
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name      string
+		key       interface{}
+		compliant bool
+	}{
+		{"RSA 2048", rsaKey(2048), true},
+		{"RSA 1024", rsaKey(1024), false},
+		{"ECDSA P-256", ecKey(elliptic.P256()), true},
+		{"ECDSA P-384", ecKey(elliptic.P384()), true},
+		{"ECDSA P-224", ecKey(elliptic.P224()), false},
+		{"Ed25519", edKey, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPublicKey(tt.key)
+			if tt.compliant && err != nil {
+				t.Fatalf("expected the key to be compliant, got %v", err)
+			}
+			if !tt.compliant && err == nil {
+				t.Fatalf("expected the key to be refused")
+			}
+		})
+	}
+}
+
+func TestRecordViolation(t *testing.T) {
+	RecordViolation("cipher suite", "AES128-SHA", "not FIPS approved")
+	RecordViolation("cipher suite", "AES128-SHA", "not FIPS approved")
+	RecordViolation("ECDH curve", "X25519", "not FIPS approved")
+	got := Violations()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 violations, got %v", got)
+	}
+	if got[0].Kind != "ECDH curve" || got[1].Value != "AES128-SHA" || got[1].Count != 2 {
+		t.Fatalf("unexpected violations %v", got)
+	}
+}
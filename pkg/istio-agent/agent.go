@@ -34,6 +34,7 @@ import (
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/gogo/protobuf/types"
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/google/uuid"
 
 	mesh "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/cmd/pilot-agent/config"
@@ -115,6 +116,10 @@ type Agent struct {
 	// local DNS Server that processes DNS requests locally and forwards to upstream DNS if needed.
 	localDNSServer *dnsClient.LocalDNSServer
 
+	// instanceID uniquely identifies this agent process. It is sent in the node metadata so that
+	// Istiod can recognize reconnects of the same proxy instance.
+	instanceID string
+
 	// Signals true completion (e.g. with delayed graceful termination of Envoy)
 	wg sync.WaitGroup
 }
@@ -136,6 +141,8 @@ type AgentOptions struct {
 	DNSCapture bool
 	// DNSAddr is the DNS capture address
 	DNSAddr string
+	// DNSQueryLogSampleRate is the fraction of DNS queries handled by the DNS proxy that are logged.
+	DNSQueryLogSampleRate float64
 	// ProxyType is the type of proxy we are configured to handle
 	ProxyType model.NodeType
 	// ProxyNamespace to use for local dns resolution
@@ -166,6 +173,26 @@ type AgentOptions struct {
 	// Ability to retrieve ProxyConfig dynamically through XDS
 	EnableDynamicProxyConfig bool
 
+	// EnableConfigWatermark if true, the agent subscribes to the config watermark pushed by istiod and
+	// exposes it as a metric, to track data plane config freshness.
+	EnableConfigWatermark bool
+
+	// EnableXDSResumption if true, the agent subscribes to the resumption tokens issued by istiod and
+	// presents the latest one when reconnecting, so unchanged config is not sent again.
+	EnableXDSResumption bool
+
+	// XDSFailoverAddresses are the discovery addresses, by order of preference, the XDS proxy connects to when
+	// the discovery address of the proxy config is unhealthy.
+	XDSFailoverAddresses []string
+
+	// XDSHealthCheckInterval is the interval at which the health of the discovery addresses is checked, when
+	// XDSFailoverAddresses are set.
+	XDSHealthCheckInterval time.Duration
+
+	// XDSCacheTTL, if set, is how long the XDS proxy keeps serving Envoy the last config it acked while istiod
+	// is unreachable.
+	XDSCacheTTL time.Duration
+
 	// All of the proxy's IP Addresses
 	ProxyIPAddresses []string
 
@@ -188,6 +215,10 @@ type AgentOptions struct {
 
 	// Disables all envoy agent features
 	DisableEnvoy bool
+
+	// NativeSidecar is true if the proxy runs as a native sidecar. Kubernetes then only stops it once the
+	// application containers have exited, so there is no traffic left to drain on termination.
+	NativeSidecar bool
 }
 
 // NewAgent hosts the functionality for local SDS and XDS. This consists of the local SDS server and
@@ -200,6 +231,7 @@ func NewAgent(proxyConfig *mesh.ProxyConfig, agentOpts *AgentOptions, sopts *sec
 		cfg:         agentOpts,
 		secOpts:     sopts,
 		envoyOpts:   eopts,
+		instanceID:  uuid.New().String(),
 	}
 }
 
@@ -245,6 +277,7 @@ func (a *Agent) generateNodeMetadata() (*model.Node, error) {
 		ProvCert:            provCert,
 		EnvoyPrometheusPort: a.cfg.EnvoyPrometheusPort,
 		EnvoyStatusPort:     a.cfg.EnvoyStatusPort,
+		InstanceID:          a.instanceID,
 	})
 }
 
@@ -288,6 +321,9 @@ func (a *Agent) initializeEnvoyAgent(ctx context.Context) error {
 	envoyProxy := envoy.NewProxy(a.envoyOpts)
 
 	drainDuration, _ := types.DurationFromProto(a.proxyConfig.TerminationDrainDuration)
+	if a.cfg.NativeSidecar {
+		drainDuration = 0
+	}
 	a.envoyAgent = envoy.NewAgent(envoyProxy, drainDuration)
 	a.envoyWaitCh = make(chan error, 1)
 	if a.cfg.EnableDynamicBootstrap {
@@ -417,6 +453,12 @@ func (a *Agent) Run(ctx context.Context) (func(), error) {
 				return nil, fmt.Errorf("failed to start istio tap server: %v", err)
 			}
 		}
+		go func() {
+			// Envoy keeps serving for the drain duration after termination starts. Ask istiod to remove
+			// our endpoints right away, rather than when the platform notices the workload is going away.
+			<-ctx.Done()
+			a.xdsProxy.notifyDraining()
+		}()
 	}
 
 	if a.cfg.GRPCBootstrapPath != "" {
@@ -474,6 +516,7 @@ func (a *Agent) initLocalDNSServer() (err error) {
 		if a.localDNSServer, err = dnsClient.NewLocalDNSServer(a.cfg.ProxyNamespace, a.cfg.ProxyDomain, a.cfg.DNSAddr); err != nil {
 			return err
 		}
+		a.localDNSServer.SetQueryLogSampleRate(a.cfg.DNSQueryLogSampleRate)
 		a.localDNSServer.StartDNS()
 	}
 	return nil
@@ -509,6 +552,25 @@ func (a *Agent) Check() (err error) {
 	return nil
 }
 
+// CheckWorkloadCert returns an error if the workload certificate has not been issued yet or has expired.
+// File mounted certificates are not checked.
+func (a *Agent) CheckWorkloadCert() error {
+	if a.secOpts.FileMountedCerts {
+		return nil
+	}
+	if a.secretCache == nil {
+		return errors.New("secret manager is not started")
+	}
+	expiry, f := a.secretCache.WorkloadCertExpiry()
+	if !f {
+		return errors.New("workload certificate has not been issued yet")
+	}
+	if time.Now().After(expiry) {
+		return fmt.Errorf("workload certificate expired at %v", expiry)
+	}
+	return nil
+}
+
 func (a *Agent) GetDNSTable() *dnsProto.NameTable {
 	if a.localDNSServer != nil {
 		return a.localDNSServer.NameTable()
@@ -516,6 +578,22 @@ func (a *Agent) GetDNSTable() *dnsProto.NameTable {
 	return nil
 }
 
+// GetDNSCache returns the current contents of the DNS proxy lookup table, or nil if DNS capture is disabled.
+func (a *Agent) GetDNSCache() *dnsClient.CacheDump {
+	if a.localDNSServer != nil {
+		return a.localDNSServer.DumpCache()
+	}
+	return nil
+}
+
+// FlushDNSCache rebuilds the DNS proxy lookup table from the last received NameTable.
+func (a *Agent) FlushDNSCache() bool {
+	if a.localDNSServer != nil {
+		return a.localDNSServer.FlushCache()
+	}
+	return false
+}
+
 func (a *Agent) Close() {
 	if a.xdsProxy != nil {
 		a.xdsProxy.close()
@@ -55,6 +55,18 @@ var (
 		"The total number of Xds Proxy Responses",
 	)
 
+	// ConfigWatermarkTimestamp records when the push that produced the proxy's current config was triggered.
+	ConfigWatermarkTimestamp = monitoring.NewGauge(
+		"config_watermark_timestamp_seconds",
+		"Unix time at which istiod triggered the push that produced the current proxy config",
+	)
+
+	// XdsProxyServingFromCache is 1 while the XDS proxy serves Envoy the cached config, istiod being unreachable.
+	XdsProxyServingFromCache = monitoring.NewGauge(
+		"xds_proxy_serving_from_cache",
+		"Whether the Xds Proxy serves Envoy the cached config, as istiod is unreachable",
+	)
+
 	IstiodConnectionCancellations = istiodDisconnections.With(disconnectionTypeTag.Value(Cancel))
 	IstiodConnectionErrors        = istiodDisconnections.With(disconnectionTypeTag.Value(Error))
 	EnvoyConnectionCancellations  = envoyDisconnections.With(disconnectionTypeTag.Value(Cancel))
@@ -72,5 +84,7 @@ func init() {
 		IstiodConnectionErrors,
 		istiodDisconnections,
 		envoyDisconnections,
+		ConfigWatermarkTimestamp,
+		XdsProxyServingFromCache,
 	)
 }
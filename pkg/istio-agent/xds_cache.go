@@ -0,0 +1,145 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"sync"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/istio-agent/metrics"
+)
+
+// offlineRetryInterval is how long Envoy is served the cached config before the upstream connection is retried.
+var offlineRetryInterval = 10 * time.Second
+
+// xdsCache keeps the last responses Envoy acked, by type, so Envoy can be served its config while istiod is
+// unreachable, including after Envoy restarts. A nil cache is disabled.
+type xdsCache struct {
+	ttl time.Duration
+
+	mu sync.Mutex
+	// sent are the responses forwarded to Envoy which are not acked yet.
+	sent map[string]cachedResponse
+	// acked are the last responses Envoy acked.
+	acked map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	resp     *discovery.DiscoveryResponse
+	received time.Time
+}
+
+func newXdsCache(ttl time.Duration) *xdsCache {
+	return &xdsCache{
+		ttl:   ttl,
+		sent:  map[string]cachedResponse{},
+		acked: map[string]cachedResponse{},
+	}
+}
+
+// sentToEnvoy records a response from istiod forwarded to Envoy, to be cached once Envoy acks it.
+func (c *xdsCache) sentToEnvoy(resp *discovery.DiscoveryResponse) {
+	if c == nil || !v3.IsEnvoyType(resp.TypeUrl) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent[resp.TypeUrl] = cachedResponse{resp: resp, received: time.Now()}
+}
+
+// observeRequest caches the response the request of Envoy acks, or drops it if the request is a NACK.
+func (c *xdsCache) observeRequest(req *discovery.DiscoveryRequest) {
+	if c == nil || req.ResponseNonce == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending, f := c.sent[req.TypeUrl]
+	if !f || pending.resp.Nonce != req.ResponseNonce {
+		return
+	}
+	delete(c.sent, req.TypeUrl)
+	if req.ErrorDetail == nil {
+		c.acked[req.TypeUrl] = pending
+	}
+}
+
+// get returns the cached response of the type, unless it is older than the TTL.
+func (c *xdsCache) get(typeURL string) *discovery.DiscoveryResponse {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, f := c.acked[typeURL]
+	if !f || time.Since(cached.received) > c.ttl {
+		return nil
+	}
+	return cached.resp
+}
+
+// usable returns true if any cached response is within the TTL.
+func (c *xdsCache) usable() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cached := range c.acked {
+		if time.Since(cached.received) <= c.ttl {
+			return true
+		}
+	}
+	return false
+}
+
+// serveFromCache answers the requests of Envoy with the cached config while istiod is unreachable. Each type is
+// sent once per stream, as the cached config does not change. It returns upstreamErr once the upstream
+// connection should be retried, which terminates the stream; Envoy then reconnects and the agent dials istiod again.
+func (p *XdsProxy) serveFromCache(con *ProxyConnection, upstreamErr error) error {
+	proxyLog.Warnf("istiod is unreachable, serving the cached config to Envoy: %v", upstreamErr)
+	metrics.XdsProxyServingFromCache.Record(1)
+	defer metrics.XdsProxyServingFromCache.Record(0)
+
+	served := map[string]bool{}
+	retryTimer := time.NewTimer(offlineRetryInterval)
+	defer retryTimer.Stop()
+	for {
+		select {
+		case req := <-con.requestsChan:
+			if served[req.TypeUrl] {
+				continue
+			}
+			resp := p.cache.get(req.TypeUrl)
+			if resp == nil {
+				continue
+			}
+			served[req.TypeUrl] = true
+			if err := sendDownstream(con.downstream, resp); err != nil {
+				proxyLog.Errorf("downstream [%d] send error: %v", con.conID, err)
+				return err
+			}
+		case err := <-con.downstreamError:
+			return err
+		case <-retryTimer.C:
+			return upstreamErr
+		case <-con.stopChan:
+			return nil
+		}
+	}
+}
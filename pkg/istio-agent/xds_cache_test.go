@@ -0,0 +1,125 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestXdsCache(t *testing.T) {
+	var disabled *xdsCache
+	disabled.sentToEnvoy(&discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, Nonce: "1"})
+	if disabled.usable() || disabled.get(v3.ClusterType) != nil {
+		t.Fatalf("expected a nil cache to be disabled")
+	}
+
+	c := newXdsCache(time.Hour)
+	first := &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, VersionInfo: "1", Nonce: "a"}
+	c.sentToEnvoy(first)
+	c.sentToEnvoy(&discovery.DiscoveryResponse{TypeUrl: v3.NameTableType, Nonce: "b"})
+	if c.usable() {
+		t.Fatalf("expected responses to be cached only once acked")
+	}
+	c.observeRequest(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, VersionInfo: "1", ResponseNonce: "a"})
+	if got := c.get(v3.ClusterType); got != first {
+		t.Fatalf("expected the acked response to be cached, got %v", got)
+	}
+	if !c.usable() {
+		t.Fatalf("expected the cache to be usable")
+	}
+
+	// A NACK keeps the previous response.
+	c.sentToEnvoy(&discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, VersionInfo: "2", Nonce: "c"})
+	c.observeRequest(&discovery.DiscoveryRequest{
+		TypeUrl:       v3.ClusterType,
+		VersionInfo:   "1",
+		ResponseNonce: "c",
+		ErrorDetail:   &google_rpc.Status{Message: "rejected"},
+	})
+	if got := c.get(v3.ClusterType); got != first {
+		t.Fatalf("expected the NACKed response not to be cached, got %v", got)
+	}
+
+	// Responses older than the TTL are not served.
+	c.ttl = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if c.usable() || c.get(v3.ClusterType) != nil {
+		t.Fatalf("expected stale responses not to be served")
+	}
+}
+
+type fakeDownstream struct {
+	ctx  context.Context
+	sent chan *discovery.DiscoveryResponse
+}
+
+func (f *fakeDownstream) Send(resp *discovery.DiscoveryResponse) error {
+	f.sent <- resp
+	return nil
+}
+
+func (f *fakeDownstream) Recv() (*discovery.DiscoveryRequest, error) {
+	<-f.ctx.Done()
+	return nil, f.ctx.Err()
+}
+
+func (f *fakeDownstream) Context() context.Context {
+	return f.ctx
+}
+
+func TestServeFromCache(t *testing.T) {
+	retryInterval := offlineRetryInterval
+	offlineRetryInterval = 100 * time.Millisecond
+	defer func() { offlineRetryInterval = retryInterval }()
+
+	p := &XdsProxy{cache: newXdsCache(time.Hour)}
+	cds := &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, VersionInfo: "1", Nonce: "a"}
+	p.cache.sentToEnvoy(cds)
+	p.cache.observeRequest(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	downstream := &fakeDownstream{ctx: ctx, sent: make(chan *discovery.DiscoveryResponse, 10)}
+	con := &ProxyConnection{
+		downstreamError: make(chan error, 2),
+		requestsChan:    make(chan *discovery.DiscoveryRequest, 10),
+		stopChan:        make(chan struct{}),
+		downstream:      downstream,
+	}
+	con.requestsChan <- &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType}
+	con.requestsChan <- &discovery.DiscoveryRequest{TypeUrl: v3.ListenerType}
+	con.requestsChan <- &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "a"}
+
+	upstreamErr := errors.New("istiod unreachable")
+	if err := p.serveFromCache(con, upstreamErr); err != upstreamErr {
+		t.Fatalf("expected the upstream error once the connection should be retried, got %v", err)
+	}
+	close(downstream.sent)
+	var got []*discovery.DiscoveryResponse
+	for resp := range downstream.sent {
+		got = append(got, resp)
+	}
+	if len(got) != 1 || got[0] != cds {
+		t.Fatalf("expected the cached clusters to be sent once, got %v", got)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// defaultHealthCheckInterval is the interval at which the discovery addresses are checked, if not configured.
+const defaultHealthCheckInterval = 10 * time.Second
+
+// healthCheckTimeout bounds the time to connect to a discovery address when checking its health.
+const healthCheckTimeout = 5 * time.Second
+
+// upstreamFailover selects the istiod the XDS proxy connects to among an ordered list of discovery addresses,
+// for instance the istiod of the cluster and a regional one: the first healthy address. The addresses are
+// healthy when a connection can be established to them. When a preferred address becomes healthy again, the
+// proxy fails back to it.
+type upstreamFailover struct {
+	// addresses are the discovery addresses, by order of preference. The first one is the one of the proxy config.
+	addresses []string
+	// dialOptions are the options to dial the addresses other than the first one, which uses the ones of the proxy.
+	dialOptions map[string][]grpc.DialOption
+	interval    time.Duration
+	// reconnect closes the upstream connection, if it is to a less preferred address than the one returned.
+	reconnect func(preferred string)
+
+	mu      sync.RWMutex
+	healthy map[string]bool
+}
+
+func newUpstreamFailover(p *XdsProxy, ia *Agent) (*upstreamFailover, error) {
+	f := &upstreamFailover{
+		addresses:   append([]string{p.istiodAddress}, ia.cfg.XDSFailoverAddresses...),
+		dialOptions: map[string][]grpc.DialOption{},
+		interval:    ia.cfg.XDSHealthCheckInterval,
+		reconnect:   p.failBack,
+		healthy:     map[string]bool{},
+	}
+	for _, address := range ia.cfg.XDSFailoverAddresses {
+		opts, err := p.buildUpstreamClientDialOpts(ia, address)
+		if err != nil {
+			return nil, err
+		}
+		f.dialOptions[address] = opts
+	}
+	if f.interval <= 0 {
+		f.interval = defaultHealthCheckInterval
+	}
+	// Until checked, the addresses are assumed healthy, so that the proxy first connects to the preferred one.
+	for _, address := range f.addresses {
+		f.healthy[address] = true
+	}
+	proxyLog.Infof("Initializing XDS failover across %v", f.addresses)
+	return f, nil
+}
+
+// preferred returns the first healthy address, or the first address if none is healthy.
+func (f *upstreamFailover) preferred() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, address := range f.addresses {
+		if f.healthy[address] {
+			return address
+		}
+	}
+	return f.addresses[0]
+}
+
+// setHealthy records the health of an address.
+func (f *upstreamFailover) setHealthy(address string, healthy bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, known := f.healthy[address]; !known {
+		return
+	}
+	if f.healthy[address] != healthy {
+		proxyLog.Infof("discovery address %s is healthy: %v", address, healthy)
+	}
+	f.healthy[address] = healthy
+}
+
+// run checks the health of the addresses at each interval until stop is closed, failing back to the preferred
+// healthy address.
+func (f *upstreamFailover) run(p *XdsProxy, stop <-chan struct{}) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, address := range f.addresses {
+				f.setHealthy(address, f.check(address, p.dialOptions(address)))
+			}
+			f.reconnect(f.preferred())
+		}
+	}
+}
+
+// check returns true if a connection can be established to the address.
+func (f *upstreamFailover) check(address string, opts []grpc.DialOption) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	opts = append(append([]grpc.DialOption{}, opts...), grpc.WithBlock())
+	conn, err := grpc.DialContext(ctx, address, opts...)
+	if err != nil {
+		proxyLog.Debugf("health check of discovery address %s failed: %v", address, err)
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// rank returns the order of preference of an address, or -1 if it is not one of the addresses.
+func (f *upstreamFailover) rank(address string) int {
+	for i, a := range f.addresses {
+		if a == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// failBack closes the upstream connection if it is to a less preferred discovery address than the preferred one,
+// so that Envoy reconnects and the proxy connects to the preferred address.
+func (p *XdsProxy) failBack(preferred string) {
+	p.connectedMutex.Lock()
+	defer p.connectedMutex.Unlock()
+	if p.connected == nil || p.connected.upstreamAddress == "" {
+		return
+	}
+	if p.failover.rank(p.connected.upstreamAddress) <= p.failover.rank(preferred) {
+		return
+	}
+	proxyLog.Infof("failing back from %s to %s", p.connected.upstreamAddress, preferred)
+	close(p.connected.stopChan)
+	p.connected = nil
+}
@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"testing"
+)
+
+func TestUpstreamFailover(t *testing.T) {
+	f := &upstreamFailover{
+		addresses: []string{"istiod:15012", "regional:15012", "global:15012"},
+		healthy:   map[string]bool{"istiod:15012": true, "regional:15012": true, "global:15012": true},
+	}
+	p := &XdsProxy{istiodAddress: "istiod:15012", failover: f}
+
+	if got := f.preferred(); got != "istiod:15012" {
+		t.Fatalf("expected the address of the proxy config to be preferred, got %s", got)
+	}
+	f.setHealthy("istiod:15012", false)
+	if got := f.preferred(); got != "regional:15012" {
+		t.Fatalf("expected to fail over to the next address, got %s", got)
+	}
+	f.setHealthy("regional:15012", false)
+	f.setHealthy("global:15012", false)
+	if got := f.preferred(); got != "istiod:15012" {
+		t.Fatalf("expected the first address when none is healthy, got %s", got)
+	}
+	f.setHealthy("unknown:15012", true)
+	if got := f.preferred(); got != "istiod:15012" {
+		t.Fatalf("expected unknown addresses to be ignored, got %s", got)
+	}
+
+	// Connected to the regional istiod, the proxy fails back once the one of the cluster is healthy.
+	con := &ProxyConnection{stopChan: make(chan struct{}), upstreamAddress: "regional:15012"}
+	p.connected = con
+	f.setHealthy("regional:15012", true)
+	p.failBack(f.preferred())
+	if p.connected != con {
+		t.Fatalf("expected the connection to the preferred address to be kept")
+	}
+	f.setHealthy("istiod:15012", true)
+	p.failBack(f.preferred())
+	if p.connected != nil {
+		t.Fatalf("expected the connection to a less preferred address to be closed")
+	}
+	select {
+	case <-con.stopChan:
+	default:
+		t.Fatalf("expected the connection to be stopped")
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +38,7 @@ import (
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
+	structpb "github.com/golang/protobuf/ptypes/struct"
 	"go.uber.org/atomic"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
@@ -118,6 +120,23 @@ type XdsProxy struct {
 	// in case istiod changes its behavior, or a different ECDS server is used.
 	ecdsLastAckVersion atomic.String
 	ecdsLastNonce      atomic.String
+
+	// resumptionToken is the last resumption token received from istiod.
+	resumptionToken atomic.String
+
+	// failover selects the discovery address to connect to, if failover addresses are configured.
+	failover *upstreamFailover
+
+	// affinityAddress is the address of the istiod replica istiod asked to connect to, for the next connection.
+	affinityAddress atomic.String
+	// affinityOrigin is the discovery address of the istiod which asked to connect to affinityAddress.
+	affinityOrigin atomic.String
+	// affinityUnreachable is set once an istiod replica could not be dialed directly, to stop following the
+	// affinity hints.
+	affinityUnreachable atomic.Bool
+
+	// cache keeps the config Envoy acked, to serve it while istiod is unreachable. Nil if disabled.
+	cache *xdsCache
 }
 
 var proxyLog = log.RegisterScope("xdsproxy", "XDS Proxy in Istio Agent", 0)
@@ -151,6 +170,9 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		wasmCache:      wasm.NewLocalFileCache(constants.IstioDataDir, wasm.DefaultWasmModulePurgeInterval, wasm.DefaultWasmModuleExpiry),
 		proxyAddresses: ia.cfg.ProxyIPAddresses,
 	}
+	if ia.cfg.XDSCacheTTL > 0 {
+		proxy.cache = newXdsCache(ia.cfg.XDSCacheTTL)
+	}
 
 	if ia.localDNSServer != nil {
 		proxy.handlers[v3.NameTableType] = func(resp *any.Any) error {
@@ -181,15 +203,49 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		}
 	}
 
+	if ia.cfg.EnableConfigWatermark {
+		proxy.handlers[v3.WatermarkType] = func(resp *any.Any) error {
+			var wm structpb.Struct
+			// nolint: staticcheck
+			if err := ptypes.UnmarshalAny(resp, &wm); err != nil {
+				log.Errorf("failed to unmarshall config watermark: %v", err)
+				return err
+			}
+			ts := wm.Fields["timestamp"].GetNumberValue()
+			proxyLog.Debugf("received config watermark version %q, timestamp %v", wm.Fields["version"].GetStringValue(), ts)
+			metrics.ConfigWatermarkTimestamp.Record(ts)
+			return nil
+		}
+	}
+
+	if ia.cfg.EnableXDSResumption {
+		proxy.handlers[v3.ResumptionTokenType] = func(resp *any.Any) error {
+			var token structpb.Struct
+			// nolint: staticcheck
+			if err := ptypes.UnmarshalAny(resp, &token); err != nil {
+				log.Errorf("failed to unmarshall resumption token: %v", err)
+				return err
+			}
+			proxy.resumptionToken.Store(token.Fields["token"].GetStringValue())
+			return nil
+		}
+	}
+
 	proxyLog.Infof("Initializing with upstream address %q and cluster %q", proxy.istiodAddress, proxy.clusterID)
 
 	if err = proxy.initDownstreamServer(); err != nil {
 		return nil, err
 	}
 
-	if proxy.istiodDialOptions, err = proxy.buildUpstreamClientDialOpts(ia); err != nil {
+	if proxy.istiodDialOptions, err = proxy.buildUpstreamClientDialOpts(ia, proxy.istiodAddress); err != nil {
 		return nil, err
 	}
+	if len(ia.cfg.XDSFailoverAddresses) > 0 {
+		if proxy.failover, err = newUpstreamFailover(proxy, ia); err != nil {
+			return nil, err
+		}
+		go proxy.failover.run(proxy.stopChan)
+	}
 
 	go func() {
 		if err := proxy.downstreamGrpcServer.Serve(proxy.downstreamListener); err != nil {
@@ -248,6 +304,13 @@ func (p *XdsProxy) PersistRequest(req *discovery.DiscoveryRequest) {
 	}
 }
 
+// notifyDraining tells istiod the workload is terminating, so it stops sending traffic to it while
+// Envoy drains. The request is persisted so it is repeated if the upstream connection is re-established.
+func (p *XdsProxy) notifyDraining() {
+	p.PersistRequest(&discovery.DiscoveryRequest{TypeUrl: v3.DrainingType})
+	p.PersistDeltaRequest(&discovery.DeltaDiscoveryRequest{TypeUrl: v3.DrainingType})
+}
+
 func (p *XdsProxy) UnregisterStream(c *ProxyConnection) {
 	p.connectedMutex.Lock()
 	defer p.connectedMutex.Unlock()
@@ -280,6 +343,8 @@ type ProxyConnection struct {
 	upstream           discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
 	downstreamDeltas   discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesServer
 	upstreamDeltas     discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesClient
+	// upstreamAddress is the discovery address the upstream connection was opened to.
+	upstreamAddress string
 }
 
 type adsStream interface {
@@ -335,6 +400,18 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 						TypeUrl: v3.ProxyConfigType,
 					}
 				}
+				// fire off an initial watermark request
+				if _, f := p.handlers[v3.WatermarkType]; f {
+					con.requestsChan <- &discovery.DiscoveryRequest{
+						TypeUrl: v3.WatermarkType,
+					}
+				}
+				// fire off an initial resumption token request
+				if _, f := p.handlers[v3.ResumptionTokenType]; f {
+					con.requestsChan <- &discovery.DiscoveryRequest{
+						TypeUrl: v3.ResumptionTokenType,
+					}
+				}
 				// Fire of a configured initial request, if there is one
 				p.connectedMutex.RLock()
 				initialRequest := p.initialRequest
@@ -347,21 +424,29 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 		}
 	}()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-	upstreamConn, err := grpc.DialContext(ctx, p.istiodAddress, p.istiodDialOptions...)
+	upstreamConn, address, hinted, err := p.dialUpstream()
 	if err != nil {
-		proxyLog.Errorf("failed to connect to upstream %s: %v", p.istiodAddress, err)
-		metrics.IstiodConnectionFailures.Increment()
+		if p.cache.usable() {
+			return p.serveFromCache(con, err)
+		}
 		return err
 	}
 	defer upstreamConn.Close()
+	p.connectedMutex.Lock()
+	con.upstreamAddress = address
+	p.connectedMutex.Unlock()
 
 	xds := discovery.NewAggregatedDiscoveryServiceClient(upstreamConn)
-	ctx = metadata.AppendToOutgoingContext(context.Background(), "ClusterID", p.clusterID)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "ClusterID", p.clusterID)
 	for k, v := range p.xdsHeaders {
 		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
 	}
+	if !hinted && !p.affinityUnreachable.Load() {
+		ctx = metadata.AppendToOutgoingContext(ctx, v3.AffinityHeader, "true")
+	}
+	if token := p.resumptionToken.Load(); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, v3.ResumptionTokenHeader, token)
+	}
 	// We must propagate upstream termination to Envoy. This ensures that we resume the full XDS sequence on new connection
 	return p.HandleUpstream(ctx, con, xds)
 }
@@ -372,10 +457,13 @@ func (p *XdsProxy) HandleUpstream(ctx context.Context, con *ProxyConnection, xds
 	if err != nil {
 		// Envoy logs errors again, so no need to log beyond debug level
 		proxyLog.Debugf("failed to create upstream grpc client: %v", err)
+		if p.cache.usable() {
+			return p.serveFromCache(con, err)
+		}
 		return err
 	}
-	proxyLog.Infof("connected to upstream XDS server: %s", p.istiodAddress)
-	defer proxyLog.Debugf("disconnected from XDS server: %s", p.istiodAddress)
+	proxyLog.Infof("connected to upstream XDS server: %s", con.upstreamAddress)
+	defer proxyLog.Debugf("disconnected from XDS server: %s", con.upstreamAddress)
 
 	con.upstream = upstream
 
@@ -406,6 +494,8 @@ func (p *XdsProxy) HandleUpstream(ctx context.Context, con *ProxyConnection, xds
 				proxyLog.Warnf("upstream [%d] terminated with unexpected error %v", con.conID, err)
 				metrics.IstiodConnectionErrors.Increment()
 			}
+			p.recordAffinityHint(con.upstreamAddress, upstream.Trailer())
+			waitReconnectDelay(con, upstream.Trailer())
 			return err
 		case err := <-con.downstreamError:
 			// error from downstream Envoy.
@@ -432,6 +522,7 @@ func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 		case req := <-con.requestsChan:
 			proxyLog.Debugf("request for type url %s", req.TypeUrl)
 			metrics.XdsProxyRequests.Increment()
+			p.cache.observeRequest(req)
 			if req.TypeUrl == v3.ExtensionConfigurationType {
 				if req.VersionInfo != "" {
 					p.ecdsLastAckVersion.Store(req.VersionInfo)
@@ -486,12 +577,14 @@ func (p *XdsProxy) handleUpstreamResponse(con *ProxyConnection) {
 					go p.rewriteAndForward(con, resp)
 				} else {
 					// Otherwise, forward ECDS resource update directly to Envoy.
+					p.cache.sentToEnvoy(resp)
 					forwardToEnvoy(con, resp)
 				}
 			default:
 				if strings.HasPrefix(resp.TypeUrl, "istio.io/debug") {
 					p.forwardToTap(resp)
 				} else {
+					p.cache.sentToEnvoy(resp)
 					forwardToEnvoy(con, resp)
 				}
 			}
@@ -517,6 +610,7 @@ func (p *XdsProxy) rewriteAndForward(con *ProxyConnection, resp *discovery.Disco
 		return
 	}
 	proxyLog.Debugf("forward ECDS resources %+v", resp.Resources)
+	p.cache.sentToEnvoy(resp)
 	forwardToEnvoy(con, resp)
 }
 
@@ -601,10 +695,17 @@ func (p *XdsProxy) getCertKeyPaths(agent *Agent) (string, string) {
 	return key, cert
 }
 
-func (p *XdsProxy) buildUpstreamClientDialOpts(sa *Agent) ([]grpc.DialOption, error) {
-	tlsOpts, err := p.getTLSDialOption(sa)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build TLS dial option to talk to upstream: %v", err)
+func (p *XdsProxy) buildUpstreamClientDialOpts(sa *Agent, address string) ([]grpc.DialOption, error) {
+	// A node-local istiod (or relay) reached over a unix domain socket only accepts the users of
+	// the group allowed by its socket file, so neither TLS nor a token is used on that connection.
+	unixSocket := uds.IsUnixAddress(address)
+	tlsOpts := grpc.WithInsecure()
+	if !unixSocket {
+		var err error
+		tlsOpts, err = p.getTLSDialOption(sa, address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build TLS dial option to talk to upstream: %v", err)
+		}
 	}
 
 	keepaliveOption := grpc.WithKeepaliveParams(keepalive.ClientParameters{
@@ -622,7 +723,7 @@ func (p *XdsProxy) buildUpstreamClientDialOpts(sa *Agent) ([]grpc.DialOption, er
 		keepaliveOption, initialWindowSizeOption, initialConnWindowSizeOption, msgSizeOption,
 	}
 
-	if !sa.secOpts.FileMountedCerts {
+	if !sa.secOpts.FileMountedCerts && !unixSocket {
 		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(caclient.NewXDSTokenProvider(sa.secOpts)))
 	}
 	return dialOptions, nil
@@ -632,7 +733,7 @@ func (p *XdsProxy) buildUpstreamClientDialOpts(sa *Agent) ([]grpc.DialOption, er
 // If provisioned cert is set, it will return a mTLS related config
 // Else it will return a one-way TLS related config with the assumption
 // that the consumer code will use tokens to authenticate the upstream.
-func (p *XdsProxy) getTLSDialOption(agent *Agent) (grpc.DialOption, error) {
+func (p *XdsProxy) getTLSDialOption(agent *Agent, address string) (grpc.DialOption, error) {
 	if agent.proxyConfig.ControlPlaneAuthPolicy == meshconfig.AuthenticationPolicy_NONE {
 		return grpc.WithInsecure(), nil
 	}
@@ -658,7 +759,7 @@ func (p *XdsProxy) getTLSDialOption(agent *Agent) (grpc.DialOption, error) {
 	}
 
 	// strip the port from the address
-	parts := strings.Split(agent.proxyConfig.DiscoveryAddress, ":")
+	parts := strings.Split(address, ":")
 	config.ServerName = parts[0]
 	// For debugging on localhost (with port forward)
 	// This matches the logic for the CA; this code should eventually be shared
@@ -699,6 +800,98 @@ func (p *XdsProxy) getRootCertificate(agent *Agent) (*x509.CertPool, error) {
 	return certPool, nil
 }
 
+// maxReconnectDelay bounds the delay istiod may ask to wait before reconnecting.
+const maxReconnectDelay = 5 * time.Minute
+
+// reconnectDelay returns the delay istiod asked to wait before reconnecting, in the trailer of a stream it closed.
+func reconnectDelay(trailer metadata.MD) time.Duration {
+	v := trailer.Get(v3.RetryPushbackTrailer)
+	if len(v) == 0 {
+		return 0
+	}
+	ms, err := strconv.ParseInt(v[0], 10, 64)
+	if err != nil || ms <= 0 {
+		return 0
+	}
+	if d := time.Duration(ms) * time.Millisecond; d < maxReconnectDelay {
+		return d
+	}
+	return maxReconnectDelay
+}
+
+// waitReconnectDelay keeps the stream of Envoy open, with its config, for the delay istiod asked to wait before
+// reconnecting, so that the proxies drained by an istiod spread their reconnections. It returns early if Envoy
+// closes the stream.
+func waitReconnectDelay(con *ProxyConnection, trailer metadata.MD) {
+	d := reconnectDelay(trailer)
+	if d == 0 {
+		return
+	}
+	proxyLog.Infof("upstream [%d] asked to reconnect in %v", con.conID, d)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-con.downstreamError:
+	case <-con.stopChan:
+	}
+}
+
+// dialUpstream connects to the preferred discovery address, or to the istiod replica it asked to connect to on the
+// previous connection. It returns the discovery address, and true if the connection is to that replica.
+func (p *XdsProxy) dialUpstream() (*grpc.ClientConn, string, bool, error) {
+	origin := p.istiodAddress
+	if p.failover != nil {
+		origin = p.failover.preferred()
+	}
+	address, hinted := origin, false
+	if a := p.affinityAddress.Load(); a != "" {
+		// The hint only applies to the next connection, to the same discovery address.
+		p.affinityAddress.Store("")
+		if p.affinityOrigin.Load() == origin {
+			address, hinted = a, true
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, address, p.dialOptions(origin)...)
+	if err != nil {
+		if hinted {
+			// The replicas may not be reachable directly, for instance from outside of the cluster.
+			proxyLog.Warnf("failed to connect to istiod replica %s, no longer following affinity hints: %v", address, err)
+			p.affinityUnreachable.Store(true)
+		} else {
+			proxyLog.Errorf("failed to connect to upstream %s: %v", address, err)
+			if p.failover != nil {
+				p.failover.setHealthy(address, false)
+			}
+		}
+		metrics.IstiodConnectionFailures.Increment()
+		return nil, "", false, err
+	}
+	return conn, origin, hinted, nil
+}
+
+// dialOptions returns the options to dial the discovery address, or the replicas it hints to connect to.
+func (p *XdsProxy) dialOptions(address string) []grpc.DialOption {
+	if p.failover != nil {
+		if opts, f := p.failover.dialOptions[address]; f {
+			return opts
+		}
+	}
+	return p.istiodDialOptions
+}
+
+// recordAffinityHint records the istiod replica istiod asked to connect to, in the trailer of a stream it closed.
+// The origin is the discovery address of the stream.
+func (p *XdsProxy) recordAffinityHint(origin string, trailer metadata.MD) {
+	if v := trailer.Get(v3.AffinityTrailer); len(v) > 0 && v[0] != "" && !p.affinityUnreachable.Load() {
+		proxyLog.Infof("upstream asked to connect to istiod replica %s", v[0])
+		p.affinityOrigin.Store(origin)
+		p.affinityAddress.Store(v[0])
+	}
+}
+
 // sendUpstream sends discovery request.
 func sendUpstream(upstream discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient,
 	request *discovery.DiscoveryRequest) error {
@@ -16,7 +16,6 @@ package istioagent
 
 import (
 	"context"
-	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
@@ -79,6 +78,12 @@ func (p *XdsProxy) DeltaAggregatedResources(downstream discovery.AggregatedDisco
 						TypeUrl: v3.ProxyConfigType,
 					}
 				}
+				// fire off an initial watermark request
+				if _, f := p.handlers[v3.WatermarkType]; f {
+					con.deltaRequestsChan <- &discovery.DeltaDiscoveryRequest{
+						TypeUrl: v3.WatermarkType,
+					}
+				}
 				// Fire of a configured initial request, if there is one
 				if initialRequest != nil {
 					con.deltaRequestsChan <- initialRequest
@@ -88,21 +93,23 @@ func (p *XdsProxy) DeltaAggregatedResources(downstream discovery.AggregatedDisco
 		}
 	}()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-	upstreamConn, err := grpc.DialContext(ctx, p.istiodAddress, p.istiodDialOptions...)
+	upstreamConn, address, hinted, err := p.dialUpstream()
 	if err != nil {
-		proxyLog.Errorf("failed to connect to upstream %s: %v", p.istiodAddress, err)
-		metrics.IstiodConnectionFailures.Increment()
 		return err
 	}
 	defer upstreamConn.Close()
+	p.connectedMutex.Lock()
+	con.upstreamAddress = address
+	p.connectedMutex.Unlock()
 
 	xds := discovery.NewAggregatedDiscoveryServiceClient(upstreamConn)
-	ctx = metadata.AppendToOutgoingContext(context.Background(), "ClusterID", p.clusterID)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "ClusterID", p.clusterID)
 	for k, v := range p.xdsHeaders {
 		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
 	}
+	if !hinted && !p.affinityUnreachable.Load() {
+		ctx = metadata.AppendToOutgoingContext(ctx, v3.AffinityHeader, "true")
+	}
 	// We must propagate upstream termination to Envoy. This ensures that we resume the full XDS sequence on new connection
 	return p.HandleDeltaUpstream(ctx, con, xds)
 }
@@ -115,8 +122,8 @@ func (p *XdsProxy) HandleDeltaUpstream(ctx context.Context, con *ProxyConnection
 		proxyLog.Debugf("failed to create delta upstream grpc client: %v", err)
 		return err
 	}
-	proxyLog.Infof("connected to delta upstream XDS server: %s", p.istiodAddress)
-	defer proxyLog.Debugf("disconnected from delta XDS server: %s", p.istiodAddress)
+	proxyLog.Infof("connected to delta upstream XDS server: %s", con.upstreamAddress)
+	defer proxyLog.Debugf("disconnected from delta XDS server: %s", con.upstreamAddress)
 
 	con.upstreamDeltas = deltaUpstream
 
@@ -147,6 +154,8 @@ func (p *XdsProxy) HandleDeltaUpstream(ctx context.Context, con *ProxyConnection
 				proxyLog.Warnf("upstream terminated with unexpected error %v", err)
 				metrics.IstiodConnectionErrors.Increment()
 			}
+			p.recordAffinityHint(con.upstreamAddress, deltaUpstream.Trailer())
+			waitReconnectDelay(con, deltaUpstream.Trailer())
 			return err
 		case err := <-con.downstreamError:
 			// error from downstream Envoy.
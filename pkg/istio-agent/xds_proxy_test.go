@@ -50,6 +50,7 @@ import (
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/env"
 	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/pkg/uds"
 )
 
 func init() {
@@ -70,6 +71,30 @@ func TestXdsProxyBasicFlow(t *testing.T) {
 	})
 }
 
+// Validates the xds proxy can reach a node-local istiod over a unix domain socket.
+func TestXdsProxyUnixSocketUpstream(t *testing.T) {
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	socket := filepath.Join(t.TempDir(), "istiod.sock")
+	l, err := uds.NewListener(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	f.Discovery.Register(grpcServer)
+	go func() {
+		_ = grpcServer.Serve(l)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	proxy := setupXdsProxyWithAddress(t, uds.UnixAddressPrefix+socket)
+	conn := setupDownstreamConnection(t, proxy)
+	downstream := stream(t, conn)
+	sendDownstreamWithNode(t, downstream, model.NodeMetadata{
+		Namespace:   "default",
+		InstanceIPs: []string{"1.1.1.1"},
+	})
+}
+
 // Validates the proxy health checking updates
 func TestXdsProxyHealthCheck(t *testing.T) {
 	healthy := &discovery.DiscoveryRequest{TypeUrl: v3.HealthInfoType}
@@ -202,11 +227,15 @@ func TestXdsProxyHealthCheck(t *testing.T) {
 }
 
 func setupXdsProxy(t *testing.T) *XdsProxy {
+	return setupXdsProxyWithAddress(t, "buffcon")
+}
+
+func setupXdsProxyWithAddress(t *testing.T, discoveryAddress string) *XdsProxy {
 	secOpts := &security.Options{
 		FileMountedCerts: true,
 	}
 	proxyConfig := mesh.DefaultProxyConfig()
-	proxyConfig.DiscoveryAddress = "buffcon"
+	proxyConfig.DiscoveryAddress = discoveryAddress
 
 	// While the actual test runs on plain text, these are setup to build the default dial options
 	// with out these it looks for default cert location and fails.
@@ -579,3 +608,43 @@ func setupDownstreamConnectionUDS(t test.Failer, path string) *grpc.ClientConn {
 func setupDownstreamConnection(t *testing.T, proxy *XdsProxy) *grpc.ClientConn {
 	return setupDownstreamConnectionUDS(t, proxy.xdsUdsPath)
 }
+
+func TestReconnectDelay(t *testing.T) {
+	cases := []struct {
+		name     string
+		trailer  metadata.MD
+		expected time.Duration
+	}{
+		{"no hint", metadata.MD{}, 0},
+		{"hint", metadata.Pairs(v3.RetryPushbackTrailer, "1500"), 1500 * time.Millisecond},
+		{"invalid hint", metadata.Pairs(v3.RetryPushbackTrailer, "soon"), 0},
+		{"negative hint", metadata.Pairs(v3.RetryPushbackTrailer, "-1"), 0},
+		{"capped hint", metadata.Pairs(v3.RetryPushbackTrailer, "3600000"), maxReconnectDelay},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reconnectDelay(tt.trailer); got != tt.expected {
+				t.Fatalf("got %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecordAffinityHint(t *testing.T) {
+	p := &XdsProxy{}
+	p.recordAffinityHint("istiod:15012", metadata.MD{})
+	if got := p.affinityAddress.Load(); got != "" {
+		t.Fatalf("expected no hint, got %q", got)
+	}
+	p.recordAffinityHint("istiod:15012", metadata.Pairs(v3.AffinityTrailer, "10.0.0.2:15012"))
+	if got := p.affinityAddress.Load(); got != "10.0.0.2:15012" {
+		t.Fatalf("expected the hinted replica, got %q", got)
+	}
+
+	p.affinityAddress.Store("")
+	p.affinityUnreachable.Store(true)
+	p.recordAffinityHint("istiod:15012", metadata.Pairs(v3.AffinityTrailer, "10.0.0.2:15012"))
+	if got := p.affinityAddress.Load(); got != "" {
+		t.Fatalf("expected hints to be ignored once a replica was unreachable, got %q", got)
+	}
+}
@@ -44,6 +44,19 @@ type Options struct {
 	// MaxServerConnectionAgeGrace is an additive period after MaxServerConnectionAge
 	// after which the connection will be forcibly closed by the server.
 	MaxServerConnectionAgeGrace time.Duration // default value 10s
+	// MinClientPingInterval is the minimum interval at which the server allows clients to send keepalive
+	// pings. Clients pinging more often get a GoAway. Zero defaults to half of Time.
+	MinClientPingInterval time.Duration
+	// PermitClientPingsWithoutStream allows clients to send keepalive pings when there is no active stream.
+	PermitClientPingsWithoutStream bool
+}
+
+// ClientPingInterval returns the minimum interval at which the server allows clients to send keepalive pings.
+func (o *Options) ClientPingInterval() time.Duration {
+	if o.MinClientPingInterval > 0 {
+		return o.MinClientPingInterval
+	}
+	return o.Time / 2
 }
 
 // DefaultOption returns the default keepalive options.
@@ -68,4 +81,12 @@ func (o *Options) AttachCobraFlags(cmd *cobra.Command) {
 			"and if no activity is seen even after that the connection is closed.")
 	cmd.PersistentFlags().DurationVar(&o.MaxServerConnectionAge, "keepaliveMaxServerConnectionAge",
 		o.MaxServerConnectionAge, "Maximum duration a connection will be kept open on the server before a graceful close.")
+	cmd.PersistentFlags().DurationVar(&o.MaxServerConnectionAgeGrace, "keepaliveMaxServerConnectionAgeGrace",
+		o.MaxServerConnectionAgeGrace, "Grace period after the maximum connection age for the pending RPCs to "+
+			"complete, before the connection is forcibly closed.")
+	cmd.PersistentFlags().DurationVar(&o.MinClientPingInterval, "keepaliveMinClientPingInterval",
+		o.MinClientPingInterval, "Minimum interval at which clients may send keepalive pings. Clients pinging more "+
+			"often are disconnected. Defaults to half of keepaliveInterval.")
+	cmd.PersistentFlags().BoolVar(&o.PermitClientPingsWithoutStream, "keepalivePermitWithoutStream",
+		o.PermitClientPingsWithoutStream, "Allow clients to send keepalive pings when there is no active stream.")
 }
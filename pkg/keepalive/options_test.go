@@ -56,3 +56,35 @@ func TestSetConnectionAgeCommandlineOptions(t *testing.T) {
 		t.Errorf("%s maximum connection age %v", t.Name(), ko.MaxServerConnectionAge)
 	}
 }
+
+// Confirm the keepalive enforcement parameters can be set from the command line.
+func TestSetEnforcementCommandlineOptions(t *testing.T) {
+	ko := keepalive.DefaultOption()
+	if ko.ClientPingInterval() != ko.Time/2 {
+		t.Errorf("%s default client ping interval %v", t.Name(), ko.ClientPingInterval())
+	}
+	cmd := &cobra.Command{}
+	ko.AttachCobraFlags(cmd)
+
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	cmd.SetArgs([]string{
+		"--keepaliveMaxServerConnectionAgeGrace=1m",
+		"--keepaliveMinClientPingInterval=5s",
+		"--keepalivePermitWithoutStream",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Errorf("%s %s", t.Name(), err.Error())
+	}
+	if ko.MaxServerConnectionAgeGrace != time.Minute {
+		t.Errorf("%s maximum connection age grace %v", t.Name(), ko.MaxServerConnectionAgeGrace)
+	}
+	if ko.ClientPingInterval() != 5*time.Second {
+		t.Errorf("%s client ping interval %v", t.Name(), ko.ClientPingInterval())
+	}
+	if !ko.PermitClientPingsWithoutStream {
+		t.Errorf("%s pings without stream not permitted", t.Name())
+	}
+}
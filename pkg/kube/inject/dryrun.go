@@ -0,0 +1,83 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/api/annotation"
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+// DryRunResult is the result of injecting a pod with DryRun.
+type DryRunResult struct {
+	// Injected is false if the pod is skipped by the injection policy, in which case Pod is not modified.
+	Injected bool `json:"injected"`
+	// Templates are the names of the templates applied to the pod, in order.
+	Templates []string `json:"templates,omitempty"`
+	// Pod is the pod as it would be admitted by the webhook.
+	Pod *corev1.Pod `json:"pod"`
+	// Values are the values the templates are rendered with.
+	Values map[string]interface{} `json:"values,omitempty"`
+	// ProxyConfig is the proxy configuration the templates are rendered with: the mesh defaults, with the
+	// overrides of the proxy config annotation of the pod.
+	ProxyConfig *meshconfig.ProxyConfig `json:"proxyConfig,omitempty"`
+}
+
+// DryRun injects a pod as the webhook would with the current configuration, without admitting it. This
+// allows validating injection changes, such as new templates or values, before they are rolled out.
+// The namespace is used if the pod does not specify one.
+func (wh *Webhook) DryRun(pod *corev1.Pod, namespace string) (*DryRunResult, error) {
+	pod = pod.DeepCopy()
+	if pod.Namespace == "" {
+		pod.Namespace = namespace
+	}
+
+	wh.mu.RLock()
+	if !injectRequired(IgnoredNamespaces, wh.Config, &pod.Spec, pod.ObjectMeta) {
+		wh.mu.RUnlock()
+		return &DryRunResult{Pod: pod}, nil
+	}
+	params := wh.injectionParameters(pod, "")
+	wh.mu.RUnlock()
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(params.valuesConfig), &values); err != nil {
+		return nil, err
+	}
+	meshConfig := params.meshConfig
+	if pca, f := pod.Annotations[annotation.ProxyConfig.Name]; f && meshConfig != nil {
+		var err error
+		if meshConfig, err = mesh.ApplyProxyConfig(pca, *meshConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	// Templates are selected before rendering, as the injection may modify the annotations of the pod.
+	templates := selectTemplates(params)
+	injected, err := renderPod(params)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunResult{
+		Injected:    true,
+		Templates:   templates,
+		Pod:         injected,
+		Values:      values,
+		ProxyConfig: meshConfig.GetDefaultConfig(),
+	}, nil
+}
@@ -397,17 +397,37 @@ func knownTemplates(t Templates) []string {
 	return keys
 }
 
+// selectTemplates returns the templates to apply to the pod, in order. Templates selected more than once,
+// such as through an alias and an additional template, are only applied the first time.
 func selectTemplates(params InjectionParameters) []string {
 	// TODO move annotation to istio/api
+	names := params.defaultTemplate
 	if a, f := params.pod.Annotations[TemplatesAnnotation]; f {
-		names := []string{}
-		for _, tmplName := range strings.Split(a, ",") {
-			name := strings.TrimSpace(tmplName)
+		names = splitTemplates(a)
+	}
+	if a, f := params.pod.Annotations[AdditionalTemplatesAnnotation]; f {
+		names = append(append([]string{}, names...), splitTemplates(a)...)
+	}
+	seen := map[string]bool{}
+	ret := []string{}
+	for _, name := range resolveAliases(params, names) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		ret = append(ret, name)
+	}
+	return ret
+}
+
+func splitTemplates(a string) []string {
+	names := []string{}
+	for _, tmplName := range strings.Split(a, ",") {
+		if name := strings.TrimSpace(tmplName); name != "" {
 			names = append(names, name)
 		}
-		return resolveAliases(params, names)
 	}
-	return resolveAliases(params, params.defaultTemplate)
+	return names
 }
 
 func resolveAliases(params InjectionParameters, names []string) []string {
@@ -432,6 +452,8 @@ func stripPod(req InjectionParameters) *corev1.Pod {
 	// To ensure idempotency, remove our injected containers first
 	for _, c := range prevStatus.Containers {
 		pod.Spec.Containers = modifyContainers(pod.Spec.Containers, c, Remove)
+		// The proxy may have been injected as a native sidecar.
+		pod.Spec.InitContainers = modifyContainers(pod.Spec.InitContainers, c, Remove)
 	}
 	for _, c := range prevStatus.InitContainers {
 		pod.Spec.InitContainers = modifyContainers(pod.Spec.InitContainers, c, Remove)
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/gogo/protobuf/types"
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	meshapi "istio.io/api/mesh/v1alpha1"
@@ -511,6 +513,181 @@ spec:
 	runWebhook(t, webhook, []byte(inputAlias), []byte(fmt.Sprintf(expected, "both")), false)
 }
 
+func TestSelectTemplates(t *testing.T) {
+	params := InjectionParameters{
+		defaultTemplate: []string{"sidecar"},
+		aliases:         map[string][]string{"both": {"sidecar", "init"}},
+	}
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        []string
+	}{
+		{"default", nil, []string{"sidecar"}},
+		{"explicit", map[string]string{TemplatesAnnotation: "gvisor, debug-tools"}, []string{"gvisor", "debug-tools"}},
+		{"additional", map[string]string{AdditionalTemplatesAnnotation: "debug-tools"}, []string{"sidecar", "debug-tools"}},
+		{
+			"explicit and additional",
+			map[string]string{TemplatesAnnotation: "gvisor", AdditionalTemplatesAnnotation: "debug-tools,"},
+			[]string{"gvisor", "debug-tools"},
+		},
+		{"duplicates", map[string]string{TemplatesAnnotation: "both", AdditionalTemplatesAnnotation: "sidecar"}, []string{"sidecar", "init"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			params.pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			if got := selectTemplates(params); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected templates %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	webhook := &Webhook{
+		Config: &Config{
+			Templates: map[string]string{
+				"sidecar": `
+spec:
+  containers:
+  - name: istio-proxy
+    image: {{ .Values.global.hub }}/proxy
+`,
+				"debug-tools": `
+spec:
+  containers:
+  - name: debug
+    image: tools
+`,
+			},
+			DefaultTemplates: []string{"sidecar"},
+			Policy:           InjectionPolicyEnabled,
+		},
+		valuesConfig: "global:\n  hub: example.com\n",
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "hello",
+			Annotations: map[string]string{AdditionalTemplatesAnnotation: "debug-tools"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "hello", Image: "hello"}}},
+	}
+	result, err := webhook.DryRun(pod, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Injected {
+		t.Fatalf("expected the pod to be injected")
+	}
+	if want := []string{"sidecar", "debug-tools"}; !reflect.DeepEqual(result.Templates, want) {
+		t.Fatalf("expected templates %v, got %v", want, result.Templates)
+	}
+	images := map[string]string{}
+	for _, c := range result.Pod.Spec.Containers {
+		images[c.Name] = c.Image
+	}
+	if want := map[string]string{"hello": "hello", "istio-proxy": "example.com/proxy", "debug": "tools"}; !reflect.DeepEqual(images, want) {
+		t.Fatalf("expected containers %v, got %v", want, images)
+	}
+	if result.Pod.Namespace != "default" {
+		t.Fatalf("expected namespace default, got %q", result.Pod.Namespace)
+	}
+	if hub := result.Values["global"].(map[string]interface{})["hub"]; hub != "example.com" {
+		t.Fatalf("expected values to be returned, got %v", result.Values)
+	}
+	if len(pod.Spec.Containers) != 1 {
+		t.Fatalf("expected the input pod to be unmodified, got %v", pod.Spec.Containers)
+	}
+
+	skipped := pod.DeepCopy()
+	skipped.Namespace = "kube-system"
+	if result, err := webhook.DryRun(skipped, ""); err != nil || result.Injected {
+		t.Fatalf("expected pod in kube-system to be skipped, got %+v, %v", result, err)
+	}
+
+	pod.Annotations[TemplatesAnnotation] = "unknown"
+	if _, err := webhook.DryRun(pod, "default"); err == nil {
+		t.Fatalf("expected error for unknown template")
+	}
+}
+
+func TestNativeSidecar(t *testing.T) {
+	webhook := &Webhook{
+		Config: &Config{
+			Templates: map[string]string{
+				"sidecar": `
+spec:
+  containers:
+  - name: istio-proxy
+    image: proxy
+  initContainers:
+  - name: istio-init
+    image: proxy
+`,
+			},
+			DefaultTemplates: []string{"sidecar"},
+			Policy:           InjectionPolicyEnabled,
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "hello",
+			Namespace:   "default",
+			Annotations: map[string]string{NativeSidecarAnnotation: "true"},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "migrate", Image: "migrate"}},
+			Containers:     []corev1.Container{{Name: "hello", Image: "hello"}},
+		},
+	}
+	podJSON := convertToJSON(pod, t)
+	got := webhook.inject(&kube.AdmissionReview{
+		Request: &kube.AdmissionRequest{Object: runtime.RawExtension{Raw: podJSON}, Namespace: "default"},
+	}, "")
+	if got.Patch == nil {
+		t.Fatalf("expected a patch, got %+v", got)
+	}
+
+	var injected struct {
+		Spec struct {
+			InitContainers []struct {
+				Name          string
+				RestartPolicy string
+				Env           []corev1.EnvVar
+				Lifecycle     *corev1.Lifecycle
+			}
+			Containers []corev1.Container
+		}
+	}
+	if err := json.Unmarshal(applyJSONPatch(podJSON, prettyJSON(got.Patch, t), t), &injected); err != nil {
+		t.Fatal(err)
+	}
+	var initContainers []string
+	for _, c := range injected.Spec.InitContainers {
+		initContainers = append(initContainers, c.Name)
+	}
+	if want := []string{"istio-init", "istio-proxy", "migrate"}; !reflect.DeepEqual(initContainers, want) {
+		t.Fatalf("expected init containers %v, got %v", want, initContainers)
+	}
+	if len(injected.Spec.Containers) != 1 || injected.Spec.Containers[0].Name != "hello" {
+		t.Fatalf("expected only the application container, got %v", injected.Spec.Containers)
+	}
+	proxy := injected.Spec.InitContainers[1]
+	if proxy.RestartPolicy != "Always" {
+		t.Fatalf("expected restartPolicy Always, got %q", proxy.RestartPolicy)
+	}
+	if proxy.Lifecycle == nil || proxy.Lifecycle.PostStart == nil {
+		t.Fatalf("expected a postStart hook holding the application")
+	}
+	native := false
+	for _, e := range proxy.Env {
+		native = native || e.Name == NativeSidecarEnv && e.Value == "true"
+	}
+	if !native {
+		t.Fatalf("expected %s to be set, got %v", NativeSidecarEnv, proxy.Env)
+	}
+}
+
 // TestStrategicMerge ensures we can use https://github.com/kubernetes/community/blob/master/contributors/devel/sig-api-machinery/strategic-merge-patch.md
 // directives in the injection template
 func TestStrategicMerge(t *testing.T) {
@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// NativeSidecarAnnotation overrides, per pod, whether the proxy is injected as a native sidecar: an init
+// container with restartPolicy Always, which Kubernetes starts before the application containers and stops
+// after them. Defaults to the ENABLE_NATIVE_SIDECARS setting of istiod. Requires Kubernetes 1.28 or newer.
+// TODO move this to api repo
+const NativeSidecarAnnotation = "sidecar.istio.io/nativeSidecar"
+
+// NativeSidecarEnv is set on the proxy container when it is injected as a native sidecar.
+const NativeSidecarEnv = "NATIVE_SIDECAR"
+
+// nativeSidecar returns true if the proxy should be injected as a native sidecar.
+func nativeSidecar(req InjectionParameters) bool {
+	if v, f := req.pod.Annotations[NativeSidecarAnnotation]; f {
+		if native, err := strconv.ParseBool(v); err == nil {
+			return native
+		}
+		log.Warnf("ignoring invalid %s annotation %q", NativeSidecarAnnotation, v)
+	}
+	return features.EnableNativeSidecars
+}
+
+// moveProxyToInitContainers turns the proxy container into a native sidecar. It is started after the
+// traffic capture is set up and before the user init containers, which are then able to use the mesh. The
+// pilot-agent wait hook holds the following containers until the proxy is ready, unless the template
+// configures lifecycle hooks of its own.
+func moveProxyToInitContainers(pod *corev1.Pod) {
+	var proxy *corev1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == ProxyContainerName {
+			proxy = pod.Spec.Containers[i].DeepCopy()
+			break
+		}
+	}
+	if proxy == nil {
+		return
+	}
+	if proxy.Lifecycle == nil {
+		proxy.Lifecycle = &corev1.Lifecycle{}
+	}
+	if proxy.Lifecycle.PostStart == nil {
+		proxy.Lifecycle.PostStart = &corev1.Handler{
+			Exec: &corev1.ExecAction{Command: []string{"pilot-agent", "wait"}},
+		}
+	}
+	proxy.Env = append(proxy.Env, corev1.EnvVar{Name: NativeSidecarEnv, Value: "true"})
+
+	pod.Spec.Containers = modifyContainers(pod.Spec.Containers, ProxyContainerName, Remove)
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, *proxy)
+	pod.Spec.InitContainers = modifyContainers(pod.Spec.InitContainers, ProxyContainerName, MoveFirst)
+	pod.Spec.InitContainers = modifyContainers(pod.Spec.InitContainers, EnableCoreDumpName, MoveFirst)
+	pod.Spec.InitContainers = modifyContainers(pod.Spec.InitContainers, InitContainerName, MoveFirst)
+	pod.Spec.InitContainers = modifyContainers(pod.Spec.InitContainers, ValidationContainerName, MoveFirst)
+}
+
+// setNativeSidecarRestartPolicy sets the restartPolicy of the proxy init container of a serialized pod.
+// The field is not known to the Kubernetes API types used here, so it can't be set on the typed pod.
+func setNativeSidecarRestartPolicy(podJSON []byte) ([]byte, error) {
+	pod := map[string]interface{}{}
+	if err := json.Unmarshal(podJSON, &pod); err != nil {
+		return nil, err
+	}
+	spec, _ := pod["spec"].(map[string]interface{})
+	initContainers, _ := spec["initContainers"].([]interface{})
+	for _, c := range initContainers {
+		container, ok := c.(map[string]interface{})
+		if ok && container["name"] == ProxyContainerName {
+			container["restartPolicy"] = "Always"
+			return json.Marshal(pod)
+		}
+	}
+	return nil, fmt.Errorf("init container %s not found", ProxyContainerName)
+}
@@ -32,32 +32,34 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/interception"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/pkg/log"
 )
 
 func CreateInjectionFuncmap() template.FuncMap {
 	return template.FuncMap{
-		"formatDuration":      formatDuration,
-		"isset":               isset,
-		"excludeInboundPort":  excludeInboundPort,
-		"includeInboundPorts": includeInboundPorts,
-		"kubevirtInterfaces":  kubevirtInterfaces,
-		"applicationPorts":    applicationPorts,
-		"annotation":          getAnnotation,
-		"valueOrDefault":      valueOrDefault,
-		"toJSON":              toJSON,
-		"toJson":              toJSON, // Used by, e.g. Istio 1.0.5 template sidecar-injector-configmap.yaml
-		"fromJSON":            fromJSON,
-		"structToJSON":        structToJSON,
-		"protoToJSON":         protoToJSON,
-		"toYaml":              toYaml,
-		"indent":              indent,
-		"directory":           directory,
-		"contains":            flippedContains,
-		"toLower":             strings.ToLower,
-		"appendMultusNetwork": appendMultusNetwork,
-		"env":                 env,
+		"formatDuration":       formatDuration,
+		"isset":                isset,
+		"excludeInboundPort":   excludeInboundPort,
+		"excludedInboundPorts": excludedInboundPorts,
+		"includeInboundPorts":  includeInboundPorts,
+		"kubevirtInterfaces":   kubevirtInterfaces,
+		"applicationPorts":     applicationPorts,
+		"annotation":           getAnnotation,
+		"valueOrDefault":       valueOrDefault,
+		"toJSON":               toJSON,
+		"toJson":               toJSON, // Used by, e.g. Istio 1.0.5 template sidecar-injector-configmap.yaml
+		"fromJSON":             fromJSON,
+		"structToJSON":         structToJSON,
+		"protoToJSON":          protoToJSON,
+		"toYaml":               toYaml,
+		"indent":               indent,
+		"directory":            directory,
+		"contains":             flippedContains,
+		"toLower":              strings.ToLower,
+		"appendMultusNetwork":  appendMultusNetwork,
+		"env":                  env,
 	}
 }
 
@@ -120,6 +122,12 @@ func excludeInboundPort(port interface{}, excludedInboundPorts string) string {
 	return strings.Join(outPorts, ",")
 }
 
+// excludedInboundPorts returns the value of the istio-iptables -d flag for the given status port and excluded inbound
+// ports, the same way istiod computes the intercepted ports of the proxy.
+func excludedInboundPorts(statusPort interface{}, excludedInboundPorts string) string {
+	return interception.ExcludedInboundPorts(fmt.Sprint(statusPort), excludedInboundPorts)
+}
+
 func valueOrDefault(value interface{}, defaultValue interface{}) interface{} {
 	if value == "" || value == nil {
 		return defaultValue
@@ -352,6 +352,22 @@ func injectPod(req InjectionParameters) ([]byte, error) {
 		return nil, err
 	}
 
+	mergedPod, err := renderPod(req)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := createPatch(mergedPod, originalPodSpec, nativeSidecar(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patch: %v", err)
+	}
+
+	log.Debugf("AdmissionResponse: patch=%v\n", string(patch))
+	return patch, nil
+}
+
+// renderPod runs the injection templates and returns the injected pod.
+func renderPod(req InjectionParameters) (*corev1.Pod, error) {
 	// Run the injection template, giving us a partial pod spec
 	mergedPod, injectedPodData, err := RunTemplate(req)
 	if err != nil {
@@ -367,14 +383,7 @@ func injectPod(req InjectionParameters) ([]byte, error) {
 	if err := postProcessPod(mergedPod, *injectedPodData, req); err != nil {
 		return nil, fmt.Errorf("failed to process pod: %v", err)
 	}
-
-	patch, err := createPatch(mergedPod, originalPodSpec)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create patch: %v", err)
-	}
-
-	log.Debugf("AdmissionResponse: patch=%v\n", string(patch))
-	return patch, nil
+	return mergedPod, nil
 }
 
 // OverrideAnnotation is used to store the overrides for injected containers
@@ -387,6 +396,12 @@ const OverrideAnnotation = "proxy.istio.io/overrides"
 // TODO move this to api repo
 const TemplatesAnnotation = "inject.istio.io/templates"
 
+// AdditionalTemplatesAnnotation declares templates to apply after the ones selected by TemplatesAnnotation,
+// or DefaultTemplates if it is not specified. This allows adding templates to a pod without knowing the defaults.
+// The format is a comma separated list. For example, `inject.istio.io/additional-templates: debug-tools`.
+// TODO move this to api repo
+const AdditionalTemplatesAnnotation = "inject.istio.io/additional-templates"
+
 // reapplyOverwrittenContainers enables users to provide container level overrides for settings in the injection template
 // * originalPod: the pod before injection. If needed, we will apply some configurations from this pod on top of the final pod
 // * templatePod: the rendered injection template. This is needed only to see what containers we injected
@@ -505,11 +520,16 @@ func reinsertOverrides(pod *corev1.Pod) (*corev1.Pod, error) {
 	return pod, nil
 }
 
-func createPatch(pod *corev1.Pod, original []byte) ([]byte, error) {
+func createPatch(pod *corev1.Pod, original []byte, nativeSidecar bool) ([]byte, error) {
 	reinjected, err := json.Marshal(pod)
 	if err != nil {
 		return nil, err
 	}
+	if nativeSidecar {
+		if reinjected, err = setNativeSidecarRestartPolicy(reinjected); err != nil {
+			return nil, err
+		}
+	}
 	p, err := jsonpatch.CreatePatch(original, reinjected)
 	if err != nil {
 		return nil, err
@@ -594,6 +614,10 @@ func reorderPod(pod *corev1.Pod, req InjectionParameters) error {
 	pod.Spec.InitContainers = modifyContainers(pod.Spec.InitContainers, InitContainerName, MoveLast)
 	pod.Spec.InitContainers = modifyContainers(pod.Spec.InitContainers, EnableCoreDumpName, MoveLast)
 
+	if nativeSidecar(req) {
+		moveProxyToInitContainers(pod)
+	}
+
 	return nil
 }
 
@@ -737,20 +761,7 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 		}
 	}
 
-	deploy, typeMeta := kube.GetDeployMetaFromPod(&pod)
-	params := InjectionParameters{
-		pod:                 &pod,
-		deployMeta:          deploy,
-		typeMeta:            typeMeta,
-		templates:           wh.Config.Templates,
-		defaultTemplate:     wh.Config.DefaultTemplates,
-		aliases:             wh.Config.Aliases,
-		meshConfig:          wh.meshConfig,
-		valuesConfig:        wh.valuesConfig,
-		revision:            wh.revision,
-		injectedAnnotations: wh.Config.InjectedAnnotations,
-		proxyEnvs:           parseInjectEnvs(path),
-	}
+	params := wh.injectionParameters(&pod, path)
 	wh.mu.RUnlock()
 
 	patchBytes, err := injectPod(params)
@@ -771,6 +782,25 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 	return &reviewResponse
 }
 
+// injectionParameters returns the parameters to inject the pod with the current configuration. The caller
+// must hold the read lock.
+func (wh *Webhook) injectionParameters(pod *corev1.Pod, path string) InjectionParameters {
+	deploy, typeMeta := kube.GetDeployMetaFromPod(pod)
+	return InjectionParameters{
+		pod:                 pod,
+		deployMeta:          deploy,
+		typeMeta:            typeMeta,
+		templates:           wh.Config.Templates,
+		defaultTemplate:     wh.Config.DefaultTemplates,
+		aliases:             wh.Config.Aliases,
+		meshConfig:          wh.meshConfig,
+		valuesConfig:        wh.valuesConfig,
+		revision:            wh.revision,
+		injectedAnnotations: wh.Config.InjectedAnnotations,
+		proxyEnvs:           parseInjectEnvs(path),
+	}
+}
+
 func (wh *Webhook) serveInject(w http.ResponseWriter, r *http.Request) {
 	totalInjections.Increment()
 	var body []byte
@@ -38,6 +38,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/dependency"
 	"istio.io/istio/pkg/kube"
 	"istio.io/pkg/log"
 	"istio.io/pkg/monitoring"
@@ -53,6 +54,9 @@ func init() {
 	monitoring.MustRegister(timeouts)
 }
 
+// healthCheckInterval is the interval at which the API servers of the remote clusters are probed.
+var healthCheckInterval = 30 * time.Second
+
 var timeouts = monitoring.NewSum(
 	"remote_cluster_sync_timeouts_total",
 	"Number of times remote clusters took too long to sync, causing slow startup that excludes remote clusters.",
@@ -101,10 +105,23 @@ type Cluster struct {
 // Run starts the cluster's informers and waits for caches to sync. Once caches are synced, we mark the cluster synced.
 // This should be called after each of the handlers have registered informers, and should be run in a goroutine.
 func (r *Cluster) Run() {
+	go wait.Until(r.checkHealth, healthCheckInterval, r.Stop)
 	r.Client.RunAndWait(r.Stop)
 	r.initialSync.Store(true)
 }
 
+// checkHealth probes the API server of the cluster, and reports the outcome to the dependency registry.
+func (r *Cluster) checkHealth() {
+	start := time.Now()
+	_, err := r.Client.Kube().Discovery().ServerVersion()
+	select {
+	case <-r.Stop:
+		// The cluster was removed while probing.
+	default:
+		dependency.Report(dependency.RemoteCluster, r.clusterID, start, err)
+	}
+}
+
 func (r *Cluster) HasSynced() bool {
 	return r.initialSync.Load() || r.SyncTimeout.Load()
 }
@@ -455,8 +472,10 @@ func (c *Controller) addSecret(secretKey string, s *corev1.Secret) {
 		}
 		log.Infof("%s cluster %v from secret %v", action, clusterID, secretKey)
 
+		start := time.Now()
 		remoteCluster, err := c.createRemoteCluster(kubeConfig, clusterID)
 		if err != nil {
+			dependency.Report(dependency.RemoteCluster, clusterID, start, err)
 			log.Errorf("%s cluster_id=%v from secret=%v: %v", action, clusterID, secretKey, err)
 			continue
 		}
@@ -486,6 +505,7 @@ func (c *Controller) deleteSecret(secretKey string) {
 				clusterID, secretKey, err)
 		}
 		close(cluster.Stop)
+		dependency.Remove(dependency.RemoteCluster, string(clusterID))
 		delete(c.cs.remoteClusters, secretKey)
 	}
 }
@@ -503,6 +523,7 @@ func (c *Controller) deleteMemberCluster(secretKey string, clusterID cluster.ID)
 			clusterID, secretKey, err)
 	}
 	close(c.cs.remoteClusters[secretKey][clusterID].Stop)
+	dependency.Remove(dependency.RemoteCluster, string(clusterID))
 	delete(c.cs.remoteClusters[secretKey], clusterID)
 }
 
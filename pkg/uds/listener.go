@@ -19,11 +19,34 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"istio.io/pkg/log"
 )
 
+// UnixAddressPrefix is the scheme of gRPC targets that refer to a unix domain socket.
+const UnixAddressPrefix = "unix://"
+
+// IsUnixAddress returns true if the gRPC target addr refers to a unix domain socket.
+func IsUnixAddress(addr string) bool {
+	return strings.HasPrefix(addr, UnixAddressPrefix)
+}
+
+// NewListener returns a listener on a unix domain socket which all the local users can connect to.
 func NewListener(path string) (net.Listener, error) {
+	return newListener(path, 0o666, -1)
+}
+
+// NewGroupListener returns a listener on a unix domain socket which only the current user and, if gid is
+// positive, the members of the group gid can connect to.
+func NewGroupListener(path string, gid int) (net.Listener, error) {
+	if gid <= 0 {
+		return newListener(path, 0o600, -1)
+	}
+	return newListener(path, 0o660, gid)
+}
+
+func newListener(path string, mode os.FileMode, gid int) (net.Listener, error) {
 	// Remove unix socket before use.
 	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		// Anything other than "file not found" is an error.
@@ -46,7 +69,13 @@ func NewListener(path string) (net.Listener, error) {
 	if _, err := os.Stat(path); err != nil {
 		return nil, fmt.Errorf("uds file %q doesn't exist", path)
 	}
-	if err := os.Chmod(path, 0o666); err != nil {
+	if gid > 0 {
+		if err := os.Chown(path, -1, gid); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to update %q group: %v", path, err)
+		}
+	}
+	if err := os.Chmod(path, mode); err != nil {
 		return nil, fmt.Errorf("failed to update %q permission", path)
 	}
 
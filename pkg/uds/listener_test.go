@@ -17,6 +17,8 @@ package uds
 import (
 	"context"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"google.golang.org/grpc"
@@ -50,3 +52,35 @@ func connect(socket string) (*grpc.ClientConn, error) {
 
 	return conn, nil
 }
+
+func TestGroupListener(t *testing.T) {
+	// The group must be positive, and one the test can give the socket to.
+	gid := os.Getgid()
+	if gid == 0 {
+		gid = 1
+	}
+	for _, tt := range []struct {
+		name string
+		gid  int
+		mode os.FileMode
+	}{
+		{"owner only", 0, 0o600},
+		{"group", gid, 0o660},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "socket")
+			l, err := NewGroupListener(path, tt.gid)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			defer l.Close()
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Mode().Perm() != tt.mode {
+				t.Fatalf("expected mode %v, got %v", tt.mode, fi.Mode().Perm())
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/keycertbundle"
+	"istio.io/istio/pkg/webhooks/util"
+)
+
+// WebhookStatus is the state of a single webhook of a configuration managed by istiod.
+type WebhookStatus struct {
+	Kind              string                `json:"kind"`
+	Configuration     string                `json:"configuration"`
+	Name              string                `json:"name"`
+	FailurePolicy     string                `json:"failurePolicy,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	ObjectSelector    *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	// CABundleCurrent is true if the CA bundle matches the one currently used by istiod.
+	CABundleCurrent bool `json:"caBundleCurrent"`
+	// CABundleExpiry is the earliest expiry of the certificates of the CA bundle.
+	CABundleExpiry *time.Time `json:"caBundleExpiry,omitempty"`
+	// CABundleError is set if the CA bundle can not be parsed.
+	CABundleError string `json:"caBundleError,omitempty"`
+}
+
+// Statuses returns the state of the injection and validation webhooks of the given revision, compared
+// against the CA bundle currently used by istiod.
+func Statuses(client kubernetes.Interface, revision string, caBundleWatcher *keycertbundle.Watcher) ([]WebhookStatus, error) {
+	caBundle, err := util.LoadCABundle(caBundleWatcher)
+	if err != nil {
+		return nil, err
+	}
+	opts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", label.IoIstioRev.Name, revision)}
+	var out []WebhookStatus
+
+	mutating, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range mutating.Items {
+		for _, wh := range config.Webhooks {
+			out = append(out, webhookStatus("mutating", config.Name, wh.Name, wh.FailurePolicy,
+				wh.NamespaceSelector, wh.ObjectSelector, wh.ClientConfig.CABundle, caBundle))
+		}
+	}
+
+	validating, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range validating.Items {
+		for _, wh := range config.Webhooks {
+			out = append(out, webhookStatus("validating", config.Name, wh.Name, wh.FailurePolicy,
+				wh.NamespaceSelector, wh.ObjectSelector, wh.ClientConfig.CABundle, caBundle))
+		}
+	}
+	return out, nil
+}
+
+func webhookStatus(kind, configName, name string, failurePolicy *v1.FailurePolicyType,
+	namespaceSelector, objectSelector *metav1.LabelSelector, bundle, current []byte) WebhookStatus {
+	status := WebhookStatus{
+		Kind:              kind,
+		Configuration:     configName,
+		Name:              name,
+		NamespaceSelector: namespaceSelector,
+		ObjectSelector:    objectSelector,
+		CABundleCurrent:   bytes.Equal(bundle, current),
+	}
+	if failurePolicy != nil {
+		status.FailurePolicy = string(*failurePolicy)
+	}
+	expiry, err := caBundleExpiry(bundle)
+	if err != nil {
+		status.CABundleError = err.Error()
+	} else {
+		status.CABundleExpiry = &expiry
+	}
+	return status
+}
+
+// caBundleExpiry returns the earliest expiry of the certificates of a PEM encoded bundle.
+func caBundleExpiry(bundle []byte) (time.Time, error) {
+	var expiry time.Time
+	for {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	if expiry.IsZero() {
+		return time.Time{}, fmt.Errorf("no certificate found in CA bundle")
+	}
+	return expiry, nil
+}
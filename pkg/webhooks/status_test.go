@@ -0,0 +1,47 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+)
+
+func TestWebhookStatus(t *testing.T) {
+	fail := admissionregistrationv1.Fail
+	status := webhookStatus("mutating", "istio-sidecar-injector", "sidecar-injector.istio.io", &fail,
+		nil, nil, caBundle0, caBundle0)
+	if !status.CABundleCurrent {
+		t.Errorf("expected CA bundle to be current")
+	}
+	if status.FailurePolicy != "Fail" {
+		t.Errorf("expected failure policy Fail, got %q", status.FailurePolicy)
+	}
+	want := time.Date(2291, 10, 7, 18, 4, 24, 0, time.UTC)
+	if status.CABundleExpiry == nil || !status.CABundleExpiry.Equal(want) {
+		t.Errorf("expected CA bundle expiry %v, got %v", want, status.CABundleExpiry)
+	}
+
+	status = webhookStatus("validating", "istiod-istio-system", "validation.istio.io", nil,
+		nil, nil, []byte("invalid"), caBundle0)
+	if status.CABundleCurrent {
+		t.Errorf("expected CA bundle to be stale")
+	}
+	if status.CABundleError == "" || status.CABundleExpiry != nil {
+		t.Errorf("expected CA bundle error, got %+v", status)
+	}
+}
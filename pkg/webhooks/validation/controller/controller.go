@@ -267,6 +267,15 @@ func (c *Controller) startCaBundleWatcher(stop <-chan struct{}) {
 	}
 }
 
+// Reconcile queues a reconciliation of the webhook configuration, such as to repair a CA bundle modified
+// by another party.
+func (c *Controller) Reconcile() {
+	c.queue.Add(&reconcileRequest{
+		updateEvent,
+		"forced reconcile",
+	})
+}
+
 func (c *Controller) runWorker() {
 	for c.processNextWorkItem() {
 	}
@@ -0,0 +1,145 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+)
+
+// ReferenceLookup resolves the resources referenced by a config, to validate it against them. It is served from
+// a cached snapshot of the configs and services, which may be slightly stale: references it can not resolve are
+// reported as warnings, and never reject a config.
+type ReferenceLookup interface {
+	// Ready returns true once the snapshot is populated. References are not checked before.
+	Ready() bool
+	// GatewayExists returns true if the Gateway exists.
+	GatewayExists(namespace, name string) bool
+	// HostExists returns true if a service or ServiceEntry declares the host.
+	HostExists(hostname host.Name) bool
+	// WorkloadsMatch returns true if a workload of the service with the host has the labels.
+	WorkloadsMatch(hostname host.Name, workloadLabels labels.Instance) bool
+}
+
+// referenceWarnings returns a warning for each reference of the config to a resource the lookup does not know.
+func referenceWarnings(lookup ReferenceLookup, cfg config.Config) []string {
+	switch spec := cfg.Spec.(type) {
+	case *networking.VirtualService:
+		return virtualServiceReferenceWarnings(lookup, cfg.Meta, spec)
+	case *networking.DestinationRule:
+		return destinationRuleReferenceWarnings(lookup, cfg.Meta, spec)
+	}
+	return nil
+}
+
+func virtualServiceReferenceWarnings(lookup ReferenceLookup, meta config.Meta, vs *networking.VirtualService) []string {
+	var warnings []string
+	seen := map[string]bool{}
+	checkGateway := func(gw string) {
+		if gw == constants.IstioMeshGateway || seen[gw] {
+			return
+		}
+		seen[gw] = true
+		namespace, name := gatewayReference(gw, meta.Namespace)
+		if !lookup.GatewayExists(namespace, name) {
+			warnings = append(warnings, fmt.Sprintf("referenced gateway %s/%s does not exist", namespace, name))
+		}
+	}
+	checkHost := func(destination *networking.Destination) {
+		if destination == nil {
+			return
+		}
+		h := model.ResolveShortnameToFQDN(destination.Host, meta)
+		if h.IsWildCarded() || seen[string(h)] {
+			return
+		}
+		seen[string(h)] = true
+		if !lookup.HostExists(h) {
+			warnings = append(warnings, fmt.Sprintf("destination host %s matches no service or ServiceEntry", h))
+		}
+	}
+
+	for _, gw := range vs.Gateways {
+		checkGateway(gw)
+	}
+	for _, route := range vs.Http {
+		for _, match := range route.Match {
+			for _, gw := range match.GetGateways() {
+				checkGateway(gw)
+			}
+		}
+		for _, dest := range route.Route {
+			checkHost(dest.Destination)
+		}
+		checkHost(route.Mirror)
+	}
+	for _, route := range vs.Tls {
+		for _, match := range route.Match {
+			for _, gw := range match.GetGateways() {
+				checkGateway(gw)
+			}
+		}
+		for _, dest := range route.Route {
+			checkHost(dest.Destination)
+		}
+	}
+	for _, route := range vs.Tcp {
+		for _, match := range route.Match {
+			for _, gw := range match.GetGateways() {
+				checkGateway(gw)
+			}
+		}
+		for _, dest := range route.Route {
+			checkHost(dest.Destination)
+		}
+	}
+	return warnings
+}
+
+func destinationRuleReferenceWarnings(lookup ReferenceLookup, meta config.Meta, dr *networking.DestinationRule) []string {
+	h := model.ResolveShortnameToFQDN(dr.Host, meta)
+	if h.IsWildCarded() {
+		return nil
+	}
+	if !lookup.HostExists(h) {
+		return []string{fmt.Sprintf("host %s matches no service or ServiceEntry", h)}
+	}
+	var warnings []string
+	for _, subset := range dr.Subsets {
+		if len(subset.Labels) > 0 && !lookup.WorkloadsMatch(h, subset.Labels) {
+			warnings = append(warnings, fmt.Sprintf("labels of subset %s match no workload of %s", subset.Name, h))
+		}
+	}
+	return warnings
+}
+
+// gatewayReference returns the namespace and name of a gateway referenced by a config of the namespace, as
+// <namespace>/<name>, <name>.<namespace>.svc.<domain> or <name>.
+func gatewayReference(gw, namespace string) (string, string) {
+	if parts := strings.SplitN(gw, "/", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	if parts := strings.Split(gw, "."); len(parts) > 1 {
+		return parts[1], parts[0]
+	}
+	return namespace, gw
+}
@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+type fakeLookup struct {
+	gateways  map[string]bool
+	workloads map[host.Name][]labels.Instance
+}
+
+func (l fakeLookup) Ready() bool {
+	return true
+}
+
+func (l fakeLookup) GatewayExists(namespace, name string) bool {
+	return l.gateways[namespace+"/"+name]
+}
+
+func (l fakeLookup) HostExists(hostname host.Name) bool {
+	_, f := l.workloads[hostname]
+	return f
+}
+
+func (l fakeLookup) WorkloadsMatch(hostname host.Name, workloadLabels labels.Instance) bool {
+	for _, w := range l.workloads[hostname] {
+		if workloadLabels.SubsetOf(w) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReferenceWarnings(t *testing.T) {
+	lookup := fakeLookup{
+		gateways: map[string]bool{"istio-system/ingress": true, "ns/local": true},
+		workloads: map[host.Name][]labels.Instance{
+			"reviews.ns.svc.cluster.local": {{"app": "reviews", "version": "v1"}},
+			"external.example.com":         nil,
+		},
+	}
+	meta := func(kind config.GroupVersionKind) config.Meta {
+		return config.Meta{GroupVersionKind: kind, Name: "test", Namespace: "ns", Domain: "cluster.local"}
+	}
+	cases := []struct {
+		name     string
+		config   config.Config
+		expected []string
+	}{
+		{
+			name: "virtual service with known references",
+			config: config.Config{Meta: meta(gvk.VirtualService), Spec: &networking.VirtualService{
+				Hosts:    []string{"reviews"},
+				Gateways: []string{"mesh", "istio-system/ingress", "local"},
+				Http: []*networking.HTTPRoute{{
+					Route:  []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews"}}},
+					Mirror: &networking.Destination{Host: "external.example.com"},
+				}},
+				Tcp: []*networking.TCPRoute{{
+					Route: []*networking.RouteDestination{{Destination: &networking.Destination{Host: "*.example.com"}}},
+				}},
+			}},
+		},
+		{
+			name: "virtual service with missing references",
+			config: config.Config{Meta: meta(gvk.VirtualService), Spec: &networking.VirtualService{
+				Hosts:    []string{"reviews"},
+				Gateways: []string{"missing", "ingress.other.svc.cluster.local"},
+				Http: []*networking.HTTPRoute{
+					{
+						Match: []*networking.HTTPMatchRequest{{Gateways: []string{"missing"}}},
+						Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "ratings"}}},
+					},
+					{Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "ratings"}}}},
+				},
+			}},
+			expected: []string{
+				"referenced gateway ns/missing does not exist",
+				"referenced gateway other/ingress does not exist",
+				"destination host ratings.ns.svc.cluster.local matches no service or ServiceEntry",
+			},
+		},
+		{
+			name: "destination rule with unmatched subset",
+			config: config.Config{Meta: meta(gvk.DestinationRule), Spec: &networking.DestinationRule{
+				Host: "reviews",
+				Subsets: []*networking.Subset{
+					{Name: "v1", Labels: map[string]string{"version": "v1"}},
+					{Name: "v2", Labels: map[string]string{"version": "v2"}},
+					{Name: "all"},
+				},
+			}},
+			expected: []string{"labels of subset v2 match no workload of reviews.ns.svc.cluster.local"},
+		},
+		{
+			name: "destination rule with missing host",
+			config: config.Config{Meta: meta(gvk.DestinationRule), Spec: &networking.DestinationRule{
+				Host:    "ratings",
+				Subsets: []*networking.Subset{{Name: "v1", Labels: map[string]string{"version": "v1"}}},
+			}},
+			expected: []string{"host ratings.ns.svc.cluster.local matches no service or ServiceEntry"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := referenceWarnings(lookup, tt.config); !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("got %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
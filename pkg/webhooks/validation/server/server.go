@@ -78,6 +78,9 @@ type Options struct {
 
 	// Use an existing mux instead of creating our own.
 	Mux *http.ServeMux
+
+	// Lookup resolves the resources referenced by configs, which are then validated against them. Optional.
+	Lookup ReferenceLookup
 }
 
 // String produces a stringified version of the arguments for debugging.
@@ -102,6 +105,7 @@ type Webhook struct {
 	// pilot
 	schemas      collection.Schemas
 	domainSuffix string
+	lookup       ReferenceLookup
 }
 
 // New creates a new instance of the admission webhook server.
@@ -113,6 +117,7 @@ func New(o Options) (*Webhook, error) {
 	wh := &Webhook{
 		schemas:      o.Schemas,
 		domainSuffix: o.DomainSuffix,
+		lookup:       o.Lookup,
 	}
 
 	o.Mux.HandleFunc("/validate", wh.serveValidate)
@@ -240,8 +245,13 @@ func (wh *Webhook) validate(request *kube.AdmissionRequest) *kube.AdmissionRespo
 		return toAdmissionResponse(err)
 	}
 
+	kubeWarnings := toKubeWarnings(warnings)
+	if wh.lookup != nil && wh.lookup.Ready() {
+		kubeWarnings = append(kubeWarnings, referenceWarnings(wh.lookup, *out)...)
+	}
+
 	reportValidationPass(request)
-	return &kube.AdmissionResponse{Allowed: true, Warnings: toKubeWarnings(warnings)}
+	return &kube.AdmissionResponse{Allowed: true, Warnings: kubeWarnings}
 }
 
 func toKubeWarnings(warn validation.Warning) []string {
@@ -187,28 +187,32 @@ func (w *WebhookCertPatcher) patchMutatingWebhookConfig(
 // startCaBundleWatcher listens for updates to the CA bundle and patches the webhooks.
 func (w *WebhookCertPatcher) startCaBundleWatcher(stop <-chan struct{}) {
 	watchCh := w.CABundleWatcher.AddWatcher()
-	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", label.IoIstioRev.Name, w.revision)}
 	for {
 		select {
 		case <-watchCh:
-			lists, err := w.whcLw.List(options)
-			if err != nil {
+			if err := w.PatchAll(); err != nil {
 				log.Errorf("failed to get mutatingWebhookConfigurations %s", err)
-				break
-			}
-			whcList := lists.(*v1.MutatingWebhookConfigurationList)
-			var whcNameList []string
-			for _, whc := range whcList.Items {
-				whcNameList = append(whcNameList, whc.Name)
-			}
-			for _, whcName := range whcNameList {
-				log.Debugf("updating caBundle for webhook %q", whcName)
-				w.queue.Push(func() error {
-					return w.webhookPatchTask(whcName)
-				})
 			}
 		case <-stop:
 			return
 		}
 	}
 }
+
+// PatchAll queues patching the CA bundle of all the webhook configurations of the revision.
+func (w *WebhookCertPatcher) PatchAll() error {
+	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", label.IoIstioRev.Name, w.revision)}
+	lists, err := w.whcLw.List(options)
+	if err != nil {
+		return err
+	}
+	whcList := lists.(*v1.MutatingWebhookConfigurationList)
+	for _, whc := range whcList.Items {
+		whcName := whc.Name
+		log.Debugf("updating caBundle for webhook %q", whcName)
+		w.queue.Push(func() error {
+			return w.webhookPatchTask(whcName)
+		})
+	}
+	return nil
+}
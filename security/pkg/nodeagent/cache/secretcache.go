@@ -239,6 +239,14 @@ func (sc *SecretManagerClient) getCachedSecret(resourceName string) (secret *sec
 	return nil
 }
 
+// WorkloadCertExpiry returns the expiry of the cached workload certificate, if one has been issued.
+func (sc *SecretManagerClient) WorkloadCertExpiry() (time.Time, bool) {
+	if c := sc.cache.GetWorkload(); c != nil {
+		return c.ExpireTime, true
+	}
+	return time.Time{}, false
+}
+
 // GenerateSecret passes the cached secret to SDS.StreamSecrets and SDS.FetchSecret.
 func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *security.SecretItem, err error) {
 	cacheLog.Debugf("generate secret %q", resourceName)
@@ -25,6 +25,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
+	"istio.io/istio/pkg/fips"
 	"istio.io/istio/security/pkg/cmd"
 	k8ssecret "istio.io/istio/security/pkg/k8s/secret"
 	caerror "istio.io/istio/security/pkg/pki/error"
@@ -384,6 +385,12 @@ func (ca *IstioCA) sign(csrPEM []byte, subjectIDs []string, requestedLifetime ti
 	if err != nil {
 		return nil, caerror.NewError(caerror.CSRError, err)
 	}
+	if fips.Enabled() {
+		if err := fips.CheckPublicKey(csr.PublicKey); err != nil {
+			fips.RecordViolation("CSR key", csr.PublicKeyAlgorithm.String(), err.Error())
+			return nil, caerror.NewError(caerror.CSRError, err)
+		}
+	}
 
 	lifetime := requestedLifetime
 	// If the requested requestedLifetime is non-positive, apply the default TTL.
@@ -16,10 +16,12 @@ package ra
 
 import (
 	"fmt"
+	"time"
 
 	cert "k8s.io/api/certificates/v1beta1"
 	certclient "k8s.io/client-go/kubernetes/typed/certificates/v1beta1"
 
+	"istio.io/istio/pkg/dependency"
 	"istio.io/istio/security/pkg/k8s/chiron"
 	"istio.io/istio/security/pkg/pki/ca"
 	raerror "istio.io/istio/security/pkg/pki/error"
@@ -59,7 +61,9 @@ func (r *KubernetesRA) kubernetesSign(csrPEM []byte, csrName string, caCertFile
 			cert.UsageClientAuth,
 		},
 	}
+	start := time.Now()
 	certChain, _, err := chiron.SignCSRK8s(r.csrInterface.CertificateSigningRequests(), csrName, csrSpec, "", caCertFile, false)
+	dependency.Report(dependency.ExternalCA, r.raOpts.CaSigner, start, err)
 	if err != nil {
 		return nil, raerror.NewError(raerror.CertGenError, err)
 	}
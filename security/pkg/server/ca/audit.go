@@ -0,0 +1,208 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	// auditResultIssued is the result of the requests issued a certificate.
+	auditResultIssued = "issued"
+	// defaultAuditQueryLimit is the number of records returned by the audit debug view, if not set.
+	defaultAuditQueryLimit = 100
+)
+
+// IssuanceRecord is the audit record of a certificate request of an authenticated caller.
+type IssuanceRecord struct {
+	Time time.Time `json:"time"`
+	// Identities are the identities of the caller, which are the ones the certificate is requested for.
+	Identities []string `json:"identities"`
+	// SANs are the subject alternative names of the certificate issued.
+	SANs         []string `json:"sans,omitempty"`
+	SerialNumber string   `json:"serialNumber,omitempty"`
+	RequestedTTL string   `json:"requestedTTL,omitempty"`
+	// TTL is the lifetime of the certificate issued.
+	TTL         string `json:"ttl,omitempty"`
+	RequesterIP string `json:"requesterIP"`
+	AuthMethod  string `json:"authMethod"`
+	// Result is issued, or the reason the certificate was denied.
+	Result string `json:"result"`
+}
+
+// AuditSink receives the audit records, for instance to ship them to an external store. Record must not block.
+type AuditSink interface {
+	Record(r IssuanceRecord)
+}
+
+// auditLog keeps the most recent audit records in a ring buffer, and forwards all of them to the sinks.
+type auditLog struct {
+	sinks []AuditSink
+
+	mu      sync.RWMutex
+	records []IssuanceRecord
+	next    int
+	full    bool
+}
+
+func newAuditLog(size int, sinks []AuditSink) *auditLog {
+	if size < 0 {
+		size = 0
+	}
+	return &auditLog{records: make([]IssuanceRecord, size), sinks: sinks}
+}
+
+// record adds the record to the log and forwards it to the sinks.
+func (a *auditLog) record(r IssuanceRecord) {
+	if a == nil {
+		return
+	}
+	for _, s := range a.sinks {
+		s.Record(r)
+	}
+	if len(a.records) == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.records[a.next] = r
+	a.next = (a.next + 1) % len(a.records)
+	if a.next == 0 {
+		a.full = true
+	}
+}
+
+// query returns the records, most recent first, of the identity if not empty, since the given time, up to limit.
+func (a *auditLog) query(identity string, since time.Time, limit int) []IssuanceRecord {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := []IssuanceRecord{}
+	if len(a.records) == 0 {
+		return out
+	}
+	n := a.next
+	if a.full {
+		n = len(a.records)
+	}
+	for i := 1; i <= n && len(out) < limit; i++ {
+		r := a.records[(a.next-i+len(a.records))%len(a.records)]
+		if r.Time.Before(since) {
+			break
+		}
+		if identity != "" && !containsString(r.Identities, identity) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// newIssuanceRecord returns the audit record of a request of the caller, without result.
+func newIssuanceRecord(ctx context.Context, caller *security.Caller, requestedTTL time.Duration) IssuanceRecord {
+	r := IssuanceRecord{
+		Time:        time.Now(),
+		Identities:  caller.Identities,
+		RequesterIP: getConnectionAddress(ctx),
+		AuthMethod:  authMethod(caller.AuthSource),
+	}
+	if host, _, err := net.SplitHostPort(r.RequesterIP); err == nil {
+		r.RequesterIP = host
+	}
+	if requestedTTL > 0 {
+		r.RequestedTTL = requestedTTL.String()
+	}
+	return r
+}
+
+// withCertificate sets the SANs, serial number and TTL of the certificate issued, if it can be parsed.
+func (r IssuanceRecord) withCertificate(certPEM []byte) IssuanceRecord {
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	if err != nil {
+		return r
+	}
+	if ids, err := util.ExtractIDs(cert.Extensions); err == nil {
+		r.SANs = ids
+	}
+	r.SerialNumber = cert.SerialNumber.String()
+	r.TTL = cert.NotAfter.Sub(cert.NotBefore).String()
+	return r
+}
+
+func authMethod(source security.AuthSource) string {
+	switch source {
+	case security.AuthSourceClientCertificate:
+		return "client_certificate"
+	case security.AuthSourceIDToken:
+		return "id_token"
+	}
+	return "unknown"
+}
+
+// SetAuditLog enables the audit of the certificate requests, keeping the size most recent records for the debug
+// view and forwarding all of them to the sinks.
+func (s *Server) SetAuditLog(size int, sinks ...AuditSink) {
+	s.audit = newAuditLog(size, sinks)
+}
+
+// AuditHandler serves the most recent audit records, filtered by ?identity=<identity> and ?since=<RFC 3339 time>,
+// up to ?limit=<n>.
+func (s *Server) AuditHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if s.audit == nil {
+			http.Error(w, "the CA server does not audit its requests", http.StatusNotFound)
+			return
+		}
+		q := req.URL.Query()
+		limit := defaultAuditQueryLimit
+		if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+			limit = v
+		}
+		var since time.Time
+		if v := q.Get("since"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			since = t
+		}
+		w.Header().Set("Content-Type", "application/json")
+		b, err := json.MarshalIndent(s.audit.query(q.Get("identity"), since, limit), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"istio.io/pkg/log"
+)
+
+var auditLogScope = log.RegisterScope("caaudit", "Audit log of the certificate requests of the CA", 0)
+
+const (
+	// auditBatchSize is the maximum number of records sent at once by the HTTP sink.
+	auditBatchSize = 100
+	// auditFlushInterval is the maximum time the HTTP sink waits to fill a batch.
+	auditFlushInterval = time.Second
+	// auditSinkTimeout bounds the time to send a batch.
+	auditSinkTimeout = 10 * time.Second
+	// auditQueueSize is the number of records the HTTP sink queues while sending.
+	auditQueueSize = 10000
+)
+
+type logAuditSink struct{}
+
+// NewLogAuditSink returns a sink logging each record as JSON to the caaudit scope.
+func NewLogAuditSink() AuditSink {
+	return logAuditSink{}
+}
+
+func (logAuditSink) Record(r IssuanceRecord) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		auditLogScope.Warnf("failed to marshal audit record: %v", err)
+		return
+	}
+	auditLogScope.Info(string(b))
+}
+
+// httpAuditSink posts the records, in JSON arrays of up to auditBatchSize records, to an external endpoint. The
+// records are queued and sent in the background; they are dropped when the queue is full.
+type httpAuditSink struct {
+	url    string
+	client *http.Client
+	queue  chan IssuanceRecord
+}
+
+// NewHTTPAuditSink returns a sink posting the records to the URL until stop is closed.
+func NewHTTPAuditSink(url string, stop <-chan struct{}) AuditSink {
+	s := &httpAuditSink{
+		url:    url,
+		client: &http.Client{Timeout: auditSinkTimeout},
+		queue:  make(chan IssuanceRecord, auditQueueSize),
+	}
+	go s.run(stop)
+	return s
+}
+
+func (s *httpAuditSink) Record(r IssuanceRecord) {
+	select {
+	case s.queue <- r:
+	default:
+		auditRecordsDropped.Increment()
+	}
+}
+
+func (s *httpAuditSink) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+	batch := make([]IssuanceRecord, 0, auditBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.send(batch); err != nil {
+			auditLogScope.Warnf("failed to send %d audit records to %s: %v", len(batch), s.url, err)
+			auditRecordsDropped.RecordInt(int64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case <-stop:
+			flush()
+			return
+		case r := <-s.queue:
+			batch = append(batch, r)
+			if len(batch) == auditBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *httpAuditSink) send(batch []IssuanceRecord) error {
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
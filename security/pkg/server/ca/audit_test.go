@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestAuditLog(t *testing.T) {
+	a := newAuditLog(3, nil)
+	start := time.Unix(1000, 0)
+	ids := []string{"a", "b", "a", "c", "a"}
+	for i, id := range ids {
+		a.record(IssuanceRecord{Time: start.Add(time.Duration(i) * time.Second), Identities: []string{id}, Result: auditResultIssued})
+	}
+
+	got := a.query("", time.Time{}, 10)
+	if len(got) != 3 || got[0].Identities[0] != "a" || got[1].Identities[0] != "c" || got[2].Identities[0] != "a" {
+		t.Fatalf("expected the 3 most recent records, most recent first, got %+v", got)
+	}
+	if got := a.query("a", time.Time{}, 10); len(got) != 2 {
+		t.Fatalf("expected the records of a, got %+v", got)
+	}
+	if got := a.query("", start.Add(4*time.Second), 10); len(got) != 1 {
+		t.Fatalf("expected the records since the last one, got %+v", got)
+	}
+	if got := a.query("", time.Time{}, 1); len(got) != 1 {
+		t.Fatalf("expected the records up to the limit, got %+v", got)
+	}
+	if got := newAuditLog(0, nil).query("", time.Time{}, 10); len(got) != 0 {
+		t.Fatalf("expected no records without log, got %+v", got)
+	}
+}
+
+func TestHTTPAuditSink(t *testing.T) {
+	received := make(chan []IssuanceRecord, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var batch []IssuanceRecord
+		if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- batch
+	}))
+	defer srv.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	s := &Server{}
+	s.SetAuditLog(10, NewHTTPAuditSink(srv.URL, stop))
+	s.audit.record(IssuanceRecord{Identities: []string{"spiffe://cluster.local/ns/default/sa/a"}, Result: auditResultIssued})
+	s.audit.record(IssuanceRecord{Identities: []string{"spiffe://cluster.local/ns/default/sa/b"}, Result: auditResultIssued})
+
+	total := 0
+	retry.UntilSuccessOrFail(t, func() error {
+		select {
+		case batch := <-received:
+			total += len(batch)
+		default:
+		}
+		if total != 2 {
+			return fmt.Errorf("received %d records, expected 2", total)
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+}
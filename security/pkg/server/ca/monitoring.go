@@ -19,11 +19,13 @@ import (
 )
 
 const (
-	errorlabel = "error"
+	errorlabel  = "error"
+	reasonlabel = "reason"
 )
 
 var (
-	errorTag = monitoring.MustCreateLabel(errorlabel)
+	errorTag  = monitoring.MustCreateLabel(errorlabel)
+	reasonTag = monitoring.MustCreateLabel(reasonlabel)
 
 	csrCounts = monitoring.NewSum(
 		"citadel_server_csr_count",
@@ -51,6 +53,17 @@ var (
 		monitoring.WithLabels(errorTag),
 	)
 
+	rateLimitedCounts = monitoring.NewSum(
+		"citadel_server_csr_rate_limited_count",
+		"The number of CSRs denied by the issuance rate limits and quotas.",
+		monitoring.WithLabels(reasonTag),
+	)
+
+	auditRecordsDropped = monitoring.NewSum(
+		"citadel_server_audit_records_dropped_count",
+		"The number of audit records of certificate requests that could not be sent to the external sink.",
+	)
+
 	successCounts = monitoring.NewSum(
 		"citadel_server_success_cert_issuance_count",
 		"The number of certificates issuances that have succeeded.",
@@ -75,6 +88,8 @@ func init() {
 		csrParsingErrorCounts,
 		idExtractionErrorCounts,
 		certSignErrorCounts,
+		rateLimitedCounts,
+		auditRecordsDropped,
 		successCounts,
 		rootCertExpiryTimestamp,
 		certChainExpiryTimestamp,
@@ -89,6 +104,7 @@ type monitoringMetrics struct {
 	CSRError          monitoring.Metric
 	IDExtractionError monitoring.Metric
 	certSignErrors    monitoring.Metric
+	rateLimited       monitoring.Metric
 }
 
 // newMonitoringMetrics creates a new monitoringMetrics.
@@ -100,9 +116,14 @@ func newMonitoringMetrics() monitoringMetrics {
 		CSRError:          csrParsingErrorCounts,
 		IDExtractionError: idExtractionErrorCounts,
 		certSignErrors:    certSignErrorCounts,
+		rateLimited:       rateLimitedCounts,
 	}
 }
 
 func (m *monitoringMetrics) GetCertSignError(err string) monitoring.Metric {
 	return m.certSignErrors.With(errorTag.Value(err))
 }
+
+func (m *monitoringMetrics) GetRateLimited(reason string) monitoring.Metric {
+	return m.rateLimited.With(reasonTag.Value(reason))
+}
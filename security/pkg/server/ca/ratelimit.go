@@ -0,0 +1,278 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	// rateLimitedIdentity is the reason of the rejections by the rate limit of an identity.
+	rateLimitedIdentity = "identity_rate"
+	// rateLimitedNamespace is the reason of the rejections by the rate limit of a namespace.
+	rateLimitedNamespace = "namespace_rate"
+	// quotaExceededIdentity is the reason of the rejections by the quota of an identity.
+	quotaExceededIdentity = "identity_quota"
+
+	// defaultQuotaWindow is the window of the quotas and of the requester statistics, if not configured.
+	defaultQuotaWindow = time.Hour
+	// defaultTopRequesters is the number of requesters listed by the debug view.
+	defaultTopRequesters = 20
+)
+
+// IssuanceLimits bound the certificates the CA server issues to each identity and namespace, so that a misbehaving
+// workload, such as an agent in a crash loop, cannot exhaust the capacity of the CA for the rest of the mesh. Zero
+// values disable the corresponding limit.
+type IssuanceLimits struct {
+	// IdentityRate is the sustained number of certificates per second issued to an identity.
+	IdentityRate float64
+	// IdentityBurst is the number of certificates an identity can get at once, above the rate.
+	IdentityBurst int
+	// NamespaceRate is the sustained number of certificates per second issued to the identities of a namespace.
+	NamespaceRate float64
+	// NamespaceBurst is the number of certificates a namespace can get at once, above the rate.
+	NamespaceBurst int
+	// IdentityQuota is the number of certificates issued to an identity per QuotaWindow.
+	IdentityQuota int
+	// QuotaWindow is the window of the quotas, and of the statistics of the requesters.
+	QuotaWindow time.Duration
+}
+
+// requester tracks the requests of an identity or a namespace in the current window.
+type requester struct {
+	limiter  *rate.Limiter
+	issued   int
+	rejected int
+	lastSeen time.Time
+}
+
+// issuanceLimiter enforces the IssuanceLimits and keeps the statistics of the requesters.
+type issuanceLimiter struct {
+	limits IssuanceLimits
+	now    func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	identities  map[string]*requester
+	namespaces  map[string]*requester
+}
+
+func newIssuanceLimiter(limits IssuanceLimits) *issuanceLimiter {
+	if limits.QuotaWindow <= 0 {
+		limits.QuotaWindow = defaultQuotaWindow
+	}
+	l := &issuanceLimiter{
+		limits:     limits,
+		now:        time.Now,
+		identities: map[string]*requester{},
+		namespaces: map[string]*requester{},
+	}
+	l.windowStart = l.now()
+	return l
+}
+
+func newLimiter(r float64, burst int) *rate.Limiter {
+	if r <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(r), burst)
+}
+
+// get returns the requester of the key, creating it if needed.
+func get(requesters map[string]*requester, key string, r float64, burst int, now time.Time) *requester {
+	req, f := requesters[key]
+	if !f {
+		req = &requester{limiter: newLimiter(r, burst)}
+		requesters[key] = req
+	}
+	req.lastSeen = now
+	return req
+}
+
+// allow returns the reason the identity is denied a certificate, or an empty string if it is allowed one.
+func (l *issuanceLimiter) allow(identity string) string {
+	if l == nil || identity == "" {
+		return ""
+	}
+	namespace := ""
+	if id, err := spiffe.ParseIdentity(identity); err == nil {
+		namespace = id.Namespace
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.windowStart) >= l.limits.QuotaWindow {
+		l.startWindow(now)
+	}
+	id := get(l.identities, identity, l.limits.IdentityRate, l.limits.IdentityBurst, now)
+	var ns *requester
+	if namespace != "" {
+		ns = get(l.namespaces, namespace, l.limits.NamespaceRate, l.limits.NamespaceBurst, now)
+	}
+
+	// A request denied by a limit does not consume the tokens of the others: the token taken from the identity limiter
+	// is given back if the namespace limiter denies the request. The rejection is counted against the requester whose
+	// limit denied it.
+	if l.limits.IdentityQuota > 0 && id.issued >= l.limits.IdentityQuota {
+		id.rejected++
+		return quotaExceededIdentity
+	}
+	idReservation, ok := reserve(id.limiter, now)
+	if !ok {
+		id.rejected++
+		return rateLimitedIdentity
+	}
+	if ns != nil {
+		if _, ok := reserve(ns.limiter, now); !ok {
+			if idReservation != nil {
+				idReservation.CancelAt(now)
+			}
+			ns.rejected++
+			return rateLimitedNamespace
+		}
+	}
+	id.issued++
+	if ns != nil {
+		ns.issued++
+	}
+	return ""
+}
+
+// reserve takes a token of the limiter if one is available now. The reservation is returned, so that the token can be
+// given back if another limit denies the request. A nil limiter allows all the requests.
+func reserve(limiter *rate.Limiter, now time.Time) (*rate.Reservation, bool) {
+	if limiter == nil {
+		return nil, true
+	}
+	r := limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return nil, false
+	}
+	if r.DelayFrom(now) > 0 {
+		r.CancelAt(now)
+		return nil, false
+	}
+	return r, true
+}
+
+// startWindow resets the quotas and statistics, and forgets the requesters idle during the last window.
+func (l *issuanceLimiter) startWindow(now time.Time) {
+	for _, requesters := range []map[string]*requester{l.identities, l.namespaces} {
+		for key, req := range requesters {
+			if req.lastSeen.Before(l.windowStart) {
+				delete(requesters, key)
+				continue
+			}
+			req.issued, req.rejected = 0, 0
+		}
+	}
+	l.windowStart = now
+}
+
+// Requester are the certificates issued to and denied to an identity or namespace in the current window.
+type Requester struct {
+	Name     string `json:"name"`
+	Issued   int    `json:"issued"`
+	Rejected int    `json:"rejected"`
+}
+
+// IssuanceStatus is the debug view of the issuance limits and of the top requesters of the current window.
+type IssuanceStatus struct {
+	IdentityRate   float64     `json:"identityRate,omitempty"`
+	IdentityBurst  int         `json:"identityBurst,omitempty"`
+	NamespaceRate  float64     `json:"namespaceRate,omitempty"`
+	NamespaceBurst int         `json:"namespaceBurst,omitempty"`
+	IdentityQuota  int         `json:"identityQuota,omitempty"`
+	QuotaWindow    string      `json:"quotaWindow"`
+	WindowStart    time.Time   `json:"windowStart"`
+	Identities     []Requester `json:"identities"`
+	Namespaces     []Requester `json:"namespaces"`
+}
+
+// status returns the limits and the top n requesters, by number of requests.
+func (l *issuanceLimiter) status(n int) IssuanceStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return IssuanceStatus{
+		IdentityRate:   l.limits.IdentityRate,
+		IdentityBurst:  l.limits.IdentityBurst,
+		NamespaceRate:  l.limits.NamespaceRate,
+		NamespaceBurst: l.limits.NamespaceBurst,
+		IdentityQuota:  l.limits.IdentityQuota,
+		QuotaWindow:    l.limits.QuotaWindow.String(),
+		WindowStart:    l.windowStart,
+		Identities:     top(l.identities, n),
+		Namespaces:     top(l.namespaces, n),
+	}
+}
+
+func top(requesters map[string]*requester, n int) []Requester {
+	out := make([]Requester, 0, len(requesters))
+	for name, req := range requesters {
+		if req.issued+req.rejected > 0 {
+			out = append(out, Requester{Name: name, Issued: req.issued, Rejected: req.rejected})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if a, b := out[i].Issued+out[i].Rejected, out[j].Issued+out[j].Rejected; a != b {
+			return a > b
+		}
+		return out[i].Name < out[j].Name
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// SetIssuanceLimits sets the limits of the certificates issued to each identity and namespace.
+func (s *Server) SetIssuanceLimits(limits IssuanceLimits) {
+	s.limiter = newIssuanceLimiter(limits)
+}
+
+// IssuanceHandler serves the issuance limits and the top requesters of certificates of the current window. The
+// number of requesters listed can be set with ?top=<n>.
+func (s *Server) IssuanceHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := defaultTopRequesters
+		if v, err := strconv.Atoi(req.URL.Query().Get("top")); err == nil && v > 0 {
+			n = v
+		}
+		if s.limiter == nil {
+			http.Error(w, "the CA server does not track its requesters", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		b, err := json.MarshalIndent(s.limiter.status(n), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssuanceLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newIssuanceLimiter(IssuanceLimits{
+		IdentityRate:   1,
+		IdentityBurst:  2,
+		NamespaceRate:  1,
+		NamespaceBurst: 3,
+		IdentityQuota:  4,
+		QuotaWindow:    time.Minute,
+	})
+	l.now = func() time.Time { return now }
+	l.windowStart = now
+
+	crashloop := "spiffe://cluster.local/ns/default/sa/crashloop"
+	other := "spiffe://cluster.local/ns/default/sa/other"
+	expect := func(identity, reason string) {
+		t.Helper()
+		if got := l.allow(identity); got != reason {
+			t.Fatalf("%s: got %q, expected %q", identity, got, reason)
+		}
+	}
+
+	expect(crashloop, "")
+	expect(crashloop, "")
+	expect(crashloop, rateLimitedIdentity)
+	// The namespace has a single certificate left in its burst.
+	expect(other, "")
+	expect(other, rateLimitedNamespace)
+
+	now = now.Add(2 * time.Second)
+	expect(crashloop, "")
+	expect(crashloop, "")
+	now = now.Add(2 * time.Second)
+	// The crash looping identity exhausted its quota of the window.
+	expect(crashloop, quotaExceededIdentity)
+
+	st := l.status(1)
+	if len(st.Identities) != 1 || st.Identities[0].Name != crashloop || st.Identities[0].Issued != 4 ||
+		st.Identities[0].Rejected != 2 {
+		t.Fatalf("unexpected top requesters %+v", st.Identities)
+	}
+	// Only the rejection of other is due to the limit of the namespace.
+	if len(st.Namespaces) != 1 || st.Namespaces[0].Name != "default" || st.Namespaces[0].Rejected != 1 {
+		t.Fatalf("unexpected top namespaces %+v", st.Namespaces)
+	}
+
+	// A new window resets the quota, and forgets the idle requesters of the previous one.
+	now = now.Add(time.Minute)
+	expect(crashloop, "")
+	now = now.Add(time.Minute)
+	expect(crashloop, "")
+	if _, f := l.identities[other]; f {
+		t.Fatalf("expected the idle identity to be forgotten")
+	}
+}
+
+func TestIssuanceLimiterRejectionKeepsTokens(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newIssuanceLimiter(IssuanceLimits{
+		IdentityRate:   0.001,
+		IdentityBurst:  1,
+		NamespaceRate:  1,
+		NamespaceBurst: 1,
+	})
+	l.now = func() time.Time { return now }
+	l.windowStart = now
+
+	a := "spiffe://cluster.local/ns/default/sa/a"
+	b := "spiffe://cluster.local/ns/default/sa/b"
+	if got := l.allow(a); got != "" {
+		t.Fatalf("expected a to be allowed, got %q", got)
+	}
+	if got := l.allow(b); got != rateLimitedNamespace {
+		t.Fatalf("expected b to be denied by the namespace limit, got %q", got)
+	}
+	// The namespace got a new token, and b did not lose its own to the rejected request.
+	now = now.Add(time.Second)
+	if got := l.allow(b); got != "" {
+		t.Fatalf("expected b to be allowed, got %q", got)
+	}
+	if r := l.identities[b]; r.issued != 1 || r.rejected != 0 {
+		t.Fatalf("expected the rejection to be counted against the namespace, got %+v", r)
+	}
+	if r := l.namespaces["default"]; r.issued != 2 || r.rejected != 1 {
+		t.Fatalf("unexpected namespace requests %+v", r)
+	}
+}
+
+func TestIssuanceHandler(t *testing.T) {
+	s := &Server{}
+	rec := httptest.NewRecorder()
+	s.IssuanceHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/ext/ca_requesterz", nil))
+	if rec.Code != 404 {
+		t.Fatalf("expected not found without limiter, got %d", rec.Code)
+	}
+
+	s.SetIssuanceLimits(IssuanceLimits{})
+	s.limiter.allow("spiffe://cluster.local/ns/default/sa/a")
+	rec = httptest.NewRecorder()
+	s.IssuanceHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/ext/ca_requesterz?top=5", nil))
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), "spiffe://cluster.local/ns/default/sa/a") {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+}
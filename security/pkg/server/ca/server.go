@@ -51,6 +51,8 @@ type Server struct {
 	Authenticators []security.Authenticator
 	ca             CertificateAuthority
 	serverCertTTL  time.Duration
+	limiter        *issuanceLimiter
+	audit          *auditLog
 }
 
 func getConnectionAddress(ctx context.Context) string {
@@ -77,6 +79,18 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 		return nil, status.Error(codes.Unauthenticated, "request authenticate failure")
 	}
 
+	audit := newIssuanceRecord(ctx, caller, time.Duration(request.ValidityDuration)*time.Second)
+	if len(caller.Identities) > 0 {
+		if reason := s.limiter.allow(caller.Identities[0]); reason != "" {
+			s.monitoring.GetRateLimited(reason).Increment()
+			serverCaLog.Warnf("denying certificate to %s: %s exceeded", caller.Identities[0], reason)
+			audit.Result = reason + " exceeded"
+			s.audit.record(audit)
+			return nil, status.Errorf(codes.ResourceExhausted, "certificate issuance %s exceeded for %s",
+				reason, caller.Identities[0])
+		}
+	}
+
 	// TODO: Call authorizer.
 
 	_, _, certChainBytes, rootCertBytes := s.ca.GetCAKeyCertBundle().GetAll()
@@ -88,6 +102,8 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	cert, signErr := s.ca.Sign([]byte(request.Csr), certOpts)
 	if signErr != nil {
 		serverCaLog.Errorf("CSR signing error (%v)", signErr.Error())
+		audit.Result = signErr.Error()
+		s.audit.record(audit)
 		s.monitoring.GetCertSignError(signErr.(*caerror.Error).ErrorType()).Increment()
 		return nil, status.Errorf(signErr.(*caerror.Error).HTTPErrorCode(), "CSR signing error (%v)", signErr.(*caerror.Error))
 	}
@@ -99,6 +115,11 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	response := &pb.IstioCertificateResponse{
 		CertChain: respCertChain,
 	}
+	if s.audit != nil {
+		audit = audit.withCertificate(cert)
+		audit.Result = auditResultIssued
+		s.audit.record(audit)
+	}
 	s.monitoring.Success.Increment()
 	serverCaLog.Debug("CSR successfully signed.")
 	return response, nil
@@ -139,6 +160,7 @@ func New(ca CertificateAuthority, ttl time.Duration,
 		serverCertTTL:  ttl,
 		ca:             ca,
 		monitoring:     newMonitoringMetrics(),
+		limiter:        newIssuanceLimiter(IssuanceLimits{}),
 	}
 	return server, nil
 }